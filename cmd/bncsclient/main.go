@@ -37,6 +37,8 @@ var (
 	sha1        = flag.Bool("sha1", false, "SHA1 password authentication (used in old PvPGN servers)")
 	create      = flag.Bool("create", false, "Create account")
 	changepass  = flag.Bool("changepass", false, "Change password")
+	trace       = flag.Bool("trace", false, "Log every sent/received packet")
+	record      = flag.String("record", "", "Record packet trace to file")
 )
 
 var logOut = log.New(color.Output, "", log.Ltime)
@@ -58,6 +60,11 @@ func main() {
 		logErr.Fatal("NewClient error: ", err)
 	}
 
+	if *trace {
+		c.Logger = network.NewStdLogger(logErr, true)
+		c.SetLogger(c.Logger)
+	}
+
 	c.ServerAddr = strings.Join(flag.Args(), ":")
 	if c.ServerAddr == "" {
 		c.ServerAddr = "uswest.battle.net:6112"
@@ -92,6 +99,21 @@ func main() {
 		fmt.Println()
 	}
 
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			logErr.Fatal("Create error: ", err)
+		}
+		defer f.Close()
+
+		tw, err := network.NewTraceWriter(f)
+		if err != nil {
+			logErr.Fatal("NewTraceWriter error: ", err)
+		}
+
+		tw.TraceBNCS(&c.BNCSConn)
+	}
+
 	c.On(&network.AsyncError{}, func(ev *network.Event) {
 		var err = ev.Arg.(*network.AsyncError)
 		logErr.Println(color.RedString("[ERROR] %s", err.Error()))
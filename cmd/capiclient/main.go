@@ -7,8 +7,11 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
@@ -24,6 +27,7 @@ import (
 var (
 	endpoint = flag.String("e", capi.Endpoint, "Endpoint")
 	apikey   = flag.String("k", "", "API Key")
+	cafile   = flag.String("ca", "", "Trusted CA certificates (PEM file)")
 )
 
 var logOut = log.New(color.Output, "", log.Ltime)
@@ -43,10 +47,26 @@ func main() {
 		fmt.Println()
 	}
 
-	b, err := chat.NewBot(&chat.Config{
+	var conf = chat.Config{
 		Endpoint: *endpoint,
 		APIKey:   *apikey,
-	})
+	}
+
+	if *cafile != "" {
+		pem, err := ioutil.ReadFile(*cafile)
+		if err != nil {
+			logErr.Fatal("ReadFile error: ", err)
+		}
+
+		var pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			logErr.Fatal("No certificates found in ", *cafile)
+		}
+
+		conf.TLSConfig = &tls.Config{RootCAs: pool}
+	}
+
+	b, err := chat.NewBot(&conf)
 	if err != nil {
 		logErr.Fatal("NewBot error: ", err)
 	}
@@ -49,11 +49,18 @@ func main() {
 		logErr.Fatal("Checksum error: ", err)
 	}
 
+	file, err := w3m.HashFile(filename)
+	if err != nil {
+		logErr.Fatal("HashFile error: ", err)
+	}
+
 	var print = struct {
 		Info     w3m.Info
+		File     w3m.FileHash
 		Checksum w3m.Hash
 	}{
 		*info,
+		*file,
 		*hash,
 	}
 
@@ -8,6 +8,7 @@ package blp
 import (
 	"errors"
 	"image"
+	"image/color"
 	"image/draw"
 	"image/jpeg"
 	"io"
@@ -25,6 +26,45 @@ var (
 // Header constant for BLP files
 var Header = protocol.DString("BLP1")
 
+// Header2 constant for BLP2 files (World of Warcraft, Reforged)
+var Header2 = protocol.DString("BLP2")
+
+func init() {
+	image.RegisterFormat("blp", "BLP?", Decode, DecodeConfig)
+}
+
+// DecodeConfig returns the color model and dimensions of a BLP image without decoding the entire image
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	var b protocol.Buffer
+	switch _, err := b.ReadSizeFrom(r, 20); err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		return image.Config{}, ErrBadFormat
+	default:
+		return image.Config{}, err
+	}
+
+	var cfg = image.Config{ColorModel: color.NRGBAModel}
+	switch b.ReadLEDString() {
+	case Header:
+		if b.ReadUInt32() == 0x00 {
+			cfg.ColorModel = color.RGBAModel
+		}
+		b.ReadUInt32() //alpha
+	case Header2:
+		if b.ReadUInt32() == 0x00 {
+			cfg.ColorModel = color.RGBAModel
+		}
+		b.ReadUInt32() //encoding, alphaDepth, alphaEncoding, hasMipmap
+	default:
+		return image.Config{}, ErrBadFormat
+	}
+
+	cfg.Width = int(b.ReadUInt32())
+	cfg.Height = int(b.ReadUInt32())
+	return cfg, nil
+}
+
 // Decode a BLP image. Only take the first image if it's a mipmap.
 func Decode(r io.Reader) (image.Image, error) {
 	var b protocol.Buffer
@@ -32,6 +72,24 @@ func Decode(r io.Reader) (image.Image, error) {
 		return nil, err
 	}
 
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	var magic = protocol.Buffer{Bytes: b.Bytes[:4]}
+	switch magic.ReadLEDString() {
+	case Header:
+		return decodeBLP1(&b)
+	case Header2:
+		return decodeBLP2(&b)
+	default:
+		return nil, ErrBadFormat
+	}
+}
+
+func decodeBLP1(b *protocol.Buffer) (image.Image, error) {
+	var data = b.Bytes
+
 	var size = b.Size()
 	if size < 156 {
 		return nil, ErrBadFormat
@@ -45,15 +103,15 @@ func Decode(r io.Reader) (image.Image, error) {
 	var alphaBits = b.ReadUInt32()   //alpha
 
 	switch alphaBits {
-	case 0, 8:
+	case 0, 1, 4, 8:
 	default:
 		return nil, ErrBadFormat
 	}
 
-	b.ReadUInt32() //width
-	b.ReadUInt32() //height
-	b.ReadUInt32() //flags
-	b.ReadUInt32() //hasMipmap
+	var width = b.ReadUInt32()  //width
+	var height = b.ReadUInt32() //height
+	b.ReadUInt32()              //flags
+	b.ReadUInt32()              //hasMipmap
 
 	var mmOffset [16]uint32
 	for i := 0; i < len(mmOffset); i++ {
@@ -111,8 +169,245 @@ func Decode(r io.Reader) (image.Image, error) {
 
 		return img, nil
 
-	// case 0x01: PALETTE
+	// Palette
+	case 0x01:
+		if b.Size() < 256*4 {
+			return nil, ErrBadFormat
+		}
+
+		var palette = b.ReadBlob(256 * 4)
+		mm, err := mipmap(data, mmOffset[0], mmSize[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return decodePalette(mm, palette, int(width), int(height), alphaBits)
+
+	default:
+		return nil, ErrInvalidCompression
+	}
+}
+
+func decodeBLP2(b *protocol.Buffer) (image.Image, error) {
+	var data = b.Bytes
+
+	if b.Size() < 148+256*4 {
+		return nil, ErrBadFormat
+	}
+
+	if b.ReadLEDString() != Header2 {
+		return nil, ErrBadFormat
+	}
+
+	var compression = b.ReadUInt32() //type
+	var encoding = b.ReadUInt8()     //encoding
+	var alphaBits = b.ReadUInt8()    //alphaDepth
+	var alphaType = b.ReadUInt8()    //alphaEncoding
+	b.ReadUInt8()                    //hasMipmap
+
+	var width = int(b.ReadUInt32())
+	var height = int(b.ReadUInt32())
+
+	var mmOffset [16]uint32
+	for i := 0; i < len(mmOffset); i++ {
+		mmOffset[i] = b.ReadUInt32()
+	}
+
+	var mmSize [16]uint32
+	for i := 0; i < len(mmOffset); i++ {
+		mmSize[i] = b.ReadUInt32()
+	}
+
+	var palette = b.ReadBlob(256 * 4)
+
+	if compression != 0x01 {
+		return nil, ErrInvalidCompression
+	}
+
+	mm, err := mipmap(data, mmOffset[0], mmSize[0])
+	if err != nil {
+		return nil, err
+	}
+
+	switch encoding {
+	// Palette
+	case 0x01:
+		switch alphaBits {
+		case 0, 1, 4, 8:
+		default:
+			return nil, ErrBadFormat
+		}
+		return decodePalette(mm, palette, width, height, uint32(alphaBits))
+
+	// DXT
+	case 0x02:
+		switch alphaType {
+		case 0x00:
+			return decodeDXT(mm, width, height, 8, alphaBits != 0, nil)
+		case 0x01:
+			return decodeDXT(mm, width, height, 16, false, alphaDXT3)
+		case 0x07:
+			return decodeDXT(mm, width, height, 16, false, alphaDXT5)
+		default:
+			return nil, ErrInvalidCompression
+		}
+
+	// BGRA
+	case 0x03:
+		if len(mm) < width*height*4 {
+			return nil, ErrBadFormat
+		}
+
+		var img = image.NewNRGBA(image.Rect(0, 0, width, height))
+		for i, m := 0, width*height; i < m; i++ {
+			img.Pix[i*4+0] = mm[i*4+2]
+			img.Pix[i*4+1] = mm[i*4+1]
+			img.Pix[i*4+2] = mm[i*4+0]
+			img.Pix[i*4+3] = mm[i*4+3]
+		}
+
+		return img, nil
+
 	default:
 		return nil, ErrInvalidCompression
 	}
 }
+
+func mipmap(data []byte, offset uint32, size uint32) ([]byte, error) {
+	if offset == 0 || size == 0 || uint64(offset)+uint64(size) > uint64(len(data)) {
+		return nil, ErrBadFormat
+	}
+	return data[offset : offset+size], nil
+}
+
+func decodePalette(mm []byte, palette []byte, width int, height int, alphaBits uint32) (image.Image, error) {
+	var num = width * height
+	if len(mm) < num+(num*int(alphaBits)+7)/8 {
+		return nil, ErrBadFormat
+	}
+
+	var alpha = mm[num:]
+	var img = image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < num; i++ {
+		var c = palette[int(mm[i])*4:]
+		img.Pix[i*4+0] = c[2]
+		img.Pix[i*4+1] = c[1]
+		img.Pix[i*4+2] = c[0]
+
+		switch alphaBits {
+		case 0:
+			img.Pix[i*4+3] = 0xFF
+		case 1:
+			img.Pix[i*4+3] = (alpha[i/8] >> uint(i%8) & 0x01) * 0xFF
+		case 4:
+			img.Pix[i*4+3] = (alpha[i/2] >> uint(i%2*4) & 0x0F) * 0x11
+		case 8:
+			img.Pix[i*4+3] = alpha[i]
+		}
+	}
+
+	return img, nil
+}
+
+func rgb565(c uint16) [4]uint8 {
+	var r = uint8(c >> 11 & 0x1F)
+	var g = uint8(c >> 5 & 0x3F)
+	var b = uint8(c & 0x1F)
+	return [4]uint8{r<<3 | r>>2, g<<2 | g>>4, b<<3 | b>>2, 0xFF}
+}
+
+func lerp(a uint8, b uint8, wa int, wb int) uint8 {
+	return uint8((int(a)*wa + int(b)*wb) / (wa + wb))
+}
+
+// alphaDXT3 decodes 4-bit explicit alpha
+func alphaDXT3(blk []byte, res *[16]uint8) {
+	for i := 0; i < 16; i++ {
+		res[i] = (blk[i/2] >> uint(i%2*4) & 0x0F) * 0x11
+	}
+}
+
+// alphaDXT5 decodes interpolated alpha
+func alphaDXT5(blk []byte, res *[16]uint8) {
+	var a [8]uint8
+	a[0], a[1] = blk[0], blk[1]
+	if a[0] > a[1] {
+		for i := 1; i < 7; i++ {
+			a[i+1] = lerp(a[0], a[1], 7-i, i)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			a[i+1] = lerp(a[0], a[1], 5-i, i)
+		}
+		a[6], a[7] = 0x00, 0xFF
+	}
+
+	var bits uint64
+	for i := 0; i < 6; i++ {
+		bits |= uint64(blk[2+i]) << uint(i*8)
+	}
+	for i := 0; i < 16; i++ {
+		res[i] = a[bits>>uint(i*3)&0x07]
+	}
+}
+
+func decodeDXT(mm []byte, width int, height int, blockSize int, punchThrough bool, alpha func([]byte, *[16]uint8)) (image.Image, error) {
+	var bw = (width + 3) / 4
+	var bh = (height + 3) / 4
+	if len(mm) < bw*bh*blockSize {
+		return nil, ErrBadFormat
+	}
+
+	var img = image.NewNRGBA(image.Rect(0, 0, width, height))
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			var blk = mm[(by*bw+bx)*blockSize:]
+
+			var a [16]uint8
+			if alpha != nil {
+				alpha(blk, &a)
+				blk = blk[8:]
+			}
+
+			var c0 = uint16(blk[0]) | uint16(blk[1])<<8
+			var c1 = uint16(blk[2]) | uint16(blk[3])<<8
+
+			var c [4][4]uint8
+			c[0], c[1] = rgb565(c0), rgb565(c1)
+			if c0 > c1 || alpha != nil {
+				for i := 0; i < 3; i++ {
+					c[2][i] = lerp(c[0][i], c[1][i], 2, 1)
+					c[3][i] = lerp(c[0][i], c[1][i], 1, 2)
+				}
+				c[2][3], c[3][3] = 0xFF, 0xFF
+			} else {
+				for i := 0; i < 3; i++ {
+					c[2][i] = lerp(c[0][i], c[1][i], 1, 1)
+				}
+				c[2][3] = 0xFF
+				if !punchThrough {
+					c[3][3] = 0xFF
+				}
+			}
+
+			var idx = uint32(blk[4]) | uint32(blk[5])<<8 | uint32(blk[6])<<16 | uint32(blk[7])<<24
+			for i := 0; i < 16; i++ {
+				var x = bx*4 + i%4
+				var y = by*4 + i/4
+				if x >= width || y >= height {
+					continue
+				}
+
+				var p = c[idx>>uint(i*2)&0x03]
+				if alpha != nil {
+					p[3] = a[i]
+				}
+
+				var o = img.PixOffset(x, y)
+				copy(img.Pix[o:o+4], p[:])
+			}
+		}
+	}
+
+	return img, nil
+}
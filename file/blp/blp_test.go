@@ -9,11 +9,14 @@ import (
 	"crypto/sha512"
 	"encoding/base64"
 	"fmt"
+	"image"
+	"image/color"
 	"image/png"
 	"os"
 	"testing"
 
 	"github.com/nielsAD/gowarcraft3/file/blp"
+	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
 func Example() {
@@ -61,3 +64,99 @@ func TestJPEG(t *testing.T) {
 		t.Fatal("Sha512 mismatch")
 	}
 }
+
+func header(magic string, info []byte, width uint32, height uint32, offset uint32, size uint32) *protocol.Buffer {
+	var b protocol.Buffer
+	b.WriteLEDString(protocol.DString(magic))
+	b.WriteBlob(info)
+	b.WriteUInt32(width)
+	b.WriteUInt32(height)
+	if magic == "BLP1" {
+		b.WriteUInt32(0) //flags
+		b.WriteUInt32(0) //hasMipmap
+	}
+	b.WriteUInt32(offset)
+	b.WriteBlob(make([]byte, 15*4))
+	b.WriteUInt32(size)
+	b.WriteBlob(make([]byte, 15*4))
+	return &b
+}
+
+func TestPalette(t *testing.T) {
+	var palette = make([]byte, 256*4)
+	copy(palette[4:], []byte{0x30, 0x20, 0x10, 0x00})
+	copy(palette[8:], []byte{0x60, 0x50, 0x40, 0x00})
+
+	var pix = []byte{0, 1, 2, 1}
+	var alpha = []byte{0xF0, 0x5A}
+
+	var b = header("BLP1", []byte{1, 0, 0, 0, 4, 0, 0, 0}, 2, 2, 156+256*4, uint32(len(pix)+len(alpha)))
+	b.WriteBlob(palette)
+	b.WriteBlob(pix)
+	b.WriteBlob(alpha)
+
+	img, err := blp.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []color.NRGBA{
+		{0x00, 0x00, 0x00, 0x00}, {0x10, 0x20, 0x30, 0xFF},
+		{0x40, 0x50, 0x60, 0xAA}, {0x10, 0x20, 0x30, 0x55},
+	}
+	for i, c := range expected {
+		if p := img.At(i%2, i/2); p != c {
+			t.Fatalf("Pixel %v mismatch %v != %v", i, p, c)
+		}
+	}
+}
+
+func TestBLP2(t *testing.T) {
+	var dxt1 = []byte{
+		0x00, 0xF8, 0x1F, 0x00, // red, blue
+		0xE4, 0xE4, 0xE4, 0xE4, // 0, 1, 2, 3
+	}
+	var dxt5 = append([]byte{
+		0xFF, 0x00, // alpha 255, 0
+		0x88, 0x88, 0x88, 0x88, 0x88, 0x88, // 0, 1, 0, 1, ...
+	}, dxt1...)
+	var bgra = []byte{0x10, 0x20, 0x30, 0x40}
+
+	var formats = []struct {
+		info       []byte
+		width      uint32
+		height     uint32
+		data       []byte
+		topLeft    color.NRGBA
+		bottomLeft color.NRGBA
+	}{
+		{[]byte{1, 0, 0, 0, 2, 0, 0, 0}, 4, 4, dxt1, color.NRGBA{0xFF, 0x00, 0x00, 0xFF}, color.NRGBA{0xFF, 0x00, 0x00, 0xFF}},
+		{[]byte{1, 0, 0, 0, 2, 8, 7, 0}, 4, 4, dxt5, color.NRGBA{0xFF, 0x00, 0x00, 0xFF}, color.NRGBA{0xFF, 0x00, 0x00, 0xFF}},
+		{[]byte{1, 0, 0, 0, 3, 8, 0, 0}, 1, 1, bgra, color.NRGBA{0x30, 0x20, 0x10, 0x40}, color.NRGBA{0x30, 0x20, 0x10, 0x40}},
+	}
+
+	for _, f := range formats {
+		var b = header("BLP2", f.info, f.width, f.height, 148+256*4, uint32(len(f.data)))
+		b.WriteBlob(make([]byte, 256*4))
+		b.WriteBlob(f.data)
+
+		cfg, err := blp.DecodeConfig(&protocol.Buffer{Bytes: b.Bytes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Width != int(f.width) || cfg.Height != int(f.height) {
+			t.Fatalf("Config mismatch %v", cfg)
+		}
+
+		img, _, err := image.Decode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := img.At(0, 0); p != f.topLeft {
+			t.Fatalf("Pixel mismatch %v != %v", p, f.topLeft)
+		}
+		if p := img.At(0, int(f.height)-1); p != f.bottomLeft {
+			t.Fatalf("Pixel mismatch %v != %v", p, f.bottomLeft)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package mpq
+
+import (
+	"crypto/md5"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Difference between the subfiles of two archives
+// Named subfiles are compared by name and locale, the special files (listfile), (attributes), and (signature) are ignored
+// Subfiles without name (pseudo-names) are matched by content instead, as their pseudo-name is only an index in the file table
+type Difference struct {
+	Added          []Entry
+	Removed        []Entry
+	Modified       []Entry
+	UnnamedAdded   []Entry
+	UnnamedRemoved []Entry
+}
+
+type entryKey struct {
+	name   string
+	locale Locale
+}
+
+type hashedEntry struct {
+	Entry
+	sum [md5.Size]byte
+}
+
+func (a *Archive) hashEntry(e Entry) ([md5.Size]byte, error) {
+	var sum [md5.Size]byte
+
+	var f *File
+	var err error
+	if pseudoName.MatchString(e.Name) {
+		f, err = a.Open(e.Name)
+	} else {
+		f, err = a.OpenLocale(e.Name, e.Locale)
+	}
+	if err != nil {
+		return sum, err
+	}
+
+	var h = md5.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+
+	copy(sum[:], h.Sum(nil))
+	return sum, err
+}
+
+func (a *Archive) hashes() (map[entryKey]hashedEntry, []hashedEntry, error) {
+	entries, err := a.Entries()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var named = make(map[entryKey]hashedEntry, len(entries))
+	var unnamed []hashedEntry
+	for _, e := range entries {
+		// Subfile names are case insensitive
+		var name = strings.ToLower(e.Name)
+		if name == ListFileName || name == AttributesName || name == SignatureName {
+			continue
+		}
+
+		sum, err := a.hashEntry(e)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if pseudoName.MatchString(e.Name) {
+			unnamed = append(unnamed, hashedEntry{Entry: e, sum: sum})
+		} else {
+			named[entryKey{name: name, locale: e.Locale}] = hashedEntry{Entry: e, sum: sum}
+		}
+	}
+
+	return named, unnamed, nil
+}
+
+// unmatched returns the entries in a without an entry of the same content in b
+func unmatched(a []hashedEntry, b []hashedEntry) []Entry {
+	var count = make(map[[md5.Size]byte]int, len(b))
+	for _, e := range b {
+		count[e.sum]++
+	}
+
+	var res []Entry
+	for _, e := range a {
+		if count[e.sum] > 0 {
+			count[e.sum]--
+		} else {
+			res = append(res, e.Entry)
+		}
+	}
+	return res
+}
+
+func sortEntries(e []Entry) {
+	sort.Slice(e, func(i, j int) bool {
+		if e[i].Name != e[j].Name {
+			return e[i].Name < e[j].Name
+		}
+		return e[i].Locale < e[j].Locale
+	})
+}
+
+// Diff reports the subfiles that were added, removed, or modified (by MD5 hash of their content) in b compared to a
+func Diff(a *Archive, b *Archive) (*Difference, error) {
+	namedA, unnamedA, err := a.hashes()
+	if err != nil {
+		return nil, err
+	}
+	namedB, unnamedB, err := b.hashes()
+	if err != nil {
+		return nil, err
+	}
+
+	var res Difference
+	for k, x := range namedA {
+		if y, ok := namedB[k]; !ok {
+			res.Removed = append(res.Removed, x.Entry)
+		} else if x.sum != y.sum {
+			res.Modified = append(res.Modified, y.Entry)
+		}
+	}
+	for k, y := range namedB {
+		if _, ok := namedA[k]; !ok {
+			res.Added = append(res.Added, y.Entry)
+		}
+	}
+
+	res.UnnamedAdded = unmatched(unnamedB, unnamedA)
+	res.UnnamedRemoved = unmatched(unnamedA, unnamedB)
+
+	sortEntries(res.Added)
+	sortEntries(res.Removed)
+	sortEntries(res.Modified)
+	sortEntries(res.UnnamedAdded)
+	sortEntries(res.UnnamedRemoved)
+
+	return &res, nil
+}
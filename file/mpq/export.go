@@ -0,0 +1,104 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package mpq
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func (a *Archive) export(fn func(name string, mod time.Time, f *File) error) error {
+	files, err := a.Files()
+	if err != nil {
+		return err
+	}
+
+	attr, err := a.Attributes()
+	if err == os.ErrNotExist {
+		attr = nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, name := range files {
+		f, err := a.Open(name)
+		if err != nil {
+			return err
+		}
+
+		var mod time.Time
+		if attr != nil && attr.Flags&AttributeFileTime != 0 {
+			if idx, err := f.Index(); err == nil && idx < len(attr.Files) {
+				mod = attr.Files[idx].Time
+			}
+		}
+
+		err = fn(strings.Replace(name, "\\", "/", -1), mod, f)
+		f.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportZip streams all subfiles into w as zip archive, with modification times from (attributes)
+func (a *Archive) ExportZip(w io.Writer) error {
+	var z = zip.NewWriter(w)
+	if err := a.export(func(name string, mod time.Time, f *File) error {
+		var hdr = zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: mod,
+		}
+
+		dst, err := z.CreateHeader(&hdr)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(dst, f)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return z.Close()
+}
+
+// ExportTar streams all subfiles into w as tar archive, with modification times from (attributes)
+func (a *Archive) ExportTar(w io.Writer) error {
+	var t = tar.NewWriter(w)
+	if err := a.export(func(name string, mod time.Time, f *File) error {
+		var size = f.Size()
+		if size < 0 {
+			return ErrFileInfo
+		}
+
+		var hdr = tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     size,
+			Mode:     0644,
+			ModTime:  mod,
+		}
+		if err := t.WriteHeader(&hdr); err != nil {
+			return err
+		}
+
+		_, err := io.CopyN(t, f, size)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return t.Close()
+}
@@ -0,0 +1,137 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package mpq
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Progress is called after each extracted subfile (err is nil on success)
+type Progress func(subFileName string, done int, total int, err error)
+
+// Extractor decompresses multiple subfiles concurrently with a pool of workers
+// Archive handles are not safe for concurrent use, each worker opens its own handle
+type Extractor struct {
+	Open     func() (*Archive, error) // Opens a new handle to the archive
+	Workers  int                      // Number of concurrent workers (0 for runtime.NumCPU())
+	Progress Progress                 // Optional progress callback, called sequentially
+}
+
+// Extract subfiles from archive fileName into directory dst
+func Extract(fileName string, dst string, subFileNames []string) error {
+	var e = Extractor{
+		Open: func() (*Archive, error) { return OpenArchive(fileName) },
+	}
+	return e.Extract(dst, subFileNames)
+}
+
+// ExtractPath returns the path of subFileName extracted into directory dst
+func ExtractPath(dst string, subFileName string) (string, error) {
+	var name = filepath.FromSlash(strings.Replace(subFileName, "\\", "/", -1))
+	if name == "" || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", ErrFileName
+	}
+
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part == ".." {
+			return "", ErrFileName
+		}
+	}
+
+	return filepath.Join(dst, name), nil
+}
+
+func extractFile(a *Archive, dst string, subFileName string) error {
+	path, err := ExtractPath(dst, subFileName)
+	if err != nil {
+		return err
+	}
+
+	f, err := a.Open(subFileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, f); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// Extract subfiles into directory dst
+// All subfiles are attempted, the first error encountered is returned
+func (e *Extractor) Extract(dst string, subFileNames []string) error {
+	var workers = e.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(subFileNames) {
+		workers = len(subFileNames)
+	}
+
+	var queue = make(chan string)
+	var wg sync.WaitGroup
+
+	var mut sync.Mutex
+	var done = 0
+	var res error
+
+	var report = func(name string, err error) {
+		mut.Lock()
+		done++
+		if res == nil {
+			res = err
+		}
+		if e.Progress != nil {
+			e.Progress(name, done, len(subFileNames), err)
+		}
+		mut.Unlock()
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			a, err := e.Open()
+			if err != nil {
+				for name := range queue {
+					report(name, err)
+				}
+				return
+			}
+			defer a.Close()
+
+			for name := range queue {
+				report(name, extractFile(a, dst, name))
+			}
+		}()
+	}
+
+	for _, name := range subFileNames {
+		queue <- name
+	}
+	close(queue)
+
+	wg.Wait()
+	return res
+}
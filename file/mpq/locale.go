@@ -0,0 +1,86 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package mpq
+
+// #include <StormLib.h>
+import "C"
+import (
+	"os"
+	"sync"
+	"unsafe"
+)
+
+// Locale of a subfile (Windows LCID)
+type Locale uint16
+
+// Locales used by Warcraft III
+const (
+	LocaleNeutral    Locale = 0x000
+	LocaleChinese    Locale = 0x404
+	LocaleCzech      Locale = 0x405
+	LocaleGerman     Locale = 0x407
+	LocaleEnglish    Locale = 0x409
+	LocaleSpanish    Locale = 0x40A
+	LocaleFrench     Locale = 0x40C
+	LocaleItalian    Locale = 0x410
+	LocaleJapanese   Locale = 0x411
+	LocaleKorean     Locale = 0x412
+	LocalePolish     Locale = 0x415
+	LocalePortuguese Locale = 0x416
+	LocaleRussian    Locale = 0x419
+	LocaleEnglishUK  Locale = 0x809
+)
+
+// StormLib searches subfiles using a global locale, writers change it
+var localeMut sync.RWMutex
+
+// OpenLocale opens a subfile stored in the first available locale of locales, falling back to LocaleNeutral
+func (a *Archive) OpenLocale(subFileName string, locales ...Locale) (*File, error) {
+	var cstr = C.CString(subFileName)
+	defer C.free(unsafe.Pointer(cstr))
+
+	localeMut.Lock()
+	defer localeMut.Unlock()
+	defer C.SFileSetLocale(C.LCID(LocaleNeutral))
+
+	for _, l := range append(append([]Locale(nil), locales...), LocaleNeutral) {
+		C.SFileSetLocale(C.LCID(l))
+
+		var res File
+
+		//bool SFileOpenFileEx(HANDLE hMpq, const char * szFileName, DWORD dwSearchScope, HANDLE * phFile)
+		if C.SFileOpenFileEx(a.h, cstr, 0, &res.h) == 0 {
+			if err := getLastError(ErrFileOpen); err != os.ErrNotExist {
+				return nil, err
+			}
+			continue
+		}
+
+		// StormLib falls back to other locales if the requested one is not available
+		loc, err := res.Locale()
+		if err != nil {
+			res.Close()
+			return nil, err
+		}
+		if loc == l || l == LocaleNeutral {
+			return &res, nil
+		}
+		res.Close()
+	}
+
+	return nil, os.ErrNotExist
+}
+
+// Locale the subfile is stored in
+func (f *File) Locale() (Locale, error) {
+	var loc C.LCID
+
+	//bool SFileGetFileInfo(HANDLE hMpqOrFile, SFileInfoClass InfoClass, void * pvFileInfo, DWORD cbFileInfo, LPDWORD pcbLengthNeeded)
+	if C.SFileGetFileInfo(f.h, C.SFileInfoLocale, unsafe.Pointer(&loc), C.DWORD(unsafe.Sizeof(loc)), nil) == 0 {
+		return 0, getLastError(ErrFileInfo)
+	}
+
+	return Locale(loc), nil
+}
@@ -3,6 +3,19 @@
 // License: Mozilla Public License, v2.0
 
 // Package mpq provides golang bindings to the StormLib library to read MPQ archives.
+//
+// StormLib supports all archive format versions, including the extended headers and 64-bit file
+// positions of versions 3 and 4.
+// Sector decompression (zlib, bzip2, LZMA, sparse, PKWare DCL implode, the Huffman and ADPCM
+// compression used for WAV files, and their combinations) is handled by StormLib.
+// Subfiles are written with the same compressors (see WriteFileCompressed).
+// StormLib also decrypts encrypted subfiles, deriving the key from the file name (adjusted for FIX_KEY)
+// or recovering it from known plaintext if the name is unknown.
+// StormLib tolerates the common map protection tricks (fake headers, corrupted hash and block table entries,
+// invalid sector offsets), see OpenForceV1 and Files to open and enumerate protected maps.
+// Archives are opened read-only, unless opened with OpenWritable to add, replace and remove subfiles in place.
+// Patch archives can be chained onto a base archive (see OpenArchiveChain), StormLib then resolves
+// subfiles through the chain, applying incremental patches and honoring deleted-file markers.
 package mpq
 
 // #cgo CFLAGS: -I${SRCDIR}/../../vendor/StormLib/src
@@ -11,10 +24,15 @@ package mpq
 // #include <StormLib.h>
 import "C"
 import (
+	"bytes"
+	"crypto/md5"
 	"errors"
+	"hash/crc32"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
+	"time"
 	"unsafe"
 )
 
@@ -26,6 +44,16 @@ var (
 	ErrFileOpen     = errors.New("mpq: Could not open subfile")
 	ErrFileClose    = errors.New("mpq: Could not close subfile")
 	ErrFileRead     = errors.New("mpq: Could not read subfile")
+	ErrFileSeek     = errors.New("mpq: Could not seek subfile")
+	ErrFileName     = errors.New("mpq: Invalid subfile name")
+	ErrFileWrite    = errors.New("mpq: Could not write subfile")
+	ErrFileRemove   = errors.New("mpq: Could not remove subfile")
+	ErrArchiveWrite = errors.New("mpq: Could not write archive")
+	ErrSectorCRC    = errors.New("mpq: Sector checksum mismatch")
+	ErrHTTPStatus   = errors.New("mpq: Unexpected HTTP status")
+	ErrHTTPRange    = errors.New("mpq: Server does not support range requests")
+	ErrFileInfo     = errors.New("mpq: Could not query subfile info")
+	ErrChecksum     = errors.New("mpq: Subfile checksum mismatch")
 )
 
 func getLastError(def error) error {
@@ -48,6 +76,8 @@ func getLastError(def error) error {
 		return ErrBadFormat
 	case C.ERROR_HANDLE_EOF:
 		return io.EOF
+	case C.ERROR_CHECKSUM_ERROR:
+		return ErrSectorCRC
 	default:
 		return def
 	}
@@ -55,23 +85,108 @@ func getLastError(def error) error {
 
 // Archive stores a handle to an opened MPQ archive
 type Archive struct {
-	h C.HANDLE
+	h   C.HANDLE
+	tmp string
 }
 
 // File stores a handle to an opened subfile in an MPQ archive
+// Sectors are decompressed on demand while reading, so large subfiles can be processed with constant memory
 type File struct {
 	h C.HANDLE
 }
 
+// OpenFlags enum
+type OpenFlags uint32
+
+// Archive open flags
+const (
+	OpenMemoryMap OpenFlags = C.BASE_PROVIDER_MAP         // Map the archive into memory, falls back to regular file access if unsupported
+	OpenWritable  OpenFlags = 0x80000000                  // Open for modification, (listfile) and (attributes) are kept up to date
+	OpenForceV1   OpenFlags = C.MPQ_OPEN_FORCE_MPQ_V1     // Ignore the version and size fields of the header, like Warcraft III does (tolerates headers faked by map protectors)
+	OpenHTTP      OpenFlags = C.BASE_PROVIDER_HTTP        // Treat fileName as URL and read it on demand (Windows only, see OpenURL for other platforms)
+	OpenCheckCRC  OpenFlags = C.MPQ_OPEN_CHECK_SECTOR_CRC // Validate sectors against the sector CRC table (if present) while reading, Read fails with ErrSectorCRC on mismatch
+)
+
 // OpenArchive opens fileName as MPQ archive
 func OpenArchive(fileName string) (*Archive, error) {
+	return OpenArchiveFlags(fileName, 0)
+}
+
+// OpenArchiveFlags opens fileName as MPQ archive with additional flags
+func OpenArchiveFlags(fileName string, flags OpenFlags) (*Archive, error) {
 	var res Archive
 
 	var cstr = (*C.TCHAR)(C.CString(fileName))
 	defer C.free(unsafe.Pointer(cstr))
 
+	var cflags = C.DWORD(flags &^ OpenWritable)
+	if flags&OpenWritable == 0 {
+		cflags |= C.MPQ_OPEN_READ_ONLY | C.MPQ_OPEN_NO_LISTFILE | C.MPQ_OPEN_NO_ATTRIBUTES
+	}
+
 	//bool SFileOpenArchive(const TCHAR * szMpqName, DWORD dwPriority, DWORD dwFlags, HANDLE * phMpq)
-	if C.SFileOpenArchive(cstr, 0, C.MPQ_OPEN_READ_ONLY|C.MPQ_OPEN_NO_LISTFILE|C.MPQ_OPEN_NO_ATTRIBUTES, &res.h) == 0 {
+	if C.SFileOpenArchive(cstr, 0, cflags, &res.h) == 0 {
+		var err = getLastError(ErrArchiveOpen)
+		if flags&OpenMemoryMap == 0 || err == os.ErrNotExist {
+			return nil, err
+		}
+		return OpenArchiveFlags(fileName, flags&^OpenMemoryMap)
+	}
+
+	return &res, nil
+}
+
+// OpenArchiveChain opens fileName as base MPQ archive with a chain of patch archives, in order of increasing priority
+func OpenArchiveChain(fileName string, patches ...string) (*Archive, error) {
+	a, err := OpenArchive(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range patches {
+		if err := a.OpenPatch(p, ""); err != nil {
+			a.Close()
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// OpenPatch adds fileName as patch archive on top of the opened archive
+// Subfiles in the patch are looked up with prefix prepended to their name (empty to let StormLib detect it)
+func (a *Archive) OpenPatch(fileName string, prefix string) error {
+	var cstr = (*C.TCHAR)(C.CString(fileName))
+	defer C.free(unsafe.Pointer(cstr))
+
+	var cpre *C.char
+	if prefix != "" {
+		cpre = C.CString(prefix)
+		defer C.free(unsafe.Pointer(cpre))
+	}
+
+	//bool SFileOpenPatchArchive(HANDLE hMpq, const TCHAR * szPatchMpqName, const char * szPatchPathPrefix, DWORD dwFlags)
+	if C.SFileOpenPatchArchive(a.h, cstr, cpre, 0) == 0 {
+		return getLastError(ErrArchiveOpen)
+	}
+
+	return nil
+}
+
+// Patched reports whether patch archives are chained onto the archive
+func (a *Archive) Patched() bool {
+	return C.SFileIsPatchedArchive(a.h) != 0
+}
+
+// CreateArchive creates fileName as new (format version 1) MPQ archive with room for maxFiles subfiles, opened with OpenWritable
+func CreateArchive(fileName string, maxFiles int) (*Archive, error) {
+	var res Archive
+
+	var cstr = (*C.TCHAR)(C.CString(fileName))
+	defer C.free(unsafe.Pointer(cstr))
+
+	//bool SFileCreateArchive(const TCHAR * szMpqName, DWORD dwCreateFlags, DWORD dwMaxFileCount, HANDLE * phMpq)
+	if C.SFileCreateArchive(cstr, C.MPQ_CREATE_ARCHIVE_V1|C.MPQ_CREATE_LISTFILE|C.MPQ_CREATE_ATTRIBUTES, C.DWORD(maxFiles), &res.h) == 0 {
 		return nil, getLastError(ErrArchiveOpen)
 	}
 
@@ -86,17 +201,42 @@ func (a *Archive) Close() error {
 		}
 		a.h = nil
 	}
+	if a.tmp != "" {
+		if err := os.Remove(a.tmp); err != nil {
+			return err
+		}
+		a.tmp = ""
+	}
 	return nil
 }
 
+// Signature verification result
+type Signature uint32
+
+// Signature enum
+const (
+	SignatureNone          Signature = C.ERROR_NO_SIGNATURE
+	SignatureError         Signature = C.ERROR_VERIFY_FAILED
+	SignatureWeakValid     Signature = C.ERROR_WEAK_SIGNATURE_OK
+	SignatureWeakInvalid   Signature = C.ERROR_WEAK_SIGNATURE_ERROR
+	SignatureStrongValid   Signature = C.ERROR_STRONG_SIGNATURE_OK
+	SignatureStrongInvalid Signature = C.ERROR_STRONG_SIGNATURE_ERROR
+)
+
+// Signature checks and verifies the archive against its signature if present,
+// the weak (512-bit RSA) signature in (signature) or the strong (2048-bit RSA) signature appended to the archive
+func (a *Archive) Signature() Signature {
+	return Signature(C.SFileVerifyArchive(a.h))
+}
+
 // WeakSigned checks and verifies the archive against its weak signature if present
 func (a *Archive) WeakSigned() bool {
-	return C.SFileVerifyArchive(a.h) == C.ERROR_WEAK_SIGNATURE_OK
+	return a.Signature() == SignatureWeakValid
 }
 
 // StrongSigned checks and verifies the archive against its strong signature if present
 func (a *Archive) StrongSigned() bool {
-	return C.SFileVerifyArchive(a.h) == C.ERROR_STRONG_SIGNATURE_OK
+	return a.Signature() == SignatureStrongValid
 }
 
 // Open a subfile inside an opened MPQ archive
@@ -106,6 +246,10 @@ func (a *Archive) Open(subFileName string) (*File, error) {
 	var cstr = C.CString(subFileName)
 	defer C.free(unsafe.Pointer(cstr))
 
+	// Locale is a global setting in StormLib, see OpenLocale
+	localeMut.RLock()
+	defer localeMut.RUnlock()
+
 	//bool SFileOpenFileEx(HANDLE hMpq, const char * szFileName, DWORD dwSearchScope, HANDLE * phFile)
 	if C.SFileOpenFileEx(a.h, cstr, 0, &res.h) == 0 {
 		return nil, getLastError(ErrFileOpen)
@@ -114,6 +258,320 @@ func (a *Archive) Open(subFileName string) (*File, error) {
 	return &res, nil
 }
 
+// ReadFile reads the entire content of a subfile inside an opened MPQ archive
+func (a *Archive) ReadFile(subFileName string) ([]byte, error) {
+	f, err := a.Open(subFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return b, f.Close()
+}
+
+// Compression of subfile sectors
+type Compression uint8
+
+// Compression methods, can be combined except for LZMA
+// ADPCM is lossy and only suitable for 16-bit PCM samples (usually combined with Huffman for WAV files)
+const (
+	CompressHuffman     Compression = C.MPQ_COMPRESSION_HUFFMANN
+	CompressZlib        Compression = C.MPQ_COMPRESSION_ZLIB
+	CompressPKWare      Compression = C.MPQ_COMPRESSION_PKWARE
+	CompressBZip2       Compression = C.MPQ_COMPRESSION_BZIP2
+	CompressSparse      Compression = C.MPQ_COMPRESSION_SPARSE
+	CompressADPCMMono   Compression = C.MPQ_COMPRESSION_ADPCM_MONO
+	CompressADPCMStereo Compression = C.MPQ_COMPRESSION_ADPCM_STEREO
+	CompressLZMA        Compression = C.MPQ_COMPRESSION_LZMA
+)
+
+// WriteFile adds a zlib compressed subfile with content b to an archive opened with OpenWritable, replacing any existing subfile
+func (a *Archive) WriteFile(subFileName string, b []byte) error {
+	return a.WriteFileCompressed(subFileName, b, CompressZlib)
+}
+
+// WriteFileCompressed adds a subfile with content b compressed with c (0 for none), see WriteFile
+func (a *Archive) WriteFileCompressed(subFileName string, b []byte, c Compression) error {
+	return a.writeFile(subFileName, b, LocaleNeutral, c)
+}
+
+// WriteFileLocale adds a zlib compressed subfile with content b stored in locale l, replacing any existing subfile in that locale
+func (a *Archive) WriteFileLocale(subFileName string, b []byte, l Locale) error {
+	return a.writeFile(subFileName, b, l, CompressZlib)
+}
+
+func (a *Archive) writeFile(subFileName string, b []byte, l Locale, c Compression) error {
+	var cstr = C.CString(subFileName)
+	defer C.free(unsafe.Pointer(cstr))
+
+	var flags = C.DWORD(C.MPQ_FILE_REPLACEEXISTING)
+	if c != 0 {
+		flags |= C.MPQ_FILE_COMPRESS
+	}
+
+	var h C.HANDLE
+
+	//bool SFileCreateFile(HANDLE hMpq, const char * szArchivedName, ULONGLONG FileTime, DWORD dwFileSize, LCID lcLocale, DWORD dwFlags, HANDLE * phFile)
+	if C.SFileCreateFile(a.h, cstr, C.ULONGLONG(timeToFileTime(time.Now())), C.DWORD(len(b)), C.LCID(l), flags, &h) == 0 {
+		return getLastError(ErrFileWrite)
+	}
+
+	//bool SFileWriteFile(HANDLE hFile, const void * pvData, DWORD dwSize, DWORD dwCompression)
+	if len(b) > 0 && C.SFileWriteFile(h, unsafe.Pointer(&b[0]), C.DWORD(len(b)), C.DWORD(c)) == 0 {
+		var err = getLastError(ErrFileWrite)
+		C.SFileFinishFile(h)
+		return err
+	}
+
+	//bool SFileFinishFile(HANDLE hFile)
+	if C.SFileFinishFile(h) == 0 {
+		return getLastError(ErrFileWrite)
+	}
+
+	return nil
+}
+
+// Remove a subfile from an archive opened with OpenWritable
+func (a *Archive) Remove(subFileName string) error {
+	var cstr = C.CString(subFileName)
+	defer C.free(unsafe.Pointer(cstr))
+
+	//bool SFileRemoveFile(HANDLE hMpq, const char * szFileName, DWORD dwSearchScope)
+	if C.SFileRemoveFile(a.h, cstr, 0) == 0 {
+		return getLastError(ErrFileRemove)
+	}
+
+	return nil
+}
+
+// Flush pending changes to an archive opened with OpenWritable
+func (a *Archive) Flush() error {
+	if C.SFileFlushArchive(a.h) == 0 {
+		return getLastError(ErrArchiveWrite)
+	}
+	return nil
+}
+
+// Compact rebuilds an archive opened with OpenWritable, reclaiming the space of removed and replaced subfiles
+func (a *Archive) Compact() error {
+	//bool SFileCompactArchive(HANDLE hMpq, const TCHAR * szListFile, bool bReserved)
+	if C.SFileCompactArchive(a.h, nil, 0) == 0 {
+		return getLastError(ErrArchiveWrite)
+	}
+	return nil
+}
+
+// Entry in the hash table of an archive
+type Entry struct {
+	Name           string
+	Locale         Locale
+	Platform       uint8 // Unused by Warcraft III
+	Size           int64
+	CompressedSize int64
+}
+
+// hashTable returns the raw (decrypted) hash table entries, nil if unavailable (e.g. archives with only a HET table)
+func (a *Archive) hashTable() []byte {
+	var num C.DWORD
+
+	//bool SFileGetFileInfo(HANDLE hMpqOrFile, SFileInfoClass InfoClass, void * pvFileInfo, DWORD cbFileInfo, LPDWORD pcbLengthNeeded)
+	if C.SFileGetFileInfo(a.h, C.SFileMpqHashTableSize, unsafe.Pointer(&num), C.DWORD(unsafe.Sizeof(num)), nil) == 0 || num == 0 {
+		return nil
+	}
+
+	var res = make([]byte, int(num)*hashEntrySize)
+	if C.SFileGetFileInfo(a.h, C.SFileMpqHashTable, unsafe.Pointer(&res[0]), C.DWORD(len(res)), nil) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// TMPQHash is {Name1, Name2 uint32; Locale uint16; Platform uint8; Reserved uint8; BlockIndex uint32}
+const hashEntrySize = 16
+const hashPlatformOffset = 10
+
+// Entries enumerates the hash table entries in the archive, a subfile is listed once for each locale it is stored in
+// Subfiles not named in the (listfile) are reported as pseudo-names (File00000001.xxx) that can still be opened
+func (a *Archive) Entries() ([]Entry, error) {
+	// Load internal listfile if present, ignore errors
+	//DWORD SFileAddListFile(HANDLE hMpq, const TCHAR * szListFile)
+	C.SFileAddListFile(a.h, nil)
+
+	var mask = C.CString("*")
+	defer C.free(unsafe.Pointer(mask))
+
+	var data C.SFILE_FIND_DATA
+
+	//HANDLE SFileFindFirstFile(HANDLE hMpq, const char * szMask, SFILE_FIND_DATA * lpFindFileData, const TCHAR * szListFile)
+	var h = C.SFileFindFirstFile(a.h, mask, &data, nil)
+	if h == nil {
+		if C.GetLastError() == C.ERROR_NO_MORE_FILES {
+			return nil, nil
+		}
+		return nil, getLastError(ErrFileOpen)
+	}
+	defer C.SFileFindClose(h)
+
+	// Platform is not part of the search results, look it up in the hash table
+	var hashes = a.hashTable()
+
+	var res []Entry
+	for {
+		var e = Entry{
+			Name:           C.GoString(&data.cFileName[0]),
+			Locale:         Locale(data.lcLocale),
+			Size:           int64(data.dwFileSize),
+			CompressedSize: int64(data.dwCompSize),
+		}
+		if off := int(data.dwHashIndex)*hashEntrySize + hashPlatformOffset; off < len(hashes) {
+			e.Platform = hashes[off]
+		}
+		res = append(res, e)
+
+		//bool SFileFindNextFile(HANDLE hFind, SFILE_FIND_DATA * lpFindFileData)
+		if C.SFileFindNextFile(h, &data) == 0 {
+			break
+		}
+	}
+
+	return res, nil
+}
+
+// Files enumerates the names of the subfiles in the archive (see Entries), which allows extracting
+// protected maps without (listfile)
+func (a *Archive) Files() ([]string, error) {
+	entries, err := a.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	var seen = make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		if _, ok := seen[e.Name]; ok {
+			continue
+		}
+		seen[e.Name] = struct{}{}
+		res = append(res, e.Name)
+	}
+
+	return res, nil
+}
+
+// Discover resolves the names of unnamed subfiles from a list of candidate names
+// Returns the pseudo-names of subfiles that remain anonymous, their number is the index in the file table (File00000001.xxx)
+func (a *Archive) Discover(candidates []string) ([]string, error) {
+	tmp, err := ioutil.TempFile("", "listfile")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(EncodeListFile(candidates)); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	var cstr = (*C.TCHAR)(C.CString(tmp.Name()))
+	defer C.free(unsafe.Pointer(cstr))
+
+	//DWORD SFileAddListFile(HANDLE hMpq, const TCHAR * szListFile)
+	if C.SFileAddListFile(a.h, cstr) != C.ERROR_SUCCESS {
+		return nil, getLastError(ErrFileRead)
+	}
+
+	files, err := a.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, f := range files {
+		if pseudoName.MatchString(f) {
+			res = append(res, f)
+		}
+	}
+	return res, nil
+}
+
+// ListFile returns the file names listed in the (listfile) of the archive
+func (a *Archive) ListFile() ([]string, error) {
+	b, err := a.ReadFile(ListFileName)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeListFile(b), nil
+}
+
+// Attributes returns the content of the (attributes) of the archive
+func (a *Archive) Attributes() (*Attributes, error) {
+	b, err := a.ReadFile(AttributesName)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAttributes(b)
+}
+
+// VerifySectors reads a subfile and verifies its sectors against the sector CRC table (if present)
+func (a *Archive) VerifySectors(subFileName string) error {
+	var cstr = C.CString(subFileName)
+	defer C.free(unsafe.Pointer(cstr))
+
+	//DWORD SFileVerifyFile(HANDLE hMpq, const char * szFileName, DWORD dwFlags)
+	var res = C.SFileVerifyFile(a.h, cstr, C.SFILE_VERIFY_SECTOR_CRC)
+	switch {
+	case res&C.VERIFY_OPEN_ERROR != 0:
+		return ErrFileOpen
+	case res&C.VERIFY_READ_ERROR != 0:
+		return ErrFileRead
+	case res&C.VERIFY_FILE_SECTOR_CRC_ERROR != 0:
+		return ErrSectorCRC
+	}
+
+	return nil
+}
+
+// Verify the content of a subfile against the CRC32 and MD5 stored in attr
+func (a *Archive) Verify(subFileName string, attr *Attributes) error {
+	f, err := a.Open(subFileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	idx, err := f.Index()
+	if err != nil {
+		return err
+	}
+	if idx >= len(attr.Files) {
+		return ErrBadFormat
+	}
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	var fa = &attr.Files[idx]
+	if attr.Flags&AttributeCRC32 != 0 && fa.CRC32 != 0 && crc32.ChecksumIEEE(b) != fa.CRC32 {
+		return ErrChecksum
+	}
+	if sum := md5.Sum(b); attr.Flags&AttributeMD5 != 0 && fa.MD5 != [16]byte{} && !bytes.Equal(sum[:], fa.MD5[:]) {
+		return ErrChecksum
+	}
+
+	return nil
+}
+
 // Close an MPQ subfile
 func (f *File) Close() error {
 	if f.h != nil {
@@ -141,6 +599,18 @@ func (f *File) Size() int64 {
 	return int64(size)
 }
 
+// Index of the subfile in the file table of the archive, used to look up its Attributes
+func (f *File) Index() (int, error) {
+	var idx C.DWORD
+
+	//bool SFileGetFileInfo(HANDLE hMpqOrFile, SFileInfoClass InfoClass, void * pvFileInfo, DWORD cbFileInfo, LPDWORD pcbLengthNeeded)
+	if C.SFileGetFileInfo(f.h, C.SFileInfoFileIndex, unsafe.Pointer(&idx), C.DWORD(unsafe.Sizeof(idx)), nil) == 0 {
+		return -1, getLastError(ErrFileInfo)
+	}
+
+	return int(idx), nil
+}
+
 // Read implements the io.Reader interface
 func (f *File) Read(b []byte) (int, error) {
 	var bytesRead C.DWORD
@@ -152,3 +622,28 @@ func (f *File) Read(b []byte) (int, error) {
 
 	return int(bytesRead), nil
 }
+
+// Seek implements the io.Seeker interface
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	var method C.DWORD
+	switch whence {
+	case io.SeekStart:
+		method = C.FILE_BEGIN
+	case io.SeekCurrent:
+		method = C.FILE_CURRENT
+	case io.SeekEnd:
+		method = C.FILE_END
+	default:
+		return 0, os.ErrInvalid
+	}
+
+	var high = C.LONG(offset >> 32)
+
+	//DWORD SFileSetFilePointer(HANDLE hFile, LONG lFilePos, LONG * plFilePosHigh, DWORD dwMoveMethod)
+	var low = C.SFileSetFilePointer(f.h, C.LONG(int32(offset)), &high, method)
+	if low == C.SFILE_INVALID_POS {
+		return 0, getLastError(ErrFileSeek)
+	}
+
+	return int64(uint64(low) | uint64(uint32(high))<<32), nil
+}
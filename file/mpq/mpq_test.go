@@ -5,11 +5,24 @@
 package mpq_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/nielsAD/gowarcraft3/file/mpq"
 )
@@ -46,7 +59,7 @@ func TestMPQ(t *testing.T) {
 		t.Fatal("test.mpq", err)
 	}
 
-	if archive.WeakSigned() || archive.StrongSigned() {
+	if archive.WeakSigned() || archive.StrongSigned() || archive.Signature() != mpq.SignatureNone {
 		t.Fatal("Expected test.mpq not to be signed")
 	}
 
@@ -61,6 +74,23 @@ func TestMPQ(t *testing.T) {
 	} else if strings.TrimSpace(string(content)) != "Hello" {
 		t.Fatalf("hello.txt: '%v' != '%v'\n", string(content), "Hello")
 	}
+
+	// Test seeking
+	if pos, err := hello.Seek(1, io.SeekStart); err != nil || pos != 1 {
+		t.Fatal("hello.txt: seek", pos, err)
+	}
+	if content, err := ioutil.ReadAll(hello); err != nil {
+		t.Fatal("hello.txt", err)
+	} else if strings.TrimSpace(string(content)) != "ello" {
+		t.Fatalf("hello.txt: '%v' != '%v'\n", string(content), "ello")
+	}
+	if pos, err := hello.Seek(-1, io.SeekEnd); err != nil || pos != hello.Size()-1 {
+		t.Fatal("hello.txt: seek", pos, err)
+	}
+	if pos, err := hello.Seek(0, io.SeekCurrent); err != nil || pos != hello.Size()-1 {
+		t.Fatal("hello.txt: seek", pos, err)
+	}
+
 	if err := hello.Close(); err != nil {
 		t.Fatal("hello.txt", err)
 	}
@@ -106,7 +136,741 @@ func TestMPQ(t *testing.T) {
 		t.Fatal("foobar.mpq", err)
 	}
 
+	// Test enumeration
+	if files, err := archive.Files(); err != nil || len(files) < 2 {
+		t.Fatal("Files", files, err)
+	}
+
+	// Test locale
+	if entries, err := archive.Entries(); err != nil || len(entries) < 2 || entries[0].Locale != mpq.LocaleNeutral {
+		t.Fatal("Entries", entries, err)
+	}
+	if f, err := archive.OpenLocale("hello.txt", mpq.LocaleGerman, mpq.LocaleEnglish); err != nil {
+		t.Fatal("OpenLocale", err)
+	} else if loc, err := f.Locale(); err != nil || loc != mpq.LocaleNeutral {
+		t.Fatal("Locale", loc, err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+	if _, err := archive.OpenLocale("foobar.txt", mpq.LocaleGerman); err != os.ErrNotExist {
+		t.Fatal("foobar.txt", err)
+	}
+
+	// Test memory mapped
+	mapped, err := mpq.OpenArchiveFlags("./test.mpq", mpq.OpenMemoryMap)
+	if err != nil {
+		t.Fatal("test.mpq", err)
+	}
+	if content, err := mapped.ReadFile("hello.txt"); err != nil {
+		t.Fatal("hello.txt", err)
+	} else if strings.TrimSpace(string(content)) != "Hello" {
+		t.Fatalf("hello.txt: '%v' != '%v'\n", string(content), "Hello")
+	}
+	if err := mapped.Close(); err != nil {
+		t.Fatal("test.mpq", err)
+	}
+
+	// Test sector CRC validation
+	checked, err := mpq.OpenArchiveFlags("./test.mpq", mpq.OpenCheckCRC)
+	if err != nil {
+		t.Fatal("test.mpq", err)
+	}
+	if content, err := checked.ReadFile("sub\\WORLD.txt"); err != nil {
+		t.Fatal("WORLD.txt", err)
+	} else if strings.TrimSpace(string(content)) != "world" {
+		t.Fatalf("WORLD.txt: '%v' != '%v'\n", string(content), "world")
+	}
+	if err := checked.Close(); err != nil {
+		t.Fatal("test.mpq", err)
+	}
+
+	// Test patch chain
+	if archive.Patched() {
+		t.Fatal("Expected test.mpq not to be patched")
+	}
+	if err := archive.OpenPatch("foobar.mpq", ""); err != os.ErrNotExist {
+		t.Fatal("foobar.mpq", err)
+	}
+	if _, err := mpq.OpenArchiveChain("./test.mpq", "foobar.mpq"); err != os.ErrNotExist {
+		t.Fatal("foobar.mpq", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatal("test.mpq", err)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	var files = []struct {
+		archive string
+		file    string // Empty for the anonymous subfile
+		prefix  string
+		size    int
+		md5     string
+	}{
+		// PKWare DCL imploded sectors (MPQ_FILE_IMPLODE)
+		{"./test_implode.mpq", "implode.txt", "Hello, imploded world!\n", 6900, "075f1dc0f79d3686a3814772c8f1f010"},
+		// WAV file with a zlib compressed header sector, followed by ADPCM (mono) compressed samples
+		{"./test_adpcm.mpq", "sound.wav", "RIFF", 8236, "97ad466ddc9de3e39f5a21dec11fd674"},
+		// Encrypted subfiles with FIX_KEY, the second is missing from (listfile) so its key has to be recovered
+		{"./test_encrypted.mpq", "secret.txt", "Secret line 0000\n", 6800, "3e871dc7ff7d4c73f0907ce776ac4a6e"},
+		{"./test_encrypted.mpq", "", "Hidden line 0000\n", 5100, "fa487a80720f2c89f7cd1196932a8235"},
+		// Format version 4 header (with table MD5s) and classic hash and block tables
+		{"./test_v4.mpq", "hello.txt", "Hello v4\n", 9, "7b60ca3a0408bc575caa553ba0f8ddac"},
+	}
+
+	for _, f := range files {
+		archive, err := mpq.OpenArchive(f.archive)
+		if err != nil {
+			t.Fatal(f.archive, err)
+		}
+
+		var name = f.file
+		if name == "" {
+			files, err := archive.Files()
+			if err != nil {
+				t.Fatal(f.archive, err)
+			}
+			for _, n := range files {
+				if strings.HasPrefix(n, "File") {
+					name = n
+				}
+			}
+		}
+
+		content, err := archive.ReadFile(name)
+		if err != nil {
+			t.Fatal(f.archive, name, err)
+		}
+		if len(content) != f.size || !bytes.HasPrefix(content, []byte(f.prefix)) {
+			t.Fatalf("%v %v: content mismatch (%v bytes)\n", f.archive, name, len(content))
+		}
+		if sum := md5.Sum(content); hex.EncodeToString(sum[:]) != f.md5 {
+			t.Fatalf("%v %v: md5 %x != %v\n", f.archive, name, sum, f.md5)
+		}
+
+		if err := archive.Close(); err != nil {
+			t.Fatal(f.archive, err)
+		}
+	}
+}
+
+func TestListFile(t *testing.T) {
+	var names = mpq.DecodeListFile([]byte("war3map.j\r\nsub\\world.txt\n;war3map.w3i; \r\n"))
+	if !reflect.DeepEqual(names, []string{"war3map.j", "sub\\world.txt", "war3map.w3i"}) {
+		t.Fatal("DecodeListFile", names)
+	}
+
+	if res := mpq.DecodeListFile(mpq.EncodeListFile(names)); !reflect.DeepEqual(res, names) {
+		t.Fatal("EncodeListFile", res)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	var flags = []mpq.AttributeFlags{
+		0,
+		mpq.AttributeCRC32,
+		mpq.AttributeCRC32 | mpq.AttributeFileTime | mpq.AttributeMD5,
+		mpq.AttributeCRC32 | mpq.AttributeFileTime | mpq.AttributeMD5 | mpq.AttributePatchBit,
+	}
+
+	for _, f := range flags {
+		for n := 0; n < 20; n++ {
+			var attr = mpq.Attributes{
+				Version: mpq.AttributesVersion,
+				Flags:   f,
+				Files:   make([]mpq.FileAttributes, n),
+			}
+			for i := range attr.Files {
+				if f&mpq.AttributeCRC32 != 0 {
+					attr.Files[i].CRC32 = uint32(i + 1)
+				}
+				if f&mpq.AttributeFileTime != 0 {
+					attr.Files[i].Time = time.Date(2002, 7, 3, 0, 0, i, 0, time.UTC)
+				}
+				if f&mpq.AttributeMD5 != 0 {
+					attr.Files[i].MD5[i%16] = byte(i)
+				}
+				if f&mpq.AttributePatchBit != 0 {
+					attr.Files[i].Patch = i%3 == 0
+				}
+			}
+
+			res, err := mpq.DecodeAttributes(attr.Encode())
+			if f == 0 {
+				// Number of files cannot be derived without attributes
+				if err != nil || len(res.Files) != 0 {
+					t.Fatal("DecodeAttributes", f, n, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal("DecodeAttributes", f, n, err)
+			}
+			if len(res.Files) != n {
+				t.Fatalf("DecodeAttributes %v: %v != %v", f, len(res.Files), n)
+			}
+			for i := range res.Files {
+				var a, b = res.Files[i], attr.Files[i]
+				if a.CRC32 != b.CRC32 || a.MD5 != b.MD5 || a.Patch != b.Patch || !a.Time.Equal(b.Time) {
+					t.Fatalf("DecodeAttributes %v: %v != %v", f, a, b)
+				}
+			}
+		}
+	}
+
+	if _, err := mpq.DecodeAttributes([]byte{100, 0, 0, 0, 1, 0, 0, 0, 1, 2, 3}); err != mpq.ErrBadFormat {
+		t.Fatal("ErrBadFormat expected", err)
+	}
+	if _, err := mpq.DecodeAttributes([]byte{99, 0, 0, 0, 1, 0, 0, 0}); err != mpq.ErrBadFormat {
+		t.Fatal("ErrBadFormat expected", err)
+	}
+}
+
+func TestExtractPath(t *testing.T) {
+	if p, err := mpq.ExtractPath("out", "sub\\world.txt"); err != nil || p != filepath.Join("out", "sub", "world.txt") {
+		t.Fatal("ExtractPath", p, err)
+	}
+	for _, n := range []string{"", "..\\world.txt", "sub\\..\\..\\world.txt", "/world.txt"} {
+		if _, err := mpq.ExtractPath("out", n); err != mpq.ErrFileName {
+			t.Fatal("ErrFileName expected", n, err)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var names = []string{"hello.txt", "sub\\WORLD.txt", "foobar.txt"}
+	var seen = map[string]error{}
+
+	var e = mpq.Extractor{
+		Open:    func() (*mpq.Archive, error) { return mpq.OpenArchive("./test.mpq") },
+		Workers: 2,
+		Progress: func(name string, done int, total int, err error) {
+			if total != len(names) || done != len(seen)+1 {
+				t.Error("Progress", done, total)
+			}
+			seen[name] = err
+		},
+	}
+
+	if err := e.Extract(dir, names); err != os.ErrNotExist {
+		t.Fatal("foobar.txt", err)
+	}
+	if len(seen) != len(names) || seen["hello.txt"] != nil || seen["sub\\WORLD.txt"] != nil {
+		t.Fatal("Progress", seen)
+	}
+
+	if content, err := ioutil.ReadFile(filepath.Join(dir, "hello.txt")); err != nil {
+		t.Fatal("hello.txt", err)
+	} else if strings.TrimSpace(string(content)) != "Hello" {
+		t.Fatalf("hello.txt: '%v' != '%v'\n", string(content), "Hello")
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(dir, "sub", "WORLD.txt")); err != nil {
+		t.Fatal("WORLD.txt", err)
+	} else if strings.TrimSpace(string(content)) != "world" {
+		t.Fatalf("WORLD.txt: '%v' != '%v'\n", string(content), "world")
+	}
+}
+
+func copyArchive(t *testing.T) string {
+	raw, err := ioutil.ReadFile("./test.mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmp, err := ioutil.TempFile("", "mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tmp.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := tmp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return tmp.Name()
+}
+
+func editArchive(t *testing.T, fileName string) {
+	archive, err := mpq.OpenArchiveFlags(fileName, mpq.OpenWritable)
+	if err != nil {
+		t.Fatal("OpenWritable", err)
+	}
+
+	if err := archive.WriteFile("hello.txt", []byte("Bye")); err != nil {
+		t.Fatal("WriteFile hello.txt", err)
+	}
+	if err := archive.WriteFile("sub\\new.txt", []byte("new")); err != nil {
+		t.Fatal("WriteFile new.txt", err)
+	}
+	if err := archive.Remove("sub\\WORLD.txt"); err != nil {
+		t.Fatal("Remove WORLD.txt", err)
+	}
+	if err := archive.Compact(); err != nil {
+		t.Fatal("Compact", err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var tmp = copyArchive(t)
+	defer os.Remove(tmp)
+
+	editArchive(t, tmp)
+
+	archive, err := mpq.OpenArchive(tmp)
+	if err != nil {
+		t.Fatal("OpenArchive", err)
+	}
+
+	if content, err := archive.ReadFile("hello.txt"); err != nil || string(content) != "Bye" {
+		t.Fatal("hello.txt", string(content), err)
+	}
+	if content, err := archive.ReadFile("sub\\new.txt"); err != nil || string(content) != "new" {
+		t.Fatal("new.txt", string(content), err)
+	}
+	if _, err := archive.ReadFile("sub\\WORLD.txt"); err != os.ErrNotExist {
+		t.Fatal("WORLD.txt", err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+
+	// Localized copy
+	archive, err = mpq.OpenArchiveFlags(tmp, mpq.OpenWritable)
+	if err != nil {
+		t.Fatal("OpenWritable", err)
+	}
+	defer archive.Close()
+
+	if err := archive.WriteFileLocale("hello.txt", []byte("Hallo"), mpq.LocaleGerman); err != nil {
+		t.Fatal("WriteFileLocale", err)
+	}
+	if err := archive.Flush(); err != nil {
+		t.Fatal("Flush", err)
+	}
+
+	var locales []mpq.Locale
+	if entries, err := archive.Entries(); err != nil {
+		t.Fatal("Entries", err)
+	} else {
+		for _, e := range entries {
+			if e.Platform != 0 {
+				t.Fatal("Platform", e)
+			}
+			if e.Name == "hello.txt" {
+				locales = append(locales, e.Locale)
+			}
+		}
+	}
+	if len(locales) != 2 {
+		t.Fatal("Locales", locales)
+	}
+
+	if f, err := archive.OpenLocale("hello.txt", mpq.LocaleGerman); err != nil {
+		t.Fatal("OpenLocale", err)
+	} else if content, err := ioutil.ReadAll(f); err != nil || string(content) != "Hallo" {
+		t.Fatal("hello.txt German", string(content), err)
+	} else {
+		f.Close()
+	}
+	if content, err := archive.ReadFile("hello.txt"); err != nil || string(content) != "Bye" {
+		t.Fatal("hello.txt", string(content), err)
+	}
+}
+
+func TestWriteCompressed(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+
+	// Text and a run of zeroes, spanning multiple sectors
+	var content = []byte(strings.Repeat("Hello compressed world!\n", 300) + strings.Repeat("\x00", 8192) + strings.Repeat("Bye\n", 100))
+
+	// ADPCM is lossy, see TestFormats
+	var methods = map[string]mpq.Compression{
+		"none.txt":         0,
+		"huffman.txt":      mpq.CompressHuffman,
+		"zlib.txt":         mpq.CompressZlib,
+		"pkware.txt":       mpq.CompressPKWare,
+		"bzip2.txt":        mpq.CompressBZip2,
+		"sparse.txt":       mpq.CompressSparse,
+		"lzma.txt":         mpq.CompressLZMA,
+		"sparse_zlib.txt":  mpq.CompressSparse | mpq.CompressZlib,
+		"sparse_bzip2.txt": mpq.CompressSparse | mpq.CompressBZip2,
+	}
+
+	archive, err := mpq.CreateArchive(tmp.Name(), 16)
+	if err != nil {
+		t.Fatal("CreateArchive", err)
+	}
+	for name, c := range methods {
+		if err := archive.WriteFileCompressed(name, content, c); err != nil {
+			t.Fatal("WriteFileCompressed", name, err)
+		}
+	}
 	if err := archive.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+
+	archive, err = mpq.OpenArchive(tmp.Name())
+	if err != nil {
+		t.Fatal("OpenArchive", err)
+	}
+	defer archive.Close()
+
+	entries, err := archive.Entries()
+	if err != nil {
+		t.Fatal("Entries", err)
+	}
+	var compressed = map[string]int64{}
+	for _, e := range entries {
+		compressed[e.Name] = e.CompressedSize
+	}
+
+	for name, c := range methods {
+		if b, err := archive.ReadFile(name); err != nil || !bytes.Equal(b, content) {
+			t.Fatal("ReadFile", name, len(b), err)
+		}
+		if size, ok := compressed[name]; !ok || (c != 0) != (size < int64(len(content))) {
+			t.Fatal("CompressedSize", name, size)
+		}
+	}
+}
+
+func TestRepair(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+
+	archive, err := mpq.OpenArchive("./test.mpq")
+	if err != nil {
 		t.Fatal("test.mpq", err)
 	}
+	if res, err := archive.VerifyAll(); err != nil {
+		t.Fatal("VerifyAll", err)
+	} else {
+		for _, r := range res {
+			if r.Err != nil {
+				t.Fatal("VerifyAll", r.Name, r.Err)
+			}
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal("test.mpq", err)
+	}
+
+	if dropped, err := mpq.Repair("./test.mpq", tmp.Name()); err != nil || len(dropped) != 0 {
+		t.Fatal("Repair", dropped, err)
+	}
+
+	repaired, err := mpq.OpenArchive(tmp.Name())
+	if err != nil {
+		t.Fatal("OpenArchive", err)
+	}
+	defer repaired.Close()
+
+	if content, err := repaired.ReadFile("sub\\WORLD.txt"); err != nil || strings.TrimSpace(string(content)) != "world" {
+		t.Fatal("WORLD.txt", string(content), err)
+	}
+	if _, err := repaired.Attributes(); err != nil {
+		t.Fatal("Attributes", err)
+	}
+}
+
+func TestHTTPReaderAt(t *testing.T) {
+	var content = strings.Repeat("0123456789", 100)
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "test.mpq", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	var r = mpq.HTTPReaderAt{URL: srv.URL}
+	if size, err := r.Size(); err != nil || size != int64(len(content)) {
+		t.Fatal("Size", size, err)
+	}
+
+	var b = make([]byte, 15)
+	if n, err := r.ReadAt(b, 95); err != nil || n != len(b) || string(b) != content[95:110] {
+		t.Fatal("ReadAt", n, err, string(b))
+	}
+	if n, err := r.ReadAt(b, int64(len(content))-5); err != io.EOF || n != 5 || string(b[:n]) != content[len(content)-5:] {
+		t.Fatal("ReadAt EOF", n, err)
+	}
+	if _, err := r.ReadAt(b, int64(len(content))); err != io.EOF {
+		t.Fatal("ReadAt EOF", err)
+	}
+
+	var full = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer full.Close()
+
+	var f = mpq.HTTPReaderAt{URL: full.URL}
+	if _, err := f.ReadAt(b, 0); err != mpq.ErrHTTPRange {
+		t.Fatal("ErrHTTPRange expected", err)
+	}
+}
+
+func TestOpenReader(t *testing.T) {
+	raw, err := os.Open("./test.mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	stat, err := raw.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r = countingReaderAt{ReaderAt: raw}
+	archive, err := mpq.OpenReader(&r, stat.Size(), 0)
+	if err != nil {
+		t.Fatal("OpenReader", err)
+	}
+	if content, err := archive.ReadFile("hello.txt"); err != nil || strings.TrimSpace(string(content)) != "Hello" {
+		t.Fatal("hello.txt", string(content), err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+
+	// Copied with a large buffer, EOF is reported by a second call
+	if r.calls > 2 {
+		t.Fatal("Expected a single read, got", r.calls)
+	}
+}
+
+type countingReaderAt struct {
+	io.ReaderAt
+	calls int
+}
+
+func (r *countingReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	r.calls++
+	return r.ReaderAt.ReadAt(b, off)
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *int64
+}
+
+func (w countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	*w.n += int64(n)
+	return n, err
+}
+
+func TestOpenURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("StormLib reads URLs on demand on Windows")
+	}
+
+	raw, err := ioutil.ReadFile("./test.mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mut sync.Mutex
+	var requests int
+	var served int64
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		requests++
+		http.ServeContent(countingWriter{w, &served}, r, "test.mpq", time.Time{}, strings.NewReader(string(raw)))
+	}))
+	defer srv.Close()
+
+	archive, err := mpq.OpenURL(srv.URL+"/test.mpq", 0)
+	if err != nil {
+		t.Fatal("OpenURL", err)
+	}
+	if content, err := archive.ReadFile("hello.txt"); err != nil || strings.TrimSpace(string(content)) != "Hello" {
+		t.Fatal("hello.txt", string(content), err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+
+	mut.Lock()
+	defer mut.Unlock()
+	if requests != 1 || served != int64(len(raw)) {
+		t.Fatalf("Expected a single download, got %v requests for %v bytes\n", requests, served)
+	}
+
+	var missing = httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	if _, err := mpq.OpenURL(missing.URL, 0); err != mpq.ErrHTTPStatus {
+		t.Fatal("ErrHTTPStatus expected", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	archive, err := mpq.OpenArchive("./test.mpq")
+	if err != nil {
+		t.Fatal("test.mpq", err)
+	}
+	defer archive.Close()
+
+	var buf bytes.Buffer
+	if err := archive.ExportZip(&buf); err != nil {
+		t.Fatal("ExportZip", err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal("zip", err)
+	}
+
+	var found = false
+	for _, f := range z.File {
+		if f.Name != "sub/WORLD.txt" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal("zip", err)
+		}
+		if content, err := ioutil.ReadAll(r); err != nil || strings.TrimSpace(string(content)) != "world" {
+			t.Fatal("zip WORLD.txt", string(content), err)
+		}
+		found = true
+	}
+	if !found {
+		t.Fatal("zip: WORLD.txt not found")
+	}
+
+	buf.Reset()
+	if err := archive.ExportTar(&buf); err != nil {
+		t.Fatal("ExportTar", err)
+	}
+
+	found = false
+	for r := tar.NewReader(&buf); ; {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("tar", err)
+		}
+		if hdr.Name != "hello.txt" {
+			continue
+		}
+		if content, err := ioutil.ReadAll(r); err != nil || strings.TrimSpace(string(content)) != "Hello" {
+			t.Fatal("tar hello.txt", string(content), err)
+		}
+		found = true
+	}
+	if !found {
+		t.Fatal("tar: hello.txt not found")
+	}
+}
+
+func entryNames(entries []mpq.Entry) []string {
+	var res []string
+	for _, e := range entries {
+		res = append(res, e.Name)
+	}
+	return res
+}
+
+func TestDiff(t *testing.T) {
+	var tmp = copyArchive(t)
+	defer os.Remove(tmp)
+
+	editArchive(t, tmp)
+
+	// Localized copy of an unmodified subfile
+	edit, err := mpq.OpenArchiveFlags(tmp, mpq.OpenWritable)
+	if err != nil {
+		t.Fatal("OpenWritable", err)
+	}
+	if err := edit.WriteFileLocale("sub\\new.txt", []byte("neu"), mpq.LocaleGerman); err != nil {
+		t.Fatal("WriteFileLocale", err)
+	}
+	if err := edit.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+
+	a, err := mpq.OpenArchive("./test.mpq")
+	if err != nil {
+		t.Fatal("test.mpq", err)
+	}
+	defer a.Close()
+
+	b, err := mpq.OpenArchive(tmp)
+	if err != nil {
+		t.Fatal("OpenArchive", err)
+	}
+	defer b.Close()
+
+	diff, err := mpq.Diff(a, b)
+	if err != nil {
+		t.Fatal("Diff", err)
+	}
+	if len(diff.Added) != 2 || diff.Added[0].Name != "sub\\new.txt" || diff.Added[0].Locale != mpq.LocaleNeutral ||
+		diff.Added[1].Name != "sub\\new.txt" || diff.Added[1].Locale != mpq.LocaleGerman {
+		t.Fatal("Added", diff.Added)
+	}
+	if !reflect.DeepEqual(entryNames(diff.Removed), []string{"sub\\WORLD.txt"}) {
+		t.Fatal("Removed", diff.Removed)
+	}
+
+	// Special files are ignored
+	if !reflect.DeepEqual(entryNames(diff.Modified), []string{"hello.txt"}) {
+		t.Fatal("Modified", diff.Modified)
+	}
+	if len(diff.UnnamedAdded)+len(diff.UnnamedRemoved) != 0 {
+		t.Fatal("Unnamed", diff.UnnamedAdded, diff.UnnamedRemoved)
+	}
+
+	if same, err := mpq.Diff(a, a); err != nil || len(same.Added)+len(same.Removed)+len(same.Modified) != 0 {
+		t.Fatal("Diff", same, err)
+	}
+
+	// Subfiles without name in a different order, matched by content instead of pseudo-name
+	ua, err := mpq.OpenArchive("./test_unnamed_a.mpq")
+	if err != nil {
+		t.Fatal("test_unnamed_a.mpq", err)
+	}
+	defer ua.Close()
+
+	ub, err := mpq.OpenArchive("./test_unnamed_b.mpq")
+	if err != nil {
+		t.Fatal("test_unnamed_b.mpq", err)
+	}
+	defer ub.Close()
+
+	unnamed, err := mpq.Diff(ua, ub)
+	if err != nil {
+		t.Fatal("Diff", err)
+	}
+	if len(unnamed.Added)+len(unnamed.Removed)+len(unnamed.Modified)+len(unnamed.UnnamedRemoved) != 0 || len(unnamed.UnnamedAdded) != 1 {
+		t.Fatal("Diff unnamed", unnamed)
+	}
+	if content, err := ub.ReadFile(unnamed.UnnamedAdded[0].Name); err != nil || string(content) != "third\n" {
+		t.Fatal("Unnamed content", string(content), err)
+	}
 }
@@ -0,0 +1,140 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package mpq
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// OpenReader opens the first size bytes of r as MPQ archive
+// StormLib requires a file, so the content of r is copied in full to a temporary file that is removed on Close
+func OpenReader(r io.ReaderAt, size int64, flags OpenFlags) (*Archive, error) {
+	return openCopy(io.NewSectionReader(r, 0, size), flags)
+}
+
+// copyBufferSize limits the number of ReadAt calls when copying from an io.ReaderAt
+const copyBufferSize = 1 << 20
+
+func openCopy(r io.Reader, flags OpenFlags) (*Archive, error) {
+	tmp, err := ioutil.TempFile("", "mpq")
+	if err != nil {
+		return nil, err
+	}
+
+	// Hide io.ReaderFrom so that the buffer size is honored
+	var name = tmp.Name()
+	if _, err := io.CopyBuffer(struct{ io.Writer }{tmp}, r, make([]byte, copyBufferSize)); err != nil {
+		tmp.Close()
+		os.Remove(name)
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(name)
+		return nil, err
+	}
+
+	a, err := OpenArchiveFlags(name, flags&^OpenWritable)
+	if err != nil {
+		os.Remove(name)
+		return nil, err
+	}
+
+	a.tmp = name
+	return a, nil
+}
+
+// HTTPReaderAt implements io.ReaderAt for a remote file using HTTP range requests
+type HTTPReaderAt struct {
+	URL    string
+	Client *http.Client // nil for http.DefaultClient
+}
+
+func (h *HTTPReaderAt) client() *http.Client {
+	if h.Client != nil {
+		return h.Client
+	}
+	return http.DefaultClient
+}
+
+// Size of the remote file in bytes, using a HEAD request
+func (h *HTTPReaderAt) Size() (int64, error) {
+	resp, err := h.client().Head(h.URL)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, ErrHTTPStatus
+	}
+	if resp.ContentLength < 0 {
+		return 0, ErrHTTPRange
+	}
+	return resp.ContentLength, nil
+}
+
+// ReadAt implements the io.ReaderAt interface
+func (h *HTTPReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, h.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(off, 10)+"-"+strconv.FormatInt(off+int64(len(b))-1, 10))
+
+	resp, err := h.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), "bytes "+strconv.FormatInt(off, 10)+"-") {
+			return 0, ErrHTTPRange
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	case http.StatusOK:
+		return 0, ErrHTTPRange
+	default:
+		return 0, ErrHTTPStatus
+	}
+
+	n, err := io.ReadFull(resp.Body, b)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// OpenURL opens the MPQ archive at url
+// StormLib reads the archive on demand on Windows (see OpenHTTP), on other platforms the
+// whole archive is downloaded with a single request to a temporary file that is removed on Close
+func OpenURL(url string, flags OpenFlags) (*Archive, error) {
+	if runtime.GOOS == "windows" {
+		return OpenArchiveFlags(url, (flags&^(OpenWritable|OpenMemoryMap))|OpenHTTP)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, ErrHTTPStatus
+	}
+	return openCopy(resp.Body, flags)
+}
@@ -0,0 +1,206 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package mpq
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Names of special files
+const (
+	ListFileName   = "(listfile)"
+	AttributesName = "(attributes)"
+	SignatureName  = "(signature)"
+)
+
+// AttributesVersion of the (attributes) format
+const AttributesVersion = 100
+
+// AttributeFlags enum
+type AttributeFlags uint32
+
+// Attributes stored in (attributes)
+const (
+	AttributeCRC32    AttributeFlags = 0x01
+	AttributeFileTime AttributeFlags = 0x02
+	AttributeMD5      AttributeFlags = 0x04
+	AttributePatchBit AttributeFlags = 0x08
+)
+
+// Windows FILETIME epoch (1601-01-01) in 100ns intervals before the Unix epoch
+const fileTimeEpoch = 116444736000000000
+
+// FileAttributes of a single subfile, as stored in (attributes)
+type FileAttributes struct {
+	CRC32 uint32
+	Time  time.Time
+	MD5   [16]byte
+	Patch bool
+}
+
+// Attributes stored in the (attributes) special file, Files is indexed by file table index (see File.Index)
+type Attributes struct {
+	Version uint32
+	Flags   AttributeFlags
+	Files   []FileAttributes
+}
+
+// DecodeListFile returns the file names listed in the content of a (listfile)
+func DecodeListFile(b []byte) []string {
+	var res []string
+	for _, n := range strings.FieldsFunc(string(b), func(r rune) bool {
+		return r == '\r' || r == '\n' || r == ';'
+	}) {
+		if n = strings.TrimSpace(n); n != "" {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// EncodeListFile returns the content of a (listfile) listing names
+func EncodeListFile(names []string) []byte {
+	var b strings.Builder
+	for _, n := range names {
+		b.WriteString(n)
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+func (f AttributeFlags) entrySize() int {
+	var size = 0
+	if f&AttributeCRC32 != 0 {
+		size += 4
+	}
+	if f&AttributeFileTime != 0 {
+		size += 8
+	}
+	if f&AttributeMD5 != 0 {
+		size += 16
+	}
+	return size
+}
+
+// size of the attributes of n files in bytes
+func (f AttributeFlags) size(n int) int {
+	var size = 8 + n*f.entrySize()
+	if f&AttributePatchBit != 0 {
+		size += (n + 7) / 8
+	}
+	return size
+}
+
+func timeToFileTime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano()/100 + fileTimeEpoch)
+}
+
+func fileTimeToTime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, (int64(ft)-fileTimeEpoch)*100)
+}
+
+// DecodeAttributes decodes the content of an (attributes) special file
+func DecodeAttributes(b []byte) (*Attributes, error) {
+	var buf = protocol.Buffer{Bytes: b}
+	if buf.Size() < 8 {
+		return nil, ErrBadFormat
+	}
+
+	var res = Attributes{
+		Version: buf.ReadUInt32(),
+		Flags:   AttributeFlags(buf.ReadUInt32()),
+	}
+	if res.Version != AttributesVersion {
+		return nil, ErrBadFormat
+	}
+
+	// Number of files is not stored, derive it from the size
+	var n = -1
+	var bits = 0
+	if res.Flags&AttributePatchBit != 0 {
+		bits = 1
+	}
+	if per := 8*res.Flags.entrySize() + bits; per > 0 {
+		for i := buf.Size()*8/per + 1; i >= 0 && res.Flags.size(i) >= len(b); i-- {
+			if res.Flags.size(i) == len(b) {
+				n = i
+				break
+			}
+		}
+	} else if buf.Size() == 0 {
+		n = 0
+	}
+	if n < 0 {
+		return nil, ErrBadFormat
+	}
+
+	res.Files = make([]FileAttributes, n)
+	if res.Flags&AttributeCRC32 != 0 {
+		for i := range res.Files {
+			res.Files[i].CRC32 = buf.ReadUInt32()
+		}
+	}
+	if res.Flags&AttributeFileTime != 0 {
+		for i := range res.Files {
+			res.Files[i].Time = fileTimeToTime(buf.ReadUInt64())
+		}
+	}
+	if res.Flags&AttributeMD5 != 0 {
+		for i := range res.Files {
+			copy(res.Files[i].MD5[:], buf.ReadBlob(16))
+		}
+	}
+	if res.Flags&AttributePatchBit != 0 {
+		var bits = buf.ReadBlob((n + 7) / 8)
+		for i := range res.Files {
+			res.Files[i].Patch = bits[i/8]&(1<<(uint(i)%8)) != 0
+		}
+	}
+
+	return &res, nil
+}
+
+// Encode the content of an (attributes) special file
+func (a *Attributes) Encode() []byte {
+	var buf = protocol.Buffer{Bytes: make([]byte, 0, a.Flags.size(len(a.Files)))}
+	buf.WriteUInt32(a.Version)
+	buf.WriteUInt32(uint32(a.Flags))
+
+	if a.Flags&AttributeCRC32 != 0 {
+		for i := range a.Files {
+			buf.WriteUInt32(a.Files[i].CRC32)
+		}
+	}
+	if a.Flags&AttributeFileTime != 0 {
+		for i := range a.Files {
+			buf.WriteUInt64(timeToFileTime(a.Files[i].Time))
+		}
+	}
+	if a.Flags&AttributeMD5 != 0 {
+		for i := range a.Files {
+			buf.WriteBlob(a.Files[i].MD5[:])
+		}
+	}
+	if a.Flags&AttributePatchBit != 0 {
+		var bits = make([]byte, (len(a.Files)+7)/8)
+		for i := range a.Files {
+			if a.Files[i].Patch {
+				bits[i/8] |= 1 << (uint(i) % 8)
+			}
+		}
+		buf.WriteBlob(bits)
+	}
+
+	return buf.Bytes
+}
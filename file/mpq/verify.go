@@ -0,0 +1,105 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package mpq
+
+import (
+	"os"
+	"regexp"
+)
+
+// VerifyResult of a single subfile
+type VerifyResult struct {
+	Name string
+	Err  error // nil if the subfile passed verification
+}
+
+var pseudoName = regexp.MustCompile(`^File[0-9]{8}\.`)
+
+func special(subFileName string) bool {
+	switch subFileName {
+	case ListFileName, AttributesName, "(signature)":
+		return true
+	default:
+		return false
+	}
+}
+
+// VerifyAll checks the sector CRCs of every subfile (see Files), and their CRC32 and MD5 if the archive has (attributes)
+// Subfiles without a known name cannot be rebuilt and are reported with ErrFileName
+func (a *Archive) VerifyAll() ([]VerifyResult, error) {
+	files, err := a.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	attr, err := a.Attributes()
+	if err == os.ErrNotExist {
+		attr = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res = make([]VerifyResult, 0, len(files))
+	for _, name := range files {
+		if special(name) {
+			continue
+		}
+
+		var r = VerifyResult{Name: name}
+		switch {
+		case pseudoName.MatchString(name):
+			r.Err = ErrFileName
+		default:
+			if r.Err = a.VerifySectors(name); r.Err == nil && attr != nil {
+				r.Err = a.Verify(name, attr)
+			}
+		}
+
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// Repair rebuilds archive fileName into a new archive dst with fresh tables, (listfile) and (attributes)
+// Only subfiles that pass verification are copied, the returned results list the subfiles that were dropped
+func Repair(fileName string, dst string) ([]VerifyResult, error) {
+	src, err := OpenArchiveFlags(fileName, OpenForceV1)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	files, err := src.VerifyAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reserve room for special files
+	out, err := CreateArchive(dst, len(files)+3)
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []VerifyResult
+	for _, f := range files {
+		if f.Err != nil {
+			dropped = append(dropped, f)
+			continue
+		}
+
+		b, err := src.ReadFile(f.Name)
+		if err != nil {
+			dropped = append(dropped, VerifyResult{Name: f.Name, Err: err})
+			continue
+		}
+		if err := out.WriteFile(f.Name, b); err != nil {
+			out.Close()
+			return nil, err
+		}
+	}
+
+	return dropped, out.Close()
+}
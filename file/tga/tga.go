@@ -0,0 +1,294 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package tga is a Truevision TGA image format decoder.
+package tga
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Errors
+var (
+	ErrBadFormat          = errors.New("tga: Invalid file format")
+	ErrInvalidCompression = errors.New("tga: Compression not supported")
+)
+
+// ImageType enum
+type ImageType uint8
+
+// Image types
+const (
+	TypeNone           ImageType = 0
+	TypeColorMapped    ImageType = 1
+	TypeTrueColor      ImageType = 2
+	TypeGrayscale      ImageType = 3
+	TypeColorMappedRLE ImageType = 9
+	TypeTrueColorRLE   ImageType = 10
+	TypeGrayscaleRLE   ImageType = 11
+)
+
+// Header of a TGA file
+type Header struct {
+	IDLength     uint8
+	ColorMapType uint8
+	ImageType    ImageType
+	MapOrigin    uint16
+	MapLength    uint16
+	MapDepth     uint8
+	OriginX      uint16
+	OriginY      uint16
+	Width        uint16
+	Height       uint16
+	Depth        uint8
+	Descriptor   uint8
+}
+
+const headerSize = 18
+
+const (
+	descriptorAlphaBits   = 0x0F
+	descriptorRightToLeft = 0x10
+	descriptorTopToBottom = 0x20
+)
+
+// Signature in TGA 2.0 footer
+const signature = "TRUEVISION-XFILE.\x00"
+
+const (
+	attributeAlpha         = 3
+	attributePremultiplied = 4
+)
+
+// attributes reads the alpha attribute type from the TGA 2.0 extension area, nil if not available
+func attributes(data []byte) (*bool, bool) {
+	if len(data) < headerSize+26 || string(data[len(data)-len(signature):]) != signature {
+		return nil, false
+	}
+
+	var footer = protocol.Buffer{Bytes: data[len(data)-26:]}
+	var ext = int(footer.ReadUInt32())
+	if ext == 0 || ext+495 > len(data)-26 {
+		return nil, false
+	}
+
+	var t = data[ext+494]
+	var alpha = t == attributeAlpha || t == attributePremultiplied
+	return &alpha, t == attributePremultiplied
+}
+
+func readHeader(b *protocol.Buffer) (*Header, error) {
+	if b.Size() < headerSize {
+		return nil, ErrBadFormat
+	}
+
+	var h = Header{
+		IDLength:     b.ReadUInt8(),
+		ColorMapType: b.ReadUInt8(),
+		ImageType:    ImageType(b.ReadUInt8()),
+		MapOrigin:    b.ReadUInt16(),
+		MapLength:    b.ReadUInt16(),
+		MapDepth:     b.ReadUInt8(),
+		OriginX:      b.ReadUInt16(),
+		OriginY:      b.ReadUInt16(),
+		Width:        b.ReadUInt16(),
+		Height:       b.ReadUInt16(),
+		Depth:        b.ReadUInt8(),
+		Descriptor:   b.ReadUInt8(),
+	}
+
+	switch h.ImageType {
+	case TypeColorMapped, TypeColorMappedRLE:
+		if h.ColorMapType != 1 || h.Depth != 8 {
+			return nil, ErrBadFormat
+		}
+		switch h.MapDepth {
+		case 15, 16, 24, 32:
+		default:
+			return nil, ErrBadFormat
+		}
+	case TypeTrueColor, TypeTrueColorRLE:
+		switch h.Depth {
+		case 15, 16, 24, 32:
+		default:
+			return nil, ErrBadFormat
+		}
+	case TypeGrayscale, TypeGrayscaleRLE:
+		if h.Depth != 8 && h.Depth != 16 {
+			return nil, ErrBadFormat
+		}
+	default:
+		return nil, ErrInvalidCompression
+	}
+
+	return &h, nil
+}
+
+// DecodeConfig returns the color model and dimensions of a TGA image without decoding the entire image
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	var b protocol.Buffer
+	switch _, err := b.ReadSizeFrom(r, headerSize); err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		return image.Config{}, ErrBadFormat
+	default:
+		return image.Config{}, err
+	}
+
+	h, err := readHeader(&b)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      int(h.Width),
+		Height:     int(h.Height),
+	}, nil
+}
+
+// readColor converts a pixel of depth bits (little-endian BGR(A)) to NRGBA
+func readColor(p []byte, depth uint8) color.NRGBA {
+	switch depth {
+	case 8:
+		return color.NRGBA{p[0], p[0], p[0], 0xFF}
+	case 15, 16:
+		var v = uint16(p[0]) | uint16(p[1])<<8
+		var r = uint8(v >> 10 & 0x1F)
+		var g = uint8(v >> 5 & 0x1F)
+		var b = uint8(v & 0x1F)
+		var a = uint8(v>>15) * 0xFF
+		return color.NRGBA{r<<3 | r>>2, g<<3 | g>>2, b<<3 | b>>2, a}
+	case 24:
+		return color.NRGBA{p[2], p[1], p[0], 0xFF}
+	default:
+		return color.NRGBA{p[2], p[1], p[0], p[3]}
+	}
+}
+
+// Decode a TGA image (color-mapped, true-color, or grayscale; uncompressed or RLE)
+func Decode(r io.Reader) (image.Image, error) {
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, r); err != nil {
+		return nil, err
+	}
+
+	var alpha, premultiplied = attributes(b.Bytes)
+
+	h, err := readHeader(&b)
+	if err != nil {
+		return nil, err
+	}
+	if alpha == nil {
+		// Alpha bits are often omitted for 32-bit images
+		var a = h.Descriptor&descriptorAlphaBits != 0 || h.Depth == 32 ||
+			(h.ImageType&0x07 == TypeGrayscale && h.Depth == 16) ||
+			(h.ImageType&0x07 == TypeColorMapped && h.MapDepth == 32)
+		alpha = &a
+	}
+
+	if b.Size() < int(h.IDLength) {
+		return nil, ErrBadFormat
+	}
+	b.Skip(int(h.IDLength))
+
+	var palette []color.NRGBA
+	if h.ColorMapType == 1 {
+		var size = (int(h.MapDepth) + 7) / 8
+		if b.Size() < int(h.MapLength)*size {
+			return nil, ErrBadFormat
+		}
+
+		palette = make([]color.NRGBA, int(h.MapOrigin)+int(h.MapLength))
+		for i := int(h.MapOrigin); i < len(palette); i++ {
+			palette[i] = readColor(b.ReadBlob(size), h.MapDepth)
+		}
+	}
+
+	var width = int(h.Width)
+	var height = int(h.Height)
+	var depth = int(h.Depth+7) / 8
+
+	var num = width * height
+	var pix = make([]byte, 0, num*depth)
+
+	switch h.ImageType {
+	case TypeColorMapped, TypeTrueColor, TypeGrayscale:
+		if b.Size() < num*depth {
+			return nil, ErrBadFormat
+		}
+		pix = append(pix, b.ReadBlob(num*depth)...)
+	default:
+		for len(pix) < num*depth {
+			if b.Size() < 1 {
+				return nil, ErrBadFormat
+			}
+
+			var packet = b.ReadUInt8()
+			var count = int(packet&0x7F) + 1
+			if packet&0x80 != 0 {
+				if b.Size() < depth {
+					return nil, ErrBadFormat
+				}
+				var p = b.ReadBlob(depth)
+				for i := 0; i < count; i++ {
+					pix = append(pix, p...)
+				}
+			} else {
+				if b.Size() < count*depth {
+					return nil, ErrBadFormat
+				}
+				pix = append(pix, b.ReadBlob(count*depth)...)
+			}
+		}
+		pix = pix[:num*depth]
+	}
+
+	var img = image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < num; i++ {
+		var x = i % width
+		var y = i / width
+		if h.Descriptor&descriptorRightToLeft != 0 {
+			x = width - 1 - x
+		}
+		if h.Descriptor&descriptorTopToBottom == 0 {
+			y = height - 1 - y
+		}
+
+		var p = pix[i*depth : (i+1)*depth]
+
+		var c color.NRGBA
+		switch h.ImageType {
+		case TypeColorMapped, TypeColorMappedRLE:
+			if int(p[0]) >= len(palette) {
+				return nil, ErrBadFormat
+			}
+			c = palette[p[0]]
+		case TypeGrayscale, TypeGrayscaleRLE:
+			c = readColor(p, 8)
+			if depth == 2 {
+				c.A = p[1]
+			}
+		default:
+			c = readColor(p, h.Depth)
+		}
+
+		if !*alpha {
+			c.A = 0xFF
+		} else if premultiplied && c.A != 0 {
+			c.R = uint8(uint32(c.R) * 0xFF / uint32(c.A))
+			c.G = uint8(uint32(c.G) * 0xFF / uint32(c.A))
+			c.B = uint8(uint32(c.B) * 0xFF / uint32(c.A))
+		}
+
+		img.SetNRGBA(x, y, c)
+	}
+
+	return img, nil
+}
@@ -0,0 +1,110 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package tga_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/tga"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+func header(h *tga.Header) *protocol.Buffer {
+	var b protocol.Buffer
+	b.WriteUInt8(h.IDLength)
+	b.WriteUInt8(h.ColorMapType)
+	b.WriteUInt8(uint8(h.ImageType))
+	b.WriteUInt16(h.MapOrigin)
+	b.WriteUInt16(h.MapLength)
+	b.WriteUInt8(h.MapDepth)
+	b.WriteUInt16(h.OriginX)
+	b.WriteUInt16(h.OriginY)
+	b.WriteUInt16(h.Width)
+	b.WriteUInt16(h.Height)
+	b.WriteUInt8(h.Depth)
+	b.WriteUInt8(h.Descriptor)
+	return &b
+}
+
+func TestDecode(t *testing.T) {
+	var red = color.NRGBA{0xFF, 0x00, 0x00, 0xFF}
+	var green = color.NRGBA{0x00, 0xFF, 0x00, 0x80}
+	var blue = color.NRGBA{0x00, 0x00, 0xFF, 0xFF}
+
+	var images = []struct {
+		hdr  tga.Header
+		data []byte
+	}{
+		// Uncompressed 24-bit, bottom-left origin, with image ID
+		{
+			tga.Header{IDLength: 2, ImageType: tga.TypeTrueColor, Width: 2, Height: 2, Depth: 24},
+			[]byte{
+				'I', 'D',
+				0xFF, 0x00, 0x00, 0xFF, 0x00, 0x00,
+				0x00, 0x00, 0xFF, 0x00, 0x00, 0xFF,
+			},
+		},
+		// RLE 32-bit, top-left origin
+		{
+			tga.Header{ImageType: tga.TypeTrueColorRLE, Width: 2, Height: 2, Depth: 32, Descriptor: 0x28},
+			[]byte{
+				0x81, 0x00, 0x00, 0xFF, 0xFF,
+				0x01, 0x00, 0xFF, 0x00, 0x80, 0xFF, 0x00, 0x00, 0xFF,
+			},
+		},
+		// Color-mapped, top-left origin
+		{
+			tga.Header{ColorMapType: 1, ImageType: tga.TypeColorMapped, MapOrigin: 1, MapLength: 3, MapDepth: 32, Width: 2, Height: 2, Depth: 8, Descriptor: 0x28},
+			[]byte{
+				0x00, 0x00, 0xFF, 0xFF, 0x00, 0xFF, 0x00, 0x80, 0xFF, 0x00, 0x00, 0xFF,
+				0x01, 0x01, 0x02, 0x03,
+			},
+		},
+	}
+
+	var expected = [][]color.NRGBA{
+		{red, red, blue, blue},
+		{red, red, green, blue},
+		{red, red, green, blue},
+	}
+
+	for i, tc := range images {
+		var b = header(&tc.hdr)
+		b.WriteBlob(tc.data)
+
+		cfg, err := tga.DecodeConfig(&protocol.Buffer{Bytes: b.Bytes})
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if cfg.Width != 2 || cfg.Height != 2 {
+			t.Fatalf("%v config mismatch %v", i, cfg)
+		}
+
+		img, err := tga.Decode(b)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+
+		for p, c := range expected[i] {
+			if px := img.At(p%2, p/2); px != c {
+				t.Fatalf("%v pixel %v mismatch %v != %v", i, p, px, c)
+			}
+		}
+	}
+}
+
+func TestBadFormat(t *testing.T) {
+	var b = header(&tga.Header{ImageType: tga.TypeTrueColor, Width: 2, Height: 2, Depth: 24})
+	b.WriteBlob([]byte{0x00, 0x00, 0x00})
+	if _, err := tga.Decode(b); err != tga.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat, got", err)
+	}
+
+	b = header(&tga.Header{ImageType: 0x20, Width: 2, Height: 2, Depth: 24})
+	if _, err := tga.Decode(b); err != tga.ErrInvalidCompression {
+		t.Fatal("Expected ErrInvalidCompression, got", err)
+	}
+}
@@ -6,12 +6,14 @@ package w3g
 
 import (
 	"errors"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
 // Errors
 var (
 	ErrBadFormat       = errors.New("w3g: Invalid file format")
-	ErrInvalidChecksum = errors.New("w3g: Checksum invalid")
+	ErrInvalidChecksum = protocol.NewError(protocol.KindChecksum, "w3g: Checksum invalid")
 	ErrUnexpectedConst = errors.New("w3g: Unexpected constant value")
 	ErrUnknownRecord   = errors.New("w3g: Unknown record ID")
 )
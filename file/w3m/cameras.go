@@ -0,0 +1,110 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Cameras placed on the map as found in the war3map.w3c file
+type Cameras struct {
+	FileFormat uint32
+	Cameras    []Camera
+}
+
+// Camera structure in war3map.w3c file
+type Camera struct {
+	TargetX       float32
+	TargetY       float32
+	ZOffset       float32
+	Rotation      float32 // Degrees
+	AngleOfAttack float32 // Degrees
+	Distance      float32
+	Roll          float32
+	FieldOfView   float32 // Degrees
+	FarClipping   float32
+	NearClipping  float32
+	LocalPitch    float32 // 1.31+
+	LocalYaw      float32 // 1.31+
+	LocalRoll     float32 // 1.31+
+	Name          string
+}
+
+// Cameras read from war3map.w3c
+func (m *Map) Cameras() (*Cameras, error) {
+	w3c, err := m.open("war3map.w3c")
+	if err != nil {
+		return nil, err
+	}
+	defer w3c.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, w3c); err != nil {
+		return nil, err
+	}
+
+	return decodeCameras(&b, m.localCameras(), m.ExpandString)
+}
+
+// localCameras reports whether cameras store local rotation (1.31+)
+func (m *Map) localCameras() bool {
+	info, err := m.Info()
+	return err == nil && info.FileFormat >= editorVersion131
+}
+
+func decodeCameras(b *protocol.Buffer, local bool, expand func(string) (string, error)) (*Cameras, error) {
+	if b.Size() < 8 {
+		return nil, ErrBadFormat
+	}
+
+	var c = Cameras{
+		FileFormat: b.ReadUInt32(),
+	}
+
+	var minSize = 41
+	if local {
+		minSize += 12
+	}
+
+	var num = b.ReadUInt32()
+	if uint64(num)*uint64(minSize) > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+	c.Cameras = make([]Camera, num)
+
+	var err error
+	for n := uint32(0); n < num; n++ {
+		var cam = &c.Cameras[n]
+		if b.Size() < minSize {
+			return nil, ErrBadFormat
+		}
+
+		cam.TargetX = b.ReadFloat32()
+		cam.TargetY = b.ReadFloat32()
+		cam.ZOffset = b.ReadFloat32()
+		cam.Rotation = b.ReadFloat32()
+		cam.AngleOfAttack = b.ReadFloat32()
+		cam.Distance = b.ReadFloat32()
+		cam.Roll = b.ReadFloat32()
+		cam.FieldOfView = b.ReadFloat32()
+		cam.FarClipping = b.ReadFloat32()
+		cam.NearClipping = b.ReadFloat32()
+		if local {
+			cam.LocalPitch = b.ReadFloat32()
+			cam.LocalYaw = b.ReadFloat32()
+			cam.LocalRoll = b.ReadFloat32()
+		}
+		if cam.Name, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+		if cam.Name, err = expand(cam.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
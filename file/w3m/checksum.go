@@ -8,6 +8,7 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math/bits"
 	"os"
@@ -26,26 +27,51 @@ func (h *Hash) String() string {
 	return fmt.Sprintf("0x%02X|%s", h.Xoro, base64.RawStdEncoding.EncodeToString(h.Sha1[:]))
 }
 
-// Helper for XOR - ROTL hash function
-type xoro uint32
-
-func (v *xoro) Write8(b uint8) {
-	*v = xoro(bits.RotateLeft32(uint32(*v)^uint32(b), 3))
+func rotl(v uint32, b uint32) uint32 {
+	return bits.RotateLeft32(v^b, 3)
 }
 
-func (v *xoro) Write32(b uint32) {
-	*v = xoro(bits.RotateLeft32(uint32(*v)^b, 3))
+// Helper for XOR - ROTL hash function
+// Data is hashed per dword, trailing bytes are hashed individually (see Sum32)
+type xoro struct {
+	sum uint32
+	buf []byte // Partial dword carried over to next Write
 }
 
 func (v *xoro) Write(b []byte) (int, error) {
+	var n = len(b)
+	if len(v.buf) > 0 {
+		var k = 4 - len(v.buf)
+		if k > len(b) {
+			k = len(b)
+		}
+		v.buf = append(v.buf, b[:k]...)
+		b = b[k:]
+		if len(v.buf) < 4 {
+			return n, nil
+		}
+
+		var p = protocol.Buffer{Bytes: v.buf}
+		v.sum = rotl(v.sum, p.ReadUInt32())
+		v.buf = v.buf[:0]
+	}
+
 	var buf = protocol.Buffer{Bytes: b}
 	for buf.Size() >= 4 {
-		v.Write32(buf.ReadUInt32())
+		v.sum = rotl(v.sum, buf.ReadUInt32())
 	}
-	for buf.Size() >= 1 {
-		v.Write8(buf.ReadUInt8())
+	v.buf = append(v.buf, buf.Bytes...)
+
+	return n, nil
+}
+
+// Sum32 returns the hash of all data written so far
+func (v *xoro) Sum32() uint32 {
+	var sum = v.sum
+	for _, b := range v.buf {
+		sum = rotl(sum, uint32(b))
 	}
-	return len(b), nil
+	return sum
 }
 
 var hashFiles1 = [][]string{
@@ -85,56 +111,80 @@ func (m *Map) findFile(files []string, stor *fs.Storage) (io.ReadCloser, error)
 	return nil, nil
 }
 
+// hashFile copies the first file found in files to w, returns false if none of the files exist
+func (m *Map) hashFile(files []string, stor *fs.Storage, w io.Writer, buf []byte) (bool, error) {
+	r, err := m.findFile(files, stor)
+	if err != nil || r == nil {
+		return false, err
+	}
+	defer r.Close()
+
+	if _, err := io.CopyBuffer(w, r, buf); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Checksum returns the content hash that identifies the map (used in version < 1.32)
+// The game prefers common.j and blizzard.j embedded in the map over the ones in stor
 func (m *Map) Checksum(stor *fs.Storage) (*Hash, error) {
 	var sha = sha1.New()
-	var xor = xoro(0)
+	var xor uint32
 	var buf = make([]byte, 32*1024)
 
 	for _, file := range hashFiles1 {
-		r, err := m.findFile(file, stor)
-		if err != nil {
+		var sub xoro
+		if ok, err := m.hashFile(file, stor, io.MultiWriter(sha, &sub), buf); err != nil {
 			return nil, err
-		}
-		if r == nil {
-			continue
-		}
-
-		var subxor xoro
-		io.CopyBuffer(io.MultiWriter(sha, &subxor), r, buf)
-		xor ^= subxor
-
-		if r != nil {
-			r.Close()
+		} else if ok {
+			xor ^= sub.Sum32()
 		}
 	}
 
-	xor = xoro(bits.RotateLeft32(uint32(xor), 3))
+	xor = bits.RotateLeft32(xor, 3)
 
 	var magic = []byte{0x9E, 0x37, 0xF1, 0x03}
-	xor.Write(magic)
+	var mbuf = protocol.Buffer{Bytes: magic}
+	xor = rotl(xor, mbuf.ReadUInt32())
 	sha.Write(magic)
 
 	for _, file := range hashFiles2 {
-		r, err := m.findFile(file, stor)
-		if err != nil {
+		var sub xoro
+		if ok, err := m.hashFile(file, stor, io.MultiWriter(sha, &sub), buf); err != nil {
 			return nil, err
-		}
-		if r == nil {
-			continue
-		}
-
-		var subxor xoro
-		io.CopyBuffer(io.MultiWriter(sha, &subxor), r, buf)
-		xor.Write32(uint32(subxor))
-
-		if r != nil {
-			r.Close()
+		} else if ok {
+			xor = rotl(xor, sub.Sum32())
 		}
 	}
 
-	var h = Hash{Xoro: uint32(xor)}
+	var h = Hash{Xoro: xor}
 	copy(h.Sha1[:], sha.Sum(nil))
 
 	return &h, nil
 }
+
+// FileHash identifies a map file by its size and CRC32 (as verified in W3GS_MapCheck)
+type FileHash struct {
+	Size uint32
+	CRC  uint32
+}
+
+// HashFile computes the FileHash of the map file at fileName
+func HashFile(fileName string) (*FileHash, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var crc = crc32.NewIEEE()
+	size, err := io.Copy(crc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileHash{
+		Size: uint32(size),
+		CRC:  crc.Sum32(),
+	}, nil
+}
@@ -15,7 +15,8 @@ import (
 
 // Errors
 var (
-	ErrBadFormat = errors.New("w3m: Invalid file format")
+	ErrBadFormat       = errors.New("w3m: Invalid file format")
+	ErrUnknownFunction = errors.New("w3m: Unknown trigger function")
 )
 
 // Size enum
@@ -42,6 +43,17 @@ const (
 	GameCodeFormatLua
 )
 
+func (f GameCodeFormat) String() string {
+	switch f {
+	case GameCodeFormatJASS:
+		return "JASS"
+	case GameCodeFormatLua:
+		return "Lua"
+	default:
+		return fmt.Sprintf("GameCodeFormat(0x%02X)", uint32(f))
+	}
+}
+
 func (s Size) String() string {
 	switch s {
 	case SizeTiny:
@@ -350,6 +362,494 @@ func (u UpgradeAvailability) String() string {
 	}
 }
 
+// RandomTableType enum
+type RandomTableType uint32
+
+// Random unit table position types
+const (
+	RandomTableUnit RandomTableType = iota
+	RandomTableBuilding
+	RandomTableItem
+)
+
+func (t RandomTableType) String() string {
+	switch t {
+	case RandomTableUnit:
+		return "Unit"
+	case RandomTableBuilding:
+		return "Building"
+	case RandomTableItem:
+		return "Item"
+	default:
+		return fmt.Sprintf("RandomTableType(0x%02X)", uint32(t))
+	}
+}
+
+// RandomUnitType enum
+type RandomUnitType uint32
+
+// Random unit/item types of placed units
+const (
+	RandomUnitAny    RandomUnitType = iota // Any unit/item of RandomLevel and RandomClass (also used for non-random units)
+	RandomUnitGroup                        // Unit/item from a random group
+	RandomUnitCustom                       // Unit from a custom table
+)
+
+func (t RandomUnitType) String() string {
+	switch t {
+	case RandomUnitAny:
+		return "Any"
+	case RandomUnitGroup:
+		return "Group"
+	case RandomUnitCustom:
+		return "Custom"
+	default:
+		return fmt.Sprintf("RandomUnitType(0x%02X)", uint32(t))
+	}
+}
+
+// TriggerElementType enum
+type TriggerElementType uint32
+
+// Trigger element types
+const (
+	TriggerElementMap      TriggerElementType = 1 << iota // Root category
+	TriggerElementLibrary                                 // Category
+	TriggerElementCategory                                // Category
+	TriggerElementGUI                                     // Trigger
+	TriggerElementComment                                 // Trigger
+	TriggerElementScript                                  // Trigger (custom text)
+	TriggerElementVariable                                // Variable
+)
+
+func (t TriggerElementType) String() string {
+	switch t {
+	case TriggerElementMap:
+		return "Map"
+	case TriggerElementLibrary:
+		return "Library"
+	case TriggerElementCategory:
+		return "Category"
+	case TriggerElementGUI:
+		return "GUI"
+	case TriggerElementComment:
+		return "Comment"
+	case TriggerElementScript:
+		return "Script"
+	case TriggerElementVariable:
+		return "Variable"
+	default:
+		return fmt.Sprintf("TriggerElementType(0x%02X)", uint32(t))
+	}
+}
+
+// ECAType enum
+type ECAType uint32
+
+// ECA types
+const (
+	ECAEvent ECAType = iota
+	ECACondition
+	ECAAction
+	ECACall
+)
+
+func (t ECAType) String() string {
+	switch t {
+	case ECAEvent:
+		return "Event"
+	case ECACondition:
+		return "Condition"
+	case ECAAction:
+		return "Action"
+	case ECACall:
+		return "Call"
+	default:
+		return fmt.Sprintf("ECAType(0x%02X)", uint32(t))
+	}
+}
+
+// ParameterType enum
+type ParameterType uint32
+
+// Parameter types
+const (
+	ParameterPreset ParameterType = iota
+	ParameterVariable
+	ParameterFunction
+	ParameterString
+	ParameterInvalid ParameterType = 0xFFFFFFFF
+)
+
+func (t ParameterType) String() string {
+	switch t {
+	case ParameterPreset:
+		return "Preset"
+	case ParameterVariable:
+		return "Variable"
+	case ParameterFunction:
+		return "Function"
+	case ParameterString:
+		return "String"
+	case ParameterInvalid:
+		return "Invalid"
+	default:
+		return fmt.Sprintf("ParameterType(0x%02X)", uint32(t))
+	}
+}
+
+// TilepointFlags enum
+type TilepointFlags uint8
+
+// Tilepoint Flags
+const (
+	TileFlagRamp     TilepointFlags = 0x01
+	TileFlagBlight   TilepointFlags = 0x02
+	TileFlagWater    TilepointFlags = 0x04
+	TileFlagBoundary TilepointFlags = 0x08
+	TileFlagEdge     TilepointFlags = 0x10 // Map edge (shadow boundary)
+)
+
+func (f TilepointFlags) String() string {
+	var res string
+	if f&TileFlagRamp != 0 {
+		res += "|Ramp"
+		f &= ^TileFlagRamp
+	}
+	if f&TileFlagBlight != 0 {
+		res += "|Blight"
+		f &= ^TileFlagBlight
+	}
+	if f&TileFlagWater != 0 {
+		res += "|Water"
+		f &= ^TileFlagWater
+	}
+	if f&TileFlagBoundary != 0 {
+		res += "|Boundary"
+		f &= ^TileFlagBoundary
+	}
+	if f&TileFlagEdge != 0 {
+		res += "|Edge"
+		f &= ^TileFlagEdge
+	}
+	if f != 0 {
+		res += fmt.Sprintf("|TilepointFlags(0x%02X)", uint8(f))
+	}
+	if res != "" {
+		res = res[1:]
+	}
+	return res
+}
+
+// PathingFlags enum
+type PathingFlags uint8
+
+// Pathing Flags
+const (
+	PathNoWalk  PathingFlags = 0x02
+	PathNoFly   PathingFlags = 0x04
+	PathNoBuild PathingFlags = 0x08
+	PathBlight  PathingFlags = 0x20
+	PathNoWater PathingFlags = 0x40
+)
+
+// Walkable by ground units
+func (f PathingFlags) Walkable() bool {
+	return f&PathNoWalk == 0
+}
+
+// Flyable by air units
+func (f PathingFlags) Flyable() bool {
+	return f&PathNoFly == 0
+}
+
+// Buildable for structures
+func (f PathingFlags) Buildable() bool {
+	return f&PathNoBuild == 0
+}
+
+func (f PathingFlags) String() string {
+	var res string
+	if f&PathNoWalk != 0 {
+		res += "|NoWalk"
+		f &= ^PathNoWalk
+	}
+	if f&PathNoFly != 0 {
+		res += "|NoFly"
+		f &= ^PathNoFly
+	}
+	if f&PathNoBuild != 0 {
+		res += "|NoBuild"
+		f &= ^PathNoBuild
+	}
+	if f&PathBlight != 0 {
+		res += "|Blight"
+		f &= ^PathBlight
+	}
+	if f&PathNoWater != 0 {
+		res += "|NoWater"
+		f &= ^PathNoWater
+	}
+	if f != 0 {
+		res += fmt.Sprintf("|PathingFlags(0x%02X)", uint8(f))
+	}
+	if res != "" {
+		res = res[1:]
+	}
+	return res
+}
+
+// ProtectionFlags enum
+type ProtectionFlags uint32
+
+// Protection and corruption signatures
+const (
+	ProtectFakeHeader     ProtectionFlags = 0x01 // Archive header has fake version or size fields
+	ProtectBadTables      ProtectionFlags = 0x02 // Hash or block table cannot be enumerated
+	ProtectNoListFile     ProtectionFlags = 0x04 // Archive has no (listfile)
+	ProtectAnonymousFiles ProtectionFlags = 0x08 // Subfiles remain without name after discovery
+	ProtectJunkFiles      ProtectionFlags = 0x10 // Unreadable subfiles without name
+	ProtectCorruptFiles   ProtectionFlags = 0x20 // Subfiles cannot be read or fail verification
+	ProtectBadInfo        ProtectionFlags = 0x40 // war3map.w3i is missing or garbled
+	ProtectNoScript       ProtectionFlags = 0x80 // Map script is missing or unreadable
+)
+
+func (f ProtectionFlags) String() string {
+	var res string
+	if f&ProtectFakeHeader != 0 {
+		res += "|FakeHeader"
+		f &= ^ProtectFakeHeader
+	}
+	if f&ProtectBadTables != 0 {
+		res += "|BadTables"
+		f &= ^ProtectBadTables
+	}
+	if f&ProtectNoListFile != 0 {
+		res += "|NoListFile"
+		f &= ^ProtectNoListFile
+	}
+	if f&ProtectAnonymousFiles != 0 {
+		res += "|AnonymousFiles"
+		f &= ^ProtectAnonymousFiles
+	}
+	if f&ProtectJunkFiles != 0 {
+		res += "|JunkFiles"
+		f &= ^ProtectJunkFiles
+	}
+	if f&ProtectCorruptFiles != 0 {
+		res += "|CorruptFiles"
+		f &= ^ProtectCorruptFiles
+	}
+	if f&ProtectBadInfo != 0 {
+		res += "|BadInfo"
+		f &= ^ProtectBadInfo
+	}
+	if f&ProtectNoScript != 0 {
+		res += "|NoScript"
+		f &= ^ProtectNoScript
+	}
+	if f != 0 {
+		res += fmt.Sprintf("|ProtectionFlags(0x%02X)", uint32(f))
+	}
+	if res != "" {
+		res = res[1:]
+	}
+	return res
+}
+
+// SoundFlags enum
+type SoundFlags uint32
+
+// Sound flags
+const (
+	SoundLooping        SoundFlags = 0x01
+	Sound3D             SoundFlags = 0x02
+	SoundStopOutOfRange SoundFlags = 0x04
+	SoundMusic          SoundFlags = 0x08
+)
+
+func (f SoundFlags) String() string {
+	var res string
+	if f&SoundLooping != 0 {
+		res += "|Looping"
+		f &= ^SoundLooping
+	}
+	if f&Sound3D != 0 {
+		res += "|3D"
+		f &= ^Sound3D
+	}
+	if f&SoundStopOutOfRange != 0 {
+		res += "|StopOutOfRange"
+		f &= ^SoundStopOutOfRange
+	}
+	if f&SoundMusic != 0 {
+		res += "|Music"
+		f &= ^SoundMusic
+	}
+	if f != 0 {
+		res += fmt.Sprintf("|SoundFlags(0x%02X)", uint32(f))
+	}
+	if res != "" {
+		res = res[1:]
+	}
+	return res
+}
+
+// ObjectType enum
+type ObjectType uint8
+
+// Object types
+const (
+	ObjectUnit ObjectType = iota
+	ObjectItem
+	ObjectDestructable
+	ObjectDoodad
+	ObjectAbility
+	ObjectBuff
+	ObjectUpgrade
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjectUnit:
+		return "Unit"
+	case ObjectItem:
+		return "Item"
+	case ObjectDestructable:
+		return "Destructable"
+	case ObjectDoodad:
+		return "Doodad"
+	case ObjectAbility:
+		return "Ability"
+	case ObjectBuff:
+		return "Buff"
+	case ObjectUpgrade:
+		return "Upgrade"
+	default:
+		return fmt.Sprintf("ObjectType(0x%02X)", uint8(t))
+	}
+}
+
+// Extension of the object modification file
+func (t ObjectType) Extension() string {
+	switch t {
+	case ObjectUnit:
+		return "w3u"
+	case ObjectItem:
+		return "w3t"
+	case ObjectDestructable:
+		return "w3b"
+	case ObjectDoodad:
+		return "w3d"
+	case ObjectAbility:
+		return "w3a"
+	case ObjectBuff:
+		return "w3h"
+	case ObjectUpgrade:
+		return "w3q"
+	default:
+		return ""
+	}
+}
+
+// levels reports whether modifications store a level and data column
+func (t ObjectType) levels() bool {
+	return t == ObjectDoodad || t == ObjectAbility || t == ObjectUpgrade
+}
+
+// ValueType enum
+type ValueType uint32
+
+// Modification value types
+const (
+	ValueInt ValueType = iota
+	ValueReal
+	ValueUnreal // Real in [0, 1]
+	ValueString
+)
+
+func (v ValueType) String() string {
+	switch v {
+	case ValueInt:
+		return "Int"
+	case ValueReal:
+		return "Real"
+	case ValueUnreal:
+		return "Unreal"
+	case ValueString:
+		return "String"
+	default:
+		return fmt.Sprintf("ValueType(0x%02X)", uint32(v))
+	}
+}
+
+// DoodadState enum
+type DoodadState uint8
+
+// Doodad states
+const (
+	DoodadHidden   DoodadState = iota // Invisible and non-solid
+	DoodadNonSolid                    // Visible but non-solid
+	DoodadNormal                      // Visible and solid
+)
+
+func (d DoodadState) String() string {
+	switch d {
+	case DoodadHidden:
+		return "Hidden"
+	case DoodadNonSolid:
+		return "NonSolid"
+	case DoodadNormal:
+		return "Normal"
+	default:
+		return fmt.Sprintf("DoodadState(0x%02X)", uint8(d))
+	}
+}
+
+// SupportedModes enum
+type SupportedModes uint32
+
+// Supported graphics modes (Reforged)
+const (
+	ModeSD SupportedModes = 1 << iota
+	ModeHD
+)
+
+func (m SupportedModes) String() string {
+	switch m {
+	case 0:
+		return "None"
+	case ModeSD:
+		return "SD"
+	case ModeHD:
+		return "HD"
+	case ModeSD | ModeHD:
+		return "SD|HD"
+	default:
+		return fmt.Sprintf("SupportedModes(0x%02X)", uint32(m))
+	}
+}
+
+// GameDataVersion enum
+type GameDataVersion uint32
+
+// Game data versions (Reforged)
+const (
+	GameDataRoc GameDataVersion = iota
+	GameDataTft
+)
+
+func (v GameDataVersion) String() string {
+	switch v {
+	case GameDataRoc:
+		return "RoC"
+	case GameDataTft:
+		return "TFT"
+	default:
+		return fmt.Sprintf("GameDataVersion(0x%02X)", uint32(v))
+	}
+}
+
 // MinimapIcon enum
 type MinimapIcon uint32
 
@@ -0,0 +1,148 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Doodads (and destructables) placed on the map as found in the war3map.doo file
+type Doodads struct {
+	FileFormat     uint32
+	SubVersion     uint32
+	Doodads        []Doodad
+	SpecialFormat  uint32
+	SpecialDoodads []SpecialDoodad
+}
+
+// Doodad (or destructable) structure in war3map.doo file
+type Doodad struct {
+	TypeID         protocol.DWordString
+	Variation      uint32
+	X              float32
+	Y              float32
+	Z              float32
+	Angle          float32
+	ScaleX         float32
+	ScaleY         float32
+	ScaleZ         float32
+	SkinID         protocol.DWordString
+	State          DoodadState
+	Life           uint8 // Percentage
+	ItemTable      int32 // -1 for none (see Info.RandomItemTables)
+	ItemSets       [][]RandomItem
+	CreationNumber uint32
+}
+
+// SpecialDoodad (cliffs, etc.) structure in war3map.doo file, at w3e coordinates
+type SpecialDoodad struct {
+	TypeID    protocol.DWordString
+	Variation uint32
+	X         uint32
+	Y         uint32
+}
+
+const doodadVersionTft = 8
+
+// Doodads read from war3map.doo
+func (m *Map) Doodads() (*Doodads, error) {
+	doo, err := m.open("war3map.doo")
+	if err != nil {
+		return nil, err
+	}
+	defer doo.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, doo); err != nil {
+		return nil, err
+	}
+
+	return decodeDoodads(&b, m.skins())
+}
+
+func decodeDoodads(b *protocol.Buffer, skins bool) (*Doodads, error) {
+	if b.Size() < 16 || b.ReadLEDString() != protocol.DString("W3do") {
+		return nil, ErrBadFormat
+	}
+
+	var d = Doodads{
+		FileFormat: b.ReadUInt32(),
+		SubVersion: b.ReadUInt32(),
+	}
+
+	var minSize = 42
+	if skins {
+		minSize += 4
+	}
+	if d.FileFormat >= doodadVersionTft {
+		minSize += 8
+	}
+
+	var num = b.ReadUInt32()
+	if uint64(num)*uint64(minSize) > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+	d.Doodads = make([]Doodad, num)
+
+	var err error
+	for n := uint32(0); n < num; n++ {
+		var doodad = &d.Doodads[n]
+		if b.Size() < minSize {
+			return nil, ErrBadFormat
+		}
+
+		doodad.TypeID = b.ReadLEDString()
+		doodad.Variation = b.ReadUInt32()
+		doodad.X = b.ReadFloat32()
+		doodad.Y = b.ReadFloat32()
+		doodad.Z = b.ReadFloat32()
+		doodad.Angle = b.ReadFloat32()
+		doodad.ScaleX = b.ReadFloat32()
+		doodad.ScaleY = b.ReadFloat32()
+		doodad.ScaleZ = b.ReadFloat32()
+		if skins {
+			doodad.SkinID = b.ReadLEDString()
+		}
+		doodad.State = DoodadState(b.ReadUInt8())
+		doodad.Life = b.ReadUInt8()
+
+		doodad.ItemTable = -1
+		if d.FileFormat >= doodadVersionTft {
+			doodad.ItemTable = int32(b.ReadUInt32())
+			if doodad.ItemSets, err = readItemSets(b); err != nil {
+				return nil, err
+			}
+		}
+
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		doodad.CreationNumber = b.ReadUInt32()
+	}
+
+	// Special doodads are optional
+	if b.Size() < 8 {
+		return &d, nil
+	}
+
+	d.SpecialFormat = b.ReadUInt32()
+
+	var numSpecial = b.ReadUInt32()
+	if uint64(numSpecial)*16 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	d.SpecialDoodads = make([]SpecialDoodad, numSpecial)
+	for n := uint32(0); n < numSpecial; n++ {
+		d.SpecialDoodads[n].TypeID = b.ReadLEDString()
+		d.SpecialDoodads[n].Variation = b.ReadUInt32()
+		d.SpecialDoodads[n].X = b.ReadUInt32()
+		d.SpecialDoodads[n].Y = b.ReadUInt32()
+	}
+
+	return &d, nil
+}
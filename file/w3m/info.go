@@ -54,10 +54,15 @@ type Info struct {
 	LightEnv   Tileset
 	WaterColor uint32
 
+	SupportedModes  SupportedModes
+	GameDataVersion GameDataVersion
+
 	Players                     []Player
 	Forces                      []Force
 	CustomUpgradeAvailabilities []CustomUpgradeAvailability
 	CustomTechAvailabilities    []CustomTechAvailability
+	RandomUnitTables            []RandomUnitTable
+	RandomItemTables            []RandomItemTable
 }
 
 // GameVersion stored in map file
@@ -70,15 +75,17 @@ type GameVersion struct {
 
 // Player structure in war3map.w3i file
 type Player struct {
-	ID           uint32
-	Type         PlayerType
-	Race         Race
-	Flags        PlayerFlags
-	Name         string
-	StartPosX    float32
-	StartPosY    float32
-	AllyPrioLow  protocol.BitSet32
-	AllyPrioHigh protocol.BitSet32
+	ID            uint32
+	Type          PlayerType
+	Race          Race
+	Flags         PlayerFlags
+	Name          string
+	StartPosX     float32
+	StartPosY     float32
+	AllyPrioLow   protocol.BitSet32
+	AllyPrioHigh  protocol.BitSet32
+	EnemyPrioLow  protocol.BitSet32
+	EnemyPrioHigh protocol.BitSet32
 }
 
 // Force structure in war3map.w3i file
@@ -102,14 +109,50 @@ type CustomTechAvailability struct {
 	TechID    protocol.DWordString
 }
 
+// RandomUnitTable (random group) in war3map.w3i file
+type RandomUnitTable struct {
+	Number    uint32
+	Name      string
+	Positions []RandomTableType
+	Lines     []RandomUnitLine
+}
+
+// RandomUnitLine in a RandomUnitTable, with an ID for each position (0 if none)
+type RandomUnitLine struct {
+	Chance uint32
+	IDs    []protocol.DWordString
+}
+
+// RandomItemTable in war3map.w3i file
+type RandomItemTable struct {
+	Number uint32
+	Name   string
+	Sets   [][]RandomItem
+}
+
+// RandomItem in a set of a RandomItemTable
+type RandomItem struct {
+	Chance uint32
+	ItemID protocol.DWordString
+}
+
 const editorVersionRoc = 18
 const editorVersionTft = 25
 const editorVersion131 = 28
 const editorVersionReforged = 31
 
-// Info read from war3map.w3i
+// Info read from war3map.w3i, trigger strings are expanded
 func (m *Map) Info() (*Info, error) {
-	w3i, err := m.Archive.Open("war3map.w3i")
+	return m.info(m.ExpandString)
+}
+
+// RawInfo read from war3map.w3i, with TRIGSTR_### references left intact (see WriteInfo)
+func (m *Map) RawInfo() (*Info, error) {
+	return m.info(func(s string) (string, error) { return s, nil })
+}
+
+func (m *Map) info(expand func(string) (string, error)) (*Info, error) {
+	w3i, err := m.open("war3map.w3i")
 	if err != nil {
 		return nil, err
 	}
@@ -124,14 +167,34 @@ func (m *Map) Info() (*Info, error) {
 		return nil, err
 	}
 
+	i, err := decodeInfo(&b, expand)
+	if err != nil {
+		return nil, err
+	}
+
+	// CodeFormat is not stored before 1.31, and may be faked by protectors
+	if f, ok := m.scriptFormat(); ok {
+		i.CodeFormat = f
+	}
+
+	return i, nil
+}
+
+func decodeInfo(b *protocol.Buffer, expand func(string) (string, error)) (*Info, error) {
+	if b.Size() < 96 {
+		return nil, ErrBadFormat
+	}
+
 	var readTS = func() (string, error) {
 		s, err := b.ReadCString()
 		if err != nil {
 			return "", err
 		}
-		return m.ExpandString(s)
+		return expand(s)
 	}
 
+	var err error
+
 	var i = Info{
 		FileFormat: b.ReadUInt32(),
 	}
@@ -231,9 +294,11 @@ func (m *Map) Info() (*Info, error) {
 	}
 
 	if i.FileFormat >= editorVersionReforged {
-		// TODO: two unknown reforged int's
-		b.ReadUInt32()
-		b.ReadUInt32()
+		if b.Size() < 8 {
+			return nil, ErrBadFormat
+		}
+		i.SupportedModes = SupportedModes(b.ReadUInt32())
+		i.GameDataVersion = GameDataVersion(b.ReadUInt32())
 	}
 
 	if b.Size() < 8 {
@@ -264,9 +329,11 @@ func (m *Map) Info() (*Info, error) {
 		i.Players[p].AllyPrioHigh = protocol.BitSet32(b.ReadUInt32())
 
 		if i.FileFormat >= editorVersionReforged {
-			// TODO: two unknown reforged player options
-			b.ReadUInt32()
-			b.ReadUInt32()
+			if b.Size() < 8 {
+				return nil, ErrBadFormat
+			}
+			i.Players[p].EnemyPrioLow = protocol.BitSet32(b.ReadUInt32())
+			i.Players[p].EnemyPrioHigh = protocol.BitSet32(b.ReadUInt32())
 		}
 	}
 
@@ -309,13 +376,254 @@ func (m *Map) Info() (*Info, error) {
 			i.CustomTechAvailabilities[u].PlayerSet = protocol.BitSet32(b.ReadUInt32())
 			i.CustomTechAvailabilities[u].TechID = b.ReadLEDString()
 		}
+	}
 
-		// TODO: RandomUnitTable and RandomItemTable
+	if b.Size() >= 4 {
+		var numTables = b.ReadUInt32()
+		i.RandomUnitTables = make([]RandomUnitTable, numTables)
+
+		for t := uint32(0); t < numTables; t++ {
+			if b.Size() < 13 {
+				return nil, ErrBadFormat
+			}
+			i.RandomUnitTables[t].Number = b.ReadUInt32()
+			i.RandomUnitTables[t].Name, err = readTS()
+			if err != nil {
+				return nil, err
+			} else if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+
+			var numPos = b.ReadUInt32()
+			if uint32(b.Size()) < numPos*4+4 {
+				return nil, ErrBadFormat
+			}
+			i.RandomUnitTables[t].Positions = make([]RandomTableType, numPos)
+			for p := uint32(0); p < numPos; p++ {
+				i.RandomUnitTables[t].Positions[p] = RandomTableType(b.ReadUInt32())
+			}
+
+			var numLines = b.ReadUInt32()
+			i.RandomUnitTables[t].Lines = make([]RandomUnitLine, numLines)
+			for l := uint32(0); l < numLines; l++ {
+				if uint32(b.Size()) < numPos*4+4 {
+					return nil, ErrBadFormat
+				}
+				i.RandomUnitTables[t].Lines[l].Chance = b.ReadUInt32()
+				i.RandomUnitTables[t].Lines[l].IDs = make([]protocol.DWordString, numPos)
+				for p := uint32(0); p < numPos; p++ {
+					i.RandomUnitTables[t].Lines[l].IDs[p] = b.ReadLEDString()
+				}
+			}
+		}
+	}
+
+	if i.FileFormat >= editorVersionTft && b.Size() >= 4 {
+		var numTables = b.ReadUInt32()
+		i.RandomItemTables = make([]RandomItemTable, numTables)
+
+		for t := uint32(0); t < numTables; t++ {
+			if b.Size() < 9 {
+				return nil, ErrBadFormat
+			}
+			i.RandomItemTables[t].Number = b.ReadUInt32()
+			i.RandomItemTables[t].Name, err = readTS()
+			if err != nil {
+				return nil, err
+			} else if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+
+			var numSets = b.ReadUInt32()
+			i.RandomItemTables[t].Sets = make([][]RandomItem, numSets)
+			for s := uint32(0); s < numSets; s++ {
+				if b.Size() < 4 {
+					return nil, ErrBadFormat
+				}
+				var numItems = b.ReadUInt32()
+				if uint32(b.Size()) < numItems*8 {
+					return nil, ErrBadFormat
+				}
+				i.RandomItemTables[t].Sets[s] = make([]RandomItem, numItems)
+				for n := uint32(0); n < numItems; n++ {
+					i.RandomItemTables[t].Sets[s][n].Chance = b.ReadUInt32()
+					i.RandomItemTables[t].Sets[s][n].ItemID = b.ReadLEDString()
+				}
+			}
+		}
 	}
 
 	return &i, nil
 }
 
+// Encode Info to war3map.w3i format (trigger strings are stored as literal strings)
+func (i *Info) Encode() ([]byte, error) {
+	switch i.FileFormat {
+	case editorVersionRoc:
+	case editorVersionTft:
+	case editorVersion131:
+	case editorVersionReforged:
+	default:
+		return nil, ErrBadFormat
+	}
+
+	var b protocol.Buffer
+	b.WriteUInt32(i.FileFormat)
+	b.WriteUInt32(i.SaveCount)
+	b.WriteUInt32(i.EditorVersion)
+
+	if i.FileFormat >= editorVersion131 {
+		b.WriteUInt32(i.GameVersion.Major)
+		b.WriteUInt32(i.GameVersion.Minor)
+		b.WriteUInt32(i.GameVersion.Patch)
+		b.WriteUInt32(i.GameVersion.Commit)
+	}
+
+	b.WriteCString(i.Name)
+	b.WriteCString(i.Author)
+	b.WriteCString(i.Description)
+	b.WriteCString(i.SuggestedPlayers)
+
+	for c := 0; c < len(i.CamBounds); c++ {
+		b.WriteFloat32(i.CamBounds[c])
+	}
+	for c := 0; c < len(i.CamBoundsCompl); c++ {
+		b.WriteUInt32(i.CamBoundsCompl[c])
+	}
+
+	b.WriteUInt32(i.Width)
+	b.WriteUInt32(i.Height)
+	b.WriteUInt32(uint32(i.Flags))
+
+	b.WriteUInt8(uint8(i.Tileset))
+	b.WriteUInt32(i.LsBackground)
+
+	if i.FileFormat >= editorVersionTft {
+		b.WriteCString(i.LsPath)
+	}
+	b.WriteCString(i.LsText)
+	b.WriteCString(i.LsTitle)
+	b.WriteCString(i.LsSubTitle)
+
+	b.WriteUInt32(i.DataSet)
+
+	if i.FileFormat >= editorVersionTft {
+		b.WriteCString(i.PsPath)
+	}
+	b.WriteCString(i.PsText)
+	b.WriteCString(i.PsTitle)
+	b.WriteCString(i.PsSubTitle)
+
+	if i.FileFormat >= editorVersionTft {
+		b.WriteUInt32(i.Fog)
+		b.WriteFloat32(i.FogStart)
+		b.WriteFloat32(i.FogEnd)
+		b.WriteFloat32(i.FogDensity)
+		b.WriteUInt32(i.FogColor)
+		b.WriteLEDString(i.WeatherID)
+		b.WriteCString(i.SoundEnv)
+		b.WriteUInt8(uint8(i.LightEnv))
+		b.WriteUInt32(i.WaterColor)
+	}
+
+	if i.FileFormat >= editorVersion131 {
+		b.WriteUInt32(uint32(i.CodeFormat))
+	}
+
+	if i.FileFormat >= editorVersionReforged {
+		b.WriteUInt32(uint32(i.SupportedModes))
+		b.WriteUInt32(uint32(i.GameDataVersion))
+	}
+
+	b.WriteUInt32(uint32(len(i.Players)))
+	for _, p := range i.Players {
+		b.WriteUInt32(p.ID)
+		b.WriteUInt32(uint32(p.Type))
+		b.WriteUInt32(uint32(p.Race))
+		b.WriteUInt32(uint32(p.Flags))
+		b.WriteCString(p.Name)
+		b.WriteFloat32(p.StartPosX)
+		b.WriteFloat32(p.StartPosY)
+		b.WriteUInt32(uint32(p.AllyPrioLow))
+		b.WriteUInt32(uint32(p.AllyPrioHigh))
+
+		if i.FileFormat >= editorVersionReforged {
+			b.WriteUInt32(uint32(p.EnemyPrioLow))
+			b.WriteUInt32(uint32(p.EnemyPrioHigh))
+		}
+	}
+
+	b.WriteUInt32(uint32(len(i.Forces)))
+	for _, f := range i.Forces {
+		b.WriteUInt32(uint32(f.Flags))
+		b.WriteUInt32(uint32(f.PlayerSet))
+		b.WriteCString(f.Name)
+	}
+
+	b.WriteUInt32(uint32(len(i.CustomUpgradeAvailabilities)))
+	for _, u := range i.CustomUpgradeAvailabilities {
+		b.WriteUInt32(uint32(u.PlayerSet))
+		b.WriteLEDString(u.UpgradeID)
+		b.WriteUInt32(u.Level)
+		b.WriteUInt32(uint32(u.Availability))
+	}
+
+	b.WriteUInt32(uint32(len(i.CustomTechAvailabilities)))
+	for _, t := range i.CustomTechAvailabilities {
+		b.WriteUInt32(uint32(t.PlayerSet))
+		b.WriteLEDString(t.TechID)
+	}
+
+	b.WriteUInt32(uint32(len(i.RandomUnitTables)))
+	for _, t := range i.RandomUnitTables {
+		b.WriteUInt32(t.Number)
+		b.WriteCString(t.Name)
+		b.WriteUInt32(uint32(len(t.Positions)))
+		for _, p := range t.Positions {
+			b.WriteUInt32(uint32(p))
+		}
+
+		b.WriteUInt32(uint32(len(t.Lines)))
+		for _, l := range t.Lines {
+			if len(l.IDs) != len(t.Positions) {
+				return nil, ErrBadFormat
+			}
+			b.WriteUInt32(l.Chance)
+			for _, id := range l.IDs {
+				b.WriteLEDString(id)
+			}
+		}
+	}
+
+	if i.FileFormat >= editorVersionTft {
+		b.WriteUInt32(uint32(len(i.RandomItemTables)))
+		for _, t := range i.RandomItemTables {
+			b.WriteUInt32(t.Number)
+			b.WriteCString(t.Name)
+			b.WriteUInt32(uint32(len(t.Sets)))
+			for _, s := range t.Sets {
+				b.WriteUInt32(uint32(len(s)))
+				for _, n := range s {
+					b.WriteUInt32(n.Chance)
+					b.WriteLEDString(n.ItemID)
+				}
+			}
+		}
+	}
+
+	return b.Bytes, nil
+}
+
+// WriteInfo replaces war3map.w3i in a map opened with OpenWritable
+// Strings are stored as is, modify the result of RawInfo rather than Info to keep TRIGSTR_### references (and localization)
+func (m *Map) WriteInfo(i *Info) error {
+	b, err := i.Encode()
+	if err != nil {
+		return err
+	}
+	return m.Archive.WriteFile("war3map.w3i", b)
+}
+
 // Size returns the map size category
 func (m *Info) Size() Size {
 	var s = m.Width * m.Height
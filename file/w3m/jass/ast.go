@@ -0,0 +1,208 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass
+
+// Node in the syntax tree
+type Node interface {
+	Position() Pos
+}
+
+// Expr node
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Stmt node
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// File is the syntax tree of a JASS script
+type File struct {
+	Types     []*TypeDecl
+	Globals   []*VarDecl
+	Natives   []*FuncDecl
+	Functions []*FuncDecl
+}
+
+// TypeDecl declares a handle type (type Name extends Base)
+type TypeDecl struct {
+	Pos
+	Name string
+	Base string
+}
+
+// VarDecl declares a global or local variable
+type VarDecl struct {
+	Pos
+	Constant bool
+	Type     string
+	Array    bool
+	Name     string
+	Init     Expr // nil if uninitialized
+}
+
+// Param of a function
+type Param struct {
+	Type string
+	Name string
+}
+
+// FuncDecl declares a native or function
+type FuncDecl struct {
+	Pos
+	Constant bool
+	Native   bool
+	Name     string
+	Params   []Param
+	Returns  string // "nothing" if no return value
+	Locals   []*VarDecl
+	Body     []Stmt
+}
+
+// SetStmt assigns Value to variable Name (or Name[Index] for arrays)
+type SetStmt struct {
+	Pos
+	Debug bool
+	Name  string
+	Index Expr // nil for non-arrays
+	Value Expr
+}
+
+// CallStmt calls a function and discards its result
+type CallStmt struct {
+	Pos
+	Debug bool
+	Call  *CallExpr
+}
+
+// IfStmt executes Then if Cond holds, otherwise Else
+// An elseif clause is represented by a nested IfStmt as only statement in Else
+type IfStmt struct {
+	Pos
+	Debug bool
+	Cond  Expr
+	Then  []Stmt
+	Else  []Stmt
+}
+
+// LoopStmt repeats Body until an ExitWhenStmt is satisfied
+type LoopStmt struct {
+	Pos
+	Debug bool
+	Body  []Stmt
+}
+
+// ExitWhenStmt exits the enclosing loop if Cond holds
+type ExitWhenStmt struct {
+	Pos
+	Cond Expr
+}
+
+// ReturnStmt returns from the function with an optional Value
+type ReturnStmt struct {
+	Pos
+	Value Expr // nil if no return value
+}
+
+// Ident refers to a variable
+type Ident struct {
+	Pos
+	Name string
+}
+
+// IndexExpr refers to an element of array Name
+type IndexExpr struct {
+	Pos
+	Name  string
+	Index Expr
+}
+
+// CallExpr calls function Name
+type CallExpr struct {
+	Pos
+	Name string
+	Args []Expr
+}
+
+// FuncRef refers to function Name (function Name)
+type FuncRef struct {
+	Pos
+	Name string
+}
+
+// IntLit is an integer literal (decimal, octal, hexadecimal, or rawcode)
+type IntLit struct {
+	Pos
+	Raw   string
+	Value int32
+}
+
+// RealLit is a real literal
+type RealLit struct {
+	Pos
+	Raw   string
+	Value float32
+}
+
+// StringLit is a string literal, with escape sequences resolved
+type StringLit struct {
+	Pos
+	Value string
+}
+
+// BoolLit is either true or false
+type BoolLit struct {
+	Pos
+	Value bool
+}
+
+// NullLit is the null literal
+type NullLit struct {
+	Pos
+}
+
+// UnaryExpr applies Op (TokenNot, TokenMinus, or TokenPlus) to X
+type UnaryExpr struct {
+	Pos
+	Op TokenType
+	X  Expr
+}
+
+// BinaryExpr applies Op to X and Y
+type BinaryExpr struct {
+	Pos
+	Op TokenType
+	X  Expr
+	Y  Expr
+}
+
+// ParenExpr is a parenthesized expression
+type ParenExpr struct {
+	Pos
+	X Expr
+}
+
+func (*SetStmt) stmtNode()      {}
+func (*CallStmt) stmtNode()     {}
+func (*IfStmt) stmtNode()       {}
+func (*LoopStmt) stmtNode()     {}
+func (*ExitWhenStmt) stmtNode() {}
+func (*ReturnStmt) stmtNode()   {}
+
+func (*Ident) exprNode()      {}
+func (*IndexExpr) exprNode()  {}
+func (*CallExpr) exprNode()   {}
+func (*FuncRef) exprNode()    {}
+func (*IntLit) exprNode()     {}
+func (*RealLit) exprNode()    {}
+func (*StringLit) exprNode()  {}
+func (*BoolLit) exprNode()    {}
+func (*NullLit) exprNode()    {}
+func (*UnaryExpr) exprNode()  {}
+func (*BinaryExpr) exprNode() {}
+func (*ParenExpr) exprNode()  {}
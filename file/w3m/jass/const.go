@@ -0,0 +1,136 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors
+var (
+	ErrUnexpectedChar = errors.New("jass: Unexpected character")
+	ErrUnterminated   = errors.New("jass: Unterminated literal")
+	ErrBadLiteral     = errors.New("jass: Invalid literal")
+	ErrUnexpected     = errors.New("jass: Unexpected token")
+)
+
+// Error annotates an error with its position in the source
+type Error struct {
+	Pos
+	Err  error
+	Near string // Offending source text
+}
+
+func (e *Error) Error() string {
+	if e.Near != "" {
+		return fmt.Sprintf("%v %q (line %d, column %d)", e.Err, e.Near, e.Line, e.Col)
+	}
+	return fmt.Sprintf("%v (line %d, column %d)", e.Err, e.Line, e.Col)
+}
+
+// TokenType enum
+type TokenType uint8
+
+// Token types
+const (
+	TokenEOF TokenType = iota
+	TokenNewline
+	TokenIdent
+	TokenKeyword
+	TokenInt
+	TokenReal
+	TokenString
+	TokenRawcode
+	TokenAssign   // =
+	TokenEq       // ==
+	TokenNeq      // !=
+	TokenLt       // <
+	TokenLe       // <=
+	TokenGt       // >
+	TokenGe       // >=
+	TokenPlus     // +
+	TokenMinus    // -
+	TokenMul      // *
+	TokenDiv      // /
+	TokenAnd      // and
+	TokenOr       // or
+	TokenNot      // not
+	TokenLParen   // (
+	TokenRParen   // )
+	TokenLBracket // [
+	TokenRBracket // ]
+	TokenComma    // ,
+)
+
+func (t TokenType) String() string {
+	switch t {
+	case TokenEOF:
+		return "EOF"
+	case TokenNewline:
+		return "Newline"
+	case TokenIdent:
+		return "Ident"
+	case TokenKeyword:
+		return "Keyword"
+	case TokenInt:
+		return "Int"
+	case TokenReal:
+		return "Real"
+	case TokenString:
+		return "String"
+	case TokenRawcode:
+		return "Rawcode"
+	case TokenAssign:
+		return "="
+	case TokenEq:
+		return "=="
+	case TokenNeq:
+		return "!="
+	case TokenLt:
+		return "<"
+	case TokenLe:
+		return "<="
+	case TokenGt:
+		return ">"
+	case TokenGe:
+		return ">="
+	case TokenPlus:
+		return "+"
+	case TokenMinus:
+		return "-"
+	case TokenMul:
+		return "*"
+	case TokenDiv:
+		return "/"
+	case TokenAnd:
+		return "and"
+	case TokenOr:
+		return "or"
+	case TokenNot:
+		return "not"
+	case TokenLParen:
+		return "("
+	case TokenRParen:
+		return ")"
+	case TokenLBracket:
+		return "["
+	case TokenRBracket:
+		return "]"
+	case TokenComma:
+		return ","
+	default:
+		return fmt.Sprintf("TokenType(0x%02X)", uint8(t))
+	}
+}
+
+// Keywords reserved by the language (excluding the operators and, or, not)
+var Keywords = map[string]struct{}{
+	"globals": {}, "endglobals": {}, "constant": {}, "native": {}, "array": {},
+	"type": {}, "extends": {}, "function": {}, "takes": {}, "returns": {}, "nothing": {}, "endfunction": {},
+	"local": {}, "set": {}, "call": {}, "debug": {}, "return": {},
+	"if": {}, "then": {}, "elseif": {}, "else": {}, "endif": {}, "loop": {}, "exitwhen": {}, "endloop": {},
+	"true": {}, "false": {}, "null": {},
+}
@@ -0,0 +1,160 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package jass implements a lexer and parser for JASS scripts (war3map.j, common.j, blizzard.j).
+//
+// Parse builds a syntax tree of the type declarations, globals, natives, and functions in a script.
+// Inspect traverses the tree, which allows analysis such as finding the settings in config(),
+// detecting cheats, or listing the natives a script requires (see File.Unresolved).
+package jass
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+// Parse JASS source
+func Parse(src string) (*File, error) {
+	toks, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var p = parser{toks: toks}
+	return p.parseFile()
+}
+
+// Read and parse JASS source from r
+func Read(r io.Reader) (*File, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(b))
+}
+
+// Position of the file (start of source)
+func (f *File) Position() Pos {
+	return Pos{Line: 1, Col: 1}
+}
+
+// Function or native declared as name, nil if not found
+func (f *File) Function(name string) *FuncDecl {
+	for _, d := range f.Functions {
+		if d.Name == name {
+			return d
+		}
+	}
+	for _, d := range f.Natives {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// Global variable declared as name, nil if not found
+func (f *File) Global(name string) *VarDecl {
+	for _, v := range f.Globals {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Unresolved lists the names of functions that are called or referenced, but not declared in f
+// For war3map.j, these are the natives and functions it requires from common.j and blizzard.j
+func (f *File) Unresolved() []string {
+	var seen = map[string]bool{}
+	Inspect(f, func(n Node) bool {
+		switch v := n.(type) {
+		case *CallExpr:
+			seen[v.Name] = true
+		case *FuncRef:
+			seen[v.Name] = true
+		}
+		return true
+	})
+
+	var res = []string{}
+	for name := range seen {
+		if f.Function(name) == nil {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Inspect traverses the syntax tree in depth-first order, starting at node
+// fn is called for each node, children are only visited if fn returns true
+func Inspect(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	var stmts = func(l []Stmt) {
+		for _, s := range l {
+			Inspect(s, fn)
+		}
+	}
+	var expr = func(e Expr) {
+		if e != nil {
+			Inspect(e, fn)
+		}
+	}
+
+	switch n := node.(type) {
+	case *File:
+		for _, d := range n.Types {
+			Inspect(d, fn)
+		}
+		for _, v := range n.Globals {
+			Inspect(v, fn)
+		}
+		for _, d := range n.Natives {
+			Inspect(d, fn)
+		}
+		for _, d := range n.Functions {
+			Inspect(d, fn)
+		}
+	case *VarDecl:
+		expr(n.Init)
+	case *FuncDecl:
+		for _, v := range n.Locals {
+			Inspect(v, fn)
+		}
+		stmts(n.Body)
+	case *SetStmt:
+		expr(n.Index)
+		expr(n.Value)
+	case *CallStmt:
+		Inspect(n.Call, fn)
+	case *IfStmt:
+		expr(n.Cond)
+		stmts(n.Then)
+		stmts(n.Else)
+	case *LoopStmt:
+		stmts(n.Body)
+	case *ExitWhenStmt:
+		expr(n.Cond)
+	case *ReturnStmt:
+		expr(n.Value)
+	case *IndexExpr:
+		expr(n.Index)
+	case *CallExpr:
+		for _, a := range n.Args {
+			expr(a)
+		}
+	case *UnaryExpr:
+		expr(n.X)
+	case *BinaryExpr:
+		expr(n.X)
+		expr(n.Y)
+	case *ParenExpr:
+		expr(n.X)
+	}
+}
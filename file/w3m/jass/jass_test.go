@@ -0,0 +1,204 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3m/jass"
+)
+
+var script = `
+// Comment
+type agent extends handle
+type unit  extends agent
+
+constant native GetPlayerId takes player whichPlayer returns integer
+native SetMapName takes string name returns nothing
+
+globals
+    constant integer bj_MAX_PLAYERS = 12
+    unit array       udg_units
+    real             udg_speed     = -.5
+    string           udg_name      = "Foo \"Bar\"\n"
+endglobals
+
+function config takes nothing returns nothing
+    call SetMapName( "TRIGSTR_001" )
+    call SetPlayers( 2 )
+    call SetTeams( 0x2 )
+endfunction
+
+function Loop takes integer n, real r returns integer
+    local integer i = 0
+    local unit array u
+
+    loop
+        exitwhen i >= n
+        set udg_units[i] = null
+        if i == 'hfoo' or not (i > $FF and i < 010) then
+            debug call BJDebugMsg( I2S( i ) )
+        elseif i * 2 + 1 == -n then
+            return i
+        else
+            set i = i + 1
+        endif
+    endloop
+
+    call ForGroup( null, function config )
+    return 1 - 2 - 3
+endfunction
+`
+
+func TestParse(t *testing.T) {
+	f, err := jass.Parse(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.Types) != 2 || f.Types[1].Name != "unit" || f.Types[1].Base != "agent" {
+		t.Fatal("Types mismatch", f.Types)
+	}
+	if len(f.Natives) != 2 || !f.Natives[0].Constant || f.Natives[0].Params[0] != (jass.Param{Type: "player", Name: "whichPlayer"}) {
+		t.Fatal("Natives mismatch", f.Natives)
+	}
+	if len(f.Globals) != 4 || !f.Globals[0].Constant || !f.Globals[1].Array || f.Globals[1].Init != nil {
+		t.Fatal("Globals mismatch", f.Globals)
+	}
+	if u, ok := f.Global("udg_speed").Init.(*jass.UnaryExpr); !ok || u.Op != jass.TokenMinus || u.X.(*jass.RealLit).Value != .5 {
+		t.Fatal("Real mismatch", f.Global("udg_speed").Init)
+	}
+	if lit := f.Global("udg_name").Init.(*jass.StringLit); lit.Value != "Foo \"Bar\"\n" {
+		t.Fatal("String mismatch", lit.Value)
+	}
+
+	var fn = f.Function("Loop")
+	if fn == nil || fn.Returns != "integer" || len(fn.Params) != 2 || len(fn.Locals) != 2 || len(fn.Body) != 3 {
+		t.Fatal("Function mismatch", fn)
+	}
+	if fn.Line != 22 || fn.Col != 1 {
+		t.Fatal("Position mismatch", fn.Pos)
+	}
+
+	var loop = fn.Body[0].(*jass.LoopStmt)
+	if len(loop.Body) != 3 {
+		t.Fatal("Loop mismatch", loop.Body)
+	}
+
+	var cond = loop.Body[2].(*jass.IfStmt)
+	var or = cond.Cond.(*jass.BinaryExpr)
+	if or.Op != jass.TokenOr || or.X.(*jass.BinaryExpr).Y.(*jass.IntLit).Value != 0x68666F6F {
+		t.Fatal("Condition mismatch", or)
+	}
+	var and = or.Y.(*jass.UnaryExpr).X.(*jass.ParenExpr).X.(*jass.BinaryExpr)
+	if and.Op != jass.TokenAnd || and.X.(*jass.BinaryExpr).Y.(*jass.IntLit).Value != 255 || and.Y.(*jass.BinaryExpr).Y.(*jass.IntLit).Value != 8 {
+		t.Fatal("Operand mismatch", and)
+	}
+	if s := cond.Then[0].(*jass.CallStmt); !s.Debug || s.Call.Name != "BJDebugMsg" {
+		t.Fatal("Debug mismatch", s)
+	}
+
+	var elseif = cond.Else[0].(*jass.IfStmt)
+	var eq = elseif.Cond.(*jass.BinaryExpr)
+	if eq.Op != jass.TokenEq || eq.X.(*jass.BinaryExpr).Op != jass.TokenPlus || eq.X.(*jass.BinaryExpr).X.(*jass.BinaryExpr).Op != jass.TokenMul {
+		t.Fatal("Precedence mismatch", eq)
+	}
+	if len(elseif.Else) != 1 {
+		t.Fatal("Else mismatch", elseif.Else)
+	}
+
+	// Left associative
+	var sub = fn.Body[2].(*jass.ReturnStmt).Value.(*jass.BinaryExpr)
+	if sub.Y.(*jass.IntLit).Value != 3 || sub.X.(*jass.BinaryExpr).Y.(*jass.IntLit).Value != 2 {
+		t.Fatal("Associativity mismatch", sub)
+	}
+
+	if u := f.Unresolved(); !reflect.DeepEqual(u, []string{"BJDebugMsg", "ForGroup", "I2S", "SetPlayers", "SetTeams"}) {
+		t.Fatal("Unresolved mismatch", u)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var files = []struct {
+		src  string
+		err  error
+		line int
+		col  int
+	}{
+		{"globals\n  integer i = 1 +\nendglobals\n", jass.ErrUnexpected, 2, 18},
+		{"function f takes nothing returns nothing\n  call f(\"foo)\nendfunction", jass.ErrUnterminated, 2, 10},
+		{"function f takes nothing returns nothing\n  set i = 'abc'\nendfunction", jass.ErrBadLiteral, 2, 11},
+		{"function f takes nothing returns nothing\n  set i = 1 ? 2\nendfunction", jass.ErrUnexpectedChar, 2, 13},
+		{"function f takes nothing returns nothing\n  local integer i\n  set i = 1\n  local integer j\nendfunction", jass.ErrUnexpected, 4, 3},
+		{"function f takes nothing returns nothing\n  debug exitwhen true\nendfunction", jass.ErrUnexpected, 2, 9},
+		{"function f takes nothing returns nothing\n  if true then\n  endloop\nendfunction", jass.ErrUnexpected, 3, 3},
+		{"native f takes nothing returns nothing", nil, 0, 0},
+	}
+
+	for _, f := range files {
+		_, err := jass.Parse(f.src)
+		if f.err == nil {
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		e, ok := err.(*jass.Error)
+		if !ok || e.Err != f.err || e.Line != f.line || e.Col != f.col {
+			t.Fatalf("Error mismatch for %q: %v\n", f.src, err)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	toks, err := jass.Tokenize("set x=a[1]!=.5 // c\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []jass.TokenType
+	for _, t := range toks {
+		types = append(types, t.Type)
+	}
+
+	var expected = []jass.TokenType{
+		jass.TokenKeyword, jass.TokenIdent, jass.TokenAssign, jass.TokenIdent, jass.TokenLBracket, jass.TokenInt,
+		jass.TokenRBracket, jass.TokenNeq, jass.TokenReal, jass.TokenNewline, jass.TokenEOF,
+	}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatal("Token mismatch", types)
+	}
+}
+
+func Example() {
+	f, err := jass.Parse(script)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Find map settings in config()
+	jass.Inspect(f.Function("config"), func(n jass.Node) bool {
+		c, ok := n.(*jass.CallExpr)
+		if !ok {
+			return true
+		}
+		switch a := c.Args[0].(type) {
+		case *jass.StringLit:
+			fmt.Printf("%s(%q)\n", c.Name, a.Value)
+		case *jass.IntLit:
+			fmt.Printf("%s(%d)\n", c.Name, a.Value)
+		}
+		return false
+	})
+
+	// Output:
+	// SetMapName("TRIGSTR_001")
+	// SetPlayers(2)
+	// SetTeams(2)
+}
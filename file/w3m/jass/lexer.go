@@ -0,0 +1,229 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass
+
+import (
+	"strings"
+)
+
+// Pos of a token or node in the source (1-based)
+type Pos struct {
+	Line int
+	Col  int
+}
+
+// Position of the token or node
+func (p Pos) Position() Pos {
+	return p
+}
+
+// Token in JASS source
+type Token struct {
+	Pos
+	Type  TokenType
+	Value string // Source text, content for strings and rawcodes
+}
+
+// Lexer splits JASS source into tokens
+type Lexer struct {
+	src  string
+	off  int
+	line int
+	col  int
+}
+
+// NewLexer initializes a Lexer for src
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: src, line: 1, col: 1}
+}
+
+// Tokenize splits src into tokens, terminated by a TokenEOF
+func Tokenize(src string) ([]Token, error) {
+	var l = NewLexer(src)
+	var res []Token
+	for {
+		t, err := l.Next()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+		if t.Type == TokenEOF {
+			return res, nil
+		}
+	}
+}
+
+func (l *Lexer) peek(n int) byte {
+	if l.off+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.off+n]
+}
+
+func (l *Lexer) advance() byte {
+	var c = l.src[l.off]
+	l.off++
+	if c == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return c
+}
+
+func (l *Lexer) errorf(pos Pos, err error) error {
+	return &Error{Pos: pos, Err: err}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+var operators = map[string]TokenType{
+	"==": TokenEq, "!=": TokenNeq, "<=": TokenLe, ">=": TokenGe,
+	"=": TokenAssign, "<": TokenLt, ">": TokenGt,
+	"+": TokenPlus, "-": TokenMinus, "*": TokenMul, "/": TokenDiv,
+	"(": TokenLParen, ")": TokenRParen, "[": TokenLBracket, "]": TokenRBracket, ",": TokenComma,
+}
+
+// Next token in source, returns TokenEOF at the end
+func (l *Lexer) Next() (Token, error) {
+	// Skip whitespace and comments
+	for l.off < len(l.src) {
+		var c = l.peek(0)
+		if c == ' ' || c == '\t' || c == '\r' || c == 0x1A {
+			l.advance()
+		} else if c == '/' && l.peek(1) == '/' {
+			for l.off < len(l.src) && l.peek(0) != '\n' {
+				l.advance()
+			}
+		} else {
+			break
+		}
+	}
+
+	var pos = Pos{Line: l.line, Col: l.col}
+	if l.off >= len(l.src) {
+		return Token{Pos: pos, Type: TokenEOF}, nil
+	}
+
+	var start = l.off
+	var c = l.advance()
+	switch {
+	case c == '\n':
+		return Token{Pos: pos, Type: TokenNewline, Value: "\n"}, nil
+
+	case isLetter(c):
+		for isLetter(l.peek(0)) || isDigit(l.peek(0)) {
+			l.advance()
+		}
+
+		var word = l.src[start:l.off]
+		switch word {
+		case "and":
+			return Token{Pos: pos, Type: TokenAnd, Value: word}, nil
+		case "or":
+			return Token{Pos: pos, Type: TokenOr, Value: word}, nil
+		case "not":
+			return Token{Pos: pos, Type: TokenNot, Value: word}, nil
+		}
+		if _, ok := Keywords[word]; ok {
+			return Token{Pos: pos, Type: TokenKeyword, Value: word}, nil
+		}
+		return Token{Pos: pos, Type: TokenIdent, Value: word}, nil
+
+	case isDigit(c) || (c == '.' && isDigit(l.peek(0))) || c == '$':
+		var typ = TokenInt
+		switch {
+		case c == '$' || (c == '0' && (l.peek(0) == 'x' || l.peek(0) == 'X')):
+			if c == '0' {
+				l.advance()
+			}
+			if !isHexDigit(l.peek(0)) {
+				return Token{}, l.errorf(pos, ErrBadLiteral)
+			}
+			for isHexDigit(l.peek(0)) {
+				l.advance()
+			}
+		default:
+			if c == '.' {
+				typ = TokenReal
+			}
+			for isDigit(l.peek(0)) || (typ == TokenInt && l.peek(0) == '.') {
+				if l.advance() == '.' {
+					typ = TokenReal
+				}
+			}
+		}
+		if isLetter(l.peek(0)) {
+			return Token{}, l.errorf(pos, ErrBadLiteral)
+		}
+		return Token{Pos: pos, Type: typ, Value: l.src[start:l.off]}, nil
+
+	case c == '"':
+		var sb strings.Builder
+		for {
+			if l.off >= len(l.src) {
+				return Token{}, l.errorf(pos, ErrUnterminated)
+			}
+			var c = l.advance()
+			if c == '"' {
+				break
+			}
+			if c == '\\' && l.off < len(l.src) {
+				switch e := l.advance(); e {
+				case 'n':
+					c = '\n'
+				case 'r':
+					c = '\r'
+				case 't':
+					c = '\t'
+				case 'b':
+					c = '\b'
+				case 'f':
+					c = '\f'
+				default:
+					c = e
+				}
+			}
+			sb.WriteByte(c)
+		}
+		return Token{Pos: pos, Type: TokenString, Value: sb.String()}, nil
+
+	case c == '\'':
+		var end = strings.IndexByte(l.src[l.off:], '\'')
+		if end < 0 {
+			return Token{}, l.errorf(pos, ErrUnterminated)
+		}
+		for i := 0; i <= end; i++ {
+			l.advance()
+		}
+
+		var val = l.src[start+1 : l.off-1]
+		if len(val) != 1 && len(val) != 4 {
+			return Token{}, l.errorf(pos, ErrBadLiteral)
+		}
+		return Token{Pos: pos, Type: TokenRawcode, Value: val}, nil
+	}
+
+	if t, ok := operators[l.src[start:start+1]+string(l.peek(0))]; ok && l.peek(0) != 0 {
+		l.advance()
+		return Token{Pos: pos, Type: t, Value: l.src[start:l.off]}, nil
+	}
+	if t, ok := operators[l.src[start:l.off]]; ok {
+		return Token{Pos: pos, Type: t, Value: l.src[start:l.off]}, nil
+	}
+
+	return Token{}, l.errorf(pos, ErrUnexpectedChar)
+}
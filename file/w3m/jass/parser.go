@@ -0,0 +1,641 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass
+
+import (
+	"strconv"
+)
+
+type parser struct {
+	toks []Token
+	pos  int
+}
+
+func (p *parser) peek(n int) Token {
+	if p.pos+n >= len(p.toks) {
+		return p.toks[len(p.toks)-1]
+	}
+	return p.toks[p.pos+n]
+}
+
+func (p *parser) next() Token {
+	var t = p.toks[p.pos]
+	if t.Type != TokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(t TokenType) bool {
+	return p.peek(0).Type == t
+}
+
+func (p *parser) keyword(kw string) bool {
+	var t = p.peek(0)
+	return t.Type == TokenKeyword && t.Value == kw
+}
+
+func (p *parser) unexpected() error {
+	var t = p.peek(0)
+	return &Error{Pos: t.Pos, Err: ErrUnexpected, Near: t.Value}
+}
+
+func (p *parser) expect(t TokenType) (Token, error) {
+	if !p.is(t) {
+		return Token{}, p.unexpected()
+	}
+	return p.next(), nil
+}
+
+func (p *parser) expectKeyword(kw string) (Token, error) {
+	if !p.keyword(kw) {
+		return Token{}, p.unexpected()
+	}
+	return p.next(), nil
+}
+
+func (p *parser) skipNewlines() {
+	for p.is(TokenNewline) {
+		p.next()
+	}
+}
+
+// newline terminates a line, followed by any number of empty lines
+func (p *parser) newline() error {
+	if p.is(TokenEOF) {
+		return nil
+	}
+	if _, err := p.expect(TokenNewline); err != nil {
+		return err
+	}
+	p.skipNewlines()
+	return nil
+}
+
+// typeName is an identifier, or nothing if allowNothing is set
+func (p *parser) typeName(allowNothing bool) (string, error) {
+	if allowNothing && p.keyword("nothing") {
+		return p.next().Value, nil
+	}
+	t, err := p.expect(TokenIdent)
+	return t.Value, err
+}
+
+func (p *parser) parseFile() (*File, error) {
+	var f File
+
+	p.skipNewlines()
+	for !p.is(TokenEOF) {
+		var t = p.peek(0)
+		if t.Type != TokenKeyword {
+			return nil, p.unexpected()
+		}
+
+		switch t.Value {
+		case "type":
+			d, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			f.Types = append(f.Types, d)
+		case "globals":
+			g, err := p.parseGlobals()
+			if err != nil {
+				return nil, err
+			}
+			f.Globals = append(f.Globals, g...)
+		case "constant", "native", "function":
+			d, err := p.parseFunc()
+			if err != nil {
+				return nil, err
+			}
+			if d.Native {
+				f.Natives = append(f.Natives, d)
+			} else {
+				f.Functions = append(f.Functions, d)
+			}
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	return &f, nil
+}
+
+func (p *parser) parseType() (*TypeDecl, error) {
+	var d = TypeDecl{Pos: p.next().Pos}
+
+	var err error
+	if d.Name, err = p.typeName(false); err != nil {
+		return nil, err
+	}
+	if _, err := p.expectKeyword("extends"); err != nil {
+		return nil, err
+	}
+	if d.Base, err = p.typeName(false); err != nil {
+		return nil, err
+	}
+
+	return &d, p.newline()
+}
+
+func (p *parser) parseGlobals() ([]*VarDecl, error) {
+	p.next()
+	if err := p.newline(); err != nil {
+		return nil, err
+	}
+
+	var res []*VarDecl
+	for !p.keyword("endglobals") {
+		var pos = p.peek(0).Pos
+		var constant = p.keyword("constant")
+		if constant {
+			p.next()
+		}
+
+		v, err := p.parseVar(pos)
+		if err != nil {
+			return nil, err
+		}
+
+		v.Constant = constant
+		res = append(res, v)
+	}
+
+	p.next()
+	return res, p.newline()
+}
+
+// parseVar parses a variable declaration starting at its type
+func (p *parser) parseVar(pos Pos) (*VarDecl, error) {
+	var v = VarDecl{Pos: pos}
+
+	var err error
+	if v.Type, err = p.typeName(false); err != nil {
+		return nil, err
+	}
+	if p.keyword("array") {
+		p.next()
+		v.Array = true
+	}
+
+	t, err := p.expect(TokenIdent)
+	if err != nil {
+		return nil, err
+	}
+	v.Name = t.Value
+
+	if p.is(TokenAssign) {
+		p.next()
+		if v.Init, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &v, p.newline()
+}
+
+func (p *parser) parseFunc() (*FuncDecl, error) {
+	var d = FuncDecl{Pos: p.peek(0).Pos}
+	if p.keyword("constant") {
+		p.next()
+		d.Constant = true
+	}
+
+	switch {
+	case p.keyword("native"):
+		d.Native = true
+	case p.keyword("function"):
+	default:
+		return nil, p.unexpected()
+	}
+	p.next()
+
+	t, err := p.expect(TokenIdent)
+	if err != nil {
+		return nil, err
+	}
+	d.Name = t.Value
+
+	if _, err := p.expectKeyword("takes"); err != nil {
+		return nil, err
+	}
+	if p.keyword("nothing") {
+		p.next()
+	} else {
+		for {
+			var param Param
+			if param.Type, err = p.typeName(false); err != nil {
+				return nil, err
+			}
+			t, err := p.expect(TokenIdent)
+			if err != nil {
+				return nil, err
+			}
+			param.Name = t.Value
+			d.Params = append(d.Params, param)
+
+			if !p.is(TokenComma) {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if _, err := p.expectKeyword("returns"); err != nil {
+		return nil, err
+	}
+	if d.Returns, err = p.typeName(true); err != nil {
+		return nil, err
+	}
+	if err := p.newline(); err != nil {
+		return nil, err
+	}
+	if d.Native {
+		return &d, nil
+	}
+
+	for p.keyword("local") {
+		v, err := p.parseVar(p.next().Pos)
+		if err != nil {
+			return nil, err
+		}
+		d.Locals = append(d.Locals, v)
+	}
+
+	if d.Body, err = p.parseBlock("endfunction"); err != nil {
+		return nil, err
+	}
+
+	p.next()
+	return &d, p.newline()
+}
+
+// parseBlock parses statements up to (excluding) one of the terminating keywords
+func (p *parser) parseBlock(end ...string) ([]Stmt, error) {
+	var res = []Stmt{}
+	for {
+		for _, kw := range end {
+			if p.keyword(kw) {
+				return res, nil
+			}
+		}
+
+		s, err := p.parseStmt()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+}
+
+func (p *parser) parseStmt() (Stmt, error) {
+	var pos = p.peek(0).Pos
+	var debug = p.keyword("debug")
+	if debug {
+		p.next()
+	}
+
+	var t = p.peek(0)
+	if t.Type != TokenKeyword {
+		return nil, p.unexpected()
+	}
+
+	switch t.Value {
+	case "set":
+		p.next()
+		var s = SetStmt{Pos: pos, Debug: debug}
+
+		n, err := p.expect(TokenIdent)
+		if err != nil {
+			return nil, err
+		}
+		s.Name = n.Value
+
+		if p.is(TokenLBracket) {
+			p.next()
+			if s.Index, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(TokenRBracket); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := p.expect(TokenAssign); err != nil {
+			return nil, err
+		}
+		if s.Value, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		return &s, p.newline()
+
+	case "call":
+		p.next()
+		n, err := p.expect(TokenIdent)
+		if err != nil {
+			return nil, err
+		}
+		c, err := p.parseCall(n)
+		if err != nil {
+			return nil, err
+		}
+		return &CallStmt{Pos: pos, Debug: debug, Call: c}, p.newline()
+
+	case "if":
+		p.next()
+		s, err := p.parseIf(pos)
+		if err != nil {
+			return nil, err
+		}
+		s.Debug = debug
+		return s, nil
+
+	case "loop":
+		p.next()
+		if err := p.newline(); err != nil {
+			return nil, err
+		}
+		body, err := p.parseBlock("endloop")
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+		return &LoopStmt{Pos: pos, Debug: debug, Body: body}, p.newline()
+	}
+
+	if debug {
+		return nil, p.unexpected()
+	}
+
+	switch t.Value {
+	case "exitwhen":
+		p.next()
+		c, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &ExitWhenStmt{Pos: pos, Cond: c}, p.newline()
+
+	case "return":
+		p.next()
+		var s = ReturnStmt{Pos: pos}
+		if !p.is(TokenNewline) && !p.is(TokenEOF) {
+			var err error
+			if s.Value, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		return &s, p.newline()
+	}
+
+	return nil, p.unexpected()
+}
+
+// parseIf parses an if (or elseif) statement after the keyword, up to and including endif
+func (p *parser) parseIf(pos Pos) (*IfStmt, error) {
+	var s = IfStmt{Pos: pos}
+
+	var err error
+	if s.Cond, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if _, err := p.expectKeyword("then"); err != nil {
+		return nil, err
+	}
+	if err := p.newline(); err != nil {
+		return nil, err
+	}
+	if s.Then, err = p.parseBlock("elseif", "else", "endif"); err != nil {
+		return nil, err
+	}
+
+	switch p.next().Value {
+	case "elseif":
+		elseif, err := p.parseIf(p.toks[p.pos-1].Pos)
+		if err != nil {
+			return nil, err
+		}
+		s.Else = []Stmt{elseif}
+		return &s, nil
+	case "else":
+		if err := p.newline(); err != nil {
+			return nil, err
+		}
+		if s.Else, err = p.parseBlock("endif"); err != nil {
+			return nil, err
+		}
+		p.next()
+	}
+
+	return &s, p.newline()
+}
+
+func (p *parser) parseCall(name Token) (*CallExpr, error) {
+	var c = CallExpr{Pos: name.Pos, Name: name.Value, Args: []Expr{}}
+	if _, err := p.expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	if p.is(TokenRParen) {
+		p.next()
+		return &c, nil
+	}
+
+	for {
+		a, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.Args = append(c.Args, a)
+
+		if !p.is(TokenComma) {
+			break
+		}
+		p.next()
+	}
+
+	if _, err := p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// parseBinary parses a left-associative chain of ops, with operands parsed by sub
+func (p *parser) parseBinary(sub func() (Expr, error), ops ...TokenType) (Expr, error) {
+	x, err := sub()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var t = p.peek(0)
+
+		var match bool
+		for _, op := range ops {
+			match = match || t.Type == op
+		}
+		if !match {
+			return x, nil
+		}
+
+		p.next()
+		y, err := sub()
+		if err != nil {
+			return nil, err
+		}
+		x = &BinaryExpr{Pos: t.Pos, Op: t.Type, X: x, Y: y}
+	}
+}
+
+// parseExpr parses an expression, operators ordered by increasing precedence:
+// or, and, not, comparison (== != < <= > >=), additive (+ -), multiplicative (* /), unary (+ - not)
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseBinary(p.parseAnd, TokenOr)
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	return p.parseBinary(p.parseNot, TokenAnd)
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if !p.is(TokenNot) {
+		return p.parseComparison()
+	}
+
+	var t = p.next()
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryExpr{Pos: t.Pos, Op: t.Type, X: x}, nil
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	return p.parseBinary(p.parseAdditive, TokenEq, TokenNeq, TokenLt, TokenLe, TokenGt, TokenGe)
+}
+
+func (p *parser) parseAdditive() (Expr, error) {
+	return p.parseBinary(p.parseMultiplicative, TokenPlus, TokenMinus)
+}
+
+func (p *parser) parseMultiplicative() (Expr, error) {
+	return p.parseBinary(p.parseUnary, TokenMul, TokenDiv)
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch p.peek(0).Type {
+	case TokenPlus, TokenMinus, TokenNot:
+		var t = p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Pos: t.Pos, Op: t.Type, X: x}, nil
+	default:
+		return p.parsePrimary()
+	}
+}
+
+func parseInt(s string) (int32, bool) {
+	var base = 10
+	switch {
+	case s[0] == '$':
+		s = s[1:]
+		base = 16
+	case len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X'):
+		s = s[2:]
+		base = 16
+	case len(s) > 1 && s[0] == '0':
+		base = 8
+	}
+
+	// Integers wrap around on overflow
+	v, err := strconv.ParseUint(s, base, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int32(uint32(v)), true
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	var t = p.peek(0)
+	switch t.Type {
+	case TokenInt:
+		p.next()
+		v, ok := parseInt(t.Value)
+		if !ok {
+			return nil, &Error{Pos: t.Pos, Err: ErrBadLiteral, Near: t.Value}
+		}
+		return &IntLit{Pos: t.Pos, Raw: t.Value, Value: v}, nil
+
+	case TokenRawcode:
+		p.next()
+		var v uint32
+		for i := 0; i < len(t.Value); i++ {
+			v = v<<8 | uint32(t.Value[i])
+		}
+		return &IntLit{Pos: t.Pos, Raw: "'" + t.Value + "'", Value: int32(v)}, nil
+
+	case TokenReal:
+		p.next()
+		v, err := strconv.ParseFloat(t.Value, 32)
+		if err != nil {
+			return nil, &Error{Pos: t.Pos, Err: ErrBadLiteral, Near: t.Value}
+		}
+		return &RealLit{Pos: t.Pos, Raw: t.Value, Value: float32(v)}, nil
+
+	case TokenString:
+		p.next()
+		return &StringLit{Pos: t.Pos, Value: t.Value}, nil
+
+	case TokenLParen:
+		p.next()
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(TokenRParen); err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Pos: t.Pos, X: x}, nil
+
+	case TokenIdent:
+		p.next()
+		switch p.peek(0).Type {
+		case TokenLParen:
+			return p.parseCall(t)
+		case TokenLBracket:
+			p.next()
+			i, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(TokenRBracket); err != nil {
+				return nil, err
+			}
+			return &IndexExpr{Pos: t.Pos, Name: t.Value, Index: i}, nil
+		default:
+			return &Ident{Pos: t.Pos, Name: t.Value}, nil
+		}
+
+	case TokenKeyword:
+		switch t.Value {
+		case "true", "false":
+			p.next()
+			return &BoolLit{Pos: t.Pos, Value: t.Value == "true"}, nil
+		case "null":
+			p.next()
+			return &NullLit{Pos: t.Pos}, nil
+		case "function":
+			p.next()
+			n, err := p.expect(TokenIdent)
+			if err != nil {
+				return nil, err
+			}
+			return &FuncRef{Pos: t.Pos, Name: n.Value}, nil
+		}
+	}
+
+	return nil, p.unexpected()
+}
@@ -0,0 +1,336 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"strconv"
+	"strings"
+)
+
+type luaTokenType uint8
+
+const (
+	luaName luaTokenType = iota
+	luaNumber
+	luaString
+	luaSymbol
+)
+
+type luaToken struct {
+	typ luaTokenType
+	val string
+}
+
+// luaLongBracket returns the length of the opening long bracket ([[, [=[, ...) at the start of s, 0 if none
+func luaLongBracket(s string) int {
+	if len(s) < 2 || s[0] != '[' {
+		return 0
+	}
+	var n = 1
+	for n < len(s) && s[n] == '=' {
+		n++
+	}
+	if n < len(s) && s[n] == '[' {
+		return n + 1
+	}
+	return 0
+}
+
+// luaTokenize splits Lua source into tokens, comments are skipped
+func luaTokenize(src string) ([]luaToken, error) {
+	var res []luaToken
+	for i := 0; i < len(src); {
+		var c = src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+
+		case strings.HasPrefix(src[i:], "--"):
+			i += 2
+			if n := luaLongBracket(src[i:]); n > 0 {
+				var end = strings.Index(src[i+n:], "]"+strings.Repeat("=", n-2)+"]")
+				if end < 0 {
+					return nil, ErrBadFormat
+				}
+				i += n + end + n
+			} else {
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+			}
+
+		case isLetter(c):
+			var start = i
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			res = append(res, luaToken{typ: luaName, val: src[start:i]})
+
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			var start = i
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i]) || src[i] == '.' ||
+				((src[i] == '-' || src[i] == '+') && (src[i-1] == 'e' || src[i-1] == 'E' || src[i-1] == 'p' || src[i-1] == 'P'))) {
+				i++
+			}
+			res = append(res, luaToken{typ: luaNumber, val: src[start:i]})
+
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) || src[i] == '\n' {
+					return nil, ErrBadFormat
+				}
+				if src[i] == c {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 'r':
+						sb.WriteByte('\r')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[i])
+					}
+					continue
+				}
+				sb.WriteByte(src[i])
+			}
+			res = append(res, luaToken{typ: luaString, val: sb.String()})
+
+		case luaLongBracket(src[i:]) > 0:
+			var n = luaLongBracket(src[i:])
+			var end = strings.Index(src[i+n:], "]"+strings.Repeat("=", n-2)+"]")
+			if end < 0 {
+				return nil, ErrBadFormat
+			}
+			res = append(res, luaToken{typ: luaString, val: strings.TrimPrefix(src[i+n:i+n+end], "\n")})
+			i += n + end + n
+
+		default:
+			var n = 1
+			for _, op := range []string{"...", "..", "==", "~=", "<=", ">=", "//", "::", "<<", ">>"} {
+				if strings.HasPrefix(src[i:], op) {
+					n = len(op)
+					break
+				}
+			}
+			res = append(res, luaToken{typ: luaSymbol, val: src[i : i+n]})
+			i += n
+		}
+	}
+	return res, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+var luaKeywords = map[string]struct{}{
+	"and": {}, "break": {}, "else": {}, "elseif": {}, "for": {}, "goto": {}, "in": {}, "local": {},
+	"nil": {}, "not": {}, "or": {}, "return": {}, "then": {}, "while": {}, "true": {}, "false": {},
+}
+
+type luaParser struct {
+	toks []luaToken
+	pos  int
+}
+
+func (p *luaParser) peek(n int) luaToken {
+	if p.pos+n >= len(p.toks) {
+		return luaToken{typ: luaSymbol}
+	}
+	return p.toks[p.pos+n]
+}
+
+func (p *luaParser) symbol(s string) bool {
+	var t = p.peek(0)
+	return t.typ == luaSymbol && t.val == s
+}
+
+// skipArg skips tokens up to the next top-level ',' or ')'
+func (p *luaParser) skipArg() {
+	var depth int
+	for p.pos < len(p.toks) {
+		var t = p.toks[p.pos]
+		if t.typ == luaSymbol {
+			switch t.val {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth == 0 {
+					return
+				}
+				depth--
+			case ",":
+				if depth == 0 {
+					return
+				}
+			}
+		}
+		p.pos++
+	}
+}
+
+// arg parses a literal, identifier, or call as argument, nil for any other expression
+func (p *luaParser) arg() interface{} {
+	var start = p.pos
+	var neg = p.symbol("-")
+	if neg {
+		p.pos++
+	}
+
+	var res interface{}
+	var t = p.peek(0)
+	switch t.typ {
+	case luaNumber:
+		p.pos++
+		res = luaNumberValue(t.val, neg)
+	case luaString:
+		p.pos++
+		res = t.val
+	case luaName:
+		p.pos++
+		switch {
+		case t.val == "true" || t.val == "false":
+			res = t.val == "true"
+		case t.val == "nil":
+			res = nil
+		case p.symbol("("):
+			res = p.call(t.val)
+		default:
+			res = scriptIdent(t.val)
+		}
+	}
+
+	if !p.symbol(",") && !p.symbol(")") || (neg && t.typ != luaNumber) {
+		p.pos = start
+		p.skipArg()
+		return nil
+	}
+	return res
+}
+
+// call parses the arguments of a call to name, starting at the opening parenthesis
+func (p *luaParser) call(name string) *scriptCall {
+	var c = scriptCall{name: name}
+
+	p.pos++
+	for !p.symbol(")") && p.pos < len(p.toks) {
+		c.args = append(c.args, p.arg())
+		if p.symbol(",") {
+			p.pos++
+		}
+	}
+	p.pos++
+
+	return &c
+}
+
+func luaNumberValue(s string, neg bool) interface{} {
+	var l = strings.ToLower(s)
+	if !strings.HasPrefix(l, "0x") && strings.ContainsAny(l, ".e") {
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return nil
+		}
+		if neg {
+			f = -f
+		}
+		return float32(f)
+	}
+
+	i, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return nil
+	}
+	if neg {
+		i = -i
+	}
+	return int32(i)
+}
+
+// luaFunctions extracts the calls made in each global function of a Lua script
+func luaFunctions(src string) (map[string][]*scriptCall, error) {
+	toks, err := luaTokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var p = luaParser{toks: toks}
+	var res = map[string][]*scriptCall{}
+
+	// Block stack, with function names for function blocks
+	var stack []string
+	var fn = func() string {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i] != "" {
+				return stack[i]
+			}
+		}
+		return ""
+	}
+
+	for p.pos < len(p.toks) {
+		var t = p.toks[p.pos]
+		if t.typ != luaName {
+			p.pos++
+			continue
+		}
+
+		switch t.val {
+		case "function":
+			p.pos++
+
+			// Anonymous functions are attributed to the enclosing function
+			var name = fn()
+			if n := p.peek(0); n.typ == luaName && p.peek(1).val == "(" {
+				name = n.val
+			}
+			stack = append(stack, name)
+			if _, ok := res[name]; !ok && name != "" {
+				res[name] = []*scriptCall{}
+			}
+
+			// Skip name and parameters
+			for p.pos < len(p.toks) && !p.symbol(")") {
+				p.pos++
+			}
+		case "if", "do", "repeat":
+			stack = append(stack, "")
+		case "end", "until":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			var prev luaToken
+			if p.pos > 0 {
+				prev = p.toks[p.pos-1]
+			}
+			if _, kw := luaKeywords[t.val]; kw {
+				break
+			}
+			if p.peek(1).typ == luaSymbol && p.peek(1).val == "(" && !(prev.typ == luaSymbol && (prev.val == "." || prev.val == ":")) {
+				p.pos++
+				var c = p.call(t.val)
+				if name := fn(); name != "" {
+					res[name] = append(res[name], c)
+				}
+				continue
+			}
+		}
+		p.pos++
+	}
+
+	return res, nil
+}
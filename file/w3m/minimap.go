@@ -10,14 +10,14 @@ import (
 	"image/draw"
 	"io"
 
-	"github.com/ftrvxmtrx/tga"
 	"github.com/nielsAD/gowarcraft3/file/blp"
+	"github.com/nielsAD/gowarcraft3/file/tga"
 	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
 // Preview returns a preview image
 func (m *Map) Preview() (image.Image, error) {
-	f, err := m.Archive.Open("war3mapPreview.tga")
+	f, err := m.open("war3mapPreview.tga")
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +28,7 @@ func (m *Map) Preview() (image.Image, error) {
 
 // Minimap returns an image with the minimap
 func (m *Map) Minimap() (image.Image, error) {
-	f, err := m.Archive.Open("war3mapMap.blp")
+	f, err := m.open("war3mapMap.blp")
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +39,7 @@ func (m *Map) Minimap() (image.Image, error) {
 
 // MinimapIcons returns an image with (just the) minimap icons
 func (m *Map) MinimapIcons() (image.Image, error) {
-	mmp, err := m.Archive.Open("war3map.mmp")
+	mmp, err := m.open("war3map.mmp")
 	if err != nil {
 		return nil, err
 	}
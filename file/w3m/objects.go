@@ -0,0 +1,389 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"io"
+	"os"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Objects modified or added by the map as found in the war3map.w3u/w3t/w3b/w3d/w3a/w3h/w3q files
+type Objects struct {
+	Type       ObjectType
+	FileFormat uint32
+	Original   []Object // Modified base objects
+	Custom     []Object // New objects derived from base objects
+}
+
+// Object structure in object modification files
+type Object struct {
+	BaseID        protocol.DWordString
+	ID            protocol.DWordString // Equal to BaseID for original objects
+	Modifications []Modification
+}
+
+// Modification of a single object field
+type Modification struct {
+	ID     protocol.DWordString
+	Type   ValueType
+	Level  uint32 // Level or variation (doodads, abilities, upgrades)
+	Column uint32 // Data column (abilities, upgrades)
+	Set    uint32 // Data set flags (Reforged)
+	Value  interface{}
+}
+
+// ObjectField identifies a field of an object
+type ObjectField struct {
+	ID    protocol.DWordString
+	Level uint32
+}
+
+// ObjectFields maps object fields to their value (int32, float32, or string)
+type ObjectFields map[ObjectField]interface{}
+
+// ObjectData maps object IDs to their fields, with field IDs as found in the object meta data (i.e. UnitMetaData.slk)
+type ObjectData map[protocol.DWordString]ObjectFields
+
+const objectVersionReforged = 3
+
+// Objects read from war3map.w3u/w3t/w3b/w3d/w3a/w3h/w3q, depending on t
+func (m *Map) Objects(t ObjectType) (*Objects, error) {
+	f, err := m.open("war3map." + t.Extension())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, f); err != nil {
+		return nil, err
+	}
+
+	o, err := decodeObjects(&b, t)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, objs := range [][]Object{o.Original, o.Custom} {
+		for i := range objs {
+			for j := range objs[i].Modifications {
+				var mod = &objs[i].Modifications[j]
+				if s, ok := mod.Value.(string); ok {
+					if mod.Value, err = m.ExpandString(s); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	return o, nil
+}
+
+// ObjectData merges the map's object modifications of all types with base (see Objects.Merge)
+func (m *Map) ObjectData(base map[ObjectType]ObjectData) (map[ObjectType]ObjectData, error) {
+	var res = map[ObjectType]ObjectData{}
+	for t := ObjectUnit; t <= ObjectUpgrade; t++ {
+		o, err := m.Objects(t)
+		switch err {
+		case nil:
+			res[t] = o.Merge(base[t])
+		case os.ErrNotExist:
+			res[t] = ObjectData{}.Merge(base[t])
+		default:
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Merge copies base and applies the modifications in o
+// Custom objects inherit the fields of their base object
+func (o *Objects) Merge(base ObjectData) ObjectData {
+	var res = ObjectData{}.Merge(base)
+
+	for _, obj := range o.Original {
+		var f = res[obj.ID]
+		if f == nil {
+			f = ObjectFields{}
+			res[obj.ID] = f
+		}
+		f.apply(obj.Modifications)
+	}
+
+	for _, obj := range o.Custom {
+		var f = ObjectFields{}.Merge(res[obj.BaseID])
+		f.apply(obj.Modifications)
+		res[obj.ID] = f
+	}
+
+	return res
+}
+
+// Merge copies the objects of other into d
+func (d ObjectData) Merge(other ObjectData) ObjectData {
+	for id, f := range other {
+		d[id] = ObjectFields{}.Merge(f)
+	}
+	return d
+}
+
+// Merge copies the fields of other into f
+func (f ObjectFields) Merge(other ObjectFields) ObjectFields {
+	for k, v := range other {
+		f[k] = v
+	}
+	return f
+}
+
+func (f ObjectFields) apply(mods []Modification) {
+	for _, mod := range mods {
+		f[ObjectField{ID: mod.ID, Level: mod.Level}] = mod.Value
+	}
+}
+
+func readModifications(b *protocol.Buffer, levels bool, set uint32, res []Modification) ([]Modification, error) {
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	var minSize = 12
+	if levels {
+		minSize += 8
+	}
+
+	var num = b.ReadUInt32()
+	if uint64(num)*uint64(minSize) > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	for i := uint32(0); i < num; i++ {
+		if b.Size() < minSize {
+			return nil, ErrBadFormat
+		}
+
+		var mod = Modification{
+			ID:  b.ReadLEDString(),
+			Set: set,
+		}
+		mod.Type = ValueType(b.ReadUInt32())
+		if levels {
+			mod.Level = b.ReadUInt32()
+			mod.Column = b.ReadUInt32()
+		}
+
+		switch mod.Type {
+		case ValueInt:
+			mod.Value = int32(b.ReadUInt32())
+		case ValueReal, ValueUnreal:
+			mod.Value = b.ReadFloat32()
+		case ValueString:
+			s, err := b.ReadCString()
+			if err != nil {
+				return nil, err
+			}
+			mod.Value = s
+		default:
+			return nil, ErrBadFormat
+		}
+
+		// End of modification (object ID or 0)
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		b.Skip(4)
+
+		res = append(res, mod)
+	}
+
+	return res, nil
+}
+
+func readObjects(b *protocol.Buffer, ver uint32, levels bool) ([]Object, error) {
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	var num = b.ReadUInt32()
+	if uint64(num)*12 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	var err error
+	var res = make([]Object, num)
+	for i := uint32(0); i < num; i++ {
+		var obj = &res[i]
+		if b.Size() < 8 {
+			return nil, ErrBadFormat
+		}
+
+		obj.BaseID = b.ReadLEDString()
+		obj.ID = b.ReadLEDString()
+		if obj.ID == 0 {
+			obj.ID = obj.BaseID
+		}
+
+		obj.Modifications = []Modification{}
+		if ver < objectVersionReforged {
+			if obj.Modifications, err = readModifications(b, levels, 0, obj.Modifications); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+
+		var numSets = b.ReadUInt32()
+		for s := uint32(0); s < numSets; s++ {
+			if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+			var set = b.ReadUInt32()
+			if obj.Modifications, err = readModifications(b, levels, set, obj.Modifications); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func decodeObjects(b *protocol.Buffer, t ObjectType) (*Objects, error) {
+	if b.Size() < 12 {
+		return nil, ErrBadFormat
+	}
+
+	var o = Objects{
+		Type:       t,
+		FileFormat: b.ReadUInt32(),
+	}
+
+	switch o.FileFormat {
+	case 1, 2, objectVersionReforged:
+	default:
+		return nil, ErrBadFormat
+	}
+
+	var err error
+	if o.Original, err = readObjects(b, o.FileFormat, t.levels()); err != nil {
+		return nil, err
+	}
+	if o.Custom, err = readObjects(b, o.FileFormat, t.levels()); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+func writeModifications(b *protocol.Buffer, levels bool, mods []Modification, end protocol.DWordString) error {
+	b.WriteUInt32(uint32(len(mods)))
+	for _, mod := range mods {
+		b.WriteLEDString(mod.ID)
+		b.WriteUInt32(uint32(mod.Type))
+		if levels {
+			b.WriteUInt32(mod.Level)
+			b.WriteUInt32(mod.Column)
+		}
+
+		switch v := mod.Value.(type) {
+		case int32:
+			if mod.Type != ValueInt {
+				return ErrBadFormat
+			}
+			b.WriteUInt32(uint32(v))
+		case float32:
+			if mod.Type != ValueReal && mod.Type != ValueUnreal {
+				return ErrBadFormat
+			}
+			b.WriteFloat32(v)
+		case string:
+			if mod.Type != ValueString {
+				return ErrBadFormat
+			}
+			b.WriteCString(v)
+		default:
+			return ErrBadFormat
+		}
+
+		b.WriteLEDString(end)
+	}
+	return nil
+}
+
+func writeObjects(b *protocol.Buffer, ver uint32, levels bool, objs []Object, custom bool) error {
+	b.WriteUInt32(uint32(len(objs)))
+	for _, obj := range objs {
+		var id protocol.DWordString
+		if custom {
+			id = obj.ID
+		}
+
+		b.WriteLEDString(obj.BaseID)
+		b.WriteLEDString(id)
+
+		var end = obj.BaseID
+		if custom {
+			end = obj.ID
+		}
+
+		if ver < objectVersionReforged {
+			if err := writeModifications(b, levels, obj.Modifications, end); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Group consecutive modifications by data set
+		var sets [][]Modification
+		for i, mod := range obj.Modifications {
+			if i == 0 || mod.Set != obj.Modifications[i-1].Set {
+				sets = append(sets, nil)
+			}
+			sets[len(sets)-1] = append(sets[len(sets)-1], mod)
+		}
+
+		b.WriteUInt32(uint32(len(sets)))
+		for _, s := range sets {
+			b.WriteUInt32(s[0].Set)
+			if err := writeModifications(b, levels, s, end); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Encode Objects to object modification file format (see ObjectType.Extension)
+func (o *Objects) Encode() ([]byte, error) {
+	switch o.FileFormat {
+	case 1, 2, objectVersionReforged:
+	default:
+		return nil, ErrBadFormat
+	}
+
+	var b protocol.Buffer
+	b.WriteUInt32(o.FileFormat)
+	if err := writeObjects(&b, o.FileFormat, o.Type.levels(), o.Original, false); err != nil {
+		return nil, err
+	}
+	if err := writeObjects(&b, o.FileFormat, o.Type.levels(), o.Custom, true); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes, nil
+}
+
+// WriteObjects replaces the object modification file of o.Type in a map opened with OpenWritable
+func (m *Map) WriteObjects(o *Objects) error {
+	b, err := o.Encode()
+	if err != nil {
+		return err
+	}
+	return m.Archive.WriteFile("war3map."+o.Type.Extension(), b)
+}
@@ -0,0 +1,147 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"image"
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// PathingMap as found in the war3map.wpm file
+type PathingMap struct {
+	FileFormat uint32
+	Width      int
+	Height     int
+	Cells      []PathingFlags // Row by row, starting at the bottom left corner
+}
+
+// PathingCellSize in world coordinates (4 cells per tile)
+const PathingCellSize = TileSize / 4
+
+// Flags of cell (x, y), fully blocked if out of bounds
+func (p *PathingMap) Flags(x int, y int) PathingFlags {
+	if x < 0 || y < 0 || x >= p.Width || y >= p.Height {
+		return PathNoWalk | PathNoFly | PathNoBuild | PathNoWater
+	}
+	return p.Cells[y*p.Width+x]
+}
+
+// Walkable reports whether ground units can pass cell (x, y)
+func (p *PathingMap) Walkable(x int, y int) bool {
+	return p.Flags(x, y).Walkable()
+}
+
+// Flyable reports whether air units can pass cell (x, y)
+func (p *PathingMap) Flyable(x int, y int) bool {
+	return p.Flags(x, y).Flyable()
+}
+
+// Buildable reports whether structures can be placed on cell (x, y)
+func (p *PathingMap) Buildable(x int, y int) bool {
+	return p.Flags(x, y).Buildable()
+}
+
+// Region returns the cells connected to (x, y) that satisfy pass
+func (p *PathingMap) Region(x int, y int, pass func(PathingFlags) bool) []image.Point {
+	if !pass(p.Flags(x, y)) {
+		return nil
+	}
+
+	var seen = make([]bool, len(p.Cells))
+	return p.fill(x, y, pass, seen, nil)
+}
+
+// Regions labels the connected regions of cells that satisfy pass
+// Returns the label of each cell (0 if it does not satisfy pass) and the number of regions
+func (p *PathingMap) Regions(pass func(PathingFlags) bool) ([]int, int) {
+	var seen = make([]bool, len(p.Cells))
+	var labels = make([]int, len(p.Cells))
+
+	var num int
+	for i := range p.Cells {
+		if seen[i] || !pass(p.Cells[i]) {
+			continue
+		}
+
+		num++
+		for _, c := range p.fill(i%p.Width, i/p.Width, pass, seen, nil) {
+			labels[c.Y*p.Width+c.X] = num
+		}
+	}
+
+	return labels, num
+}
+
+// fill collects the 4-connected cells from (x, y) that satisfy pass
+func (p *PathingMap) fill(x int, y int, pass func(PathingFlags) bool, seen []bool, res []image.Point) []image.Point {
+	var stack = []image.Point{{X: x, Y: y}}
+	seen[y*p.Width+x] = true
+
+	for len(stack) > 0 {
+		var c = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		res = append(res, c)
+
+		for _, n := range [4]image.Point{{X: c.X - 1, Y: c.Y}, {X: c.X + 1, Y: c.Y}, {X: c.X, Y: c.Y - 1}, {X: c.X, Y: c.Y + 1}} {
+			if n.X < 0 || n.Y < 0 || n.X >= p.Width || n.Y >= p.Height {
+				continue
+			}
+			var i = n.Y*p.Width + n.X
+			if seen[i] || !pass(p.Cells[i]) {
+				continue
+			}
+			seen[i] = true
+			stack = append(stack, n)
+		}
+	}
+
+	return res
+}
+
+// PathingMap read from war3map.wpm
+func (m *Map) PathingMap() (*PathingMap, error) {
+	wpm, err := m.open("war3map.wpm")
+	if err != nil {
+		return nil, err
+	}
+	defer wpm.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, wpm); err != nil {
+		return nil, err
+	}
+
+	return decodePathingMap(&b)
+}
+
+func decodePathingMap(b *protocol.Buffer) (*PathingMap, error) {
+	if b.Size() < 16 || b.ReadLEDString() != protocol.DString("MP3W") {
+		return nil, ErrBadFormat
+	}
+
+	var p = PathingMap{
+		FileFormat: b.ReadUInt32(),
+	}
+	if p.FileFormat != 0 {
+		return nil, ErrBadFormat
+	}
+
+	var width = b.ReadUInt32()
+	var height = b.ReadUInt32()
+	if uint64(width)*uint64(height) > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	p.Width = int(width)
+	p.Height = int(height)
+	p.Cells = make([]PathingFlags, p.Width*p.Height)
+	for i, f := range b.ReadBlob(len(p.Cells)) {
+		p.Cells[i] = PathingFlags(f)
+	}
+
+	return &p, nil
+}
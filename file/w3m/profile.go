@@ -0,0 +1,140 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Profile maps sections to their key/value pairs, as found in war3mapSkin.txt, war3mapMisc.txt, and war3mapExtra.txt
+type Profile map[string]map[string]string
+
+// DecodeProfile parses the content of a profile (ini-like) file
+func DecodeProfile(b []byte) Profile {
+	var res = Profile{}
+	var sec map[string]string
+
+	for _, l := range strings.Split(string(bytes.TrimPrefix(b, bom)), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "//") || strings.HasPrefix(l, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(l, "[") && strings.HasSuffix(l, "]") {
+			var name = l[1 : len(l)-1]
+			if sec = res[name]; sec == nil {
+				sec = map[string]string{}
+				res[name] = sec
+			}
+			continue
+		}
+
+		var eq = strings.IndexByte(l, '=')
+		if eq < 0 || sec == nil {
+			continue
+		}
+		sec[strings.TrimSpace(l[:eq])] = strings.TrimSpace(l[eq+1:])
+	}
+
+	return res
+}
+
+// Value of key in section, falls back to a case-insensitive match like the game does
+func (p Profile) Value(section string, key string) (string, bool) {
+	var sec, ok = p[section]
+	if !ok {
+		for k, v := range p {
+			if strings.EqualFold(k, section) {
+				sec, ok = v, true
+				break
+			}
+		}
+		if !ok {
+			return "", false
+		}
+	}
+
+	if v, ok := sec[key]; ok {
+		return v, true
+	}
+	for k, v := range sec {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// List of comma separated values of key in section, quotes are removed
+func (p Profile) List(section string, key string) []string {
+	var v, ok = p.Value(section, key)
+	if !ok {
+		return nil
+	}
+
+	var res = strings.Split(v, ",")
+	for i := range res {
+		res[i] = strings.Trim(strings.TrimSpace(res[i]), "\"")
+	}
+	return res
+}
+
+// Int value of key in section
+func (p Profile) Int(section string, key string) (int, bool) {
+	var l = p.List(section, key)
+	if len(l) == 0 {
+		return 0, false
+	}
+	i, err := strconv.Atoi(l[0])
+	return i, err == nil
+}
+
+// Float value of key in section
+func (p Profile) Float(section string, key string) (float32, bool) {
+	var l = p.List(section, key)
+	if len(l) == 0 {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(l[0], 32)
+	return float32(f), err == nil
+}
+
+func (m *Map) profile(subFileName string) (Profile, error) {
+	b, err := m.readFile(subFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var p = DecodeProfile(b)
+	for _, sec := range p {
+		for k, v := range sec {
+			if sec[k], err = m.ExpandString(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// Misc returns the gameplay constant overrides in war3mapMisc.txt
+// Trigger strings are expanded
+func (m *Map) Misc() (Profile, error) {
+	return m.profile("war3mapMisc.txt")
+}
+
+// Extra returns the additional map properties (sky, fog, and such) in war3mapExtra.txt
+// Trigger strings are expanded
+func (m *Map) Extra() (Profile, error) {
+	return m.profile("war3mapExtra.txt")
+}
+
+// Skin returns the object data overrides in war3mapSkin.txt (in the preferred locale, see SetLocale)
+// Trigger strings are expanded
+func (m *Map) Skin() (Profile, error) {
+	return m.profile("war3mapSkin.txt")
+}
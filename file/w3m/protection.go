@@ -0,0 +1,107 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"os"
+
+	"github.com/nielsAD/gowarcraft3/file/mpq"
+)
+
+// Analysis of the protection and corruption of a map, see Analyze
+type Analysis struct {
+	Flags     ProtectionFlags
+	Readable  []string           // Standard files (see FileNames) that can be read
+	Anonymous []string           // Subfiles without known name that can be read (pseudo-names, see Map.Discover)
+	Corrupt   []mpq.VerifyResult // Subfiles that cannot be read or fail verification
+}
+
+// Hostable reports whether the game can still load the map (info and script are readable)
+func (a *Analysis) Hostable() bool {
+	return a.Flags&(ProtectBadTables|ProtectBadInfo|ProtectNoScript) == 0
+}
+
+// Analyze opens fileName and detects common protection and corruption signatures (see Map.Analyze)
+func Analyze(fileName string) (*Analysis, error) {
+	m, err := Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	var res = m.Analyze()
+
+	// Header fields faked by map protectors are only tolerated with mpq.OpenForceV1
+	if a, err := mpq.OpenArchive(fileName); err == nil {
+		a.Close()
+	} else {
+		res.Flags |= ProtectFakeHeader
+	}
+
+	return res, nil
+}
+
+// Analyze detects common protection and corruption signatures and reports which files are still readable
+func (m *Map) Analyze() *Analysis {
+	var res Analysis
+	var corrupt = map[string]bool{}
+
+	for _, name := range FileNames {
+		switch _, err := m.Archive.ReadFile(name); err {
+		case nil:
+			res.Readable = append(res.Readable, name)
+		case os.ErrNotExist:
+			if name == mpq.ListFileName {
+				res.Flags |= ProtectNoListFile
+			}
+		default:
+			res.Flags |= ProtectCorruptFiles
+			res.Corrupt = append(res.Corrupt, mpq.VerifyResult{Name: name, Err: err})
+			corrupt[name] = true
+		}
+	}
+
+	if anon, err := m.Discover(); err != nil {
+		res.Flags |= ProtectBadTables
+	} else {
+		for _, name := range anon {
+			if _, err := m.Archive.ReadFile(name); err != nil {
+				// Unreadable subfiles without name are typically junk inserted by protectors
+				res.Flags |= ProtectJunkFiles
+				res.Corrupt = append(res.Corrupt, mpq.VerifyResult{Name: name, Err: err})
+				corrupt[name] = true
+			} else {
+				res.Flags |= ProtectAnonymousFiles
+				res.Anonymous = append(res.Anonymous, name)
+			}
+		}
+	}
+
+	if ver, err := m.Archive.VerifyAll(); err != nil {
+		res.Flags |= ProtectBadTables
+	} else {
+		for _, r := range ver {
+			if r.Err == nil || r.Err == mpq.ErrFileName || corrupt[r.Name] {
+				continue
+			}
+			res.Flags |= ProtectCorruptFiles
+			res.Corrupt = append(res.Corrupt, r)
+		}
+	}
+
+	if _, err := m.Info(); err != nil {
+		res.Flags |= ProtectBadInfo
+	}
+
+	res.Flags |= ProtectNoScript
+	for _, name := range res.Readable {
+		switch name {
+		case "war3map.j", "scripts\\war3map.j", "war3map.lua", "scripts\\war3map.lua":
+			res.Flags &= ^ProtectNoScript
+		}
+	}
+
+	return &res
+}
@@ -0,0 +1,107 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Regions (rects) placed on the map as found in the war3map.w3r file
+type Regions struct {
+	FileFormat uint32
+	Regions    []Region
+}
+
+// Region structure in war3map.w3r file
+type Region struct {
+	Left           float32
+	Bottom         float32
+	Right          float32
+	Top            float32
+	Name           string
+	CreationNumber uint32
+	WeatherID      protocol.DWordString // 0 for none
+	AmbientSound   string               // Sound name as in war3map.w3s
+	Color          uint32               // ARGB
+}
+
+// Region by creation number (as referred to by Unit.Waygate), nil if not found
+func (r *Regions) Region(creationNumber uint32) *Region {
+	for i := range r.Regions {
+		if r.Regions[i].CreationNumber == creationNumber {
+			return &r.Regions[i]
+		}
+	}
+	return nil
+}
+
+// Regions read from war3map.w3r
+func (m *Map) Regions() (*Regions, error) {
+	w3r, err := m.open("war3map.w3r")
+	if err != nil {
+		return nil, err
+	}
+	defer w3r.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, w3r); err != nil {
+		return nil, err
+	}
+
+	return decodeRegions(&b, m.ExpandString)
+}
+
+func decodeRegions(b *protocol.Buffer, expand func(string) (string, error)) (*Regions, error) {
+	if b.Size() < 8 {
+		return nil, ErrBadFormat
+	}
+
+	var r = Regions{
+		FileFormat: b.ReadUInt32(),
+	}
+
+	var num = b.ReadUInt32()
+	if uint64(num)*32 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+	r.Regions = make([]Region, num)
+
+	var err error
+	for n := uint32(0); n < num; n++ {
+		var region = &r.Regions[n]
+		if b.Size() < 17 {
+			return nil, ErrBadFormat
+		}
+
+		region.Left = b.ReadFloat32()
+		region.Bottom = b.ReadFloat32()
+		region.Right = b.ReadFloat32()
+		region.Top = b.ReadFloat32()
+		if region.Name, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+		if region.Name, err = expand(region.Name); err != nil {
+			return nil, err
+		}
+
+		if b.Size() < 9 {
+			return nil, ErrBadFormat
+		}
+		region.CreationNumber = b.ReadUInt32()
+		region.WeatherID = b.ReadLEDString()
+		if region.AmbientSound, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		region.Color = b.ReadUInt32()
+	}
+
+	return &r, nil
+}
@@ -0,0 +1,334 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"os"
+	"sort"
+
+	"github.com/nielsAD/gowarcraft3/file/w3m/jass"
+)
+
+// ScriptConfig is the lobby configuration set up by the config function in the map script
+type ScriptConfig struct {
+	Name        string
+	Description string
+	NumPlayers  int
+	NumTeams    int
+	Placement   string   // MAP_PLACEMENT_* constant
+	Players     []Player // Slots set up by config, sorted by ID
+	Forces      []Force  // Teams assigned with SetPlayerTeam
+}
+
+var scriptFiles = map[GameCodeFormat][]string{
+	GameCodeFormatJASS: []string{"war3map.j", "scripts\\war3map.j"},
+	GameCodeFormatLua:  []string{"war3map.lua", "scripts\\war3map.lua"},
+}
+
+func (m *Map) hasScript(format GameCodeFormat) bool {
+	for _, name := range scriptFiles[format] {
+		if r, err := m.open(name); err == nil {
+			r.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// scriptFormat detects the language of the map script by the files present in the map
+// Returns false if the map contains scripts of neither or both languages
+func (m *Map) scriptFormat() (GameCodeFormat, bool) {
+	var j = m.hasScript(GameCodeFormatJASS)
+	var l = m.hasScript(GameCodeFormatLua)
+	switch {
+	case j && !l:
+		return GameCodeFormatJASS, true
+	case l && !j:
+		return GameCodeFormatLua, true
+	default:
+		return GameCodeFormatJASS, false
+	}
+}
+
+// Script returns the content and language of the map script (war3map.j or war3map.lua)
+func (m *Map) Script() (string, GameCodeFormat, error) {
+	var format = GameCodeFormatJASS
+	if info, err := m.Info(); err == nil {
+		format = info.CodeFormat
+	} else if f, ok := m.scriptFormat(); ok {
+		format = f
+	}
+
+	for _, name := range scriptFiles[format] {
+		b, err := m.readFile(name)
+		if err == nil {
+			return string(b), format, nil
+		} else if err != os.ErrNotExist {
+			return "", format, err
+		}
+	}
+
+	return "", format, os.ErrNotExist
+}
+
+// ScriptConfig extracts the lobby configuration from the map script, trigger strings are expanded
+func (m *Map) ScriptConfig() (*ScriptConfig, error) {
+	src, format, err := m.Script()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := ParseScriptConfig(src, format)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Name, err = m.ExpandString(c.Name); err != nil {
+		return nil, err
+	}
+	if c.Description, err = m.ExpandString(c.Description); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// scriptIdent refers to a constant or variable in the script
+type scriptIdent string
+
+// scriptCall with its arguments (int32, float32, string, bool, nil, scriptIdent or *scriptCall)
+type scriptCall struct {
+	name string
+	args []interface{}
+}
+
+func (c *scriptCall) arg(i int) interface{} {
+	if i >= len(c.args) {
+		return nil
+	}
+	return c.args[i]
+}
+
+func (c *scriptCall) int(i int) (int, bool) {
+	switch v := c.arg(i).(type) {
+	case int32:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+func (c *scriptCall) float(i int) float32 {
+	switch v := c.arg(i).(type) {
+	case int32:
+		return float32(v)
+	case float32:
+		return v
+	default:
+		return 0
+	}
+}
+
+// player index of Player(n) argument
+func (c *scriptCall) player(i int) (int, bool) {
+	var p, ok = c.arg(i).(*scriptCall)
+	if !ok || p.name != "Player" {
+		return 0, false
+	}
+	return p.int(0)
+}
+
+func jassValue(e jass.Expr) interface{} {
+	switch v := e.(type) {
+	case *jass.IntLit:
+		return v.Value
+	case *jass.RealLit:
+		return v.Value
+	case *jass.StringLit:
+		return v.Value
+	case *jass.BoolLit:
+		return v.Value
+	case *jass.Ident:
+		return scriptIdent(v.Name)
+	case *jass.ParenExpr:
+		return jassValue(v.X)
+	case *jass.CallExpr:
+		return jassCall(v)
+	case *jass.UnaryExpr:
+		if v.Op != jass.TokenMinus {
+			return nil
+		}
+		switch x := jassValue(v.X).(type) {
+		case int32:
+			return -x
+		case float32:
+			return -x
+		}
+	}
+	return nil
+}
+
+func jassCall(e *jass.CallExpr) *scriptCall {
+	var c = scriptCall{name: e.Name}
+	for _, a := range e.Args {
+		c.args = append(c.args, jassValue(a))
+	}
+	return &c
+}
+
+// jassFunctions extracts the calls made in each function of a JASS script
+func jassFunctions(src string) (map[string][]*scriptCall, error) {
+	f, err := jass.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = map[string][]*scriptCall{}
+	for _, fn := range f.Functions {
+		var calls = []*scriptCall{}
+		jass.Inspect(fn, func(n jass.Node) bool {
+			if s, ok := n.(*jass.CallStmt); ok {
+				calls = append(calls, jassCall(s.Call))
+				return false
+			}
+			return true
+		})
+		res[fn.Name] = calls
+	}
+
+	return res, nil
+}
+
+// flatten returns the calls made by function name, calls to functions in funcs are replaced by their calls
+// Recursive calls (tracked in active) are skipped
+func flatten(funcs map[string][]*scriptCall, name string, active map[string]bool, res []*scriptCall) []*scriptCall {
+	active[name] = true
+	for _, c := range funcs[name] {
+		if _, ok := funcs[c.name]; !ok {
+			res = append(res, c)
+		} else if !active[c.name] {
+			res = flatten(funcs, c.name, active, res)
+		}
+	}
+	delete(active, name)
+	return res
+}
+
+// ParseScriptConfig extracts the lobby configuration from the config function of a JASS or Lua map script
+// Only calls reachable from config are taken into account, trigger strings are not expanded
+func ParseScriptConfig(src string, format GameCodeFormat) (*ScriptConfig, error) {
+	var funcs map[string][]*scriptCall
+	var err error
+
+	switch format {
+	case GameCodeFormatJASS:
+		funcs, err = jassFunctions(src)
+	case GameCodeFormatLua:
+		funcs, err = luaFunctions(src)
+	default:
+		return nil, ErrBadFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := funcs["config"]; !ok {
+		return nil, ErrBadFormat
+	}
+
+	var cfg ScriptConfig
+	var start = map[int][2]float32{}
+	var loc = map[int]int{}
+	var players = map[int]*Player{}
+
+	var player = func(c *scriptCall, i int) *Player {
+		var id, ok = c.player(i)
+		if !ok || id < 0 {
+			return nil
+		}
+		if p := players[id]; p != nil {
+			return p
+		}
+		var p = &Player{ID: uint32(id)}
+		players[id] = p
+		return p
+	}
+
+	for _, c := range flatten(funcs, "config", map[string]bool{}, nil) {
+		switch c.name {
+		case "SetMapName":
+			cfg.Name, _ = c.arg(0).(string)
+		case "SetMapDescription":
+			cfg.Description, _ = c.arg(0).(string)
+		case "SetPlayers":
+			cfg.NumPlayers, _ = c.int(0)
+		case "SetTeams":
+			cfg.NumTeams, _ = c.int(0)
+		case "SetGamePlacement":
+			if s, ok := c.arg(0).(scriptIdent); ok {
+				cfg.Placement = string(s)
+			}
+		case "DefineStartLocation":
+			if i, ok := c.int(0); ok {
+				start[i] = [2]float32{c.float(1), c.float(2)}
+			}
+		case "SetPlayerStartLocation", "ForcePlayerStartLocation":
+			var p = player(c, 0)
+			if i, ok := c.int(1); ok && p != nil {
+				loc[int(p.ID)] = i
+				if c.name == "ForcePlayerStartLocation" {
+					p.Flags |= PlayerFlagFixedPos
+				}
+			}
+		case "SetPlayerRacePreference":
+			if p := player(c, 0); p != nil {
+				if s, ok := c.arg(1).(scriptIdent); ok {
+					p.Race = scriptRaces[string(s)]
+				}
+			}
+		case "SetPlayerController", "SetPlayerSlotAvailable":
+			if p := player(c, 0); p != nil {
+				if s, ok := c.arg(1).(scriptIdent); ok && (p.Type == 0 || c.name == "SetPlayerController") {
+					p.Type = scriptControllers[string(s)]
+				}
+			}
+		case "SetPlayerTeam":
+			var p = player(c, 0)
+			if t, ok := c.int(1); ok && p != nil && p.ID < 32 && t >= 0 && t < 32 {
+				for len(cfg.Forces) <= t {
+					cfg.Forces = append(cfg.Forces, Force{})
+				}
+				cfg.Forces[t].PlayerSet.Set(uint(p.ID) + 1)
+			}
+		}
+	}
+
+	cfg.Players = []Player{}
+	for id, p := range players {
+		if l, ok := loc[id]; ok {
+			p.StartPosX = start[l][0]
+			p.StartPosY = start[l][1]
+		}
+		cfg.Players = append(cfg.Players, *p)
+	}
+	sort.Slice(cfg.Players, func(i, j int) bool { return cfg.Players[i].ID < cfg.Players[j].ID })
+
+	return &cfg, nil
+}
+
+var scriptRaces = map[string]Race{
+	"RACE_PREF_HUMAN":           RaceHuman,
+	"RACE_PREF_ORC":             RaceOrc,
+	"RACE_PREF_UNDEAD":          RaceUndead,
+	"RACE_PREF_NIGHTELF":        RaceNightElf,
+	"RACE_PREF_RANDOM":          RaceSelectable,
+	"RACE_PREF_USER_SELECTABLE": RaceSelectable,
+}
+
+var scriptControllers = map[string]PlayerType{
+	"MAP_CONTROL_USER":      PlayerHuman,
+	"MAP_CONTROL_COMPUTER":  PlayerComputer,
+	"MAP_CONTROL_NEUTRAL":   PlayerNeutral,
+	"MAP_CONTROL_RESCUABLE": PlayerRescuable,
+}
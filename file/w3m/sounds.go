@@ -0,0 +1,114 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Sounds defined in the sound editor as found in the war3map.w3s file
+type Sounds struct {
+	FileFormat uint32
+	Sounds     []Sound
+}
+
+// Sound structure in war3map.w3s file
+type Sound struct {
+	Name              string // Variable name (gg_snd_*)
+	File              string
+	EAXEffect         string
+	Flags             SoundFlags
+	FadeInRate        uint32
+	FadeOutRate       uint32
+	Volume            int32 // -1 for default
+	Pitch             float32
+	PitchVariance     float32
+	Priority          int32
+	Channel           int32
+	MinDistance       float32
+	MaxDistance       float32
+	DistanceCutoff    float32
+	ConeInside        float32
+	ConeOutside       float32
+	ConeOutsideVolume int32
+	ConeOrientation   [3]float32
+}
+
+const soundVersion = 1
+
+// Sounds read from war3map.w3s
+// Only the classic format (version 1) is supported, Reforged sound sets are rejected with ErrBadFormat
+func (m *Map) Sounds() (*Sounds, error) {
+	w3s, err := m.open("war3map.w3s")
+	if err != nil {
+		return nil, err
+	}
+	defer w3s.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, w3s); err != nil {
+		return nil, err
+	}
+
+	return decodeSounds(&b)
+}
+
+func decodeSounds(b *protocol.Buffer) (*Sounds, error) {
+	if b.Size() < 8 {
+		return nil, ErrBadFormat
+	}
+
+	var s = Sounds{
+		FileFormat: b.ReadUInt32(),
+	}
+	if s.FileFormat != soundVersion {
+		return nil, ErrBadFormat
+	}
+
+	var num = b.ReadUInt32()
+	if uint64(num)*71 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+	s.Sounds = make([]Sound, num)
+
+	var err error
+	for n := uint32(0); n < num; n++ {
+		var snd = &s.Sounds[n]
+		if snd.Name, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+		if snd.File, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+		if snd.EAXEffect, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+
+		if b.Size() < 68 {
+			return nil, ErrBadFormat
+		}
+		snd.Flags = SoundFlags(b.ReadUInt32())
+		snd.FadeInRate = b.ReadUInt32()
+		snd.FadeOutRate = b.ReadUInt32()
+		snd.Volume = int32(b.ReadUInt32())
+		snd.Pitch = b.ReadFloat32()
+		snd.PitchVariance = b.ReadFloat32()
+		snd.Priority = int32(b.ReadUInt32())
+		snd.Channel = int32(b.ReadUInt32())
+		snd.MinDistance = b.ReadFloat32()
+		snd.MaxDistance = b.ReadFloat32()
+		snd.DistanceCutoff = b.ReadFloat32()
+		snd.ConeInside = b.ReadFloat32()
+		snd.ConeOutside = b.ReadFloat32()
+		snd.ConeOutsideVolume = int32(b.ReadUInt32())
+		snd.ConeOrientation[0] = b.ReadFloat32()
+		snd.ConeOrientation[1] = b.ReadFloat32()
+		snd.ConeOrientation[2] = b.ReadFloat32()
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,163 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"io"
+	"math"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Terrain (environment) as found in the war3map.w3e file
+type Terrain struct {
+	FileFormat     uint32
+	Tileset        Tileset
+	CustomTilesets bool
+	GroundTilesets []protocol.DWordString
+	CliffTilesets  []protocol.DWordString
+	Width          int
+	Height         int
+	OffsetX        float32
+	OffsetY        float32
+	Tilepoints     []Tilepoint // Row by row, starting at the bottom left corner
+}
+
+// Tilepoint structure in war3map.w3e file
+type Tilepoint struct {
+	GroundHeight uint16
+	WaterLevel   uint16
+	Flags        TilepointFlags
+	Texture      uint8 // Index in Terrain.GroundTilesets
+	Variation    uint8
+	CliffTexture uint8 // Index in Terrain.CliffTilesets
+	Layer        uint8 // Cliff level
+}
+
+// TileSize in world coordinates
+const TileSize = 128
+
+const heightZero = 0x2000
+
+// Height of the ground in world coordinates
+func (t *Tilepoint) Height() float32 {
+	return (float32(t.GroundHeight) - heightZero + (float32(t.Layer)-2)*0x200) / 4
+}
+
+// Water height in world coordinates
+func (t *Tilepoint) Water() float32 {
+	return (float32(t.WaterLevel)-heightZero)/4 - 89.6
+}
+
+// Submerged reports whether the tilepoint is covered by water
+func (t *Tilepoint) Submerged() bool {
+	return t.Flags&TileFlagWater != 0 && t.Water() > t.Height()
+}
+
+// Tilepoint at grid position (x, y), nil if out of bounds
+func (t *Terrain) Tilepoint(x int, y int) *Tilepoint {
+	if x < 0 || y < 0 || x >= t.Width || y >= t.Height {
+		return nil
+	}
+	return &t.Tilepoints[y*t.Width+x]
+}
+
+// Position of grid point (x, y) in world coordinates
+func (t *Terrain) Position(x int, y int) (float32, float32) {
+	return t.OffsetX + float32(x)*TileSize, t.OffsetY + float32(y)*TileSize
+}
+
+// Grid position of the tilepoint closest to world coordinates (x, y)
+func (t *Terrain) Grid(x float32, y float32) (int, int) {
+	var gx = (x - t.OffsetX) / TileSize
+	var gy = (y - t.OffsetY) / TileSize
+	return int(math.Floor(float64(gx) + 0.5)), int(math.Floor(float64(gy) + 0.5))
+}
+
+// Terrain read from war3map.w3e
+func (m *Map) Terrain() (*Terrain, error) {
+	w3e, err := m.open("war3map.w3e")
+	if err != nil {
+		return nil, err
+	}
+	defer w3e.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, w3e); err != nil {
+		return nil, err
+	}
+
+	return decodeTerrain(&b)
+}
+
+const terrainVersion = 11
+
+func decodeTerrain(b *protocol.Buffer) (*Terrain, error) {
+	if b.Size() < 17 || b.ReadLEDString() != protocol.DString("W3E!") {
+		return nil, ErrBadFormat
+	}
+
+	var t = Terrain{
+		FileFormat: b.ReadUInt32(),
+	}
+	if t.FileFormat != terrainVersion {
+		return nil, ErrBadFormat
+	}
+
+	t.Tileset = Tileset(b.ReadUInt8())
+	t.CustomTilesets = b.ReadUInt32() != 0
+
+	var numGround = b.ReadUInt32()
+	if uint64(numGround)*4+4 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+	t.GroundTilesets = make([]protocol.DWordString, numGround)
+	for i := range t.GroundTilesets {
+		t.GroundTilesets[i] = b.ReadLEDString()
+	}
+
+	var numCliff = b.ReadUInt32()
+	if uint64(numCliff)*4+16 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+	t.CliffTilesets = make([]protocol.DWordString, numCliff)
+	for i := range t.CliffTilesets {
+		t.CliffTilesets[i] = b.ReadLEDString()
+	}
+
+	var width = b.ReadUInt32()
+	var height = b.ReadUInt32()
+	t.OffsetX = b.ReadFloat32()
+	t.OffsetY = b.ReadFloat32()
+
+	if uint64(width)*uint64(height)*7 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	t.Width = int(width)
+	t.Height = int(height)
+	t.Tilepoints = make([]Tilepoint, t.Width*t.Height)
+	for i := range t.Tilepoints {
+		var p = &t.Tilepoints[i]
+		p.GroundHeight = b.ReadUInt16()
+
+		var water = b.ReadUInt16()
+		p.WaterLevel = water & 0x3FFF
+		if water&0x4000 != 0 {
+			p.Flags |= TileFlagEdge
+		}
+
+		var tex = b.ReadUInt8()
+		p.Texture = tex & 0x0F
+		p.Flags |= TilepointFlags(tex >> 4)
+		p.Variation = b.ReadUInt8()
+
+		var cliff = b.ReadUInt8()
+		p.CliffTexture = cliff >> 4
+		p.Layer = cliff & 0x0F
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,691 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Triggers as found in the war3map.wtg (GUI) and war3map.wct (custom text) files
+type Triggers struct {
+	FileFormat uint32
+	SubVersion uint32 // Reforged only
+	Categories []TriggerCategory
+	Variables  []TriggerVariable
+	Triggers   []Trigger
+	Comment    string // Custom script comment
+	Script     string // Custom script code (header)
+}
+
+// TriggerCategory structure in war3map.wtg file
+type TriggerCategory struct {
+	Type     TriggerElementType
+	ID       uint32
+	Name     string
+	Comment  bool
+	Expanded bool
+	ParentID int32 // -1 for root
+}
+
+// TriggerVariable structure in war3map.wtg file
+type TriggerVariable struct {
+	Name         string
+	Type         string
+	Array        bool
+	ArraySize    uint32
+	Initialized  bool
+	InitialValue string
+	ID           uint32
+	ParentID     int32 // -1 for root
+}
+
+// Trigger structure in war3map.wtg file
+type Trigger struct {
+	Type         TriggerElementType
+	ID           uint32
+	Name         string
+	Description  string
+	Comment      bool
+	Enabled      bool
+	Custom       bool
+	InitiallyOff bool
+	RunOnInit    bool
+	CategoryID   int32 // ParentID for Reforged
+	ECA          []ECA
+	Script       string // Custom text (see war3map.wct)
+}
+
+// ECA (event, condition, action, or function call) structure in war3map.wtg file
+type ECA struct {
+	Type       ECAType
+	Group      uint32 // Block of parent (i.e. if/then/else)
+	Name       string
+	Enabled    bool
+	Parameters []Parameter
+	Children   []ECA
+}
+
+// Parameter of an ECA
+type Parameter struct {
+	Type  ParameterType
+	Value string
+	Call  *ECA       // For ParameterFunction
+	Index *Parameter // Array index
+}
+
+// CustomText as found in the war3map.wct file
+type CustomText struct {
+	FileFormat uint32
+	Comment    string
+	Script     string
+	Triggers   []string
+}
+
+// TriggerData defines the number of arguments of trigger functions, as found in (UI\TriggerData.txt)
+type TriggerData struct {
+	Events     map[string]int
+	Conditions map[string]int
+	Actions    map[string]int
+	Calls      map[string]int
+}
+
+// LoadTriggerData from TriggerData.txt
+func LoadTriggerData(r io.Reader) (*TriggerData, error) {
+	var d = TriggerData{
+		Events:     map[string]int{},
+		Conditions: map[string]int{},
+		Actions:    map[string]int{},
+		Calls:      map[string]int{},
+	}
+
+	var sec map[string]int
+	var skip int
+
+	var s = bufio.NewScanner(r)
+	for s.Scan() {
+		var line = strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			sec = nil
+			switch line[1 : len(line)-1] {
+			case "TriggerEvents":
+				sec, skip = d.Events, 1
+			case "TriggerConditions":
+				sec, skip = d.Conditions, 1
+			case "TriggerActions":
+				sec, skip = d.Actions, 1
+			case "TriggerCalls":
+				// version, usable in events, return type
+				sec, skip = d.Calls, 3
+			}
+			continue
+		}
+
+		var eq = strings.IndexByte(line, '=')
+		if sec == nil || eq <= 0 || line[0] == '_' {
+			continue
+		}
+
+		var args = 0
+		var val = strings.Split(line[eq+1:], ",")
+		for i := skip; i < len(val); i++ {
+			if a := strings.TrimSpace(val[i]); a != "" && a != "nothing" {
+				args++
+			}
+		}
+
+		sec[strings.TrimSpace(line[:eq])] = args
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// Arguments returns the number of arguments of trigger function name
+func (d *TriggerData) Arguments(t ECAType, name string) (int, bool) {
+	if d == nil {
+		return 0, false
+	}
+
+	var n int
+	var ok bool
+	switch t {
+	case ECAEvent:
+		n, ok = d.Events[name]
+	case ECACondition:
+		n, ok = d.Conditions[name]
+	case ECAAction:
+		n, ok = d.Actions[name]
+	case ECACall:
+		n, ok = d.Calls[name]
+	}
+	return n, ok
+}
+
+const wtgVersionRoc = 4
+const wtgVersionTft = 7
+const wtgVersionReforged = 0x80000004
+
+// Triggers read from war3map.wtg and war3map.wct, data defines the arguments of trigger functions
+func (m *Map) Triggers(data *TriggerData) (*Triggers, error) {
+	wtg, err := m.open("war3map.wtg")
+	if err != nil {
+		return nil, err
+	}
+	defer wtg.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, wtg); err != nil {
+		return nil, err
+	}
+
+	t, err := decodeTriggers(&b, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ct, err := m.CustomText()
+	switch err {
+	case nil:
+		t.Comment = ct.Comment
+		t.Script = ct.Script
+		if len(ct.Triggers) == len(t.Triggers) {
+			for i := range ct.Triggers {
+				t.Triggers[i].Script = ct.Triggers[i]
+			}
+		}
+	case os.ErrNotExist:
+	default:
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// CustomText read from war3map.wct
+func (m *Map) CustomText() (*CustomText, error) {
+	wct, err := m.open("war3map.wct")
+	if err != nil {
+		return nil, err
+	}
+	defer wct.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, wct); err != nil {
+		return nil, err
+	}
+
+	return decodeCustomText(&b)
+}
+
+func readWctScript(b *protocol.Buffer) (string, error) {
+	if b.Size() < 4 {
+		return "", ErrBadFormat
+	}
+
+	var size = b.ReadUInt32()
+	if uint32(b.Size()) < size {
+		return "", ErrBadFormat
+	}
+
+	return strings.TrimRight(string(b.ReadBlob(int(size))), "\x00"), nil
+}
+
+func decodeCustomText(b *protocol.Buffer) (*CustomText, error) {
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	var c = CustomText{
+		FileFormat: b.ReadUInt32(),
+	}
+
+	var reforged = c.FileFormat == wtgVersionReforged
+	if reforged {
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		c.FileFormat = b.ReadUInt32()
+	}
+
+	var err error
+	switch c.FileFormat {
+	case 0:
+	case 1:
+		if c.Comment, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+		if c.Script, err = readWctScript(b); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrBadFormat
+	}
+
+	// Reforged omits the number of triggers
+	var num = ^uint32(0)
+	if !reforged {
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		num = b.ReadUInt32()
+	}
+
+	for n := uint32(0); n < num && (!reforged || b.Size() > 0); n++ {
+		s, err := readWctScript(b)
+		if err != nil {
+			return nil, err
+		}
+		c.Triggers = append(c.Triggers, s)
+	}
+
+	return &c, nil
+}
+
+func readParameter(b *protocol.Buffer, data *TriggerData, ver uint32) (*Parameter, error) {
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	var err error
+	var p = Parameter{
+		Type: ParameterType(b.ReadUInt32()),
+	}
+	if p.Value, err = b.ReadCString(); err != nil {
+		return nil, err
+	}
+
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+	if b.ReadUInt32() != 0 {
+		if p.Call, err = readECA(b, data, ver, false, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if ver < wtgVersionTft && p.Type == ParameterFunction {
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		b.Skip(4)
+		return &p, nil
+	}
+
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+	if b.ReadUInt32() != 0 {
+		if p.Index, err = readParameter(b, data, ver); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+func readECA(b *protocol.Buffer, data *TriggerData, ver uint32, child bool, call bool) (*ECA, error) {
+	if b.Size() < 4 || (child && b.Size() < 8) {
+		return nil, ErrBadFormat
+	}
+
+	var err error
+	var e = ECA{
+		Type: ECAType(b.ReadUInt32()),
+	}
+	if child {
+		e.Group = b.ReadUInt32()
+	}
+	if e.Name, err = b.ReadCString(); err != nil {
+		return nil, err
+	}
+
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	// Function calls in parameters store a begin parameters flag instead
+	e.Enabled = b.ReadUInt32() != 0
+	if !call || e.Enabled {
+		args, ok := data.Arguments(e.Type, e.Name)
+		if !ok {
+			return nil, ErrUnknownFunction
+		}
+
+		e.Parameters = make([]Parameter, args)
+		for i := 0; i < args; i++ {
+			p, err := readParameter(b, data, ver)
+			if err != nil {
+				return nil, err
+			}
+			e.Parameters[i] = *p
+		}
+	}
+
+	if ver < wtgVersionTft {
+		return &e, nil
+	}
+
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	// Unused for function calls
+	var numChildren = b.ReadUInt32()
+	if call {
+		return &e, nil
+	}
+	if uint64(numChildren)*13 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	e.Children = make([]ECA, numChildren)
+	for i := uint32(0); i < numChildren; i++ {
+		c, err := readECA(b, data, ver, true, false)
+		if err != nil {
+			return nil, err
+		}
+		e.Children[i] = *c
+	}
+
+	return &e, nil
+}
+
+func readTriggerVariable(b *protocol.Buffer, ver uint32, reforged bool) (*TriggerVariable, error) {
+	var err error
+	var v = TriggerVariable{
+		ParentID: -1,
+	}
+	if v.Name, err = b.ReadCString(); err != nil {
+		return nil, err
+	}
+	if v.Type, err = b.ReadCString(); err != nil {
+		return nil, err
+	}
+
+	if b.Size() < 12 || (ver >= wtgVersionTft && b.Size() < 16) {
+		return nil, ErrBadFormat
+	}
+	b.Skip(4)
+	v.Array = b.ReadUInt32() != 0
+	if ver >= wtgVersionTft {
+		v.ArraySize = b.ReadUInt32()
+	}
+	v.Initialized = b.ReadUInt32() != 0
+	if v.InitialValue, err = b.ReadCString(); err != nil {
+		return nil, err
+	}
+
+	if reforged {
+		if b.Size() < 8 {
+			return nil, ErrBadFormat
+		}
+		v.ID = b.ReadUInt32()
+		v.ParentID = int32(b.ReadUInt32())
+	}
+
+	return &v, nil
+}
+
+func readTrigger(b *protocol.Buffer, data *TriggerData, ver uint32, reforged bool) (*Trigger, error) {
+	var err error
+	var t = Trigger{
+		Type: TriggerElementGUI,
+	}
+	if t.Name, err = b.ReadCString(); err != nil {
+		return nil, err
+	}
+	if t.Description, err = b.ReadCString(); err != nil {
+		return nil, err
+	}
+
+	var minSize = 24
+	if ver >= wtgVersionTft {
+		minSize += 4
+	}
+	if reforged {
+		minSize += 4
+	}
+	if b.Size() < minSize {
+		return nil, ErrBadFormat
+	}
+
+	if ver >= wtgVersionTft {
+		t.Comment = b.ReadUInt32() != 0
+	}
+	if reforged {
+		t.ID = b.ReadUInt32()
+	}
+	t.Enabled = b.ReadUInt32() != 0
+	t.Custom = b.ReadUInt32() != 0
+	t.InitiallyOff = b.ReadUInt32() != 0
+	t.RunOnInit = b.ReadUInt32() != 0
+	t.CategoryID = int32(b.ReadUInt32())
+
+	var num = b.ReadUInt32()
+	if uint64(num)*9 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	t.ECA = make([]ECA, num)
+	for i := uint32(0); i < num; i++ {
+		e, err := readECA(b, data, ver, false, false)
+		if err != nil {
+			return nil, err
+		}
+		t.ECA[i] = *e
+	}
+
+	return &t, nil
+}
+
+func decodeTriggers(b *protocol.Buffer, data *TriggerData) (*Triggers, error) {
+	if b.Size() < 12 || b.ReadLEDString() != protocol.DString("WTG!") {
+		return nil, ErrBadFormat
+	}
+
+	var t = Triggers{
+		FileFormat: b.ReadUInt32(),
+	}
+
+	switch t.FileFormat {
+	case wtgVersionRoc, wtgVersionTft:
+		return decodeTriggersClassic(b, data, &t)
+	case wtgVersionReforged:
+		t.SubVersion = b.ReadUInt32()
+		switch t.SubVersion {
+		case wtgVersionRoc, wtgVersionTft:
+			return decodeTriggersReforged(b, data, &t)
+		}
+	}
+
+	return nil, ErrBadFormat
+}
+
+func decodeTriggersClassic(b *protocol.Buffer, data *TriggerData, t *Triggers) (*Triggers, error) {
+	var ver = t.FileFormat
+
+	var num = b.ReadUInt32()
+	if uint64(num)*5 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	var err error
+	t.Categories = make([]TriggerCategory, num)
+	for i := uint32(0); i < num; i++ {
+		var c = &t.Categories[i]
+		c.Type = TriggerElementCategory
+		c.ParentID = -1
+
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		c.ID = b.ReadUInt32()
+		if c.Name, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+		if ver >= wtgVersionTft {
+			if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+			c.Comment = b.ReadUInt32() != 0
+		}
+	}
+
+	if b.Size() < 8 {
+		return nil, ErrBadFormat
+	}
+	b.Skip(4)
+
+	num = b.ReadUInt32()
+	if uint64(num)*18 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	t.Variables = make([]TriggerVariable, num)
+	for i := uint32(0); i < num; i++ {
+		v, err := readTriggerVariable(b, ver, false)
+		if err != nil {
+			return nil, err
+		}
+		t.Variables[i] = *v
+	}
+
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	num = b.ReadUInt32()
+	if uint64(num)*26 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	t.Triggers = make([]Trigger, num)
+	for i := uint32(0); i < num; i++ {
+		trig, err := readTrigger(b, data, ver, false)
+		if err != nil {
+			return nil, err
+		}
+		if trig.Custom {
+			trig.Type = TriggerElementScript
+		}
+		t.Triggers[i] = *trig
+	}
+
+	return t, nil
+}
+
+func decodeTriggersReforged(b *protocol.Buffer, data *TriggerData, t *Triggers) (*Triggers, error) {
+	var ver = t.SubVersion
+
+	// Element counts and deleted element IDs for map, library, category, trigger, comment, script, variable
+	for i := 0; i < 7; i++ {
+		if b.Size() < 8 {
+			return nil, ErrBadFormat
+		}
+		b.Skip(4)
+
+		var del = b.ReadUInt32()
+		if uint64(del)*4 > uint64(b.Size()) {
+			return nil, ErrBadFormat
+		}
+		b.Skip(int(del) * 4)
+	}
+
+	if b.Size() < 16 {
+		return nil, ErrBadFormat
+	}
+	b.Skip(12)
+
+	var num = b.ReadUInt32()
+	if uint64(num)*26 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	t.Variables = make([]TriggerVariable, num)
+	for i := uint32(0); i < num; i++ {
+		v, err := readTriggerVariable(b, ver, true)
+		if err != nil {
+			return nil, err
+		}
+		t.Variables[i] = *v
+	}
+
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	num = b.ReadUInt32()
+	if uint64(num)*4 > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+
+	t.Categories = []TriggerCategory{}
+	t.Triggers = []Trigger{}
+
+	var err error
+	for i := uint32(0); i < num; i++ {
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+
+		var typ = TriggerElementType(b.ReadUInt32())
+		switch typ {
+		case TriggerElementMap, TriggerElementLibrary, TriggerElementCategory:
+			var c = TriggerCategory{Type: typ}
+			if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+			c.ID = b.ReadUInt32()
+			if c.Name, err = b.ReadCString(); err != nil {
+				return nil, err
+			}
+			if b.Size() < 8 || (ver >= wtgVersionTft && b.Size() < 12) {
+				return nil, ErrBadFormat
+			}
+			if ver >= wtgVersionTft {
+				c.Comment = b.ReadUInt32() != 0
+			}
+			c.Expanded = b.ReadUInt32() != 0
+			c.ParentID = int32(b.ReadUInt32())
+			t.Categories = append(t.Categories, c)
+		case TriggerElementGUI, TriggerElementComment, TriggerElementScript:
+			trig, err := readTrigger(b, data, ver, true)
+			if err != nil {
+				return nil, err
+			}
+			trig.Type = typ
+			t.Triggers = append(t.Triggers, *trig)
+		case TriggerElementVariable:
+			// Duplicate of variable definition
+			if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+			b.Skip(4)
+			if _, err := b.ReadCString(); err != nil {
+				return nil, err
+			}
+			if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+			b.Skip(4)
+		default:
+			return nil, ErrBadFormat
+		}
+	}
+
+	return t, nil
+}
@@ -0,0 +1,370 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Units (and items) placed on the map as found in the war3mapUnits.doo file
+type Units struct {
+	FileFormat uint32
+	SubVersion uint32
+	Units      []Unit
+}
+
+// Unit (or item) structure in war3mapUnits.doo file
+type Unit struct {
+	TypeID    protocol.DWordString
+	Variation uint32
+	X         float32
+	Y         float32
+	Z         float32
+	Angle     float32
+	ScaleX    float32
+	ScaleY    float32
+	ScaleZ    float32
+	SkinID    protocol.DWordString
+	Flags     uint8
+	Owner     uint32
+
+	HitPoints int32 // -1 for default
+	Mana      int32 // -1 for default
+	ItemTable int32 // -1 for none (see Info.RandomItemTables)
+	ItemSets  [][]RandomItem
+
+	Gold              uint32
+	TargetAcquisition float32
+	HeroLevel         uint32
+	HeroStrength      uint32
+	HeroAgility       uint32
+	HeroIntelligence  uint32
+	Inventory         []InventoryItem
+	Abilities         []AbilityModification
+
+	RandomType     RandomUnitType
+	RandomLevel    int32 // -1 for any
+	RandomClass    uint8
+	RandomGroup    uint32 // See Info.RandomUnitTables
+	RandomPosition uint32
+	RandomUnits    []RandomUnit
+
+	Color          int32 // -1 for none
+	Waygate        int32 // -1 for deactivated
+	CreationNumber uint32
+}
+
+// InventoryItem structure in war3mapUnits.doo file
+type InventoryItem struct {
+	Slot   uint32
+	ItemID protocol.DWordString
+}
+
+// AbilityModification structure in war3mapUnits.doo file
+type AbilityModification struct {
+	AbilityID protocol.DWordString
+	Autocast  bool
+	Level     uint32
+}
+
+// RandomUnit in the custom random table of a Unit
+type RandomUnit struct {
+	UnitID protocol.DWordString
+	Chance uint32
+}
+
+// StartLocation type ID
+var StartLocation = protocol.DString("sloc")
+
+// StartLocations returns the placed start locations
+func (u *Units) StartLocations() []Unit {
+	var res []Unit
+	for _, unit := range u.Units {
+		if unit.TypeID == StartLocation {
+			res = append(res, unit)
+		}
+	}
+	return res
+}
+
+const subVersionTft = 11
+
+// skins reports whether placed objects store a skin ID (Reforged 1.32+)
+func (m *Map) skins() bool {
+	info, err := m.Info()
+	if err != nil || info.FileFormat < editorVersionReforged {
+		return false
+	}
+	return info.GameVersion.Major > 1 || (info.GameVersion.Major == 1 && info.GameVersion.Minor >= 32)
+}
+
+func readItemSets(b *protocol.Buffer) ([][]RandomItem, error) {
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	var numSets = b.ReadUInt32()
+	var res = make([][]RandomItem, numSets)
+	for s := uint32(0); s < numSets; s++ {
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		var numItems = b.ReadUInt32()
+		if uint32(b.Size()) < numItems*8 {
+			return nil, ErrBadFormat
+		}
+		res[s] = make([]RandomItem, numItems)
+		for i := uint32(0); i < numItems; i++ {
+			res[s][i].ItemID = b.ReadLEDString()
+			res[s][i].Chance = b.ReadUInt32()
+		}
+	}
+
+	return res, nil
+}
+
+func writeItemSets(b *protocol.Buffer, sets [][]RandomItem) {
+	b.WriteUInt32(uint32(len(sets)))
+	for _, s := range sets {
+		b.WriteUInt32(uint32(len(s)))
+		for _, i := range s {
+			b.WriteLEDString(i.ItemID)
+			b.WriteUInt32(i.Chance)
+		}
+	}
+}
+
+// Units read from war3mapUnits.doo
+func (m *Map) Units() (*Units, error) {
+	doo, err := m.open("war3mapUnits.doo")
+	if err != nil {
+		return nil, err
+	}
+	defer doo.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, doo); err != nil {
+		return nil, err
+	}
+
+	return decodeUnits(&b, m.skins())
+}
+
+func decodeUnits(b *protocol.Buffer, skins bool) (*Units, error) {
+	if b.Size() < 16 || b.ReadLEDString() != protocol.DString("W3do") {
+		return nil, ErrBadFormat
+	}
+
+	var u = Units{
+		FileFormat: b.ReadUInt32(),
+		SubVersion: b.ReadUInt32(),
+	}
+
+	var minSize = 51
+	if skins {
+		minSize += 4
+	}
+	if u.SubVersion >= subVersionTft {
+		minSize += 4
+	}
+
+	var num = b.ReadUInt32()
+	if uint64(num)*uint64(minSize) > uint64(b.Size()) {
+		return nil, ErrBadFormat
+	}
+	u.Units = make([]Unit, num)
+
+	var err error
+	for n := uint32(0); n < num; n++ {
+		var unit = &u.Units[n]
+		if b.Size() < minSize {
+			return nil, ErrBadFormat
+		}
+
+		unit.TypeID = b.ReadLEDString()
+		unit.Variation = b.ReadUInt32()
+		unit.X = b.ReadFloat32()
+		unit.Y = b.ReadFloat32()
+		unit.Z = b.ReadFloat32()
+		unit.Angle = b.ReadFloat32()
+		unit.ScaleX = b.ReadFloat32()
+		unit.ScaleY = b.ReadFloat32()
+		unit.ScaleZ = b.ReadFloat32()
+		if skins {
+			unit.SkinID = b.ReadLEDString()
+		}
+		unit.Flags = b.ReadUInt8()
+		unit.Owner = b.ReadUInt32()
+		b.Skip(2)
+		unit.HitPoints = int32(b.ReadUInt32())
+		unit.Mana = int32(b.ReadUInt32())
+
+		unit.ItemTable = -1
+		if u.SubVersion >= subVersionTft {
+			unit.ItemTable = int32(b.ReadUInt32())
+		}
+		if unit.ItemSets, err = readItemSets(b); err != nil {
+			return nil, err
+		}
+
+		if b.Size() < 16 || (u.SubVersion >= subVersionTft && b.Size() < 28) {
+			return nil, ErrBadFormat
+		}
+		unit.Gold = b.ReadUInt32()
+		unit.TargetAcquisition = b.ReadFloat32()
+		unit.HeroLevel = b.ReadUInt32()
+		if u.SubVersion >= subVersionTft {
+			unit.HeroStrength = b.ReadUInt32()
+			unit.HeroAgility = b.ReadUInt32()
+			unit.HeroIntelligence = b.ReadUInt32()
+		}
+
+		var numItems = b.ReadUInt32()
+		if uint32(b.Size()) < numItems*8+4 {
+			return nil, ErrBadFormat
+		}
+		unit.Inventory = make([]InventoryItem, numItems)
+		for i := uint32(0); i < numItems; i++ {
+			unit.Inventory[i].Slot = b.ReadUInt32()
+			unit.Inventory[i].ItemID = b.ReadLEDString()
+		}
+
+		var numAbilities = b.ReadUInt32()
+		if uint32(b.Size()) < numAbilities*12+8 {
+			return nil, ErrBadFormat
+		}
+		unit.Abilities = make([]AbilityModification, numAbilities)
+		for i := uint32(0); i < numAbilities; i++ {
+			unit.Abilities[i].AbilityID = b.ReadLEDString()
+			unit.Abilities[i].Autocast = b.ReadUInt32() != 0
+			unit.Abilities[i].Level = b.ReadUInt32()
+		}
+
+		unit.RandomType = RandomUnitType(b.ReadUInt32())
+		switch unit.RandomType {
+		case RandomUnitAny:
+			var r = b.ReadUInt32()
+			unit.RandomLevel = int32(r<<8) >> 8
+			unit.RandomClass = uint8(r >> 24)
+		case RandomUnitGroup:
+			if b.Size() < 8 {
+				return nil, ErrBadFormat
+			}
+			unit.RandomGroup = b.ReadUInt32()
+			unit.RandomPosition = b.ReadUInt32()
+		case RandomUnitCustom:
+			var numUnits = b.ReadUInt32()
+			if uint32(b.Size()) < numUnits*8 {
+				return nil, ErrBadFormat
+			}
+			unit.RandomUnits = make([]RandomUnit, numUnits)
+			for i := uint32(0); i < numUnits; i++ {
+				unit.RandomUnits[i].UnitID = b.ReadLEDString()
+				unit.RandomUnits[i].Chance = b.ReadUInt32()
+			}
+		default:
+			return nil, ErrBadFormat
+		}
+
+		if b.Size() < 12 {
+			return nil, ErrBadFormat
+		}
+		unit.Color = int32(b.ReadUInt32())
+		unit.Waygate = int32(b.ReadUInt32())
+		unit.CreationNumber = b.ReadUInt32()
+	}
+
+	return &u, nil
+}
+
+// Encode Units to war3mapUnits.doo format, skins indicates whether to store skin IDs (see Map.WriteUnits)
+func (u *Units) Encode(skins bool) ([]byte, error) {
+	var b protocol.Buffer
+	b.WriteLEDString(protocol.DString("W3do"))
+	b.WriteUInt32(u.FileFormat)
+	b.WriteUInt32(u.SubVersion)
+
+	b.WriteUInt32(uint32(len(u.Units)))
+	for _, unit := range u.Units {
+		b.WriteLEDString(unit.TypeID)
+		b.WriteUInt32(unit.Variation)
+		b.WriteFloat32(unit.X)
+		b.WriteFloat32(unit.Y)
+		b.WriteFloat32(unit.Z)
+		b.WriteFloat32(unit.Angle)
+		b.WriteFloat32(unit.ScaleX)
+		b.WriteFloat32(unit.ScaleY)
+		b.WriteFloat32(unit.ScaleZ)
+		if skins {
+			b.WriteLEDString(unit.SkinID)
+		}
+		b.WriteUInt8(unit.Flags)
+		b.WriteUInt32(unit.Owner)
+		b.WriteUInt16(0)
+		b.WriteUInt32(uint32(unit.HitPoints))
+		b.WriteUInt32(uint32(unit.Mana))
+
+		if u.SubVersion >= subVersionTft {
+			b.WriteUInt32(uint32(unit.ItemTable))
+		}
+		writeItemSets(&b, unit.ItemSets)
+
+		b.WriteUInt32(unit.Gold)
+		b.WriteFloat32(unit.TargetAcquisition)
+		b.WriteUInt32(unit.HeroLevel)
+		if u.SubVersion >= subVersionTft {
+			b.WriteUInt32(unit.HeroStrength)
+			b.WriteUInt32(unit.HeroAgility)
+			b.WriteUInt32(unit.HeroIntelligence)
+		}
+
+		b.WriteUInt32(uint32(len(unit.Inventory)))
+		for _, i := range unit.Inventory {
+			b.WriteUInt32(i.Slot)
+			b.WriteLEDString(i.ItemID)
+		}
+
+		b.WriteUInt32(uint32(len(unit.Abilities)))
+		for _, a := range unit.Abilities {
+			b.WriteLEDString(a.AbilityID)
+			b.WriteBool32(a.Autocast)
+			b.WriteUInt32(a.Level)
+		}
+
+		b.WriteUInt32(uint32(unit.RandomType))
+		switch unit.RandomType {
+		case RandomUnitAny:
+			b.WriteUInt32(uint32(unit.RandomLevel)&0xFFFFFF | uint32(unit.RandomClass)<<24)
+		case RandomUnitGroup:
+			b.WriteUInt32(unit.RandomGroup)
+			b.WriteUInt32(unit.RandomPosition)
+		case RandomUnitCustom:
+			b.WriteUInt32(uint32(len(unit.RandomUnits)))
+			for _, r := range unit.RandomUnits {
+				b.WriteLEDString(r.UnitID)
+				b.WriteUInt32(r.Chance)
+			}
+		default:
+			return nil, ErrBadFormat
+		}
+
+		b.WriteUInt32(uint32(unit.Color))
+		b.WriteUInt32(uint32(unit.Waygate))
+		b.WriteUInt32(unit.CreationNumber)
+	}
+
+	return b.Bytes, nil
+}
+
+// WriteUnits replaces war3mapUnits.doo in a map opened with OpenWritable
+func (m *Map) WriteUnits(u *Units) error {
+	b, err := u.Encode(m.skins())
+	if err != nil {
+		return err
+	}
+	return m.Archive.WriteFile("war3mapUnits.doo", b)
+}
@@ -6,24 +6,109 @@
 package w3m
 
 import (
+	"io/ioutil"
+	"os"
+	"sort"
+
 	"github.com/nielsAD/gowarcraft3/file/mpq"
 )
 
 // Map refers to an w3m/w3x map (MPQ archive)
 type Map struct {
 	Archive *mpq.Archive
+	locales []mpq.Locale
 	ts      map[int]string
 }
 
-// Open a w3m/w3x map file
+// Open a w3m/w3x map file, header fields faked by map protectors are ignored
 func Open(fileName string) (*Map, error) {
-	var archive, err = mpq.OpenArchive(fileName)
+	var archive, err = mpq.OpenArchiveFlags(fileName, mpq.OpenForceV1)
+	if err != nil {
+		return nil, err
+	}
+	return &Map{Archive: archive}, nil
+}
+
+// OpenWritable opens a w3m/w3x map file for modification
+// Note that writing to a map invalidates its signature
+func OpenWritable(fileName string) (*Map, error) {
+	var archive, err = mpq.OpenArchiveFlags(fileName, mpq.OpenForceV1|mpq.OpenWritable)
 	if err != nil {
 		return nil, err
 	}
 	return &Map{Archive: archive}, nil
 }
 
+// SetLocale selects the preferred locales of localized files (i.e. war3map.wts and war3mapSkin.txt)
+// Files are read in the first available locale, falling back to mpq.LocaleNeutral like the game does
+// Checksum and writes are not affected
+func (m *Map) SetLocale(locales ...mpq.Locale) {
+	m.locales = append([]mpq.Locale(nil), locales...)
+	m.ts = nil
+}
+
+// Locales lists the locales of localized files in the map (excluding mpq.LocaleNeutral)
+func (m *Map) Locales() ([]mpq.Locale, error) {
+	entries, err := m.Archive.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var res = []mpq.Locale{}
+	var seen = map[mpq.Locale]bool{mpq.LocaleNeutral: true}
+	for _, e := range entries {
+		if !seen[e.Locale] {
+			seen[e.Locale] = true
+			res = append(res, e.Locale)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res, nil
+}
+
+// open subfile in the preferred locale (see SetLocale)
+func (m *Map) open(subFileName string) (*mpq.File, error) {
+	if len(m.locales) == 0 {
+		return m.Archive.Open(subFileName)
+	}
+	return m.Archive.OpenLocale(subFileName, m.locales...)
+}
+
+// readFile reads subfile in the preferred locale (see SetLocale)
+func (m *Map) readFile(subFileName string) ([]byte, error) {
+	f, err := m.open(subFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// FileNames lists the names of the standard files inside w3m/w3x maps
+var FileNames = []string{
+	"(listfile)", "(attributes)", "(signature)",
+	"war3map.j", "scripts\\war3map.j", "war3map.lua", "scripts\\war3map.lua",
+	"scripts\\common.j", "scripts\\blizzard.j",
+	"war3map.w3i", "war3map.w3e", "war3map.wpm", "war3map.doo", "war3mapUnits.doo",
+	"war3map.w3r", "war3map.w3c", "war3map.w3s", "war3map.wtg", "war3map.wct", "war3map.wts",
+	"war3map.w3u", "war3map.w3t", "war3map.w3b", "war3map.w3d", "war3map.w3a", "war3map.w3h", "war3map.w3q",
+	"war3map.imp", "war3map.mmp", "war3map.shd",
+	"war3mapMap.blp", "war3mapMap.tga", "war3mapPreview.tga", "war3mapPath.tga",
+	"war3mapMisc.txt", "war3mapSkin.txt", "war3mapExtra.txt",
+}
+
+// Discover resolves the names of unnamed files from FileNames and additional candidates
+// Returns the pseudo-names of files that remain anonymous (see mpq.Archive.Discover)
+func (m *Map) Discover(candidates ...string) ([]string, error) {
+	return m.Archive.Discover(append(append([]string(nil), FileNames...), candidates...))
+}
+
+// Flush pending changes to a map opened with OpenWritable
+func (m *Map) Flush() error {
+	return m.Archive.Flush()
+}
+
 // Close a w3m/w3x map file
 func (m *Map) Close() error {
 	return m.Archive.Close()
@@ -33,3 +118,28 @@ func (m *Map) Close() error {
 func (m *Map) Signed() bool {
 	return m.Archive.StrongSigned()
 }
+
+// WriteScript replaces the map script (war3map.j or war3map.lua, depending on Info.CodeFormat)
+// in a map opened with OpenWritable
+func (m *Map) WriteScript(script []byte) error {
+	info, err := m.Info()
+	if err != nil {
+		return err
+	}
+
+	// Prefer existing location of script
+	var names = scriptFiles[info.CodeFormat]
+	var name = names[0]
+	for _, p := range names {
+		f, err := m.Archive.Open(p)
+		if err == nil {
+			f.Close()
+			name = p
+			break
+		} else if err != os.ErrNotExist {
+			return err
+		}
+	}
+
+	return m.Archive.WriteFile(name, script)
+}
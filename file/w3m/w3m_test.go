@@ -11,10 +11,16 @@ import (
 	"fmt"
 	"image"
 	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/nielsAD/gowarcraft3/file/mpq"
 	"github.com/nielsAD/gowarcraft3/file/w3m"
+	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
 func Example() {
@@ -51,6 +57,325 @@ func shaImage(img image.Image) string {
 	return base64.RawStdEncoding.EncodeToString(sha[:])
 }
 
+func TestExpandString(t *testing.T) {
+	var ts = map[int]string{1: "Small Wars", 12: "Rorslae"}
+	if s := w3m.ExpandString("TRIGSTR_001", ts); s != "Small Wars" {
+		t.Fatal("ExpandString", s)
+	}
+	if s := w3m.ExpandString("TRIGSTR_1 by TRIGSTR_012 (TRIGSTR_999)", ts); s != "Small Wars by Rorslae ()" {
+		t.Fatal("ExpandString", s)
+	}
+	if s := w3m.ExpandString("Player 1", ts); s != "Player 1" {
+		t.Fatal("ExpandString", s)
+	}
+}
+
+var meleeTriggerData, _ = w3m.LoadTriggerData(strings.NewReader(`
+[TriggerEvents]
+MapInitializationEvent=0
+_MapInitializationEvent_Category=TC_MAP
+
+[TriggerActions]
+MeleeStartingVisibility=0
+MeleeStartingHeroLimit=0
+MeleeGrantHeroItems=0
+MeleeStartingResources=0
+MeleeClearExcessUnits=0
+MeleeStartingUnits=0
+MeleeStartingAI=0
+MeleeInitVictoryDefeat=0
+`))
+
+func TestTriggerData(t *testing.T) {
+	data, err := w3m.LoadTriggerData(strings.NewReader(`
+// Comment
+[TriggerEvents]
+TriggerRegisterTimerEventSingle=0,real
+_TriggerRegisterTimerEventSingle_Defaults=5
+
+[TriggerActions]
+DoNothing=0,nothing
+SetUnitLifePercentBJ=0,unit,real
+
+[TriggerCalls]
+GetUnitStateSwap=0,1,real,unitstate,unit
+GetLastCreatedUnit=0,0,unit
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var args = []struct {
+		typ  w3m.ECAType
+		name string
+		args int
+	}{
+		{w3m.ECAEvent, "TriggerRegisterTimerEventSingle", 1},
+		{w3m.ECAAction, "DoNothing", 0},
+		{w3m.ECAAction, "SetUnitLifePercentBJ", 2},
+		{w3m.ECACall, "GetUnitStateSwap", 2},
+		{w3m.ECACall, "GetLastCreatedUnit", 0},
+	}
+	for _, a := range args {
+		if n, ok := data.Arguments(a.typ, a.name); !ok || n != a.args {
+			t.Fatalf("%v %v arguments %v != %v", a.typ, a.name, n, a.args)
+		}
+	}
+
+	if _, ok := data.Arguments(w3m.ECAEvent, "_TriggerRegisterTimerEventSingle_Defaults"); ok {
+		t.Fatal("Expected meta keys to be skipped")
+	}
+	if _, ok := data.Arguments(w3m.ECACondition, "DoNothing"); ok {
+		t.Fatal("Expected sections to be separated")
+	}
+}
+
+func TestMergeObjects(t *testing.T) {
+	var hfoo = protocol.DString("hfoo")
+	var hcst = protocol.DString("h000")
+	var uhpm = w3m.ObjectField{ID: protocol.DString("uhpm")}
+	var unam = w3m.ObjectField{ID: protocol.DString("unam")}
+
+	var base = w3m.ObjectData{
+		hfoo: w3m.ObjectFields{uhpm: int32(420), unam: "Footman"},
+	}
+	var objs = w3m.Objects{
+		Original: []w3m.Object{
+			{BaseID: hfoo, ID: hfoo, Modifications: []w3m.Modification{{ID: uhpm.ID, Type: w3m.ValueInt, Value: int32(500)}}},
+		},
+		Custom: []w3m.Object{
+			{BaseID: hfoo, ID: hcst, Modifications: []w3m.Modification{{ID: unam.ID, Type: w3m.ValueString, Value: "Custom"}}},
+		},
+	}
+
+	var res = objs.Merge(base)
+	var expected = w3m.ObjectData{
+		hfoo: w3m.ObjectFields{uhpm: int32(500), unam: "Footman"},
+		hcst: w3m.ObjectFields{uhpm: int32(500), unam: "Custom"},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("Merge mismatch %v != %v", res, expected)
+	}
+	if base[hfoo][uhpm] != int32(420) {
+		t.Fatal("Expected base to be unmodified")
+	}
+}
+
+func TestPathingMap(t *testing.T) {
+	const x = w3m.PathNoWalk | w3m.PathNoBuild
+	var p = w3m.PathingMap{
+		Width:  4,
+		Height: 3,
+		Cells: []w3m.PathingFlags{
+			0, 0, x, 0,
+			x, x, x, 0,
+			0, w3m.PathNoBuild, x, 0,
+		},
+	}
+
+	if !p.Walkable(0, 0) || p.Walkable(2, 0) || p.Walkable(-1, 0) || p.Walkable(4, 0) {
+		t.Fatal("Walkable mismatch")
+	}
+	if !p.Walkable(1, 2) || p.Buildable(1, 2) || !p.Buildable(3, 2) {
+		t.Fatal("Buildable mismatch")
+	}
+
+	if r := p.Region(3, 0, w3m.PathingFlags.Walkable); len(r) != 3 {
+		t.Fatalf("Region size mismatch %v", r)
+	}
+	if r := p.Region(2, 0, w3m.PathingFlags.Walkable); r != nil {
+		t.Fatalf("Expected empty region, got %v", r)
+	}
+
+	labels, n := p.Regions(w3m.PathingFlags.Walkable)
+	if n != 3 {
+		t.Fatalf("Region count mismatch %v != 3", n)
+	}
+	if labels[0] != labels[1] || labels[0] == labels[3] || labels[2] != 0 || labels[8] != labels[9] || labels[3] != labels[11] {
+		t.Fatalf("Region labels mismatch %v", labels)
+	}
+
+	if _, n := p.Regions(w3m.PathingFlags.Buildable); n != 3 {
+		t.Fatalf("Buildable region count mismatch %v != 3", n)
+	}
+}
+
+var configJASS = `
+function InitCustomPlayerSlots takes nothing returns nothing
+    // Player 0
+    call SetPlayerStartLocation( Player(0), 0 )
+    call ForcePlayerStartLocation( Player(0), 0 )
+    call SetPlayerRacePreference( Player(0), RACE_PREF_HUMAN )
+    call SetPlayerController( Player(0), MAP_CONTROL_USER )
+
+    // Player 1
+    call SetPlayerStartLocation( Player(1), 1 )
+    call SetPlayerRacePreference( Player(1), RACE_PREF_NIGHTELF )
+    call SetPlayerController( Player(1), MAP_CONTROL_COMPUTER )
+endfunction
+
+function InitCustomTeams takes nothing returns nothing
+    call SetPlayerTeam( Player(0), 0 )
+    call SetPlayerTeam( Player(1), 1 )
+endfunction
+
+function config takes nothing returns nothing
+    call SetMapName( "TRIGSTR_004" )
+    call SetMapDescription( "Foo \"Bar\"" )
+    call SetPlayers( 2 )
+    call SetTeams( 2 )
+    call SetGamePlacement( MAP_PLACEMENT_TEAMS_TOGETHER )
+
+    call DefineStartLocation( 0, -1664.0, 1152.0 )
+    call DefineStartLocation( 1, 1280, -1664.0 )
+
+    call InitCustomPlayerSlots(  )
+    call InitCustomTeams(  )
+    call SetPlayerSlotAvailable( Player(0), MAP_CONTROL_USER )
+    call SetPlayerSlotAvailable( Player(1), MAP_CONTROL_COMPUTER )
+endfunction
+
+function main takes nothing returns nothing
+    call SetPlayers( 12 )
+endfunction
+`
+
+var configLua = `
+--[[ Player setup
+]]
+function InitCustomPlayerSlots()
+    SetPlayerStartLocation(Player(0), 0)
+    ForcePlayerStartLocation(Player(0), 0)
+    SetPlayerRacePreference(Player(0), RACE_PREF_HUMAN)
+    SetPlayerController(Player(0), MAP_CONTROL_USER)
+
+    SetPlayerStartLocation(Player(1), 1)
+    SetPlayerRacePreference(Player(1), RACE_PREF_NIGHTELF)
+    SetPlayerController(Player(1), MAP_CONTROL_COMPUTER)
+end
+
+function InitCustomTeams()
+    for i = 0, 1 do
+        SetPlayerTeam(Player(i), i)
+    end
+    SetPlayerTeam(Player(0), 0)
+    SetPlayerTeam(Player(1), 1)
+end
+
+local function unused()
+    local t = CreateTrigger()
+    TriggerAddAction(t, function()
+        if GetTriggerPlayer() == Player(0) then
+            SetPlayers(12)
+        end
+    end)
+end
+
+function config()
+    SetMapName("TRIGSTR_004")
+    SetMapDescription('Foo "Bar"') -- comment
+    SetPlayers(2)
+    SetTeams(2)
+    SetGamePlacement(MAP_PLACEMENT_TEAMS_TOGETHER)
+
+    DefineStartLocation(0, -1664.0, 1152.0)
+    DefineStartLocation(1, 1280, -1.664e3)
+
+    InitCustomPlayerSlots()
+    InitCustomTeams()
+    SetPlayerSlotAvailable(Player(0), MAP_CONTROL_USER)
+    SetPlayerSlotAvailable(Player(1), MAP_CONTROL_COMPUTER)
+end
+
+function main()
+    SetPlayers(12)
+end
+`
+
+func TestScriptConfig(t *testing.T) {
+	var expected = w3m.ScriptConfig{
+		Name:        "TRIGSTR_004",
+		Description: "Foo \"Bar\"",
+		NumPlayers:  2,
+		NumTeams:    2,
+		Placement:   "MAP_PLACEMENT_TEAMS_TOGETHER",
+		Players: []w3m.Player{
+			w3m.Player{
+				Type:      w3m.PlayerHuman,
+				Race:      w3m.RaceHuman,
+				Flags:     w3m.PlayerFlagFixedPos,
+				StartPosX: -1664,
+				StartPosY: 1152,
+			},
+			w3m.Player{
+				ID:        1,
+				Type:      w3m.PlayerComputer,
+				Race:      w3m.RaceNightElf,
+				StartPosX: 1280,
+				StartPosY: -1664,
+			},
+		},
+		Forces: []w3m.Force{
+			w3m.Force{PlayerSet: 0x01},
+			w3m.Force{PlayerSet: 0x02},
+		},
+	}
+
+	for _, s := range []struct {
+		src    string
+		format w3m.GameCodeFormat
+	}{
+		{configJASS, w3m.GameCodeFormatJASS},
+		{configLua, w3m.GameCodeFormatLua},
+	} {
+		c, err := w3m.ParseScriptConfig(s.src, s.format)
+		if err != nil {
+			t.Fatal(s.format, err)
+		}
+		if !reflect.DeepEqual(c, &expected) {
+			t.Fatalf("%v config mismatch %+v\n", s.format, c)
+		}
+	}
+
+	if _, err := w3m.ParseScriptConfig("function main takes nothing returns nothing\nendfunction", w3m.GameCodeFormatJASS); err != w3m.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat without config, got", err)
+	}
+}
+
+func TestDecodeProfile(t *testing.T) {
+	var p = w3m.DecodeProfile([]byte("\xEF\xBB\xBF// Comment\r\nignored=1\r\n[hfoo]\r\nName=TRIGSTR_001\r\nTip = Foo=Bar \r\n\r\n[Misc]\nGoldTextHeight=0.024\n[hfoo]\nHotkey=F\n"))
+	var expected = w3m.Profile{
+		"hfoo": map[string]string{"Name": "TRIGSTR_001", "Tip": "Foo=Bar", "Hotkey": "F"},
+		"Misc": map[string]string{"GoldTextHeight": "0.024"},
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatal("Profile mismatch", p)
+	}
+
+	var m = w3m.DecodeProfile([]byte("[Misc]\nGoldTextHeight=0.024\nStrAttackBonus=1\nHeroExpRange=\"1200\", 600\nBadInt=foo\n"))
+	if f, ok := m.Float("misc", "goldtextheight"); !ok || f != 0.024 {
+		t.Fatal("Float mismatch", f)
+	}
+	if i, ok := m.Int("Misc", "StrAttackBonus"); !ok || i != 1 {
+		t.Fatal("Int mismatch", i)
+	}
+	if l := m.List("Misc", "HeroExpRange"); !reflect.DeepEqual(l, []string{"1200", "600"}) {
+		t.Fatal("List mismatch", l)
+	}
+	if i, ok := m.Int("Misc", "HeroExpRange"); !ok || i != 1200 {
+		t.Fatal("Int list mismatch", i)
+	}
+	if _, ok := m.Int("Misc", "BadInt"); ok {
+		t.Fatal("Expected invalid int")
+	}
+	if _, ok := m.Value("Misc", "Missing"); ok {
+		t.Fatal("Expected missing key")
+	}
+	if _, ok := m.Value("Missing", "GoldTextHeight"); ok {
+		t.Fatal("Expected missing section")
+	}
+}
+
 func TestFiles(t *testing.T) {
 	var files = []struct {
 		file       string
@@ -60,6 +385,10 @@ func TestFiles(t *testing.T) {
 		previewSHA string
 		minimapSHA string
 		checksum   string
+		fileHash   w3m.FileHash
+		doodads    int
+		triggers   int
+		objects    int
 	}{
 		{
 			"test_roc.w3m",
@@ -69,7 +398,7 @@ func TestFiles(t *testing.T) {
 				FileFormat:       18,
 				SaveCount:        2,
 				EditorVersion:    6059,
-				Name:             "",
+				Name:             "Smallest Map",
 				Author:           "DragonX",
 				Description:      "Smallest map in W3",
 				SuggestedPlayers: "Any",
@@ -97,10 +426,15 @@ func TestFiles(t *testing.T) {
 				},
 				CustomUpgradeAvailabilities: []w3m.CustomUpgradeAvailability{},
 				CustomTechAvailabilities:    []w3m.CustomTechAvailability{},
+				RandomUnitTables:            []w3m.RandomUnitTable{},
 			},
 			"",
 			"rEfl+K13/fxgOhjUqXxjPjsoLb7JulvzFvNpMab101cr8V9wKLNZFQcUD+TFSH2j7mgMoSb9bAyBkYA6sZU0Cg",
 			"0xDD4E3EBE|P5c/izfa1qstJu5zYYVyc2FD2gE",
+			w3m.FileHash{Size: 15802, CRC: 0x648A2F17},
+			84,
+			1,
+			0,
 		},
 		{
 			"test_tft.w3x",
@@ -131,6 +465,7 @@ func TestFiles(t *testing.T) {
 						Type:      w3m.PlayerHuman,
 						Race:      w3m.RaceHuman,
 						Flags:     w3m.PlayerFlagFixedPos,
+						Name:      "Player 1",
 						StartPosX: -1664,
 						StartPosY: 1152,
 					},
@@ -152,10 +487,16 @@ func TestFiles(t *testing.T) {
 				},
 				CustomUpgradeAvailabilities: []w3m.CustomUpgradeAvailability{},
 				CustomTechAvailabilities:    []w3m.CustomTechAvailability{},
+				RandomUnitTables:            []w3m.RandomUnitTable{},
+				RandomItemTables:            []w3m.RandomItemTable{},
 			},
 			"",
 			"cF03T1FzQzhwZwm3F/yp0fo8uDbHe/3qqqOQyJLKcg5HEHQTtk5M08L6mbDoRvzdbWd8SgWNQ+Fb3qSaovCuYg",
 			"0x7F321A74|/1ndO+WvBCWiQutD9VyCefo3GYM",
+			w3m.FileHash{Size: 15148, CRC: 0x7B626511},
+			120,
+			0,
+			6,
 		},
 	}
 
@@ -201,8 +542,273 @@ func TestFiles(t *testing.T) {
 			t.Fatalf("%v checksum mismatch %v != %v\n", f.file, hash, f.checksum)
 		}
 
+		fh, err := w3m.HashFile("./" + f.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *fh != f.fileHash {
+			t.Fatalf("%v file hash mismatch %+v != %+v\n", f.file, *fh, f.fileHash)
+		}
+
+		units, err := m.Units()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		var start = map[uint32]w3m.Unit{}
+		for _, s := range units.StartLocations() {
+			start[s.Owner] = s
+		}
+		for _, p := range f.info.Players {
+			if s, ok := start[p.ID]; !ok || s.X != p.StartPosX || s.Y != p.StartPosY {
+				t.Fatalf("%v start location mismatch %v,%v != %v,%v\n", f.file, s.X, s.Y, p.StartPosX, p.StartPosY)
+			}
+		}
+
+		doo, err := m.Doodads()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if len(doo.Doodads) != f.doodads {
+			t.Fatalf("%v doodad count mismatch %v != %v\n", f.file, len(doo.Doodads), f.doodads)
+		}
+
+		if reg, err := m.Regions(); err != nil {
+			t.Fatal(f.file, err)
+		} else if reg.FileFormat != 5 || len(reg.Regions) != 0 {
+			t.Fatalf("%v region mismatch %+v\n", f.file, reg)
+		}
+		if cam, err := m.Cameras(); err != nil {
+			t.Fatal(f.file, err)
+		} else if len(cam.Cameras) != 0 {
+			t.Fatalf("%v camera mismatch %+v\n", f.file, cam)
+		}
+		if _, err := m.Misc(); err != os.ErrNotExist {
+			t.Fatalf("%v expected no gameplay constants, got %v\n", f.file, err)
+		}
+		if _, err := m.Sounds(); err != os.ErrNotExist {
+			t.Fatalf("%v expected no sounds, got %v\n", f.file, err)
+		}
+
+		trig, err := m.Triggers(meleeTriggerData)
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if len(trig.Triggers) != f.triggers {
+			t.Fatalf("%v trigger count mismatch %v != %v\n", f.file, len(trig.Triggers), f.triggers)
+		}
+
+		ter, err := m.Terrain()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if ter.Width-1 < int(inf.Width) || ter.Height-1 < int(inf.Height) || len(ter.Tilepoints) != ter.Width*ter.Height {
+			t.Fatalf("%v terrain size mismatch %vx%v\n", f.file, ter.Width, ter.Height)
+		}
+		if ter.Tilepoint(ter.Width, 0) != nil || ter.Tilepoint(0, -1) != nil {
+			t.Fatalf("%v expected nil tilepoint out of bounds\n", f.file)
+		}
+		if x, y := ter.Grid(ter.Position(3, 5)); x != 3 || y != 5 {
+			t.Fatalf("%v grid position mismatch %v,%v\n", f.file, x, y)
+		}
+
+		path, err := m.PathingMap()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if path.Width != (ter.Width-1)*4 || path.Height != (ter.Height-1)*4 {
+			t.Fatalf("%v pathing map size mismatch %vx%v\n", f.file, path.Width, path.Height)
+		}
+		if _, n := path.Regions(w3m.PathingFlags.Walkable); n != 1 {
+			t.Fatalf("%v walkable region count mismatch %v != 1\n", f.file, n)
+		}
+
+		var numObjects int
+		if objs, err := m.Objects(w3m.ObjectUnit); err == nil {
+			numObjects = len(objs.Original) + len(objs.Custom)
+		} else if err != os.ErrNotExist {
+			t.Fatal(f.file, err)
+		}
+		if numObjects != f.objects {
+			t.Fatalf("%v object count mismatch %v != %v\n", f.file, numObjects, f.objects)
+		}
+
+		cfg, err := m.ScriptConfig()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if len(cfg.Players) != len(inf.Players) || cfg.Name != inf.Name {
+			t.Fatalf("%v script config mismatch %+v\n", f.file, cfg)
+		}
+		for i, p := range cfg.Players {
+			var q = inf.Players[i]
+			if p.ID != q.ID || p.Type != q.Type || p.Race != q.Race || p.Flags != q.Flags || p.StartPosX != q.StartPosX || p.StartPosY != q.StartPosY {
+				t.Fatalf("%v script player mismatch %+v != %+v\n", f.file, p, q)
+			}
+		}
+
+		if loc, err := m.Locales(); err != nil {
+			t.Fatal(f.file, err)
+		} else if len(loc) != 0 {
+			t.Fatalf("%v unexpected locales %v\n", f.file, loc)
+		}
+
+		// Fall back to neutral locale
+		m.SetLocale(mpq.LocaleGerman, mpq.LocaleFrench)
+		if inf2, err := m.Info(); err != nil {
+			t.Fatal(f.file, err)
+		} else if !reflect.DeepEqual(inf, inf2) {
+			t.Fatalf("%v localized info mismatch\n", f.file)
+		}
+		m.SetLocale()
+
+		if a := m.Analyze(); a.Flags != 0 || !a.Hostable() || len(a.Corrupt) != 0 {
+			t.Fatalf("%v protection mismatch %v %v\n", f.file, a.Flags, a.Corrupt)
+		}
+
+		if anon, err := m.Discover(); err != nil {
+			t.Fatal(err)
+		} else if len(anon) != 0 {
+			t.Fatalf("%v anonymous files %v\n", f.file, anon)
+		}
+
 		if err := m.Close(); err != nil {
 			t.Fatal(err)
 		}
 	}
 }
+
+func copyFile(dst string, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "w3m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var file = dir + "/test_tft.w3x"
+	if err := copyFile(file, "./test_tft.w3x"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := w3m.OpenWritable(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inf, err := m.RawInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	units, err := m.Units()
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := m.Objects(w3m.ObjectUnit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inf.Name = "Modified Map"
+	units.Units = units.Units[:len(units.Units)-1]
+	objs.Custom = objs.Custom[:0]
+
+	var script = []byte("function main takes nothing returns nothing\r\nendfunction\r\n")
+	var ts = map[int]string{7: "Modified Author", 1000: "Bar\nBaz"}
+	var tsGerman = map[int]string{7: "Geänderter Autor"}
+
+	if err := m.WriteInfo(inf); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteUnits(units); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteObjects(objs); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteTriggerStrings(ts); err != nil {
+		t.Fatal(err)
+	}
+	m.SetLocale(mpq.LocaleGerman)
+	if err := m.WriteTriggerStrings(tsGerman); err != nil {
+		t.Fatal(err)
+	}
+	m.SetLocale()
+	if err := m.WriteScript(script); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err = w3m.Open(file); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if inf2, err := m.RawInfo(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(inf, inf2) || inf2.Author != "TRIGSTR_007" {
+		t.Fatal("RawInfo() mismatch after write", inf2.Author)
+	}
+	if inf2, err := m.Info(); err != nil {
+		t.Fatal(err)
+	} else if inf2.Name != "Modified Map" || inf2.Author != ts[7] {
+		t.Fatal("Info() mismatch after write", inf2.Author)
+	}
+	if units2, err := m.Units(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(units, units2) {
+		t.Fatal("Units() mismatch after write")
+	}
+	if objs2, err := m.Objects(w3m.ObjectUnit); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(objs, objs2) {
+		t.Fatal("Objects() mismatch after write")
+	}
+	if ts2, err := m.TriggerStrings(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(ts, ts2) {
+		t.Fatal("TriggerStrings() mismatch after write", ts2)
+	}
+
+	m.SetLocale(mpq.LocaleGerman)
+	if ts2, err := m.TriggerStrings(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(tsGerman, ts2) {
+		t.Fatal("Localized TriggerStrings() mismatch after write", ts2)
+	}
+	if inf2, err := m.Info(); err != nil {
+		t.Fatal(err)
+	} else if inf2.Author != tsGerman[7] {
+		t.Fatal("Localized Info() mismatch after write", inf2.Author)
+	}
+	m.SetLocale()
+
+	f, err := m.Archive.Open("war3map.j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if b, err := ioutil.ReadAll(f); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(b, script) {
+		t.Fatal("Script mismatch after write")
+	}
+}
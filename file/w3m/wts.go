@@ -6,29 +6,41 @@ package w3m
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/nielsAD/gowarcraft3/file/mpq"
 )
 
 // TriggerString recognition
 var reWTS = regexp.MustCompile("^STRING (\\d+)$")
-var reTS = regexp.MustCompile("^TRIGSTR_(\\d+)$")
+var reTS = regexp.MustCompile("TRIGSTR_(\\d+)")
+
+var bom = []byte{0xEF, 0xBB, 0xBF}
 
-// TriggerStrings from war3map.wts
+// TriggerStrings from war3map.wts (empty if the map has no string table)
 func (m *Map) TriggerStrings() (map[int]string, error) {
 	if m.ts == nil {
-		wts, err := m.Archive.Open("war3map.wts")
-		if err != nil {
+		wts, err := m.open("war3map.wts")
+		if err == os.ErrNotExist {
+			m.ts = make(map[int]string)
+			return m.ts, nil
+		} else if err != nil {
 			return nil, err
 		}
 		defer wts.Close()
 
 		buf := bufio.NewReader(wts)
 
-		if _, err := buf.Discard(1); err != nil && err != io.EOF {
-			return nil, err
+		// Skip UTF-8 byte order mark
+		if b, err := buf.Peek(len(bom)); err == nil && bytes.Equal(b, bom) {
+			buf.Discard(len(bom))
 		}
 
 		var ts = make(map[int]string)
@@ -65,12 +77,12 @@ func (m *Map) TriggerStrings() (map[int]string, error) {
 			var sb strings.Builder
 			for {
 				l, err := buf.ReadString('\n')
-				if err != nil {
-					return nil, err
-				}
 				if strings.TrimSpace(l) == "}" {
 					break
 				}
+				if err != nil {
+					return nil, err
+				}
 
 				if sb.Len() > 0 {
 					sb.WriteByte('\n')
@@ -87,22 +99,63 @@ func (m *Map) TriggerStrings() (map[int]string, error) {
 	return m.ts, nil
 }
 
-// ExpandString expands trigger strings in s and returns the expanded string
-func (m *Map) ExpandString(s string) (string, error) {
-	ts, err := m.TriggerStrings()
-	if err != nil {
-		return "", err
+// EncodeTriggerStrings to war3map.wts format
+func EncodeTriggerStrings(ts map[int]string) []byte {
+	var ids = make([]int, 0, len(ts))
+	for id := range ts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var buf bytes.Buffer
+	buf.Write(bom)
+	for _, id := range ids {
+		fmt.Fprintf(&buf, "STRING %d\r\n{\r\n%s\r\n}\r\n\r\n", id, strings.Replace(ts[id], "\n", "\r\n", -1))
 	}
 
-	match := reTS.FindStringSubmatch(s)
-	if ts == nil || len(match) == 0 {
+	return buf.Bytes()
+}
+
+// WriteTriggerStrings replaces war3map.wts in a map opened with OpenWritable
+// Strings are stored in the first preferred locale (see SetLocale), or the neutral locale if none
+func (m *Map) WriteTriggerStrings(ts map[int]string) error {
+	var loc = mpq.LocaleNeutral
+	if len(m.locales) > 0 {
+		loc = m.locales[0]
+	}
+	if err := m.Archive.WriteFileLocale("war3map.wts", EncodeTriggerStrings(ts), loc); err != nil {
+		return err
+	}
+
+	m.ts = make(map[int]string, len(ts))
+	for id, s := range ts {
+		m.ts[id] = s
+	}
+
+	return nil
+}
+
+// ExpandString expands all TRIGSTR_### references in s and returns the expanded string
+func (m *Map) ExpandString(s string) (string, error) {
+	if !strings.Contains(s, "TRIGSTR_") {
 		return s, nil
 	}
 
-	id, err := strconv.Atoi(match[1])
+	ts, err := m.TriggerStrings()
 	if err != nil {
 		return "", err
 	}
 
-	return ts[id], nil
+	return ExpandString(s, ts), nil
+}
+
+// ExpandString expands all TRIGSTR_### references in s with the strings in ts (empty for unknown references)
+func ExpandString(s string, ts map[int]string) string {
+	return reTS.ReplaceAllStringFunc(s, func(ref string) string {
+		id, err := strconv.Atoi(ref[len("TRIGSTR_"):])
+		if err != nil {
+			return ref
+		}
+		return ts[id]
+	})
 }
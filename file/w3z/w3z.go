@@ -0,0 +1,159 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package w3z implements a decoder for Warcraft III saved game files (w3z).
+//
+// Saved games share the compressed container format of replays (see package w3g).
+// Only the game setup at the start of the decompressed data is decoded, the
+// game state itself is loaded by the clients. To open a file, use w3z.Open().
+//
+// Format:
+//
+//    size/type | Description
+//   -----------+-----------------------------------------------------------
+//     1 dword  | unknown
+//     n bytes  | zero terminated string map path
+//     n bytes  | zero terminated string game name
+//     1 byte   | unknown (always zero so far)
+//     n bytes  | encoded stat string (see w3gs.GameSettings)
+//     1 dword  | number of slots
+//     1 dword  | game flags
+//     1 dword  | unknown
+//     1 dword  | unknown
+//     n bytes  | slot info (see w3gs.SlotInfo)
+//     1 dword  | magic number (replaces map checksum when advertising)
+//
+package w3z
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Errors
+var (
+	ErrBadFormat = errors.New("w3z: Invalid file format")
+)
+
+// MaxSetupSize is the max number of decompressed bytes read to decode the game setup
+const MaxSetupSize = 16384
+
+// SaveGame information for a Warcraft III saved game
+type SaveGame struct {
+	w3g.Header
+	MapPath      string
+	GameName     string
+	GameSettings w3gs.GameSettings
+	SlotsTotal   uint32
+	GameFlags    w3gs.GameFlags
+	SlotInfo     w3gs.SlotInfo
+	MagicNumber  uint32
+}
+
+// Open a w3z file
+func Open(name string) (*SaveGame, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var b = bufio.NewReaderSize(f, 8192)
+	if _, err := w3g.FindHeader(b); err != nil {
+		return nil, ErrBadFormat
+	}
+
+	return Decode(b)
+}
+
+// Decode a saved game from r
+func Decode(r io.Reader) (*SaveGame, error) {
+	hdr, data, _, err := w3g.DecodeHeader(r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var size = data.SizeTotal
+	if size > MaxSetupSize {
+		size = MaxSetupSize
+	}
+
+	var buf = protocol.Buffer{Bytes: make([]byte, size)}
+	if _, err := io.ReadFull(data, buf.Bytes); err != nil {
+		return nil, err
+	}
+
+	var res = SaveGame{Header: *hdr}
+	if err := res.DeserializeContent(&buf); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// DeserializeContent decodes the game setup from the decompressed data in buf
+func (s *SaveGame) DeserializeContent(buf *protocol.Buffer) error {
+	var enc = w3gs.Encoding{GameVersion: s.GameVersion.Version}
+	var err error
+
+	if buf.Size() < 4 {
+		return io.ErrShortBuffer
+	}
+	buf.Skip(4)
+
+	if s.MapPath, err = buf.ReadCString(); err != nil {
+		return err
+	}
+	if s.GameName, err = buf.ReadCString(); err != nil {
+		return err
+	}
+
+	if buf.Size() < 1 {
+		return io.ErrShortBuffer
+	}
+	buf.Skip(1)
+
+	if err := s.GameSettings.DeserializeContent(buf, &enc); err != nil {
+		return err
+	}
+
+	if buf.Size() < 18 {
+		return io.ErrShortBuffer
+	}
+	s.SlotsTotal = buf.ReadUInt32()
+	s.GameFlags = w3gs.GameFlags(buf.ReadUInt32())
+	buf.Skip(8)
+
+	if err := s.SlotInfo.DeserializeContent(buf, &enc); err != nil {
+		return err
+	}
+	if len(s.SlotInfo.Slots) == 0 {
+		return ErrBadFormat
+	}
+
+	if buf.Size() < 4 {
+		return io.ErrShortBuffer
+	}
+	s.MagicNumber = buf.ReadUInt32()
+
+	return nil
+}
+
+// Settings returns the GameSettings to advertise when hosting the saved game stored in file name
+// Clients look up the save game in their local "Save\Multiplayer" directory by file name
+func (s *SaveGame) Settings(name string) w3gs.GameSettings {
+	var res = s.GameSettings
+	res.MapWidth = 0
+	res.MapHeight = 0
+	res.MapXoro = s.MagicNumber
+	res.MapPath = "Save\\Multiplayer\\" + filepath.Base(name)
+	return res
+}
@@ -0,0 +1,90 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3z_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/file/w3z"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestDecode(t *testing.T) {
+	var settings = w3gs.GameSettings{
+		GameSettingFlags: w3gs.SettingSpeedFast,
+		MapWidth:         116,
+		MapHeight:        84,
+		MapXoro:          0xDEADBEEF,
+		MapPath:          "Maps\\FrozenThrone\\(2)EchoIsles.w3x",
+		HostName:         "niels",
+	}
+	var slots = w3gs.SlotInfo{
+		Slots: []w3gs.SlotData{
+			w3gs.SlotData{PlayerID: 1, DownloadStatus: 100, SlotStatus: w3gs.SlotOccupied, Team: 0, Color: 0, Race: w3gs.RaceHuman, ComputerType: w3gs.ComputerNormal, Handicap: 100},
+			w3gs.SlotData{PlayerID: 2, DownloadStatus: 100, SlotStatus: w3gs.SlotOccupied, Team: 1, Color: 1, Race: w3gs.RaceOrc, ComputerType: w3gs.ComputerNormal, Handicap: 100},
+		},
+		RandomSeed: 0x12345678,
+		SlotLayout: w3gs.LayoutMelee,
+		NumPlayers: 2,
+	}
+
+	var data protocol.Buffer
+	data.WriteUInt32(0)
+	data.WriteCString(settings.MapPath)
+	data.WriteCString("gowarcraft3")
+	data.WriteUInt8(0)
+	settings.SerializeContent(&data, &w3gs.Encoding{})
+	data.WriteUInt32(12)
+	data.WriteUInt32(uint32(w3gs.GameFlagSavedGame | w3gs.GameFlagCustomGame))
+	data.WriteUInt32(1)
+	data.WriteUInt32(0)
+	slots.SerializeContent(&data, &w3gs.Encoding{})
+	data.WriteUInt32(0xCAFEBABE)
+	data.WriteBlob(make([]byte, 20000)) // Game state
+
+	var b bytes.Buffer
+	e, err := w3g.NewEncoder(&b, w3g.Encoding{Encoding: w3gs.Encoding{GameVersion: 26}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.GameVersion = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+	if _, err := e.Write(data.Bytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	save, err := w3z.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if save.GameVersion.Version != 26 || save.GameName != "gowarcraft3" || save.MapPath != settings.MapPath {
+		t.Fatal("Header mismatch", save)
+	}
+	if save.SlotsTotal != 12 || save.GameFlags != w3gs.GameFlagSavedGame|w3gs.GameFlagCustomGame || save.MagicNumber != 0xCAFEBABE {
+		t.Fatal("Setup mismatch", save)
+	}
+	if !reflect.DeepEqual(save.GameSettings, settings) {
+		t.Fatal("GameSettings mismatch", save.GameSettings)
+	}
+	if !reflect.DeepEqual(save.SlotInfo, slots) {
+		t.Fatal("SlotInfo mismatch", save.SlotInfo)
+	}
+
+	var adv = save.Settings("/tmp/mygame.w3z")
+	if adv.MapPath != "Save\\Multiplayer\\mygame.w3z" || adv.MapXoro != 0xCAFEBABE || adv.MapWidth != 0 || adv.HostName != settings.HostName {
+		t.Fatal("Advertised settings mismatch", adv)
+	}
+
+	if _, err := w3z.Decode(bytes.NewReader(make([]byte, 100))); err == nil {
+		t.Fatal("Expected error for invalid file")
+	}
+}
@@ -90,7 +90,13 @@ func CreateBNCSKeyInfo(cdkey string, clientToken uint32, serverToken uint32) (*b
 
 // VerifyServerSignature received in SID_AUTH_INFO (0x50)
 func VerifyServerSignature(ip net.IP, sig *[128]byte) bool {
-	var aton = binary.LittleEndian.Uint32(ip.To4())
+	var ip4 = ip.To4()
+	if ip4 == nil {
+		// Signature is computed over the IPv4 address
+		return false
+	}
+
+	var aton = binary.LittleEndian.Uint32(ip4)
 	return C.nls_check_signature(C.uint32_t(aton), (*C.char)(unsafe.Pointer(&sig[0]))) != 0
 }
 
@@ -6,6 +6,7 @@
 package bnet
 
 import (
+	"context"
 	"io/ioutil"
 	"net"
 	"os"
@@ -27,7 +28,9 @@ import (
 // Config for bnet.Client
 type Config struct {
 	ServerAddr        string
+	Family            network.IPFamily
 	KeepAliveInterval time.Duration
+	KeepAliveTimeout  time.Duration
 	Platform          bncs.AuthInfoReq
 	BinPath           string
 	ExeInfo           string
@@ -40,6 +43,8 @@ type Config struct {
 	CDKeyOwner        string
 	CDKeys            []string
 	GamePort          uint16
+	Logger            network.Logger
+	Reconnect         network.ReconnectConfig
 }
 
 // Client represents a mocked BNCS client
@@ -75,8 +80,11 @@ var DefaultConfig = Config{
 	CDKeyOwner:        "gowarcraft3",
 	GamePort:          6112,
 	BinPath:           fs.FindInstallationDir(),
+	Reconnect:         network.DefaultReconnectConfig,
 }
 
+var _ network.Chat = (*Client)(nil)
+
 // NewClient initializes a Client struct
 func NewClient(conf *Config) (*Client, error) {
 	var c = Client{
@@ -90,6 +98,8 @@ func NewClient(conf *Config) (*Client, error) {
 		return nil, err
 	}
 
+	c.SetLogger(c.Logger)
+
 	if conf.Platform.GameVersion.Version == 0 {
 		if c.ExeVersion != 0 {
 			c.Platform.GameVersion.Version = (c.ExeVersion >> 16) & 0xFF
@@ -203,11 +213,22 @@ func (b *Client) Encoding() bncs.Encoding {
 //     7. Connection to BNFTPv2 to do file downloads
 //
 func (b *Client) DialWithConn(conn net.Conn) (*network.BNCSConn, error) {
+	return b.DialWithConnContext(context.Background(), conn)
+}
+
+// DialWithConnContext initializes a connection to server, aborts once ctx is done
+func (b *Client) DialWithConnContext(ctx context.Context, conn net.Conn) (*network.BNCSConn, error) {
 	conn.Write([]byte{bncs.ProtocolGreeting})
 
 	bncsconn := network.NewBNCSConn(conn, nil, b.Encoding())
+	bncsconn.SetLogger(b.Logger)
+
+	// Handshake goes through the same middleware and trace as the client connection
+	bncsconn.UseIngress(b.Ingress()...)
+	bncsconn.UseEgress(b.Egress()...)
+	bncsconn.SetTrace(b.Trace())
 
-	authInfo, err := b.sendAuthInfo(bncsconn)
+	authInfo, err := b.sendAuthInfo(ctx, bncsconn)
 	if err != nil {
 		bncsconn.Close()
 		return nil, err
@@ -219,7 +240,7 @@ func (b *Client) DialWithConn(conn net.Conn) (*network.BNCSConn, error) {
 	}
 
 	clientToken := uint32(time.Now().Unix())
-	authCheck, err := b.sendAuthCheck(bncsconn, clientToken, authInfo)
+	authCheck, err := b.sendAuthCheck(ctx, bncsconn, clientToken, authInfo)
 	if err != nil {
 		bncsconn.Close()
 		return nil, err
@@ -235,16 +256,16 @@ func (b *Client) DialWithConn(conn net.Conn) (*network.BNCSConn, error) {
 
 // Dial opens a new connection to server, verifies game version, and authenticates with CD keys
 func (b *Client) Dial() (*network.BNCSConn, error) {
+	return b.DialContext(context.Background())
+}
+
+// DialContext opens a new connection to server, aborts once ctx is done
+func (b *Client) DialContext(ctx context.Context) (*network.BNCSConn, error) {
 	if !strings.ContainsRune(b.ServerAddr, ':') {
 		b.ServerAddr += ":6112"
 	}
 
-	addr, err := net.ResolveTCPAddr("tcp", b.ServerAddr)
-	if err != nil {
-		return nil, err
-	}
-
-	conn, err := net.DialTCP("tcp", nil, addr)
+	conn, err := network.DialTCP(ctx, b.Family, b.ServerAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -253,7 +274,7 @@ func (b *Client) Dial() (*network.BNCSConn, error) {
 	conn.SetNoDelay(true)
 	conn.SetLinger(3)
 
-	return b.DialWithConn(conn)
+	return b.DialWithConnContext(ctx, conn)
 }
 
 // Logon opens a new connection to server, logs on, and joins chat
@@ -278,6 +299,11 @@ func (b *Client) Dial() (*network.BNCSConn, error) {
 //  13. A sequence of chat events for entering chat follow.
 //
 func (b *Client) Logon() error {
+	return b.LogonContext(context.Background())
+}
+
+// LogonContext opens a new connection to server, logs on, and joins chat, aborts once ctx is done
+func (b *Client) LogonContext(ctx context.Context) error {
 	srp, err := b.newSRP(b.Password)
 	if err != nil {
 		return err
@@ -285,12 +311,12 @@ func (b *Client) Logon() error {
 
 	defer srp.Free()
 
-	bncsconn, err := b.Dial()
+	bncsconn, err := b.DialContext(ctx)
 	if err != nil {
 		return err
 	}
 
-	logon, err := b.sendLogon(bncsconn, srp)
+	logon, err := b.sendLogon(ctx, bncsconn, srp)
 	if err != nil {
 		bncsconn.Close()
 		return err
@@ -301,7 +327,7 @@ func (b *Client) Logon() error {
 		return LogonResultToError(logon.Result)
 	}
 
-	proof, err := b.sendLogonProof(bncsconn, srp, logon)
+	proof, err := b.sendLogonProof(ctx, bncsconn, srp, logon)
 	if err != nil {
 		bncsconn.Close()
 		return err
@@ -325,7 +351,7 @@ func (b *Client) Logon() error {
 		return ErrPasswordVerification
 	}
 
-	chat, err := b.sendEnterChat(bncsconn)
+	chat, err := b.sendEnterChat(ctx, bncsconn)
 	if err != nil {
 		bncsconn.Close()
 		return err
@@ -336,6 +362,10 @@ func (b *Client) Logon() error {
 		return err
 	}
 
+	if b.Logger != nil {
+		b.Logger.Info("Logged on", network.LogKeyUser, chat.UniqueName, network.LogKeyPeer, b.ServerAddr)
+	}
+
 	b.UniqueName = chat.UniqueName
 	b.SetConn(bncsconn.Conn(), bncs.NewFactoryCache(bncs.DefaultFactory), b.Encoding())
 	return nil
@@ -351,6 +381,11 @@ func (b *Client) Logon() error {
 //  3. Client can continue with logon ([0x53] SID_AUTH_ACCOUNTLOGON)
 //
 func (b *Client) CreateAccount() error {
+	return b.CreateAccountContext(context.Background())
+}
+
+// CreateAccountContext registers a new account, aborts once ctx is done
+func (b *Client) CreateAccountContext(ctx context.Context) error {
 	srp, err := b.newSRP(b.Password)
 	if err != nil {
 		return err
@@ -358,14 +393,14 @@ func (b *Client) CreateAccount() error {
 
 	defer srp.Free()
 
-	bncsconn, err := b.Dial()
+	bncsconn, err := b.DialContext(ctx)
 	if err != nil {
 		return err
 	}
 
 	defer bncsconn.Close()
 
-	create, err := b.sendCreateAccount(bncsconn, srp)
+	create, err := b.sendCreateAccount(ctx, bncsconn, srp)
 	if err != nil {
 		return err
 	}
@@ -389,6 +424,11 @@ func (b *Client) CreateAccount() error {
 //  3. Client can continue with logon ([0x53] SID_AUTH_ACCOUNTLOGON)
 //
 func (b *Client) ChangePassword(newPassword string) error {
+	return b.ChangePasswordContext(context.Background(), newPassword)
+}
+
+// ChangePasswordContext of an existing account, aborts once ctx is done
+func (b *Client) ChangePasswordContext(ctx context.Context, newPassword string) error {
 	oldSRP, err := b.newSRP(b.Password)
 	if err != nil {
 		return err
@@ -403,14 +443,14 @@ func (b *Client) ChangePassword(newPassword string) error {
 
 	defer newSRP.Free()
 
-	bncsconn, err := b.Dial()
+	bncsconn, err := b.DialContext(ctx)
 	if err != nil {
 		return err
 	}
 
 	defer bncsconn.Close()
 
-	resp, err := b.sendChangePass(bncsconn, oldSRP)
+	resp, err := b.sendChangePass(ctx, bncsconn, oldSRP)
 	if err != nil {
 		return err
 	}
@@ -419,7 +459,7 @@ func (b *Client) ChangePassword(newPassword string) error {
 		return LogonResultToError(resp.Result)
 	}
 
-	proof, err := b.sendChangePassProof(bncsconn, oldSRP, newSRP, resp)
+	proof, err := b.sendChangePassProof(ctx, bncsconn, oldSRP, newSRP, resp)
 	if err != nil {
 		return err
 	}
@@ -443,12 +483,12 @@ func (b *Client) newSRP(password string) (SRP, error) {
 	return NewNLS(b.Username, password)
 }
 
-func (b *Client) sendAuthInfo(conn *network.BNCSConn) (*bncs.AuthInfoResp, error) {
+func (b *Client) sendAuthInfo(ctx context.Context, conn *network.BNCSConn) (*bncs.AuthInfoResp, error) {
 	if _, err := conn.Send(&b.Platform); err != nil {
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(10 * time.Second)
+	pkt, err := conn.NextPacketContext(ctx, 10*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -461,7 +501,7 @@ func (b *Client) sendAuthInfo(conn *network.BNCSConn) (*bncs.AuthInfoResp, error
 		return nil, ErrUnexpectedPacket
 	}
 
-	pkt, err = conn.NextPacket(10 * time.Second)
+	pkt, err = conn.NextPacketContext(ctx, 10*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -473,7 +513,7 @@ func (b *Client) sendAuthInfo(conn *network.BNCSConn) (*bncs.AuthInfoResp, error
 	}
 }
 
-func (b *Client) sendAuthCheck(conn *network.BNCSConn, clientToken uint32, authinfo *bncs.AuthInfoResp) (*bncs.AuthCheckResp, error) {
+func (b *Client) sendAuthCheck(ctx context.Context, conn *network.BNCSConn, clientToken uint32, authinfo *bncs.AuthInfoResp) (*bncs.AuthCheckResp, error) {
 	var exeInfo = b.ExeInfo
 	var exeVers = b.ExeVersion
 	var exeHash = b.ExeHash
@@ -545,7 +585,7 @@ func (b *Client) sendAuthCheck(conn *network.BNCSConn, clientToken uint32, authi
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(10 * time.Second)
+	pkt, err := conn.NextPacketContext(ctx, 10*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -557,7 +597,7 @@ func (b *Client) sendAuthCheck(conn *network.BNCSConn, clientToken uint32, authi
 	}
 }
 
-func (b *Client) sendLogon(conn *network.BNCSConn, srp SRP) (*bncs.AuthAccountLogonResp, error) {
+func (b *Client) sendLogon(ctx context.Context, conn *network.BNCSConn, srp SRP) (*bncs.AuthAccountLogonResp, error) {
 	var req = &bncs.AuthAccountLogonReq{
 		ClientKey: srp.ClientKey(),
 		Username:  b.Username,
@@ -567,7 +607,7 @@ func (b *Client) sendLogon(conn *network.BNCSConn, srp SRP) (*bncs.AuthAccountLo
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(15 * time.Second)
+	pkt, err := conn.NextPacketContext(ctx, 15*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -579,7 +619,7 @@ func (b *Client) sendLogon(conn *network.BNCSConn, srp SRP) (*bncs.AuthAccountLo
 	}
 }
 
-func (b *Client) sendLogonProof(conn *network.BNCSConn, srp SRP, logon *bncs.AuthAccountLogonResp) (*bncs.AuthAccountLogonProofResp, error) {
+func (b *Client) sendLogonProof(ctx context.Context, conn *network.BNCSConn, srp SRP, logon *bncs.AuthAccountLogonResp) (*bncs.AuthAccountLogonProofResp, error) {
 	var req = &bncs.AuthAccountLogonProofReq{
 		ClientPasswordProof: srp.PasswordProof(&logon.ServerKey, &logon.Salt),
 	}
@@ -588,7 +628,7 @@ func (b *Client) sendLogonProof(conn *network.BNCSConn, srp SRP, logon *bncs.Aut
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(10 * time.Second)
+	pkt, err := conn.NextPacketContext(ctx, 10*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -600,7 +640,7 @@ func (b *Client) sendLogonProof(conn *network.BNCSConn, srp SRP, logon *bncs.Aut
 	}
 }
 
-func (b *Client) sendCreateAccount(conn *network.BNCSConn, srp SRP) (*bncs.AuthAccountCreateResp, error) {
+func (b *Client) sendCreateAccount(ctx context.Context, conn *network.BNCSConn, srp SRP) (*bncs.AuthAccountCreateResp, error) {
 	salt, verifier, err := srp.AccountCreate()
 	if err != nil {
 		return nil, err
@@ -614,7 +654,7 @@ func (b *Client) sendCreateAccount(conn *network.BNCSConn, srp SRP) (*bncs.AuthA
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(10 * time.Second)
+	pkt, err := conn.NextPacketContext(ctx, 10*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -626,7 +666,7 @@ func (b *Client) sendCreateAccount(conn *network.BNCSConn, srp SRP) (*bncs.AuthA
 	}
 }
 
-func (b *Client) sendChangePass(conn *network.BNCSConn, srp SRP) (*bncs.AuthAccountChangePassResp, error) {
+func (b *Client) sendChangePass(ctx context.Context, conn *network.BNCSConn, srp SRP) (*bncs.AuthAccountChangePassResp, error) {
 	var req = &bncs.AuthAccountChangePassReq{
 		AuthAccountLogonReq: bncs.AuthAccountLogonReq{
 			ClientKey: srp.ClientKey(),
@@ -638,7 +678,7 @@ func (b *Client) sendChangePass(conn *network.BNCSConn, srp SRP) (*bncs.AuthAcco
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(15 * time.Second)
+	pkt, err := conn.NextPacketContext(ctx, 15*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -650,7 +690,7 @@ func (b *Client) sendChangePass(conn *network.BNCSConn, srp SRP) (*bncs.AuthAcco
 	}
 }
 
-func (b *Client) sendChangePassProof(conn *network.BNCSConn, oldSRP SRP, newSRP SRP, resp *bncs.AuthAccountChangePassResp) (*bncs.AuthAccountChangePassProofResp, error) {
+func (b *Client) sendChangePassProof(ctx context.Context, conn *network.BNCSConn, oldSRP SRP, newSRP SRP, resp *bncs.AuthAccountChangePassResp) (*bncs.AuthAccountChangePassProofResp, error) {
 	salt, verifier, err := newSRP.AccountCreate()
 	if err != nil {
 		return nil, err
@@ -666,7 +706,7 @@ func (b *Client) sendChangePassProof(conn *network.BNCSConn, oldSRP SRP, newSRP
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(10 * time.Second)
+	pkt, err := conn.NextPacketContext(ctx, 10*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -678,7 +718,7 @@ func (b *Client) sendChangePassProof(conn *network.BNCSConn, oldSRP SRP, newSRP
 	}
 }
 
-func (b *Client) sendEnterChat(conn *network.BNCSConn) (*bncs.EnterChatResp, error) {
+func (b *Client) sendEnterChat(ctx context.Context, conn *network.BNCSConn) (*bncs.EnterChatResp, error) {
 	if _, err := conn.Send(&bncs.NetGamePort{Port: b.GamePort}); err != nil {
 		return nil, err
 	}
@@ -687,7 +727,7 @@ func (b *Client) sendEnterChat(conn *network.BNCSConn) (*bncs.EnterChatResp, err
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(10 * time.Second)
+	pkt, err := conn.NextPacketContext(ctx, 10*time.Second)
 	for {
 		if err != nil {
 			return nil, err
@@ -701,44 +741,67 @@ func (b *Client) sendEnterChat(conn *network.BNCSConn) (*bncs.EnterChatResp, err
 			return nil, ErrUnexpectedPacket
 		}
 
-		pkt, err = conn.NextPacket(network.NoTimeout)
+		pkt, err = conn.NextPacketContext(ctx, network.NoTimeout)
 	}
 }
 
-func (b *Client) runKeepAlive() func() {
-	var stop = make(chan struct{})
+// Run reads packets and emits an event for each received packet
+// Not safe for concurrent invocation
+func (b *Client) Run() error {
+	return b.RunContext(context.Background())
+}
 
-	go func() {
-		var ticker = time.NewTicker(b.KeepAliveInterval)
+// RunContext reads packets until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (b *Client) RunContext(ctx context.Context) error {
+	if b.KeepAliveInterval != 0 || b.KeepAliveTimeout != 0 {
+		var k = network.NewBNCSKeepAlive(&b.BNCSConn, b.KeepAliveInterval, b.KeepAliveTimeout)
+		k.Emitter = &b.EventEmitter
 
-		var pkt bncs.KeepAlive
-		for {
-			select {
-			case <-stop:
-				ticker.Stop()
-				return
-			case <-ticker.C:
-				if _, err := b.Send(&pkt); err != nil && !network.IsCloseError(err) {
-					b.Fire(&network.AsyncError{Src: "runKeepAlive[Send]", Err: err})
-				}
-			}
-		}
-	}()
+		var eid = k.Bind(&b.EventEmitter)
+		defer b.Off(eid)
 
-	return func() {
-		stop <- struct{}{}
+		var stop = k.Start()
+		defer stop()
 	}
+
+	return b.BNCSConn.RunContext(ctx, &b.EventEmitter, 30*time.Second)
 }
 
-// Run reads packets and emits an event for each received packet
-// Not safe for concurrent invocation
-func (b *Client) Run() error {
-	if b.KeepAliveInterval != 0 {
-		var stop = b.runKeepAlive()
-		defer stop()
+// NewReconnector returns a Reconnector that logs on again whenever the connection breaks and rejoins the last channel
+// Authentication failures and cleanly closed connections are not retried
+func (b *Client) NewReconnector() *network.Reconnector {
+	var channel string
+	var r = network.NewReconnector(b.Reconnect, b.LogonContext, func(ctx context.Context) error {
+		var err = b.RunContext(ctx)
+		channel = b.Channel()
+		return err
+	})
+
+	r.Emitter = &b.EventEmitter
+	r.Probe = func(ctx context.Context) error {
+		var addr = b.ServerAddr
+		if !strings.ContainsRune(addr, ':') {
+			addr += ":6112"
+		}
+		return network.DialProbe(b.Family.Network("tcp"), addr)(ctx)
+	}
+	r.Resume = func(ctx context.Context) error {
+		if channel == "" || strings.EqualFold(channel, b.Channel()) {
+			return nil
+		}
+		return b.JoinChannel(channel)
+	}
+	r.Retry = func(err error) bool {
+		switch err {
+		case ErrInvalidGameVersion, ErrCDKeyInvalid, ErrCDKeyBanned, ErrUnknownAccount, ErrInvalidAccount, ErrIncorrectPassword:
+			return false
+		default:
+			return !network.IsCloseError(err)
+		}
 	}
 
-	return b.BNCSConn.Run(&b.EventEmitter, 30*time.Second)
+	return r
 }
 
 var emojiToText = func() *strings.Replacer {
@@ -783,10 +846,97 @@ func (b *Client) Say(s string) error {
 	return nil
 }
 
+// ChatUsers in channel (implements network.Chat)
+func (b *Client) ChatUsers() map[string]network.ChatUser {
+	var res = make(map[string]network.ChatUser)
+
+	b.chatmut.Lock()
+	for k, v := range b.users {
+		res[k] = v.ChatUser()
+	}
+	b.chatmut.Unlock()
+
+	return res
+}
+
+// JoinChannel leaves the current channel and joins another
+func (b *Client) JoinChannel(channel string) error {
+	_, err := b.SendRL(&bncs.JoinChannel{Flag: bncs.ChannelJoinOrCreate, Channel: channel})
+	return err
+}
+
+// Whisper sends a private chat message to user
+// May block while rate-limiting packets
+func (b *Client) Whisper(username string, s string) error {
+	return b.Say("/w " + username + " " + s)
+}
+
+// Kick user from the channel
+// May block while rate-limiting packets
+func (b *Client) Kick(username string) error {
+	return b.Say("/kick " + username)
+}
+
+// Ban user from the channel
+// May block while rate-limiting packets
+func (b *Client) Ban(username string) error {
+	return b.Say("/ban " + username)
+}
+
+// Unban user from the channel
+// May block while rate-limiting packets
+func (b *Client) Unban(username string) error {
+	return b.Say("/unban " + username)
+}
+
 // InitDefaultHandlers adds the default callbacks for relevant packets
 func (b *Client) InitDefaultHandlers() {
 	b.On(&bncs.Ping{}, b.onPing)
 	b.On(&bncs.ChatEvent{}, b.onChatEvent)
+	b.On(&Channel{}, b.onChannel)
+	b.On(&UserJoined{}, b.onUserJoined)
+	b.On(&UserLeft{}, b.onUserLeft)
+	b.On(&Chat{}, b.onChat)
+	b.On(&Whisper{}, b.onWhisper)
+}
+
+func (b *Client) onChannel(ev *network.Event) {
+	var pkt = ev.Arg.(*Channel)
+	b.Fire(&network.ChatChannelJoined{Channel: pkt.Name})
+}
+
+func (b *Client) onUserJoined(ev *network.Event) {
+	var pkt = ev.Arg.(*UserJoined)
+	b.Fire(&network.ChatUserJoined{ChatUser: pkt.User.ChatUser()})
+}
+
+func (b *Client) onUserLeft(ev *network.Event) {
+	var pkt = ev.Arg.(*UserLeft)
+	b.Fire(&network.ChatUserLeft{ChatUser: pkt.User.ChatUser()})
+}
+
+func (b *Client) onChat(ev *network.Event) {
+	var pkt = ev.Arg.(*Chat)
+	b.Fire(&network.ChatMessage{
+		ChatUser: pkt.User.ChatUser(),
+		Content:  pkt.Content,
+		Emote:    pkt.Type == bncs.ChatEmote,
+	})
+}
+
+func (b *Client) onWhisper(ev *network.Event) {
+	var pkt = ev.Arg.(*Whisper)
+
+	var user = network.ChatUser{Name: pkt.Username}
+	if u, ok := b.User(pkt.Username); ok {
+		user = u.ChatUser()
+	}
+
+	b.Fire(&network.ChatMessage{
+		ChatUser: user,
+		Content:  pkt.Content,
+		Whisper:  true,
+	})
 }
 
 func (b *Client) onPing(ev *network.Event) {
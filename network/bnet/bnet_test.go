@@ -5,10 +5,15 @@
 package bnet_test
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"testing"
+	"time"
 
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/network/bnet"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
 )
 
 func Example() {
@@ -38,3 +43,83 @@ func Example() {
 	// Run() blocks until the connection is closed
 	client.Run()
 }
+
+func TestChatEvents(t *testing.T) {
+	client, err := bnet.NewClient(&bnet.Config{ExeVersion: 0x011A0001})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var joined []network.ChatUser
+	var left []network.ChatUser
+	var msg []network.ChatMessage
+	client.On(&network.ChatUserJoined{}, func(ev *network.Event) {
+		joined = append(joined, ev.Arg.(*network.ChatUserJoined).ChatUser)
+	})
+	client.On(&network.ChatUserLeft{}, func(ev *network.Event) {
+		left = append(left, ev.Arg.(*network.ChatUserLeft).ChatUser)
+	})
+	client.On(&network.ChatMessage{}, func(ev *network.Event) {
+		msg = append(msg, *ev.Arg.(*network.ChatMessage))
+	})
+
+	var op = bnet.User{Name: "foo", Flags: bncs.ChatUserFlagOperator}
+	client.Fire(&bnet.UserJoined{User: op})
+	client.Fire(&bnet.Chat{User: op, Content: "hello", Type: bncs.ChatTalk})
+	client.Fire(&bnet.Chat{User: op, Content: "waves", Type: bncs.ChatEmote})
+	client.Fire(&bnet.Whisper{Username: "bar", Content: "psst"})
+	client.Fire(&bnet.UserLeft{User: op})
+
+	if len(joined) != 1 || joined[0].Name != "foo" || !joined[0].Operator {
+		t.Fatalf("ChatUserJoined mismatch %+v", joined)
+	}
+	if len(left) != 1 || left[0].Name != "foo" {
+		t.Fatalf("ChatUserLeft mismatch %+v", left)
+	}
+	if len(msg) != 3 ||
+		msg[0].Name != "foo" || msg[0].Content != "hello" || msg[0].Emote || msg[0].Whisper ||
+		msg[1].Content != "waves" || !msg[1].Emote ||
+		msg[2].Name != "bar" || msg[2].Content != "psst" || !msg[2].Whisper {
+		t.Fatalf("ChatMessage mismatch %+v", msg)
+	}
+}
+
+func TestDialTrace(t *testing.T) {
+	client, err := bnet.NewClient(&bnet.Config{ExeVersion: 0x011A0001})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	tw, err := network.NewTraceWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw.TraceBNCS(&client.BNCSConn)
+
+	var c1, c2 = net.Pipe()
+	go func() {
+		var greet [1]byte
+		c2.Read(greet[:])
+
+		var srv = network.NewBNCSConn(c2, nil, client.Encoding())
+		srv.NextPacket(time.Second)
+		srv.Close()
+	}()
+
+	if _, err := client.DialWithConn(c1); err == nil {
+		t.Fatal("Expected error after server closed connection")
+	}
+
+	tr, err := network.NewTraceReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Dir != network.TraceEgress || len(rec.Data) < 2 || rec.Data[0] != bncs.ProtocolSig || rec.Data[1] != bncs.PidAuthInfo {
+		t.Fatal("Expected SID_AUTH_INFO in trace, got", rec)
+	}
+}
@@ -7,6 +7,7 @@ package bnet
 import (
 	"errors"
 
+	"github.com/nielsAD/gowarcraft3/protocol"
 	"github.com/nielsAD/gowarcraft3/protocol/bncs"
 )
 
@@ -17,19 +18,19 @@ var (
 	ErrKeyDecoder           = errors.New("bnet: BNCSUtil call to keyDecoder failed")
 	ErrNLS                  = errors.New("bnet: BNCSUtil call to NLS failed")
 	ErrUnexpectedPacket     = errors.New("bnet: Received unexpected packet")
-	ErrAuthFail             = errors.New("bnet: Authentication failed")
+	ErrAuthFail             = protocol.NewError(protocol.KindRejected, "bnet: Authentication failed")
 	ErrInvalidServerSig     = errors.New("bnet: Authentication failed (invalid server signature)")
-	ErrInvalidGameVersion   = errors.New("bnet: Authentication failed (game version invalid)")
-	ErrCDKeyInvalid         = errors.New("bnet: Authentication failed (CD key invalid)")
-	ErrCDKeyInUse           = errors.New("bnet: Authentication failed (CD key in use)")
-	ErrCDKeyBanned          = errors.New("bnet: Authentication failed (CD key banned)")
-	ErrUnknownAccount       = errors.New("bnet: Authentication failed (account does not exist)")
-	ErrInvalidAccount       = errors.New("bnet: Authentication failed (account invalid)")
-	ErrPasswordVerification = errors.New("bnet: Authentication failed (server cannot verify password)")
-	ErrIncorrectPassword    = errors.New("bnet: Authentication failed (password incorrect)")
-	ErrAccountCreate        = errors.New("bnet: Account creation failed")
-	ErrAccountNameTaken     = errors.New("bnet: Account creation failed (account name taken)")
-	ErrAccountNameIllegal   = errors.New("bnet: Account creation failed (illegal account name)")
+	ErrInvalidGameVersion   = protocol.NewError(protocol.KindVersionMismatch, "bnet: Authentication failed (game version invalid)")
+	ErrCDKeyInvalid         = protocol.NewError(protocol.KindRejected, "bnet: Authentication failed (CD key invalid)")
+	ErrCDKeyInUse           = protocol.NewError(protocol.KindRejected, "bnet: Authentication failed (CD key in use)")
+	ErrCDKeyBanned          = protocol.NewError(protocol.KindRejected, "bnet: Authentication failed (CD key banned)")
+	ErrUnknownAccount       = protocol.NewError(protocol.KindRejected, "bnet: Authentication failed (account does not exist)")
+	ErrInvalidAccount       = protocol.NewError(protocol.KindRejected, "bnet: Authentication failed (account invalid)")
+	ErrPasswordVerification = protocol.NewError(protocol.KindRejected, "bnet: Authentication failed (server cannot verify password)")
+	ErrIncorrectPassword    = protocol.NewError(protocol.KindRejected, "bnet: Authentication failed (password incorrect)")
+	ErrAccountCreate        = protocol.NewError(protocol.KindRejected, "bnet: Account creation failed")
+	ErrAccountNameTaken     = protocol.NewError(protocol.KindRejected, "bnet: Account creation failed (account name taken)")
+	ErrAccountNameIllegal   = protocol.NewError(protocol.KindRejected, "bnet: Account creation failed (illegal account name)")
 )
 
 // AuthResultToError converts bncs.AuthResult to an appropriate error
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/protocol"
 	"github.com/nielsAD/gowarcraft3/protocol/bncs"
 )
@@ -28,6 +29,16 @@ func (u *User) Operator() bool {
 	return u.Flags&(bncs.ChatUserFlagBlizzard|bncs.ChatUserFlagOperator|bncs.ChatUserFlagAdmin) != 0
 }
 
+// ChatUser converts u to its transport-agnostic representation
+func (u *User) ChatUser() network.ChatUser {
+	return network.ChatUser{
+		Name:     u.Name,
+		Operator: u.Operator(),
+		Joined:   u.Joined,
+		LastSeen: u.LastSeen,
+	}
+}
+
 func reverse(s string) string {
 	r := []rune(s)
 	for i, j := 0, len(r)-1; i < len(r)/2; i, j = i+1, j-1 {
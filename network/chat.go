@@ -0,0 +1,80 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"time"
+)
+
+// Chat is the transport-agnostic interface shared by chat clients (e.g. bnet.Client and chat.Bot)
+type Chat interface {
+	Emitter
+	Listener
+
+	// Run reads packets and emits an event for each received packet
+	Run() error
+
+	// RunContext reads packets until ctx is done and emits an event for each received packet
+	RunContext(ctx context.Context) error
+
+	// Close the connection
+	Close() error
+
+	// Channel currently chatting in
+	Channel() string
+
+	// ChatUsers in channel (indexed by lowercase name)
+	ChatUsers() map[string]ChatUser
+
+	// JoinChannel leaves the current channel and joins another
+	JoinChannel(channel string) error
+
+	// Say sends a chat message to the channel
+	Say(s string) error
+
+	// Whisper sends a private chat message to user
+	Whisper(username string, s string) error
+
+	// Kick user from the channel
+	Kick(username string) error
+
+	// Ban user from the channel
+	Ban(username string) error
+
+	// Unban user from the channel
+	Unban(username string) error
+}
+
+// ChatUser in a channel (transport-agnostic)
+type ChatUser struct {
+	Name     string
+	Operator bool
+	Joined   time.Time
+	LastSeen time.Time
+}
+
+// ChatChannelJoined event
+type ChatChannelJoined struct {
+	Channel string
+}
+
+// ChatUserJoined event
+type ChatUserJoined struct {
+	ChatUser
+}
+
+// ChatUserLeft event
+type ChatUserLeft struct {
+	ChatUser
+}
+
+// ChatMessage event
+type ChatMessage struct {
+	ChatUser
+	Content string
+	Whisper bool
+	Emote   bool
+}
@@ -7,8 +7,14 @@ package chat
 
 import (
 	"context"
+	"crypto/tls"
+	"io"
 	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,9 +28,25 @@ import (
 
 // Config for chat.Bot
 type Config struct {
-	Endpoint   string
-	APIKey     string
-	RPCTimeout time.Duration
+	Endpoint    string
+	APIKey      string
+	RPCTimeout  time.Duration
+	RateLimiter *RateLimiter
+	Logger      network.Logger
+
+	// Websocket ping interval and max time without activity before disconnecting (optional, 0 to disable)
+	KeepAliveInterval time.Duration
+	KeepAliveTimeout  time.Duration
+
+	// Backoff settings for NewReconnector() (optional, zero values use network.DefaultReconnectConfig)
+	Reconnect network.ReconnectConfig
+
+	// Websocket dialer settings (optional, defaults to websocket.DefaultDialer behavior)
+	TLSConfig        *tls.Config
+	Proxy            func(*http.Request) (*url.URL, error)
+	DialContext      func(ctx context.Context, network, addr string) (net.Conn, error)
+	HandshakeTimeout time.Duration
+	Header           http.Header
 }
 
 // Bot implements a basic chat bot using the official classic Battle.net chat API
@@ -33,7 +55,9 @@ type Bot struct {
 	network.EventEmitter
 	network.CAPIConn
 
-	rid uint32
+	rid     uint32
+	rmut    sync.Mutex
+	pending map[int64]chan capi.Packet
 
 	chatmut sync.Mutex
 	channel string
@@ -43,6 +67,8 @@ type Bot struct {
 	Config
 }
 
+var _ network.Chat = (*Bot)(nil)
+
 // NewBot initializes a Bot struct
 func NewBot(conf *Config) (*Bot, error) {
 	var b = Bot{
@@ -51,6 +77,7 @@ func NewBot(conf *Config) (*Bot, error) {
 
 	b.InitDefaultHandlers()
 	b.SetWriteTimeout(30 * time.Second)
+	b.SetLogger(b.Logger)
 
 	return &b, nil
 }
@@ -89,31 +116,59 @@ func (b *Bot) Users() map[int64]User {
 	return res
 }
 
+// Dialer used to open the websocket connection
+func (b *Bot) Dialer() *websocket.Dialer {
+	var d = *websocket.DefaultDialer
+	if b.TLSConfig != nil {
+		d.TLSClientConfig = b.TLSConfig
+	}
+	if b.Proxy != nil {
+		d.Proxy = b.Proxy
+	}
+	if b.DialContext != nil {
+		d.NetDialContext = b.DialContext
+	}
+	if b.HandshakeTimeout != 0 {
+		d.HandshakeTimeout = b.HandshakeTimeout
+	}
+	return &d
+}
+
 // Connect opens a new connection to server and joins chat
 func (b *Bot) Connect() error {
-	conn, _, err := websocket.DefaultDialer.Dial(b.Endpoint, nil)
+	return b.ConnectContext(context.Background())
+}
+
+// ConnectContext opens a new connection to server and joins chat, aborts once ctx is done
+func (b *Bot) ConnectContext(ctx context.Context) error {
+	conn, _, err := b.Dialer().DialContext(ctx, b.Endpoint, b.Header)
 	if err != nil {
 		return err
 	}
 
 	capiconn := network.NewCAPIConn(conn)
+	capiconn.SetLogger(b.Logger)
 
-	if _, err := syncRPC(capiconn, 5*time.Second, capi.CmdAuthenticate, capi.Authenticate{
+	if _, err := syncRPC(ctx, capiconn, 5*time.Second, capi.CmdAuthenticate, capi.Authenticate{
 		APIKey: b.Config.APIKey,
 	}); err != nil {
 		capiconn.Close()
 		return err
 	}
-	if _, err := syncRPC(capiconn, 5*time.Second, capi.CmdConnect); err != nil {
+	if _, err := syncRPC(ctx, capiconn, 5*time.Second, capi.CmdConnect); err != nil {
 		capiconn.Close()
 		return err
 	}
 
+	if b.Logger != nil {
+		b.Logger.Info("Connected to chat API", network.LogKeyPeer, b.Endpoint)
+	}
+
 	b.SetConn(capiconn.Conn())
 	return nil
 }
 
-func syncRPC(conn *network.CAPIConn, timeout time.Duration, command string, arg ...interface{}) (interface{}, error) {
+func syncRPC(ctx context.Context, conn *network.CAPIConn, timeout time.Duration, command string, arg ...interface{}) (interface{}, error) {
 	var p interface{}
 	switch len(arg) {
 	case 0:
@@ -134,7 +189,7 @@ func syncRPC(conn *network.CAPIConn, timeout time.Duration, command string, arg
 		return nil, err
 	}
 
-	pkt, err := conn.NextPacket(timeout)
+	pkt, err := conn.NextPacketContext(ctx, timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -147,6 +202,51 @@ func syncRPC(conn *network.CAPIConn, timeout time.Duration, command string, arg
 	return pkt.Payload, nil
 }
 
+func (b *Bot) addPending(rid int64) chan capi.Packet {
+	var rsp = make(chan capi.Packet, 1)
+
+	b.rmut.Lock()
+	if b.pending == nil {
+		b.pending = make(map[int64]chan capi.Packet)
+	}
+	b.pending[rid] = rsp
+	b.rmut.Unlock()
+
+	return rsp
+}
+
+func (b *Bot) delPending(rid int64) {
+	b.rmut.Lock()
+	delete(b.pending, rid)
+	b.rmut.Unlock()
+}
+
+// resolvePending delivers pkt to the goroutine waiting for its response, returns false if nobody is waiting
+func (b *Bot) resolvePending(pkt *capi.Packet) bool {
+	b.rmut.Lock()
+	var rsp = b.pending[pkt.RequestID]
+	delete(b.pending, pkt.RequestID)
+	b.rmut.Unlock()
+
+	if rsp == nil {
+		return false
+	}
+
+	// Buffered channel, never blocks
+	rsp <- *pkt
+	return true
+}
+
+// closePending wakes up all goroutines waiting for a response
+func (b *Bot) closePending() {
+	b.rmut.Lock()
+	for k, v := range b.pending {
+		close(v)
+		delete(b.pending, k)
+	}
+	b.rmut.Unlock()
+}
+
 func (b *Bot) asyncRPC(ctx context.Context, command string, arg ...interface{}) (interface{}, error) {
 	var p interface{}
 	switch len(arg) {
@@ -158,25 +258,14 @@ func (b *Bot) asyncRPC(ctx context.Context, command string, arg ...interface{})
 		p = arg
 	}
 
-	var rid = int64(atomic.AddUint32(&b.rid, 1))
-	var cmd = command + capi.CmdResponseSuffix
-	var rsp = make(chan capi.Packet, 1)
-
-	var eid = b.On(&capi.Packet{}, func(ev *network.Event) {
-		var pkt = ev.Arg.(*capi.Packet)
-		if pkt.RequestID != rid || pkt.Command != cmd {
-			return
-		}
+	if err := b.RateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 
-		select {
-		case rsp <- *pkt:
-			// Successfully sent to channel
-		default:
-			// Ignore duplicate response
-		}
-	})
+	var rid = int64(atomic.AddUint32(&b.rid, 1))
+	var rsp = b.addPending(rid)
 
-	defer b.Off(eid)
+	defer b.delPending(rid)
 
 	if err := b.Send(&capi.Packet{
 		Command:   command + capi.CmdRequestSuffix,
@@ -189,7 +278,13 @@ func (b *Bot) asyncRPC(ctx context.Context, command string, arg ...interface{})
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case pkt := <-rsp:
+	case pkt, ok := <-rsp:
+		if !ok {
+			return nil, io.EOF
+		}
+		if pkt.Command != command+capi.CmdResponseSuffix {
+			return nil, ErrUnexpectedPacket
+		}
 		if pkt.Status != nil && *pkt.Status != capi.Success {
 			return nil, pkt.Status
 		}
@@ -200,6 +295,12 @@ func (b *Bot) asyncRPC(ctx context.Context, command string, arg ...interface{})
 // RPC executes Remote Procedure Call cmd asynchronously, retries on timeout/rate-limit
 // Needs to called in a goroutine while Run() is running asynchronously to process incoming packets
 func (b *Bot) RPC(command string, arg ...interface{}) (interface{}, error) {
+	return b.RPCContext(context.Background(), command, arg...)
+}
+
+// RPCContext executes Remote Procedure Call cmd asynchronously, retries on timeout/rate-limit until ctx expires
+// Needs to called in a goroutine while Run() is running asynchronously to process incoming packets
+func (b *Bot) RPCContext(ctx context.Context, command string, arg ...interface{}) (interface{}, error) {
 	var t = b.RPCTimeout
 	if t == 0 {
 		t = 5 * time.Second
@@ -207,17 +308,25 @@ func (b *Bot) RPC(command string, arg ...interface{}) (interface{}, error) {
 
 	var d = time.Second
 	for {
-		var ctx, cancel = context.WithTimeout(context.Background(), t)
-		var res, err = b.asyncRPC(ctx, command, arg...)
+		var tctx, cancel = context.WithTimeout(ctx, t)
+		var res, err = b.asyncRPC(tctx, command, arg...)
 		cancel()
 
 		if err == nil {
 			return res, nil
 		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if !os.IsTimeout(err) || d >= 10*time.Second {
 			return nil, err
 		}
-		time.Sleep(d)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(d):
+		}
 		d *= 2
 	}
 }
@@ -225,66 +334,203 @@ func (b *Bot) RPC(command string, arg ...interface{}) (interface{}, error) {
 // Run reads packets and emits an event for each received packet
 // Not safe for concurrent invocation
 func (b *Bot) Run() error {
-	return b.CAPIConn.Run(&b.EventEmitter, 12*time.Hour)
+	return b.RunContext(context.Background())
+}
+
+// RunContext reads packets until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (b *Bot) RunContext(ctx context.Context) error {
+	defer b.closePending()
+
+	if conn := b.Conn(); conn != nil && (b.KeepAliveInterval != 0 || b.KeepAliveTimeout != 0) {
+		var k = network.NewCAPIKeepAlive(&b.CAPIConn, b.KeepAliveInterval, b.KeepAliveTimeout)
+		k.Emitter = &b.EventEmitter
+
+		var eid = k.Bind(&b.EventEmitter)
+		defer b.Off(eid)
+
+		conn.SetPongHandler(func(string) error {
+			k.Touch()
+			return nil
+		})
+
+		var stop = k.Start()
+		defer stop()
+	}
+	return b.CAPIConn.RunContext(ctx, &b.EventEmitter, 12*time.Hour)
+}
+
+// NewReconnector returns a Reconnector that connects again whenever the connection breaks
+// Cleanly closed connections are not retried
+func (b *Bot) NewReconnector() *network.Reconnector {
+	var r = network.NewReconnector(b.Reconnect, b.ConnectContext, b.RunContext)
+	r.Emitter = &b.EventEmitter
+	r.Retry = func(err error) bool {
+		return !network.IsCloseError(err)
+	}
+	return r
 }
 
 // SendMessage sends a chat message to the channel
 func (b *Bot) SendMessage(s string) error {
+	return b.SendMessageContext(context.Background(), s)
+}
+
+// SendMessageContext sends a chat message to the channel, returns when response arrives or ctx expires
+func (b *Bot) SendMessageContext(ctx context.Context, s string) error {
 	s = bnet.FilterChat(s)
 	if len(s) == 0 {
 		return nil
 	}
 
-	_, err := b.RPC(capi.CmdSendMessage, &capi.SendMessage{Message: s})
+	_, err := b.RPCContext(ctx, capi.CmdSendMessage, &capi.SendMessage{Message: s})
 	return err
 }
 
 // SendEmote sends an emote on behalf of a bot
 func (b *Bot) SendEmote(s string) error {
+	return b.SendEmoteContext(context.Background(), s)
+}
+
+// SendEmoteContext sends an emote on behalf of a bot, returns when response arrives or ctx expires
+func (b *Bot) SendEmoteContext(ctx context.Context, s string) error {
 	s = bnet.FilterChat(s)
 	if len(s) == 0 {
 		return nil
 	}
 
-	_, err := b.RPC(capi.CmdSendEmote, &capi.SendEmote{Message: s})
+	_, err := b.RPCContext(ctx, capi.CmdSendEmote, &capi.SendEmote{Message: s})
 	return err
 }
 
 // SendWhisper sends a chat message to one user in the channel
 func (b *Bot) SendWhisper(uid int64, s string) error {
+	return b.SendWhisperContext(context.Background(), uid, s)
+}
+
+// SendWhisperContext sends a chat message to one user in the channel, returns when response arrives or ctx expires
+func (b *Bot) SendWhisperContext(ctx context.Context, uid int64, s string) error {
 	s = bnet.FilterChat(s)
 	if len(s) == 0 {
 		return nil
 	}
 
-	_, err := b.RPC(capi.CmdSendWhisper, &capi.SendWhisper{UserID: uid, Message: s})
+	_, err := b.RPCContext(ctx, capi.CmdSendWhisper, &capi.SendWhisper{UserID: uid, Message: s})
 	return err
 }
 
 // KickUser kicks a user from the channel
 func (b *Bot) KickUser(uid int64) error {
-	_, err := b.RPC(capi.CmdKickUser, &capi.KickUser{UserID: uid})
+	return b.KickUserContext(context.Background(), uid)
+}
+
+// KickUserContext kicks a user from the channel, returns when response arrives or ctx expires
+func (b *Bot) KickUserContext(ctx context.Context, uid int64) error {
+	_, err := b.RPCContext(ctx, capi.CmdKickUser, &capi.KickUser{UserID: uid})
 	return err
 }
 
 // BanUser bans a user from the channel
 func (b *Bot) BanUser(uid int64) error {
-	_, err := b.RPC(capi.CmdBanUser, &capi.BanUser{UserID: uid})
+	return b.BanUserContext(context.Background(), uid)
+}
+
+// BanUserContext bans a user from the channel, returns when response arrives or ctx expires
+func (b *Bot) BanUserContext(ctx context.Context, uid int64) error {
+	_, err := b.RPCContext(ctx, capi.CmdBanUser, &capi.BanUser{UserID: uid})
 	return err
 }
 
 // UnbanUser un-bans a user from the channel
 func (b *Bot) UnbanUser(username string) error {
-	_, err := b.RPC(capi.CmdUnbanUser, &capi.UnbanUser{Username: username})
+	return b.UnbanUserContext(context.Background(), username)
+}
+
+// UnbanUserContext un-bans a user from the channel, returns when response arrives or ctx expires
+func (b *Bot) UnbanUserContext(ctx context.Context, username string) error {
+	_, err := b.RPCContext(ctx, capi.CmdUnbanUser, &capi.UnbanUser{Username: username})
 	return err
 }
 
 // SetModerator sets the current chat moderator to a member of the current chat
 func (b *Bot) SetModerator(uid int64) error {
-	_, err := b.RPC(capi.CmdSetModerator, &capi.SetModerator{UserID: uid})
+	return b.SetModeratorContext(context.Background(), uid)
+}
+
+// SetModeratorContext sets the current chat moderator to a member of the current chat, returns when response arrives or ctx expires
+func (b *Bot) SetModeratorContext(ctx context.Context, uid int64) error {
+	_, err := b.RPCContext(ctx, capi.CmdSetModerator, &capi.SetModerator{UserID: uid})
 	return err
 }
 
+// ChatUsers in channel (implements network.Chat)
+func (b *Bot) ChatUsers() map[string]network.ChatUser {
+	var res = make(map[string]network.ChatUser)
+
+	b.chatmut.Lock()
+	for _, v := range b.users {
+		res[strings.ToLower(v.Username)] = v.ChatUser()
+	}
+	b.chatmut.Unlock()
+
+	return res
+}
+
+func (b *Bot) userID(username string) (int64, error) {
+	b.chatmut.Lock()
+	defer b.chatmut.Unlock()
+
+	for k, v := range b.users {
+		if strings.EqualFold(v.Username, username) {
+			return k, nil
+		}
+	}
+
+	return 0, ErrUnknownUser
+}
+
+// JoinChannel is not supported by the chat API, the bot is bound to the channel of its API key
+func (b *Bot) JoinChannel(channel string) error {
+	return ErrNotSupported
+}
+
+// Say sends a chat message to the channel
+func (b *Bot) Say(s string) error {
+	return b.SendMessage(s)
+}
+
+// Whisper sends a private chat message to user in the channel
+func (b *Bot) Whisper(username string, s string) error {
+	uid, err := b.userID(username)
+	if err != nil {
+		return err
+	}
+	return b.SendWhisper(uid, s)
+}
+
+// Kick user from the channel
+func (b *Bot) Kick(username string) error {
+	uid, err := b.userID(username)
+	if err != nil {
+		return err
+	}
+	return b.KickUser(uid)
+}
+
+// Ban user from the channel
+func (b *Bot) Ban(username string) error {
+	uid, err := b.userID(username)
+	if err != nil {
+		return err
+	}
+	return b.BanUser(uid)
+}
+
+// Unban user from the channel
+func (b *Bot) Unban(username string) error {
+	return b.UnbanUser(username)
+}
+
 // InitDefaultHandlers adds the default callbacks for relevant packets
 func (b *Bot) InitDefaultHandlers() {
 	b.On(&capi.Packet{}, b.onPacket)
@@ -297,6 +543,10 @@ func (b *Bot) InitDefaultHandlers() {
 
 func (b *Bot) onPacket(ev *network.Event) {
 	var pkt = ev.Arg.(*capi.Packet)
+	if strings.HasSuffix(pkt.Command, capi.CmdResponseSuffix) {
+		b.resolvePending(pkt)
+	}
+
 	if pkt.Status != nil && *pkt.Status == capi.ErrNotConnected {
 		b.Fire(&network.AsyncError{Src: "onPacket", Err: pkt.Status})
 		b.Close()
@@ -314,17 +564,35 @@ func (b *Bot) onConnectEvent(ev *network.Event) {
 	b.channel = pkt.Channel
 	b.users = nil
 	b.chatmut.Unlock()
+
+	b.Fire(&network.ChatChannelJoined{Channel: pkt.Channel})
 }
 
 func (b *Bot) onMessageEvent(ev *network.Event) {
 	var pkt = ev.Arg.(*capi.MessageEvent)
 
+	var user network.ChatUser
+
 	b.chatmut.Lock()
 	var u = b.users[pkt.UserID]
 	if u != nil {
 		u.LastSeen = time.Now()
+		user = u.ChatUser()
 	}
 	b.chatmut.Unlock()
+
+	switch pkt.Type {
+	case capi.MessageChannel, capi.MessageWhisper, capi.MessageEmote:
+		if u == nil {
+			return
+		}
+		b.Fire(&network.ChatMessage{
+			ChatUser: user,
+			Content:  pkt.Message,
+			Whisper:  pkt.Type == capi.MessageWhisper,
+			Emote:    pkt.Type == capi.MessageEmote,
+		})
+	}
 }
 
 func (b *Bot) onUserUpdateEvent(ev *network.Event) {
@@ -358,6 +626,7 @@ func (b *Bot) onUserUpdateEvent(ev *network.Event) {
 
 	if join {
 		b.Fire(&UserJoined{User: user})
+		b.Fire(&network.ChatUserJoined{ChatUser: user.ChatUser()})
 	} else {
 		b.Fire(&UserUpdate{User: user})
 	}
@@ -373,5 +642,6 @@ func (b *Bot) onUserLeaveEvent(ev *network.Event) {
 
 	if u != nil {
 		b.Fire(&UserLeft{User: *u})
+		b.Fire(&network.ChatUserLeft{ChatUser: u.ChatUser()})
 	}
 }
@@ -6,6 +6,7 @@ package chat_test
 
 import (
 	"fmt"
+	"testing"
 
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/network/chat"
@@ -38,3 +39,45 @@ func Example() {
 	// Run() blocks until the connection is closed
 	bot.Run()
 }
+
+func TestChatEvents(t *testing.T) {
+	bot, err := chat.NewBot(&chat.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var joined []network.ChatUser
+	var left []network.ChatUser
+	var msg []network.ChatMessage
+	bot.On(&network.ChatUserJoined{}, func(ev *network.Event) {
+		joined = append(joined, ev.Arg.(*network.ChatUserJoined).ChatUser)
+	})
+	bot.On(&network.ChatUserLeft{}, func(ev *network.Event) {
+		left = append(left, ev.Arg.(*network.ChatUserLeft).ChatUser)
+	})
+	bot.On(&network.ChatMessage{}, func(ev *network.Event) {
+		msg = append(msg, *ev.Arg.(*network.ChatMessage))
+	})
+
+	bot.Fire(&capi.UserUpdateEvent{UserID: 1, Username: "foo", Flags: []string{capi.UserFlagModerator}})
+	bot.Fire(&capi.UserUpdateEvent{UserID: 1, Username: "foo"})
+	bot.Fire(&capi.MessageEvent{UserID: 1, Message: "hello", Type: capi.MessageChannel})
+	bot.Fire(&capi.MessageEvent{UserID: 1, Message: "waves", Type: capi.MessageEmote})
+	bot.Fire(&capi.MessageEvent{UserID: 1, Message: "psst", Type: capi.MessageWhisper})
+	bot.Fire(&capi.MessageEvent{UserID: 2, Message: "unknown", Type: capi.MessageChannel})
+	bot.Fire(&capi.UserLeaveEvent{UserID: 1})
+	bot.Fire(&capi.UserLeaveEvent{UserID: 2})
+
+	if len(joined) != 1 || joined[0].Name != "foo" || !joined[0].Operator {
+		t.Fatalf("ChatUserJoined mismatch %+v", joined)
+	}
+	if len(left) != 1 || left[0].Name != "foo" {
+		t.Fatalf("ChatUserLeft mismatch %+v", left)
+	}
+	if len(msg) != 3 ||
+		msg[0].Name != "foo" || msg[0].Content != "hello" || msg[0].Emote || msg[0].Whisper ||
+		msg[1].Content != "waves" || !msg[1].Emote ||
+		msg[2].Content != "psst" || !msg[2].Whisper {
+		t.Fatalf("ChatMessage mismatch %+v", msg)
+	}
+}
@@ -8,12 +8,19 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/nielsAD/gowarcraft3/protocol"
 	"github.com/nielsAD/gowarcraft3/protocol/capi"
 )
 
 // Errors
 var (
 	ErrUnexpectedPacket = errors.New("chat: Received unexpected packet")
+	ErrUnknownCommand   = errors.New("chat: Unknown command")
+	ErrPermissionDenied = protocol.NewError(protocol.KindRejected, "chat: Permission denied")
+	ErrCooldown         = protocol.NewError(protocol.KindRateLimited, "chat: Command on cooldown")
+	ErrDupBotName       = errors.New("chat: Duplicate bot name")
+	ErrUnknownUser      = errors.New("chat: Unknown user")
+	ErrNotSupported     = errors.New("chat: Operation not supported")
 )
 
 // UserFlags enum
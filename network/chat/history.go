@@ -0,0 +1,235 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/bnet"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/capi"
+)
+
+// EntryType enum
+type EntryType uint32
+
+// History entry types
+const (
+	EntryMessage EntryType = iota
+	EntryWhisper
+	EntryEmote
+	EntryJoin
+	EntryLeave
+)
+
+func (t EntryType) String() string {
+	switch t {
+	case EntryMessage:
+		return "Message"
+	case EntryWhisper:
+		return "Whisper"
+	case EntryEmote:
+		return "Emote"
+	case EntryJoin:
+		return "Join"
+	case EntryLeave:
+		return "Leave"
+	default:
+		return fmt.Sprintf("EntryType(0x%02X)", uint32(t))
+	}
+}
+
+// Entry in History
+type Entry struct {
+	Time     time.Time
+	Type     EntryType
+	Username string
+	Content  string
+}
+
+// History keeps the last N chat entries in a ring buffer
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type History struct {
+	mut  sync.Mutex
+	buf  []Entry
+	head int
+	size int
+}
+
+// NewHistory initializes a History struct that holds at most n entries
+func NewHistory(n int) *History {
+	if n < 0 {
+		n = 0
+	}
+	return &History{
+		buf: make([]Entry, n),
+	}
+}
+
+// Len returns the number of entries in History
+func (h *History) Len() int {
+	h.mut.Lock()
+	var n = h.size
+	h.mut.Unlock()
+	return n
+}
+
+// Add e to History, overwrites the oldest entry if full
+func (h *History) Add(e Entry) {
+	h.mut.Lock()
+	if len(h.buf) > 0 {
+		h.buf[(h.head+h.size)%len(h.buf)] = e
+		if h.size < len(h.buf) {
+			h.size++
+		} else {
+			h.head = (h.head + 1) % len(h.buf)
+		}
+	}
+	h.mut.Unlock()
+}
+
+// Clear all entries
+func (h *History) Clear() {
+	h.mut.Lock()
+	for i := range h.buf {
+		h.buf[i] = Entry{}
+	}
+	h.head = 0
+	h.size = 0
+	h.mut.Unlock()
+}
+
+// Filter returns all entries (oldest first) for which f returns true
+func (h *History) Filter(f func(e *Entry) bool) []Entry {
+	var res []Entry
+
+	h.mut.Lock()
+	for i := 0; i < h.size; i++ {
+		var e = &h.buf[(h.head+i)%len(h.buf)]
+		if f == nil || f(e) {
+			res = append(res, *e)
+		}
+	}
+	h.mut.Unlock()
+
+	return res
+}
+
+// Entries returns all entries (oldest first)
+func (h *History) Entries() []Entry {
+	return h.Filter(nil)
+}
+
+// Since returns all entries (oldest first) added at or after t
+func (h *History) Since(t time.Time) []Entry {
+	return h.Filter(func(e *Entry) bool { return !e.Time.Before(t) })
+}
+
+// Between returns all entries (oldest first) added in the interval [from, to)
+func (h *History) Between(from time.Time, to time.Time) []Entry {
+	return h.Filter(func(e *Entry) bool { return !e.Time.Before(from) && e.Time.Before(to) })
+}
+
+// User returns all entries (oldest first) of user with name (case insensitive)
+func (h *History) User(name string) []Entry {
+	return h.Filter(func(e *Entry) bool { return strings.EqualFold(e.Username, name) })
+}
+
+// LastSeen returns the most recent entry of user with name (case insensitive)
+func (h *History) LastSeen(name string) (*Entry, bool) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	for i := h.size - 1; i >= 0; i-- {
+		var e = h.buf[(h.head+i)%len(h.buf)]
+		if strings.EqualFold(e.Username, name) {
+			return &e, true
+		}
+	}
+
+	return nil, false
+}
+
+// BindBot records chat events of b
+func (h *History) BindBot(b *Bot) []network.EventID {
+	var msg = b.On(&capi.MessageEvent{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*capi.MessageEvent)
+
+		var e = Entry{
+			Time:    time.Now(),
+			Content: pkt.Message,
+		}
+		switch pkt.Type {
+		case capi.MessageChannel:
+			e.Type = EntryMessage
+		case capi.MessageWhisper:
+			e.Type = EntryWhisper
+		case capi.MessageEmote:
+			e.Type = EntryEmote
+		default:
+			return
+		}
+		if u, ok := b.User(pkt.UserID); ok {
+			e.Username = u.Username
+		}
+
+		h.Add(e)
+	})
+
+	var join = b.On(&UserJoined{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*UserJoined)
+		h.Add(Entry{Time: pkt.Joined, Type: EntryJoin, Username: pkt.Username})
+	})
+
+	var leave = b.On(&UserLeft{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*UserLeft)
+		h.Add(Entry{Time: time.Now(), Type: EntryLeave, Username: pkt.Username})
+	})
+
+	return []network.EventID{msg, join, leave}
+}
+
+// BindClient records chat events of c
+func (h *History) BindClient(c *bnet.Client) []network.EventID {
+	var chat = c.On(&bnet.Chat{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*bnet.Chat)
+
+		var e = Entry{
+			Time:     time.Now(),
+			Type:     EntryMessage,
+			Username: pkt.User.Name,
+			Content:  pkt.Content,
+		}
+		if pkt.Type == bncs.ChatEmote {
+			e.Type = EntryEmote
+		}
+
+		h.Add(e)
+	})
+
+	var whisper = c.On(&bnet.Whisper{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*bnet.Whisper)
+		h.Add(Entry{Time: time.Now(), Type: EntryWhisper, Username: pkt.Username, Content: pkt.Content})
+	})
+
+	var join = c.On(&bnet.UserJoined{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*bnet.UserJoined)
+		if pkt.AlreadyInChannel {
+			return
+		}
+		h.Add(Entry{Time: pkt.Joined, Type: EntryJoin, Username: pkt.Name})
+	})
+
+	var leave = c.On(&bnet.UserLeft{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*bnet.UserLeft)
+		h.Add(Entry{Time: time.Now(), Type: EntryLeave, Username: pkt.Name})
+	})
+
+	return []network.EventID{chat, whisper, join, leave}
+}
@@ -0,0 +1,67 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network/chat"
+)
+
+func TestHistory(t *testing.T) {
+	var h = chat.NewHistory(3)
+
+	if _, ok := h.LastSeen("foo"); ok {
+		t.Fatal("Expected empty history")
+	}
+
+	var t0 = time.Now()
+	h.Add(chat.Entry{Time: t0, Username: "foo", Content: "1"})
+	h.Add(chat.Entry{Time: t0.Add(1 * time.Second), Username: "bar", Content: "2"})
+	h.Add(chat.Entry{Time: t0.Add(2 * time.Second), Username: "Foo", Content: "3"})
+
+	if h.Len() != 3 {
+		t.Fatal("Expected 3 entries, got", h.Len())
+	}
+	if e := h.User("FOO"); len(e) != 2 || e[0].Content != "1" || e[1].Content != "3" {
+		t.Fatalf("Unexpected User() result %+v", e)
+	}
+
+	// Overwrite oldest entry
+	h.Add(chat.Entry{Time: t0.Add(3 * time.Second), Username: "baz", Content: "4"})
+
+	var all = h.Entries()
+	if len(all) != 3 || all[0].Content != "2" || all[2].Content != "4" {
+		t.Fatalf("Unexpected Entries() result %+v", all)
+	}
+	if e := h.Since(t0.Add(2 * time.Second)); len(e) != 2 || e[0].Content != "3" {
+		t.Fatalf("Unexpected Since() result %+v", e)
+	}
+	if e := h.Between(t0, t0.Add(2*time.Second)); len(e) != 1 || e[0].Content != "2" {
+		t.Fatalf("Unexpected Between() result %+v", e)
+	}
+	if e, ok := h.LastSeen("foo"); !ok || e.Content != "3" {
+		t.Fatalf("Unexpected LastSeen() result %+v", e)
+	}
+
+	h.Clear()
+	if h.Len() != 0 || len(h.Entries()) != 0 {
+		t.Fatal("Expected empty history after Clear()")
+	}
+}
+
+func TestHistoryEmpty(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		var h = chat.NewHistory(n)
+		h.Add(chat.Entry{Username: "foo"})
+		if h.Len() != 0 || len(h.Entries()) != 0 {
+			t.Fatal("Expected empty history for size", n)
+		}
+		if _, ok := h.LastSeen("foo"); ok {
+			t.Fatal("Expected empty history for size", n)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package chat
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter enforces a minimum interval between requests, can be shared by multiple bots
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type RateLimiter struct {
+	mut     sync.Mutex
+	last    time.Time
+	queue   []chan struct{}
+	pending bool
+
+	// Set once before first use, read-only after that
+	Interval time.Duration
+}
+
+// NewRateLimiter initializes a RateLimiter struct
+func NewRateLimiter(interval time.Duration) *RateLimiter {
+	return &RateLimiter{Interval: interval}
+}
+
+// Wait until the next request is allowed or ctx expires
+// Requests are served in order of arrival, cancelled requests give up their place in line
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.Interval <= 0 {
+		return nil
+	}
+
+	l.mut.Lock()
+	var t = time.Now()
+	if len(l.queue) == 0 && !l.pending && t.Sub(l.last) >= l.Interval {
+		l.last = t
+		l.mut.Unlock()
+		return nil
+	}
+
+	var c = make(chan struct{})
+	l.queue = append(l.queue, c)
+	l.schedule()
+	l.mut.Unlock()
+
+	select {
+	case <-c:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	for i, q := range l.queue {
+		if q == c {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return ctx.Err()
+		}
+	}
+
+	// Granted while being cancelled
+	return nil
+}
+
+// Make sure mut is locked before calling
+func (l *RateLimiter) schedule() {
+	if l.pending {
+		return
+	}
+	l.pending = true
+	time.AfterFunc(time.Until(l.last.Add(l.Interval)), l.grant)
+}
+
+func (l *RateLimiter) grant() {
+	l.mut.Lock()
+	l.pending = false
+	if len(l.queue) > 0 {
+		close(l.queue[0])
+		l.queue[0] = nil
+		l.queue = l.queue[1:]
+		l.last = time.Now()
+	}
+	if len(l.queue) > 0 {
+		l.schedule()
+	}
+	l.mut.Unlock()
+}
@@ -0,0 +1,75 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package chat_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network/chat"
+)
+
+func TestRateLimiterOrder(t *testing.T) {
+	var l = chat.NewRateLimiter(20 * time.Millisecond)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var mut sync.Mutex
+	var res []int
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := l.Wait(context.Background()); err != nil {
+				t.Error(err)
+			}
+			mut.Lock()
+			res = append(res, i)
+			mut.Unlock()
+		}(i)
+
+		// Make sure goroutines enter the queue in order
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	var start = time.Now()
+	wg.Wait()
+
+	if d := time.Since(start); d < 60*time.Millisecond {
+		t.Fatalf("Expected at least 4 intervals, took %v", d)
+	}
+	for i, v := range res {
+		if i != v {
+			t.Fatalf("Out of order: %v", res)
+		}
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	var l = chat.NewRateLimiter(50 * time.Millisecond)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatal("Expected DeadlineExceeded, got", err)
+	}
+
+	// Cancelled request should not delay the next one by another interval
+	var start = time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 80*time.Millisecond {
+		t.Fatalf("Cancelled slot was not released, waited %v", d)
+	}
+}
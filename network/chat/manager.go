@@ -0,0 +1,197 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package chat
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/network"
+)
+
+// Source identifies the bot that fired an event through Manager
+type Source struct {
+	Name string
+	Bot  *Bot
+}
+
+// EventSource returns the bot that originally fired ev
+// Manager appends Source to the optional event arguments
+func EventSource(ev *network.Event) *Source {
+	if len(ev.Opt) == 0 {
+		return nil
+	}
+	s, _ := ev.Opt[len(ev.Opt)-1].(*Source)
+	return s
+}
+
+// BotAdded event
+type BotAdded Source
+
+// BotRemoved event
+type BotRemoved Source
+
+// Manager maintains multiple Chat API connections that share a rate limiter
+// Every event fired by a managed bot is fired again through Manager, with its *Source appended to Event.Opt
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Manager struct {
+	network.EventEmitter
+
+	wg   sync.WaitGroup
+	mut  sync.Mutex
+	bots map[string]*managedBot
+
+	// Set once before first use, read-only after that
+	RateLimiter *RateLimiter
+}
+
+type managedBot struct {
+	Source
+	eid     network.EventID
+	running bool
+}
+
+// NewManager initializes a Manager struct
+func NewManager(limit *RateLimiter) *Manager {
+	return &Manager{
+		RateLimiter: limit,
+	}
+}
+
+// Add a new bot with name and config
+// The bot shares the manager's rate limiter unless conf specifies its own
+func (m *Manager) Add(name string, conf *Config) (*Bot, error) {
+	var c = *conf
+	if c.RateLimiter == nil {
+		c.RateLimiter = m.RateLimiter
+	}
+
+	bot, err := NewBot(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	var mb = &managedBot{Source: Source{Name: name, Bot: bot}}
+	mb.eid = bot.On(nil, func(ev *network.Event) {
+		m.Fire(ev.Arg, append(ev.Opt[:len(ev.Opt):len(ev.Opt)], &mb.Source)...)
+	})
+
+	m.mut.Lock()
+	if m.bots[name] != nil {
+		m.mut.Unlock()
+		bot.Off(mb.eid)
+		return nil, ErrDupBotName
+	}
+	if m.bots == nil {
+		m.bots = make(map[string]*managedBot)
+	}
+	m.bots[name] = mb
+	m.mut.Unlock()
+
+	m.Fire(&BotAdded{Name: name, Bot: bot})
+	return bot, nil
+}
+
+// Remove (and disconnect) bot by name
+func (m *Manager) Remove(name string) {
+	m.mut.Lock()
+	var mb = m.bots[name]
+	delete(m.bots, name)
+	m.mut.Unlock()
+
+	if mb == nil {
+		return
+	}
+
+	mb.Bot.Close()
+	mb.Bot.Off(mb.eid)
+
+	m.Fire(&BotRemoved{Name: name, Bot: mb.Bot})
+}
+
+// Bot by name
+func (m *Manager) Bot(name string) (*Bot, bool) {
+	m.mut.Lock()
+	var mb = m.bots[name]
+	m.mut.Unlock()
+
+	if mb == nil {
+		return nil, false
+	}
+	return mb.Bot, true
+}
+
+// Bots currently managed
+func (m *Manager) Bots() map[string]*Bot {
+	var res = make(map[string]*Bot)
+
+	m.mut.Lock()
+	for k, v := range m.bots {
+		res[k] = v.Bot
+	}
+	m.mut.Unlock()
+
+	return res
+}
+
+// Close all connections
+func (m *Manager) Close() error {
+	var err error
+
+	m.mut.Lock()
+	for _, mb := range m.bots {
+		if e := mb.Bot.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	m.mut.Unlock()
+
+	return err
+}
+
+func (m *Manager) run(ctx context.Context, mb *managedBot) {
+	if err := mb.Bot.ConnectContext(ctx); err != nil {
+		m.Fire(&network.AsyncError{Src: "Manager.run[Connect]", Err: err}, &mb.Source)
+	} else if err := mb.Bot.RunContext(ctx); err != nil && !network.IsCloseError(err) && err != ctx.Err() {
+		m.Fire(&network.AsyncError{Src: "Manager.run[Run]", Err: err}, &mb.Source)
+	}
+
+	m.mut.Lock()
+	mb.running = false
+	m.mut.Unlock()
+
+	m.wg.Done()
+}
+
+// Start connects and runs all bots that are not running yet
+func (m *Manager) Start() {
+	m.StartContext(context.Background())
+}
+
+// StartContext connects and runs all bots that are not running yet, bots stop once ctx is done
+func (m *Manager) StartContext(ctx context.Context) {
+	m.mut.Lock()
+	for _, mb := range m.bots {
+		if mb.running {
+			continue
+		}
+		mb.running = true
+
+		m.wg.Add(1)
+		go m.run(ctx, mb)
+	}
+	m.mut.Unlock()
+}
+
+// Run connects and runs all bots, blocks until all connections are closed
+func (m *Manager) Run() {
+	m.RunContext(context.Background())
+}
+
+// RunContext connects and runs all bots, blocks until all connections are closed or ctx is done
+func (m *Manager) RunContext(ctx context.Context) {
+	m.StartContext(ctx)
+	m.wg.Wait()
+}
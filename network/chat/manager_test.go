@@ -0,0 +1,80 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/chat"
+)
+
+type managerEvent struct{ n int }
+
+func TestManager(t *testing.T) {
+	var limit = chat.NewRateLimiter(0)
+	var m = chat.NewManager(limit)
+
+	var added, removed []string
+	m.On(&chat.BotAdded{}, func(ev *network.Event) {
+		added = append(added, ev.Arg.(*chat.BotAdded).Name)
+	})
+	m.On(&chat.BotRemoved{}, func(ev *network.Event) {
+		removed = append(removed, ev.Arg.(*chat.BotRemoved).Name)
+	})
+
+	var src []*chat.Source
+	m.On(&managerEvent{}, func(ev *network.Event) {
+		src = append(src, chat.EventSource(ev))
+	})
+
+	b1, err := m.Add("one", &chat.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b1.RateLimiter != limit {
+		t.Fatal("Expected shared rate limiter")
+	}
+	b2, err := m.Add("two", &chat.Config{RateLimiter: chat.NewRateLimiter(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b2.RateLimiter == limit {
+		t.Fatal("Expected own rate limiter")
+	}
+	if _, err := m.Add("one", &chat.Config{}); err != chat.ErrDupBotName {
+		t.Fatal("Expected ErrDupBotName, got", err)
+	}
+
+	if len(added) != 2 || added[0] != "one" || added[1] != "two" {
+		t.Fatal("BotAdded mismatch", added)
+	}
+	if bots := m.Bots(); len(bots) != 2 || bots["one"] != b1 || bots["two"] != b2 {
+		t.Fatal("Bots mismatch", bots)
+	}
+
+	b1.Fire(&managerEvent{1})
+	b2.Fire(&managerEvent{2})
+	if len(src) != 2 || src[0].Name != "one" || src[0].Bot != b1 || src[1].Name != "two" || src[1].Bot != b2 {
+		t.Fatal("Event source mismatch", src)
+	}
+
+	m.Remove("one")
+	m.Remove("one")
+	if len(removed) != 1 || removed[0] != "one" {
+		t.Fatal("BotRemoved mismatch", removed)
+	}
+	if _, ok := m.Bot("one"); ok {
+		t.Fatal("Expected bot to be removed")
+	}
+
+	// Removed bots are no longer tagged
+	b1.Fire(&managerEvent{3})
+	if len(src) != 2 {
+		t.Fatal("Expected no event from removed bot")
+	}
+
+	m.Close()
+}
@@ -0,0 +1,347 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package chat
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/bnet"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+)
+
+// RouterQueueSize is the maximum number of pending commands per bound chat client
+const RouterQueueSize = 64
+
+// Message that can be routed as a command
+type Message struct {
+	Username string
+	Flags    UserFlags
+	Content  string
+	Whisper  bool
+
+	// Reply sends a response to the origin of the message (channel or whisper)
+	Reply func(s string) error
+}
+
+// Command passed to a CommandHandler
+type Command struct {
+	Name string
+	Args []string
+	Arg  string // Raw (unsplit) argument string
+	*Message
+}
+
+// CommandHandler callback function
+type CommandHandler func(cmd *Command) error
+
+// Route configuration for a single command
+type Route struct {
+	Handler CommandHandler
+
+	// Require any of these flags to execute the command (0 for everyone)
+	// Admins are always allowed to execute a command
+	Require UserFlags
+
+	// Minimum time between two executions of the command (global and per user)
+	Cooldown     time.Duration
+	UserCooldown time.Duration
+
+	// Short description of the command
+	Help string
+}
+
+type route struct {
+	Route
+	last  time.Time
+	users map[string]time.Time
+}
+
+// Router parses chat messages and dispatches them to the registered command handlers
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Router struct {
+	mut    sync.Mutex
+	routes map[string]*route
+
+	// Set once before first use, read-only after that
+	Prefix        string
+	CaseSensitive bool
+}
+
+// NewRouter initializes a Router struct
+func NewRouter(prefix string) *Router {
+	return &Router{
+		Prefix: prefix,
+	}
+}
+
+func (r *Router) key(name string) string {
+	if r.CaseSensitive {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// Add command name (and its aliases) to the router
+func (r *Router) Add(cmd *Route, name string, alias ...string) {
+	r.mut.Lock()
+	if r.routes == nil {
+		r.routes = make(map[string]*route)
+	}
+
+	// Aliases share cooldown state with the original command
+	var rt = &route{Route: *cmd}
+	r.routes[r.key(name)] = rt
+	for _, a := range alias {
+		r.routes[r.key(a)] = rt
+	}
+	r.mut.Unlock()
+}
+
+// Handle is a shorthand for adding a command without permission checks or cooldowns
+func (r *Router) Handle(name string, h CommandHandler) {
+	r.Add(&Route{Handler: h}, name)
+}
+
+// Remove command name from the router
+func (r *Router) Remove(name string) {
+	r.mut.Lock()
+	delete(r.routes, r.key(name))
+	r.mut.Unlock()
+}
+
+// Commands currently registered (including aliases) with their help text
+func (r *Router) Commands() map[string]string {
+	var res = make(map[string]string)
+
+	r.mut.Lock()
+	for k, v := range r.routes {
+		res[k] = v.Help
+	}
+	r.mut.Unlock()
+
+	return res
+}
+
+// SplitArgs splits s into space separated arguments, respecting double quotes
+func SplitArgs(s string) []string {
+	var res []string
+	var arg strings.Builder
+	var quoted, inarg bool
+
+	for _, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			inarg = true
+		case unicode.IsSpace(c) && !quoted:
+			if inarg {
+				res = append(res, arg.String())
+				arg.Reset()
+				inarg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inarg = true
+		}
+	}
+	if inarg {
+		res = append(res, arg.String())
+	}
+
+	return res
+}
+
+// Parse s into a command, returns false if s does not start with Prefix
+func (r *Router) Parse(s string) (*Command, bool) {
+	if !strings.HasPrefix(s, r.Prefix) {
+		return nil, false
+	}
+
+	s = strings.TrimLeftFunc(s[len(r.Prefix):], unicode.IsSpace)
+	if s == "" {
+		return nil, false
+	}
+
+	var cmd = Command{Name: s}
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		cmd.Name = s[:i]
+		cmd.Arg = strings.TrimSpace(s[i:])
+		cmd.Args = SplitArgs(cmd.Arg)
+	}
+
+	return &cmd, true
+}
+
+func (rt *route) allowed(f UserFlags) bool {
+	return rt.Require == 0 || f&(rt.Require|UserFlagAdmin) != 0
+}
+
+// Route m to its command handler
+// Returns false if m is not a command
+func (r *Router) Route(m *Message) (bool, error) {
+	cmd, ok := r.Parse(m.Content)
+	if !ok {
+		return false, nil
+	}
+	cmd.Message = m
+
+	r.mut.Lock()
+	var rt = r.routes[r.key(cmd.Name)]
+	if rt == nil {
+		r.mut.Unlock()
+		return true, ErrUnknownCommand
+	}
+	if !rt.allowed(m.Flags) {
+		r.mut.Unlock()
+		return true, ErrPermissionDenied
+	}
+
+	var t = time.Now()
+	var u = strings.ToLower(m.Username)
+	if t.Sub(rt.last) < rt.Cooldown || t.Sub(rt.users[u]) < rt.UserCooldown {
+		r.mut.Unlock()
+		return true, ErrCooldown
+	}
+
+	if rt.Cooldown > 0 {
+		rt.last = t
+	}
+	if rt.UserCooldown > 0 {
+		if rt.users == nil {
+			rt.users = make(map[string]time.Time)
+		}
+		for k, v := range rt.users {
+			if t.Sub(v) >= rt.UserCooldown {
+				delete(rt.users, k)
+			}
+		}
+		rt.users[u] = t
+	}
+
+	var h = rt.Handler
+	r.mut.Unlock()
+
+	if h == nil {
+		return true, nil
+	}
+	return true, h(cmd)
+}
+
+func (r *Router) fire(f network.Emitter, src string, err error) {
+	switch err {
+	case nil, ErrUnknownCommand, ErrPermissionDenied, ErrCooldown:
+	default:
+		f.Fire(&network.AsyncError{Src: src, Err: err})
+	}
+}
+
+// ClientFlags converts bncs.ChatUserFlags to UserFlags
+func ClientFlags(f bncs.ChatUserFlags) UserFlags {
+	var res UserFlags
+	if f&(bncs.ChatUserFlagBlizzard|bncs.ChatUserFlagAdmin) != 0 {
+		res |= UserFlagAdmin
+	}
+	if f&bncs.ChatUserFlagOperator != 0 {
+		res |= UserFlagModerator
+	}
+	if f&bncs.ChatUserFlagSpeaker != 0 {
+		res |= UserFlagSpeaker
+	}
+	if f&bncs.ChatUserFlagSquelched != 0 {
+		res |= UserFlagMuteWhisper
+	}
+	return res
+}
+
+// ChatFlags returns the UserFlags of user in c
+// Detailed flags are only available for *Bot and *bnet.Client, other transports only report operators
+func ChatFlags(c network.Chat, user *network.ChatUser) UserFlags {
+	switch t := c.(type) {
+	case *Bot:
+		if uid, err := t.userID(user.Name); err == nil {
+			if u, ok := t.User(uid); ok {
+				return u.Flags
+			}
+		}
+	case *bnet.Client:
+		if u, ok := t.User(user.Name); ok {
+			return ClientFlags(u.Flags)
+		}
+	}
+
+	if user.Operator {
+		return UserFlagModerator
+	}
+	return 0
+}
+
+// Bind routes incoming chat messages and whispers of c, emotes are ignored
+// Commands are handled in order on a separate goroutine (handlers are likely to wait for RPC responses),
+// at most RouterQueueSize messages are queued before new messages are dropped
+// Returns a function that unbinds c and stops the goroutine
+func (r *Router) Bind(c network.Chat) func() {
+	var queue = make(chan *Message, RouterQueueSize)
+	var done = make(chan struct{})
+
+	go func() {
+		for msg := range queue {
+			_, err := r.Route(msg)
+			r.fire(c, "Router.Bind[Route]", err)
+		}
+		close(done)
+	}()
+
+	var mut sync.Mutex
+	var closed bool
+
+	var eid = c.On(&network.ChatMessage{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*network.ChatMessage)
+		if pkt.Emote || !strings.HasPrefix(pkt.Content, r.Prefix) {
+			return
+		}
+
+		var msg = Message{
+			Username: pkt.Name,
+			Flags:    ChatFlags(c, &pkt.ChatUser),
+			Content:  pkt.Content,
+			Whisper:  pkt.Whisper,
+			Reply:    c.Say,
+		}
+		if pkt.Whisper {
+			var name = pkt.Name
+			msg.Reply = func(s string) error { return c.Whisper(name, s) }
+		}
+
+		mut.Lock()
+		if closed {
+			mut.Unlock()
+			return
+		}
+		select {
+		case queue <- &msg:
+			mut.Unlock()
+		default:
+			mut.Unlock()
+			c.Fire(&network.AsyncError{Src: "Router.Bind[Queue]", Err: network.ErrQueueFull})
+		}
+	})
+
+	return func() {
+		c.Off(eid)
+
+		mut.Lock()
+		if !closed {
+			closed = true
+			close(queue)
+		}
+		mut.Unlock()
+
+		<-done
+	}
+}
@@ -0,0 +1,139 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package chat_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/chat"
+)
+
+func TestSplitArgs(t *testing.T) {
+	var tests = map[string][]string{
+		"":                    nil,
+		"foo":                 []string{"foo"},
+		"  foo   bar ":        []string{"foo", "bar"},
+		`foo "bar baz" qux`:   []string{"foo", "bar baz", "qux"},
+		`"" a`:                []string{"", "a"},
+		`say "hello world"!`:  []string{"say", "hello world!"},
+		`unterminated "quote`: []string{"unterminated", "quote"},
+	}
+
+	for s, e := range tests {
+		if a := chat.SplitArgs(s); !reflect.DeepEqual(a, e) {
+			t.Fatalf("SplitArgs(%q): expected %q, got %q", s, e, a)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	var r = chat.NewRouter("!")
+
+	var last *chat.Command
+	var h = func(cmd *chat.Command) error {
+		last = cmd
+		return nil
+	}
+
+	r.Add(&chat.Route{Handler: h}, "echo", "say")
+	r.Add(&chat.Route{Handler: h, Require: chat.UserFlagModerator}, "kick")
+	r.Add(&chat.Route{Handler: h, UserCooldown: time.Hour}, "roll")
+
+	if ok, err := r.Route(&chat.Message{Content: "hello"}); ok || err != nil {
+		t.Fatal("Expected non-command to be ignored")
+	}
+	if ok, err := r.Route(&chat.Message{Content: "!nope"}); !ok || err != chat.ErrUnknownCommand {
+		t.Fatal("Expected ErrUnknownCommand, got", err)
+	}
+
+	if _, err := r.Route(&chat.Message{Username: "niels", Content: "!SAY  hello  world"}); err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || last.Name != "SAY" || last.Arg != "hello  world" || !reflect.DeepEqual(last.Args, []string{"hello", "world"}) || last.Username != "niels" {
+		t.Fatalf("Unexpected command %+v", last)
+	}
+
+	if _, err := r.Route(&chat.Message{Content: "!kick foo"}); err != chat.ErrPermissionDenied {
+		t.Fatal("Expected ErrPermissionDenied, got", err)
+	}
+	if _, err := r.Route(&chat.Message{Content: "!kick foo", Flags: chat.UserFlagModerator}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Route(&chat.Message{Content: "!kick foo", Flags: chat.UserFlagAdmin}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Route(&chat.Message{Username: "a", Content: "!roll"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Route(&chat.Message{Username: "A", Content: "!roll"}); err != chat.ErrCooldown {
+		t.Fatal("Expected ErrCooldown, got", err)
+	}
+	if _, err := r.Route(&chat.Message{Username: "b", Content: "!roll"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Remove("echo")
+	if _, err := r.Route(&chat.Message{Content: "!echo"}); err != chat.ErrUnknownCommand {
+		t.Fatal("Expected ErrUnknownCommand, got", err)
+	}
+	if _, err := r.Route(&chat.Message{Content: "!say"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeChat struct {
+	network.EventEmitter
+	mut  sync.Mutex
+	said []string
+}
+
+func (c *fakeChat) Run() error                             { return nil }
+func (c *fakeChat) RunContext(ctx context.Context) error   { return nil }
+func (c *fakeChat) Close() error                           { return nil }
+func (c *fakeChat) Channel() string                        { return "" }
+func (c *fakeChat) ChatUsers() map[string]network.ChatUser { return nil }
+func (c *fakeChat) JoinChannel(channel string) error       { return nil }
+func (c *fakeChat) Kick(username string) error             { return nil }
+func (c *fakeChat) Ban(username string) error              { return nil }
+func (c *fakeChat) Unban(username string) error            { return nil }
+func (c *fakeChat) Say(s string) error                     { return c.Whisper("", s) }
+func (c *fakeChat) Whisper(username string, s string) error {
+	c.mut.Lock()
+	c.said = append(c.said, username+":"+s)
+	c.mut.Unlock()
+	return nil
+}
+
+func TestRouterBind(t *testing.T) {
+	var r = chat.NewRouter("!")
+	r.Add(&chat.Route{Handler: func(cmd *chat.Command) error { return cmd.Reply(cmd.Arg) }}, "echo")
+	r.Add(&chat.Route{Handler: func(cmd *chat.Command) error { return cmd.Reply(cmd.Arg) }, Require: chat.UserFlagModerator}, "op")
+
+	var c fakeChat
+	var unbind = r.Bind(&c)
+
+	for i := 0; i < 10; i++ {
+		c.Fire(&network.ChatMessage{ChatUser: network.ChatUser{Name: "a"}, Content: fmt.Sprintf("!echo %d", i)})
+	}
+	c.Fire(&network.ChatMessage{ChatUser: network.ChatUser{Name: "b"}, Content: "!echo w", Whisper: true})
+	c.Fire(&network.ChatMessage{ChatUser: network.ChatUser{Name: "c"}, Content: "!echo emote", Emote: true})
+	c.Fire(&network.ChatMessage{ChatUser: network.ChatUser{Name: "d"}, Content: "!op no"})
+	c.Fire(&network.ChatMessage{ChatUser: network.ChatUser{Name: "e", Operator: true}, Content: "!op yes"})
+
+	unbind()
+	c.Fire(&network.ChatMessage{ChatUser: network.ChatUser{Name: "a"}, Content: "!echo late"})
+
+	var expected = []string{":0", ":1", ":2", ":3", ":4", ":5", ":6", ":7", ":8", ":9", "b:w", ":yes"}
+	if !reflect.DeepEqual(c.said, expected) {
+		t.Fatalf("Expected %v, got %v", expected, c.said)
+	}
+}
@@ -7,6 +7,7 @@ package chat
 import (
 	"time"
 
+	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/protocol/capi"
 )
 
@@ -53,3 +54,13 @@ func (u *User) Update(ev *capi.UserUpdateEvent) {
 func (u *User) Operator() bool {
 	return u.Flags&(UserFlagAdmin|UserFlagModerator) != 0
 }
+
+// ChatUser converts u to its transport-agnostic representation
+func (u *User) ChatUser() network.ChatUser {
+	return network.ChatUser{
+		Name:     u.Username,
+		Operator: u.Operator(),
+		Joined:   u.Joined,
+		LastSeen: u.LastSeen,
+	}
+}
@@ -0,0 +1,99 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// ReadDeadliner is implemented by connections that support read deadlines (net.Conn, net.PacketConn, websocket.Conn)
+type ReadDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// ContextDeadline returns the earliest deadline of timeout and ctx
+// ok is false if neither timeout nor ctx impose a deadline
+func ContextDeadline(ctx context.Context, timeout time.Duration) (deadline time.Time, ok bool) {
+	if timeout >= 0 {
+		deadline = Deadline(timeout)
+		ok = true
+	}
+	if d, has := ctx.Deadline(); has && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+		ok = true
+	}
+	return
+}
+
+// setReadDeadline applies ContextDeadline to conn
+func setReadDeadline(ctx context.Context, conn ReadDeadliner, timeout time.Duration) error {
+	if d, ok := ContextDeadline(ctx, timeout); ok {
+		return conn.SetReadDeadline(d)
+	}
+	return nil
+}
+
+// WatchContext interrupts pending reads on conn once ctx is done
+// Call the returned function once the read is finished
+// Starts a goroutine for every call if ctx can be cancelled
+func WatchContext(ctx context.Context, conn ReadDeadliner) func() {
+	var done = ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+
+	var stop = make(chan struct{})
+	var res = make(chan bool, 1)
+
+	go func() {
+		select {
+		case <-done:
+			conn.SetReadDeadline(time.Unix(1, 0))
+			res <- true
+		case <-stop:
+			res <- false
+		}
+	}()
+
+	return func() {
+		close(stop)
+		if <-res {
+			// Reset deadline so that subsequent reads do not fail immediately
+			conn.SetReadDeadline(time.Time{})
+		}
+	}
+}
+
+// interrupted reports whether a read timed out (or was cancelled) after n bytes of a packet were already consumed
+// A stream connection can not be resynchronized in that case
+func interrupted(n int, err error) bool {
+	if n == 0 || err == nil {
+		return false
+	}
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+// ContextError returns ctx.Err() if ctx expired while err occurred
+// Timeouts (including context.DeadlineExceeded) are wrapped as protocol.KindTimeout
+func ContextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if e := ctx.Err(); e != nil {
+		err = e
+	} else if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+		// Read deadline may trigger just before ctx is marked as expired
+		err = context.DeadlineExceeded
+	}
+	if err == context.DeadlineExceeded || IsTimeout(err) {
+		return protocol.WrapError(protocol.KindTimeout, err)
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestContextDeadline(t *testing.T) {
+	if _, ok := network.ContextDeadline(context.Background(), network.NoTimeout); ok {
+		t.Fatal("Expected no deadline")
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if d, ok := network.ContextDeadline(ctx, time.Hour); !ok || time.Until(d) > time.Second {
+		t.Fatal("Expected context deadline")
+	}
+	if d, ok := network.ContextDeadline(ctx, time.Millisecond); !ok || time.Until(d) > time.Millisecond {
+		t.Fatal("Expected timeout deadline")
+	}
+}
+
+func TestNextPacketContext(t *testing.T) {
+	var c1, c2 = net.Pipe()
+	defer c2.Close()
+
+	var conn = network.NewW3GSConn(c1, nil, w3gs.Encoding{})
+	defer conn.Close()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	if _, err := conn.NextPacketContext(ctx, network.NoTimeout); err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := conn.NextPacketContext(ctx, network.NoTimeout); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, protocol.KindTimeout) {
+		t.Fatal("Expected context.DeadlineExceeded, got", err)
+	}
+
+	// Connection still usable after interrupted read
+	go c2.Write([]byte{w3gs.ProtocolSig, w3gs.PidPingFromHost, 8, 0, 1, 2, 3, 4})
+	pkt, err := conn.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pkt.(*w3gs.Ping); !ok {
+		t.Fatalf("Expected Ping, got %T", pkt)
+	}
+}
+
+func TestNextPacketContextPartial(t *testing.T) {
+	var c1, c2 = net.Pipe()
+	defer c2.Close()
+
+	var conn = network.NewW3GSConn(c1, nil, w3gs.Encoding{})
+	defer conn.Close()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	// Write part of the header, cancel once it is consumed
+	go func() {
+		c2.Write([]byte{w3gs.ProtocolSig, w3gs.PidPingFromHost})
+		cancel()
+	}()
+
+	if _, err := conn.NextPacketContext(ctx, network.NoTimeout); err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+
+	// Stream is out of sync, connection should be closed
+	if _, err := c2.Write([]byte{8, 0, 1, 2, 3, 4}); err == nil {
+		t.Fatal("Expected closed connection")
+	}
+	if _, err := conn.NextPacket(time.Second); err == nil {
+		t.Fatal("Expected error after partial read")
+	}
+}
+
+func TestNextPacketTimeout(t *testing.T) {
+	udp, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conn = network.NewW3GSPacketConn(udp, nil, w3gs.Encoding{})
+	defer conn.Close()
+
+	_, _, err = conn.NextPacket(10 * time.Millisecond)
+	if !errors.Is(err, protocol.KindTimeout) {
+		t.Fatal("Expected KindTimeout, got", err)
+	}
+	if !network.IsTimeout(err) || network.ErrorKind(err) != protocol.KindTimeout {
+		t.Fatal("Expected timeout, got", err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err = conn.NextPacketContext(ctx, network.NoTimeout)
+	if !errors.Is(err, protocol.KindTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("Expected DeadlineExceeded, got", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := conn.NextPacketContext(ctx, network.NoTimeout); err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+}
@@ -0,0 +1,164 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/capi"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// DispatchDropped event, fired when an asynchronous handler is skipped because the queue of its worker is full
+type DispatchDropped struct {
+	Topic string
+	Arg   EventArg
+}
+
+// Dispatcher runs asynchronous event handlers on a bounded pool of worker goroutines, see EventEmitter.OnAsync()
+// Events with the same topic are always handled by the same worker, in the order they were fired
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Dispatcher struct {
+	workers []*dispatchWorker
+	size    int
+	dropped uint64
+	wg      sync.WaitGroup
+}
+
+type dispatchWorker struct {
+	mut    sync.Mutex
+	queue  []func()
+	ready  chan struct{}
+	closed bool
+}
+
+type dispatchResult int
+
+const (
+	dispatchQueued dispatchResult = iota
+	dispatchDropped
+	dispatchClosed
+)
+
+// NewDispatcher starts a pool of n workers with room for queueSize pending events each (0 for unbounded)
+// Events fired while the queue of a worker is full are dropped
+func NewDispatcher(n int, queueSize int) *Dispatcher {
+	if n < 1 {
+		n = 1
+	}
+
+	var d = Dispatcher{
+		workers: make([]*dispatchWorker, n),
+		size:    queueSize,
+	}
+
+	d.wg.Add(n)
+	for i := range d.workers {
+		var w = &dispatchWorker{ready: make(chan struct{}, 1)}
+		d.workers[i] = w
+		go func() {
+			w.run()
+			d.wg.Done()
+		}()
+	}
+
+	return &d
+}
+
+// Dropped returns the number of events dropped because of a full queue
+func (d *Dispatcher) Dropped() uint64 {
+	return atomic.LoadUint64(&d.dropped)
+}
+
+// Len returns the number of pending events
+func (d *Dispatcher) Len() int {
+	var n = 0
+	for _, w := range d.workers {
+		w.mut.Lock()
+		n += len(w.queue)
+		w.mut.Unlock()
+	}
+	return n
+}
+
+// Close stops the workers once all pending events are handled, events fired after Close() are handled synchronously
+func (d *Dispatcher) Close() {
+	for _, w := range d.workers {
+		w.mut.Lock()
+		w.closed = true
+		w.mut.Unlock()
+		w.signal()
+	}
+	d.wg.Wait()
+}
+
+// dispatch schedules f on the worker for topic ht
+func (d *Dispatcher) dispatch(ht string, f func()) dispatchResult {
+	var w = d.workers[0]
+	if len(d.workers) > 1 {
+		var h = fnv.New32a()
+		h.Write([]byte(ht))
+		w = d.workers[h.Sum32()%uint32(len(d.workers))]
+	}
+
+	w.mut.Lock()
+	if w.closed {
+		w.mut.Unlock()
+		return dispatchClosed
+	}
+	if d.size > 0 && len(w.queue) >= d.size {
+		w.mut.Unlock()
+		atomic.AddUint64(&d.dropped, 1)
+		return dispatchDropped
+	}
+	w.queue = append(w.queue, f)
+	w.mut.Unlock()
+
+	w.signal()
+	return dispatchQueued
+}
+
+func (w *dispatchWorker) signal() {
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (w *dispatchWorker) run() {
+	for range w.ready {
+		for {
+			w.mut.Lock()
+			if len(w.queue) == 0 {
+				var closed = w.closed
+				w.mut.Unlock()
+				if closed {
+					return
+				}
+				break
+			}
+
+			var f = w.queue[0]
+			w.queue[0] = nil
+			w.queue = w.queue[1:]
+			w.mut.Unlock()
+
+			f()
+		}
+	}
+}
+
+// Decoded packets are reused by the next read, so they can not outlive the Fire() call
+func isPacket(a EventArg) bool {
+	switch a.(type) {
+	case w3gs.Packet, bncs.Packet, *capi.Packet:
+		return true
+	default:
+		return false
+	}
+}
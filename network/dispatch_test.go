@@ -0,0 +1,124 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestDispatcher(t *testing.T) {
+	var d = network.NewDispatcher(4, 0)
+
+	var e network.EventEmitter
+	e.SetDispatcher(d)
+
+	var block = make(chan struct{})
+	e.OnAsync(network.Topic("slow"), func(ev *network.Event) {
+		<-block
+	})
+
+	var res []int
+	e.OnAsync(0, func(ev *network.Event) {
+		res = append(res, ev.Arg.(int))
+	})
+
+	var start = time.Now()
+	e.Fire(network.Topic("slow"))
+	for i := 0; i < 100; i++ {
+		e.Fire(i)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("Fire blocked by slow handler")
+	}
+
+	close(block)
+	d.Close()
+
+	if len(res) != 100 {
+		t.Fatal("Expected 100 events, got", len(res))
+	}
+	for i, v := range res {
+		if v != i {
+			t.Fatal("Out of order", i, v)
+		}
+	}
+
+	// Handled synchronously after Close()
+	e.Fire(100)
+	if len(res) != 101 {
+		t.Fatal("Expected synchronous dispatch after Close()")
+	}
+
+	d = network.NewDispatcher(1, 1)
+	e.SetDispatcher(d)
+
+	var dropped []*network.DispatchDropped
+	e.On(&network.DispatchDropped{}, func(ev *network.Event) {
+		dropped = append(dropped, ev.Arg.(*network.DispatchDropped))
+	})
+
+	block = make(chan struct{})
+	e.Fire(network.Topic("slow"))
+	for d.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	e.Fire(101)
+	e.Fire(102)
+	if d.Dropped() != 1 || len(dropped) != 1 || dropped[0].Arg != 102 {
+		t.Fatal("Expected 1 dropped event, got", d.Dropped(), dropped)
+	}
+
+	close(block)
+	d.Close()
+
+	if len(res) != 102 || res[101] != 101 {
+		t.Fatal("Unexpected events", res[100:])
+	}
+}
+
+func TestDispatcherSync(t *testing.T) {
+	var d = network.NewDispatcher(1, 0)
+
+	var e network.EventEmitter
+	e.SetDispatcher(d)
+
+	// Synchronous handlers can still prevent async handlers
+	var async = 0
+	e.OnAsync(0, func(ev *network.Event) { async++ })
+	e.On(0, func(ev *network.Event) {
+		if ev.Arg.(int) == 1 {
+			ev.PreventNext()
+		}
+	})
+
+	if !e.Fire(1) {
+		t.Fatal("Expected PreventNext")
+	}
+	if e.Fire(2) {
+		t.Fatal("Unexpected PreventNext")
+	}
+
+	// Packets are handled before Fire returns
+	var pkt = &w3gs.Ping{Payload: 1}
+	var seen uint32
+	e.OnAsync(pkt, func(ev *network.Event) {
+		seen = ev.Arg.(*w3gs.Ping).Payload
+	})
+	e.Fire(pkt)
+	pkt.Payload = 2
+	if seen != 1 {
+		t.Fatal("Expected synchronous packet handler")
+	}
+
+	d.Close()
+	if async != 1 {
+		t.Fatal("Expected 1 async event, got", async)
+	}
+}
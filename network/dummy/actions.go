@@ -0,0 +1,148 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package dummy
+
+import (
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Action is a single in-game action submitted with Player.Act() (see file/w3g/w3g_actions.txt)
+type Action interface {
+	Serialize(buf *protocol.Buffer, enc *w3gs.Encoding) error
+}
+
+// Action type identifiers
+const (
+	AidOrder           = 0x10
+	AidPointOrder      = 0x11
+	AidTargetOrder     = 0x12
+	AidChangeSelection = 0x16
+	AidSyncInteger     = 0x6B
+)
+
+// Selection modes
+const (
+	SelectAdd    = 0x01
+	SelectRemove = 0x02
+)
+
+// ObjectID of a unit, building or item
+type ObjectID struct {
+	A uint32
+	B uint32
+}
+
+// NoObject is used as target for orders without target object
+var NoObject = ObjectID{A: 0xFFFFFFFF, B: 0xFFFFFFFF}
+
+// Order action [0x10], (unit/building) ability without additional parameters
+type Order struct {
+	AbilityFlags uint16
+	ItemID       uint32
+}
+
+func (a *Order) serializeContent(aid uint8, buf *protocol.Buffer, enc *w3gs.Encoding) {
+	buf.WriteUInt8(aid)
+	if enc.GameVersion == 0 || enc.GameVersion >= 13 {
+		buf.WriteUInt16(a.AbilityFlags)
+	} else {
+		buf.WriteUInt8(uint8(a.AbilityFlags))
+	}
+	buf.WriteUInt32(a.ItemID)
+	if enc.GameVersion == 0 || enc.GameVersion >= 7 {
+		buf.WriteUInt32(0xFFFFFFFF)
+		buf.WriteUInt32(0xFFFFFFFF)
+	}
+}
+
+// Serialize encodes the struct into its binary form.
+func (a *Order) Serialize(buf *protocol.Buffer, enc *w3gs.Encoding) error {
+	a.serializeContent(AidOrder, buf, enc)
+	return nil
+}
+
+// PointOrder action [0x11], (unit/building) ability with target position
+type PointOrder struct {
+	Order
+	X float32
+	Y float32
+}
+
+// Serialize encodes the struct into its binary form.
+func (a *PointOrder) Serialize(buf *protocol.Buffer, enc *w3gs.Encoding) error {
+	a.serializeContent(AidPointOrder, buf, enc)
+	buf.WriteFloat32(a.X)
+	buf.WriteFloat32(a.Y)
+	return nil
+}
+
+// TargetOrder action [0x12], (unit/building) ability with target position and target object
+type TargetOrder struct {
+	Order
+	X      float32
+	Y      float32
+	Target ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (a *TargetOrder) Serialize(buf *protocol.Buffer, enc *w3gs.Encoding) error {
+	a.serializeContent(AidTargetOrder, buf, enc)
+	buf.WriteFloat32(a.X)
+	buf.WriteFloat32(a.Y)
+	buf.WriteUInt32(a.Target.A)
+	buf.WriteUInt32(a.Target.B)
+	return nil
+}
+
+// ChangeSelection action [0x16], adds or removes units/buildings from selection
+type ChangeSelection struct {
+	Mode    uint8
+	Objects []ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (a *ChangeSelection) Serialize(buf *protocol.Buffer, enc *w3gs.Encoding) error {
+	buf.WriteUInt8(AidChangeSelection)
+	buf.WriteUInt8(a.Mode)
+	buf.WriteUInt16(uint16(len(a.Objects)))
+	for _, o := range a.Objects {
+		buf.WriteUInt32(o.A)
+		buf.WriteUInt32(o.B)
+	}
+	return nil
+}
+
+// SyncInteger action [0x6B], stores an integer in a game cache (i.e. W3MMD)
+type SyncInteger struct {
+	File    string
+	Mission string
+	Key     string
+	Value   uint32
+}
+
+// Serialize encodes the struct into its binary form.
+func (a *SyncInteger) Serialize(buf *protocol.Buffer, enc *w3gs.Encoding) error {
+	buf.WriteUInt8(AidSyncInteger)
+	buf.WriteCString(a.File)
+	buf.WriteCString(a.Mission)
+	buf.WriteCString(a.Key)
+	buf.WriteUInt32(a.Value)
+	return nil
+}
+
+// RawAction is sent as-is, for action types not implemented in this package
+type RawAction struct {
+	Data []byte
+}
+
+// Serialize encodes the struct into its binary form.
+func (a *RawAction) Serialize(buf *protocol.Buffer, enc *w3gs.Encoding) error {
+	if len(a.Data) == 0 {
+		return ErrInvalidAction
+	}
+	buf.WriteBlob(a.Data)
+	return nil
+}
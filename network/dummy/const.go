@@ -7,17 +7,25 @@ package dummy
 import (
 	"errors"
 
+	"github.com/nielsAD/gowarcraft3/protocol"
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
 // Errors
 var (
-	ErrJoinRejected       = errors.New("dummy: Join rejected")
-	ErrGameFull           = errors.New("dummy: Join rejected (game full)")
-	ErrGameStarted        = errors.New("dummy: Join rejected (game started)")
+	ErrJoinRejected       = protocol.NewError(protocol.KindRejected, "dummy: Join rejected")
+	ErrGameFull           = protocol.NewError(protocol.KindRejected, "dummy: Join rejected (game full)")
+	ErrGameStarted        = protocol.NewError(protocol.KindRejected, "dummy: Join rejected (game started)")
 	ErrInvalidFirstPacket = errors.New("dummy: Invalid first packet")
+	ErrNotStarted         = errors.New("dummy: Game not started")
+	ErrInvalidAction      = errors.New("dummy: Invalid action")
+	ErrDesync             = protocol.NewError(protocol.KindChecksum, "dummy: Game state split from host")
+	ErrMapMismatch        = errors.New("dummy: Local map does not match host")
 )
 
+// MaxActionSize is the max size of the actions sent in a single GameAction packet
+const MaxActionSize = 1024
+
 // RejectReasonToError converts w3gs.RejectReason to an appropriate error
 func RejectReasonToError(r w3gs.RejectReason) error {
 	switch r {
@@ -6,8 +6,11 @@
 package dummy
 
 import (
+	"context"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
@@ -28,19 +31,42 @@ type Say struct {
 	Content string
 }
 
+// Loaded event, fired once GameLoaded is sent to host
+type Loaded struct{}
+
 // Player represents a mocked player that can join a game lobby
 type Player struct {
 	peer.Host
 	network.W3GSConn
 
+	actmut  sync.Mutex
+	actions protocol.Buffer
+	actcut  []int
+
+	// Atomic
+	started uint32
+	loaded  uint32
+
 	// Set once before Join(), read-only after that
-	HostAddr    string
-	HostCounter uint32
-	DialPeers   bool
+	HostAddr     string
+	HostCounter  uint32
+	DialPeers    bool
+	Observer     bool                      // Move to observer team after joining
+	AckTimeSlots bool                      // Acknowledge TimeSlots, required to stay in game once it has started
+	Checksum     func(ticks uint32) uint32 // Game state checksum sent with TimeSlotAck after ticks ms of game time (nil for zero, desyncs with real clients)
+	LoadTime     time.Duration             // Time spent loading between CountDownEnd and GameLoaded
+	Map          *MapFile                  // Local map verified against MapCheck (nil to accept any map)
+	Logger       network.Logger
+	Reconnect    network.ReconnectConfig
 }
 
 // Join a game lobby as a mocked player
 func Join(addr string, name string, hostCounter uint32, entryKey uint32, listenPort int, encoding w3gs.Encoding) (*Player, error) {
+	return JoinContext(context.Background(), addr, name, hostCounter, entryKey, listenPort, encoding)
+}
+
+// JoinContext joins a game lobby as a mocked player, aborts once ctx is done
+func JoinContext(ctx context.Context, addr string, name string, hostCounter uint32, entryKey uint32, listenPort int, encoding w3gs.Encoding) (*Player, error) {
 	var p = Player{
 		Host: peer.Host{
 			PlayerInfo: w3gs.PlayerInfo{
@@ -49,10 +75,13 @@ func Join(addr string, name string, hostCounter uint32, entryKey uint32, listenP
 			Encoding:     encoding,
 			EntryKey:     entryKey,
 			PingInterval: 10 * time.Second,
+
+			KeepAliveTimeout: 30 * time.Second,
 		},
-		HostAddr:    addr,
-		HostCounter: hostCounter,
-		DialPeers:   true,
+		HostAddr:     addr,
+		HostCounter:  hostCounter,
+		DialPeers:    true,
+		AckTimeSlots: true,
 	}
 
 	p.InitDefaultHandlers()
@@ -65,36 +94,40 @@ func Join(addr string, name string, hostCounter uint32, entryKey uint32, listenP
 		}
 	}
 
-	var err = p.Join()
+	var err = p.JoinContext(ctx)
 	return &p, err
 }
 
 // JoinWithConn initializes a connection to host
 // Not safe for concurrent invocation
 func (p *Player) JoinWithConn(conn net.Conn) error {
+	return p.JoinWithConnContext(context.Background(), conn)
+}
+
+// JoinWithConnContext initializes a connection to host, aborts once ctx is done
+// Not safe for concurrent invocation
+func (p *Player) JoinWithConnContext(ctx context.Context, conn net.Conn) error {
 	w3gsconn := network.NewW3GSConn(conn, nil, p.Encoding)
+	w3gsconn.SetLogger(p.Logger)
 
 	p.PlayerInfo.JoinCounter++
-	if _, err := w3gsconn.Send(&w3gs.Join{
+	pkt, err := w3gsconn.RequestContext(ctx, &w3gs.Join{
 		HostCounter:  p.HostCounter,
 		EntryKey:     p.EntryKey,
 		ListenPort:   p.PlayerInfo.InternalAddr.Port,
 		JoinCounter:  p.PlayerInfo.JoinCounter,
 		PlayerName:   p.PlayerInfo.PlayerName,
 		InternalAddr: p.PlayerInfo.InternalAddr,
-	}); err != nil {
-		w3gsconn.Close()
-		return err
-	}
-
-	pkt, err := w3gsconn.NextPacket(10 * time.Second)
+	}, nil, 10*time.Second)
 	if err != nil {
 		w3gsconn.Close()
 		return err
 	}
 
+	var join *w3gs.SlotInfoJoin
 	switch r := pkt.(type) {
 	case *w3gs.SlotInfoJoin:
+		join = r
 		p.PlayerInfo.PlayerID = r.PlayerID
 	case *w3gs.RejectJoin:
 		w3gsconn.Close()
@@ -104,7 +137,19 @@ func (p *Player) JoinWithConn(conn net.Conn) error {
 		return ErrInvalidFirstPacket
 	}
 
+	if p.Logger != nil {
+		p.Logger.Info("Joined game", network.LogKeyGameID, p.HostCounter, network.LogKeyPeer, conn.RemoteAddr(), network.LogKeyUser, p.PlayerInfo.PlayerName)
+	}
+
+	atomic.StoreUint32(&p.started, 0)
+	atomic.StoreUint32(&p.loaded, 0)
+	p.actmut.Lock()
+	p.actions.Truncate()
+	p.actcut = p.actcut[:0]
+	p.actmut.Unlock()
+
 	p.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), p.Encoding)
+	p.SetLogger(p.Logger)
 
 	if p.Encoding.GameVersion == 0 || p.Encoding.GameVersion >= 10032 {
 		if _, err := p.SendOrClose(&w3gs.PlayerExtra{
@@ -134,18 +179,31 @@ func (p *Player) JoinWithConn(conn net.Conn) error {
 		}
 	}
 
+	p.Fire(join)
+
+	if p.Observer {
+		var obs uint8 = 12
+		if p.Encoding.GameVersion == 0 || p.Encoding.GameVersion >= 29 {
+			obs = 24
+		}
+		if err := p.ChangeTeam(obs); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Join opens a new connection to host
 // Not safe for concurrent invocation
 func (p *Player) Join() error {
-	addr, err := net.ResolveTCPAddr("tcp", p.HostAddr)
-	if err != nil {
-		return err
-	}
+	return p.JoinContext(context.Background())
+}
 
-	conn, err := net.DialTCP("tcp", nil, addr)
+// JoinContext opens a new connection to host, aborts once ctx is done
+// Not safe for concurrent invocation
+func (p *Player) JoinContext(ctx context.Context) error {
+	conn, err := network.DialTCP(ctx, p.Family, p.HostAddr)
 	if err != nil {
 		return err
 	}
@@ -154,7 +212,7 @@ func (p *Player) Join() error {
 	conn.SetNoDelay(true)
 	conn.SetLinger(3)
 
-	return p.JoinWithConn(conn)
+	return p.JoinWithConnContext(ctx, conn)
 }
 
 // SendOrClose sends pkt to player, closes connection on failure
@@ -177,20 +235,121 @@ func (p *Player) Close() error {
 // Run reads packets and emits an event for each received packet
 // Not safe for concurrent invocation
 func (p *Player) Run() error {
-	var err = p.W3GSConn.Run(&p.EventEmitter, 35*time.Second)
+	return p.RunContext(context.Background())
+}
+
+// RunContext reads packets until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (p *Player) RunContext(ctx context.Context) error {
+	if p.KeepAliveTimeout != 0 {
+		// Pings are sent by host, only check for activity
+		var k = network.NewW3GSKeepAlive(&p.W3GSConn, 0, p.KeepAliveTimeout)
+		k.Emitter = &p.EventEmitter
+
+		var eid = k.Bind(&p.EventEmitter)
+		defer p.Off(eid)
+
+		var stop = k.Start()
+		defer stop()
+	}
+
+	var err = p.W3GSConn.RunContext(ctx, &p.EventEmitter, 35*time.Second)
 	p.Leave(w3gs.LeaveLobby)
 
 	return err
 }
 
-// Say sends a chat message
-func (p *Player) Say(s string) error {
-	s = strings.Map(func(r rune) rune {
+// NewReconnector returns a Reconnector that joins the lobby again whenever the connection breaks
+// Rejected joins and cleanly closed connections are not retried
+func (p *Player) NewReconnector() *network.Reconnector {
+	var r = network.NewReconnector(p.Reconnect, p.JoinContext, p.RunContext)
+	r.Emitter = &p.EventEmitter
+	r.Probe = network.DialProbe(p.Family.Network("tcp"), p.HostAddr)
+	r.Retry = func(err error) bool {
+		switch err {
+		case ErrJoinRejected, ErrGameFull, ErrGameStarted, ErrInvalidFirstPacket:
+			return false
+		default:
+			return !network.IsCloseError(err)
+		}
+	}
+	return r
+}
+
+// Started reports if the game countdown has ended
+func (p *Player) Started() bool {
+	return atomic.LoadUint32(&p.started) != 0
+}
+
+// Loaded reports if the game has finished loading
+func (p *Player) Loaded() bool {
+	return atomic.LoadUint32(&p.loaded) != 0
+}
+
+// Act queues actions, they are sent to host after the next TimeSlot
+func (p *Player) Act(actions ...Action) error {
+	if !p.Started() {
+		return ErrNotStarted
+	}
+
+	p.actmut.Lock()
+	defer p.actmut.Unlock()
+
+	var size = p.actions.Size()
+	var cuts = len(p.actcut)
+	for _, a := range actions {
+		var start = p.actions.Size()
+		if err := a.Serialize(&p.actions, &p.Encoding); err != nil || p.actions.Size()-start > MaxActionSize {
+			p.actions.Bytes = p.actions.Bytes[:size]
+			p.actcut = p.actcut[:cuts]
+			if err == nil {
+				err = ErrInvalidAction
+			}
+			return err
+		}
+		p.actcut = append(p.actcut, p.actions.Size())
+	}
+
+	return nil
+}
+
+// flushActions sends queued actions to host, split in packets of at most MaxActionSize
+func (p *Player) flushActions() error {
+	p.actmut.Lock()
+	defer p.actmut.Unlock()
+
+	var start = 0
+	for i, end := range p.actcut {
+		if i+1 < len(p.actcut) && p.actcut[i+1]-start <= MaxActionSize {
+			continue
+		}
+		if _, err := p.SendOrClose(&w3gs.GameAction{Data: p.actions.Bytes[start:end]}); err != nil {
+			return err
+		}
+		start = end
+	}
+
+	p.actions.Truncate()
+	p.actcut = p.actcut[:0]
+	return nil
+}
+
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
 		if !unicode.IsPrint(r) {
 			return -1
 		}
 		return r
 	}, s)
+}
+
+// Say sends a chat message, to all players once the game has started
+func (p *Player) Say(s string) error {
+	if p.Started() {
+		return p.SayScope(s, w3gs.ScopeAll)
+	}
+
+	s = printable(s)
 	if len(s) == 0 {
 		return nil
 	}
@@ -209,6 +368,31 @@ func (p *Player) Say(s string) error {
 	return nil
 }
 
+// SayScope sends an in-game chat message to scope
+func (p *Player) SayScope(s string, scope w3gs.MessageScope) error {
+	if !p.Started() {
+		return ErrNotStarted
+	}
+
+	s = printable(s)
+	if len(s) == 0 {
+		return nil
+	}
+
+	if _, err := p.Send(&w3gs.Message{
+		RecipientIDs: p.PeerIDs(),
+		SenderID:     p.PlayerInfo.PlayerID,
+		Type:         w3gs.MsgChatExtra,
+		Scope:        scope,
+		Content:      s,
+	}); err != nil {
+		return err
+	}
+
+	p.Fire(&Say{Content: s})
+	return nil
+}
+
 func (p *Player) changeVal(t w3gs.MessageType, v uint8) error {
 	var _, err = p.SendOrClose(&w3gs.Message{
 		RecipientIDs: []uint8{1},
@@ -251,7 +435,10 @@ func (p *Player) Leave(reason w3gs.LeaveReason) error {
 
 // InitDefaultHandlers adds the default callbacks for relevant packets
 func (p *Player) InitDefaultHandlers() {
-	p.On(&peer.Connected{}, p.onPeerConnected)
+	p.On(&peer.Connected{}, p.onPeerSetChanged)
+	p.On(&peer.Disconnected{}, p.onPeerSetChanged)
+	p.On(&peer.Degraded{}, p.onPeerSetChanged)
+	p.On(&peer.Recovered{}, p.onPeerSetChanged)
 	p.On(&peer.Chat{}, p.onPeerChat)
 	p.On(&w3gs.Ping{}, p.onPing)
 	p.On(&w3gs.MapCheck{}, p.onMapCheck)
@@ -260,11 +447,13 @@ func (p *Player) InitDefaultHandlers() {
 	p.On(&w3gs.PlayerLeft{}, p.onPlayerLeft)
 	p.On(&w3gs.CountDownEnd{}, p.onCountDownEnd)
 	p.On(&w3gs.TimeSlot{}, p.onTimeSlot)
+	p.On(&w3gs.Desync{}, p.onDesync)
 }
 
-func (p *Player) onPeerConnected(ev *network.Event) {
-	if _, err := p.SendOrClose(&w3gs.PeerSet{PeerSet: protocol.BitSet16(p.PeerSet())}); err != nil {
-		p.Fire(&network.AsyncError{Src: "onPeerConnected[Send]", Err: err})
+// Report healthy peers to host, traffic to other peers is routed through host
+func (p *Player) onPeerSetChanged(ev *network.Event) {
+	if _, err := p.SendOrClose(&w3gs.PeerSet{PeerSet: protocol.BitSet16(p.HealthySet())}); err != nil {
+		p.Fire(&network.AsyncError{Src: "onPeerSetChanged[Send]", Err: err})
 	}
 }
 
@@ -287,6 +476,14 @@ func (p *Player) onPing(ev *network.Event) {
 func (p *Player) onMapCheck(ev *network.Event) {
 	var pkt = ev.Arg.(*w3gs.MapCheck)
 
+	if p.Map != nil && !p.Map.Matches(pkt) {
+		p.Fire(&network.AsyncError{Src: "onMapCheck[Matches]", Err: ErrMapMismatch})
+		if _, err := p.SendOrClose(&w3gs.MapState{Ready: false}); err != nil {
+			p.Fire(&network.AsyncError{Src: "onMapCheck[Send]", Err: err})
+		}
+		return
+	}
+
 	if _, err := p.SendOrClose(&w3gs.MapState{Ready: true, FileSize: pkt.FileSize}); err != nil {
 		p.Fire(&network.AsyncError{Src: "onMapCheck[Send]", Err: err})
 	}
@@ -334,16 +531,61 @@ func (p *Player) onPlayerLeft(ev *network.Event) {
 }
 
 func (p *Player) onCountDownEnd(ev *network.Event) {
+	atomic.StoreUint32(&p.started, 1)
+
+	if p.LoadTime <= 0 {
+		p.load()
+		return
+	}
+	time.AfterFunc(p.LoadTime, p.load)
+}
+
+func (p *Player) load() {
+	if !p.Started() || !atomic.CompareAndSwapUint32(&p.loaded, 0, 1) {
+		return
+	}
 	if _, err := p.SendOrClose(&w3gs.GameLoaded{}); err != nil {
-		p.Fire(&network.AsyncError{Src: "onCountDownEnd[Send]", Err: err})
+		p.Fire(&network.AsyncError{Src: "load[Send]", Err: err})
+		return
 	}
+	p.Fire(&Loaded{})
 }
 
 func (p *Player) onTimeSlot(ev *network.Event) {
-	// Cannot reply to this as we don't know the correct checksum for this round
-	// replying with wrong info will result in a desync
+	// We don't know the correct checksum for this round (unless Checksum is set),
+	// replying with wrong info will result in a desync with real clients,
 	// not replying will result in lagscreen and drop
 
 	var pkt = ev.Arg.(*w3gs.TimeSlot)
 	p.IncGameTicks(uint32(pkt.TimeIncrementMS))
+
+	if pkt.Fragment {
+		return
+	}
+
+	if p.AckTimeSlots {
+		var ack w3gs.TimeSlotAck
+		if p.Checksum != nil {
+			ack.Checksum = p.Checksum(p.GameTicks())
+		}
+		if _, err := p.SendOrClose(&ack); err != nil {
+			p.Fire(&network.AsyncError{Src: "onTimeSlot[Ack]", Err: err})
+			return
+		}
+	}
+
+	if err := p.flushActions(); err != nil {
+		p.Fire(&network.AsyncError{Src: "onTimeSlot[Send]", Err: err})
+	}
+}
+
+func (p *Player) onDesync(ev *network.Event) {
+	var pkt = ev.Arg.(*w3gs.Desync)
+	for _, pid := range pkt.PlayersInState {
+		if pid == p.PlayerInfo.PlayerID {
+			return
+		}
+	}
+
+	p.Fire(&network.AsyncError{Src: "onDesync", Err: ErrDesync})
 }
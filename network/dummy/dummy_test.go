@@ -5,13 +5,22 @@
 package dummy_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net"
+	"reflect"
+	"testing"
 	"time"
 
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/file/w3m"
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/network/dummy"
 	"github.com/nielsAD/gowarcraft3/network/lan"
+	"github.com/nielsAD/gowarcraft3/network/lobby"
+	"github.com/nielsAD/gowarcraft3/network/peer"
+	"github.com/nielsAD/gowarcraft3/protocol"
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
@@ -48,3 +57,385 @@ func Example() {
 	// Run() blocks until the connection is closed
 	player.Run()
 }
+
+func TestActions(t *testing.T) {
+	var enc = w3gs.Encoding{GameVersion: w3gs.CurrentGameVersion}
+
+	c1, c2 := net.Pipe()
+	var host = network.NewW3GSConn(c2, nil, enc)
+	defer host.Close()
+
+	var p = dummy.Player{
+		Host:         peer.Host{Encoding: enc},
+		AckTimeSlots: true,
+	}
+	p.PlayerInfo.PlayerID = 2
+	p.InitDefaultHandlers()
+	p.SetConn(c1, w3gs.NewFactoryCache(w3gs.DefaultFactory), enc)
+	defer p.Close()
+
+	go p.Run()
+
+	var order = dummy.PointOrder{Order: dummy.Order{ItemID: 0x000D0012}, X: 1, Y: -1}
+	var sync = dummy.SyncInteger{File: "MMD.Dat", Mission: "val:0", Key: "init", Value: 1}
+	if err := p.Act(&order); err != dummy.ErrNotStarted {
+		t.Fatal("Expected ErrNotStarted, got", err)
+	}
+	if err := p.SayScope("gl hf", w3gs.ScopeAll); err != dummy.ErrNotStarted {
+		t.Fatal("Expected ErrNotStarted, got", err)
+	}
+
+	if _, err := host.Send(&w3gs.CountDownEnd{}); err != nil {
+		t.Fatal(err)
+	}
+	if pkt, err := host.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	} else if _, ok := pkt.(*w3gs.GameLoaded); !ok {
+		t.Fatal("Expected GameLoaded, got", pkt)
+	}
+	if !p.Started() {
+		t.Fatal("Expected started")
+	}
+
+	if err := p.Act(&order, &sync); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Act(&dummy.RawAction{}); err != dummy.ErrInvalidAction {
+		t.Fatal("Expected ErrInvalidAction, got", err)
+	}
+
+	go host.Send(&w3gs.TimeSlot{TimeIncrementMS: 100})
+
+	if pkt, err := host.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	} else if _, ok := pkt.(*w3gs.TimeSlotAck); !ok {
+		t.Fatal("Expected TimeSlotAck, got", pkt)
+	}
+
+	pkt, err := host.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, ok := pkt.(*w3gs.GameAction)
+	if !ok {
+		t.Fatal("Expected GameAction, got", pkt)
+	}
+
+	var expected protocol.Buffer
+	expected.WriteUInt8(dummy.AidPointOrder)
+	expected.WriteUInt16(0)
+	expected.WriteUInt32(0x000D0012)
+	expected.WriteUInt32(0xFFFFFFFF)
+	expected.WriteUInt32(0xFFFFFFFF)
+	expected.WriteFloat32(1)
+	expected.WriteFloat32(-1)
+	expected.WriteUInt8(dummy.AidSyncInteger)
+	expected.WriteCString("MMD.Dat")
+	expected.WriteCString("val:0")
+	expected.WriteCString("init")
+	expected.WriteUInt32(1)
+
+	if !bytes.Equal(act.Data, expected.Bytes) {
+		t.Fatal("Action data mismatch", act.Data, expected.Bytes)
+	}
+	if p.GameTicks() != 100 {
+		t.Fatal("Expected 100 game ticks, got", p.GameTicks())
+	}
+
+	go p.SayScope("gl hf", w3gs.ScopeAllies)
+	if pkt, err := host.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	} else if msg, ok := pkt.(*w3gs.Message); !ok || msg.Type != w3gs.MsgChatExtra || msg.Scope != w3gs.ScopeAllies || msg.Content != "gl hf" {
+		t.Fatal("Expected in-game chat, got", pkt)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var enc = w3gs.Encoding{GameVersion: 26}
+
+	c1, c2 := net.Pipe()
+	var host = network.NewW3GSConn(c2, nil, enc)
+	defer host.Close()
+
+	var p = dummy.Player{
+		Host: peer.Host{
+			PlayerInfo: w3gs.PlayerInfo{PlayerID: 2, PlayerName: "recorder"},
+			Encoding:   enc,
+		},
+	}
+	p.InitDefaultHandlers()
+	p.SetConn(c1, w3gs.NewFactoryCache(w3gs.DefaultFactory), enc)
+	defer p.Close()
+
+	var rec = dummy.NewRecorder(&p, &w3g.GameInfo{
+		GameName:     "gowarcraft3",
+		GameSettings: w3gs.GameSettings{MapPath: "Maps\\(2)EchoIsles.w3x", HostName: "host"},
+	})
+	defer rec.Close()
+
+	go p.Run()
+
+	var slots = w3gs.SlotInfo{
+		Slots: []w3gs.SlotData{
+			w3gs.SlotData{PlayerID: 1, DownloadStatus: 100, SlotStatus: w3gs.SlotOccupied, Team: 0, Color: 0, Race: w3gs.RaceOrc, ComputerType: w3gs.ComputerNormal, Handicap: 100},
+			w3gs.SlotData{PlayerID: 2, DownloadStatus: 100, SlotStatus: w3gs.SlotOccupied, Team: 24, Color: 24, Race: w3gs.RaceRandom, ComputerType: w3gs.ComputerNormal, Handicap: 100},
+		},
+		RandomSeed: 1234,
+		SlotLayout: w3gs.LayoutMelee,
+		NumPlayers: 1,
+	}
+
+	var pkts = []w3gs.Packet{
+		&w3gs.PlayerInfo{PlayerID: 1, PlayerName: "player"},
+		&w3gs.PlayerInfo{PlayerID: 3, PlayerName: "leaver"},
+		&w3gs.PlayerLeft{PlayerID: 3, Reason: w3gs.LeaveLobby},
+		&slots,
+		&w3gs.CountDownStart{},
+		&w3gs.CountDownEnd{},
+		&w3gs.TimeSlot{TimeIncrementMS: 100, Actions: []w3gs.PlayerAction{w3gs.PlayerAction{PlayerID: 1, Data: []byte{0x11, 0x22}}}},
+		&w3gs.MessageRelay{Message: w3gs.Message{RecipientIDs: []uint8{2}, SenderID: 1, Type: w3gs.MsgChatExtra, Scope: w3gs.ScopeAll, Content: "gg"}},
+		&w3gs.TimeSlot{TimeIncrementMS: 150},
+		&w3gs.PlayerLeft{PlayerID: 1, Reason: w3gs.LeaveLost},
+		&w3gs.Ping{Payload: 42},
+	}
+
+	go func() {
+		for _, pkt := range pkts {
+			if _, err := host.Send(pkt); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		pkt, err := host.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := pkt.(*w3gs.Pong); ok {
+			break
+		}
+	}
+
+	var b protocol.Buffer
+	if err := rec.Encode(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := w3g.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replay.DurationMS != 250 || replay.GameName != "gowarcraft3" || replay.HostPlayer.Name != "recorder" {
+		t.Fatal("Header mismatch", replay.Header, replay.GameInfo)
+	}
+	if len(replay.PlayerInfo) != 2 || replay.PlayerInfo[1].Name != "player" || replay.PlayerInfo[1].Race != w3gs.RaceOrc {
+		t.Fatal("PlayerInfo mismatch", replay.PlayerInfo)
+	}
+	if !reflect.DeepEqual(replay.SlotInfo.SlotInfo, slots) {
+		t.Fatal("SlotInfo mismatch", replay.SlotInfo)
+	}
+
+	var types []string
+	for _, r := range replay.Records {
+		types = append(types, fmt.Sprintf("%T", r))
+	}
+	var expected = []string{"*w3g.TimeSlot", "*w3g.ChatMessage", "*w3g.TimeSlot", "*w3g.PlayerLeft", "*w3g.PlayerLeft"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatal("Records mismatch", types)
+	}
+	if ts := replay.Records[0].(*w3g.TimeSlot); len(ts.Actions) != 1 || !bytes.Equal(ts.Actions[0].Data, []byte{0x11, 0x22}) {
+		t.Fatal("TimeSlot mismatch", ts)
+	}
+	if msg := replay.Records[1].(*w3g.ChatMessage); msg.Content != "gg" || msg.SenderID != 1 {
+		t.Fatal("ChatMessage mismatch", msg)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	var enc = w3gs.Encoding{GameVersion: 29}
+	var slots = w3gs.SlotInfo{
+		SlotLayout: w3gs.LayoutMelee,
+		NumPlayers: 2,
+		Slots: []w3gs.SlotData{
+			w3gs.SlotData{SlotStatus: w3gs.SlotOpen, Race: w3gs.RaceRandom | w3gs.RaceSelectable, Handicap: 100},
+			w3gs.SlotData{SlotStatus: w3gs.SlotClosed, Race: w3gs.RaceRandom | w3gs.RaceSelectable, Handicap: 100},
+		},
+	}
+
+	var g = lobby.NewGame(enc, slots, w3gs.MapCheck{})
+	g.SyncLimit = 4
+
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StageDone {
+			close(done)
+		}
+	})
+	g.On(&lobby.PlayerJoined{}, func(ev *network.Event) {
+		ev.Arg.(*lobby.PlayerJoined).PingInterval = 5 * time.Millisecond
+	})
+	defer func() {
+		g.Close()
+		<-done
+	}()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go g.Serve(ln)
+
+	var p = dummy.Player{
+		Host: peer.Host{
+			PlayerInfo: w3gs.PlayerInfo{PlayerName: "DUMMY"},
+			Encoding:   enc,
+		},
+		HostAddr:     ln.Addr().String(),
+		AckTimeSlots: true,
+		LoadTime:     20 * time.Millisecond,
+		Checksum:     func(ticks uint32) uint32 { return ticks },
+	}
+	p.InitDefaultHandlers()
+
+	var loaded = make(chan struct{})
+	p.Once(&dummy.Loaded{}, func(ev *network.Event) {
+		close(loaded)
+	})
+
+	if err := p.Join(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	go p.Run()
+
+	for {
+		err := g.Start()
+		if err == nil {
+			break
+		}
+		if err != lobby.ErrNotReady {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case <-loaded:
+	case <-time.After(time.Second):
+		t.Fatal("Dummy not loaded")
+	}
+
+	for p.GameTicks() < 250 {
+		time.Sleep(10 * time.Millisecond)
+		if g.Stage() != lobby.StagePlaying || len(g.Laggers()) != 0 {
+			t.Fatal("Expected dummy to keep up with the game", g.Stage())
+		}
+	}
+	if !p.Loaded() || g.CountPlayers() != 1 {
+		t.Fatal("Expected dummy to stay in game")
+	}
+}
+
+func TestMapCheck(t *testing.T) {
+	var m = dummy.MapFile{Size: 1234, CRC: 0xDEADBEEF, Hash: w3m.Hash{Xoro: 0xC0FFEE, Sha1: [20]byte{1, 2, 3}}}
+
+	var check = m.MapCheck("Maps\\test.w3x")
+	if !m.Matches(check) || !m.Matches(&w3gs.MapCheck{FileSize: m.Size, FileCRC: m.CRC}) {
+		t.Fatal("Expected map to match")
+	}
+	if m.Matches(&w3gs.MapCheck{FileSize: m.Size, FileCRC: m.CRC + 1}) || m.Matches(&w3gs.MapCheck{FileSize: m.Size, FileCRC: m.CRC, MapXoro: 1}) {
+		t.Fatal("Expected map mismatch")
+	}
+
+	var enc = w3gs.Encoding{GameVersion: 29}
+	var slots = w3gs.SlotInfo{
+		SlotLayout: w3gs.LayoutMelee,
+		NumPlayers: 2,
+		Slots: []w3gs.SlotData{
+			w3gs.SlotData{SlotStatus: w3gs.SlotOpen, Race: w3gs.RaceRandom | w3gs.RaceSelectable, Handicap: 100},
+			w3gs.SlotData{SlotStatus: w3gs.SlotOpen, Race: w3gs.RaceRandom | w3gs.RaceSelectable, Handicap: 100},
+		},
+	}
+
+	var g = lobby.NewGame(enc, slots, *check)
+	g.On(&lobby.PlayerJoined{}, func(ev *network.Event) {
+		ev.Arg.(*lobby.PlayerJoined).PingInterval = 5 * time.Millisecond
+	})
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go g.Serve(ln)
+
+	var join = func(name string, m *dummy.MapFile) *dummy.Player {
+		var p = dummy.Player{
+			Host: peer.Host{
+				PlayerInfo: w3gs.PlayerInfo{PlayerName: name},
+				Encoding:   enc,
+			},
+			HostAddr: ln.Addr().String(),
+			Map:      m,
+		}
+		p.InitDefaultHandlers()
+
+		if err := p.Join(); err != nil {
+			t.Fatal(err)
+		}
+		return &p
+	}
+
+	var ok = join("OK", &m)
+	defer ok.Close()
+	go ok.Run()
+
+	var other = m
+	other.CRC++
+
+	var bad = join("BAD", &other)
+	defer bad.Close()
+
+	var mismatch = make(chan struct{})
+	bad.On(&network.AsyncError{}, func(ev *network.Event) {
+		if ev.Arg.(*network.AsyncError).Err == dummy.ErrMapMismatch {
+			close(mismatch)
+		}
+	})
+
+	var ran = make(chan struct{})
+	go func() {
+		bad.Run()
+		close(ran)
+	}()
+
+	select {
+	case <-mismatch:
+	case <-time.After(time.Second):
+		t.Fatal("Expected map mismatch")
+	}
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Expected dummy with mismatching map to be kicked")
+	}
+
+	for i := 0; ; i++ {
+		err := g.Start()
+		if err == nil {
+			break
+		}
+		if err != lobby.ErrNotReady || i > 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if g.CountPlayers() != 1 {
+		t.Fatal("Expected dummy with matching map to stay")
+	}
+}
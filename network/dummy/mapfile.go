@@ -0,0 +1,66 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package dummy
+
+import (
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/file/w3m"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// MapFile contains the properties of a local map file that are verified by MapCheck
+type MapFile struct {
+	Size uint32
+	CRC  uint32
+	w3m.Hash
+}
+
+// OpenMapFile computes the MapCheck properties of the map file at path
+// stor is used to find files not embedded in the map (i.e. common.j and blizzard.j), can be nil
+func OpenMapFile(path string, stor *fs.Storage) (*MapFile, error) {
+	file, err := w3m.HashFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := w3m.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	hash, err := m.Checksum(stor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MapFile{
+		Size: file.Size,
+		CRC:  file.CRC,
+		Hash: *hash,
+	}, nil
+}
+
+// MapCheck returns the MapCheck packet a host would send for this map
+func (m *MapFile) MapCheck(filePath string) *w3gs.MapCheck {
+	return &w3gs.MapCheck{
+		FilePath: filePath,
+		FileSize: m.Size,
+		FileCRC:  m.CRC,
+		MapXoro:  m.Xoro,
+		MapSha1:  m.Sha1,
+	}
+}
+
+// Matches checks if pkt refers to this map, the content hash is only compared if set by host
+func (m *MapFile) Matches(pkt *w3gs.MapCheck) bool {
+	if pkt.FileSize != m.Size || pkt.FileCRC != m.CRC {
+		return false
+	}
+	if pkt.MapXoro == 0 && pkt.MapSha1 == [20]byte{} {
+		return true
+	}
+	return pkt.MapXoro == m.Xoro && pkt.MapSha1 == m.Sha1
+}
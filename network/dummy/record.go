@@ -0,0 +1,241 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package dummy
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Recorder records the game a Player participates in as a w3g replay
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Recorder struct {
+	p   *Player
+	eid []network.EventID
+
+	mut     sync.Mutex
+	gi      w3g.GameInfo
+	hdr     w3g.Header
+	players []*w3gs.PlayerInfo
+	slots   w3gs.SlotInfo
+	extra   []*w3g.PlayerExtra
+	records []w3g.Record
+	started bool
+}
+
+// NewRecorder starts recording the game of p, bind before joining to capture the lobby state
+// info provides the game name and settings, which are not known to players
+func NewRecorder(p *Player, info *w3g.GameInfo) *Recorder {
+	var version = p.Encoding.GameVersion
+	if version == 0 {
+		version = w3gs.CurrentGameVersion
+	}
+
+	var r = Recorder{
+		p:  p,
+		gi: *info,
+		hdr: w3g.Header{
+			GameVersion: w3gs.GameVersion{Product: w3gs.ProductTFT, Version: version},
+		},
+	}
+
+	r.eid = []network.EventID{
+		p.On(&w3gs.SlotInfoJoin{}, r.onSlotInfoJoin),
+		p.On(&w3gs.SlotInfo{}, r.onSlotInfo),
+		p.On(&w3gs.PlayerInfo{}, r.onPlayerInfo),
+		p.On(&w3gs.PlayerExtra{}, r.onPlayerExtra),
+		p.On(&w3gs.PlayerLeft{}, r.onPlayerLeft),
+		p.On(&w3gs.CountDownEnd{}, r.onCountDownEnd),
+		p.On(&w3gs.TimeSlot{}, r.onTimeSlot),
+		p.On(&w3gs.MessageRelay{}, r.onMessageRelay),
+	}
+
+	return &r
+}
+
+// Close stops recording
+func (r *Recorder) Close() {
+	for _, id := range r.eid {
+		r.p.Off(id)
+	}
+}
+
+// Replay returns the recorded game, with the recording player as host
+func (r *Recorder) Replay() *w3g.Replay {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	var res = w3g.Replay{
+		Header:   r.hdr,
+		GameInfo: r.gi,
+		SlotInfo: w3g.SlotInfo{SlotInfo: r.slots},
+	}
+
+	res.SlotInfo.Slots = append([]w3gs.SlotData(nil), r.slots.Slots...)
+	res.GameInfo.NumSlots = uint32(len(r.slots.Slots))
+	res.GameInfo.HostPlayer = r.playerInfo(&r.p.PlayerInfo)
+	res.PlayerInfo = []*w3g.PlayerInfo{&res.GameInfo.HostPlayer}
+	for _, pi := range r.players {
+		var info = r.playerInfo(pi)
+		res.PlayerInfo = append(res.PlayerInfo, &info)
+	}
+	res.PlayerExtra = append([]*w3g.PlayerExtra(nil), r.extra...)
+	res.Records = append([]w3g.Record(nil), r.records...)
+	res.Records = append(res.Records, &w3g.PlayerLeft{
+		Local:    true,
+		PlayerID: r.p.PlayerInfo.PlayerID,
+		Reason:   w3gs.LeaveDisconnect,
+		Counter:  1,
+	})
+
+	return &res
+}
+
+// Encode recorded game to w
+func (r *Recorder) Encode(w io.Writer) error {
+	return r.Replay().Encode(w)
+}
+
+// Save recorded game to a w3g file
+func (r *Recorder) Save(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.Encode(f)
+}
+
+// mut should be locked
+func (r *Recorder) playerInfo(pi *w3gs.PlayerInfo) w3g.PlayerInfo {
+	var res = w3g.PlayerInfo{
+		ID:          pi.PlayerID,
+		Name:        pi.PlayerName,
+		JoinCounter: pi.JoinCounter,
+	}
+	for _, s := range r.slots.Slots {
+		if s.SlotStatus == w3gs.SlotOccupied && s.PlayerID == pi.PlayerID {
+			res.Race = s.Race
+		}
+	}
+	return res
+}
+
+func (r *Recorder) onSlotInfoJoin(ev *network.Event) {
+	var pkt = ev.Arg.(*w3gs.SlotInfoJoin)
+	r.setSlots(&pkt.SlotInfo)
+}
+
+func (r *Recorder) onSlotInfo(ev *network.Event) {
+	r.setSlots(ev.Arg.(*w3gs.SlotInfo))
+}
+
+func (r *Recorder) setSlots(s *w3gs.SlotInfo) {
+	r.mut.Lock()
+	if !r.started {
+		r.slots = *s
+		r.slots.Slots = append([]w3gs.SlotData(nil), s.Slots...)
+	}
+	r.mut.Unlock()
+}
+
+func (r *Recorder) onPlayerInfo(ev *network.Event) {
+	var pkt = *ev.Arg.(*w3gs.PlayerInfo)
+
+	r.mut.Lock()
+	if !r.started {
+		r.players = append(r.players, &pkt)
+	}
+	r.mut.Unlock()
+}
+
+func (r *Recorder) onPlayerExtra(ev *network.Event) {
+	var pkt = ev.Arg.(*w3gs.PlayerExtra)
+	var rec = w3g.PlayerExtra{PlayerExtra: w3gs.PlayerExtra{
+		Type:     pkt.Type,
+		Profiles: append([]w3gs.PlayerDataProfile(nil), pkt.Profiles...),
+		Skins:    append([]w3gs.PlayerDataSkins(nil), pkt.Skins...),
+		Unknown5: append([]w3gs.PlayerData5(nil), pkt.Unknown5...),
+	}}
+
+	r.mut.Lock()
+	r.extra = append(r.extra, &rec)
+	r.mut.Unlock()
+}
+
+func (r *Recorder) onPlayerLeft(ev *network.Event) {
+	var pkt = ev.Arg.(*w3gs.PlayerLeft)
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.started {
+		r.records = append(r.records, &w3g.PlayerLeft{
+			PlayerID: pkt.PlayerID,
+			Reason:   pkt.Reason,
+			Counter:  1,
+		})
+		return
+	}
+
+	for i, pi := range r.players {
+		if pi.PlayerID == pkt.PlayerID {
+			r.players = append(r.players[:i], r.players[i+1:]...)
+			break
+		}
+	}
+}
+
+func (r *Recorder) onCountDownEnd(ev *network.Event) {
+	r.mut.Lock()
+	r.started = true
+	r.mut.Unlock()
+}
+
+func (r *Recorder) onTimeSlot(ev *network.Event) {
+	var pkt = ev.Arg.(*w3gs.TimeSlot)
+
+	// Packets are reused after the event, copy action data
+	var rec = w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{
+		Fragment:        pkt.Fragment,
+		TimeIncrementMS: pkt.TimeIncrementMS,
+		Actions:         make([]w3gs.PlayerAction, len(pkt.Actions)),
+	}}
+	for i, a := range pkt.Actions {
+		rec.Actions[i] = w3gs.PlayerAction{
+			PlayerID: a.PlayerID,
+			Data:     append([]byte(nil), a.Data...),
+		}
+	}
+
+	r.mut.Lock()
+	if r.started {
+		r.records = append(r.records, &rec)
+		r.hdr.DurationMS += uint32(pkt.TimeIncrementMS)
+	}
+	r.mut.Unlock()
+}
+
+func (r *Recorder) onMessageRelay(ev *network.Event) {
+	var pkt = ev.Arg.(*w3gs.MessageRelay)
+	if pkt.Type != w3gs.MsgChatExtra {
+		return
+	}
+
+	var rec = w3g.ChatMessage{Message: pkt.Message}
+	rec.RecipientIDs = nil
+
+	r.mut.Lock()
+	if r.started {
+		r.records = append(r.records, &rec)
+	}
+	r.mut.Unlock()
+}
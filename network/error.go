@@ -5,12 +5,21 @@
 package network
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net"
 	"os"
 	"syscall"
 
 	"github.com/gorilla/websocket"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Errors
+var (
+	ErrQueueFull    = protocol.NewError(protocol.KindRateLimited, "network: Send queue full")
+	ErrInvalidTrace = errors.New("network: Invalid trace file")
 )
 
 // AsyncError keeps track of where a non-fatal asynchronous error orignated
@@ -26,6 +35,11 @@ func (e *AsyncError) Error() string {
 	return e.Src + ":" + e.Err.Error()
 }
 
+// Unwrap returns the underlying error
+func (e *AsyncError) Unwrap() error {
+	return e.Err
+}
+
 // Temporary error
 func (e *AsyncError) Temporary() bool {
 	return IsTemporary(e.Err)
@@ -49,6 +63,8 @@ func UnnestError(err error) error {
 		return UnnestError(e.Err)
 	case *os.LinkError:
 		return UnnestError(e.Err)
+	case *protocol.KindError:
+		return UnnestError(e.Err)
 	default:
 		return err
 	}
@@ -181,3 +197,21 @@ func IsTimeout(err error) bool {
 	}
 	return ok && t.Timeout()
 }
+
+// ErrorKind classifies err (i.e. protocol.KindTimeout), returns an empty kind if unknown
+// Timeouts are recognized for all errors that satisfy IsTimeout()
+func ErrorKind(err error) protocol.ErrorKind {
+	if err == nil {
+		return ""
+	}
+
+	var k *protocol.KindError
+	if errors.As(err, &k) {
+		return k.Kind
+	}
+	if IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return protocol.KindTimeout
+	}
+
+	return ""
+}
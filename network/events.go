@@ -6,7 +6,9 @@ package network
 
 import (
 	"math/bits"
+	"path"
 	"reflect"
+	"sort"
 	"sync"
 	"sync/atomic"
 )
@@ -18,6 +20,7 @@ type EventArg interface{}
 type EventID struct {
 	ht string
 	id uint32
+	wc bool
 }
 
 // Event structure passed to event handlers
@@ -39,18 +42,23 @@ type EventHandler func(*Event)
 type eventHandler = struct {
 	id   uint32
 	once bool
+	prio int
 	fun  EventHandler
 }
 
 // EventEmitter is an event emitter based on argument types
-// For every type, a listener can register callbacks. Callbacks will be fired in reverse order of registration.
+// For every type, a listener can register callbacks. Callbacks will be fired in order of priority (highest first),
+// then in reverse order of registration. Catch-all handlers go before wildcard handlers, which go before regular handlers.
 // The structure is thread-safe and functions can be called from multiple goroutines at the same time.
 type EventEmitter struct {
-	id       uint32
-	hanmutex sync.RWMutex
-	handlers map[string][]eventHandler
-	emask    uint32
-	epool    [16]Event
+	id        uint32
+	hanmutex  sync.RWMutex
+	handlers  map[string][]eventHandler
+	wildcards map[string][]eventHandler
+	nprio     int
+	emask     uint32
+	epool     [16]Event
+	disp      atomic.Value
 }
 
 // Emitter is the interface that wraps the basic Fire method
@@ -73,6 +81,14 @@ func Topic(s string) interface{} {
 	return topicLiteral(s)
 }
 
+type wildcardLiteral string
+
+// Wildcard topic pattern (path.Match syntax) that is matched against the topic of fired events
+// Topics of typed events are their type name, e.g. Wildcard("[*]w3gs.*") matches all W3GS packets
+func Wildcard(pattern string) interface{} {
+	return wildcardLiteral(pattern)
+}
+
 func topic(a EventArg) string {
 	if a == nil {
 		return "*"
@@ -82,62 +98,162 @@ func topic(a EventArg) string {
 	return reflect.TypeOf(a).String()
 }
 
-func (e *EventEmitter) addHandler(a EventArg, h EventHandler, once bool) EventID {
-	var ht = topic(a)
+// Insert h in arr (copy on write), keeping arr sorted by priority
+func insertHandler(arr []eventHandler, h eventHandler) []eventHandler {
+	var i = 0
+	for i < len(arr) && arr[i].prio > h.prio {
+		i++
+	}
+
+	var res = make([]eventHandler, 0, len(arr)+1)
+	res = append(res, arr[:i]...)
+	res = append(res, h)
+	return append(res, arr[i:]...)
+}
+
+func (e *EventEmitter) addHandler(a EventArg, h EventHandler, once bool, prio int) EventID {
+	var wp, wc = a.(wildcardLiteral)
+	var ht = string(wp)
+	if !wc {
+		ht = topic(a)
+	}
 
 	e.hanmutex.Lock()
 	e.id++
 	var id = e.id
-
-	if e.handlers == nil {
-		e.handlers = make(map[string][]eventHandler)
-	}
-	e.handlers[ht] = append([]eventHandler{eventHandler{
+	var eh = eventHandler{
 		id:   id,
 		once: once,
+		prio: prio,
 		fun:  h,
-	}}, e.handlers[ht]...)
+	}
+
+	if prio != 0 {
+		e.nprio++
+	}
+
+	if wc {
+		if e.wildcards == nil {
+			e.wildcards = make(map[string][]eventHandler)
+		}
+		e.wildcards[ht] = insertHandler(e.wildcards[ht], eh)
+	} else {
+		if e.handlers == nil {
+			e.handlers = make(map[string][]eventHandler)
+		}
+		e.handlers[ht] = insertHandler(e.handlers[ht], eh)
+	}
 	e.hanmutex.Unlock()
 
 	return EventID{
 		ht: ht,
 		id: id,
+		wc: wc,
 	}
 }
 
 // On an event of type a is, call handler h
 func (e *EventEmitter) On(a EventArg, h EventHandler) EventID {
-	return e.addHandler(a, h, false)
+	return e.addHandler(a, h, false, 0)
 }
 
 // Once an event of type a is fired, call handler h once
 func (e *EventEmitter) Once(a EventArg, h EventHandler) EventID {
-	return e.addHandler(a, h, true)
+	return e.addHandler(a, h, true, 0)
+}
+
+// OnPriority is like On, but handlers with a higher priority are called first (default priority is 0)
+func (e *EventEmitter) OnPriority(a EventArg, prio int, h EventHandler) EventID {
+	return e.addHandler(a, h, false, prio)
+}
+
+// OncePriority is like Once, but handlers with a higher priority are called first (default priority is 0)
+func (e *EventEmitter) OncePriority(a EventArg, prio int, h EventHandler) EventID {
+	return e.addHandler(a, h, true, prio)
+}
+
+// SetDispatcher runs handlers registered with OnAsync on d, nil runs them synchronously
+func (e *EventEmitter) SetDispatcher(d *Dispatcher) {
+	e.disp.Store(d)
+}
+
+// OnAsync is like On, but h is called on the worker pool set with SetDispatcher so it can't stall Fire()
+// PreventNext has no effect in h. Packet events are always handled synchronously, as the
+// decoded packet is reused after Fire() returns. A DispatchDropped event is fired if the queue is full.
+func (e *EventEmitter) OnAsync(a EventArg, h EventHandler) EventID {
+	return e.addHandler(a, func(ev *Event) { e.dispatch(ev, h) }, false, 0)
+}
+
+func (e *EventEmitter) dispatch(ev *Event, h EventHandler) {
+	var d, _ = e.disp.Load().(*Dispatcher)
+	if d == nil || isPacket(ev.Arg) {
+		h(&Event{Arg: ev.Arg, Opt: ev.Opt})
+		return
+	}
+
+	// Event is returned to the pool after Fire(), copy it
+	var cpy = &Event{Arg: ev.Arg, Opt: append([]EventArg(nil), ev.Opt...)}
+	var ht = topic(ev.Arg)
+
+	switch d.dispatch(ht, func() { h(cpy) }) {
+	case dispatchClosed:
+		h(cpy)
+	case dispatchDropped:
+		if _, ok := ev.Arg.(*DispatchDropped); !ok {
+			e.Fire(&DispatchDropped{Topic: ht, Arg: ev.Arg})
+		}
+	}
+}
+
+// Make sure hanmutex is locked before calling
+func (e *EventEmitter) handlerMap(wc bool) map[string][]eventHandler {
+	if wc {
+		return e.wildcards
+	}
+	return e.handlers
 }
 
 // Off stops id from listening to future events
 func (e *EventEmitter) Off(id EventID) {
 	e.hanmutex.Lock()
+	var m = e.handlerMap(id.wc)
+
 	var end = 0
-	var arr = append([]eventHandler(nil), e.handlers[id.ht]...)
+	var arr = append([]eventHandler(nil), m[id.ht]...)
 
 	for i := 0; i < len(arr); i++ {
 		if arr[i].id != id.id {
 			arr[end] = arr[i]
 			end++
+		} else if arr[i].prio != 0 {
+			e.nprio--
 		}
 	}
 
-	e.handlers[id.ht] = arr[:end]
+	if m != nil {
+		m[id.ht] = arr[:end]
+	}
 	e.hanmutex.Unlock()
 }
 
 // OffAll clears the current listeners for events of type a
 func (e *EventEmitter) OffAll(a EventArg) {
-	var ht = topic(a)
+	var wp, wc = a.(wildcardLiteral)
+	var ht = string(wp)
+	if !wc {
+		ht = topic(a)
+	}
 
 	e.hanmutex.Lock()
-	e.handlers[ht] = e.handlers[ht][:0]
+	var m = e.handlerMap(wc)
+	if m != nil {
+		for _, h := range m[ht] {
+			if h.prio != 0 {
+				e.nprio--
+			}
+		}
+		m[ht] = m[ht][:0]
+	}
 	e.hanmutex.Unlock()
 }
 
@@ -150,6 +266,8 @@ func (e *EventEmitter) offOnce(ht string, minID uint32, maxID uint32) {
 		if arr[i].id > maxID || arr[i].id < minID || !arr[i].once {
 			arr[end] = arr[i]
 			end++
+		} else if arr[i].prio != 0 {
+			e.nprio--
 		}
 	}
 
@@ -218,6 +336,75 @@ func (e *EventEmitter) fire(ht string, arr []eventHandler, ev *Event) bool {
 // CatchAll
 var ca = topic(nil)
 
+type topicHandler struct {
+	eventHandler
+	ht string
+	wc bool
+}
+
+func appendHandlers(dst []topicHandler, arr []eventHandler, ht string, wc bool) []topicHandler {
+	for _, h := range arr {
+		dst = append(dst, topicHandler{eventHandler: h, ht: ht, wc: wc})
+	}
+	return dst
+}
+
+// Make sure hanmutex is read-locked before calling
+func (e *EventEmitter) matchWildcards(ht string) []topicHandler {
+	var res []topicHandler
+	for p, arr := range e.wildcards {
+		if len(arr) == 0 {
+			continue
+		}
+		if ok, _ := path.Match(p, ht); ok {
+			res = appendHandlers(res, arr, p, true)
+		}
+	}
+	return res
+}
+
+// Slow path for events with priority or wildcard handlers
+func (e *EventEmitter) fireMerged(ht string, arr1 []eventHandler, arrw []topicHandler, arr2 []eventHandler, ev *Event) bool {
+	// Patterns are visited in random order, restore registration order
+	sort.Slice(arrw, func(i, j int) bool {
+		return arrw[i].id > arrw[j].id
+	})
+
+	var all = make([]topicHandler, 0, len(arr1)+len(arrw)+len(arr2))
+	all = appendHandlers(all, arr1, ca, false)
+	all = append(all, arrw...)
+	if ht != ca {
+		all = appendHandlers(all, arr2, ht, false)
+	}
+
+	// Stable sort retains (catch-all, wildcard, regular) order for equal priorities
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].prio > all[j].prio
+	})
+
+	var prevent bool
+	var once []EventID
+
+	for i := 0; i < len(all); i++ {
+		var eh = &all[i]
+		eh.fun(ev)
+
+		if eh.once {
+			once = append(once, EventID{ht: eh.ht, id: eh.id, wc: eh.wc})
+		}
+		if ev.preventNext {
+			prevent = true
+			break
+		}
+	}
+
+	for _, id := range once {
+		e.Off(id)
+	}
+
+	return prevent
+}
+
 // Fire new event of type a
 func (e *EventEmitter) Fire(a EventArg, o ...EventArg) bool {
 	var ht = topic(a)
@@ -225,9 +412,11 @@ func (e *EventEmitter) Fire(a EventArg, o ...EventArg) bool {
 	e.hanmutex.RLock()
 	var arr1 = e.handlers[ca][:]
 	var arr2 = e.handlers[ht][:]
+	var arrw = e.matchWildcards(ht)
+	var prio = e.nprio > 0
 	e.hanmutex.RUnlock()
 
-	if len(arr1) == 0 && len(arr2) == 0 {
+	if len(arr1) == 0 && len(arr2) == 0 && len(arrw) == 0 {
 		return false
 	}
 
@@ -235,9 +424,14 @@ func (e *EventEmitter) Fire(a EventArg, o ...EventArg) bool {
 	ev.Arg = a
 	ev.Opt = o
 
-	var prevent = e.fire(ca, arr1, ev)
-	if !prevent && ht != ca {
-		prevent = e.fire(ht, arr2, ev)
+	var prevent bool
+	if prio || len(arrw) > 0 {
+		prevent = e.fireMerged(ht, arr1, arrw, arr2, ev)
+	} else {
+		prevent = e.fire(ca, arr1, ev)
+		if !prevent && ht != ca {
+			prevent = e.fire(ht, arr2, ev)
+		}
 	}
 
 	e.freeEvent(eid)
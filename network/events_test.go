@@ -192,6 +192,63 @@ func TestCatchAll(t *testing.T) {
 	}
 }
 
+func TestPriority(t *testing.T) {
+	var e network.EventEmitter
+	var order string
+
+	e.On("", func(ev *network.Event) { order += "a" })
+	var b = e.OnPriority("", 10, func(ev *network.Event) { order += "b" })
+	e.OnPriority("", -10, func(ev *network.Event) { order += "c" })
+	e.On(nil, func(ev *network.Event) { order += "d" })
+	e.OncePriority("", 20, func(ev *network.Event) { order += "e" })
+
+	e.Fire("Foo")
+	if order != "ebdac" {
+		t.Fatal("Unexpected order", order)
+	}
+
+	order = ""
+	e.Fire("Bar")
+	if order != "bdac" {
+		t.Fatal("Unexpected order after Once", order)
+	}
+
+	// Back to regular order once all priority handlers are removed
+	e.Off(b)
+	e.OffAll("")
+	e.On("", func(ev *network.Event) { order += "f" })
+	e.On("", func(ev *network.Event) { order += "g" })
+
+	order = ""
+	e.Fire("Baz")
+	if order != "dgf" {
+		t.Fatal("Unexpected order after Off", order)
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	var e network.EventEmitter
+	var fired int
+
+	var id = e.On(network.Wildcard("foo.*"), func(ev *network.Event) { fired++ })
+	e.Once(network.Wildcard("[*]network.*"), func(ev *network.Event) { fired += 10 })
+
+	e.Fire(network.Topic("foo.bar"))
+	e.Fire(network.Topic("bar.foo"))
+	e.Fire(&network.RunStart{})
+	e.Fire(&network.RunStop{})
+
+	if fired != 11 {
+		t.Fatal("Expected 11, got", fired)
+	}
+
+	e.Off(id)
+	e.Fire(network.Topic("foo.bar"))
+	if fired != 11 {
+		t.Fatal("Wildcard fired after Off")
+	}
+}
+
 func TestGoroutines(t *testing.T) {
 	var c uint32
 
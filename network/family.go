@@ -0,0 +1,166 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"net"
+)
+
+// IPFamily selects the IP version(s) used by listeners and dialers
+type IPFamily int
+
+// IP families
+const (
+	FamilyDefault IPFamily = iota // Component default (IPv4 for LAN sockets, dual-stack for dialing)
+	FamilyIPv4                    // IPv4 only
+	FamilyIPv6                    // IPv6 only
+	FamilyDual                    // IPv4 and IPv6
+)
+
+func (f IPFamily) String() string {
+	switch f {
+	case FamilyDefault:
+		return "Default"
+	case FamilyIPv4:
+		return "IPv4"
+	case FamilyIPv6:
+		return "IPv6"
+	case FamilyDual:
+		return "Dual"
+	default:
+		return "IPFamily(?)"
+	}
+}
+
+// Or returns def if f is FamilyDefault, f otherwise
+func (f IPFamily) Or(def IPFamily) IPFamily {
+	if f == FamilyDefault {
+		return def
+	}
+	return f
+}
+
+// Network returns the network name for proto ("tcp", "udp", or "ip") restricted to f
+func (f IPFamily) Network(proto string) string {
+	switch f {
+	case FamilyIPv4:
+		return proto + "4"
+	case FamilyIPv6:
+		return proto + "6"
+	default:
+		return proto
+	}
+}
+
+// Matches reports whether ip can be reached with f
+func (f IPFamily) Matches(ip net.IP) bool {
+	switch f {
+	case FamilyIPv4:
+		return ip.To4() != nil
+	case FamilyIPv6:
+		return ip.To4() == nil && ip.To16() != nil
+	default:
+		return ip.To16() != nil
+	}
+}
+
+// W3GSBroadcastAddr6 is used in place of W3GSBroadcastAddr to reach all IPv6 nodes in LAN (link-local multicast)
+var W3GSBroadcastAddr6 = net.UDPAddr{IP: net.IPv6linklocalallnodes, Port: 6112}
+
+// W3GSBroadcastAddrs returns the broadcast endpoints for f (FamilyDefault is treated as FamilyIPv4)
+func W3GSBroadcastAddrs(f IPFamily) []net.Addr {
+	switch f {
+	case FamilyIPv6:
+		return []net.Addr{&W3GSBroadcastAddr6}
+	case FamilyDual:
+		return []net.Addr{&W3GSBroadcastAddr, &W3GSBroadcastAddr6}
+	default:
+		return []net.Addr{&W3GSBroadcastAddr}
+	}
+}
+
+// W3GSBroadcastAddrsInterface is like W3GSBroadcastAddrs, but restricted to ifi (nil for the default interface)
+// IPv4 uses the directed broadcast address of every subnet of ifi, IPv6 endpoints are scoped to ifi
+func W3GSBroadcastAddrsInterface(f IPFamily, ifi *net.Interface) []net.Addr {
+	var def = W3GSBroadcastAddrs(f)
+	if ifi == nil {
+		return def
+	}
+
+	var res = make([]net.Addr, 0, len(def))
+	for _, a := range def {
+		var u = a.(*net.UDPAddr)
+		if u.IP.To4() == nil {
+			res = append(res, &net.UDPAddr{IP: u.IP, Port: u.Port, Zone: ifi.Name})
+			continue
+		}
+
+		var bcast = InterfaceBroadcastIPs(ifi)
+		if len(bcast) == 0 {
+			res = append(res, u)
+		}
+		for _, ip := range bcast {
+			res = append(res, &net.UDPAddr{IP: ip, Port: u.Port})
+		}
+	}
+	return res
+}
+
+// InterfaceBroadcastIPs returns the directed IPv4 broadcast address of every subnet of ifi
+func InterfaceBroadcastIPs(ifi *net.Interface) []net.IP {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil
+	}
+
+	var res []net.IP
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			if ip := BroadcastIP(ipnet); ip != nil {
+				res = append(res, ip)
+			}
+		}
+	}
+	return res
+}
+
+// BroadcastIP returns the directed broadcast address of IPv4 subnet n, nil for IPv6 subnets
+func BroadcastIP(n *net.IPNet) net.IP {
+	var ip4 = n.IP.To4()
+	var mask = n.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	if ip4 == nil || len(mask) != net.IPv4len {
+		return nil
+	}
+
+	var res = make(net.IP, net.IPv4len)
+	for i, b := range ip4 {
+		res[i] = b | ^mask[i]
+	}
+	return res
+}
+
+// ListenUDP opens a UDP socket on port for f (FamilyDefault is treated as FamilyIPv4)
+func ListenUDP(f IPFamily, port int) (*net.UDPConn, error) {
+	return net.ListenUDP(f.Or(FamilyIPv4).Network("udp"), &net.UDPAddr{Port: port})
+}
+
+// ListenTCP opens a TCP listener on addr for f (FamilyDefault is treated as FamilyIPv4)
+func ListenTCP(f IPFamily, addr *net.TCPAddr) (*net.TCPListener, error) {
+	return net.ListenTCP(f.Or(FamilyIPv4).Network("tcp"), addr)
+}
+
+// DialTCP connects to addr over f (FamilyDefault is treated as FamilyDual), aborts once ctx is done
+func DialTCP(ctx context.Context, f IPFamily, addr string) (*net.TCPConn, error) {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, f.Network("tcp"), addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*net.TCPConn), nil
+}
@@ -0,0 +1,108 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+)
+
+func TestFamily(t *testing.T) {
+	var networks = map[network.IPFamily]string{
+		network.FamilyDefault: "tcp",
+		network.FamilyIPv4:    "tcp4",
+		network.FamilyIPv6:    "tcp6",
+		network.FamilyDual:    "tcp",
+	}
+	for f, n := range networks {
+		if f.Network("tcp") != n {
+			t.Fatalf("Expected %s for %v, got %s", n, f, f.Network("tcp"))
+		}
+	}
+
+	var ip4 = net.ParseIP("127.0.0.1")
+	var ip6 = net.ParseIP("::1")
+	if !network.FamilyIPv4.Matches(ip4) || network.FamilyIPv4.Matches(ip6) {
+		t.Fatal("Unexpected IPv4 match")
+	}
+	if network.FamilyIPv6.Matches(ip4) || !network.FamilyIPv6.Matches(ip6) {
+		t.Fatal("Unexpected IPv6 match")
+	}
+	if !network.FamilyDual.Matches(ip4) || !network.FamilyDual.Matches(ip6) {
+		t.Fatal("Unexpected dual-stack match")
+	}
+
+	if len(network.W3GSBroadcastAddrs(network.FamilyDefault)) != 1 || len(network.W3GSBroadcastAddrs(network.FamilyDual)) != 2 {
+		t.Fatal("Unexpected broadcast addresses")
+	}
+
+	ifs, err := net.Interfaces()
+	if err != nil || len(ifs) == 0 {
+		t.Fatal("No interfaces", err)
+	}
+	for _, ifi := range ifs {
+		var addrs = network.W3GSBroadcastAddrsInterface(network.FamilyDual, &ifi)
+		var last = addrs[len(addrs)-1].(*net.UDPAddr)
+		if last.IP.To4() != nil || last.Zone != ifi.Name {
+			t.Fatal("Expected IPv6 broadcast address scoped to interface", addrs)
+		}
+		for _, a := range addrs[:len(addrs)-1] {
+			if a.(*net.UDPAddr).IP.To4() == nil {
+				t.Fatal("Expected IPv4 broadcast address", addrs)
+			}
+		}
+	}
+
+	var ipnet = &net.IPNet{IP: net.IPv4(192, 168, 1, 20), Mask: net.CIDRMask(24, 32)}
+	if bcast := network.BroadcastIP(ipnet); !bcast.Equal(net.IPv4(192, 168, 1, 255)) {
+		t.Fatal("Unexpected broadcast address", bcast)
+	}
+	if bcast := network.BroadcastIP(&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}); bcast != nil {
+		t.Fatal("Unexpected IPv6 broadcast address", bcast)
+	}
+	if network.W3GSBroadcastAddr6.Zone != "" {
+		t.Fatal("Default IPv6 broadcast address modified")
+	}
+}
+
+func TestListenDial(t *testing.T) {
+	l, err := network.ListenTCP(network.FamilyDefault, &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	conn, err := network.DialTCP(ctx, network.FamilyDual, l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if _, err := network.DialTCP(ctx, network.FamilyIPv6, l.Addr().String()); err == nil {
+		t.Fatal("Expected error dialing IPv4 address over IPv6")
+	}
+
+	u, err := network.ListenUDP(network.FamilyDefault, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := u.LocalAddr().(*net.UDPAddr).IP; ip.To4() == nil {
+		t.Fatal("Expected IPv4 socket, got", ip)
+	}
+	u.Close()
+}
@@ -0,0 +1,187 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/capi"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Stale event, fired when a connection has been inactive for longer than KeepAlive.Timeout
+type Stale struct {
+	Idle time.Duration
+}
+
+// KeepAlive periodically pings a connection and keeps track of its last activity
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type KeepAlive struct {
+	amut sync.Mutex
+	last time.Time
+
+	// Set once before Start(), read-only after that
+	Interval time.Duration // Time between pings (0 to disable pinging)
+	Timeout  time.Duration // Max time without activity before connection is stale (0 to disable)
+	Ping     func() error  // Sends a protocol-appropriate ping
+	Close    func() error  // Called when connection is stale (optional)
+	Emitter  Emitter       // Receives Stale and AsyncError events (optional)
+}
+
+// NewW3GSKeepAlive initializes a KeepAlive struct that sends w3gs.Ping packets over conn
+func NewW3GSKeepAlive(conn *W3GSConn, interval time.Duration, timeout time.Duration) *KeepAlive {
+	var start = time.Now()
+	return &KeepAlive{
+		Interval: interval,
+		Timeout:  timeout,
+		Ping: func() error {
+			_, err := conn.Send(&w3gs.Ping{Payload: uint32(time.Since(start).Milliseconds())})
+			return err
+		},
+		Close: conn.Close,
+	}
+}
+
+// NewBNCSKeepAlive initializes a KeepAlive struct that sends bncs.KeepAlive (SID_NULL) packets over conn
+func NewBNCSKeepAlive(conn *BNCSConn, interval time.Duration, timeout time.Duration) *KeepAlive {
+	return &KeepAlive{
+		Interval: interval,
+		Timeout:  timeout,
+		Ping: func() error {
+			_, err := conn.Send(&bncs.KeepAlive{})
+			return err
+		},
+		Close: conn.Close,
+	}
+}
+
+// NewCAPIKeepAlive initializes a KeepAlive struct that sends websocket ping frames over conn
+func NewCAPIKeepAlive(conn *CAPIConn, interval time.Duration, timeout time.Duration) *KeepAlive {
+	return &KeepAlive{
+		Interval: interval,
+		Timeout:  timeout,
+		Ping:     conn.Ping,
+		Close:    conn.Close,
+	}
+}
+
+// Touch marks the connection as active
+func (k *KeepAlive) Touch() {
+	k.amut.Lock()
+	k.last = time.Now()
+	k.amut.Unlock()
+}
+
+// LastActivity returns the time of the last Touch()
+func (k *KeepAlive) LastActivity() time.Time {
+	k.amut.Lock()
+	var t = k.last
+	k.amut.Unlock()
+	return t
+}
+
+// Idle returns the time since the last Touch()
+func (k *KeepAlive) Idle() time.Duration {
+	return time.Since(k.LastActivity())
+}
+
+// Bind touches k for every packet (w3gs, bncs or capi) fired by l, other events are ignored
+func (k *KeepAlive) Bind(l Listener) EventID {
+	return l.On(nil, func(ev *Event) {
+		switch ev.Arg.(type) {
+		case w3gs.Packet, bncs.Packet, *capi.Packet:
+			k.Touch()
+		}
+	})
+}
+
+// tick returns false if the connection is stale
+func (k *KeepAlive) tick(ping bool) bool {
+	if k.Timeout > 0 {
+		if idle := k.Idle(); idle > k.Timeout {
+			if k.Close != nil {
+				k.Close()
+			}
+			if k.Emitter != nil {
+				k.Emitter.Fire(&Stale{Idle: idle})
+			}
+			return false
+		}
+	}
+
+	if !ping || k.Ping == nil {
+		return true
+	}
+	if err := k.Ping(); err != nil && !IsCloseError(err) && k.Emitter != nil {
+		k.Emitter.Fire(&AsyncError{Src: "KeepAlive.tick[Ping]", Err: err})
+	}
+	return true
+}
+
+// Start pinging in a separate goroutine, returns a function that stops pinging
+// Pinging stops by itself once the connection is found stale, the returned function can be called multiple times
+func (k *KeepAlive) Start() func() {
+	k.Touch()
+
+	// Tick often enough to both ping and detect stale connections in time
+	var interval = k.Interval
+	if interval <= 0 || (k.Timeout > 0 && k.Timeout < interval) {
+		interval = k.Timeout
+	}
+	if interval <= 0 {
+		return func() {}
+	}
+
+	var stop = make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		var next = time.Now().Add(k.Interval)
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case t := <-ticker.C:
+				// Allow for some scheduling jitter
+				var ping = k.Interval > 0 && !t.Add(interval/2).Before(next)
+				if ping {
+					next = t.Add(k.Interval)
+				}
+				if !k.tick(ping) {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(stop) })
+	}
+}
+
+// Ping sends a websocket ping frame
+func (c *CAPIConn) Ping() error {
+	c.cmut.RLock()
+	if c.conn == nil {
+		c.cmut.RUnlock()
+		return io.EOF
+	}
+
+	c.smut.Lock()
+	var wto = c.wto
+	c.smut.Unlock()
+
+	var err = c.conn.WriteControl(websocket.PingMessage, nil, Deadline(wto))
+	c.cmut.RUnlock()
+
+	return err
+}
@@ -0,0 +1,102 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestKeepAlive(t *testing.T) {
+	var e network.EventEmitter
+	var pings, closed int32
+	var stale = make(chan struct{}, 1)
+
+	var k = network.KeepAlive{
+		Interval: 10 * time.Millisecond,
+		Timeout:  100 * time.Millisecond,
+		Ping: func() error {
+			atomic.AddInt32(&pings, 1)
+			return nil
+		},
+		Close: func() error {
+			atomic.AddInt32(&closed, 1)
+			return nil
+		},
+		Emitter: &e,
+	}
+
+	e.Once(&network.Stale{}, func(ev *network.Event) {
+		stale <- struct{}{}
+	})
+	k.Bind(&e)
+
+	var stop = k.Start()
+	defer stop()
+
+	// Keep connection active for a while
+	for i := 0; i < 10; i++ {
+		time.Sleep(10 * time.Millisecond)
+		e.Fire(&w3gs.Ping{})
+	}
+
+	if atomic.LoadInt32(&pings) == 0 {
+		t.Fatal("Expected pings")
+	}
+	if atomic.LoadInt32(&closed) != 0 {
+		t.Fatal("Closed active connection")
+	}
+
+	select {
+	case <-stale:
+	case <-time.After(time.Second):
+		t.Fatal("Stale not fired")
+	}
+
+	if atomic.LoadInt32(&closed) == 0 {
+		t.Fatal("Expected stale connection to be closed")
+	}
+
+	// Stop after first detection
+	time.Sleep(50 * time.Millisecond)
+	if c := atomic.LoadInt32(&closed); c != 1 {
+		t.Fatal("Expected exactly one Close, got", c)
+	}
+
+	stop()
+}
+
+func TestKeepAliveBind(t *testing.T) {
+	var e network.EventEmitter
+	var stale int32
+
+	var k = network.KeepAlive{
+		Timeout: 30 * time.Millisecond,
+		Emitter: &e,
+	}
+
+	e.On(&network.Stale{}, func(ev *network.Event) {
+		atomic.AddInt32(&stale, 1)
+	})
+	k.Bind(&e)
+
+	var stop = k.Start()
+	defer stop()
+
+	// Non-packet events do not count as activity
+	for i := 0; i < 10; i++ {
+		time.Sleep(10 * time.Millisecond)
+		e.Fire(&network.AsyncError{Src: "test"})
+		e.Fire("activity")
+	}
+
+	if atomic.LoadInt32(&stale) != 1 {
+		t.Fatal("Expected exactly one Stale event, got", atomic.LoadInt32(&stale))
+	}
+}
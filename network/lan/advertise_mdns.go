@@ -5,6 +5,7 @@
 package lan
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -17,7 +18,6 @@ import (
 
 	"github.com/dedis/protobuf"
 	"github.com/miekg/dns"
-	"golang.org/x/net/ipv4"
 
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/protocol"
@@ -33,36 +33,113 @@ type MDNSAdvertiser struct {
 	info w3gs.GameInfo
 	msgc int32
 
+	// Dual-stack uses one socket per group, DNSPacketConn is bound to groups[0]
+	groups []*net.UDPAddr
+	extra  []*DNSPacketConn
+	ifi    *net.Interface
+
 	created time.Time
+	limit   throttle
+	state   advState
 
 	// Set once before Run(), read-only after that
-	BroadcastInterval time.Duration
+	BroadcastInterval time.Duration // Time between game info broadcasts (0 to disable)
+	ResponseInterval  time.Duration // Min time between responses to the same peer or multicast group (0 to disable)
+	Logger            network.Logger
 }
 
 // NewMDNSAdvertiser initializes MDNSAdvertiser struct
 func NewMDNSAdvertiser(info *w3gs.GameInfo) (*MDNSAdvertiser, error) {
-	conn, err := net.ListenMulticastUDP("udp4", nil, &MulticastGroup)
-	if err != nil {
-		return nil, err
-	}
+	return NewMDNSAdvertiserFamily(info, network.FamilyIPv4)
+}
 
-	var conn4 = ipv4.NewPacketConn(conn)
-	conn4.SetMulticastLoopback(true)
-	conn4.SetMulticastTTL(255)
+// NewMDNSAdvertiserFamily initializes MDNSAdvertiser struct that advertises over IP family f
+func NewMDNSAdvertiserFamily(info *w3gs.GameInfo, f network.IPFamily) (*MDNSAdvertiser, error) {
+	return NewMDNSAdvertiserInterface(info, f, nil)
+}
+
+// NewMDNSAdvertiserInterface initializes MDNSAdvertiser struct that advertises over IP family f on interface ifi
+func NewMDNSAdvertiserInterface(info *w3gs.GameInfo, f network.IPFamily, ifi *net.Interface) (*MDNSAdvertiser, error) {
+	var groups = MulticastGroupsInterface(f, ifi)
+
+	var conns = make([]net.PacketConn, 0, len(groups))
+	for _, group := range groups {
+		conn, err := listenMulticast(group, ifi)
+		if err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
 
 	var a = MDNSAdvertiser{
 		info:              *info,
 		created:           time.Now().Add(time.Duration(info.UptimeSec) * -time.Second),
 		BroadcastInterval: 3 * time.Minute,
+
+		groups: groups,
+		ifi:    ifi,
 	}
 
 	a.InitDefaultHandlers()
 	a.SetWriteTimeout(time.Second)
-	a.SetConn(conn)
+	a.SetConn(conns[0])
+	a.SetBroadcastAddrs(groups[0])
+
+	for i := 1; i < len(groups); i++ {
+		var c = NewDNSPacketConn(conns[i])
+		c.SetWriteTimeout(time.Second)
+		c.SetBroadcastAddrs(groups[i])
+		a.extra = append(a.extra, c)
+	}
 
 	return &a, nil
 }
 
+// Replace the (broken) sockets with new ones
+func (a *MDNSAdvertiser) reopen() error {
+	conn, err := listenMulticast(a.groups[0], a.ifi)
+	if err != nil {
+		return err
+	}
+	a.SetConn(conn)
+
+	for i, c := range a.extra {
+		conn, err := listenMulticast(a.groups[i+1], a.ifi)
+		if err != nil {
+			return err
+		}
+		c.SetConn(conn)
+	}
+
+	return nil
+}
+
+// Socket and multicast group for the IP family of addr
+func (a *MDNSAdvertiser) route(addr net.Addr) (*DNSPacketConn, *net.UDPAddr) {
+	if u, ok := addr.(*net.UDPAddr); ok && (u.IP.To4() != nil) != (a.groups[0].IP.To4() != nil) {
+		for i, c := range a.extra {
+			if (u.IP.To4() != nil) == (a.groups[i+1].IP.To4() != nil) {
+				return c, a.groups[i+1]
+			}
+		}
+	}
+	return &a.DNSPacketConn, a.groups[0]
+}
+
+// Broadcast msg on all multicast groups
+func (a *MDNSAdvertiser) broadcast(msg *dns.Msg) error {
+	_, err := a.Broadcast(msg)
+	for _, c := range a.extra {
+		if _, e := c.Broadcast(msg); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
 var illegalChars = regexp.MustCompile("\\W")
 
 func (a *MDNSAdvertiser) mdnsService() string {
@@ -229,6 +306,20 @@ func (a *MDNSAdvertiser) addGameInfo(msg *dns.Msg) {
 	})
 }
 
+// State returns a snapshot of the advertised state
+func (a *MDNSAdvertiser) State() AdvertiserState {
+	var info = a.snapshot()
+	return a.state.get(&info)
+}
+
+func (a *MDNSAdvertiser) snapshot() w3gs.GameInfo {
+	a.imut.Lock()
+	var info = a.info
+	info.UptimeSec = (uint32)(time.Since(a.created).Seconds())
+	a.imut.Unlock()
+	return info
+}
+
 // Create local game
 func (a *MDNSAdvertiser) Create() error {
 	var msg = newMsg(0)
@@ -237,16 +328,20 @@ func (a *MDNSAdvertiser) Create() error {
 	a.addSrv(msg)
 	a.addGameInfo(msg)
 
-	_, err := a.Broadcast(msg)
-	return err
+	if err := a.broadcast(msg); err != nil {
+		return err
+	}
+
+	var info = a.snapshot()
+	a.state.create(&a.EventEmitter, &info)
+	return nil
 }
 
 func (a *MDNSAdvertiser) refresh() error {
 	var msg = newMsg(0)
 	a.addGameInfo(msg)
 
-	_, err := a.Broadcast(msg)
-	return err
+	return a.broadcast(msg)
 }
 
 // Refresh game info
@@ -256,7 +351,13 @@ func (a *MDNSAdvertiser) Refresh(slotsUsed uint32, slotsAvailable uint32) error
 	a.info.SlotsAvailable = slotsAvailable
 	a.imut.Unlock()
 
-	return a.refresh()
+	if err := a.refresh(); err != nil {
+		return err
+	}
+
+	var info = a.snapshot()
+	a.state.refresh(&a.EventEmitter, &info)
+	return nil
 }
 
 // Decreate game
@@ -266,7 +367,9 @@ func (a *MDNSAdvertiser) Decreate() error {
 
 	msg.Answer[0].(*dns.PTR).Hdr.Ttl = 0
 
-	_, err := a.Broadcast(msg)
+	var err = a.broadcast(msg)
+	var info = a.snapshot()
+	a.state.decreate(&a.EventEmitter, &info)
 	return err
 }
 
@@ -295,17 +398,38 @@ func (a *MDNSAdvertiser) runBroadcast() func() {
 
 // Run broadcasts gameinfo in Local Area Network
 func (a *MDNSAdvertiser) Run() error {
+	return a.RunContext(context.Background())
+}
+
+// RunContext broadcasts gameinfo in Local Area Network until ctx is done
+func (a *MDNSAdvertiser) RunContext(ctx context.Context) error {
+	a.state.run(true)
+	defer a.state.run(false)
+
 	if err := a.Create(); err != nil {
 		return err
 	}
 	defer a.Decreate()
 
+	if a.Logger != nil {
+		a.imut.Lock()
+		var id = a.info.HostCounter
+		a.imut.Unlock()
+
+		a.Logger.Info("Advertising game", network.LogKeyGameID, id)
+		defer a.Logger.Info("Stopped advertising game", network.LogKeyGameID, id)
+	}
+
 	if a.BroadcastInterval > 0 {
 		var stop = a.runBroadcast()
 		defer stop()
 	}
 
-	return a.DNSPacketConn.Run(&a.EventEmitter, network.NoTimeout)
+	for _, c := range a.extra {
+		go c.RunContext(ctx, &a.EventEmitter, network.NoTimeout)
+	}
+
+	return a.DNSPacketConn.RunContext(ctx, &a.EventEmitter, network.NoTimeout)
 }
 
 // Close the connection
@@ -313,6 +437,9 @@ func (a *MDNSAdvertiser) Close() error {
 	if err := a.Decreate(); err != nil && !network.IsCloseError(err) {
 		a.Fire(&network.AsyncError{Src: "Close[Decreate]", Err: err})
 	}
+	for _, c := range a.extra {
+		c.Close()
+	}
 	return a.DNSPacketConn.Close()
 }
 
@@ -328,6 +455,7 @@ func (a *MDNSAdvertiser) onDNS(ev *network.Event) {
 	}
 
 	var addr = ev.Opt[0].(net.Addr)
+	var conn, group = a.route(addr)
 	var service = a.mdnsService()
 	var name = a.mdnsName()
 
@@ -338,7 +466,7 @@ func (a *MDNSAdvertiser) onDNS(ev *network.Event) {
 
 	for _, q := range msg.Question {
 		if q.Qclass&TypeUnicastResponse == 0 {
-			addr = &MulticastGroup
+			addr = group
 		}
 
 		if strings.EqualFold(q.Name, service) && (q.Qtype == dns.TypePTR || q.Qtype == dns.TypeANY) {
@@ -362,7 +490,7 @@ func (a *MDNSAdvertiser) onDNS(ev *network.Event) {
 		}
 	}
 
-	if addPtr || addTxt || addSrv || addInfo {
+	if (addPtr || addTxt || addSrv || addInfo) && a.limit.allow(addr, a.ResponseInterval) {
 		var ans = newMsg(msg.Id)
 		if addTxt {
 			a.addTxt(ans)
@@ -376,8 +504,13 @@ func (a *MDNSAdvertiser) onDNS(ev *network.Event) {
 		if addInfo {
 			a.addGameInfo(ans)
 		}
-		if _, err := a.Send(addr, ans); err != nil && !network.IsCloseError(err) {
-			a.Fire(&network.AsyncError{Src: "onDNS[Send]", Err: err})
+		if _, err := conn.Send(addr, ans); err != nil {
+			if !network.IsCloseError(err) {
+				a.Fire(&network.AsyncError{Src: "onDNS[Send]", Err: err})
+			}
+		} else if addInfo {
+			var info = a.snapshot()
+			a.state.probe(&a.EventEmitter, addr, &info)
 		}
 	}
 }
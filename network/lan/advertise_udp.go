@@ -5,6 +5,7 @@
 package lan
 
 import (
+	"context"
 	"net"
 	"sync"
 	"time"
@@ -13,27 +14,76 @@ import (
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
+type udpGame struct {
+	info    w3gs.GameInfo
+	created time.Time
+}
+
 // UDPAdvertiser advertises a hosted game in the Local Area Network using UDP broadcast
+// Additional games (with distinct host counters) can be advertised on the same socket with AddGame()
+// Lifecycle events (except GameProbed) and State() describe the primary game
+// Public methods/fields are thread-safe unless explicitly stated otherwise
 type UDPAdvertiser struct {
 	network.EventEmitter
 	network.W3GSPacketConn
 
-	imut sync.Mutex
-	info w3gs.GameInfo
+	imut  sync.Mutex
+	info  w3gs.GameInfo
+	extra []*udpGame
 
 	created time.Time
+	open    func() (net.PacketConn, error)
+	limit   throttle
+	state   advState
 
 	// Set once before Run(), read-only after that
-	BroadcastInterval time.Duration
+	BroadcastInterval time.Duration // Time between RefreshGame broadcasts (0 to disable)
+	ResponseInterval  time.Duration // Min time between responses to the same peer (0 to disable)
+	Logger            network.Logger
 }
 
 // NewUDPAdvertiser initializes UDPAdvertiser struct
 func NewUDPAdvertiser(info *w3gs.GameInfo, port int) (*UDPAdvertiser, error) {
-	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	return NewUDPAdvertiserFamily(info, network.FamilyIPv4, port)
+}
+
+// NewUDPAdvertiserFamily initializes UDPAdvertiser struct with a UDP socket for IP family f
+func NewUDPAdvertiserFamily(info *w3gs.GameInfo, f network.IPFamily, port int) (*UDPAdvertiser, error) {
+	return NewUDPAdvertiserInterface(info, f, nil, port)
+}
+
+// NewUDPAdvertiserInterface initializes UDPAdvertiser struct with a UDP socket for IP family f that broadcasts on interface ifi
+func NewUDPAdvertiserInterface(info *w3gs.GameInfo, f network.IPFamily, ifi *net.Interface, port int) (*UDPAdvertiser, error) {
+	var open = func() (net.PacketConn, error) {
+		return network.ListenUDP(f, port)
+	}
+
+	conn, err := open()
 	if err != nil {
 		return nil, err
 	}
 
+	var a = newUDPAdvertiser(info, conn, f)
+	a.open = open
+	a.SetBroadcastAddrs(network.W3GSBroadcastAddrsInterface(f, ifi)...)
+	return a, nil
+}
+
+// NewUDPAdvertiserMux initializes UDPAdvertiser struct on an endpoint of the shared socket m
+func NewUDPAdvertiserMux(info *w3gs.GameInfo, m *network.UDPMux) *UDPAdvertiser {
+	var open = func() (net.PacketConn, error) {
+		return m.Endpoint(network.UDPFilter{
+			Types: []uint8{w3gs.PidSearchGame},
+		}), nil
+	}
+
+	var conn, _ = open()
+	var a = newUDPAdvertiser(info, conn, m.Family())
+	a.open = open
+	return a
+}
+
+func newUDPAdvertiser(info *w3gs.GameInfo, conn net.PacketConn, f network.IPFamily) *UDPAdvertiser {
 	var a = UDPAdvertiser{
 		info:              *info,
 		created:           time.Now().Add(time.Duration(info.UptimeSec) * -time.Second),
@@ -43,33 +93,181 @@ func NewUDPAdvertiser(info *w3gs.GameInfo, port int) (*UDPAdvertiser, error) {
 	a.InitDefaultHandlers()
 	a.SetWriteTimeout(time.Second)
 	a.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), w3gs.Encoding{GameVersion: info.GameVersion.Version})
+	a.SetBroadcastAddrs(network.W3GSBroadcastAddrs(f)...)
 
-	return &a, nil
+	return &a
+}
+
+// Replace the (broken) socket with a new one
+func (a *UDPAdvertiser) reopen() error {
+	conn, err := a.open()
+	if err != nil {
+		return err
+	}
+
+	a.imut.Lock()
+	var enc = w3gs.Encoding{GameVersion: a.info.GameVersion.Version}
+	a.imut.Unlock()
+
+	a.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), enc)
+	return nil
+}
+
+// State returns a snapshot of the advertised state
+func (a *UDPAdvertiser) State() AdvertiserState {
+	a.imut.Lock()
+	var info = a.info
+	info.UptimeSec = (uint32)(time.Since(a.created).Seconds())
+	a.imut.Unlock()
+
+	return a.state.get(&info)
 }
 
 // Create local game
 func (a *UDPAdvertiser) Create() error {
 	a.imut.Lock()
+	var info = a.info
+	a.imut.Unlock()
+
 	var pkt = w3gs.CreateGame{
-		GameVersion: a.info.GameVersion,
-		HostCounter: a.info.HostCounter,
+		GameVersion: info.GameVersion,
+		HostCounter: info.HostCounter,
+	}
+
+	if _, err := a.Broadcast(&pkt); err != nil {
+		return err
+	}
+
+	a.state.create(&a.EventEmitter, &info)
+	return nil
+}
+
+func refreshPacket(info *w3gs.GameInfo) *w3gs.RefreshGame {
+	return &w3gs.RefreshGame{
+		HostCounter:    info.HostCounter,
+		SlotsUsed:      info.SlotsUsed,
+		SlotsAvailable: info.SlotsAvailable,
+	}
+}
+
+func (a *UDPAdvertiser) refresh() error {
+	a.imut.Lock()
+	var pkt = []w3gs.Packet{refreshPacket(&a.info)}
+	var seen = map[uint32]bool{a.info.HostCounter: true}
+	for _, g := range a.extra {
+		if !seen[g.info.HostCounter] {
+			seen[g.info.HostCounter] = true
+			pkt = append(pkt, refreshPacket(&g.info))
+		}
 	}
 	a.imut.Unlock()
 
-	_, err := a.Broadcast(&pkt)
+	return a.broadcast(pkt...)
+}
+
+// Broadcast all pkt, returns the first error
+func (a *UDPAdvertiser) broadcast(pkt ...w3gs.Packet) error {
+	var err error
+	for _, p := range pkt {
+		if _, e := a.Broadcast(p); e != nil && err == nil {
+			err = e
+		}
+	}
 	return err
 }
 
-func (a *UDPAdvertiser) refresh() error {
+// AddGame advertises an additional game, info.HostCounter must differ from the other advertised games
+// unless info.GameVersion differs (the same game offered to multiple game versions, see AddVersion())
+func (a *UDPAdvertiser) AddGame(info *w3gs.GameInfo) error {
 	a.imut.Lock()
-	var pkt = w3gs.RefreshGame{
-		HostCounter:    a.info.HostCounter,
-		SlotsUsed:      a.info.SlotsUsed,
-		SlotsAvailable: a.info.SlotsAvailable,
+	if info.HostCounter == a.info.HostCounter && info.GameVersion == a.info.GameVersion {
+		a.imut.Unlock()
+		return ErrDuplicateGame
 	}
+	for _, g := range a.extra {
+		if g.info.HostCounter == info.HostCounter && g.info.GameVersion == info.GameVersion {
+			a.imut.Unlock()
+			return ErrDuplicateGame
+		}
+	}
+
+	a.extra = append(a.extra, &udpGame{
+		info:    *info,
+		created: time.Now().Add(time.Duration(info.UptimeSec) * -time.Second),
+	})
 	a.imut.Unlock()
 
-	_, err := a.Broadcast(&pkt)
+	_, err := a.Broadcast(&w3gs.CreateGame{
+		GameVersion: info.GameVersion,
+		HostCounter: info.HostCounter,
+	})
+	return err
+}
+
+// AddVersion also answers search queries from clients running game version gv with the primary game info
+// Slot counts of the extra version follow Refresh()
+func (a *UDPAdvertiser) AddVersion(gv w3gs.GameVersion) error {
+	a.imut.Lock()
+	var info = a.info
+	a.imut.Unlock()
+
+	info.GameVersion = gv
+	return a.AddGame(&info)
+}
+
+// Make sure imut is locked before calling
+func (a *UDPAdvertiser) updateSlots(hostCounter uint32, slotsUsed uint32, slotsAvailable uint32) bool {
+	var found = false
+	for _, g := range a.extra {
+		if g.info.HostCounter != hostCounter {
+			continue
+		}
+		g.info.SlotsUsed = slotsUsed
+		g.info.SlotsAvailable = slotsAvailable
+		found = true
+	}
+	return found
+}
+
+// RefreshGame updates the slot counts of an additional game (for all of its game versions)
+func (a *UDPAdvertiser) RefreshGame(hostCounter uint32, slotsUsed uint32, slotsAvailable uint32) error {
+	a.imut.Lock()
+	if !a.updateSlots(hostCounter, slotsUsed, slotsAvailable) {
+		a.imut.Unlock()
+		return ErrUnknownGame
+	}
+	a.imut.Unlock()
+
+	_, err := a.Broadcast(&w3gs.RefreshGame{
+		HostCounter:    hostCounter,
+		SlotsUsed:      slotsUsed,
+		SlotsAvailable: slotsAvailable,
+	})
+	return err
+}
+
+// RemoveGame stops advertising an additional game (for all of its game versions)
+func (a *UDPAdvertiser) RemoveGame(hostCounter uint32) error {
+	a.imut.Lock()
+	var end = 0
+	for _, g := range a.extra {
+		if g.info.HostCounter != hostCounter {
+			a.extra[end] = g
+			end++
+		}
+	}
+	var found = end < len(a.extra)
+	for i := end; i < len(a.extra); i++ {
+		a.extra[i] = nil
+	}
+	a.extra = a.extra[:end]
+	a.imut.Unlock()
+
+	if !found {
+		return ErrUnknownGame
+	}
+
+	_, err := a.Broadcast(&w3gs.DecreateGame{HostCounter: hostCounter})
 	return err
 }
 
@@ -78,20 +276,45 @@ func (a *UDPAdvertiser) Refresh(slotsUsed uint32, slotsAvailable uint32) error {
 	a.imut.Lock()
 	a.info.SlotsUsed = slotsUsed
 	a.info.SlotsAvailable = slotsAvailable
+	a.updateSlots(a.info.HostCounter, slotsUsed, slotsAvailable)
+	var info = a.info
 	a.imut.Unlock()
 
-	return a.refresh()
+	if err := a.refresh(); err != nil {
+		return err
+	}
+
+	a.state.refresh(&a.EventEmitter, &info)
+	return nil
+}
+
+// CreateGame (or DecreateGame) packets for the additional games
+func (a *UDPAdvertiser) extraPackets(create bool) []w3gs.Packet {
+	a.imut.Lock()
+	var res = make([]w3gs.Packet, 0, len(a.extra))
+	for _, g := range a.extra {
+		if create {
+			res = append(res, &w3gs.CreateGame{GameVersion: g.info.GameVersion, HostCounter: g.info.HostCounter})
+		} else {
+			res = append(res, &w3gs.DecreateGame{HostCounter: g.info.HostCounter})
+		}
+	}
+	a.imut.Unlock()
+	return res
 }
 
 // Decreate game
 func (a *UDPAdvertiser) Decreate() error {
 	a.imut.Lock()
+	var info = a.info
+	a.imut.Unlock()
+
 	var pkt = w3gs.DecreateGame{
-		HostCounter: a.info.HostCounter,
+		HostCounter: info.HostCounter,
 	}
-	a.imut.Unlock()
 
 	_, err := a.Broadcast(&pkt)
+	a.state.decreate(&a.EventEmitter, &info)
 	return err
 }
 
@@ -120,17 +343,41 @@ func (a *UDPAdvertiser) runBroadcast() func() {
 
 // Run broadcasts gameinfo in Local Area Network
 func (a *UDPAdvertiser) Run() error {
+	return a.RunContext(context.Background())
+}
+
+// RunContext broadcasts gameinfo in Local Area Network until ctx is done
+func (a *UDPAdvertiser) RunContext(ctx context.Context) error {
+	a.SetLogger(a.Logger)
+
+	a.state.run(true)
+	defer a.state.run(false)
+
 	if err := a.Create(); err != nil {
 		return err
 	}
 	defer a.Decreate()
 
+	if err := a.broadcast(a.extraPackets(true)...); err != nil {
+		return err
+	}
+	defer func() { a.broadcast(a.extraPackets(false)...) }()
+
+	if a.Logger != nil {
+		a.imut.Lock()
+		var id = a.info.HostCounter
+		a.imut.Unlock()
+
+		a.Logger.Info("Advertising game", network.LogKeyGameID, id)
+		defer a.Logger.Info("Stopped advertising game", network.LogKeyGameID, id)
+	}
+
 	if a.BroadcastInterval > 0 {
 		var stop = a.runBroadcast()
 		defer stop()
 	}
 
-	return a.W3GSPacketConn.Run(&a.EventEmitter, network.NoTimeout)
+	return a.W3GSPacketConn.RunContext(ctx, &a.EventEmitter, network.NoTimeout)
 }
 
 // Close the connection
@@ -138,6 +385,9 @@ func (a *UDPAdvertiser) Close() error {
 	if err := a.Decreate(); err != nil && !network.IsCloseError(err) {
 		a.Fire(&network.AsyncError{Src: "Close[Decreate]", Err: err})
 	}
+	if err := a.broadcast(a.extraPackets(false)...); err != nil && !network.IsCloseError(err) {
+		a.Fire(&network.AsyncError{Src: "Close[Decreate]", Err: err})
+	}
 	return a.W3GSPacketConn.Close()
 }
 
@@ -148,19 +398,50 @@ func (a *UDPAdvertiser) InitDefaultHandlers() {
 
 func (a *UDPAdvertiser) onSearchGame(ev *network.Event) {
 	var pkt = ev.Arg.(*w3gs.SearchGame)
+	var addr = ev.Opt[0].(net.Addr)
+	var now = time.Now()
 
-	a.imut.Lock()
-	if pkt.Product != a.info.Product {
-		a.imut.Unlock()
+	if !a.limit.allow(addr, a.ResponseInterval) {
 		return
 	}
 
-	a.info.UptimeSec = (uint32)(time.Now().Sub(a.created).Seconds())
+	a.imut.Lock()
 
-	var addr = ev.Opt[0].(net.Addr)
-	if _, err := a.Send(addr, &a.info); err != nil {
-		a.Fire(&network.AsyncError{Src: "onSearchGame[Send]", Err: err})
+	// Only answer with matching versions if the probed version is advertised, any version otherwise
+	var exact = a.info.GameVersion == pkt.GameVersion
+	for _, g := range a.extra {
+		exact = exact || g.info.GameVersion == pkt.GameVersion
 	}
 
+	var match = func(gv *w3gs.GameVersion) bool {
+		return gv.Product == pkt.Product && (!exact || gv.Version == pkt.Version)
+	}
+
+	var probed []w3gs.GameInfo
+	if match(&a.info.GameVersion) {
+		a.info.UptimeSec = (uint32)(now.Sub(a.created).Seconds())
+		if _, err := a.Send(addr, &a.info); err != nil {
+			a.Fire(&network.AsyncError{Src: "onSearchGame[Send]", Err: err})
+		} else {
+			probed = append(probed, a.info)
+		}
+	}
+
+	for _, g := range a.extra {
+		if !match(&g.info.GameVersion) {
+			continue
+		}
+
+		g.info.UptimeSec = (uint32)(now.Sub(g.created).Seconds())
+		if _, err := a.Send(addr, &g.info); err != nil {
+			a.Fire(&network.AsyncError{Src: "onSearchGame[Send]", Err: err})
+		} else {
+			probed = append(probed, g.info)
+		}
+	}
 	a.imut.Unlock()
+
+	for i := range probed {
+		a.state.probe(&a.EventEmitter, addr, &probed[i])
+	}
 }
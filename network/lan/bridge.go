@@ -0,0 +1,408 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// BridgeQueueSize is the number of game list changes buffered per subscribed tunnel
+const BridgeQueueSize = 64
+
+// BridgeExport serves the games of a local GameList to remote BridgeImports over tunnel connections
+// A tunnel connection either starts with w3gs.SearchGame to subscribe to the game list (answered with
+// GameInfo and DecreateGame packets), or with the w3gs.Join of a client that is relayed to the game host.
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type BridgeExport struct {
+	network.EventEmitter
+	list GameList
+
+	// Set once before Serve(), read-only after that
+	Timeout time.Duration // Max time to wait for the first packet of a tunnel connection
+	Logger  network.Logger
+}
+
+// NewBridgeExport initializes a BridgeExport for the games in list (list should be running)
+func NewBridgeExport(list GameList) *BridgeExport {
+	return &BridgeExport{
+		list:    list,
+		Timeout: 10 * time.Second,
+	}
+}
+
+// Serve tunnel connections accepted by l
+func (b *BridgeExport) Serve(l net.Listener) error {
+	return b.ServeContext(context.Background(), l)
+}
+
+// ServeContext serves tunnel connections accepted by l until ctx is done, l is closed afterwards
+func (b *BridgeExport) ServeContext(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if e := ctx.Err(); e != nil {
+				return e
+			}
+			return err
+		}
+
+		go func() {
+			if err := b.ServeConn(ctx, conn); err != nil && !network.IsCloseError(err) && ctx.Err() == nil {
+				b.Fire(&network.AsyncError{Src: "ServeContext[ServeConn]", Err: err})
+			}
+		}()
+	}
+}
+
+// ServeConn handles a single tunnel connection (i.e. an upgraded WebSocket) until it is closed or ctx is done
+func (b *BridgeExport) ServeConn(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+
+	var dec = w3gs.NewDecoder(w3gs.Encoding{}, w3gs.DefaultFactory)
+	if b.Timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(b.Timeout))
+	}
+
+	raw, _, err := dec.ReadRaw(conn)
+	if err != nil {
+		return err
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	pkt, _, err := dec.Deserialize(raw)
+	if err != nil {
+		return err
+	}
+
+	switch p := pkt.(type) {
+	case *w3gs.SearchGame:
+		return b.subscribe(ctx, conn)
+	case *w3gs.Join:
+		return b.join(ctx, conn, p.HostCounter, raw)
+	default:
+		return ErrBridgeProtocol
+	}
+}
+
+func (b *BridgeExport) subscribe(ctx context.Context, conn net.Conn) error {
+	var w = network.NewW3GSConn(conn, nil, w3gs.Encoding{})
+	w.SetLogger(b.Logger)
+
+	// Events are fired from the GameList goroutine, never block it
+	var queue = make(chan w3gs.Packet, BridgeQueueSize)
+	var full = make(chan struct{})
+	var once sync.Once
+	var push = func(pkt w3gs.Packet) {
+		select {
+		case queue <- pkt:
+		default:
+			once.Do(func() { close(full) })
+		}
+	}
+
+	var info = func(ev *network.Event) {
+		var gi w3gs.GameInfo
+		switch v := ev.Arg.(type) {
+		case *GameAdded:
+			gi = v.Game
+		case *GameUpdated:
+			gi = v.Game
+		}
+		push(&gi)
+	}
+
+	var ids = []network.EventID{
+		b.list.On(&GameAdded{}, info),
+		b.list.On(&GameUpdated{}, info),
+		b.list.On(&GameRemoved{}, func(ev *network.Event) {
+			push(&w3gs.DecreateGame{HostCounter: ev.Arg.(*GameRemoved).Game.HostCounter})
+		}),
+	}
+	defer func() {
+		for _, id := range ids {
+			b.list.Off(id)
+		}
+	}()
+
+	for _, g := range b.list.Games() {
+		var gi = g
+		push(&gi)
+	}
+
+	// Remote end does not send anything after subscribing, read to detect disconnects
+	var closed = make(chan error, 1)
+	go func() {
+		_, err := w.NextPacketContext(ctx, network.NoTimeout)
+		closed <- err
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-closed:
+			return err
+		case <-full:
+			return network.ErrQueueFull
+		case pkt := <-queue:
+			if _, err := w.Send(pkt); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (b *BridgeExport) join(ctx context.Context, conn net.Conn, hostCounter uint32, raw []byte) error {
+	var addr string
+	for k, g := range b.list.Games() {
+		if g.HostCounter == hostCounter {
+			addr = k
+			break
+		}
+	}
+	if addr == "" {
+		return ErrUnknownGame
+	}
+
+	host, err := network.DialTCP(ctx, network.FamilyDefault, addr)
+	if err != nil {
+		return err
+	}
+
+	if _, err := host.Write(raw); err != nil {
+		host.Close()
+		return err
+	}
+
+	return relay(ctx, conn, host)
+}
+
+// relay copies data between a and b until either is closed or ctx is done, closes both
+func relay(ctx context.Context, a net.Conn, b net.Conn) error {
+	var errc = make(chan error, 2)
+	go func() {
+		_, err := io.Copy(a, b)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(b, a)
+		errc <- err
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	a.Close()
+	b.Close()
+	return err
+}
+
+type bridgeGame struct {
+	adv    Advertiser
+	cancel context.CancelFunc
+	addr   string
+}
+
+// bridgeGames runs an Advertiser per imported game, indexed by host counter
+type bridgeGames struct {
+	gmut  sync.Mutex
+	games map[uint32]*bridgeGame
+}
+
+// BridgeImport advertises the games of a remote BridgeExport in the Local Area Network
+// Clients joining an imported game are tunneled to the remote network
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type BridgeImport struct {
+	network.EventEmitter
+	bridgeGames
+
+	// Set once before Run(), read-only after that
+	Dial      func(ctx context.Context) (net.Conn, error)   // Opens a tunnel connection to the remote BridgeExport
+	Advertise func(info *w3gs.GameInfo) (Advertiser, error) // Creates the local advertiser for an imported game
+	Family    network.IPFamily                              // IP family for the join listener
+	Port      int                                           // Port for the join listener (0 for random)
+	Logger    network.Logger
+}
+
+// NewBridgeImport initializes a BridgeImport that opens tunnel connections with dial
+func NewBridgeImport(dial func(ctx context.Context) (net.Conn, error)) *BridgeImport {
+	return &BridgeImport{
+		Dial:      dial,
+		Advertise: NewAdvertiser,
+	}
+}
+
+// Run imports games until the tunnel is closed
+func (b *BridgeImport) Run() error {
+	return b.RunContext(context.Background())
+}
+
+// RunContext imports games until the tunnel is closed or ctx is done
+// Imported games are decreated once RunContext returns
+func (b *BridgeImport) RunContext(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	l, err := network.ListenTCP(b.Family, &net.TCPAddr{Port: b.Port})
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	go b.accept(ctx, l)
+
+	tunnel, err := b.Dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	var w = network.NewW3GSConn(tunnel, nil, w3gs.Encoding{})
+	w.SetLogger(b.Logger)
+	defer w.Close()
+	defer b.removeAll()
+
+	if _, err := w.Send(&w3gs.SearchGame{}); err != nil {
+		return err
+	}
+
+	var port = uint16(l.Addr().(*net.TCPAddr).Port)
+	for {
+		pkt, err := w.NextPacketContext(ctx, network.NoTimeout)
+		if err != nil {
+			return err
+		}
+
+		switch p := pkt.(type) {
+		case *w3gs.GameInfo:
+			var info = *p
+			info.GamePort = port
+			b.add(ctx, &b.EventEmitter, b.Advertise, &info, "")
+		case *w3gs.DecreateGame:
+			b.remove(p.HostCounter)
+		default:
+			return ErrBridgeProtocol
+		}
+	}
+}
+
+func (b *BridgeImport) accept(ctx context.Context, l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			tunnel, err := b.Dial(ctx)
+			if err != nil {
+				conn.Close()
+				b.Fire(&network.AsyncError{Src: "accept[Dial]", Err: err})
+				return
+			}
+			if err := relay(ctx, conn, tunnel); err != nil && !network.IsCloseError(err) && ctx.Err() == nil {
+				b.Fire(&network.AsyncError{Src: "accept[relay]", Err: err})
+			}
+		}()
+	}
+}
+
+// add starts advertising info (hosted on addr) or refreshes it if already advertised
+func (s *bridgeGames) add(ctx context.Context, e *network.EventEmitter, advertise func(info *w3gs.GameInfo) (Advertiser, error), info *w3gs.GameInfo, addr string) {
+	s.gmut.Lock()
+	defer s.gmut.Unlock()
+
+	if g, ok := s.games[info.HostCounter]; ok {
+		if err := g.adv.Refresh(info.SlotsUsed, info.SlotsAvailable); err != nil && !network.IsCloseError(err) {
+			e.Fire(&network.AsyncError{Src: "add[Refresh]", Err: err})
+		}
+		return
+	}
+
+	adv, err := advertise(info)
+	if err != nil {
+		e.Fire(&network.AsyncError{Src: "add[Advertise]", Err: err})
+		return
+	}
+	adv.On(&network.AsyncError{}, func(ev *network.Event) {
+		e.Fire(ev.Arg)
+	})
+
+	var actx, cancel = context.WithCancel(ctx)
+	go func() {
+		if err := adv.RunContext(actx); err != nil && !network.IsCloseError(err) && actx.Err() == nil {
+			e.Fire(&network.AsyncError{Src: "add[RunContext]", Err: err})
+		}
+	}()
+
+	if s.games == nil {
+		s.games = make(map[uint32]*bridgeGame)
+	}
+	s.games[info.HostCounter] = &bridgeGame{adv: adv, cancel: cancel, addr: addr}
+}
+
+// addr returns the host address of an advertised game
+func (s *bridgeGames) addr(hostCounter uint32) (string, bool) {
+	s.gmut.Lock()
+	g, ok := s.games[hostCounter]
+	s.gmut.Unlock()
+
+	if !ok {
+		return "", false
+	}
+	return g.addr, true
+}
+
+// keys returns the host counters of all advertised games
+func (s *bridgeGames) keys() []uint32 {
+	s.gmut.Lock()
+	var res = make([]uint32, 0, len(s.games))
+	for k := range s.games {
+		res = append(res, k)
+	}
+	s.gmut.Unlock()
+	return res
+}
+
+func (s *bridgeGames) remove(hostCounter uint32) {
+	s.gmut.Lock()
+	var g = s.games[hostCounter]
+	delete(s.games, hostCounter)
+	s.gmut.Unlock()
+
+	if g != nil {
+		g.cancel()
+		g.adv.Close()
+	}
+}
+
+func (s *bridgeGames) removeAll() {
+	s.gmut.Lock()
+	var games = s.games
+	s.games = nil
+	s.gmut.Unlock()
+
+	for _, g := range games {
+		g.cancel()
+		g.adv.Close()
+	}
+}
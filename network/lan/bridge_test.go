@@ -0,0 +1,150 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/lan"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+type staticGameList struct {
+	network.EventEmitter
+	mut   sync.Mutex
+	games map[string]w3gs.GameInfo
+}
+
+func (l *staticGameList) Games() map[string]w3gs.GameInfo {
+	var res = make(map[string]w3gs.GameInfo)
+	l.mut.Lock()
+	for k, v := range l.games {
+		res[k] = v
+	}
+	l.mut.Unlock()
+	return res
+}
+
+func (l *staticGameList) Run() error                           { return nil }
+func (l *staticGameList) RunContext(ctx context.Context) error { return nil }
+func (l *staticGameList) Close() error                         { return nil }
+
+type bridgeAdvertiser struct {
+	network.EventEmitter
+	info   w3gs.GameInfo
+	closed chan struct{}
+}
+
+func (a *bridgeAdvertiser) Create() error                               { return nil }
+func (a *bridgeAdvertiser) Refresh(used uint32, available uint32) error { return nil }
+func (a *bridgeAdvertiser) Decreate() error                             { return nil }
+func (a *bridgeAdvertiser) State() lan.AdvertiserState                  { return lan.AdvertiserState{Game: a.info} }
+func (a *bridgeAdvertiser) Run() error                                  { return nil }
+func (a *bridgeAdvertiser) RunContext(ctx context.Context) error        { return nil }
+func (a *bridgeAdvertiser) Close() error {
+	close(a.closed)
+	return nil
+}
+
+func TestBridge(t *testing.T) {
+	// Game host in remote network
+	host, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+
+	go func() {
+		conn, err := host.Accept()
+		if err != nil {
+			return
+		}
+		var c = network.NewW3GSConn(conn, nil, w3gs.Encoding{})
+		defer c.Close()
+
+		pkt, err := c.NextPacket(time.Second)
+		if err != nil {
+			return
+		}
+		if j, ok := pkt.(*w3gs.Join); ok {
+			c.Send(&w3gs.Ping{Payload: j.HostCounter})
+		}
+	}()
+
+	var info = gameInfo
+	var list = staticGameList{games: map[string]w3gs.GameInfo{
+		fmt.Sprintf("127.0.0.1:%d", host.Addr().(*net.TCPAddr).Port): info,
+	}}
+
+	tunnel, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var exp = lan.NewBridgeExport(&list)
+	exp.On(&network.AsyncError{}, func(ev *network.Event) {
+		t.Log(ev.Arg)
+	})
+	go exp.ServeContext(ctx, tunnel)
+
+	var imp = lan.NewBridgeImport(func(ctx context.Context) (net.Conn, error) {
+		return network.DialTCP(ctx, network.FamilyIPv4, tunnel.Addr().String())
+	})
+	imp.Family = network.FamilyIPv4
+
+	var ads = make(chan *bridgeAdvertiser, 2)
+	imp.Advertise = func(info *w3gs.GameInfo) (lan.Advertiser, error) {
+		var a = &bridgeAdvertiser{info: *info, closed: make(chan struct{})}
+		ads <- a
+		return a, nil
+	}
+	go imp.RunContext(ctx)
+
+	var ad *bridgeAdvertiser
+	select {
+	case ad = <-ads:
+	case <-time.After(time.Second):
+		t.Fatal("Game not imported")
+	}
+	if ad.info.HostCounter != info.HostCounter || ad.info.GameName != info.GameName || ad.info.GamePort == info.GamePort {
+		t.Fatal("Unexpected imported game", ad.info)
+	}
+
+	// Join through the bridge
+	conn, err := network.DialTCP(ctx, network.FamilyIPv4, fmt.Sprintf("127.0.0.1:%d", ad.info.GamePort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = network.NewW3GSConn(conn, nil, w3gs.Encoding{})
+	defer client.Close()
+
+	if _, err := client.Send(&w3gs.Join{HostCounter: info.HostCounter, PlayerName: "client"}); err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := client.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := pkt.(*w3gs.Ping); !ok || p.Payload != info.HostCounter {
+		t.Fatal("Unexpected reply from host", pkt)
+	}
+
+	// Removal is forwarded
+	list.Fire(&lan.GameRemoved{Game: info})
+	select {
+	case <-ad.closed:
+	case <-time.After(time.Second):
+		t.Fatal("Game not removed")
+	}
+}
@@ -5,6 +5,7 @@
 package lan
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -16,7 +17,6 @@ import (
 
 	"github.com/dedis/protobuf"
 	"github.com/miekg/dns"
-	"golang.org/x/net/ipv4"
 
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/protocol"
@@ -37,23 +37,43 @@ type mdnsRecord struct {
 }
 
 // MDNSGameList keeps track of all the hosted games in the Local Area Network using MDNS (Bonjour)
-// Emits events for every received packet and Update{} when the output of Games() changes
+// Emits events for every received packet, GameAdded/GameUpdated/GameRemoved for every changed game
+// and Update{} when the output of Games() changes
 // Public methods/fields are thread-safe unless explicitly stated otherwise
 type MDNSGameList struct {
 	network.EventEmitter
 	DNSPacketConn
 
+	diff  gameDiff
 	gmut  sync.Mutex
 	games map[mdnsIndex]*mdnsRecord
 
+	family network.IPFamily
+	ifi    *net.Interface
+
 	// Set once before Run(), read-only after that
 	GameVersion       w3gs.GameVersion
 	BroadcastInterval time.Duration
+	Policy            Policy // Validate games, decides whether invalid games are listed (nil to disable validation)
+	Passive           bool   // Only listen for announcements, never send search queries
+	Logger            network.Logger
 }
 
 // NewMDNSGameList opens a new UDP socket to listen for MDNS GameList updates
 func NewMDNSGameList(gv w3gs.GameVersion) (*MDNSGameList, error) {
-	conn, err := net.ListenUDP("udp4", nil)
+	return NewMDNSGameListFamily(gv, network.FamilyIPv4)
+}
+
+// NewMDNSGameListFamily opens a new UDP socket for IP family f to listen for MDNS GameList updates
+func NewMDNSGameListFamily(gv w3gs.GameVersion, f network.IPFamily) (*MDNSGameList, error) {
+	return NewMDNSGameListInterface(gv, f, nil)
+}
+
+// NewMDNSGameListInterface opens a new UDP socket for IP family f to listen for MDNS GameList updates on interface ifi
+func NewMDNSGameListInterface(gv w3gs.GameVersion, f network.IPFamily, ifi *net.Interface) (*MDNSGameList, error) {
+	f = f.Or(network.FamilyIPv4)
+
+	conn, err := listenUDP(f, ifi)
 	if err != nil {
 		return nil, err
 	}
@@ -61,22 +81,38 @@ func NewMDNSGameList(gv w3gs.GameVersion) (*MDNSGameList, error) {
 	var g = MDNSGameList{
 		GameVersion:       gv,
 		BroadcastInterval: 5 * time.Minute,
+
+		family: f,
+		ifi:    ifi,
 	}
 
 	g.InitDefaultHandlers()
 	g.SetWriteTimeout(time.Second)
 	g.SetConn(conn)
+	g.SetBroadcastAddrs(MulticastGroupsInterface(f, ifi)...)
 
 	return &g, nil
 }
 
+// Replace the (broken) socket with a new one
+func (g *MDNSGameList) reopen() error {
+	conn, err := listenUDP(g.family, g.ifi)
+	if err != nil {
+		return err
+	}
+
+	g.SetConn(conn)
+	return nil
+}
+
 // Games returns the current list of LAN games. Map key is the remote address.
 func (g *MDNSGameList) Games() map[string]w3gs.GameInfo {
 	var res = make(map[string]w3gs.GameInfo)
+	var srcs = make(map[string]string)
 	var now = time.Now()
 
 	g.gmut.Lock()
-	for _, v := range g.games {
+	for k, v := range g.games {
 		if v.GamePort == 0 {
 			continue
 		}
@@ -96,12 +132,22 @@ func (g *MDNSGameList) Games() map[string]w3gs.GameInfo {
 		}
 
 		res[host] = v.GameInfo
+		srcs[host] = k.source
 	}
 	g.gmut.Unlock()
 
+	if g.Policy != nil {
+		res = validateGames(g.GameVersion, g.Policy, res, srcs)
+	}
+
 	return res
 }
 
+// Fire change events for the current output of Games()
+func (g *MDNSGameList) update(expired bool) {
+	g.diff.update(g, g.Games, expired)
+}
+
 // Make sure gmut is locked before calling
 func (g *MDNSGameList) initMap() {
 	if g.games != nil {
@@ -124,7 +170,7 @@ func (g *MDNSGameList) expire() {
 	g.gmut.Unlock()
 
 	if update {
-		g.Fire(Update{})
+		g.update(true)
 	}
 }
 
@@ -194,8 +240,10 @@ func (g *MDNSGameList) runSearch() func() {
 				ticker.Stop()
 				return
 			case <-ticker.C:
-				if err := g.queryAll(); err != nil && !network.IsCloseError(err) {
-					g.Fire(&network.AsyncError{Src: "runSearch[queryAll]", Err: err})
+				if !g.Passive {
+					if err := g.queryAll(); err != nil && !network.IsCloseError(err) {
+						g.Fire(&network.AsyncError{Src: "runSearch[queryAll]", Err: err})
+					}
 				}
 
 				g.expire()
@@ -211,23 +259,31 @@ func (g *MDNSGameList) runSearch() func() {
 // Run reads packets from Conn and emits an event for each received packet
 // Not safe for concurrent invocation
 func (g *MDNSGameList) Run() error {
+	return g.RunContext(context.Background())
+}
+
+// RunContext reads packets from Conn until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (g *MDNSGameList) RunContext(ctx context.Context) error {
 
 	// Query on unicast interface for quick response, listen to multicast interface for quick updates
-	if m, err := net.ListenMulticastUDP("udp4", nil, &MulticastGroup); err == nil {
-		var conn4 = ipv4.NewPacketConn(m)
-		conn4.SetMulticastLoopback(true)
-		conn4.SetMulticastTTL(255)
+	for _, group := range MulticastGroupsInterface(g.family, g.ifi) {
+		m, err := listenMulticast(group, g.ifi)
+		if err != nil {
+			g.Fire(&network.AsyncError{Src: "Run[ListenMulticastUDP]", Err: err})
+			continue
+		}
 
 		var mc = NewDNSPacketConn(m)
 		defer mc.Close()
 
-		go mc.Run(&g.EventEmitter, network.NoTimeout)
-	} else {
-		g.Fire(&network.AsyncError{Src: "Run[ListenMulticastUDP]", Err: err})
+		go mc.RunContext(ctx, &g.EventEmitter, network.NoTimeout)
 	}
 
-	if err := g.queryAll(); err != nil {
-		return err
+	if !g.Passive {
+		if err := g.queryAll(); err != nil {
+			return err
+		}
 	}
 
 	if g.BroadcastInterval > 0 {
@@ -235,7 +291,15 @@ func (g *MDNSGameList) Run() error {
 		defer stop()
 	}
 
-	return g.DNSPacketConn.Run(&g.EventEmitter, network.NoTimeout)
+	if g.Logger != nil {
+		if g.Passive {
+			g.Logger.Info("Listening for games")
+		} else {
+			g.Logger.Info("Searching for games")
+		}
+	}
+
+	return g.DNSPacketConn.RunContext(ctx, &g.EventEmitter, network.NoTimeout)
 }
 
 func (g *MDNSGameList) processPTR(msg *dns.PTR, addr net.Addr) {
@@ -393,7 +457,11 @@ func (g *MDNSGameList) onDNS(ev *network.Event) {
 	g.gmut.Unlock()
 
 	if update {
-		g.Fire(Update{})
+		g.update(false)
+	}
+
+	if g.Passive {
+		return
 	}
 
 	// Query extra info for PTR records without game info in response
@@ -5,10 +5,17 @@
 package lan_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/miekg/dns"
+
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/network/lan"
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
@@ -44,6 +51,23 @@ func testGameList(t *testing.T, g lan.GameList, gv w3gs.GameVersion) {
 	g.On(lan.Update{}, func(ev *network.Event) {
 		atomic.AddInt32(&updates, 1)
 	})
+
+	var added, updated, removed int32
+	g.On(&lan.GameAdded{}, func(ev *network.Event) {
+		atomic.AddInt32(&added, 1)
+	})
+	g.On(&lan.GameUpdated{}, func(ev *network.Event) {
+		if ev.Arg.(*lan.GameUpdated).Game.SlotsUsed != info.SlotsUsed+1 {
+			t.Fatal("Expected updated slots")
+		}
+		atomic.AddInt32(&updated, 1)
+	})
+	g.On(&lan.GameRemoved{}, func(ev *network.Event) {
+		if ev.Arg.(*lan.GameRemoved).Expired {
+			t.Fatal("Unexpected expiry")
+		}
+		atomic.AddInt32(&removed, 1)
+	})
 	g.On(&network.AsyncError{}, func(ev *network.Event) {
 		t.Fatal(ev.Arg.(*network.AsyncError))
 	})
@@ -119,6 +143,9 @@ func testGameList(t *testing.T, g lan.GameList, gv w3gs.GameVersion) {
 	if atomic.LoadInt32(&updates) != 5 {
 		t.Fatal("Update{} not fired after second decreate")
 	}
+	if atomic.LoadInt32(&added) != 2 || atomic.LoadInt32(&updated) != 1 || atomic.LoadInt32(&removed) != 2 {
+		t.Fatal("Unexpected change events", added, updated, removed)
+	}
 	if len(g.Games()) != 0 {
 		t.Fatal("Game found after second decreate")
 	}
@@ -176,3 +203,599 @@ func TestMDNS(t *testing.T) {
 
 	testGameList(t, g, gv)
 }
+
+func TestReconnector(t *testing.T) {
+	var gv = w3gs.GameVersion{
+		Product: w3gs.ProductTFT,
+		Version: 26,
+	}
+
+	g, err := lan.NewUDPGameList(gv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	var r = lan.NewReconnector(g, network.ReconnectConfig{MinDelay: time.Millisecond})
+	var run = r.Serve
+
+	var first = g.Conn()
+	var serves int
+	r.Serve = func(ctx context.Context) error {
+		serves++
+		if serves == 1 {
+			first.Close()
+			return errors.New("broken socket")
+		}
+		if g.Conn() == first {
+			t.Fatal("Expected socket to be reopened")
+		}
+		return nil
+	}
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if serves != 2 {
+		t.Fatal("Expected 2 serves, got", serves)
+	}
+
+	// Closing the game list stops the reconnector
+	r.Serve = run
+	g.Close()
+	if err := r.Run(); !network.IsCloseError(err) {
+		t.Fatal("Expected close error, got", err)
+	}
+}
+
+func TestMDNSDualStack(t *testing.T) {
+	var info = gameInfo
+	info.GameVersion = w3gs.GameVersion{
+		Product: w3gs.ProductTFT,
+		Version: 10030,
+	}
+
+	a, err := lan.NewMDNSAdvertiserFamily(&info, network.FamilyDual)
+	if err != nil {
+		t.Skip("Dual-stack multicast unavailable: ", err)
+	}
+	if err := a.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Create(); !network.IsCloseError(err) {
+		t.Fatal("Expected close error after Close(), got", err)
+	}
+}
+
+func TestNewGameList(t *testing.T) {
+	g, err := lan.NewGameList(w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*lan.UDPGameList); !ok {
+		t.Fatal("Expected UDPGameList for classic versions")
+	}
+	g.Close()
+
+	g, err = lan.NewGameList(w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 10032})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*lan.MDNSGameList); !ok {
+		t.Fatal("Expected MDNSGameList for Reforged versions")
+	}
+	g.Close()
+}
+
+func TestMDNSInterface(t *testing.T) {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ifi *net.Interface
+	for i := range ifs {
+		if ifs[i].Flags&net.FlagUp != 0 && ifs[i].Flags&net.FlagMulticast != 0 {
+			ifi = &ifs[i]
+			break
+		}
+	}
+	if ifi == nil {
+		t.Skip("No multicast interface")
+	}
+
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 10032}
+	a, err := lan.NewMDNSAdvertiserInterface(&w3gs.GameInfo{GameVersion: gv}, network.FamilyIPv6, ifi)
+	if err != nil {
+		t.Skip("IPv6 multicast unavailable:", err)
+	}
+	defer a.Close()
+
+	g, err := lan.NewMDNSGameListInterface(gv, network.FamilyIPv6, ifi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	// Queries and announcements leave through the selected interface
+	if _, err := a.Broadcast(&dns.Msg{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Broadcast(&dns.Msg{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUDPMultiGame(t *testing.T) {
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+	var info = gameInfo
+	info.GameVersion = gv
+
+	a, err := lan.NewUDPAdvertiser(&info, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var info2 = info
+	info2.HostCounter = 2
+	info2.GamePort = 6113
+	if err := a.AddGame(&info2); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddGame(&info2); err != lan.ErrDuplicateGame {
+		t.Fatal("Expected ErrDuplicateGame, got", err)
+	}
+	if err := a.RefreshGame(2, 3, 12); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RefreshGame(3, 3, 12); err != lan.ErrUnknownGame {
+		t.Fatal("Expected ErrUnknownGame, got", err)
+	}
+
+	go a.Run()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{GameVersion: gv.Version})
+	defer client.Close()
+
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: a.Conn().LocalAddr().(*net.UDPAddr).Port}
+	if _, err := client.Send(dst, &w3gs.SearchGame{GameVersion: gv}); err != nil {
+		t.Fatal(err)
+	}
+
+	var games = map[uint32]uint32{}
+	for len(games) < 2 {
+		pkt, _, err := client.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gi, ok := pkt.(*w3gs.GameInfo); ok {
+			games[gi.HostCounter] = gi.SlotsUsed
+		}
+	}
+	if games[2] != 3 {
+		t.Fatal("Expected refreshed slots for second game", games)
+	}
+
+	if err := a.RemoveGame(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RemoveGame(2); err != lan.ErrUnknownGame {
+		t.Fatal("Expected ErrUnknownGame, got", err)
+	}
+}
+
+func TestUDPMultiVersion(t *testing.T) {
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+	var info = gameInfo
+	info.GameVersion = gv
+
+	a, err := lan.NewUDPAdvertiser(&info, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var gv28 = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 28}
+	if err := a.AddVersion(gv28); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddVersion(gv28); err != lan.ErrDuplicateGame {
+		t.Fatal("Expected ErrDuplicateGame, got", err)
+	}
+	if err := a.Refresh(5, 12); err != nil {
+		t.Fatal(err)
+	}
+
+	go a.Run()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{})
+	defer client.Close()
+
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: a.Conn().LocalAddr().(*net.UDPAddr).Port}
+	for _, v := range []w3gs.GameVersion{gv, gv28} {
+		if _, err := client.Send(dst, &w3gs.SearchGame{GameVersion: v}); err != nil {
+			t.Fatal(err)
+		}
+		pkt, _, err := client.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gi, ok := pkt.(*w3gs.GameInfo); !ok || gi.GameVersion != v || gi.HostCounter != info.HostCounter || gi.SlotsUsed != 5 {
+			t.Fatal("Unexpected reply", pkt)
+		}
+		if _, _, err := client.NextPacket(50 * time.Millisecond); !network.IsTimeout(err) {
+			t.Fatal("Expected one reply per version, got", err)
+		}
+	}
+}
+
+func TestUDPThrottle(t *testing.T) {
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+	var info = gameInfo
+	info.GameVersion = gv
+
+	a, err := lan.NewUDPAdvertiser(&info, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	a.ResponseInterval = 200 * time.Millisecond
+	go a.Run()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{GameVersion: gv.Version})
+	defer client.Close()
+
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: a.Conn().LocalAddr().(*net.UDPAddr).Port}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Send(dst, &w3gs.SearchGame{GameVersion: gv}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := client.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.NextPacket(50 * time.Millisecond); !network.IsTimeout(err) {
+		t.Fatal("Expected throttled response, got", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if _, err := client.Send(dst, &w3gs.SearchGame{GameVersion: gv}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdvertiserState(t *testing.T) {
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+	var info = gameInfo
+	info.GameVersion = gv
+
+	a, err := lan.NewUDPAdvertiser(&info, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var events = make(chan interface{}, 16)
+	for _, e := range []interface{}{&lan.GameCreated{}, &lan.GameRefreshed{}, &lan.GameProbed{}, &lan.GameDecreated{}} {
+		a.On(e, func(ev *network.Event) {
+			events <- ev.Arg
+		})
+	}
+	var expect = func(e interface{}) {
+		select {
+		case ev := <-events:
+			if fmt.Sprintf("%T", ev) != fmt.Sprintf("%T", e) {
+				t.Fatalf("Expected %T, got %T", e, ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %T", e)
+		}
+	}
+
+	if s := a.State(); s.Created || s.Running || s.Game.HostCounter != info.HostCounter {
+		t.Fatal("Unexpected initial state", s)
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan error)
+	go func() { done <- a.RunContext(ctx) }()
+
+	expect(&lan.GameCreated{})
+	if s := a.State(); !s.Created || !s.Running {
+		t.Fatal("Expected created and running state", s)
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{GameVersion: gv.Version})
+	defer client.Close()
+
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: a.Conn().LocalAddr().(*net.UDPAddr).Port}
+	if _, err := client.Send(dst, &w3gs.SearchGame{GameVersion: gv}); err != nil {
+		t.Fatal(err)
+	}
+	expect(&lan.GameProbed{})
+	if s := a.State(); s.Probes != 1 || s.LastProbe.IsZero() {
+		t.Fatal("Expected probe in state", s)
+	}
+
+	if err := a.Refresh(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	expect(&lan.GameRefreshed{})
+	if s := a.State(); s.Game.SlotsUsed != 2 {
+		t.Fatal("Expected refreshed slots in state", s)
+	}
+
+	cancel()
+	<-done
+	expect(&lan.GameDecreated{})
+	if s := a.State(); s.Created || s.Running {
+		t.Fatal("Expected stopped state", s)
+	}
+
+	// Decreate is only reported once
+	a.Close()
+	select {
+	case ev := <-events:
+		t.Fatalf("Unexpected event %T", ev)
+	default:
+	}
+}
+
+func TestUDPPassive(t *testing.T) {
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var host = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{GameVersion: gv.Version})
+	defer host.Close()
+
+	g, err := lan.NewUDPGameList(gv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	g.Passive = true
+	g.BroadcastInterval = 10 * time.Millisecond
+	g.SetBroadcastAddrs(conn.LocalAddr().(*net.UDPAddr))
+
+	var added = make(chan struct{}, 1)
+	g.On(&lan.GameAdded{}, func(ev *network.Event) {
+		added <- struct{}{}
+	})
+	go g.Run()
+
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: g.Conn().LocalAddr().(*net.UDPAddr).Port}
+	if _, err := host.Send(dst, &w3gs.CreateGame{GameVersion: gv, HostCounter: gameInfo.HostCounter}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.Send(dst, &w3gs.RefreshGame{HostCounter: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if pkt, _, err := host.NextPacket(5 * wait); !network.IsTimeout(err) {
+		t.Fatal("Expected no traffic from passive list, got", pkt, err)
+	}
+
+	var info = gameInfo
+	info.GameVersion = gv
+	if _, err := host.Send(dst, &info); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("Announced game not listed")
+	}
+}
+
+func TestValidateGame(t *testing.T) {
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+	var info = gameInfo
+	info.GameVersion = gv
+
+	if err := lan.ValidateGame(gv, "127.0.0.1:6112", "127.0.0.1:6112", &info); err != nil {
+		t.Fatal(err)
+	}
+	if err := lan.ValidateGame(gv, "[::1]:6112", "127.0.0.1:6112", &info); err != nil {
+		t.Fatal("Expected families to be skipped, got", err)
+	}
+	if err := lan.ValidateGame(gv, "127.0.0.2:6112", "127.0.0.1:6112", &info); err != lan.ErrSourceMismatch {
+		t.Fatal("Expected ErrSourceMismatch, got", err)
+	}
+
+	var ver = info
+	ver.GameVersion.Product = w3gs.ProductROC
+	if err := lan.ValidateGame(gv, "127.0.0.1:6112", "127.0.0.1:6112", &ver); err != lan.ErrInvalidVersion {
+		t.Fatal("Expected ErrInvalidVersion, got", err)
+	}
+
+	var slots = info
+	slots.SlotsUsed = 25
+	if err := lan.ValidateGame(gv, "127.0.0.1:6112", "127.0.0.1:6112", &slots); err != lan.ErrInvalidInfo {
+		t.Fatal("Expected ErrInvalidInfo, got", err)
+	}
+
+	var path = info
+	path.GameSettings.MapPath = ""
+	if err := lan.ValidateGame(gv, "127.0.0.1:6112", "127.0.0.1:6112", &path); err != lan.ErrInvalidInfo {
+		t.Fatal("Expected ErrInvalidInfo, got", err)
+	}
+}
+
+func TestUDPPolicy(t *testing.T) {
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+
+	g, err := lan.NewUDPGameList(gv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	var mut sync.Mutex
+	var rejected = map[error]bool{}
+	g.Policy = func(err error, addr string, game *w3gs.GameInfo) bool {
+		mut.Lock()
+		rejected[err] = true
+		mut.Unlock()
+		return false
+	}
+
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: g.Conn().LocalAddr().(*net.UDPAddr).Port}
+	var send = func(info *w3gs.GameInfo) {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{GameVersion: gv.Version})
+		defer c.Close()
+
+		if _, err := c.Send(dst, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	go g.Run()
+
+	var info = gameInfo
+	info.GameVersion = gv
+	info.UptimeSec = 10
+	send(&info)
+
+	// Same host counter and entry key from another host
+	var spoof = info
+	spoof.UptimeSec = 0
+	spoof.GamePort = 6113
+	send(&spoof)
+
+	var bad = info
+	bad.HostCounter = 2
+	bad.GameName = ""
+	bad.GamePort = 6114
+	send(&bad)
+
+	time.Sleep(wait)
+
+	var games = g.Games()
+	if len(games) != 1 {
+		t.Fatal("Expected a single valid game, got", games)
+	}
+	for _, v := range games {
+		if v.GamePort != info.GamePort {
+			t.Fatal("Expected oldest game to be kept, got", v)
+		}
+	}
+
+	mut.Lock()
+	defer mut.Unlock()
+	if !rejected[lan.ErrDuplicateHost] || !rejected[lan.ErrInvalidInfo] {
+		t.Fatal("Expected ErrDuplicateHost and ErrInvalidInfo, got", rejected)
+	}
+}
+
+func TestMultiGameList(t *testing.T) {
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+	var info = gameInfo
+	info.GameVersion = gv
+
+	a, err := lan.NewUDPAdvertiser(&info, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	go a.Run()
+
+	g1, err := lan.NewUDPGameList(gv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := lan.NewUDPGameList(gv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m = lan.NewMultiGameList(g1, g2)
+	defer m.Close()
+
+	var added = make(chan *lan.GameAdded, 2)
+	m.On(&lan.GameAdded{}, func(ev *network.Event) {
+		added <- ev.Arg.(*lan.GameAdded)
+	})
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan error)
+	go func() { done <- m.RunContext(ctx) }()
+
+	// Point second list at the advertiser directly, events are forwarded
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: a.Conn().LocalAddr().(*net.UDPAddr).Port}
+	if _, err := g2.Send(dst, &w3gs.SearchGame{GameVersion: gv}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-added:
+		if ev.Game.HostCounter != info.HostCounter {
+			t.Fatal("Unexpected game", ev.Game)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GameAdded not forwarded")
+	}
+	if len(m.Games()) != 1 {
+		t.Fatal("Expected 1 game, got", m.Games())
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	ifs, err := lan.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ifi := range ifs {
+		if ifi.Flags&net.FlagLoopback != 0 || ifi.Flags&net.FlagUp == 0 {
+			t.Fatal("Unexpected interface", ifi)
+		}
+	}
+
+	var info = gameInfo
+	info.GameVersion = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+
+	a, err := lan.NewAdvertiserInterfaces(&info, network.FamilyIPv4, ifs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Advertisers()) != len(ifs) {
+		t.Fatal("Expected one advertiser per interface")
+	}
+	a.Close()
+}
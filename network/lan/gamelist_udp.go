@@ -5,6 +5,7 @@
 package lan
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
@@ -26,27 +27,69 @@ type udpRecord struct {
 }
 
 // UDPGameList keeps track of all the hosted games in the Local Area Network using UDP broadcast
-// Emits events for every received packet and Update{} when the output of Games() changes
+// Emits events for every received packet, GameAdded/GameUpdated/GameRemoved for every changed game
+// and Update{} when the output of Games() changes
+// Passive lists only see games announced with GameInfo, bind port 6112 to receive broadcasts
 // Public methods/fields are thread-safe unless explicitly stated otherwise
 type UDPGameList struct {
 	network.EventEmitter
 	network.W3GSPacketConn
 
+	diff  gameDiff
 	gmut  sync.Mutex
 	games map[udpIndex]*udpRecord
+	open  func() (net.PacketConn, error)
 
 	// Set once before Run(), read-only after that
 	GameVersion       w3gs.GameVersion
 	BroadcastInterval time.Duration
+	Policy            Policy // Validate games, decides whether invalid games are listed (nil to disable validation)
+	Passive           bool   // Only listen for announcements, never send search queries
+	Logger            network.Logger
 }
 
 // NewUDPGameList opens a new UDP socket to listen for LAN GameList updates
 func NewUDPGameList(gv w3gs.GameVersion, port int) (*UDPGameList, error) {
-	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	return NewUDPGameListFamily(gv, network.FamilyIPv4, port)
+}
+
+// NewUDPGameListFamily opens a new UDP socket for IP family f to listen for LAN GameList updates
+func NewUDPGameListFamily(gv w3gs.GameVersion, f network.IPFamily, port int) (*UDPGameList, error) {
+	return NewUDPGameListInterface(gv, f, nil, port)
+}
+
+// NewUDPGameListInterface opens a new UDP socket for IP family f to listen for LAN GameList updates, broadcasts on interface ifi
+func NewUDPGameListInterface(gv w3gs.GameVersion, f network.IPFamily, ifi *net.Interface, port int) (*UDPGameList, error) {
+	var open = func() (net.PacketConn, error) {
+		return network.ListenUDP(f, port)
+	}
+
+	conn, err := open()
 	if err != nil {
 		return nil, err
 	}
 
+	var g = newUDPGameList(gv, conn, f)
+	g.open = open
+	g.SetBroadcastAddrs(network.W3GSBroadcastAddrsInterface(f, ifi)...)
+	return g, nil
+}
+
+// NewUDPGameListMux initializes UDPGameList struct on an endpoint of the shared socket m
+func NewUDPGameListMux(gv w3gs.GameVersion, m *network.UDPMux) *UDPGameList {
+	var open = func() (net.PacketConn, error) {
+		return m.Endpoint(network.UDPFilter{
+			Types: []uint8{w3gs.PidGameInfo, w3gs.PidCreateGame, w3gs.PidRefreshGame, w3gs.PidDecreateGame},
+		}), nil
+	}
+
+	var conn, _ = open()
+	var g = newUDPGameList(gv, conn, m.Family())
+	g.open = open
+	return g
+}
+
+func newUDPGameList(gv w3gs.GameVersion, conn net.PacketConn, f network.IPFamily) *UDPGameList {
 	var g = UDPGameList{
 		GameVersion:       gv,
 		BroadcastInterval: 15 * time.Second,
@@ -55,8 +98,20 @@ func NewUDPGameList(gv w3gs.GameVersion, port int) (*UDPGameList, error) {
 	g.InitDefaultHandlers()
 	g.SetWriteTimeout(time.Second)
 	g.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), g.Encoding())
+	g.SetBroadcastAddrs(network.W3GSBroadcastAddrs(f)...)
 
-	return &g, nil
+	return &g
+}
+
+// Replace the (broken) socket with a new one
+func (g *UDPGameList) reopen() error {
+	conn, err := g.open()
+	if err != nil {
+		return err
+	}
+
+	g.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), g.Encoding())
+	return nil
 }
 
 //Encoding for w3gs packets
@@ -69,6 +124,7 @@ func (g *UDPGameList) Encoding() w3gs.Encoding {
 // Games returns the current list of LAN games. Map key is the remote address.
 func (g *UDPGameList) Games() map[string]w3gs.GameInfo {
 	var res = make(map[string]w3gs.GameInfo)
+	var srcs = make(map[string]string)
 	var now = time.Now()
 
 	g.gmut.Lock()
@@ -88,12 +144,22 @@ func (g *UDPGameList) Games() map[string]w3gs.GameInfo {
 		}
 
 		res[host] = v.GameInfo
+		srcs[host] = k.source
 	}
 	g.gmut.Unlock()
 
+	if g.Policy != nil {
+		res = validateGames(g.GameVersion, g.Policy, res, srcs)
+	}
+
 	return res
 }
 
+// Fire change events for the current output of Games()
+func (g *UDPGameList) update(expired bool) {
+	g.diff.update(g, g.Games, expired)
+}
+
 // Make sure gmut is locked before calling
 func (g *UDPGameList) initMap() {
 	if g.games != nil {
@@ -116,7 +182,7 @@ func (g *UDPGameList) expire() {
 	g.gmut.Unlock()
 
 	if update {
-		g.Fire(Update{})
+		g.update(true)
 	}
 }
 
@@ -139,8 +205,10 @@ func (g *UDPGameList) runSearch(sg *w3gs.SearchGame) func() {
 				ticker.Stop()
 				return
 			case <-ticker.C:
-				if _, err := g.Broadcast(sg); err != nil && !network.IsCloseError(err) {
-					g.Fire(&network.AsyncError{Src: "runSearch[Broadcast]", Err: err})
+				if !g.Passive {
+					if _, err := g.Broadcast(sg); err != nil && !network.IsCloseError(err) {
+						g.Fire(&network.AsyncError{Src: "runSearch[Broadcast]", Err: err})
+					}
 				}
 
 				g.expire()
@@ -156,12 +224,22 @@ func (g *UDPGameList) runSearch(sg *w3gs.SearchGame) func() {
 // Run reads packets from Conn and emits an event for each received packet
 // Not safe for concurrent invocation
 func (g *UDPGameList) Run() error {
+	return g.RunContext(context.Background())
+}
+
+// RunContext reads packets from Conn until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (g *UDPGameList) RunContext(ctx context.Context) error {
+	g.SetLogger(g.Logger)
+
 	var sg = w3gs.SearchGame{
 		GameVersion: g.GameVersion,
 	}
 
-	if _, err := g.Broadcast(&sg); err != nil {
-		return err
+	if !g.Passive {
+		if _, err := g.Broadcast(&sg); err != nil {
+			return err
+		}
 	}
 
 	if g.BroadcastInterval > 0 {
@@ -169,7 +247,15 @@ func (g *UDPGameList) Run() error {
 		defer stop()
 	}
 
-	return g.W3GSPacketConn.Run(&g.EventEmitter, network.NoTimeout)
+	if g.Logger != nil {
+		if g.Passive {
+			g.Logger.Info("Listening for games")
+		} else {
+			g.Logger.Info("Searching for games")
+		}
+	}
+
+	return g.W3GSPacketConn.RunContext(ctx, &g.EventEmitter, network.NoTimeout)
 }
 
 func (g *UDPGameList) onRefreshGame(ev *network.Event) {
@@ -197,7 +283,7 @@ func (g *UDPGameList) onRefreshGame(ev *network.Event) {
 		g.gmut.Unlock()
 
 		if update {
-			g.Fire(Update{})
+			g.update(false)
 		}
 
 		return
@@ -209,6 +295,10 @@ func (g *UDPGameList) onRefreshGame(ev *network.Event) {
 	}
 	g.gmut.Unlock()
 
+	if g.Passive {
+		return
+	}
+
 	var sg = w3gs.SearchGame{
 		GameVersion: g.GameVersion,
 		HostCounter: pkt.HostCounter,
@@ -235,7 +325,7 @@ func (g *UDPGameList) onCreateGame(ev *network.Event) {
 	}
 	g.gmut.Unlock()
 
-	if pkt.GameVersion != g.GameVersion {
+	if pkt.GameVersion != g.GameVersion || g.Passive {
 		return
 	}
 
@@ -268,7 +358,7 @@ func (g *UDPGameList) onDecreateGame(ev *network.Event) {
 	g.gmut.Unlock()
 
 	if update {
-		g.Fire(Update{})
+		g.update(false)
 	}
 }
 
@@ -302,6 +392,6 @@ func (g *UDPGameList) onGameInfo(ev *network.Event) {
 	g.gmut.Unlock()
 
 	if update {
-		g.Fire(Update{})
+		g.update(false)
 	}
 }
@@ -0,0 +1,218 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// GatewaySource answers bncs.GetAdvListReq with bncs.GetAdvListResp events, i.e. a logged on bnet.Client
+type GatewaySource interface {
+	network.Listener
+	Send(pkt bncs.Packet) (int, error)
+}
+
+// Gateway re-advertises games listed by a Battle.net server (SID_GETADVLISTEX) in the Local Area Network
+// Clients joining a re-advertised game are relayed to the game host
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Gateway struct {
+	network.EventEmitter
+	bridgeGames
+
+	src GatewaySource
+
+	// Set once before Run(), read-only after that
+	GameVersion     w3gs.GameVersion
+	Request         bncs.GetAdvListReq                            // Game list query sent to source
+	RefreshInterval time.Duration                                 // Time between game list queries
+	Filter          func(game *bncs.GetAdvListGame) bool          // Selects games to re-advertise (nil for all)
+	Advertise       func(info *w3gs.GameInfo) (Advertiser, error) // Creates the local advertiser for a game
+	Family          network.IPFamily                              // IP family for the join listener
+	Port            int                                           // Port for the join listener (0 for random)
+	Timeout         time.Duration                                 // Max time to wait for the join packet of a client
+	Logger          network.Logger
+}
+
+// NewGateway initializes a Gateway that queries src for games of version gv
+func NewGateway(src GatewaySource, gv w3gs.GameVersion) *Gateway {
+	return &Gateway{
+		src:         src,
+		GameVersion: gv,
+		Request: bncs.GetAdvListReq{
+			Filter:        w3gs.GameFlagCustomGame,
+			FilterMask:    w3gs.GameFlagTypeMask,
+			NumberOfGames: 20,
+		},
+		RefreshInterval: 5 * time.Second,
+		Advertise:       NewAdvertiser,
+		Timeout:         10 * time.Second,
+	}
+}
+
+// GameInfo converts game (listed by Battle.net) to a LAN game of version gv hosted on port
+func GameInfo(gv w3gs.GameVersion, game *bncs.GetAdvListGame, port uint16) w3gs.GameInfo {
+	var total uint32 = 12
+	if gv.Version == 0 || gv.Version >= 29 {
+		total = 24
+	}
+
+	// Only the number of free slots is listed, assume host is the only player
+	var available = 1 + uint32(game.GameSettings.SlotsFree)
+	if available > total {
+		available = total
+	}
+
+	return w3gs.GameInfo{
+		GameVersion:    gv,
+		HostCounter:    game.GameSettings.HostCounter,
+		GameName:       game.GameName,
+		GameSettings:   game.GameSettings.GameSettings,
+		SlotsTotal:     total,
+		GameFlags:      game.GameFlags,
+		SlotsUsed:      1,
+		SlotsAvailable: available,
+		UptimeSec:      game.UptimeSec,
+		GamePort:       port,
+	}
+}
+
+// Run re-advertises games until ctx is done
+func (g *Gateway) Run() error {
+	return g.RunContext(context.Background())
+}
+
+// RunContext re-advertises games until ctx is done
+// Re-advertised games are decreated once RunContext returns
+func (g *Gateway) RunContext(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	l, err := network.ListenTCP(g.Family, &net.TCPAddr{Port: g.Port})
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	go g.accept(ctx, l)
+
+	var port = uint16(l.Addr().(*net.TCPAddr).Port)
+	var resp = make(chan []bncs.GetAdvListGame, 1)
+
+	var id = g.src.On(&bncs.GetAdvListResp{}, func(ev *network.Event) {
+		var games = append([]bncs.GetAdvListGame(nil), ev.Arg.(*bncs.GetAdvListResp).Games...)
+		select {
+		case resp <- games:
+		case <-ctx.Done():
+		}
+	})
+	defer g.src.Off(id)
+	defer g.removeAll()
+
+	var ticker = time.NewTicker(g.RefreshInterval)
+	defer ticker.Stop()
+
+	var req = g.Request
+	if _, err := g.src.Send(&req); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case games := <-resp:
+			g.update(ctx, games, port)
+		case <-ticker.C:
+			if _, err := g.src.Send(&req); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (g *Gateway) update(ctx context.Context, games []bncs.GetAdvListGame, port uint16) {
+	var listed = make(map[uint32]struct{}, len(games))
+	for i := range games {
+		if g.Filter != nil && !g.Filter(&games[i]) {
+			continue
+		}
+
+		var info = GameInfo(g.GameVersion, &games[i], port)
+		if _, ok := listed[info.HostCounter]; ok {
+			continue
+		}
+
+		listed[info.HostCounter] = struct{}{}
+		g.add(ctx, &g.EventEmitter, g.Advertise, &info, games[i].Addr.TCPAddr().String())
+	}
+
+	for _, hc := range g.keys() {
+		if _, ok := listed[hc]; !ok {
+			g.remove(hc)
+		}
+	}
+}
+
+func (g *Gateway) accept(ctx context.Context, l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			if err := g.join(ctx, conn); err != nil && !network.IsCloseError(err) && ctx.Err() == nil {
+				g.Fire(&network.AsyncError{Src: "accept[join]", Err: err})
+			}
+		}()
+	}
+}
+
+func (g *Gateway) join(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+
+	var dec = w3gs.NewDecoder(w3gs.Encoding{GameVersion: g.GameVersion.Version}, w3gs.DefaultFactory)
+	if g.Timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(g.Timeout))
+	}
+
+	raw, _, err := dec.ReadRaw(conn)
+	if err != nil {
+		return err
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	pkt, _, err := dec.Deserialize(raw)
+	if err != nil {
+		return err
+	}
+
+	j, ok := pkt.(*w3gs.Join)
+	if !ok {
+		return ErrExpectedJoin
+	}
+
+	addr, ok := g.addr(j.HostCounter)
+	if !ok {
+		return ErrUnknownGame
+	}
+
+	host, err := network.DialTCP(ctx, network.FamilyDefault, addr)
+	if err != nil {
+		return err
+	}
+
+	if _, err := host.Write(raw); err != nil {
+		host.Close()
+		return err
+	}
+
+	return relay(ctx, conn, host)
+}
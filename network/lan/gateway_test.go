@@ -0,0 +1,149 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/lan"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+type advListSource struct {
+	network.EventEmitter
+	mut   sync.Mutex
+	games []bncs.GetAdvListGame
+}
+
+func (s *advListSource) set(games ...bncs.GetAdvListGame) {
+	s.mut.Lock()
+	s.games = games
+	s.mut.Unlock()
+}
+
+func (s *advListSource) Send(pkt bncs.Packet) (int, error) {
+	if _, ok := pkt.(*bncs.GetAdvListReq); ok {
+		s.mut.Lock()
+		var resp = bncs.GetAdvListResp{Games: append([]bncs.GetAdvListGame(nil), s.games...)}
+		s.mut.Unlock()
+
+		go s.Fire(&resp)
+	}
+	return 0, nil
+}
+
+func TestGateway(t *testing.T) {
+	// Game host on Battle.net
+	host, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+
+	go func() {
+		conn, err := host.Accept()
+		if err != nil {
+			return
+		}
+		var c = network.NewW3GSConn(conn, nil, w3gs.Encoding{})
+		defer c.Close()
+
+		pkt, err := c.NextPacket(time.Second)
+		if err != nil {
+			return
+		}
+		if j, ok := pkt.(*w3gs.Join); ok {
+			c.Send(&w3gs.Ping{Payload: j.HostCounter})
+		}
+	}()
+
+	var game = bncs.GetAdvListGame{
+		GameFlags: gameInfo.GameFlags,
+		Addr:      protocol.SockAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: uint16(host.Addr().(*net.TCPAddr).Port)},
+		UptimeSec: 10,
+		GameName:  gameInfo.GameName,
+		GameSettings: bncs.GameSettings{
+			SlotsFree:    1,
+			HostCounter:  gameInfo.HostCounter,
+			GameSettings: gameInfo.GameSettings,
+		},
+	}
+	var other = game
+	other.GameName = "filtered"
+	other.GameSettings.HostCounter = 2
+
+	var src advListSource
+	src.set(game, other)
+
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+	var gw = lan.NewGateway(&src, gv)
+	gw.Family = network.FamilyIPv4
+	gw.RefreshInterval = 10 * time.Millisecond
+	gw.Filter = func(g *bncs.GetAdvListGame) bool {
+		return g.GameName != "filtered"
+	}
+
+	var ads = make(chan *bridgeAdvertiser, 2)
+	gw.Advertise = func(info *w3gs.GameInfo) (lan.Advertiser, error) {
+		var a = &bridgeAdvertiser{info: *info, closed: make(chan struct{})}
+		ads <- a
+		return a, nil
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go gw.RunContext(ctx)
+
+	var ad *bridgeAdvertiser
+	select {
+	case ad = <-ads:
+	case <-time.After(time.Second):
+		t.Fatal("Game not advertised")
+	}
+	if ad.info.HostCounter != gameInfo.HostCounter || ad.info.GameName != gameInfo.GameName || ad.info.GameVersion != gv || ad.info.SlotsAvailable != 2 {
+		t.Fatal("Unexpected advertised game", ad.info)
+	}
+
+	// Join through the gateway
+	conn, err := network.DialTCP(ctx, network.FamilyIPv4, fmt.Sprintf("127.0.0.1:%d", ad.info.GamePort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = network.NewW3GSConn(conn, nil, w3gs.Encoding{})
+	defer client.Close()
+
+	if _, err := client.Send(&w3gs.Join{HostCounter: gameInfo.HostCounter, PlayerName: "client"}); err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := client.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := pkt.(*w3gs.Ping); !ok || p.Payload != gameInfo.HostCounter {
+		t.Fatal("Unexpected reply from host", pkt)
+	}
+
+	// Games no longer listed are decreated
+	src.set()
+	select {
+	case <-ad.closed:
+	case <-time.After(time.Second):
+		t.Fatal("Game not removed")
+	}
+
+	select {
+	case a := <-ads:
+		t.Fatal("Filtered game advertised", a.info)
+	default:
+	}
+}
@@ -7,54 +7,295 @@ package lan
 
 import (
 	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
 
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
+// Errors
+var (
+	ErrDuplicateGame  = errors.New("lan: Game with same host counter already advertised")
+	ErrUnknownGame    = errors.New("lan: Game not advertised")
+	ErrBridgeProtocol = errors.New("lan: Unexpected packet in bridge tunnel")
+	ErrExpectedJoin   = errors.New("lan: Expected join as first packet")
+	ErrSourceMismatch = errors.New("lan: Game address does not match source address")
+	ErrInvalidVersion = errors.New("lan: Unexpected game version")
+	ErrInvalidInfo    = errors.New("lan: Malformed game info")
+	ErrDuplicateHost  = errors.New("lan: Game with same host counter and entry key announced by other host")
+)
+
 // Update event for GameList changes
 type Update struct{}
 
+// GameAdded event, fired when a game appears in the output of Games()
+type GameAdded struct {
+	Addr string
+	Game w3gs.GameInfo
+}
+
+// GameUpdated event, fired when the info of a game in the output of Games() changes
+type GameUpdated struct {
+	Addr string
+	Game w3gs.GameInfo
+}
+
+// GameRemoved event, fired when a game disappears from the output of Games()
+type GameRemoved struct {
+	Addr    string
+	Game    w3gs.GameInfo
+	Expired bool // Game was not refreshed in time (as opposed to explicitly decreated)
+}
+
+// GameCreated event, fired when an Advertiser announced its game
+type GameCreated struct {
+	Game w3gs.GameInfo
+}
+
+// GameRefreshed event, fired when an Advertiser announced new slot counts for its game
+type GameRefreshed struct {
+	Game w3gs.GameInfo
+}
+
+// GameProbed event, fired when an Advertiser answered a search query from Addr
+type GameProbed struct {
+	Addr net.Addr
+	Game w3gs.GameInfo
+}
+
+// GameDecreated event, fired when an Advertiser withdrew its game
+type GameDecreated struct {
+	Game w3gs.GameInfo
+}
+
+// AdvertiserState is a snapshot of the state of an Advertiser
+type AdvertiserState struct {
+	Game      w3gs.GameInfo // Advertised game
+	Created   bool          // Game is created and not yet decreated
+	Running   bool          // Run() is active
+	Probes    uint64        // Number of answered search queries
+	LastProbe time.Time     // Time of the last answered search query
+}
+
+// advState tracks the lifecycle of an Advertiser and fires the related events
+type advState struct {
+	smut  sync.Mutex
+	state AdvertiserState
+}
+
+func (s *advState) create(e *network.EventEmitter, info *w3gs.GameInfo) {
+	s.smut.Lock()
+	s.state.Created = true
+	s.smut.Unlock()
+
+	e.Fire(&GameCreated{Game: *info})
+}
+
+func (s *advState) refresh(e *network.EventEmitter, info *w3gs.GameInfo) {
+	e.Fire(&GameRefreshed{Game: *info})
+}
+
+func (s *advState) probe(e *network.EventEmitter, addr net.Addr, info *w3gs.GameInfo) {
+	s.smut.Lock()
+	s.state.Probes++
+	s.state.LastProbe = time.Now()
+	s.smut.Unlock()
+
+	e.Fire(&GameProbed{Addr: addr, Game: *info})
+}
+
+func (s *advState) decreate(e *network.EventEmitter, info *w3gs.GameInfo) {
+	s.smut.Lock()
+	var created = s.state.Created
+	s.state.Created = false
+	s.smut.Unlock()
+
+	if created {
+		e.Fire(&GameDecreated{Game: *info})
+	}
+}
+
+func (s *advState) run(running bool) {
+	s.smut.Lock()
+	s.state.Running = running
+	s.smut.Unlock()
+}
+
+func (s *advState) get(info *w3gs.GameInfo) AdvertiserState {
+	s.smut.Lock()
+	var res = s.state
+	s.smut.Unlock()
+
+	res.Game = *info
+	return res
+}
+
 // GameList keeps track of all the hosted games in the Local Area Network
-// Emits events for every received packet and Update{} when the output of Games() changes
+// Emits events for every received packet, GameAdded/GameUpdated/GameRemoved for every changed game
+// and Update{} when the output of Games() changes. Games() returns a snapshot that is safe to keep.
 type GameList interface {
 	network.Listener
 	Games() map[string]w3gs.GameInfo
 	Run() error
+	RunContext(ctx context.Context) error
 	Close() error
 }
 
+// UsesMDNS reports whether game version gv discovers LAN games using mDNS (Reforged) instead of UDP broadcast
+// Unknown versions (0) are assumed to be Reforged
+func UsesMDNS(gv w3gs.GameVersion) bool {
+	return gv.Version == 0 || gv.Version >= 30
+}
+
 // NewGameList initializes proper GameList type for game version
 func NewGameList(gv w3gs.GameVersion) (GameList, error) {
-	if gv.Version > 0 && gv.Version < 30 {
+	return NewGameListFamily(gv, network.FamilyIPv4)
+}
+
+// NewGameListFamily initializes proper GameList type for game version using IP family f
+func NewGameListFamily(gv w3gs.GameVersion, f network.IPFamily) (GameList, error) {
+	if !UsesMDNS(gv) {
 		// Use random port to not occupy port 6112 by default
-		return NewUDPGameList(gv, 0)
+		return NewUDPGameListFamily(gv, f, 0)
 	}
 
-	return NewMDNSGameList(gv)
+	return NewMDNSGameListFamily(gv, f)
 }
 
 // Advertiser broadcasts available game information to the Local Area Network
 // Emits events for every received packet, responds to search queries
+// Emits GameCreated/GameRefreshed/GameProbed/GameDecreated during the lifecycle of the game
 type Advertiser interface {
 	network.Listener
 
 	Create() error
 	Refresh(slotsUsed uint32, slotsAvailable uint32) error
 	Decreate() error
+	State() AdvertiserState
 
 	Run() error
+	RunContext(ctx context.Context) error
 	Close() error
 }
 
 // NewAdvertiser initializes proper Advertiser type for game version
 func NewAdvertiser(info *w3gs.GameInfo) (Advertiser, error) {
-	if info.GameVersion.Version > 0 && info.GameVersion.Version < 30 {
+	return NewAdvertiserFamily(info, network.FamilyIPv4)
+}
+
+// NewAdvertiserFamily initializes proper Advertiser type for game version using IP family f
+func NewAdvertiserFamily(info *w3gs.GameInfo, f network.IPFamily) (Advertiser, error) {
+	if !UsesMDNS(info.GameVersion) {
 		// Use random port to not occupy port 6112 by default
-		return NewUDPAdvertiser(info, 0)
+		return NewUDPAdvertiserFamily(info, f, 0)
+	}
+
+	return NewMDNSAdvertiserFamily(info, f)
+}
+
+// gameDiff compares successive outputs of Games() to emit typed change events
+type gameDiff struct {
+	mut  sync.Mutex
+	last map[string]w3gs.GameInfo
+}
+
+// Changes in UptimeSec alone are not reported
+func sameGame(a w3gs.GameInfo, b w3gs.GameInfo) bool {
+	a.UptimeSec = 0
+	b.UptimeSec = 0
+	return a == b
+}
+
+// update diffs games() against the previous snapshot and fires change events followed by Update{}
+func (d *gameDiff) update(e network.Emitter, snapshot func() map[string]w3gs.GameInfo, expired bool) {
+	d.mut.Lock()
+	var games = snapshot()
+	var last = d.last
+	d.last = games
+
+	var evs []network.EventArg
+	for addr, old := range last {
+		if _, ok := games[addr]; !ok {
+			evs = append(evs, &GameRemoved{Addr: addr, Game: old, Expired: expired})
+		}
+	}
+	for addr, game := range games {
+		old, ok := last[addr]
+		if !ok {
+			evs = append(evs, &GameAdded{Addr: addr, Game: game})
+		} else if !sameGame(old, game) {
+			evs = append(evs, &GameUpdated{Addr: addr, Game: game})
+		}
+	}
+
+	// Fire while holding mut so that events of concurrent updates are not interleaved
+	for _, ev := range evs {
+		e.Fire(ev)
+	}
+	e.Fire(Update{})
+	d.mut.Unlock()
+}
+
+// throttle limits the rate of responses per peer
+type throttle struct {
+	mut  sync.Mutex
+	last map[string]time.Time
+}
+
+// allow reports whether a response to addr may be sent, at most once per interval (0 to disable)
+func (t *throttle) allow(addr net.Addr, interval time.Duration) bool {
+	if interval <= 0 {
+		return true
 	}
 
-	return NewMDNSAdvertiser(info)
+	var now = time.Now()
+	var key = addr.String()
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if last, ok := t.last[key]; ok && now.Sub(last) < interval {
+		return false
+	}
+
+	if t.last == nil {
+		t.last = make(map[string]time.Time)
+	} else if len(t.last) >= 256 {
+		for k, v := range t.last {
+			if now.Sub(v) >= interval {
+				delete(t.last, k)
+			}
+		}
+	}
+
+	t.last[key] = now
+	return true
+}
+
+// reopener is implemented by GameLists and Advertisers that can replace their socket
+type reopener interface {
+	reopen() error
+}
+
+// NewReconnector returns a Reconnector that restarts r (a GameList or Advertiser) whenever it fails
+// The socket of r is reopened before every restart. Closing r stops the Reconnector
+func NewReconnector(r interface {
+	RunContext(ctx context.Context) error
+}, conf network.ReconnectConfig) *network.Reconnector {
+	var res = network.NewReconnector(conf, nil, r.RunContext)
+	res.Emitter, _ = r.(network.Emitter)
+	res.Retry = func(err error) bool {
+		return !network.IsCloseError(err)
+	}
+	if o, ok := r.(reopener); ok {
+		res.Resume = func(ctx context.Context) error {
+			return o.reopen()
+		}
+	}
+	return res
 }
 
 // FindGame returns entry information for an arbitrary game hosted in LAN
@@ -81,7 +322,7 @@ func FindGame(ctx context.Context, gv w3gs.GameVersion) (addr string, hostCounte
 	})
 
 	go func() {
-		var err = g.Run()
+		var err = g.RunContext(ctx)
 		stop <- err
 	}()
 
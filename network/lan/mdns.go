@@ -5,6 +5,7 @@
 package lan
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"github.com/miekg/dns"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
@@ -20,6 +23,82 @@ import (
 // MulticastGroup endpoint
 var MulticastGroup = net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
 
+// MulticastGroup6 endpoint (IPv6)
+var MulticastGroup6 = net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: 5353}
+
+// MulticastGroups returns the MDNS endpoints for f (network.FamilyDefault is treated as network.FamilyIPv4)
+func MulticastGroups(f network.IPFamily) []*net.UDPAddr {
+	switch f {
+	case network.FamilyIPv6:
+		return []*net.UDPAddr{&MulticastGroup6}
+	case network.FamilyDual:
+		return []*net.UDPAddr{&MulticastGroup, &MulticastGroup6}
+	default:
+		return []*net.UDPAddr{&MulticastGroup}
+	}
+}
+
+// MulticastGroupsInterface is like MulticastGroups, but IPv6 endpoints are scoped to ifi (nil for the default interface)
+func MulticastGroupsInterface(f network.IPFamily, ifi *net.Interface) []*net.UDPAddr {
+	var res = MulticastGroups(f)
+	if ifi == nil {
+		return res
+	}
+	for i, g := range res {
+		if g.IP.To4() == nil {
+			res[i] = &net.UDPAddr{IP: g.IP, Port: g.Port, Zone: ifi.Name}
+		}
+	}
+	return res
+}
+
+// listenMulticast joins group on ifi (nil for the default interface) and configures the socket for MDNS
+func listenMulticast(group *net.UDPAddr, ifi *net.Interface) (*net.UDPConn, error) {
+	if group.IP.To4() != nil {
+		conn, err := net.ListenMulticastUDP("udp4", ifi, group)
+		if err != nil {
+			return nil, err
+		}
+
+		var conn4 = ipv4.NewPacketConn(conn)
+		conn4.SetMulticastLoopback(true)
+		conn4.SetMulticastTTL(255)
+		if ifi != nil {
+			conn4.SetMulticastInterface(ifi)
+		}
+		return conn, nil
+	}
+
+	conn, err := net.ListenMulticastUDP("udp6", ifi, group)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn6 = ipv6.NewPacketConn(conn)
+	conn6.SetMulticastLoopback(true)
+	conn6.SetMulticastHopLimit(255)
+	if ifi != nil {
+		conn6.SetMulticastInterface(ifi)
+	}
+	return conn, nil
+}
+
+// listenUDP opens a UDP socket for f that sends multicast traffic over ifi (nil for the default interface)
+func listenUDP(f network.IPFamily, ifi *net.Interface) (*net.UDPConn, error) {
+	conn, err := network.ListenUDP(f, 0)
+	if err != nil || ifi == nil {
+		return conn, err
+	}
+
+	if f != network.FamilyIPv6 {
+		ipv4.NewPacketConn(conn).SetMulticastInterface(ifi)
+	}
+	if f != network.FamilyIPv4 {
+		ipv6.NewPacketConn(conn).SetMulticastInterface(ifi)
+	}
+	return conn, nil
+}
+
 // TypeCacheFlush bit
 var TypeCacheFlush uint16 = 1 << 15
 
@@ -33,9 +112,10 @@ func mdnsService(gv *w3gs.GameVersion) string {
 // DNSPacketConn manages a UDP connection that transfers DNS packets.
 // Public methods/fields are thread-safe unless explicitly stated otherwise
 type DNSPacketConn struct {
-	cmut network.RWMutex
-	conn net.PacketConn
-	wto  time.Duration
+	cmut  network.RWMutex
+	conn  net.PacketConn
+	wto   time.Duration
+	bcast []*net.UDPAddr
 
 	msg dns.Msg
 	buf [2048]byte
@@ -108,14 +188,45 @@ func (c *DNSPacketConn) Send(addr net.Addr, pkt *dns.Msg) (int, error) {
 	return n, err
 }
 
+// SetBroadcastAddrs for Broadcast() calls (defaults to MulticastGroup)
+func (c *DNSPacketConn) SetBroadcastAddrs(addrs ...*net.UDPAddr) {
+	c.cmut.Lock()
+	c.bcast = addrs
+	c.cmut.Unlock()
+}
+
 // Broadcast a packet over LAN
 func (c *DNSPacketConn) Broadcast(pkt *dns.Msg) (int, error) {
-	return c.Send(&MulticastGroup, pkt)
+	c.cmut.RLock()
+	var addrs = c.bcast
+	c.cmut.RUnlock()
+
+	if len(addrs) == 0 {
+		return c.Send(&MulticastGroup, pkt)
+	}
+
+	var n = 0
+	var err error
+	for _, addr := range addrs {
+		s, e := c.Send(addr, pkt)
+		n += s
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return n, err
 }
 
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
 // Not safe for concurrent invocation
 func (c *DNSPacketConn) NextPacket(timeout time.Duration) (*dns.Msg, net.Addr, error) {
+	return c.NextPacketContext(context.Background(), timeout)
+}
+
+// NextPacketContext waits for the next packet (with given timeout) until ctx is done and returns its deserialized representation
+// Not safe for concurrent invocation
+func (c *DNSPacketConn) NextPacketContext(ctx context.Context, timeout time.Duration) (*dns.Msg, net.Addr, error) {
 	c.cmut.RLock()
 
 	if c.conn == nil {
@@ -123,17 +234,20 @@ func (c *DNSPacketConn) NextPacket(timeout time.Duration) (*dns.Msg, net.Addr, e
 		return nil, nil, io.EOF
 	}
 
-	if timeout >= 0 {
-		if err := c.conn.SetReadDeadline(network.Deadline(timeout)); err != nil {
+	if d, ok := network.ContextDeadline(ctx, timeout); ok {
+		if err := c.conn.SetReadDeadline(d); err != nil {
 			c.cmut.RUnlock()
 			return nil, nil, err
 		}
 	}
 
+	var stop = network.WatchContext(ctx, c.conn)
 	size, addr, err := c.conn.ReadFrom(c.buf[:])
+	stop()
+
 	if err != nil {
 		c.cmut.RUnlock()
-		return nil, nil, err
+		return nil, nil, network.ContextError(ctx, err)
 	}
 
 	err = c.msg.Unpack(c.buf[:size])
@@ -149,10 +263,16 @@ func (c *DNSPacketConn) NextPacket(timeout time.Duration) (*dns.Msg, net.Addr, e
 // Run reads packets (with given max time between packets) from Conn and emits an event for each received packet
 // Not safe for concurrent invocation
 func (c *DNSPacketConn) Run(f network.Emitter, timeout time.Duration) error {
+	return c.RunContext(context.Background(), f, timeout)
+}
+
+// RunContext reads packets (with given max time between packets) from Conn until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (c *DNSPacketConn) RunContext(ctx context.Context, f network.Emitter, timeout time.Duration) error {
 	c.cmut.RLock()
 	f.Fire(network.RunStart{})
 	for {
-		pkt, addr, err := c.NextPacket(timeout)
+		pkt, addr, err := c.NextPacketContext(ctx, timeout)
 
 		if err != nil {
 			switch err.(type) {
@@ -0,0 +1,274 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan
+
+import (
+	"context"
+	"net"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Interfaces returns the network interfaces that are up and can reach the Local Area Network (loopback excluded)
+func Interfaces() ([]net.Interface, error) {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var res = make([]net.Interface, 0, len(ifs))
+	for _, ifi := range ifs {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if ifi.Flags&(net.FlagBroadcast|net.FlagMulticast) == 0 {
+			continue
+		}
+		res = append(res, ifi)
+	}
+	return res, nil
+}
+
+// NewGameListInterface initializes proper GameList type for game version using IP family f on interface ifi
+func NewGameListInterface(gv w3gs.GameVersion, f network.IPFamily, ifi *net.Interface) (GameList, error) {
+	if !UsesMDNS(gv) {
+		return NewUDPGameListInterface(gv, f, ifi, 0)
+	}
+	return NewMDNSGameListInterface(gv, f, ifi)
+}
+
+// NewAdvertiserInterface initializes proper Advertiser type for game version using IP family f on interface ifi
+func NewAdvertiserInterface(info *w3gs.GameInfo, f network.IPFamily, ifi *net.Interface) (Advertiser, error) {
+	if !UsesMDNS(info.GameVersion) {
+		return NewUDPAdvertiserInterface(info, f, ifi, 0)
+	}
+	return NewMDNSAdvertiserInterface(info, f, ifi)
+}
+
+// forward re-fires all events of l on e
+func forward(e *network.EventEmitter, l network.Listener) {
+	l.On(nil, func(ev *network.Event) {
+		e.Fire(ev.Arg, ev.Opt...)
+	})
+}
+
+// runAll runs all r until the first one returns, returns its error
+func runAll(ctx context.Context, r []func(ctx context.Context) error) error {
+	if len(r) == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var errc = make(chan error, len(r))
+	for _, run := range r {
+		go func(run func(ctx context.Context) error) {
+			errc <- run(ctx)
+		}(run)
+	}
+
+	var err = <-errc
+	cancel()
+	for i := 1; i < len(r); i++ {
+		<-errc
+	}
+	return err
+}
+
+// MultiGameList combines the games of several GameLists (i.e. one per network interface)
+// Events of all lists are re-fired on MultiGameList
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type MultiGameList struct {
+	network.EventEmitter
+	lists []GameList
+}
+
+// NewMultiGameList combines lists
+func NewMultiGameList(lists ...GameList) *MultiGameList {
+	var m = MultiGameList{lists: lists}
+	for _, l := range lists {
+		forward(&m.EventEmitter, l)
+	}
+	return &m
+}
+
+// NewGameListInterfaces initializes a GameList per interface in ifs (nil for all interfaces, see Interfaces())
+func NewGameListInterfaces(gv w3gs.GameVersion, f network.IPFamily, ifs []net.Interface) (*MultiGameList, error) {
+	if ifs == nil {
+		var err error
+		if ifs, err = Interfaces(); err != nil {
+			return nil, err
+		}
+	}
+
+	var lists = make([]GameList, 0, len(ifs))
+	for i := range ifs {
+		l, err := NewGameListInterface(gv, f, &ifs[i])
+		if err != nil {
+			for _, l := range lists {
+				l.Close()
+			}
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+
+	return NewMultiGameList(lists...), nil
+}
+
+// Lists returns the combined GameLists
+func (m *MultiGameList) Lists() []GameList {
+	return append([]GameList(nil), m.lists...)
+}
+
+// Games returns the current list of LAN games of all lists. Map key is the remote address.
+func (m *MultiGameList) Games() map[string]w3gs.GameInfo {
+	var res = make(map[string]w3gs.GameInfo)
+	for _, l := range m.lists {
+		for k, v := range l.Games() {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// Run all lists
+func (m *MultiGameList) Run() error {
+	return m.RunContext(context.Background())
+}
+
+// RunContext runs all lists until ctx is done or one of them fails
+func (m *MultiGameList) RunContext(ctx context.Context) error {
+	var r = make([]func(ctx context.Context) error, len(m.lists))
+	for i, l := range m.lists {
+		r[i] = l.RunContext
+	}
+	return runAll(ctx, r)
+}
+
+// Close all lists
+func (m *MultiGameList) Close() error {
+	var err error
+	for _, l := range m.lists {
+		if e := l.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// MultiAdvertiser advertises a game on several Advertisers (i.e. one per network interface)
+// Events of all advertisers are re-fired on MultiAdvertiser
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type MultiAdvertiser struct {
+	network.EventEmitter
+	ads []Advertiser
+}
+
+// NewMultiAdvertiser combines ads
+func NewMultiAdvertiser(ads ...Advertiser) *MultiAdvertiser {
+	var m = MultiAdvertiser{ads: ads}
+	for _, a := range ads {
+		forward(&m.EventEmitter, a)
+	}
+	return &m
+}
+
+// NewAdvertiserInterfaces initializes an Advertiser per interface in ifs (nil for all interfaces, see Interfaces())
+func NewAdvertiserInterfaces(info *w3gs.GameInfo, f network.IPFamily, ifs []net.Interface) (*MultiAdvertiser, error) {
+	if ifs == nil {
+		var err error
+		if ifs, err = Interfaces(); err != nil {
+			return nil, err
+		}
+	}
+
+	var ads = make([]Advertiser, 0, len(ifs))
+	for i := range ifs {
+		a, err := NewAdvertiserInterface(info, f, &ifs[i])
+		if err != nil {
+			for _, a := range ads {
+				a.Close()
+			}
+			return nil, err
+		}
+		ads = append(ads, a)
+	}
+
+	return NewMultiAdvertiser(ads...), nil
+}
+
+// Advertisers returns the combined Advertisers
+func (m *MultiAdvertiser) Advertisers() []Advertiser {
+	return append([]Advertiser(nil), m.ads...)
+}
+
+func (m *MultiAdvertiser) each(f func(a Advertiser) error) error {
+	var err error
+	for _, a := range m.ads {
+		if e := f(a); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Create local game
+func (m *MultiAdvertiser) Create() error {
+	return m.each(Advertiser.Create)
+}
+
+// Refresh game info
+func (m *MultiAdvertiser) Refresh(slotsUsed uint32, slotsAvailable uint32) error {
+	return m.each(func(a Advertiser) error {
+		return a.Refresh(slotsUsed, slotsAvailable)
+	})
+}
+
+// Decreate game
+func (m *MultiAdvertiser) Decreate() error {
+	return m.each(Advertiser.Decreate)
+}
+
+// State combines the state of all advertisers, game info is taken from the first advertiser
+func (m *MultiAdvertiser) State() AdvertiserState {
+	var res AdvertiserState
+	for i, a := range m.ads {
+		var s = a.State()
+		if i == 0 {
+			res.Game = s.Game
+		}
+
+		res.Created = res.Created || s.Created
+		res.Running = res.Running || s.Running
+		res.Probes += s.Probes
+		if s.LastProbe.After(res.LastProbe) {
+			res.LastProbe = s.LastProbe
+		}
+	}
+	return res
+}
+
+// Run all advertisers
+func (m *MultiAdvertiser) Run() error {
+	return m.RunContext(context.Background())
+}
+
+// RunContext runs all advertisers until ctx is done or one of them fails
+func (m *MultiAdvertiser) RunContext(ctx context.Context) error {
+	var r = make([]func(ctx context.Context) error, len(m.ads))
+	for i, a := range m.ads {
+		r[i] = a.RunContext
+	}
+	return runAll(ctx, r)
+}
+
+// Close all advertisers
+func (m *MultiAdvertiser) Close() error {
+	return m.each(Advertiser.Close)
+}
@@ -0,0 +1,96 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan
+
+import (
+	"net"
+	"sort"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Policy decides whether a game that failed validation with err is listed anyway (return true to keep it)
+// Called from Games(), must not block or call Games() itself
+type Policy func(err error, addr string, game *w3gs.GameInfo) bool
+
+// RejectInvalid is the Policy that drops every game that failed validation
+func RejectInvalid(err error, addr string, game *w3gs.GameInfo) bool {
+	return false
+}
+
+// ValidateGame checks the sanity of game hosted on addr, announced by src
+func ValidateGame(gv w3gs.GameVersion, src string, addr string, game *w3gs.GameInfo) error {
+	if shost, _, err := net.SplitHostPort(src); err == nil {
+		if ahost, _, err := net.SplitHostPort(addr); err == nil {
+			var sip = net.ParseIP(shost)
+			var aip = net.ParseIP(ahost)
+
+			// Only compare addresses of the same family, dual-stack hosts may announce either
+			if sip != nil && aip != nil && (sip.To4() == nil) == (aip.To4() == nil) && !sip.Equal(aip) {
+				return ErrSourceMismatch
+			}
+		}
+	}
+
+	if game.GameVersion.Product != gv.Product || (gv.Version != 0 && game.GameVersion.Version != gv.Version) {
+		return ErrInvalidVersion
+	}
+
+	if game.GameName == "" || game.GamePort == 0 || game.SlotsTotal == 0 || game.SlotsTotal > 24 ||
+		game.SlotsUsed > game.SlotsTotal || game.SlotsAvailable > game.SlotsTotal {
+		return ErrInvalidInfo
+	}
+
+	var s = &game.GameSettings
+	if s.MapPath == "" || s.MapWidth == 0 || s.MapHeight == 0 {
+		return ErrInvalidInfo
+	}
+
+	return nil
+}
+
+type hostKey struct {
+	hostCounter uint32
+	entryKey    uint32
+}
+
+// validateGames filters games (from Games(), srcs maps their key to the source address) according to policy
+func validateGames(gv w3gs.GameVersion, policy Policy, games map[string]w3gs.GameInfo, srcs map[string]string) map[string]w3gs.GameInfo {
+	var keys = make([]string, 0, len(games))
+	for k := range games {
+		keys = append(keys, k)
+	}
+
+	// Oldest game wins duplicate host counters, later announcements are likely spoofed
+	sort.Slice(keys, func(i, j int) bool {
+		var a = games[keys[i]]
+		var b = games[keys[j]]
+		if a.UptimeSec != b.UptimeSec {
+			return a.UptimeSec > b.UptimeSec
+		}
+		return keys[i] < keys[j]
+	})
+
+	var seen = make(map[hostKey]struct{}, len(keys))
+	var res = make(map[string]w3gs.GameInfo, len(keys))
+	for _, k := range keys {
+		var game = games[k]
+		var err = ValidateGame(gv, srcs[k], k, &game)
+
+		var hk = hostKey{hostCounter: game.HostCounter, entryKey: game.EntryKey}
+		if _, dup := seen[hk]; dup && err == nil {
+			err = ErrDuplicateHost
+		}
+
+		if err != nil && !policy(err, k, &game) {
+			continue
+		}
+
+		seen[hk] = struct{}{}
+		res[k] = game
+	}
+
+	return res
+}
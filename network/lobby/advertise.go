@@ -0,0 +1,56 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"context"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/lan"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Advertise lobby in Local Area Network on an endpoint of the shared socket m until ctx is done
+// Slot counts in info are kept in sync with the lobby
+func (l *Lobby) Advertise(ctx context.Context, m *network.UDPMux, info *w3gs.GameInfo) error {
+	var gi = *info
+	gi.SlotsUsed = uint32(l.SlotsUsed())
+	gi.SlotsAvailable = uint32(l.SlotsAvailable())
+
+	var a = lan.NewUDPAdvertiserMux(&gi, m)
+	a.Logger = l.Logger
+	a.On(&network.AsyncError{}, func(ev *network.Event) {
+		l.Fire(ev.Arg)
+	})
+	defer a.Close()
+
+	// SlotInfo is fired while slotmut is locked, refresh asynchronously
+	var update = make(chan struct{}, 1)
+	var eid = l.On(&w3gs.SlotInfo{}, func(ev *network.Event) {
+		select {
+		case update <- struct{}{}:
+		default:
+		}
+	})
+	defer l.Off(eid)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-update:
+				if err := a.Refresh(uint32(l.SlotsUsed()), uint32(l.SlotsAvailable())); err != nil && !network.IsCloseError(err) {
+					l.Fire(&network.AsyncError{Src: "Advertise[Refresh]", Err: err})
+				}
+			}
+		}
+	}()
+
+	return a.RunContext(ctx)
+}
@@ -0,0 +1,143 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"context"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// AutoStart conditions, the game is started once all enabled conditions are met and all players are ready
+type AutoStart struct {
+	MinPlayers int           // Min number of (human, non-observer) players (0 to disable)
+	Full       bool          // All slots are either occupied or closed
+	MaxPing    time.Duration // Max RTT of every player (0 to disable)
+	Delay      time.Duration // Time conditions have to hold before starting
+	Interval   time.Duration // Time between checks (0 for default)
+}
+
+// AutoStartReady event, fired when all AutoStart conditions are met and the game starts after Delay
+type AutoStartReady struct {
+	Delay time.Duration
+}
+
+// AutoStartCanceled event, fired when AutoStart conditions are no longer met after AutoStartReady
+type AutoStartCanceled struct{}
+
+// slotmut should be locked
+func (g *Game) autoStartMet(cond *AutoStart) bool {
+	if g.locked {
+		return false
+	}
+
+	var players = 0
+	for i, s := range g.slots {
+		if cond.Full && s.SlotStatus == w3gs.SlotOpen {
+			return false
+		}
+		if s.SlotStatus == w3gs.SlotOccupied && !s.Computer && s.Team != g.ObsTeam && !g.isFakeSlot(g.slots, i) {
+			players++
+		}
+	}
+	if players < cond.MinPlayers {
+		return false
+	}
+
+	var ping = uint32(cond.MaxPing.Milliseconds())
+	for _, p := range g.players {
+		if !p.Ready() || (ping > 0 && p.Ping() > ping) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AutoStart starts the game once cond is met
+func (g *Game) AutoStart(cond AutoStart) error {
+	return g.AutoStartContext(context.Background(), cond)
+}
+
+// AutoStartContext starts the game once cond is met or returns when ctx is done
+func (g *Game) AutoStartContext(ctx context.Context, cond AutoStart) error {
+	var interval = cond.Interval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var since time.Time
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now = <-ticker.C:
+		}
+
+		if g.Stage() != StageLobby {
+			return ErrLocked
+		}
+
+		g.slotmut.Lock()
+		var met = g.autoStartMet(&cond)
+		g.slotmut.Unlock()
+
+		if !met {
+			if !since.IsZero() {
+				since = time.Time{}
+				g.Fire(&AutoStartCanceled{})
+			}
+			continue
+		}
+
+		if since.IsZero() {
+			since = now
+			g.Fire(&AutoStartReady{Delay: cond.Delay})
+		}
+		if now.Sub(since) < cond.Delay {
+			continue
+		}
+
+		switch err := g.Start(); err {
+		case nil:
+			return nil
+		case ErrNotReady:
+			// Player joined in between checks
+			continue
+		default:
+			return err
+		}
+	}
+}
+
+// ForceStart kicks all players that are not ready and starts the game
+func (g *Game) ForceStart() error {
+	g.slotmut.Lock()
+	if g.locked {
+		g.slotmut.Unlock()
+		return ErrLocked
+	}
+
+	for _, p := range g.players {
+		if p.Ready() {
+			continue
+		}
+
+		// Free slot immediately, see clearSlot()
+		p.Fire(&network.AsyncError{Src: "Game.ForceStart", Err: ErrNotReady})
+		p.Kick(w3gs.LeaveLobby)
+		g.removePlayer(p)
+		g.kicked.Set(uint(p.PlayerInfo.PlayerID))
+	}
+	g.slotmut.Unlock()
+
+	return g.Start()
+}
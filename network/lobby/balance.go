@@ -0,0 +1,67 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"sort"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Balancer reassigns players to the occupied slots in slots to balance teams before the game starts
+// players contains the participating (non-observing) players by PlayerID, other slots should be left unchanged
+type Balancer func(slots []w3gs.SlotData, players map[uint8]*Player) error
+
+// GreedyBalancer returns a Balancer that assigns players ordered by rating (highest first) to the team
+// with the lowest total rating that has slots left, keeping the number of players per team intact
+// Players adopt the team and color of their new slot
+func GreedyBalancer(rating func(p *Player) float64) Balancer {
+	return func(slots []w3gs.SlotData, players map[uint8]*Player) error {
+		var teams = make(map[uint8][]int)
+		var order []uint8
+		var data []w3gs.SlotData
+		var ratings = make(map[uint8]float64)
+
+		for sid, s := range slots {
+			if s.SlotStatus != w3gs.SlotOccupied || s.Computer {
+				continue
+			}
+			p, ok := players[s.PlayerID]
+			if !ok {
+				continue
+			}
+			if teams[s.Team] == nil {
+				order = append(order, s.Team)
+			}
+			teams[s.Team] = append(teams[s.Team], sid)
+			data = append(data, s)
+			ratings[s.PlayerID] = rating(p)
+		}
+
+		sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+		sort.SliceStable(data, func(i, j int) bool { return ratings[data[i].PlayerID] > ratings[data[j].PlayerID] })
+
+		var total = make([]float64, len(order))
+		var fill = make([]int, len(order))
+		for _, d := range data {
+			var best = -1
+			for t := range order {
+				if fill[t] < len(teams[order[t]]) && (best < 0 || total[t] < total[best]) {
+					best = t
+				}
+			}
+
+			var sid = teams[order[best]][fill[best]]
+			fill[best]++
+			total[best] += ratings[d.PlayerID]
+
+			d.Team = slots[sid].Team
+			d.Color = slots[sid].Color
+			slots[sid] = d
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ban entry, matches players by name and realm (case-insensitive) or IP
+type Ban struct {
+	Name    string // Player name (empty to match by IP only)
+	Realm   string // Realm the name belongs to (empty to match name on any realm)
+	IP      net.IP // Player IP (nil to match by name only)
+	Reason  string
+	Created time.Time
+}
+
+// Match reports if ban applies to a player with name on realm and ip
+func (b *Ban) Match(name string, realm string, ip net.IP) bool {
+	if b.Name != "" && strings.EqualFold(b.Name, name) && (b.Realm == "" || strings.EqualFold(b.Realm, realm)) {
+		return true
+	}
+	return b.IP != nil && ip != nil && b.IP.Equal(ip)
+}
+
+// BanStore keeps track of banned players, i.e. in a database or shared service (see HTTPBans)
+// Implementations should be thread-safe
+type BanStore interface {
+	Add(ban *Ban) error
+	Remove(name string, realm string, ip net.IP) error
+	Find(name string, realm string, ip net.IP) (*Ban, error)
+}
+
+// BanList is an in-memory BanStore
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type BanList struct {
+	mut  sync.Mutex
+	bans []Ban
+}
+
+// Add ban to list
+func (l *BanList) Add(ban *Ban) error {
+	var b = *ban
+	if b.Created.IsZero() {
+		b.Created = time.Now()
+	}
+
+	l.mut.Lock()
+	l.bans = append(l.bans, b)
+	l.mut.Unlock()
+	return nil
+}
+
+// Remove all bans matching name on realm or ip
+func (l *BanList) Remove(name string, realm string, ip net.IP) error {
+	l.mut.Lock()
+	var res = l.bans[:0]
+	for _, b := range l.bans {
+		if !b.Match(name, realm, ip) {
+			res = append(res, b)
+		}
+	}
+	for i := len(res); i < len(l.bans); i++ {
+		l.bans[i] = Ban{}
+	}
+	l.bans = res
+	l.mut.Unlock()
+	return nil
+}
+
+// Find the first ban matching name on realm or ip (nil if not banned)
+func (l *BanList) Find(name string, realm string, ip net.IP) (*Ban, error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	for _, b := range l.bans {
+		if b.Match(name, realm, ip) {
+			var res = b
+			return &res, nil
+		}
+	}
+	return nil, nil
+}
+
+// Bans returns all entries in the list
+func (l *BanList) Bans() []Ban {
+	l.mut.Lock()
+	var res = append([]Ban(nil), l.bans...)
+	l.mut.Unlock()
+	return res
+}
+
+// addrIP extracts the IP from a network address
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	case nil:
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
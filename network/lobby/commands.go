@@ -0,0 +1,220 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/chat"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Command permissions (see chat.Route.Require)
+const (
+	PermEveryone chat.UserFlags = 0
+	PermReserved                = chat.UserFlagModerator
+	PermHost                    = chat.UserFlagAdmin
+)
+
+// Commands parses the chat of players in a game and dispatches chat commands (i.e. "!start")
+// Players listed in Hosts are granted PermHost, players that have a slot reserved PermReserved
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Commands struct {
+	*chat.Router
+
+	// Set once before Bind(), read-only after that
+	Hosts []string // Names of players with host permissions (case-insensitive)
+	Hide  bool     // Do not relay commands to other players
+}
+
+// NewCommands initializes a Commands struct without any commands (see InitDefaultCommands)
+func NewCommands(prefix string) *Commands {
+	return &Commands{
+		Router: chat.NewRouter(prefix),
+	}
+}
+
+// Flags returns the permissions of p in l
+func (c *Commands) Flags(l *Lobby, p *Player) chat.UserFlags {
+	var name = p.PlayerInfo.PlayerName
+	for _, h := range c.Hosts {
+		if strings.EqualFold(h, name) {
+			return PermHost
+		}
+	}
+
+	l.slotmut.Lock()
+	var sid = l.findReservedSlot(name)
+	l.slotmut.Unlock()
+
+	if sid >= 0 {
+		return PermReserved
+	}
+	return PermEveryone
+}
+
+// Bind routes the chat of players in g, replies are sent as private message to the player
+// Commands are handled synchronously on the goroutine of the player
+// Returns a function that unbinds g
+func (c *Commands) Bind(g *Game) func() {
+	var eid = g.On(&PlayerChat{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*PlayerChat)
+		if _, ok := c.Parse(pkt.Content); !ok {
+			return
+		}
+		if c.Hide {
+			ev.PreventNext()
+		}
+
+		var pid = pkt.Player.PlayerInfo.PlayerID
+		var msg = chat.Message{
+			Username: pkt.Player.PlayerInfo.PlayerName,
+			Flags:    c.Flags(&g.Lobby, pkt.Player),
+			Content:  pkt.Content,
+			Reply:    func(s string) error { return g.SayTo(pid, s) },
+		}
+
+		switch _, err := c.Route(&msg); err {
+		case nil, chat.ErrUnknownCommand, chat.ErrPermissionDenied, chat.ErrCooldown:
+		default:
+			g.Fire(&network.AsyncError{Src: "Commands.Bind[Route]", Err: err})
+		}
+	})
+
+	return func() {
+		g.Off(eid)
+	}
+}
+
+func replyErr(cmd *chat.Command, err error) error {
+	if err == nil {
+		return nil
+	}
+	return cmd.Reply(err.Error())
+}
+
+func slotArgs(cmd *chat.Command, n int) ([]int, bool) {
+	if len(cmd.Args) != n {
+		return nil, false
+	}
+
+	var res = make([]int, n)
+	for i, a := range cmd.Args {
+		s, err := strconv.Atoi(a)
+		if err != nil {
+			return nil, false
+		}
+		res[i] = s - 1
+	}
+	return res, true
+}
+
+// InitDefaultCommands adds the built-in commands for g (slots are numbered from 1)
+// PermHost: "start [force]", "open <slot>", "close <slot>", "kick <name>", "hcl [cmd]"
+// PermReserved: "swap <slot> <slot>"
+// PermEveryone: "ping"
+func (c *Commands) InitDefaultCommands(g *Game) {
+	c.Add(&chat.Route{Require: PermHost, Help: "Start game", Handler: func(cmd *chat.Command) error {
+		if strings.EqualFold(cmd.Arg, "force") {
+			return replyErr(cmd, g.ForceStart())
+		}
+		return replyErr(cmd, g.Start())
+	}}, "start")
+
+	c.Add(&chat.Route{Require: PermReserved, Help: "Swap two slots", Handler: func(cmd *chat.Command) error {
+		s, ok := slotArgs(cmd, 2)
+		if !ok {
+			return cmd.Reply("Usage: swap <slot> <slot>")
+		}
+		return replyErr(cmd, g.SwapSlots(s[0], s[1]))
+	}}, "swap")
+
+	c.Add(&chat.Route{Require: PermHost, Help: "Open slot", Handler: func(cmd *chat.Command) error {
+		s, ok := slotArgs(cmd, 1)
+		if !ok {
+			return cmd.Reply("Usage: open <slot>")
+		}
+		return replyErr(cmd, g.OpenSlot(s[0], true))
+	}}, "open")
+
+	c.Add(&chat.Route{Require: PermHost, Help: "Close slot", Handler: func(cmd *chat.Command) error {
+		s, ok := slotArgs(cmd, 1)
+		if !ok {
+			return cmd.Reply("Usage: close <slot>")
+		}
+		return replyErr(cmd, g.CloseSlot(s[0], true))
+	}}, "close")
+
+	c.Add(&chat.Route{Require: PermHost, Help: "Kick player", Handler: func(cmd *chat.Command) error {
+		var p = g.FindPlayer(cmd.Arg)
+		if cmd.Arg == "" || p == nil {
+			return replyErr(cmd, ErrUnknownPlayer)
+		}
+		return replyErr(cmd, g.Kick(p.PlayerInfo.PlayerID, w3gs.LeaveLobby))
+	}}, "kick")
+
+	c.Add(&chat.Route{Require: PermHost, Help: "Show or change HCL", Handler: func(cmd *chat.Command) error {
+		if cmd.Arg == "" {
+			g.slotmut.Lock()
+			var hcl = g.HCL
+			g.slotmut.Unlock()
+			return cmd.Reply("HCL: " + hcl)
+		}
+		if err := g.SetHCL(cmd.Arg); err != nil {
+			return replyErr(cmd, err)
+		}
+		return cmd.Reply("HCL: " + cmd.Arg)
+	}}, "hcl")
+
+	c.Add(&chat.Route{Require: PermEveryone, Help: "Show player latency", Handler: func(cmd *chat.Command) error {
+		g.slotmut.Lock()
+		var ping = make([]string, 0, len(g.players))
+		var ids = make([]uint8, 0, len(g.players))
+		for pid := range g.players {
+			ids = append(ids, pid)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, pid := range ids {
+			var p = g.players[pid]
+			ping = append(ping, fmt.Sprintf("%s: %dms", p.PlayerInfo.PlayerName, p.Ping()))
+		}
+		g.slotmut.Unlock()
+
+		return cmd.Reply(strings.Join(ping, ", "))
+	}}, "ping")
+}
+
+// InitVoteKickCommands adds the vote kick commands for v
+// PermEveryone: "votekick <name>", "yes"
+func (c *Commands) InitVoteKickCommands(v *VoteKick) {
+	var player = func(cmd *chat.Command) *Player {
+		var p = v.g.FindPlayer(cmd.Username)
+		if p == nil || p.PlayerInfo.PlayerName != cmd.Username {
+			return nil
+		}
+		return p
+	}
+
+	c.Add(&chat.Route{Require: PermEveryone, Help: "Start a vote to kick player", Handler: func(cmd *chat.Command) error {
+		var p = player(cmd)
+		var t = v.g.FindPlayer(cmd.Arg)
+		if p == nil || cmd.Arg == "" || t == nil {
+			return replyErr(cmd, ErrUnknownPlayer)
+		}
+		return replyErr(cmd, v.Start(p, t))
+	}}, "votekick")
+
+	c.Add(&chat.Route{Require: PermEveryone, Help: "Vote in favor of kicking player", Handler: func(cmd *chat.Command) error {
+		var p = player(cmd)
+		if p == nil {
+			return replyErr(cmd, ErrUnknownPlayer)
+		}
+		return replyErr(cmd, v.Vote(p))
+	}}, "yes")
+}
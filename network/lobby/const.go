@@ -8,23 +8,41 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
 // Errors
 var (
-	ErrFull            = errors.New("lobby: Lobby is full")
-	ErrLocked          = errors.New("lobby: Lobby is locked")
-	ErrInvalidArgument = errors.New("lobby: Invalid argument")
-	ErrInvalidSlot     = errors.New("lobby: Invalid slot")
-	ErrInvalidPacket   = errors.New("lobby: Invalid packet")
-	ErrMapUnavailable  = errors.New("lobby: Map unavailable")
-	ErrNotReady        = errors.New("lobby: Player was not ready")
-	ErrPlayersOccupied = errors.New("lobby: No player slots left")
-	ErrSlotOccupied    = errors.New("lobby: Slot occupied")
-	ErrColorOccupied   = errors.New("lobby: Color occupied")
-	ErrHighPing        = errors.New("lobby: Ping exceeds lag recovery delay")
-	ErrStraggling      = errors.New("lobby: Player was straggling")
-	ErrDesync          = errors.New("lobby: Timeslot checksum mismatch")
+	ErrFull              = protocol.NewError(protocol.KindRejected, "lobby: Lobby is full")
+	ErrLocked            = protocol.NewError(protocol.KindRejected, "lobby: Lobby is locked")
+	ErrBanned            = protocol.NewError(protocol.KindRejected, "lobby: Player is banned")
+	ErrBanStore          = errors.New("lobby: Unexpected ban store response")
+	ErrNotReserved       = protocol.NewError(protocol.KindRejected, "lobby: No slot reserved for player")
+	ErrPlayerLimit       = protocol.NewError(protocol.KindRejected, "lobby: Player limit reached")
+	ErrGameLimit         = protocol.NewError(protocol.KindRejected, "lobby: Game limit reached")
+	ErrNoPort            = errors.New("lobby: No port available")
+	ErrManagerClosed     = errors.New("lobby: Manager closed")
+	ErrInvalidArgument   = errors.New("lobby: Invalid argument")
+	ErrInvalidSlot       = errors.New("lobby: Invalid slot")
+	ErrUnknownPlayer     = errors.New("lobby: Unknown player")
+	ErrInvalidPacket     = errors.New("lobby: Invalid packet")
+	ErrMapUnavailable    = errors.New("lobby: Map unavailable")
+	ErrNotReady          = protocol.NewError(protocol.KindTimeout, "lobby: Player was not ready")
+	ErrPlayersOccupied   = errors.New("lobby: No player slots left")
+	ErrSlotOccupied      = errors.New("lobby: Slot occupied")
+	ErrColorOccupied     = errors.New("lobby: Color occupied")
+	ErrHighPing          = errors.New("lobby: Ping exceeds lag recovery delay")
+	ErrMaxPing           = protocol.NewError(protocol.KindRejected, "lobby: Ping exceeds MaxPing")
+	ErrHCLInvalid        = errors.New("lobby: HCL contains invalid characters")
+	ErrHCLTooLong        = errors.New("lobby: HCL exceeds number of occupied slots")
+	ErrStraggling        = errors.New("lobby: Player was straggling")
+	ErrDesync            = protocol.NewError(protocol.KindChecksum, "lobby: Timeslot checksum mismatch")
+	ErrReconnectRejected = protocol.NewError(protocol.KindRejected, "lobby: Reconnect rejected")
+	ErrVoteActive        = errors.New("lobby: Vote already in progress")
+	ErrVoteCooldown      = protocol.NewError(protocol.KindRateLimited, "lobby: Vote on cooldown")
+	ErrNoVote            = errors.New("lobby: No vote in progress")
 )
 
 // ObsDisabled constant
@@ -33,6 +51,21 @@ const ObsDisabled uint8 = 0xFF
 // Maximum transmission unit
 const mtu = 1200
 
+// SendQueueSize is the default number of packets queued per player before it is dropped as a slow peer
+const SendQueueSize = 1024
+
+// MapChunkSize is the maximum size of a single map part
+const MapChunkSize = 1442
+
+// DownloadWindow is the number of unacknowledged map parts sent to a downloading player
+const DownloadWindow = 16
+
+// MapSenderID is the player ID used as sender of map parts (the lobby itself does not occupy a slot)
+const MapSenderID uint8 = 255
+
+// PingSmoothing factor, new RTT samples contribute 1/PingSmoothing to the smoothed ping (see Player.Ping)
+const PingSmoothing = 4
+
 // LagDelay timeout before showing lag screen
 const LagDelay = 2 * time.Second
 
@@ -67,3 +100,48 @@ func (s Stage) String() string {
 		return fmt.Sprintf("Stage(%d)", uint32(s))
 	}
 }
+
+// ObsMode of the game, decides whether observer slots are available and who observers can chat with
+type ObsMode uint8
+
+// ObsMode enums
+const (
+	ObsFull     ObsMode = iota // Observer slots, observers only chat with observers during the game
+	ObsReferees                // Observer slots, observers chat with everyone
+	ObsOnDefeat                // No observer slots, defeated players observe
+	ObsNone                    // No observer slots
+)
+
+// Slots reports if observer slots are available
+func (m ObsMode) Slots() bool {
+	return m == ObsFull || m == ObsReferees
+}
+
+// Settings returns the corresponding GameSettingFlags
+func (m ObsMode) Settings() w3gs.GameSettingFlags {
+	switch m {
+	case ObsFull:
+		return w3gs.SettingObsFull
+	case ObsReferees:
+		return w3gs.SettingObsReferees | w3gs.SettingObsEnabled
+	case ObsOnDefeat:
+		return w3gs.SettingObsOnDefeat
+	default:
+		return w3gs.SettingObsNone
+	}
+}
+
+func (m ObsMode) String() string {
+	switch m {
+	case ObsFull:
+		return "Full"
+	case ObsReferees:
+		return "Referees"
+	case ObsOnDefeat:
+		return "OnDefeat"
+	case ObsNone:
+		return "None"
+	default:
+		return fmt.Sprintf("ObsMode(%d)", uint8(m))
+	}
+}
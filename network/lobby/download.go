@@ -0,0 +1,199 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// UploadLimit restricts the upload rate of map downloads
+// A single UploadLimit can be shared by multiple lobbies to restrict their combined rate
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type UploadLimit struct {
+	mut  sync.Mutex
+	next time.Time
+	rate int
+}
+
+// NewUploadLimit initializes an UploadLimit of rate bytes per second
+func NewUploadLimit(rate int) *UploadLimit {
+	return &UploadLimit{rate: rate}
+}
+
+// reserve n bytes, returns the time to wait before sending them
+func (u *UploadLimit) reserve(n int) time.Duration {
+	if u == nil || u.rate <= 0 {
+		return 0
+	}
+
+	u.mut.Lock()
+	var now = time.Now()
+	if u.next.Before(now) {
+		u.next = now
+	}
+	var wait = u.next.Sub(now)
+	u.next = u.next.Add(time.Duration(n) * time.Second / time.Duration(u.rate))
+	u.mut.Unlock()
+
+	return wait
+}
+
+// download streams MapData to a single player
+type download struct {
+	l     *Lobby
+	p     *Player
+	limit UploadLimit
+
+	finish func() // Called once all parts are acknowledged
+
+	started uint32
+	ack     chan uint32
+	retry   chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func (l *Lobby) newDownload(p *Player) *download {
+	return &download{
+		l:     l,
+		p:     p,
+		limit: UploadLimit{rate: l.UploadRate},
+		ack:   make(chan uint32, DownloadWindow),
+		retry: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// start uploading if not started yet, returns false if already started
+func (d *download) start() bool {
+	if !atomic.CompareAndSwapUint32(&d.started, 0, 1) {
+		return false
+	}
+
+	go func() {
+		defer close(d.done)
+		if err := d.run(); err != nil && !network.IsCloseError(err) {
+			d.p.Fire(&network.AsyncError{Src: "Lobby.download[run]", Err: err})
+			d.p.Kick(w3gs.LeaveLobby)
+		}
+	}()
+	return true
+}
+
+// received reports the number of bytes the player has received
+func (d *download) received(pos uint32) {
+	select {
+	case d.ack <- pos:
+	default:
+		// Progress is cumulative, drop when full
+	}
+}
+
+// fail restarts the download from the last acknowledged position
+func (d *download) fail() {
+	select {
+	case d.retry <- struct{}{}:
+	default:
+	}
+}
+
+// close stops uploading and waits for it to finish
+func (d *download) close() {
+	close(d.stop)
+	if !atomic.CompareAndSwapUint32(&d.started, 0, 1) {
+		<-d.done
+	}
+}
+
+func (d *download) wait(n int) bool {
+	var w = d.limit.reserve(n)
+	if t := d.l.UploadLimit.reserve(n); t > w {
+		w = t
+	}
+	if w <= 0 {
+		return true
+	}
+
+	var t = time.NewTimer(w)
+	defer t.Stop()
+
+	select {
+	case <-d.stop:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (d *download) run() error {
+	var size = d.l.MapCheck.FileSize
+
+	d.l.Fire(&DownloadStarted{d.p})
+	if _, err := d.p.Send(&w3gs.StartDownload{PlayerID: MapSenderID}); err != nil {
+		return err
+	}
+
+	var buf [MapChunkSize]byte
+	var sent, acked uint32
+
+	for acked < size {
+		for sent < size && sent-acked < DownloadWindow*MapChunkSize {
+			var n = size - sent
+			if n > MapChunkSize {
+				n = MapChunkSize
+			}
+
+			r, err := d.l.MapData.ReadAt(buf[:n], int64(sent))
+			if uint32(r) != n {
+				if err == nil || err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return err
+			}
+
+			if !d.wait(r) {
+				return nil
+			}
+
+			if _, err := d.p.Send(&w3gs.MapPart{
+				RecipientID: d.p.PlayerInfo.PlayerID,
+				SenderID:    MapSenderID,
+				ChunkPos:    sent,
+				Data:        buf[:n],
+			}); err != nil {
+				return err
+			}
+
+			sent += n
+		}
+
+		select {
+		case <-d.stop:
+			return nil
+		case <-d.retry:
+			sent = acked
+		case pos := <-d.ack:
+			if pos <= acked || pos > sent {
+				continue
+			}
+			acked = pos
+			d.l.Fire(&DownloadProgress{Player: d.p, Received: acked, Total: size})
+		}
+	}
+
+	if d.finish != nil {
+		d.finish()
+	}
+
+	d.l.Fire(&DownloadFinished{d.p})
+	return nil
+}
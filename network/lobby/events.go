@@ -4,7 +4,11 @@
 
 package lobby
 
-import "github.com/nielsAD/gowarcraft3/protocol/w3gs"
+import (
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
 
 // Ready event
 type Ready struct{}
@@ -15,6 +19,17 @@ type StartLag struct{}
 // StopLag event
 type StopLag struct{}
 
+// DropVote event, fired when a player votes to drop the laggers
+type DropVote struct {
+	*Player
+}
+
+// LatencyChanged event
+type LatencyChanged struct {
+	Old time.Duration
+	New time.Duration
+}
+
 // PlayerJoined event
 type PlayerJoined struct {
 	*Player
@@ -25,14 +40,78 @@ type PlayerLeft struct {
 	*Player
 }
 
+// PlayerReconnected event, fired when a GProxy++ client resumed its connection
+type PlayerReconnected struct {
+	*Player
+}
+
+// PlayerLoaded event, fired when a player finished loading
+type PlayerLoaded struct {
+	*Player
+}
+
 // PlayerChat event
 type PlayerChat struct {
 	*Player
 	*w3gs.Message
 }
 
+// PingUpdated event, fired when a pong is received
+type PingUpdated struct {
+	RTT  uint32
+	Ping uint32 // Smoothed RTT
+}
+
 // StageChanged event
 type StageChanged struct {
 	Old Stage
 	New Stage
 }
+
+// SlotChanged event, fired for every slot that changed since the previous SlotInfo
+type SlotChanged struct {
+	SID int
+	Old w3gs.SlotData
+	New w3gs.SlotData
+}
+
+// MapChecked event, fired when a player replied to the initial map check
+type MapChecked struct {
+	*Player
+	Ready bool // Player has the map, no download needed
+}
+
+// CountdownStarted event
+type CountdownStarted struct {
+	Duration time.Duration
+}
+
+// CountdownEnded event, players start loading
+type CountdownEnded struct{}
+
+// LagStarted event, fired when a player starts lagging
+type LagStarted struct {
+	*Player
+}
+
+// LagStopped event, fired when a lagging player caught up
+type LagStopped struct {
+	*Player
+}
+
+// DownloadStarted event
+type DownloadStarted struct {
+	*Player
+}
+
+// DownloadProgress event
+type DownloadProgress struct {
+	*Player
+	Received uint32
+	Total    uint32
+}
+
+// DownloadFinished event
+type DownloadFinished struct {
+	*Player
+}
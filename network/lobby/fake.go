@@ -0,0 +1,117 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"sort"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// AddFakePlayer occupies an open slot with a fake player that has no connection, returns its PlayerID
+// Fake players appear like regular players, they never act and load instantly once the game starts (see Game.DropFakes)
+func (l *Lobby) AddFakePlayer(name string) (uint8, error) {
+	if name == "" {
+		return 0, ErrInvalidArgument
+	}
+
+	l.slotmut.Lock()
+	defer l.slotmut.Unlock()
+
+	if l.locked {
+		return 0, ErrLocked
+	}
+	if l.saved {
+		return 0, ErrNotReserved
+	}
+
+	var sid = l.findEmptySlot()
+	if sid < 0 {
+		return 0, ErrFull
+	}
+	if err := l.initSlot(sid); err != nil {
+		return 0, err
+	}
+
+	var pid = l.findEmptyPID()
+	l.slots[sid].PlayerID = pid
+	l.slots[sid].DownloadStatus = 100
+
+	var info = w3gs.PlayerInfo{
+		PlayerID:   pid,
+		PlayerName: name,
+	}
+	l.fakes[pid] = &info
+
+	l.sendToAll(&info)
+	l.refreshSlots()
+
+	return pid, nil
+}
+
+// RemoveFakePlayer with id, freeing its slot
+func (l *Lobby) RemoveFakePlayer(id uint8) error {
+	l.slotmut.Lock()
+	defer l.slotmut.Unlock()
+
+	if l.locked {
+		return ErrLocked
+	}
+	if l.fakes[id] == nil {
+		return ErrUnknownPlayer
+	}
+
+	l.removeFake(l.slots, l.pidToSID(id))
+	l.refreshSlots()
+	return nil
+}
+
+// FakePlayers returns the PlayerIDs of all fake players
+func (l *Lobby) FakePlayers() []uint8 {
+	l.slotmut.Lock()
+	var res = make([]uint8, 0, len(l.fakes))
+	for pid := range l.fakes {
+		res = append(res, pid)
+	}
+	l.slotmut.Unlock()
+
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// IsFake reports whether id belongs to a fake player
+func (l *Lobby) IsFake(id uint8) bool {
+	l.slotmut.Lock()
+	var fake = l.fakes[id] != nil
+	l.slotmut.Unlock()
+	return fake
+}
+
+// slotmut should be locked
+func (l *Lobby) removeFake(slots []w3gs.SlotData, sid int) {
+	var pid = slots[sid].PlayerID
+	delete(l.fakes, pid)
+
+	slots[sid] = l.slotBase.Slots[sid]
+
+	l.sendToAll(&w3gs.PlayerLeft{
+		PlayerID: pid,
+		Reason:   w3gs.LeaveLobby,
+	})
+}
+
+// slotmut should be locked
+func (l *Lobby) isFakeSlot(slots []w3gs.SlotData, sid int) bool {
+	return slots[sid].SlotStatus == w3gs.SlotOccupied && !slots[sid].Computer && l.fakes[slots[sid].PlayerID] != nil
+}
+
+// slotmut should be locked
+func (l *Lobby) dropFakes(slots []w3gs.SlotData) {
+	for sid := range slots {
+		if l.isFakeSlot(slots, sid) {
+			l.removeFake(slots, sid)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package lobby
 
 import (
 	"math"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,6 +22,7 @@ type Game struct {
 
 	actmut   sync.Mutex
 	actions  []w3gs.PlayerAction
+	delayed  []delayedAction
 	laggers  w3gs.StartLag
 	dropmask protocol.BitSet32
 
@@ -28,15 +30,30 @@ type Game struct {
 	ackmask protocol.BitSet32
 	ackarr  []plack
 
+	resmut    sync.Mutex
+	result    Result
+	resultIdx map[uint8]int
+
 	// Atomic
-	stage uint32
-	tick  uint32
+	stage   uint32
+	tick    uint32
+	latency uint32
 
 	// Set once before Run(), read-only after that
 	LoadTimeout  time.Duration
 	LagTimeout   time.Duration
 	LagObservers bool
 	TurnRate     int
+	Countdown    time.Duration // Time between CountDownStart and CountDownEnd
+	DropDelay    time.Duration // Min lag duration before laggers are dropped once all other players voted
+	SyncLimit    uint32        // Max unacknowledged TimeSlots before a player is shown on the lag screen (0 to disable)
+	MinLatency   time.Duration // Lower bound for latency adapted to player RTTs (0 to disable adaptive latency)
+	MaxLatency   time.Duration // Upper bound for adaptive latency (0 for unbounded)
+	Equalize     time.Duration // Max delay added to actions of low ping players to equalize effective latency (0 to disable)
+	HCL          string        // Command string encoded in slot handicaps at game start (see EncodeHCL, SetHCL), ignored for saved games
+	Balance      Balancer      // Reassigns players to balance teams at game start (see GreedyBalancer, nil to disable), ignored for saved games
+	DropFakes    bool          // Remove fake players at game start instead of taking them into the game (see AddFakePlayer)
+	Report       Reporter      // Called with the result once the game is done (nil to disable)
 }
 
 type plack struct {
@@ -44,6 +61,11 @@ type plack struct {
 	a uint32
 }
 
+type delayedAction struct {
+	w3gs.PlayerAction
+	at time.Time
+}
+
 // NewGame initializes a new Game struct
 func NewGame(encoding w3gs.Encoding, slotInfo w3gs.SlotInfo, mapInfo w3gs.MapCheck) *Game {
 	var g = Game{
@@ -51,12 +73,34 @@ func NewGame(encoding w3gs.Encoding, slotInfo w3gs.SlotInfo, mapInfo w3gs.MapChe
 		LoadTimeout: 2 * time.Minute,
 		LagTimeout:  1 * time.Minute,
 		TurnRate:    40,
+		DropDelay:   25 * time.Second,
 	}
 
 	g.InitDefaultHandlers()
 	return &g
 }
 
+// NewSavedGame initializes a new Game struct that continues a saved game (see w3z.SaveGame)
+// Human slots are opened and only accept the player reserved with ReserveSlot(), who is assigned
+// the PlayerID and settings stored in the slot. Close the slots of players that will not return.
+func NewSavedGame(encoding w3gs.Encoding, slotInfo w3gs.SlotInfo, mapInfo w3gs.MapCheck) *Game {
+	slotInfo.Slots = append([]w3gs.SlotData(nil), slotInfo.Slots...)
+	for i, s := range slotInfo.Slots {
+		if s.SlotStatus == w3gs.SlotOccupied && !s.Computer {
+			slotInfo.Slots[i].SlotStatus = w3gs.SlotOpen
+		}
+	}
+
+	var g = NewGame(encoding, slotInfo, mapInfo)
+	g.saved = true
+	return g
+}
+
+// Saved reports if the game continues a saved game
+func (g *Game) Saved() bool {
+	return g.saved
+}
+
 // Stage of game
 func (g *Game) Stage() Stage {
 	return Stage(atomic.LoadUint32(&g.stage))
@@ -67,6 +111,27 @@ func (g *Game) Tick() Tick {
 	return Tick(atomic.LoadUint32(&g.tick))
 }
 
+// Latency between TimeSlots (0 if the game loop is disabled)
+func (g *Game) Latency() time.Duration {
+	if ms := atomic.LoadUint32(&g.latency); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	if g.TurnRate > 0 {
+		return time.Second / time.Duration(g.TurnRate)
+	}
+	return 0
+}
+
+// SetLatency changes the time between TimeSlots (rounded to milliseconds), overrides TurnRate
+// Can be changed while playing
+func (g *Game) SetLatency(d time.Duration) {
+	var ms = d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	atomic.StoreUint32(&g.latency, uint32(ms))
+}
+
 func (g *Game) swapStage(old Stage, new Stage) bool {
 	if !atomic.CompareAndSwapUint32(&g.stage, uint32(old), uint32(new)) {
 		return false
@@ -114,7 +179,89 @@ func (g *Game) EnqueueAction(a *w3gs.PlayerAction) {
 	g.actmut.Unlock()
 }
 
-// Start game
+// actmut should be locked
+func (g *Game) enqueueDelayed(now time.Time) {
+	var n = 0
+	for _, d := range g.delayed {
+		if d.at.After(now) {
+			g.delayed[n] = d
+			n++
+			continue
+		}
+
+		g.actions = append(g.actions, d.PlayerAction)
+	}
+	g.delayed = g.delayed[:n]
+}
+
+// equalizeDelay returns the delay for actions of p, the difference between the highest ping and that of p
+func (g *Game) equalizeDelay(p *Player) time.Duration {
+	var own = p.Ping()
+	if own == math.MaxUint32 {
+		return 0
+	}
+
+	var max uint32
+	g.slotmut.Lock()
+	for _, pl := range g.players {
+		if r := pl.Ping(); r != math.MaxUint32 && r > max {
+			max = r
+		}
+	}
+	g.slotmut.Unlock()
+
+	if max <= own {
+		return 0
+	}
+
+	var d = time.Duration(max-own) * time.Millisecond
+	if d > g.Equalize {
+		d = g.Equalize
+	}
+	return d
+}
+
+// Laggers currently shown on the lag screen
+func (g *Game) Laggers() []w3gs.LagPlayer {
+	g.actmut.Lock()
+	var res = make([]w3gs.LagPlayer, 0, len(g.laggers.Players))
+	for _, l := range g.laggers.Players {
+		if l.LagDurationMS != math.MaxUint32 {
+			res = append(res, l)
+		}
+	}
+	g.actmut.Unlock()
+	return res
+}
+
+// DropLaggers kicks all players that are currently lagging
+func (g *Game) DropLaggers() {
+	g.actmut.Lock()
+	for i := range g.laggers.Players {
+		g.dropLagger(i)
+	}
+	g.actmut.Unlock()
+}
+
+// SetHCL changes the command string encoded in slot handicaps at game start
+func (g *Game) SetHCL(cmd string) error {
+	for _, c := range cmd {
+		if !strings.ContainsRune(HCLChars, c) {
+			return ErrHCLInvalid
+		}
+	}
+
+	g.slotmut.Lock()
+	defer g.slotmut.Unlock()
+
+	if g.locked {
+		return ErrLocked
+	}
+	g.HCL = cmd
+	return nil
+}
+
+// Start game, players start loading once Countdown has passed
 func (g *Game) Start() error {
 	g.slotmut.Lock()
 
@@ -126,11 +273,79 @@ func (g *Game) Start() error {
 		}
 	}
 
+	// Wait for all original players of a saved game to rejoin
+	if g.saved && g.findEmptySlot() >= 0 {
+		g.slotmut.Unlock()
+		return ErrNotReady
+	}
+
+	var slots []w3gs.SlotData
+	if g.Balance != nil && !g.saved {
+		slots = append([]w3gs.SlotData(nil), g.slots...)
+
+		var players = make(map[uint8]*Player)
+		for pid, p := range g.players {
+			if g.slots[g.pidToSID(pid)].Team != g.ObsTeam {
+				players[pid] = p
+			}
+		}
+		if err := g.Balance(slots, players); err != nil {
+			g.slotmut.Unlock()
+			return err
+		}
+	}
+
+	if g.HCL != "" && !g.saved {
+		if slots == nil {
+			slots = append([]w3gs.SlotData(nil), g.slots...)
+		}
+		if err := EncodeHCL(slots, g.HCL); err != nil {
+			g.slotmut.Unlock()
+			return err
+		}
+	}
+
 	if !g.swapStage(StageLobby, StageLoading) {
 		g.slotmut.Unlock()
 		return ErrLocked
 	}
 
+	g.locked = true
+	if g.DropFakes && len(g.fakes) > 0 {
+		if slots == nil {
+			slots = append([]w3gs.SlotData(nil), g.slots...)
+		}
+		g.dropFakes(slots)
+	}
+	if slots != nil {
+		// Bypass refreshSlots(), SlotInfo events are prevented once loading
+		g.slots = slots
+		g.fireSlotChanges()
+		g.sendToAll(g.slotInfo())
+	}
+	g.sendToAll(&w3gs.CountDownStart{})
+	g.Fire(&CountdownStarted{Duration: g.Countdown})
+
+	if g.Countdown <= 0 {
+		g.load()
+		g.slotmut.Unlock()
+		return nil
+	}
+
+	g.slotmut.Unlock()
+
+	// Players leaving during the countdown are simply not loaded
+	time.AfterFunc(g.Countdown, func() {
+		g.slotmut.Lock()
+		g.load()
+		g.slotmut.Unlock()
+	})
+
+	return nil
+}
+
+// slotmut should be locked
+func (g *Game) load() {
 	var wg sync.WaitGroup
 	for pid := range g.players {
 		// Capture player
@@ -138,6 +353,7 @@ func (g *Game) Start() error {
 
 		// We are time critical from now on
 		p.SetWriteTimeout(5 * time.Millisecond)
+		p.SetPhase(network.PhaseLoading)
 
 		wg.Add(1)
 		var timeout = time.AfterFunc(g.LoadTimeout, func() {
@@ -146,11 +362,13 @@ func (g *Game) Start() error {
 		})
 		p.Once(&w3gs.GameLoaded{}, func(ev *network.Event) {
 			timeout.Stop()
+			p.SetPhase(network.PhaseSteady)
 			wg.Done()
 
 			g.SendToAll(&w3gs.PlayerLoaded{
 				PlayerID: p.PlayerInfo.PlayerID,
 			})
+			g.Fire(&PlayerLoaded{p})
 		})
 		// Do not leave the game until everyone is done loading to prevent desync
 		p.Once(network.RunStop{}, func(ev *network.Event) {
@@ -160,27 +378,31 @@ func (g *Game) Start() error {
 		})
 	}
 
-	g.locked = true
-	g.sendToAll(&w3gs.CountDownStart{})
+	g.initResult()
 	g.sendToAll(&w3gs.CountDownEnd{})
-	g.slotmut.Unlock()
+	g.Fire(&CountdownEnded{})
+
+	// Fake players load instantly
+	for pid := range g.fakes {
+		g.sendToAll(&w3gs.PlayerLoaded{PlayerID: pid})
+	}
 
 	go func() {
 		wg.Wait()
 		if !g.swapStage(StageLoading, StagePlaying) {
 			panic("lobby: Could not switch stage to Playing")
 		}
+		g.startResult()
 
-		if g.TurnRate > 0 {
+		if g.Latency() > 0 {
 			g.gameloop()
 		}
 
 		if !g.swapStage(StagePlaying, StageDone) {
 			panic("lobby: Could not switch stage to Done")
 		}
+		g.report()
 	}()
-
-	return nil
 }
 
 func (g *Game) gameloop() {
@@ -191,7 +413,8 @@ func (g *Game) gameloop() {
 	}()
 
 	var lastTick = time.Now()
-	var interval = time.Second / time.Duration(g.TurnRate)
+	var lastAdapt = lastTick
+	var interval = g.Latency()
 	var ticker = time.NewTicker(interval)
 
 	var pkt w3gs.TimeSlot
@@ -207,6 +430,20 @@ func (g *Game) gameloop() {
 			lastTick = tick
 		}
 
+		if g.MinLatency > 0 && lastTick.Sub(lastAdapt) >= time.Second {
+			lastAdapt = lastTick
+			g.adaptLatency()
+		}
+		if g.SyncLimit > 0 {
+			g.checkSync(g.Tick())
+		}
+
+		if l := g.Latency(); l != interval {
+			ticker.Stop()
+			interval = l
+			ticker = time.NewTicker(interval)
+		}
+
 		if inc < time.Millisecond {
 			inc = time.Millisecond
 		} else {
@@ -221,6 +458,10 @@ func (g *Game) gameloop() {
 			continue
 		}
 
+		if len(g.delayed) > 0 {
+			g.enqueueDelayed(lastTick)
+		}
+
 		var newTick = atomic.AddUint32(&g.tick, 1)
 
 		pkt.TimeIncrementMS = uint16(inc.Milliseconds())
@@ -234,6 +475,63 @@ func (g *Game) gameloop() {
 	}
 }
 
+// adaptLatency moves latency towards the highest player RTT
+func (g *Game) adaptLatency() {
+	var rtt uint32
+	g.slotmut.Lock()
+	for _, p := range g.players {
+		if r := p.RTT(); r != math.MaxUint32 && r > rtt {
+			rtt = r
+		}
+	}
+	g.slotmut.Unlock()
+
+	var clamp = func(d time.Duration) time.Duration {
+		if d < g.MinLatency {
+			d = g.MinLatency
+		}
+		if g.MaxLatency > 0 && d > g.MaxLatency {
+			d = g.MaxLatency
+		}
+		return d
+	}
+
+	// Smooth out spikes in RTT
+	var old = g.Latency()
+	var new = clamp((3*old + clamp(time.Duration(rtt)*time.Millisecond)) / 4).Round(time.Millisecond)
+	if new == old {
+		return
+	}
+
+	g.SetLatency(new)
+	g.Fire(&LatencyChanged{Old: old, New: new})
+}
+
+// checkSync shows players on the lag screen when they fall more than SyncLimit TimeSlots behind
+func (g *Game) checkSync(tick Tick) {
+	var lag []*Player
+	var synced []*Player
+
+	g.slotmut.Lock()
+	for _, p := range g.players {
+		var behind = uint32(tick - p.Tick())
+		if behind > g.SyncLimit {
+			lag = append(lag, p)
+		} else if behind <= g.SyncLimit/2 {
+			synced = append(synced, p)
+		}
+	}
+	g.slotmut.Unlock()
+
+	// Lag events lock slotmut and actmut
+	for _, p := range lag {
+		p.setLag(lagSync, true)
+	}
+	for _, p := range synced {
+		p.setLag(lagSync, false)
+	}
+}
+
 // actmut should be locked
 func (g *Game) incLaggers(inc uint32) {
 	if len(g.laggers.Players) > 0 && g.laggers.Players[0].LagDurationMS == 0 {
@@ -272,18 +570,27 @@ func (g *Game) incLaggers(inc uint32) {
 		g.laggers.Players[i].LagDurationMS += inc
 
 		var dur = time.Duration(g.laggers.Players[i].LagDurationMS) * time.Millisecond
-		if dur >= g.LagTimeout || (dur >= 25*time.Second && g.dropmask == 0) {
-			g.laggers.Players[i].LagDurationMS = math.MaxUint32
-
-			g.slotmut.Lock()
-			if p, ok := g.players[g.laggers.Players[i].PlayerID]; ok {
-				p.Fire(&network.AsyncError{Src: "incLaggers[LagDuration]", Err: ErrStraggling})
-				p.Kick(w3gs.LeaveDisconnect)
-			}
-			g.slotmut.Unlock()
+		if dur >= g.LagTimeout || (dur >= g.DropDelay && g.dropmask == 0) {
+			g.dropLagger(i)
 		}
 	}
+}
 
+// actmut should be locked
+func (g *Game) dropLagger(i int) {
+	if g.laggers.Players[i].LagDurationMS == math.MaxUint32 {
+		// Already kicked
+		return
+	}
+
+	g.laggers.Players[i].LagDurationMS = math.MaxUint32
+
+	g.slotmut.Lock()
+	if p, ok := g.players[g.laggers.Players[i].PlayerID]; ok {
+		p.Fire(&network.AsyncError{Src: "dropLagger", Err: ErrStraggling})
+		p.Kick(w3gs.LeaveDisconnect)
+	}
+	g.slotmut.Unlock()
 }
 
 // ackmut should be locked
@@ -410,6 +717,8 @@ func (g *Game) onPlayerLeft(ev *network.Event) {
 	g.ackmut.Lock()
 	g.drainAcks(uint(p.PlayerInfo.PlayerID))
 	g.ackmut.Unlock()
+
+	g.recordLeave(p)
 }
 
 func (g *Game) onGameAction(p *Player, pkt *w3gs.GameAction) {
@@ -418,10 +727,36 @@ func (g *Game) onGameAction(p *Player, pkt *w3gs.GameAction) {
 		p.Kick(w3gs.LeaveDisconnect)
 		return
 	}
-	g.EnqueueAction(&w3gs.PlayerAction{
+
+	var a = w3gs.PlayerAction{
 		PlayerID: p.PlayerInfo.PlayerID,
 		Data:     pkt.Data,
-	})
+	}
+
+	var delay time.Duration
+	if g.Equalize > 0 {
+		delay = g.equalizeDelay(p)
+	}
+	if delay <= 0 {
+		g.EnqueueAction(&a)
+		return
+	}
+
+	var at = time.Now().Add(delay)
+	a.Data = append([]byte(nil), a.Data...)
+
+	g.actmut.Lock()
+	// Preserve order of actions per player
+	for i := len(g.delayed) - 1; i >= 0; i-- {
+		if g.delayed[i].PlayerID == a.PlayerID {
+			if g.delayed[i].at.After(at) {
+				at = g.delayed[i].at
+			}
+			break
+		}
+	}
+	g.delayed = append(g.delayed, delayedAction{PlayerAction: a, at: at})
+	g.actmut.Unlock()
 }
 
 func (g *Game) onGameTick(p *Player, tick Tick, queue int) {
@@ -433,7 +768,7 @@ func (g *Game) onGameTick(p *Player, tick Tick, queue int) {
 	}
 
 	g.ackmut.Lock()
-	if queue >= 2000 || (g.TurnRate > 0 && time.Duration(queue)*time.Second/time.Duration(g.TurnRate) > 30*time.Second) {
+	if queue >= 2000 || time.Duration(queue)*g.Latency() > 30*time.Second {
 		// Drop all stragglers, we are more than 30s ahead
 		g.slotmut.Lock()
 		for pid := uint8(1); pid <= 32; pid++ {
@@ -473,14 +808,19 @@ func (g *Game) onStartLag(p *Player) {
 		LagDurationMS: 0,
 	})
 	g.actmut.Unlock()
+
+	g.Fire(&LagStarted{p})
 }
 
 func (g *Game) onStopLag(p *Player) {
+	var found = false
+
 	g.actmut.Lock()
 	for i, lp := range g.laggers.Players {
 		if lp.PlayerID != p.PlayerInfo.PlayerID {
 			continue
 		}
+		found = true
 
 		// Lag screen is up if LagDurationMS > 0
 		if lp.LagDurationMS > 0 {
@@ -492,10 +832,19 @@ func (g *Game) onStopLag(p *Player) {
 		g.laggers.Players = append(g.laggers.Players[:i], g.laggers.Players[i+1:]...)
 	}
 	g.actmut.Unlock()
+
+	if found {
+		g.Fire(&LagStopped{p})
+	}
 }
 
 func (g *Game) onDropLaggers(p *Player) {
 	g.actmut.Lock()
+	var vote = g.dropmask.Test(uint(p.PlayerInfo.PlayerID))
 	g.dropmask.Clear(uint(p.PlayerInfo.PlayerID))
 	g.actmut.Unlock()
+
+	if vote {
+		g.Fire(&DropVote{p})
+	}
 }
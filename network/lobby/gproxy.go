@@ -0,0 +1,456 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// GProxySig is the magic number used in the header of GProxy++ packets
+const GProxySig = 0xF8
+
+// GProxy++ packet types
+const (
+	gpsInit      = 1
+	gpsReconnect = 2
+	gpsAck       = 3
+	gpsReject    = 4
+)
+
+// GProxy++ reject reasons
+const (
+	gpsRejectInvalid  = 1
+	gpsRejectNotFound = 2
+)
+
+// GProxyAckInterval is the number of received packets after which an acknowledgement is sent to GProxy clients
+const GProxyAckInterval = 10
+
+// gproxyConn implements the host side of the GProxy++ reconnect protocol
+// Packets written to the client are buffered until acknowledged, so that they can be replayed
+// on a new connection after a drop. Reads block while waiting for the client to reconnect.
+// Every Write is expected to contain complete W3GS packets.
+type gproxyConn struct {
+	pid     uint8
+	port    uint16
+	key     uint32
+	timeout time.Duration
+
+	mut     sync.Mutex
+	conn    net.Conn
+	gen     uint32
+	swap    chan struct{}
+	closed  bool
+	dropped time.Time
+	err     error
+	rdl     time.Time
+	laddr   net.Addr
+	raddr   net.Addr
+
+	// GPS_INIT received
+	init bool
+
+	// Number of W3GS packets received/sent
+	recv uint32
+	sent uint32
+
+	// Unacknowledged packets, buf[0] is packet number base+1
+	buf  [][]byte
+	base uint32
+
+	// Reader state, only used by Read()
+	in  []byte
+	out []byte
+}
+
+func newGProxyConn(conn net.Conn, port uint16, timeout time.Duration) *gproxyConn {
+	return &gproxyConn{
+		conn:    conn,
+		laddr:   conn.LocalAddr(),
+		raddr:   conn.RemoteAddr(),
+		port:    port,
+		key:     rand.Uint32(),
+		timeout: timeout,
+		swap:    make(chan struct{}),
+	}
+}
+
+// current returns the active connection, waits for a reconnect after a drop
+func (c *gproxyConn) current() (net.Conn, uint32, error) {
+	c.mut.Lock()
+	for {
+		if c.closed {
+			var err = c.err
+			c.mut.Unlock()
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, 0, err
+		}
+		if c.conn != nil {
+			var conn, gen = c.conn, c.gen
+			c.mut.Unlock()
+			return conn, gen, nil
+		}
+
+		var swap = c.swap
+		var wait = time.Until(c.dropped.Add(c.timeout))
+		c.mut.Unlock()
+
+		var t = time.NewTimer(wait)
+		select {
+		case <-swap:
+			t.Stop()
+		case <-t.C:
+			c.mut.Lock()
+			if c.conn == nil && !c.closed {
+				c.closed = true
+			}
+			c.mut.Unlock()
+		}
+
+		c.mut.Lock()
+	}
+}
+
+// drop connection gen after err, returns false if the connection cannot be restored
+func (c *gproxyConn) drop(gen uint32, err error) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.closed {
+		return false
+	}
+	if gen != c.gen {
+		// Already replaced by a new connection
+		return true
+	}
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.dropped = time.Now()
+		c.err = err
+	}
+	if !c.init {
+		c.closed = true
+		return false
+	}
+	return true
+}
+
+// resume on conn after a GPS_RECONNECT with key, last is the number of packets received by the client
+func (c *gproxyConn) resume(conn net.Conn, key uint32, last uint32) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.closed || !c.init || key != c.key || last < c.base || last > c.sent {
+		return ErrReconnectRejected
+	}
+
+	var buf protocol.Buffer
+	writeGProxy(&buf, gpsReconnect, c.recv)
+	for _, b := range c.buf[last-c.base:] {
+		buf.WriteBlob(b)
+	}
+
+	if _, err := conn.Write(buf.Bytes); err != nil {
+		return err
+	}
+
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	if !c.rdl.IsZero() {
+		conn.SetReadDeadline(c.rdl)
+	}
+
+	c.trim(last)
+	c.conn = conn
+	c.laddr = conn.LocalAddr()
+	c.raddr = conn.RemoteAddr()
+	c.gen++
+	close(c.swap)
+	c.swap = make(chan struct{})
+
+	return nil
+}
+
+// initialized reports if GPS_INIT was received
+func (c *gproxyConn) initialized() bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.init
+}
+
+// mut should be locked
+func (c *gproxyConn) trim(ack uint32) {
+	if ack <= c.base || ack > c.sent {
+		return
+	}
+	var n = ack - c.base
+	for i := uint32(0); i < n; i++ {
+		c.buf[i] = nil
+	}
+	c.buf = c.buf[n:]
+	c.base = ack
+}
+
+// mut should be locked
+func (c *gproxyConn) send(b []byte) error {
+	if c.conn == nil {
+		return nil
+	}
+	if _, err := c.conn.Write(b); err != nil {
+		if !c.init {
+			return err
+		}
+		// Keep buffered packets, reader notices the drop
+		c.conn.Close()
+	}
+	return nil
+}
+
+func (c *gproxyConn) onPacket(b []byte) error {
+	if b[0] == w3gs.ProtocolSig {
+		c.mut.Lock()
+		c.recv++
+		var err error
+		if c.init && c.recv%GProxyAckInterval == 0 {
+			var buf protocol.Buffer
+			writeGProxy(&buf, gpsAck, c.recv)
+			err = c.send(buf.Bytes)
+		}
+		c.mut.Unlock()
+
+		c.out = append(c.out[:0], b...)
+		return err
+	}
+
+	var pkt = protocol.Buffer{Bytes: b[4:]}
+	switch b[1] {
+	case gpsInit:
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		if c.init {
+			return nil
+		}
+
+		c.init = true
+		c.base = c.sent
+
+		var buf protocol.Buffer
+		buf.WriteUInt8(GProxySig)
+		buf.WriteUInt8(gpsInit)
+		buf.WriteUInt16(15)
+		buf.WriteUInt16(c.port)
+		buf.WriteUInt8(c.pid)
+		buf.WriteUInt32(c.key)
+		buf.WriteUInt32(0) // Number of empty actions
+		return c.send(buf.Bytes)
+	case gpsAck:
+		if pkt.Size() < 4 {
+			return ErrInvalidPacket
+		}
+		c.mut.Lock()
+		c.trim(pkt.ReadUInt32())
+		c.mut.Unlock()
+		return nil
+	default:
+		return ErrInvalidPacket
+	}
+}
+
+// Read W3GS packets from the client, GProxy++ packets are handled internally
+func (c *gproxyConn) Read(b []byte) (int, error) {
+	for len(c.out) == 0 {
+		var need = 4
+		if len(c.in) >= 4 {
+			need = int(uint16(c.in[3])<<8 | uint16(c.in[2]))
+			if (c.in[0] != w3gs.ProtocolSig && c.in[0] != GProxySig) || need < 4 {
+				return 0, w3gs.ErrNoProtocolSig
+			}
+		}
+
+		if len(c.in) == need {
+			var err = c.onPacket(c.in)
+			c.in = c.in[:0]
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		conn, gen, err := c.current()
+		if err != nil {
+			return 0, err
+		}
+
+		if cap(c.in) < need {
+			c.in = append(make([]byte, 0, need), c.in...)
+		}
+
+		n, err := conn.Read(c.in[len(c.in):need])
+		c.in = c.in[:len(c.in)+n]
+
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return 0, err
+			}
+
+			// Partial packets are resent by the client after reconnecting
+			c.in = c.in[:0]
+			if !c.drop(gen, err) {
+				return 0, err
+			}
+		}
+	}
+
+	var n = copy(b, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+// Write W3GS packets to the client, packets are buffered until acknowledged
+func (c *gproxyConn) Write(b []byte) (int, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.closed {
+		var err = c.err
+		if err == nil {
+			err = io.ErrClosedPipe
+		}
+		return 0, err
+	}
+
+	for p := b; len(p) >= 4; {
+		var size = int(uint16(p[3])<<8 | uint16(p[2]))
+		if size < 4 || size > len(p) {
+			break
+		}
+
+		c.sent++
+		if c.init {
+			c.buf = append(c.buf, append([]byte(nil), p[:size]...))
+		}
+		p = p[size:]
+	}
+
+	if err := c.send(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close the connection, stops waiting for reconnects
+func (c *gproxyConn) Close() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.closed && c.conn == nil {
+		return nil
+	}
+
+	c.closed = true
+	close(c.swap)
+	c.swap = make(chan struct{})
+
+	if c.conn == nil {
+		return nil
+	}
+	var err = c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// LocalAddr of the last active connection
+func (c *gproxyConn) LocalAddr() net.Addr {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.laddr
+}
+
+// RemoteAddr of the last active connection
+func (c *gproxyConn) RemoteAddr() net.Addr {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.raddr
+}
+
+// SetDeadline on the active connection
+func (c *gproxyConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline on the active connection and on connections after reconnecting
+func (c *gproxyConn) SetReadDeadline(t time.Time) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.rdl = t
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline on the active connection
+func (c *gproxyConn) SetWriteDeadline(t time.Time) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.SetWriteDeadline(t)
+}
+
+func rejectGProxy(conn net.Conn, reason uint32) {
+	var buf protocol.Buffer
+	writeGProxy(&buf, gpsReject, reason)
+	conn.Write(buf.Bytes)
+}
+
+func writeGProxy(buf *protocol.Buffer, pid uint8, val uint32) {
+	buf.WriteUInt8(GProxySig)
+	buf.WriteUInt8(pid)
+	buf.WriteUInt16(8)
+	buf.WriteUInt32(val)
+}
+
+// readRaw reads exactly one W3GS or GProxy++ packet from r
+func readRaw(r io.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if hdr[0] != w3gs.ProtocolSig && hdr[0] != GProxySig {
+		return nil, w3gs.ErrNoProtocolSig
+	}
+
+	var size = int(uint16(hdr[3])<<8 | uint16(hdr[2]))
+	if size < 4 {
+		return nil, w3gs.ErrNoProtocolSig
+	}
+
+	var b = make([]byte, size)
+	copy(b, hdr[:])
+	if _, err := io.ReadFull(r, b[4:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
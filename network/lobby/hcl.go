@@ -0,0 +1,99 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// HCLChars lists the characters that can be encoded in a HCL command string
+const HCLChars = "abcdefghijklmnopqrstuvwxyz0123456789 -=,."
+
+// Valid handicaps are 50, 60, .., 100
+const numHandicaps = 6
+
+// hclMap maps an encoded index to a handicap value, skipping 0 and valid handicaps
+var hclMap = func() (res [256]uint8) {
+	var j uint8
+	for i := range res {
+		if j == 0 || (j >= 50 && j <= 100 && j%10 == 0) {
+			j++
+		}
+		res[i] = j
+		j++
+	}
+	return res
+}()
+
+func validHandicap(h uint8) bool {
+	return h >= 50 && h <= 100 && h%10 == 0
+}
+
+// EncodeHCL encodes cmd in the handicaps of occupied slots, one character per slot (HostBot Command Library)
+// Maps that support HCL decode cmd once loaded and restore the original handicaps, on other maps the handicaps are ruined
+func EncodeHCL(slots []w3gs.SlotData, cmd string) error {
+	var idx = make([]int, 0, len(cmd))
+	for _, c := range cmd {
+		var i = strings.IndexRune(HCLChars, c)
+		if i < 0 {
+			return ErrHCLInvalid
+		}
+		idx = append(idx, i)
+	}
+
+	var occupied = 0
+	for _, s := range slots {
+		if s.SlotStatus != w3gs.SlotOccupied {
+			continue
+		}
+		if !validHandicap(s.Handicap) {
+			return ErrInvalidArgument
+		}
+		occupied++
+	}
+	if len(idx) > occupied {
+		return ErrHCLTooLong
+	}
+
+	var sid = 0
+	for _, c := range idx {
+		for slots[sid].SlotStatus != w3gs.SlotOccupied {
+			sid++
+		}
+		var h = int(slots[sid].Handicap-50) / 10
+		slots[sid].Handicap = hclMap[h+c*numHandicaps]
+		sid++
+	}
+
+	return nil
+}
+
+// DecodeHCL decodes the command string from the handicaps of occupied slots and restores the original handicaps
+func DecodeHCL(slots []w3gs.SlotData) (string, error) {
+	var res strings.Builder
+	for sid := range slots {
+		if slots[sid].SlotStatus != w3gs.SlotOccupied {
+			continue
+		}
+		if validHandicap(slots[sid].Handicap) {
+			break
+		}
+
+		var i = 0
+		for i < len(hclMap) && hclMap[i] != slots[sid].Handicap {
+			i++
+		}
+		if i/numHandicaps >= len(HCLChars) {
+			return res.String(), ErrHCLInvalid
+		}
+
+		res.WriteByte(HCLChars[i/numHandicaps])
+		slots[sid].Handicap = uint8(50 + 10*(i%numHandicaps))
+	}
+
+	return res.String(), nil
+}
@@ -0,0 +1,170 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPBans is a BanStore backed by a remote HTTP/JSON service, used to share a ban list between instances
+// Requests are made to URL with the ban as JSON body (POST) or with name, realm and ip as query parameters (GET, DELETE),
+// GET responds with the matching ban as JSON or with status 404 if not banned (see BanHandler)
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type HTTPBans struct {
+	URL    string
+	Client *http.Client // nil for a client with a 5 second timeout
+}
+
+var defaultBanClient = &http.Client{Timeout: 5 * time.Second}
+
+func (h *HTTPBans) client() *http.Client {
+	if h.Client != nil {
+		return h.Client
+	}
+	return defaultBanClient
+}
+
+func (h *HTTPBans) query(name string, realm string, ip net.IP) string {
+	var q = url.Values{}
+	if name != "" {
+		q.Set("name", name)
+	}
+	if realm != "" {
+		q.Set("realm", realm)
+	}
+	if ip != nil {
+		q.Set("ip", ip.String())
+	}
+	return h.URL + "?" + q.Encode()
+}
+
+func (h *HTTPBans) do(req *http.Request, res interface{}) (int, error) {
+	resp, err := h.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return resp.StatusCode, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return resp.StatusCode, ErrBanStore
+	case res != nil:
+		if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Add ban
+func (h *HTTPBans) Add(ban *Ban) error {
+	var b = *ban
+	if b.Created.IsZero() {
+		b.Created = time.Now()
+	}
+
+	body, err := json.Marshal(&b)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if status, err := h.do(req, nil); err != nil {
+		return err
+	} else if status == http.StatusNotFound {
+		return ErrBanStore
+	}
+	return nil
+}
+
+// Remove all bans matching name on realm or ip
+func (h *HTTPBans) Remove(name string, realm string, ip net.IP) error {
+	req, err := http.NewRequest(http.MethodDelete, h.query(name, realm, ip), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.do(req, nil)
+	return err
+}
+
+// Find a ban matching name on realm or ip (nil if not banned)
+func (h *HTTPBans) Find(name string, realm string, ip net.IP) (*Ban, error) {
+	req, err := http.NewRequest(http.MethodGet, h.query(name, realm, ip), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var ban Ban
+	status, err := h.do(req, &ban)
+	if err != nil || status == http.StatusNotFound {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+// BanHandler serves store over HTTP/JSON, reference implementation of the service used by HTTPBans
+func BanHandler(store BanStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q = r.URL.Query()
+
+		var ip net.IP
+		if s := q.Get("ip"); s != "" {
+			if ip = net.ParseIP(s); ip == nil {
+				http.Error(w, "Invalid IP", http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			ban, err := store.Find(q.Get("name"), q.Get("realm"), ip)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if ban == nil {
+				http.NotFound(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ban)
+		case http.MethodPost:
+			var ban Ban
+			if err := json.NewDecoder(r.Body).Decode(&ban); err != nil || (ban.Name == "" && ban.IP == nil) {
+				http.Error(w, "Invalid ban", http.StatusBadRequest)
+				return
+			}
+			if err := store.Add(&ban); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			if err := store.Remove(q.Get("name"), q.Get("realm"), ip); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -6,10 +6,14 @@
 package lobby
 
 import (
+	"context"
+	"io"
 	"math"
 	"math/bits"
 	"math/rand"
 	"net"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,16 +32,36 @@ type Lobby struct {
 	slotmut  sync.Mutex
 	slotBase w3gs.SlotInfo
 	slots    []w3gs.SlotData
+	fired    []w3gs.SlotData
 	players  map[uint8]*Player
+	fakes    map[uint8]*w3gs.PlayerInfo
+	reserved []string
+	kicked   protocol.BitSet32
 	locked   bool
+	saved    bool
 
 	// Set once before Run(), read-only after that
 	w3gs.Encoder
 	w3gs.MapCheck
 	ObsTeam      uint8
+	Observers    ObsMode // Observer slots are only available if ObsTeam is not ObsDisabled and Observers.Slots()
 	ColorSet     protocol.BitSet32
 	ReadyTimeout time.Duration
+	MaxPing      time.Duration // Players with a higher (smoothed) ping are kicked from the lobby (0 to disable)
 	ShareAddr    bool
+	Timeouts     network.TimeoutPolicy
+	Logger       network.Logger
+
+	MapData     io.ReaderAt  // Map file offered to players that do not have it, MapCheck.FileSize bytes (nil to disable downloads)
+	UploadRate  int          // Max upload rate per downloading player in bytes per second (0 for unlimited)
+	UploadLimit *UploadLimit // Max combined upload rate, can be shared with other lobbies (nil for unlimited)
+
+	ReconnectPort    uint16        // Port on which Accept() is served, announced to GProxy++ clients for reconnects (0 to disable GProxy++ support)
+	ReconnectTimeout time.Duration // Max time to wait for a dropped GProxy++ client to reconnect
+
+	Bans        BanStore     // Banned players are rejected when joining (nil to disable)
+	Realm       string       // Realm players join from, used to match bans by name (empty if unknown)
+	PlayerLimit *PlayerLimit // Max combined number of players, can be shared with other lobbies (nil for unlimited)
 }
 
 // NewLobby initializes a new Lobby struct
@@ -53,16 +77,25 @@ func NewLobby(encoding w3gs.Encoding, slotInfo w3gs.SlotInfo, mapInfo w3gs.MapCh
 	}
 
 	return &Lobby{
-		Encoder:      w3gs.Encoder{Encoding: encoding},
-		MapCheck:     mapInfo,
-		ObsTeam:      obsteam,
-		ColorSet:     colors,
-		ReadyTimeout: 10 * time.Second,
+		Encoder:          w3gs.Encoder{Encoding: encoding},
+		MapCheck:         mapInfo,
+		ObsTeam:          obsteam,
+		ColorSet:         colors,
+		ReadyTimeout:     10 * time.Second,
+		ReconnectTimeout: 45 * time.Second,
+		Timeouts: network.TimeoutPolicy{
+			Handshake: network.Timeouts{Read: 15 * time.Second},
+			Steady:    network.Timeouts{Read: time.Minute},
+			Loading:   network.Timeouts{Read: 3 * time.Minute},
+		},
 
 		slotBase: slotInfo,
 		slots:    append([]w3gs.SlotData{}, slotInfo.Slots...),
+		fired:    append([]w3gs.SlotData{}, slotInfo.Slots...),
+		reserved: make([]string, len(slotInfo.Slots)),
 
 		players: make(map[uint8]*Player),
+		fakes:   make(map[uint8]*w3gs.PlayerInfo),
 	}
 }
 
@@ -76,10 +109,33 @@ func (l *Lobby) pidToSID(pid uint8) int {
 	panic("lobby: Cannot not find slot with PlayerID")
 }
 
+// Observer team players can join, ObsDisabled if observer slots are unavailable
+func (l *Lobby) obsTeam() uint8 {
+	if !l.Observers.Slots() {
+		return ObsDisabled
+	}
+	return l.ObsTeam
+}
+
+// Slot reserved for observers while observer slots are unavailable
+func (l *Lobby) unavailable(s *w3gs.SlotData) bool {
+	return l.ObsTeam != ObsDisabled && s.Team == l.ObsTeam && !l.Observers.Slots()
+}
+
+// slotmut should be locked
+func (l *Lobby) isObserver(pid uint8) bool {
+	for _, s := range l.slots {
+		if s.SlotStatus == w3gs.SlotOccupied && s.PlayerID == pid {
+			return s.Team == l.ObsTeam
+		}
+	}
+	return false
+}
+
 // slotmut should be locked
 func (l *Lobby) findEmptySlot() int {
 	for i, s := range l.slots {
-		if s.SlotStatus == w3gs.SlotOpen {
+		if s.SlotStatus == w3gs.SlotOpen && !l.unavailable(&l.slotBase.Slots[i]) {
 			return i
 		}
 	}
@@ -89,7 +145,7 @@ func (l *Lobby) findEmptySlot() int {
 // slotmut should be locked
 func (l *Lobby) findEmptyTeamSlot(team uint8) int {
 	for i, s := range l.slots {
-		if s.SlotStatus == w3gs.SlotOpen && s.Team == team {
+		if s.SlotStatus == w3gs.SlotOpen && s.Team == team && !l.unavailable(&l.slotBase.Slots[i]) {
 			return i
 		}
 	}
@@ -98,14 +154,53 @@ func (l *Lobby) findEmptyTeamSlot(team uint8) int {
 
 // slotmut should be locked
 func (l *Lobby) findEmptyPID() uint8 {
-	var players protocol.BitSet32
+	// Do not reuse IDs of kicked players that did not leave yet
+	var players = l.kicked
 	for uid := range l.players {
 		players.Set(uint(uid))
 	}
+	for uid := range l.fakes {
+		players.Set(uint(uid))
+	}
 	players = ^players
 	return (uint8)(bits.TrailingZeros32(uint32(players)) + 1)
 }
 
+// slotmut should be locked
+func (l *Lobby) findReservedSlot(name string) int {
+	for i, r := range l.reserved {
+		if r != "" && strings.EqualFold(r, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// slotmut should be locked
+func (l *Lobby) findPlayer(name string) *Player {
+	var res *Player
+	for _, p := range l.players {
+		if strings.EqualFold(p.PlayerInfo.PlayerName, name) {
+			return p
+		}
+		if len(name) <= len(p.PlayerInfo.PlayerName) && strings.EqualFold(p.PlayerInfo.PlayerName[:len(name)], name) {
+			if res != nil {
+				return nil
+			}
+			res = p
+		}
+	}
+	return res
+}
+
+// FindPlayer by name, or by unique case-insensitive prefix of its name (nil if not found)
+func (l *Lobby) FindPlayer(name string) *Player {
+	l.slotmut.Lock()
+	var p = l.findPlayer(name)
+	l.slotmut.Unlock()
+	return p
+}
+
 // slotmut should be locked
 func (l *Lobby) findEmptyTeam() uint8 {
 	var teams protocol.BitSet32
@@ -157,20 +252,29 @@ func (l *Lobby) initSlot(slot int) error {
 		Race:           l.slotBase.Slots[slot].Race,
 		Handicap:       l.slotBase.Slots[slot].Handicap,
 	}
+	if l.saved {
+		// Restore slot from saved game
+		sd = l.slotBase.Slots[slot]
+		sd.DownloadStatus = 255
+		sd.SlotStatus = w3gs.SlotOccupied
+		l.slots[slot] = sd
+		return nil
+	}
 	if l.slotBase.SlotLayout&w3gs.LayoutFixedPlayerSettings == 0 {
 		sd.Color = l.findEmptyColor()
 	}
 	if l.slotBase.SlotLayout&w3gs.LayoutCustomForces == 0 {
-		var t = l.ObsTeam
+		var obs = l.obsTeam()
+		var t = obs
 		if uint8(l.countPlayers()) < l.slotBase.NumPlayers {
 			t = l.findEmptyTeam()
 		}
 		if t < l.slotBase.NumPlayers {
 			sd.Team = t
-		} else if l.ObsTeam == ObsDisabled {
+		} else if obs == ObsDisabled {
 			return ErrFull
 		} else {
-			sd.Team = l.ObsTeam
+			sd.Team = obs
 		}
 	}
 
@@ -186,10 +290,12 @@ func (l *Lobby) sendToAll(pkt w3gs.Packet) {
 		return
 	}
 
+	// Encoder buffer is reused, copy before queueing
+	b = append([]byte(nil), b...)
+
 	for _, p := range l.players {
-		if _, err := p.Write(b); err != nil && !network.IsCloseError(err) {
-			p.Fire(&network.AsyncError{Src: "Lobby.sendToAll[Write]", Err: err})
-			p.Close()
+		if err := p.Enqueue(b); err != nil {
+			p.Fire(&network.AsyncError{Src: "Lobby.sendToAll[Enqueue]", Err: err})
 		}
 	}
 }
@@ -202,8 +308,23 @@ func (l *Lobby) slotInfo() *w3gs.SlotInfo {
 	return &slotInfo
 }
 
+// slotmut should be locked
+func (l *Lobby) fireSlotChanges() {
+	for i := range l.slots {
+		if l.slots[i] == l.fired[i] {
+			continue
+		}
+
+		var old = l.fired[i]
+		l.fired[i] = l.slots[i]
+		l.Fire(&SlotChanged{SID: i, Old: old, New: l.slots[i]})
+	}
+}
+
 // slotmut should be locked
 func (l *Lobby) refreshSlots() {
+	l.fireSlotChanges()
+
 	var s = l.slotInfo()
 
 	if l.Fire(s) {
@@ -220,36 +341,61 @@ func (l *Lobby) join(conn net.Conn, join *w3gs.Join) (*Player, error) {
 		JoinCounter: join.JoinCounter,
 		PlayerName:  join.PlayerName,
 	})
-	p.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), l.Encoding)
+	if l.ReconnectPort != 0 {
+		p.gproxy = newGProxyConn(conn, l.ReconnectPort, l.ReconnectTimeout)
+		p.SetConn(p.gproxy, w3gs.NewFactoryCache(w3gs.DefaultFactory), l.Encoding)
+	} else {
+		p.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), l.Encoding)
+	}
+	p.SetTimeoutPolicy(&l.Timeouts)
+	p.SetLogger(l.Logger)
+	if l.MapData != nil {
+		p.DownloadSize = l.MapCheck.FileSize
+	}
 
+	// Player is not running yet, send rejections directly
 	if l.locked {
-		p.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinStarted})
+		p.W3GSConn.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinStarted})
 		return nil, ErrLocked
 	}
 
-	var sid = l.findEmptySlot()
+	var sid = l.findReservedSlot(join.PlayerName)
+	if sid >= 0 {
+		l.clearSlot(sid)
+	} else if l.saved {
+		p.W3GSConn.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinInvalid})
+		return nil, ErrNotReserved
+	} else {
+		sid = l.findEmptySlot()
+	}
 	if sid < 0 {
-		p.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinFull})
+		p.W3GSConn.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinFull})
 		return nil, ErrFull
 	}
 	if err := l.initSlot(sid); err != nil {
-		p.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinFull})
+		p.W3GSConn.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinFull})
 		return nil, err
 	}
 
 	var pid = l.findEmptyPID()
+	if l.saved {
+		pid = l.slots[sid].PlayerID
+	}
 	p.PlayerInfo.PlayerID = pid
+	if p.gproxy != nil {
+		p.gproxy.pid = pid
+	}
 	l.slots[sid].PlayerID = pid
 
 	var slotInfo = w3gs.SlotInfoJoin{
 		SlotInfo:     *l.slotInfo(),
 		PlayerID:     pid,
-		ExternalAddr: protocol.Addr(conn.RemoteAddr()),
+		ExternalAddr: protocol.Addr4(conn.RemoteAddr()),
 	}
 	slotInfo.Slots = l.slots
 
 	if _, err := p.Send(&slotInfo); err != nil {
-		p.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinInvalid})
+		p.W3GSConn.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinInvalid})
 		return nil, err
 	}
 	if _, err := p.SendOrClose(&w3gs.Ping{}); err != nil {
@@ -260,7 +406,7 @@ func (l *Lobby) join(conn net.Conn, join *w3gs.Join) (*Player, error) {
 	}
 
 	if l.ShareAddr {
-		p.PlayerInfo.ExternalAddr = protocol.Addr(conn.RemoteAddr())
+		p.PlayerInfo.ExternalAddr = protocol.Addr4(conn.RemoteAddr())
 		p.PlayerInfo.InternalAddr = join.InternalAddr
 		p.PlayerInfo.ExternalAddr.Port = join.ListenPort
 		p.PlayerInfo.InternalAddr.Port = join.ListenPort
@@ -299,12 +445,49 @@ func (l *Lobby) join(conn net.Conn, join *w3gs.Join) (*Player, error) {
 		}
 	}
 
+	for _, info := range l.fakes {
+		p.SendOrClose(info)
+	}
+
 	l.players[pid] = p
+	l.fireSlotChanges()
 	l.Fire(&slotInfo.SlotInfo)
 
 	return p, nil
 }
 
+// slotmut should be locked
+func (l *Lobby) clearSlot(slot int) {
+	if l.slots[slot].SlotStatus == w3gs.SlotOccupied {
+		if p, ok := l.players[l.slots[slot].PlayerID]; ok {
+			// Kick squatter, free slot immediately
+			p.Kick(w3gs.LeaveLobby)
+			l.removePlayer(p)
+			l.kicked.Set(uint(p.PlayerInfo.PlayerID))
+		} else if l.isFakeSlot(l.slots, slot) {
+			l.removeFake(l.slots, slot)
+		}
+	}
+
+	l.slots[slot] = l.slotBase.Slots[slot]
+	l.slots[slot].SlotStatus = w3gs.SlotOpen
+}
+
+// slotmut should be locked
+func (l *Lobby) removePlayer(p *Player) {
+	var pid = p.PlayerInfo.PlayerID
+	delete(l.players, pid)
+
+	var sid = l.pidToSID(pid)
+	l.slots[sid] = l.slotBase.Slots[sid]
+
+	l.sendToAll(&w3gs.PlayerLeft{
+		PlayerID: pid,
+		Reason:   p.LeaveReason(),
+	})
+	l.refreshSlots()
+}
+
 // slotmut should be locked
 func (l *Lobby) swapSlots(slotA int, slotB int, swapTeams bool) {
 	l.slots[slotA], l.slots[slotB] = l.slots[slotB], l.slots[slotA]
@@ -345,6 +528,11 @@ func (l *Lobby) changeSlotStatus(slot int, status w3gs.SlotStatus, kick bool) er
 		return nil
 	}
 
+	if l.isFakeSlot(l.slots, slot) {
+		l.removeFake(l.slots, slot)
+		return nil
+	}
+
 	// Reset computer slot
 	l.slots[slot] = l.slotBase.Slots[slot]
 	return nil
@@ -365,7 +553,7 @@ func (l *Lobby) changeTeam(slot int, t uint8) error {
 	if l.slotBase.SlotLayout&w3gs.LayoutCustomForces != 0 {
 		return ErrInvalidArgument
 	}
-	if (t >= l.slotBase.NumPlayers) && ((l.ObsTeam == ObsDisabled) || (t != l.ObsTeam)) {
+	if (t >= l.slotBase.NumPlayers) && ((l.obsTeam() == ObsDisabled) || (t != l.ObsTeam)) {
 		return ErrInvalidArgument
 	}
 
@@ -435,6 +623,70 @@ func (l *Lobby) Player(id uint8) *Player {
 	return player
 }
 
+// Kick player with id
+func (l *Lobby) Kick(id uint8, reason w3gs.LeaveReason) error {
+	var p = l.Player(id)
+	if p == nil {
+		return ErrUnknownPlayer
+	}
+
+	p.Kick(reason)
+	return nil
+}
+
+// Ban player with id by name and IP and kick it, requires Bans to be set
+func (l *Lobby) Ban(id uint8, reason string) error {
+	if l.Bans == nil {
+		return ErrInvalidArgument
+	}
+
+	var p = l.Player(id)
+	if p == nil {
+		return ErrUnknownPlayer
+	}
+
+	var ban = Ban{
+		Name:   p.PlayerInfo.PlayerName,
+		Realm:  l.Realm,
+		Reason: reason,
+	}
+	if conn := p.Conn(); conn != nil {
+		ban.IP = addrIP(conn.RemoteAddr())
+	}
+
+	if err := l.Bans.Add(&ban); err != nil {
+		return err
+	}
+
+	p.Kick(w3gs.LeaveLobby)
+	return nil
+}
+
+// ReserveSlot sid for player name (empty to remove reservation)
+// Whoever occupies the slot is kicked once the reserved player joins
+func (l *Lobby) ReserveSlot(sid int, name string) error {
+	if sid < 0 || sid >= len(l.slotBase.Slots) {
+		return ErrInvalidSlot
+	}
+
+	l.slotmut.Lock()
+	l.reserved[sid] = name
+	l.slotmut.Unlock()
+	return nil
+}
+
+// Reserved returns the name of the player slot sid is reserved for (empty if none)
+func (l *Lobby) Reserved(sid int) string {
+	if sid < 0 || sid >= len(l.slotBase.Slots) {
+		return ""
+	}
+
+	l.slotmut.Lock()
+	var name = l.reserved[sid]
+	l.slotmut.Unlock()
+	return name
+}
+
 // Lock lobby, disabling joins and slot changes
 func (l *Lobby) Lock() {
 	l.slotmut.Lock()
@@ -483,6 +735,24 @@ func (l *Lobby) CountPlayers() int {
 	return c
 }
 
+// Pings of the players per slot (index matches SlotInfo().Slots), math.MaxUint32 for slots without a (connected) player
+func (l *Lobby) Pings() []uint32 {
+	l.slotmut.Lock()
+	var res = make([]uint32, len(l.slots))
+	for i, s := range l.slots {
+		res[i] = math.MaxUint32
+		if s.SlotStatus != w3gs.SlotOccupied || s.Computer {
+			continue
+		}
+		if p, ok := l.players[s.PlayerID]; ok {
+			res[i] = p.Ping()
+		}
+	}
+	l.slotmut.Unlock()
+
+	return res
+}
+
 // SlotsUsed counts occupied+closed slots
 func (l *Lobby) SlotsUsed() int {
 	var c = 0
@@ -516,6 +786,72 @@ func (l *Lobby) SendToAll(pkt w3gs.Packet) {
 	l.slotmut.Unlock()
 }
 
+// Say sends a chat message to all players
+// The lobby itself does not occupy a slot, so the message appears to be sent by the player with the lowest ID
+func (l *Lobby) Say(s string) {
+	if len(s) > 254 {
+		s = s[:254]
+	}
+
+	l.slotmut.Lock()
+	defer l.slotmut.Unlock()
+
+	var ids = make([]uint8, 0, len(l.players))
+	for pid := range l.players {
+		ids = append(ids, pid)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var msg = w3gs.Message{
+		RecipientIDs: ids,
+		SenderID:     ids[0],
+		Type:         w3gs.MsgChat,
+		Content:      s,
+	}
+	if l.locked {
+		msg.Type = w3gs.MsgChatExtra
+		msg.Scope = w3gs.ScopeAll
+	}
+
+	l.sendToAll(&w3gs.MessageRelay{Message: msg})
+}
+
+// SayTo sends s as a private message to player with id
+func (l *Lobby) SayTo(id uint8, s string) error {
+	if len(s) > 254 {
+		s = s[:254]
+	}
+
+	l.slotmut.Lock()
+	defer l.slotmut.Unlock()
+
+	var p = l.players[id]
+	if p == nil {
+		return ErrUnknownPlayer
+	}
+
+	var msg = w3gs.Message{
+		RecipientIDs: []uint8{id},
+		SenderID:     id,
+		Type:         w3gs.MsgChat,
+		Content:      s,
+	}
+	if l.locked {
+		msg.Type = w3gs.MsgChatExtra
+		msg.Scope = w3gs.ScopeAll
+	}
+
+	b, err := l.Encoder.Serialize(&w3gs.MessageRelay{Message: msg})
+	if err != nil {
+		return err
+	}
+
+	return p.Enqueue(append([]byte(nil), b...))
+}
+
 // OpenAllSlots opens all closed slots
 func (l *Lobby) OpenAllSlots() error {
 	var refresh = false
@@ -756,24 +1092,75 @@ func (l *Lobby) ChangeComputer(sid int, ai w3gs.AI) error {
 
 // JoinAndServe player connection
 func (l *Lobby) JoinAndServe(conn net.Conn, join *w3gs.Join) (*Player, error) {
-	l.slotmut.Lock()
-	p, err := l.join(conn, join)
-	l.slotmut.Unlock()
+	var p *Player
+	var err = l.checkBan(conn, join)
+	if err == nil {
+		err = l.checkLimit(conn)
+	}
+	if err == nil {
+		l.slotmut.Lock()
+		p, err = l.join(conn, join)
+		l.slotmut.Unlock()
+
+		if err != nil {
+			l.PlayerLimit.release()
+		}
+	}
 
 	if err != nil {
+		if l.Logger != nil {
+			l.Logger.Warn("Join rejected", network.LogKeyUser, join.PlayerName, network.LogKeyPeer, conn.RemoteAddr(), network.LogKeyError, err)
+		}
 		conn.Close()
 		return nil, err
 	}
 
+	if l.Logger != nil {
+		l.Logger.Info("Player joined", network.LogKeyUser, join.PlayerName, network.LogKeyPeer, conn.RemoteAddr())
+	}
+
 	var timeout = time.AfterFunc(l.ReadyTimeout, func() {
+		if p.Ready() {
+			return
+		}
 		p.Fire(&network.AsyncError{Src: "Lobby.JoinAndServe[ReadyTimeout]", Err: ErrNotReady})
 		p.Kick(w3gs.LeaveLobby)
 	})
 	p.Once(&Ready{}, func(ev *network.Event) {
 		timeout.Stop()
+
+		// Join sequence finished
+		p.SetPhase(network.PhaseSteady)
+	})
+	var dl *download
+	if l.MapData != nil {
+		dl = l.newDownload(p)
+		dl.finish = func() {
+			timeout.Reset(l.ReadyTimeout)
+		}
+		p.On(&w3gs.StartDownload{}, func(ev *network.Event) {
+			l.startDownload(dl, timeout)
+		})
+		p.On(&w3gs.MapPartOK{}, func(ev *network.Event) {
+			dl.received(ev.Arg.(*w3gs.MapPartOK).ChunkPos)
+		})
+		p.On(&w3gs.MapPartError{}, func(ev *network.Event) {
+			dl.fail()
+		})
+	}
+	p.Once(&w3gs.MapState{}, func(ev *network.Event) {
+		var s = ev.Arg.(*w3gs.MapState)
+		l.Fire(&MapChecked{Player: p, Ready: s.Ready && s.FileSize == l.MapCheck.FileSize})
 	})
 	p.On(&w3gs.MapState{}, func(ev *network.Event) {
-		l.onMapState(p, ev.Arg.(*w3gs.MapState))
+		var s = ev.Arg.(*w3gs.MapState)
+		if dl != nil && (!s.Ready || s.FileSize != l.MapCheck.FileSize) {
+			l.startDownload(dl, timeout)
+			if !s.Ready {
+				dl.received(s.FileSize)
+			}
+		}
+		l.onMapState(p, s)
 	})
 	p.On(&w3gs.Message{}, func(ev *network.Event) {
 		l.onMessage(p, ev.Arg.(*w3gs.Message))
@@ -781,6 +1168,11 @@ func (l *Lobby) JoinAndServe(conn net.Conn, join *w3gs.Join) (*Player, error) {
 	p.On(&w3gs.PlayerExtra{}, func(ev *network.Event) {
 		l.onPlayerExtra(p, ev.Arg.(*w3gs.PlayerExtra))
 	})
+	if l.MaxPing > 0 {
+		p.On(&PingUpdated{}, func(ev *network.Event) {
+			l.onPingUpdated(p, ev.Arg.(*PingUpdated))
+		})
+	}
 
 	l.wg.Add(1)
 	go func() {
@@ -790,15 +1182,47 @@ func (l *Lobby) JoinAndServe(conn net.Conn, join *w3gs.Join) (*Player, error) {
 		}
 
 		timeout.Stop()
+		if dl != nil {
+			dl.close()
+		}
 		l.onLeave(p)
 	}()
 
 	return p, nil
 }
 
+func (l *Lobby) checkBan(conn net.Conn, join *w3gs.Join) error {
+	if l.Bans == nil {
+		return nil
+	}
+
+	ban, err := l.Bans.Find(join.PlayerName, l.Realm, addrIP(conn.RemoteAddr()))
+	if err == nil && ban != nil {
+		err = ErrBanned
+	}
+	if err != nil {
+		w3gs.Write(conn, &w3gs.RejectJoin{Reason: w3gs.RejectJoinInvalid}, l.Encoding)
+	}
+	return err
+}
+
+func (l *Lobby) checkLimit(conn net.Conn) error {
+	if l.PlayerLimit.acquire() {
+		return nil
+	}
+	w3gs.Write(conn, &w3gs.RejectJoin{Reason: w3gs.RejectJoinFull}, l.Encoding)
+	return ErrPlayerLimit
+}
+
 // Accept a new player connection
+// If ReconnectPort is set, conn may also resume the connection of a GProxy++ client
 func (l *Lobby) Accept(conn net.Conn) (*Player, error) {
+	if l.ReconnectPort != 0 {
+		return l.acceptGProxy(conn)
+	}
+
 	var c = network.NewW3GSConn(conn, nil, l.Encoding)
+	c.SetLogger(l.Logger)
 
 	pkt, err := c.NextPacket(10 * time.Second)
 	if err != nil {
@@ -813,28 +1237,173 @@ func (l *Lobby) Accept(conn net.Conn) (*Player, error) {
 	return l.JoinAndServe(conn, join)
 }
 
-func (l *Lobby) onLeave(p *Player) {
-	l.slotmut.Lock()
+func (l *Lobby) acceptGProxy(conn net.Conn) (*Player, error) {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	b, err := readRaw(conn)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return nil, err
+	}
 
-	var pid = p.PlayerInfo.PlayerID
-	delete(l.players, pid)
+	if b[0] == GProxySig {
+		return l.reconnect(conn, b)
+	}
 
-	var sid = l.pidToSID(pid)
-	l.slots[sid] = l.slotBase.Slots[sid]
+	pkt, _, err := w3gs.Deserialize(b, l.Encoding)
+	if err != nil {
+		return nil, err
+	}
 
-	l.sendToAll(&w3gs.PlayerLeft{
-		PlayerID: pid,
-		Reason:   p.LeaveReason(),
-	})
-	l.refreshSlots()
+	join, ok := pkt.(*w3gs.Join)
+	if !ok {
+		return nil, ErrInvalidPacket
+	}
+
+	return l.JoinAndServe(conn, join)
+}
+
+func (l *Lobby) reconnect(conn net.Conn, b []byte) (*Player, error) {
+	if b[1] != gpsReconnect || len(b) != 13 {
+		return nil, ErrInvalidPacket
+	}
+
+	var buf = protocol.Buffer{Bytes: b[4:]}
+	var pid = buf.ReadUInt8()
+	var key = buf.ReadUInt32()
+	var last = buf.ReadUInt32()
+
+	var p = l.Player(pid)
+	if p == nil || p.gproxy == nil {
+		rejectGProxy(conn, gpsRejectNotFound)
+		return nil, ErrReconnectRejected
+	}
+	if err := p.gproxy.resume(conn, key, last); err != nil {
+		rejectGProxy(conn, gpsRejectInvalid)
+		return nil, err
+	}
+
+	if l.Logger != nil {
+		l.Logger.Info("Player reconnected", network.LogKeyUser, p.PlayerInfo.PlayerName, network.LogKeyPeer, conn.RemoteAddr())
+	}
+
+	l.Fire(&PlayerReconnected{p})
+	return p, nil
+}
 
+// Serve accepts player connections from ln
+func (l *Lobby) Serve(ln net.Listener) error {
+	return l.ServeContext(context.Background(), ln)
+}
+
+// ServeContext accepts player connections from ln until ctx is done
+// ln and all player connections are closed afterwards, ServeContext waits for them to finish
+func (l *Lobby) ServeContext(ctx context.Context, ln net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	var wg sync.WaitGroup
+	var mut sync.Mutex
+	var pending = make(map[net.Conn]struct{})
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			// Reject joins that are still in progress
+			l.Lock()
+			mut.Lock()
+			for c := range pending {
+				c.Close()
+			}
+			mut.Unlock()
+			wg.Wait()
+
+			l.Close()
+			l.Wait()
+
+			if e := ctx.Err(); e != nil {
+				return e
+			}
+			return err
+		}
+
+		mut.Lock()
+		pending[conn] = struct{}{}
+		mut.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := l.Accept(conn)
+
+			mut.Lock()
+			delete(pending, conn)
+			mut.Unlock()
+
+			if err != nil {
+				conn.Close()
+				if !network.IsCloseError(err) && ctx.Err() == nil {
+					l.Fire(&network.AsyncError{Src: "ServeContext[Accept]", Err: err})
+				}
+			}
+		}()
+	}
+}
+
+func (l *Lobby) onLeave(p *Player) {
+	l.slotmut.Lock()
+	if pid := uint(p.PlayerInfo.PlayerID); l.kicked.Test(pid) {
+		// Already removed by clearSlot()
+		l.kicked.Clear(pid)
+	} else {
+		l.removePlayer(p)
+	}
 	l.slotmut.Unlock()
+
+	if l.Logger != nil {
+		l.Logger.Info("Player left", network.LogKeyUser, p.PlayerInfo.PlayerName)
+	}
+
+	l.PlayerLimit.release()
 	l.Fire(&PlayerLeft{p})
 	l.wg.Done()
 
 	p.Close()
 }
 
+func (l *Lobby) startDownload(dl *download, timeout *time.Timer) {
+	if !dl.start() {
+		return
+	}
+
+	// Downloads can take longer than ReadyTimeout, restarted once finished
+	timeout.Stop()
+}
+
+func (l *Lobby) onPingUpdated(p *Player, ping *PingUpdated) {
+	if time.Duration(ping.Ping)*time.Millisecond <= l.MaxPing {
+		return
+	}
+
+	l.slotmut.Lock()
+	var locked = l.locked
+	l.slotmut.Unlock()
+	if locked {
+		return
+	}
+
+	if l.Logger != nil {
+		l.Logger.Info("Ping exceeds limit", network.LogKeyUser, p.PlayerInfo.PlayerName)
+	}
+	p.Fire(&network.AsyncError{Src: "Lobby.onPingUpdated", Err: ErrMaxPing})
+	p.Kick(w3gs.LeaveLobby)
+}
+
 func (l *Lobby) onPlayerExtra(p *Player, msg *w3gs.PlayerExtra) {
 	if msg.Type != w3gs.PlayerProfile {
 		return
@@ -856,8 +1425,13 @@ func (l *Lobby) onPlayerExtra(p *Player, msg *w3gs.PlayerExtra) {
 
 func (l *Lobby) onMapState(p *Player, s *w3gs.MapState) {
 	var progress uint8 = 100
-	if !s.Ready {
-		progress = uint8(math.Min(100.0, math.Round(float64(s.FileSize)/float64(l.MapCheck.FileSize))*100.0))
+	if !s.Ready || (l.MapData != nil && s.FileSize != l.MapCheck.FileSize) {
+		progress = uint8(math.Min(100.0, math.Floor(float64(s.FileSize)*100.0/float64(l.MapCheck.FileSize))))
+
+		// Map download in progress
+		p.SetPhase(network.PhaseLoading)
+	} else if p.Ready() {
+		p.SetPhase(network.PhaseSteady)
 	}
 
 	l.slotmut.Lock()
@@ -908,13 +1482,25 @@ func (l *Lobby) onPlayerChat(p *Player, msg *w3gs.Message) {
 
 	l.slotmut.Lock()
 
+	var b, err = l.Encoder.Serialize(&relay)
+	if err != nil {
+		l.slotmut.Unlock()
+		l.Fire(&network.AsyncError{Src: "onPlayerChat[Serialize]", Err: err})
+		return
+	}
+
+	b = append([]byte(nil), b...)
+
+	// Only referees chat with players during the game
+	var obsOnly = msg.Type == w3gs.MsgChatExtra && l.Observers != ObsReferees && l.isObserver(p.PlayerInfo.PlayerID)
+
 	for _, rid := range msg.RecipientIDs {
 		var recipient, ok = l.players[rid]
-		if !ok {
+		if !ok || (obsOnly && !l.isObserver(rid)) {
 			continue
 		}
 
-		if _, err := recipient.SendOrClose(&relay); err != nil {
+		if err := recipient.Enqueue(b); err != nil {
 			recipient.Fire(&network.AsyncError{Src: "Lobby.onPlayerChat[Relay]", Err: err})
 		}
 	}
@@ -5,10 +5,21 @@
 package lobby_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
 	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -63,8 +74,10 @@ func makeSlots(n int) w3gs.SlotInfo {
 }
 
 func makeGame(t *testing.T, n int) *lobby.Game {
-	var g = lobby.NewGame(w3gs.Encoding{GameVersion: w3gs.CurrentGameVersion}, makeSlots(n), w3gs.MapCheck{})
+	return logGame(t, lobby.NewGame(w3gs.Encoding{GameVersion: w3gs.CurrentGameVersion}, makeSlots(n), w3gs.MapCheck{}))
+}
 
+func logGame(t *testing.T, g *lobby.Game) *lobby.Game {
 	if t != nil {
 		g.On(&network.AsyncError{}, func(ev *network.Event) {
 			var err = ev.Arg.(*network.AsyncError)
@@ -407,3 +420,2070 @@ func TestInvalidPackets(t *testing.T) {
 		t.Fatal("Expected all players to be kicked")
 	}
 }
+
+func TestPlayerSendOrder(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	var p = lobby.NewPlayer(&w3gs.PlayerInfo{PlayerID: 1})
+	p.PingInterval = 0
+	p.SetConn(c1, w3gs.NewFactoryCache(w3gs.DefaultFactory), w3gs.Encoding{})
+
+	var ping = w3gs.Ping{Payload: 1}
+	p.Send(&ping)
+	ping.Payload = 2 // Packets are serialized when sent, not when written
+	p.Send(&w3gs.TimeSlot{})
+	p.Send(&w3gs.StartLag{})
+	p.Send(&w3gs.MessageRelay{Message: w3gs.Message{Type: w3gs.MsgChat, Content: "chat"}})
+	p.Send(&w3gs.StopLag{})
+	p.Send(&w3gs.PlayerLeft{PlayerID: 2})
+	p.Kick(w3gs.LeaveLobby)
+
+	go p.Run()
+
+	var conn = network.NewW3GSConn(c2, w3gs.NewFactoryCache(w3gs.DefaultFactory), w3gs.Encoding{})
+	var expect = []w3gs.Packet{
+		&w3gs.Ping{}, &w3gs.TimeSlot{}, &w3gs.StartLag{}, &w3gs.MessageRelay{},
+		&w3gs.StopLag{}, &w3gs.PlayerLeft{}, &w3gs.PlayerKicked{},
+	}
+	for i, e := range expect {
+		pkt, err := conn.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if fmt.Sprintf("%T", pkt) != fmt.Sprintf("%T", e) {
+			t.Fatalf("Packet %d: expected %T, got %T", i, e, pkt)
+		}
+		if ping, ok := pkt.(*w3gs.Ping); ok && ping.Payload != 1 {
+			t.Fatal("Expected payload at time of Send, got", ping.Payload)
+		}
+	}
+
+	// Connection is closed after flushing PlayerKicked
+	if _, err := conn.NextPacket(time.Second); !network.IsCloseError(err) {
+		t.Fatal("Expected closed connection, got", err)
+	}
+}
+
+func TestJoinPhase(t *testing.T) {
+	var g = makeGame(t, 2)
+
+	var ready = make(chan *lobby.Player, 1)
+	g.On(&lobby.PlayerJoined{}, func(ev *network.Event) {
+		var p = ev.Arg.(*lobby.PlayerJoined).Player
+		if p.Phase() != network.PhaseHandshake {
+			t.Fatal("Expected handshake phase after join, got", p.Phase())
+		}
+		p.On(&lobby.Ready{}, func(ev *network.Event) {
+			ready <- p
+		})
+	})
+
+	d, err := joinDummy(t, g, "DUMMY1")
+	if err != nil {
+		t.Fatalf("Could not join game with dummy: %s\n", err.Error())
+	}
+
+	select {
+	case p := <-ready:
+		if p.Phase() != network.PhaseSteady {
+			t.Fatal("Expected steady phase after join sequence, got", p.Phase())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Player not ready")
+	}
+
+	d.Leave(w3gs.LeaveLobby)
+	g.Wait()
+}
+
+func TestAdvertise(t *testing.T) {
+	var g = makeGame(t, 2)
+
+	m, err := network.NewUDPMux(network.FamilyIPv4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	go m.Run()
+
+	var gv = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: w3gs.CurrentGameVersion}
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan error)
+	go func() { done <- g.Advertise(ctx, m, &w3gs.GameInfo{GameVersion: gv, HostCounter: 1}) }()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var client = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{GameVersion: gv.Version})
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: m.LocalAddr().(*net.UDPAddr).Port}
+
+	var search = func(used uint32) {
+		var deadline = time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if _, err := client.Send(dst, &w3gs.SearchGame{GameVersion: gv}); err != nil {
+				t.Fatal(err)
+			}
+			pkt, _, err := client.NextPacket(50 * time.Millisecond)
+			if network.IsTimeout(err) {
+				continue
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if info, ok := pkt.(*w3gs.GameInfo); ok && info.SlotsUsed == used {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Expected GameInfo with %d used slots", used)
+	}
+
+	search(0)
+
+	if err := g.CloseSlot(1, false); err != nil {
+		t.Fatal(err)
+	}
+	search(1)
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	var g = makeGame(t, 2)
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan error)
+	go func() { done <- g.ServeContext(ctx, ln) }()
+
+	var joined = make(chan struct{}, 1)
+	g.On(&lobby.PlayerJoined{}, func(ev *network.Event) {
+		joined <- struct{}{}
+	})
+
+	var d = dummy.Player{
+		Host: peer.Host{
+			PlayerInfo: w3gs.PlayerInfo{PlayerName: "DUMMY1"},
+			Encoding:   g.Encoding,
+		},
+	}
+	d.InitDefaultHandlers()
+
+	conn, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.JoinWithConn(conn); err != nil {
+		t.Fatal(err)
+	}
+	go d.Run()
+	defer d.Close()
+
+	select {
+	case <-joined:
+	case <-time.After(time.Second):
+		t.Fatal("Player not joined")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+	if g.SlotsUsed() != 0 {
+		t.Fatal("Expected players to be closed after serving")
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	var g = makeGame(t, 2)
+	g.Countdown = 50 * time.Millisecond
+
+	var playing = make(chan time.Time, 1)
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StagePlaying {
+			playing <- time.Now()
+		}
+	})
+
+	var start = time.Now()
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Start(); err != lobby.ErrLocked {
+		t.Fatal("Expected ErrLocked during countdown, got", err)
+	}
+
+	select {
+	case ts := <-playing:
+		if ts.Sub(start) < g.Countdown {
+			t.Fatal("Game started before countdown ended")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Game not started")
+	}
+}
+
+func TestLatency(t *testing.T) {
+	var g = makeGame(t, 2)
+	if g.Latency() != time.Second/time.Duration(g.TurnRate) {
+		t.Fatal("Expected latency to follow TurnRate, got", g.Latency())
+	}
+
+	g.SetLatency(100 * time.Millisecond)
+	if g.Latency() != 100*time.Millisecond {
+		t.Fatal("Expected 100ms latency, got", g.Latency())
+	}
+}
+
+func TestDownload(t *testing.T) {
+	var data = make([]byte, 10*lobby.MapChunkSize+123)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	var l = lobby.NewLobby(w3gs.Encoding{GameVersion: w3gs.CurrentGameVersion}, makeSlots(2), w3gs.MapCheck{FileSize: uint32(len(data))})
+	l.MapData = bytes.NewReader(data)
+	l.UploadRate = 100 * 1024
+	l.UploadLimit = lobby.NewUploadLimit(200 * 1024)
+
+	var finished = make(chan struct{})
+	var progress uint32
+	l.On(&lobby.DownloadProgress{}, func(ev *network.Event) {
+		atomic.StoreUint32(&progress, ev.Arg.(*lobby.DownloadProgress).Received)
+	})
+	l.On(&lobby.DownloadFinished{}, func(ev *network.Event) {
+		close(finished)
+	})
+
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var client = network.NewW3GSConn(c1, nil, l.Encoding)
+	defer client.Close()
+
+	if _, err := client.Send(&w3gs.Join{PlayerName: "client"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Accept(c2); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var recv = make([]byte, len(data))
+	var started = false
+	for done := false; !done; {
+		pkt, err := client.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch p := pkt.(type) {
+		case *w3gs.MapCheck:
+			client.Send(&w3gs.MapState{Ready: true, FileSize: 0})
+		case *w3gs.StartDownload:
+			started = true
+		case *w3gs.MapPart:
+			if !started {
+				t.Fatal("MapPart received before StartDownload")
+			}
+			var pos = p.ChunkPos + uint32(len(p.Data))
+			copy(recv[p.ChunkPos:], p.Data)
+			client.Send(&w3gs.MapPartOK{ChunkPos: pos})
+			done = pos == uint32(len(data))
+		}
+	}
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Download not finished")
+	}
+
+	if !bytes.Equal(recv, data) {
+		t.Fatal("Downloaded map does not match")
+	}
+	if atomic.LoadUint32(&progress) != uint32(len(data)) {
+		t.Fatal("Expected final progress to match map size")
+	}
+}
+
+func readGProxy(t *testing.T, conn net.Conn, dec *w3gs.Decoder, recv *uint32) (w3gs.Packet, []byte) {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var hdr [4]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		t.Fatal(err)
+	}
+	var b = make([]byte, int(hdr[3])<<8|int(hdr[2]))
+	copy(b, hdr[:])
+	if _, err := io.ReadFull(conn, b[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if b[0] == lobby.GProxySig {
+		return nil, b
+	}
+
+	*recv++
+	pkt, _, err := dec.Deserialize(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkt, nil
+}
+
+func TestGProxy(t *testing.T) {
+	var l = lobby.NewLobby(w3gs.Encoding{GameVersion: w3gs.CurrentGameVersion}, makeSlots(2), w3gs.MapCheck{})
+	l.ReconnectPort = 6113
+	defer l.Close()
+
+	var reconnected = make(chan *lobby.Player, 1)
+	l.On(&lobby.PlayerReconnected{}, func(ev *network.Event) {
+		reconnected <- ev.Arg.(*lobby.PlayerReconnected).Player
+	})
+
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dec = w3gs.NewDecoder(l.Encoding, w3gs.DefaultFactory)
+	if _, err := w3gs.Write(c1, &w3gs.Join{PlayerName: "client"}, l.Encoding); err != nil {
+		t.Fatal(err)
+	}
+	p, err := l.Accept(c2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GPS_INIT
+	c1.Write([]byte{lobby.GProxySig, 1, 8, 0, 1, 0, 0, 0})
+
+	var recv uint32
+	var init []byte
+	for init == nil {
+		_, init = readGProxy(t, c1, dec, &recv)
+	}
+	if len(init) != 15 || init[1] != 1 || init[4] != 0xE1 || init[5] != 0x17 || init[6] != p.PlayerInfo.PlayerID {
+		t.Fatal("Unexpected GPS_INIT", init)
+	}
+	if !p.GProxy() {
+		t.Fatal("Expected GProxy to be enabled")
+	}
+
+	// Drop connection, packets sent in the meantime are replayed after reconnecting
+	c1.Close()
+	time.Sleep(10 * time.Millisecond)
+	p.Send(&w3gs.Ping{Payload: 42})
+
+	c3, c4, err := netPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c3.Close()
+
+	var req = []byte{lobby.GProxySig, 2, 13, 0, init[6], 0, 0, 0, 0, 0, 0, 0, 0}
+	copy(req[5:9], init[7:11])
+	binary.LittleEndian.PutUint32(req[9:], recv)
+	c3.Write(req)
+
+	if _, err := l.Accept(c4); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case rp := <-reconnected:
+		if rp != p {
+			t.Fatal("Unexpected player reconnected")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Reconnect event not fired")
+	}
+
+	if _, b := readGProxy(t, c3, dec, &recv); b == nil || b[1] != 2 {
+		t.Fatal("Expected GPS_RECONNECT", b)
+	}
+	for {
+		pkt, _ := readGProxy(t, c3, dec, &recv)
+		if ping, ok := pkt.(*w3gs.Ping); ok && ping.Payload == 42 {
+			break
+		}
+	}
+
+	// Invalid key is rejected
+	c5, c6, err := netPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c5.Close()
+
+	req[5]++
+	c5.Write(req)
+	if _, err := l.Accept(c6); err != lobby.ErrReconnectRejected {
+		t.Fatal("Expected ErrReconnectRejected, got", err)
+	}
+}
+
+func joinRaw(t *testing.T, l *lobby.Lobby, name string) (*network.W3GSConn, *lobby.Player, error) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var client = network.NewW3GSConn(c1, nil, l.Encoding)
+	if _, err := client.Send(&w3gs.Join{PlayerName: name}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := l.Accept(c2)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	return client, p, nil
+}
+
+// pongRaw answers pings and map checks until c is closed
+func pongRaw(c *network.W3GSConn) {
+	for {
+		pkt, err := c.NextPacket(time.Second)
+		if err != nil {
+			return
+		}
+		switch p := pkt.(type) {
+		case *w3gs.Ping:
+			c.Send(&w3gs.Pong{Ping: *p})
+		case *w3gs.MapCheck:
+			c.Send(&w3gs.MapState{Ready: true, FileSize: p.FileSize})
+		}
+	}
+}
+
+// playRaw answers pings and map checks, and loads once the countdown ends, until c is closed
+func playRaw(c *network.W3GSConn) {
+	for {
+		pkt, err := c.NextPacket(time.Second)
+		if err != nil {
+			return
+		}
+		switch p := pkt.(type) {
+		case *w3gs.Ping:
+			c.Send(&w3gs.Pong{Ping: *p})
+		case *w3gs.MapCheck:
+			c.Send(&w3gs.MapState{Ready: true, FileSize: p.FileSize})
+		case *w3gs.CountDownEnd:
+			c.Send(&w3gs.GameLoaded{})
+		}
+	}
+}
+
+func TestKickBan(t *testing.T) {
+	var g = makeGame(t, 2)
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+	g.Bans = &lobby.BanList{}
+
+	var left = make(chan uint8, 4)
+	g.On(&lobby.PlayerLeft{}, func(ev *network.Event) {
+		left <- ev.Arg.(*lobby.PlayerLeft).PlayerInfo.PlayerID
+	})
+
+	if err := g.Kick(1, w3gs.LeaveLobby); err != lobby.ErrUnknownPlayer {
+		t.Fatal("Expected ErrUnknownPlayer, got", err)
+	}
+
+	c, p, err := joinRaw(t, &g.Lobby, "DUMMY1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := g.Ban(p.PlayerInfo.PlayerID, "test"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Fatal("Banned player not kicked")
+	}
+
+	if _, _, err := joinRaw(t, &g.Lobby, "DUMMY1"); err != lobby.ErrBanned {
+		t.Fatal("Expected ErrBanned for name, got", err)
+	}
+	if _, _, err := joinRaw(t, &g.Lobby, "DUMMY2"); err != lobby.ErrBanned {
+		t.Fatal("Expected ErrBanned for IP, got", err)
+	}
+
+	g.Bans.Remove("dummy1", "", nil)
+	if len(g.Bans.(*lobby.BanList).Bans()) != 0 {
+		t.Fatal("Expected empty ban list")
+	}
+
+	c, p, err = joinRaw(t, &g.Lobby, "DUMMY1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := g.Kick(p.PlayerInfo.PlayerID, w3gs.LeaveLobby); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Fatal("Player not kicked")
+	}
+}
+
+func TestReserveSlot(t *testing.T) {
+	var g = makeGame(t, 2)
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+	if err := g.ReserveSlot(0, "VIP"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Reserved(0) != "VIP" {
+		t.Fatal("Expected slot 0 to be reserved")
+	}
+
+	var left = make(chan uint8, 4)
+	g.On(&lobby.PlayerLeft{}, func(ev *network.Event) {
+		left <- ev.Arg.(*lobby.PlayerLeft).PlayerInfo.PlayerID
+	})
+
+	c1, squatter, err := joinRaw(t, &g.Lobby, "DUMMY1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+
+	c2, _, err := joinRaw(t, &g.Lobby, "DUMMY2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	if g.SlotInfo().Slots[0].PlayerID != squatter.PlayerInfo.PlayerID {
+		t.Fatal("Expected squatter in reserved slot")
+	}
+
+	c3, vip, err := joinRaw(t, &g.Lobby, "vip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c3.Close()
+
+	if vip.PlayerInfo.PlayerID == squatter.PlayerInfo.PlayerID {
+		t.Fatal("Expected new player ID for reserved player")
+	}
+	if g.SlotInfo().Slots[0].PlayerID != vip.PlayerInfo.PlayerID {
+		t.Fatal("Expected reserved player in reserved slot")
+	}
+
+	select {
+	case pid := <-left:
+		if pid != squatter.PlayerInfo.PlayerID {
+			t.Fatal("Unexpected player left", pid)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Squatter not kicked")
+	}
+	if g.SlotInfo().Slots[0].PlayerID != vip.PlayerInfo.PlayerID {
+		t.Fatal("Expected reserved player to keep slot after squatter left")
+	}
+}
+
+func TestSyncLimit(t *testing.T) {
+	var g = makeGame(t, 2)
+	g.SyncLimit = 2
+	g.MinLatency = 5 * time.Millisecond
+
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StageDone {
+			close(done)
+		}
+	})
+	defer func() {
+		g.Close()
+		<-done
+	}()
+
+	var latency = make(chan *lobby.LatencyChanged, 8)
+	g.On(&lobby.LatencyChanged{}, func(ev *network.Event) {
+		select {
+		case latency <- ev.Arg.(*lobby.LatencyChanged):
+		default:
+		}
+	})
+
+	c, _, err := joinRaw(t, &g.Lobby, "DUMMY1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	go func() {
+		for g.Start() == lobby.ErrNotReady {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	var slots = 0
+	var acks = 0
+	var lagging = false
+	for {
+		pkt, err := c.NextPacket(2 * time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch p := pkt.(type) {
+		case *w3gs.Ping:
+			c.Send(&w3gs.Pong{Ping: *p})
+		case *w3gs.MapCheck:
+			c.Send(&w3gs.MapState{Ready: true, FileSize: p.FileSize})
+		case *w3gs.CountDownEnd:
+			c.Send(&w3gs.GameLoaded{})
+		case *w3gs.TimeSlot:
+			slots++
+		case *w3gs.StartLag:
+			if slots <= int(g.SyncLimit) {
+				t.Fatal("Lag screen shown before SyncLimit was reached", slots)
+			}
+			lagging = true
+		case *w3gs.StopLag:
+			if !lagging {
+				t.Fatal("StopLag before StartLag")
+			}
+			lagging = false
+		}
+
+		if lagging {
+			for ; acks < slots; acks++ {
+				c.Send(&w3gs.TimeSlotAck{})
+			}
+		} else if acks > 0 {
+			break
+		}
+	}
+
+	select {
+	case l := <-latency:
+		if l.New >= l.Old || l.New < g.MinLatency {
+			t.Fatal("Expected latency to decrease towards MinLatency", l)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Latency not adapted")
+	}
+}
+
+func TestHCL(t *testing.T) {
+	var slots = makeSlots(4).Slots
+	slots[0].SlotStatus = w3gs.SlotOccupied
+	slots[1].SlotStatus = w3gs.SlotOccupied
+	slots[1].Handicap = 50
+	slots[3].SlotStatus = w3gs.SlotOccupied
+	slots[3].Handicap = 80
+
+	if err := lobby.EncodeHCL(slots, "abcd"); err != lobby.ErrHCLTooLong {
+		t.Fatal("Expected ErrHCLTooLong, got", err)
+	}
+	if err := lobby.EncodeHCL(slots, "A"); err != lobby.ErrHCLInvalid {
+		t.Fatal("Expected ErrHCLInvalid, got", err)
+	}
+
+	var enc = append([]w3gs.SlotData(nil), slots...)
+	if err := lobby.EncodeHCL(enc, "a."); err != nil {
+		t.Fatal(err)
+	}
+	if enc[0].Handicap != 6 || enc[1].Handicap != 247 || enc[3].Handicap != 80 {
+		t.Fatal("Unexpected handicaps", enc[0].Handicap, enc[1].Handicap, enc[3].Handicap)
+	}
+
+	cmd, err := lobby.DecodeHCL(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd != "a." {
+		t.Fatal("Expected 'a.', got", cmd)
+	}
+	for i := range slots {
+		if enc[i] != slots[i] {
+			t.Fatal("Handicaps not restored", i)
+		}
+	}
+
+	var g = makeGame(t, 2)
+	g.HCL = "ap"
+	if err := g.Start(); err != lobby.ErrHCLTooLong {
+		t.Fatal("Expected ErrHCLTooLong, got", err)
+	}
+}
+
+func TestAutoStart(t *testing.T) {
+	var g = makeGame(t, 3)
+
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StageDone {
+			close(done)
+		}
+	})
+	defer func() {
+		g.Close()
+		<-done
+	}()
+
+	var ready = make(chan *lobby.AutoStartReady, 1)
+	g.On(&lobby.AutoStartReady{}, func(ev *network.Event) {
+		ready <- ev.Arg.(*lobby.AutoStartReady)
+	})
+
+	c1, p1, err := joinRaw(t, &g.Lobby, "DUMMY1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+
+	go pongRaw(c1)
+
+	for !p1.Ready() {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := g.AutoStartContext(ctx, lobby.AutoStart{MinPlayers: 1, Full: true, Interval: 5 * time.Millisecond}); err != context.DeadlineExceeded {
+		t.Fatal("Expected DeadlineExceeded for Full, got", err)
+	}
+	if len(ready) != 0 {
+		t.Fatal("Unexpected AutoStartReady")
+	}
+
+	// Second player never pongs
+	c2, p2, err := joinRaw(t, &g.Lobby, "DUMMY2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := g.AutoStartContext(ctx, lobby.AutoStart{MinPlayers: 1, Interval: 5 * time.Millisecond}); err != context.DeadlineExceeded {
+		t.Fatal("Expected DeadlineExceeded for unready player, got", err)
+	}
+
+	if err := g.ForceStart(); err != nil {
+		t.Fatal(err)
+	}
+	if g.Stage() != lobby.StageLoading {
+		t.Fatal("Expected StageLoading, got", g.Stage())
+	}
+	if g.Player(p1.PlayerInfo.PlayerID) == nil || g.Player(p2.PlayerInfo.PlayerID) != nil {
+		t.Fatal("Expected unready player to be kicked")
+	}
+	if err := g.AutoStart(lobby.AutoStart{Interval: 5 * time.Millisecond}); err != lobby.ErrLocked {
+		t.Fatal("Expected ErrLocked, got", err)
+	}
+}
+
+func TestAutoStartDelay(t *testing.T) {
+	var g = makeGame(t, 2)
+
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StageDone {
+			close(done)
+		}
+	})
+	defer func() {
+		g.Close()
+		<-done
+	}()
+
+	var ready = make(chan *lobby.AutoStartReady, 1)
+	g.On(&lobby.AutoStartReady{}, func(ev *network.Event) {
+		ready <- ev.Arg.(*lobby.AutoStartReady)
+	})
+
+	if err := g.CloseSlot(1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _, err := joinRaw(t, &g.Lobby, "DUMMY1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	go pongRaw(c)
+
+	var start = time.Now()
+	if err := g.AutoStart(lobby.AutoStart{MinPlayers: 1, Full: true, MaxPing: time.Second, Delay: 50 * time.Millisecond, Interval: 5 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if g.Stage() != lobby.StageLoading {
+		t.Fatal("Expected StageLoading, got", g.Stage())
+	}
+
+	select {
+	case r := <-ready:
+		if r.Delay != 50*time.Millisecond || time.Since(start) < r.Delay {
+			t.Fatal("Started before Delay", r)
+		}
+	default:
+		t.Fatal("Expected AutoStartReady")
+	}
+}
+
+func TestSavedGame(t *testing.T) {
+	var slots = makeSlots(3)
+	slots.RandomSeed = 1234
+	slots.SlotLayout = w3gs.LayoutFixedPlayerSettings
+	slots.Slots[0] = w3gs.SlotData{PlayerID: 3, SlotStatus: w3gs.SlotOccupied, Team: 1, Color: 4, Race: w3gs.RaceOrc, Handicap: 100}
+	slots.Slots[1] = w3gs.SlotData{SlotStatus: w3gs.SlotOccupied, Computer: true, Team: 0, Color: 1, Race: w3gs.RaceHuman, Handicap: 100}
+	slots.Slots[2] = w3gs.SlotData{PlayerID: 5, SlotStatus: w3gs.SlotOccupied, Team: 0, Color: 2, Race: w3gs.RaceUndead, Handicap: 90}
+
+	var g = logGame(t, lobby.NewSavedGame(w3gs.Encoding{GameVersion: w3gs.CurrentGameVersion}, slots, w3gs.MapCheck{}))
+
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StageDone {
+			close(done)
+		}
+	})
+	defer func() {
+		g.Close()
+		<-done
+	}()
+
+	if !g.Saved() {
+		t.Fatal("Expected saved game")
+	}
+	if g.SlotsAvailable() != 2 {
+		t.Fatal("Expected human slots to be opened, got", g.SlotsAvailable())
+	}
+	if err := g.ReserveSlot(0, "DUMMY1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ReserveSlot(2, "DUMMY2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := joinRaw(t, &g.Lobby, "OTHER"); err != lobby.ErrNotReserved {
+		t.Fatal("Expected ErrNotReserved, got", err)
+	}
+
+	c, p, err := joinRaw(t, &g.Lobby, "DUMMY2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pkt, err := c.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	join, ok := pkt.(*w3gs.SlotInfoJoin)
+	if !ok {
+		t.Fatal("Expected SlotInfoJoin, got", pkt)
+	}
+	if join.PlayerID != 5 || p.PlayerInfo.PlayerID != 5 || join.RandomSeed != 1234 {
+		t.Fatal("Expected saved PlayerID and RandomSeed", join.PlayerID, join.RandomSeed)
+	}
+	if s := join.Slots[2]; s.SlotStatus != w3gs.SlotOccupied || s.Team != 0 || s.Color != 2 || s.Race != w3gs.RaceUndead || s.Handicap != 90 {
+		t.Fatal("Expected saved slot settings", s)
+	}
+
+	go pongRaw(c)
+	for !p.Ready() {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := g.Start(); err != lobby.ErrNotReady {
+		t.Fatal("Expected ErrNotReady while waiting for original players, got", err)
+	}
+
+	if err := g.CloseSlot(0, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeChat struct {
+	network.EventEmitter
+	mut  sync.Mutex
+	said []string
+}
+
+func (c *fakeChat) Run() error                              { return nil }
+func (c *fakeChat) RunContext(ctx context.Context) error    { return nil }
+func (c *fakeChat) Close() error                            { return nil }
+func (c *fakeChat) Channel() string                         { return "" }
+func (c *fakeChat) ChatUsers() map[string]network.ChatUser  { return nil }
+func (c *fakeChat) JoinChannel(channel string) error        { return nil }
+func (c *fakeChat) Kick(username string) error              { return nil }
+func (c *fakeChat) Ban(username string) error               { return nil }
+func (c *fakeChat) Unban(username string) error             { return nil }
+func (c *fakeChat) Whisper(username string, s string) error { return nil }
+func (c *fakeChat) Say(s string) error {
+	c.mut.Lock()
+	c.said = append(c.said, s)
+	c.mut.Unlock()
+	return nil
+}
+
+func TestChatRelay(t *testing.T) {
+	var g = makeGame(t, 2)
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+
+	var c fakeChat
+	var r = lobby.NewChatRelay()
+	var unbind = r.Bind(&g.Lobby, &c)
+
+	conn, p, err := joinRaw(t, &g.Lobby, "DUMMY1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var pid = p.PlayerInfo.PlayerID
+
+	c.Fire(&network.ChatMessage{ChatUser: network.ChatUser{Name: "w"}, Content: "whisper", Whisper: true})
+	c.Fire(&network.ChatMessage{ChatUser: network.ChatUser{Name: "niels"}, Content: "hello"})
+
+	for {
+		pkt, err := conn.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		relay, ok := pkt.(*w3gs.MessageRelay)
+		if !ok {
+			continue
+		}
+		if relay.Content != "[niels] hello" || relay.Type != w3gs.MsgChat || relay.SenderID != pid {
+			t.Fatal("Unexpected relay", relay)
+		}
+		break
+	}
+
+	var chat = func(scope w3gs.MessageScope, s string) {
+		var msg = w3gs.Message{RecipientIDs: []uint8{pid}, SenderID: pid, Type: w3gs.MsgChat, Content: s}
+		if scope != w3gs.ScopeAll {
+			msg.Type = w3gs.MsgChatExtra
+			msg.Scope = scope
+		}
+		if _, err := conn.Send(&msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chat(w3gs.ScopeAll, "first")
+	chat(w3gs.ScopeAllies, "allies")
+	chat(w3gs.ScopeDirected+1, "directed")
+
+	for {
+		pkt, err := conn.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if relay, ok := pkt.(*w3gs.MessageRelay); ok && relay.Content == "directed" {
+			break
+		}
+	}
+
+	unbind()
+
+	var expected = []string{"<DUMMY1> first"}
+	if !reflect.DeepEqual(c.said, expected) {
+		t.Fatalf("Expected %v, got %v", expected, c.said)
+	}
+}
+
+func TestManager(t *testing.T) {
+	var m = lobby.NewManager()
+	m.IP = net.IPv4(127, 0, 0, 1)
+	m.MaxGames = 2
+	m.PlayerLimit = lobby.NewPlayerLimit(1)
+
+	var closed = make(chan int, 2)
+	m.On(&lobby.GameClosed{}, func(ev *network.Event) {
+		closed <- ev.Arg.(*lobby.GameClosed).Port
+	})
+
+	var g1 = makeGame(t, 2)
+	var g2 = makeGame(t, 2)
+
+	p1, err := m.Host(g1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := m.Host(g2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1 == p2 || m.Port(g1) != p1 || m.CountGames() != 2 {
+		t.Fatal("Expected unique ports", p1, p2)
+	}
+	if _, err := m.Host(makeGame(t, 2), nil); err != lobby.ErrGameLimit {
+		t.Fatal("Expected ErrGameLimit, got", err)
+	}
+
+	var join = func(port int) (*network.W3GSConn, w3gs.Packet) {
+		conn, err := net.Dial("tcp4", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c = network.NewW3GSConn(conn, nil, g1.Encoding)
+		if _, err := c.Send(&w3gs.Join{PlayerName: "DUMMY"}); err != nil {
+			t.Fatal(err)
+		}
+		pkt, err := c.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c, pkt
+	}
+
+	var left = make(chan struct{}, 1)
+	g1.On(&lobby.PlayerLeft{}, func(ev *network.Event) {
+		left <- struct{}{}
+	})
+
+	c1, pkt := join(p1)
+	if _, ok := pkt.(*w3gs.SlotInfoJoin); !ok {
+		t.Fatal("Expected SlotInfoJoin, got", pkt)
+	}
+
+	c2, pkt := join(p2)
+	if r, ok := pkt.(*w3gs.RejectJoin); !ok || r.Reason != w3gs.RejectJoinFull {
+		t.Fatal("Expected RejectJoinFull, got", pkt)
+	}
+	c2.Close()
+
+	c1.Close()
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Fatal("Player not left")
+	}
+	if m.PlayerLimit.Count() != 0 {
+		t.Fatal("Expected player place to be released")
+	}
+
+	c2, pkt = join(p2)
+	if _, ok := pkt.(*w3gs.SlotInfoJoin); !ok {
+		t.Fatal("Expected SlotInfoJoin after release, got", pkt)
+	}
+	defer c2.Close()
+
+	if err := m.Unhost(g1); err != nil {
+		t.Fatal(err)
+	}
+	if port := <-closed; port != p1 {
+		t.Fatal("Expected first game to be closed, got", port)
+	}
+
+	m.Close()
+	if port := <-closed; port != p2 {
+		t.Fatal("Expected second game to be closed, got", port)
+	}
+	if m.CountGames() != 0 {
+		t.Fatal("Expected no games after Close")
+	}
+	if _, err := m.Host(g1, nil); err != lobby.ErrManagerClosed {
+		t.Fatal("Expected ErrManagerClosed, got", err)
+	}
+}
+
+func TestBalance(t *testing.T) {
+	var ratings = map[string]float64{"A": 10, "B": 9, "C": 1, "D": 2, "OBS": 100}
+	var rating = func(p *lobby.Player) float64 {
+		return ratings[p.PlayerInfo.PlayerName]
+	}
+
+	var slots = makeSlots(5).Slots
+	var players = make(map[uint8]*lobby.Player)
+	for i, name := range []string{"A", "B", "C", "D"} {
+		slots[i] = w3gs.SlotData{PlayerID: uint8(i + 1), SlotStatus: w3gs.SlotOccupied, Team: uint8(i / 2), Color: uint8(i), Handicap: 100}
+		players[uint8(i+1)] = lobby.NewPlayer(&w3gs.PlayerInfo{PlayerID: uint8(i + 1), PlayerName: name})
+	}
+	slots[4] = w3gs.SlotData{SlotStatus: w3gs.SlotOccupied, Computer: true, Team: 1, Color: 4}
+
+	if err := lobby.GreedyBalancer(rating)(slots, players); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []uint8{1, 3, 2, 4}
+	for i, pid := range expected {
+		if s := slots[i]; s.PlayerID != pid || s.Team != uint8(i/2) || s.Color != uint8(i) {
+			t.Fatal("Unexpected slot", i, s)
+		}
+	}
+	if !slots[4].Computer || slots[4].Team != 1 {
+		t.Fatal("Expected computer slot to be left in place", slots[4])
+	}
+
+	var g = makeGame(t, 4)
+	g.Balance = lobby.GreedyBalancer(rating)
+
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StageDone {
+			close(done)
+		}
+	})
+	defer func() {
+		g.Close()
+		<-done
+	}()
+
+	for i, name := range []string{"A", "B", "C", "OBS"} {
+		c, p, err := joinRaw(t, &g.Lobby, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		go pongRaw(c)
+		for !p.Ready() {
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		var team = uint8(i / 2)
+		if name == "OBS" {
+			team = g.ObsTeam
+		}
+		if err := g.ChangeTeam(i, team); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var s = g.SlotInfo().Slots
+	if s[0].PlayerID != 1 || s[1].PlayerID != 3 || s[2].PlayerID != 2 || s[3].Team != g.ObsTeam || s[3].PlayerID != 4 {
+		t.Fatal("Expected balanced teams", s)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	var g = makeGame(t, 3)
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+
+	var c = lobby.NewCommands("!")
+	c.Hosts = []string{"host"}
+	c.Hide = true
+	c.InitDefaultCommands(g)
+	defer c.Bind(g)()
+
+	if err := g.ReserveSlot(2, "RESERVED"); err != nil {
+		t.Fatal(err)
+	}
+
+	host, ph, err := joinRaw(t, &g.Lobby, "HOST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	res, pr, err := joinRaw(t, &g.Lobby, "RESERVED")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+
+	if c.Flags(&g.Lobby, ph) != lobby.PermHost || c.Flags(&g.Lobby, pr) != lobby.PermReserved {
+		t.Fatal("Unexpected permissions")
+	}
+
+	var say = func(conn *network.W3GSConn, p *lobby.Player, s string) {
+		var pid = p.PlayerInfo.PlayerID
+		if _, err := conn.Send(&w3gs.Message{RecipientIDs: []uint8{pid}, SenderID: pid, Type: w3gs.MsgChat, Content: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var reply = func(conn *network.W3GSConn) string {
+		for {
+			pkt, err := conn.NextPacket(time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r, ok := pkt.(*w3gs.MessageRelay); ok {
+				return r.Content
+			}
+		}
+	}
+
+	say(res, pr, "!close 2")
+	say(res, pr, "!swap 1 3")
+	say(res, pr, "!ping")
+	if s := reply(res); !strings.Contains(s, "HOST: ") || !strings.Contains(s, "RESERVED: ") {
+		t.Fatal("Unexpected ping reply", s)
+	}
+
+	var slots = g.SlotInfo().Slots
+	if slots[1].SlotStatus != w3gs.SlotOpen || slots[0].PlayerID != pr.PlayerInfo.PlayerID || slots[2].PlayerID != ph.PlayerInfo.PlayerID {
+		t.Fatal("Unexpected slots", slots)
+	}
+
+	say(host, ph, "!hcl ar")
+	if s := reply(host); s != "HCL: ar" || g.HCL != "ar" {
+		t.Fatal("Unexpected HCL reply", s)
+	}
+	say(host, ph, "!hcl AR")
+	if s := reply(host); s != lobby.ErrHCLInvalid.Error() {
+		t.Fatal("Expected ErrHCLInvalid reply, got", s)
+	}
+
+	var left = make(chan uint8, 1)
+	g.On(&lobby.PlayerLeft{}, func(ev *network.Event) {
+		left <- ev.Arg.(*lobby.PlayerLeft).PlayerInfo.PlayerID
+	})
+
+	say(host, ph, "!kick res")
+	select {
+	case pid := <-left:
+		if pid != pr.PlayerInfo.PlayerID {
+			t.Fatal("Wrong player kicked", pid)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Player not kicked")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	var g = makeGame(t, 3)
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+
+	g.Bans = &lobby.BanList{}
+	g.Bans.Add(&lobby.Ban{Name: "banned", Reason: "test"})
+	g.HCL = "ar"
+	g.Countdown = 3 * time.Second
+
+	if err := g.ChangeComputer(2, w3gs.ComputerInsane); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ReserveSlot(1, "R"); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _, err := joinRaw(t, &g.Lobby, "P1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	dir, err := ioutil.TempDir("", "lobby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var name = filepath.Join(dir, "lobby.json")
+	var done = make(chan error, 1)
+	go func() { done <- g.Persist(ctx, name) }()
+
+	var snap *lobby.Snapshot
+	for i := 0; i < 100; i++ {
+		if snap, err = lobby.LoadSnapshot(name); err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+	if snap == nil {
+		t.Fatal("Snapshot not persisted", err)
+	}
+	if snap.Players[0] != "P1" || snap.Reserved[1] != "R" || len(snap.Bans) != 1 {
+		t.Fatal("Unexpected snapshot", snap)
+	}
+
+	r, err := lobby.RestoreGame(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var slots = r.SlotInfo().Slots
+	if slots[0].SlotStatus != w3gs.SlotOpen || !slots[2].Computer || slots[2].ComputerType != w3gs.ComputerInsane {
+		t.Fatal("Unexpected restored slots", slots)
+	}
+	if r.Reserved(0) != "P1" || r.Reserved(1) != "R" {
+		t.Fatal("Expected restored reservations", r.Reserved(0), r.Reserved(1))
+	}
+	if ban, _ := r.Bans.Find("BANNED", "", nil); ban == nil || ban.Reason != "test" {
+		t.Fatal("Expected restored ban", ban)
+	}
+	if r.HCL != "ar" || r.Countdown != 3*time.Second || r.MapCheck != g.MapCheck {
+		t.Fatal("Expected restored settings")
+	}
+
+	if _, err := lobby.RestoreGame(&lobby.Snapshot{SlotBase: makeSlots(2)}); err != lobby.ErrInvalidArgument {
+		t.Fatal("Expected ErrInvalidArgument, got", err)
+	}
+}
+
+// expectRaw answers pings and map checks until a packet matching f is received
+func expectRaw(t *testing.T, c *network.W3GSConn, f func(pkt w3gs.Packet) bool) {
+	for {
+		pkt, err := c.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch p := pkt.(type) {
+		case *w3gs.Ping:
+			c.Send(&w3gs.Pong{Ping: *p})
+		case *w3gs.MapCheck:
+			c.Send(&w3gs.MapState{Ready: true, FileSize: p.FileSize})
+		}
+		if f(pkt) {
+			return
+		}
+	}
+}
+
+func TestFakePlayers(t *testing.T) {
+	var g = makeGame(t, 3)
+
+	fid, err := g.AddFakePlayer("Fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.AddFakePlayer(""); err != lobby.ErrInvalidArgument {
+		t.Fatal("Expected ErrInvalidArgument, got", err)
+	}
+
+	c, p, err := joinRaw(t, &g.Lobby, "P1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if p.PlayerInfo.PlayerID == fid || !g.IsFake(fid) || g.IsFake(p.PlayerInfo.PlayerID) {
+		t.Fatal("Unexpected PlayerIDs", fid, p.PlayerInfo.PlayerID)
+	}
+	expectRaw(t, c, func(pkt w3gs.Packet) bool {
+		var info, ok = pkt.(*w3gs.PlayerInfo)
+		return ok && info.PlayerID == fid && info.PlayerName == "Fake"
+	})
+
+	tmp, err := g.AddFakePlayer("Tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.SlotsAvailable() != 0 || !reflect.DeepEqual(g.FakePlayers(), []uint8{fid, tmp}) {
+		t.Fatal("Expected all slots to be occupied", g.FakePlayers())
+	}
+	if _, err := g.AddFakePlayer("Full"); err != lobby.ErrFull {
+		t.Fatal("Expected ErrFull, got", err)
+	}
+	if err := g.RemoveFakePlayer(p.PlayerInfo.PlayerID); err != lobby.ErrUnknownPlayer {
+		t.Fatal("Expected ErrUnknownPlayer, got", err)
+	}
+	if err := g.CloseSlot(2, false); err != nil {
+		t.Fatal(err)
+	}
+	expectRaw(t, c, func(pkt w3gs.Packet) bool {
+		var left, ok = pkt.(*w3gs.PlayerLeft)
+		return ok && left.PlayerID == tmp
+	})
+	if g.IsFake(tmp) || g.SlotInfo().Slots[2].SlotStatus != w3gs.SlotClosed {
+		t.Fatal("Expected fake player to be removed from closed slot")
+	}
+
+	for i := 0; i < 100 && !p.Ready(); i++ {
+		expectRaw(t, c, func(pkt w3gs.Packet) bool { return true })
+	}
+
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StageDone {
+			close(done)
+		}
+	})
+	defer func() {
+		g.Close()
+		<-done
+	}()
+
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	expectRaw(t, c, func(pkt w3gs.Packet) bool {
+		var loaded, ok = pkt.(*w3gs.PlayerLoaded)
+		return ok && loaded.PlayerID == fid
+	})
+	if err := g.RemoveFakePlayer(fid); err != lobby.ErrLocked {
+		t.Fatal("Expected ErrLocked, got", err)
+	}
+
+	var d = makeGame(t, 2)
+	d.DropFakes = true
+	if _, err := d.AddFakePlayer("Fake"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.FakePlayers()) != 0 || d.SlotInfo().Slots[0].SlotStatus != w3gs.SlotOpen {
+		t.Fatal("Expected fake player to be dropped", d.SlotInfo().Slots)
+	}
+	d.Close()
+	d.Wait()
+}
+
+func TestPing(t *testing.T) {
+	var g = makeGame(t, 2)
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+	g.MaxPing = 50 * time.Millisecond
+
+	c1, p1, err := joinRaw(t, &g.Lobby, "fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	go pongRaw(c1)
+
+	var updated = make(chan *lobby.PingUpdated, 1)
+	p1.On(&lobby.PingUpdated{}, func(ev *network.Event) {
+		select {
+		case updated <- ev.Arg.(*lobby.PingUpdated):
+		default:
+		}
+	})
+
+	select {
+	case u := <-updated:
+		if u.Ping == math.MaxUint32 || u.RTT == math.MaxUint32 {
+			t.Fatal("Unexpected ping", u)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected PingUpdated event")
+	}
+
+	if pings := g.Pings(); pings[0] != p1.Ping() || pings[1] != math.MaxUint32 {
+		t.Fatal("Unexpected pings", pings)
+	}
+
+	c2, p2, err := joinRaw(t, &g.Lobby, "slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	var left = make(chan struct{})
+	g.On(&lobby.PlayerLeft{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.PlayerLeft).Player == p2 {
+			close(left)
+		}
+	})
+
+	go func() {
+		for {
+			pkt, err := c2.NextPacket(time.Second)
+			if err != nil {
+				return
+			}
+			if p, ok := pkt.(*w3gs.Ping); ok {
+				time.Sleep(100 * time.Millisecond)
+				c2.Send(&w3gs.Pong{Ping: *p})
+			}
+		}
+	}()
+
+	select {
+	case <-left:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected slow player to be kicked")
+	}
+	if p1.Ping() > 50 {
+		t.Fatal("Expected fast player to stay", p1.Ping())
+	}
+}
+
+func TestObservers(t *testing.T) {
+	var slots = makeSlots(3)
+	slots.NumPlayers = 1
+
+	var g = logGame(t, lobby.NewGame(w3gs.Encoding{GameVersion: w3gs.CurrentGameVersion}, slots, w3gs.MapCheck{}))
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+
+	g.Observers = lobby.ObsNone
+	c1, p1, err := joinRaw(t, &g.Lobby, "P1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	if _, _, err := joinRaw(t, &g.Lobby, "P2"); err != lobby.ErrFull {
+		t.Fatal("Expected ErrFull, got", err)
+	}
+
+	g.Observers = lobby.ObsFull
+	o1, po1, err := joinRaw(t, &g.Lobby, "O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o1.Close()
+	o2, po2, err := joinRaw(t, &g.Lobby, "O2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o2.Close()
+
+	var s = g.SlotInfo().Slots
+	if s[0].Team != 0 || s[1].Team != g.ObsTeam || s[2].Team != g.ObsTeam {
+		t.Fatal("Expected observers", s)
+	}
+
+	var pid = po1.PlayerInfo.PlayerID
+	if _, err := o1.Send(&w3gs.Message{RecipientIDs: []uint8{p1.PlayerInfo.PlayerID, po2.PlayerInfo.PlayerID}, SenderID: pid, Type: w3gs.MsgChatExtra, Content: "obs"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o1.Send(&w3gs.Message{RecipientIDs: []uint8{p1.PlayerInfo.PlayerID}, SenderID: pid, Type: w3gs.MsgChat, Content: "lobby"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var relayed = func(c *network.W3GSConn) string {
+		var res string
+		expectRaw(t, c, func(pkt w3gs.Packet) bool {
+			if r, ok := pkt.(*w3gs.MessageRelay); ok {
+				res = r.Content
+				return true
+			}
+			return false
+		})
+		return res
+	}
+	if m := relayed(c1); m != "lobby" {
+		t.Fatal("Expected observer chat to be hidden from players, got", m)
+	}
+	if m := relayed(o2); m != "obs" {
+		t.Fatal("Expected observer chat to reach observers, got", m)
+	}
+
+	if lobby.ObsOnDefeat.Slots() || !lobby.ObsReferees.Slots() || lobby.ObsReferees.Settings()&w3gs.SettingObsMask == w3gs.SettingObsFull {
+		t.Fatal("Unexpected ObsMode properties")
+	}
+}
+
+func TestVoteKick(t *testing.T) {
+	var g = makeGame(t, 3)
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+
+	var v = lobby.NewVoteKick(g)
+	var c = lobby.NewCommands("!")
+	c.InitVoteKickCommands(v)
+	defer c.Bind(g)()
+
+	var conns = make([]*network.W3GSConn, 3)
+	var players = make([]*lobby.Player, 3)
+	for i, name := range []string{"A", "B", "C"} {
+		conn, p, err := joinRaw(t, &g.Lobby, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+		players[i] = p
+	}
+
+	var ended = make(chan *lobby.VoteKickEnded, 1)
+	g.On(&lobby.VoteKickEnded{}, func(ev *network.Event) {
+		ended <- ev.Arg.(*lobby.VoteKickEnded)
+	})
+	var left = make(chan uint8, 3)
+	g.On(&lobby.PlayerLeft{}, func(ev *network.Event) {
+		left <- ev.Arg.(*lobby.PlayerLeft).PlayerInfo.PlayerID
+	})
+
+	var say = func(i int, s string) {
+		var pid = players[i].PlayerInfo.PlayerID
+		if _, err := conns[i].Send(&w3gs.Message{RecipientIDs: []uint8{pid}, SenderID: pid, Type: w3gs.MsgChat, Content: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var reply = func(i int, s string) {
+		expectRaw(t, conns[i], func(pkt w3gs.Packet) bool {
+			r, ok := pkt.(*w3gs.MessageRelay)
+			return ok && r.Content == s
+		})
+	}
+
+	if err := v.Vote(players[0]); err != lobby.ErrNoVote {
+		t.Fatal("Expected ErrNoVote, got", err)
+	}
+
+	var w = lobby.NewVoteKick(g)
+	w.Duration = 10 * time.Millisecond
+	if err := w.Start(players[0], players[1]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-ended:
+		if ev.Target != players[1] || ev.Passed {
+			t.Fatal("Expected vote to fail", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Vote did not time out")
+	}
+
+	say(0, "!votekick c")
+	reply(0, "A voted to kick C (1/2)")
+	if v.Target() != players[2] {
+		t.Fatal("Expected vote against C")
+	}
+
+	say(1, "!votekick a")
+	reply(1, lobby.ErrVoteActive.Error())
+	if err := v.Vote(players[2]); err != lobby.ErrInvalidArgument {
+		t.Fatal("Expected ErrInvalidArgument, got", err)
+	}
+
+	say(1, "!yes")
+	select {
+	case ev := <-ended:
+		if ev.Target != players[2] || !ev.Passed {
+			t.Fatal("Expected vote to pass", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Vote did not end")
+	}
+
+	select {
+	case pid := <-left:
+		if pid != players[2].PlayerInfo.PlayerID {
+			t.Fatal("Wrong player kicked", pid)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected C to be kicked")
+	}
+	if v.Target() != nil {
+		t.Fatal("Expected vote to be over")
+	}
+
+	say(0, "!votekick b")
+	reply(0, lobby.ErrVoteCooldown.Error())
+}
+
+func TestEvents(t *testing.T) {
+	var g = makeGame(t, 2)
+	g.Countdown = 10 * time.Millisecond
+
+	var events = make(chan interface{}, 100)
+	for _, ev := range []interface{}{&lobby.SlotChanged{}, &lobby.MapChecked{}, &lobby.CountdownStarted{}, &lobby.CountdownEnded{}, &lobby.PlayerLoaded{}} {
+		g.On(ev, func(ev *network.Event) {
+			events <- ev.Arg
+		})
+	}
+
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StageDone {
+			close(done)
+		}
+	})
+
+	var expect = func(f func(ev interface{}) bool) {
+		for {
+			select {
+			case ev := <-events:
+				if f(ev) {
+					return
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Expected event")
+			}
+		}
+	}
+
+	conn, p, err := joinRaw(t, &g.Lobby, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	expect(func(ev interface{}) bool {
+		s, ok := ev.(*lobby.SlotChanged)
+		return ok && s.SID == 0 && s.Old.SlotStatus == w3gs.SlotOpen && s.New.PlayerID == p.PlayerInfo.PlayerID
+	})
+
+	go playRaw(conn)
+
+	expect(func(ev interface{}) bool {
+		m, ok := ev.(*lobby.MapChecked)
+		return ok && m.Player == p && m.Ready
+	})
+
+	for i := 0; !p.Ready(); i++ {
+		if i > 100 {
+			t.Fatal("Player not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(func(ev interface{}) bool {
+		c, ok := ev.(*lobby.CountdownStarted)
+		return ok && c.Duration == g.Countdown
+	})
+	expect(func(ev interface{}) bool {
+		_, ok := ev.(*lobby.CountdownEnded)
+		return ok
+	})
+	expect(func(ev interface{}) bool {
+		l, ok := ev.(*lobby.PlayerLoaded)
+		return ok && l.Player == p
+	})
+
+	g.Close()
+	<-done
+}
+
+func TestResult(t *testing.T) {
+	var g = makeGame(t, 3)
+
+	var result = make(chan *lobby.Result, 1)
+	g.Report = func(r *lobby.Result) {
+		result <- r
+	}
+
+	var playing = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		if ev.Arg.(*lobby.StageChanged).New == lobby.StagePlaying {
+			close(playing)
+		}
+	})
+
+	var conns = make([]*network.W3GSConn, 2)
+	var players = make([]*lobby.Player, 2)
+	for i, name := range []string{"A", "B"} {
+		conn, p, err := joinRaw(t, &g.Lobby, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		go playRaw(conn)
+
+		conns[i] = conn
+		players[i] = p
+	}
+
+	for i := 0; !players[0].Ready() || !players[1].Ready(); i++ {
+		if i > 100 {
+			t.Fatal("Players not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var slots = g.SlotInfo().Slots
+	if slots[0].Team == slots[1].Team {
+		t.Fatal("Expected players on different teams")
+	}
+
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-playing:
+	case <-time.After(time.Second):
+		t.Fatal("Game not started")
+	}
+
+	if _, err := conns[0].Send(&w3gs.Leave{Reason: w3gs.LeaveLost}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conns[1].Send(&w3gs.Leave{Reason: w3gs.LeaveWon}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-result:
+		if len(r.Players) != 2 || r.Players[0].Name != "A" || r.Players[0].Reason != w3gs.LeaveLost || r.Players[1].Reason != w3gs.LeaveWon {
+			t.Fatal("Unexpected players in result", r.Players)
+		}
+		if r.Players[0].Left > r.Duration || r.Players[1].Left > r.Duration {
+			t.Fatal("Unexpected leave time", r.Players, r.Duration)
+		}
+		if !reflect.DeepEqual(r.Winners, []uint8{slots[1].Team}) {
+			t.Fatal("Expected B to win, got", r.Winners)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("No result reported")
+	}
+}
+
+func TestEqualize(t *testing.T) {
+	var g = makeGame(t, 2)
+	g.Equalize = time.Second
+
+	var playing = make(chan struct{})
+	var done = make(chan struct{})
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		switch ev.Arg.(*lobby.StageChanged).New {
+		case lobby.StagePlaying:
+			close(playing)
+		case lobby.StageDone:
+			close(done)
+		}
+	})
+
+	fast, pf, err := joinRaw(t, &g.Lobby, "fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fast.Close()
+	slow, ps, err := joinRaw(t, &g.Lobby, "slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+
+	var actions = make(chan time.Time, 10)
+	go func() {
+		for {
+			pkt, err := fast.NextPacket(time.Second)
+			if err != nil {
+				return
+			}
+			switch p := pkt.(type) {
+			case *w3gs.Ping:
+				fast.Send(&w3gs.Pong{Ping: *p})
+			case *w3gs.MapCheck:
+				fast.Send(&w3gs.MapState{Ready: true, FileSize: p.FileSize})
+			case *w3gs.CountDownEnd:
+				fast.Send(&w3gs.GameLoaded{})
+			case *w3gs.TimeSlot:
+				if len(p.Actions) > 0 {
+					actions <- time.Now()
+				}
+			}
+		}
+	}()
+	go func() {
+		for {
+			pkt, err := slow.NextPacket(time.Second)
+			if err != nil {
+				return
+			}
+			switch p := pkt.(type) {
+			case *w3gs.Ping:
+				var pong = w3gs.Pong{Ping: *p}
+				time.AfterFunc(100*time.Millisecond, func() { slow.Send(&pong) })
+			case *w3gs.MapCheck:
+				slow.Send(&w3gs.MapState{Ready: true, FileSize: p.FileSize})
+			case *w3gs.CountDownEnd:
+				slow.Send(&w3gs.GameLoaded{})
+			}
+		}
+	}()
+
+	for i := 0; !pf.Ready() || !ps.Ready() || ps.Ping() < 80; i++ {
+		if i > 200 {
+			t.Fatal("Players not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-playing:
+	case <-time.After(time.Second):
+		t.Fatal("Game not started")
+	}
+
+	var start = time.Now()
+	if _, err := fast.Send(&w3gs.GameAction{Data: []byte{1, 2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ts := <-actions:
+		if ts.Sub(start) < 60*time.Millisecond {
+			t.Fatal("Expected action to be delayed, got", ts.Sub(start))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Action not relayed")
+	}
+
+	g.Close()
+	<-done
+}
+
+func TestHTTPBans(t *testing.T) {
+	var list lobby.BanList
+	var srv = httptest.NewServer(lobby.BanHandler(&list))
+	defer srv.Close()
+
+	var bans = lobby.HTTPBans{URL: srv.URL}
+	if ban, err := bans.Find("banned", "", nil); err != nil || ban != nil {
+		t.Fatal("Expected no ban", ban, err)
+	}
+
+	if err := bans.Add(&lobby.Ban{Name: "banned", Realm: "Europe", Reason: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bans.Add(&lobby.Ban{IP: net.IPv4(10, 0, 0, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bans.Add(&lobby.Ban{Reason: "empty"}); err != lobby.ErrBanStore {
+		t.Fatal("Expected ErrBanStore, got", err)
+	}
+
+	if ban, err := bans.Find("BANNED", "europe", nil); err != nil || ban == nil || ban.Reason != "test" || ban.Created.IsZero() {
+		t.Fatal("Expected ban", ban, err)
+	}
+	if ban, err := bans.Find("banned", "Azeroth", nil); err != nil || ban != nil {
+		t.Fatal("Expected no ban on other realm", ban, err)
+	}
+	if ban, err := bans.Find("other", "", net.IPv4(10, 0, 0, 1)); err != nil || ban == nil {
+		t.Fatal("Expected IP ban", ban, err)
+	}
+
+	var g = makeGame(t, 2)
+	defer func() {
+		g.Close()
+		g.Wait()
+	}()
+
+	g.Bans = &bans
+	g.Realm = "Europe"
+	if _, _, err := joinRaw(t, &g.Lobby, "banned"); err != lobby.ErrBanned {
+		t.Fatal("Expected ErrBanned, got", err)
+	}
+
+	if err := bans.Remove("banned", "Europe", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Bans()) != 1 {
+		t.Fatal("Expected one ban left", list.Bans())
+	}
+
+	c, _, err := joinRaw(t, &g.Lobby, "banned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestRehost(t *testing.T) {
+	var m = lobby.NewManager()
+	m.IP = net.IPv4(127, 0, 0, 1)
+	defer m.Close()
+
+	var hosted = make(chan *lobby.Game, 4)
+	m.On(&lobby.GameHosted{}, func(ev *network.Event) {
+		hosted <- ev.Arg.(*lobby.GameHosted).Game
+	})
+
+	var p = lobby.RehostPolicy{
+		Create: func() (*lobby.Game, error) {
+			return makeGame(t, 1), nil
+		},
+		Info:   &w3gs.GameInfo{GameName: "DotA"},
+		Number: 42,
+		Full:   true,
+	}
+	if p.GameName(42) != "DotA #42" {
+		t.Fatal("Unexpected game name", p.GameName(42))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var res = make(chan error, 1)
+	go func() {
+		res <- m.RehostContext(ctx, &p)
+	}()
+
+	var next = func() *lobby.Game {
+		select {
+		case g := <-hosted:
+			return g
+		case <-time.After(time.Second):
+			t.Fatal("Expected rehost")
+		}
+		return nil
+	}
+
+	var g1 = next()
+	c, _, err := joinRaw(t, &g1.Lobby, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Full
+	var g2 = next()
+	if g2 == g1 || m.CountGames() != 2 {
+		t.Fatal("Expected new lobby once full")
+	}
+
+	// Aborted
+	if err := m.Unhost(g2); err != nil {
+		t.Fatal(err)
+	}
+	var g3 = next()
+	if g3 == g2 {
+		t.Fatal("Expected new lobby once aborted")
+	}
+
+	cancel()
+	if err := <-res; err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+	for i := 0; m.Port(g3) != 0; i++ {
+		if i > 100 {
+			t.Fatal("Expected current lobby to be unhosted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if m.Port(g1) == 0 {
+		t.Fatal("Expected full lobby to stay hosted")
+	}
+}
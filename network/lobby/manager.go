@@ -0,0 +1,305 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// PlayerLimit restricts the number of players in a lobby
+// A single PlayerLimit can be shared by multiple lobbies to restrict their combined number of players
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type PlayerLimit struct {
+	mut sync.Mutex
+	num int
+	max int
+}
+
+// NewPlayerLimit initializes a PlayerLimit of max players
+func NewPlayerLimit(max int) *PlayerLimit {
+	return &PlayerLimit{max: max}
+}
+
+// Count the number of players currently holding a place
+func (p *PlayerLimit) Count() int {
+	p.mut.Lock()
+	var n = p.num
+	p.mut.Unlock()
+	return n
+}
+
+// acquire a place for a single player, returns false if the limit is reached
+func (p *PlayerLimit) acquire() bool {
+	if p == nil {
+		return true
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.max > 0 && p.num >= p.max {
+		return false
+	}
+	p.num++
+	return true
+}
+
+// release a place acquired with acquire()
+func (p *PlayerLimit) release() {
+	if p == nil {
+		return
+	}
+
+	p.mut.Lock()
+	p.num--
+	p.mut.Unlock()
+}
+
+// GameHosted event
+type GameHosted struct {
+	*Game
+	Port int
+}
+
+// GameStageChanged event
+type GameStageChanged struct {
+	*Game
+	StageChanged
+}
+
+// GameClosed event
+type GameClosed struct {
+	*Game
+	Port int
+}
+
+// Manager hosts multiple games in a single process
+// Every game is served on its own port and advertised on a shared UDP socket
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Manager struct {
+	network.EventEmitter
+
+	wg sync.WaitGroup
+
+	mut     sync.Mutex
+	games   map[*Game]*managed
+	counter uint32
+	closed  bool
+
+	// Set once before Host(), read-only after that
+	Family network.IPFamily
+	IP     net.IP
+
+	Mux         *network.UDPMux // Shared socket on which games are advertised in Local Area Network (nil to disable)
+	MinPort     int             // First port in range of ports that games are served on (0 for random ports)
+	MaxPort     int             // Last port in range of ports that games are served on
+	MaxGames    int             // Max number of concurrently hosted games (0 for unlimited)
+	PlayerLimit *PlayerLimit    // Max combined number of players in all games (nil for unlimited)
+}
+
+type managed struct {
+	port   int
+	cancel context.CancelFunc
+}
+
+// NewManager initializes a new Manager struct
+func NewManager() *Manager {
+	return &Manager{
+		games: make(map[*Game]*managed),
+	}
+}
+
+// Games currently hosted
+func (m *Manager) Games() []*Game {
+	m.mut.Lock()
+	var res = make([]*Game, 0, len(m.games))
+	for g := range m.games {
+		res = append(res, g)
+	}
+	m.mut.Unlock()
+	return res
+}
+
+// CountGames currently hosted
+func (m *Manager) CountGames() int {
+	m.mut.Lock()
+	var n = len(m.games)
+	m.mut.Unlock()
+	return n
+}
+
+// Port on which g is served, returns 0 if g is not hosted by m
+func (m *Manager) Port(g *Game) int {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if mg := m.games[g]; mg != nil {
+		return mg.port
+	}
+	return 0
+}
+
+// mut should be locked
+func (m *Manager) listen() (*net.TCPListener, int, error) {
+	if m.MinPort <= 0 {
+		ln, err := network.ListenTCP(m.Family, &net.TCPAddr{IP: m.IP})
+		if err != nil {
+			return nil, 0, err
+		}
+		return ln, ln.Addr().(*net.TCPAddr).Port, nil
+	}
+
+	var used = make(map[int]struct{}, len(m.games))
+	for _, mg := range m.games {
+		used[mg.port] = struct{}{}
+	}
+
+	for port := m.MinPort; port <= m.MaxPort; port++ {
+		if _, ok := used[port]; ok {
+			continue
+		}
+		if ln, err := network.ListenTCP(m.Family, &net.TCPAddr{IP: m.IP, Port: port}); err == nil {
+			return ln, port, nil
+		}
+	}
+
+	return nil, 0, ErrNoPort
+}
+
+// Host g on a free port until the game is done or m is closed, returns the port
+// If info is not nil, g is advertised on Mux while in StageLobby (GamePort is set to the allocated port, HostCounter to a unique value if 0)
+// g shares PlayerLimit with the other games in m
+func (m *Manager) Host(g *Game, info *w3gs.GameInfo) (int, error) {
+	return m.HostContext(context.Background(), g, info)
+}
+
+// HostContext hosts g on a free port until the game is done, ctx is done, or m is closed, returns the port
+func (m *Manager) HostContext(ctx context.Context, g *Game, info *w3gs.GameInfo) (int, error) {
+	m.mut.Lock()
+	if m.closed {
+		m.mut.Unlock()
+		return 0, ErrManagerClosed
+	}
+	if m.games[g] != nil {
+		m.mut.Unlock()
+		return 0, ErrInvalidArgument
+	}
+	if m.MaxGames > 0 && len(m.games) >= m.MaxGames {
+		m.mut.Unlock()
+		return 0, ErrGameLimit
+	}
+
+	ln, port, err := m.listen()
+	if err != nil {
+		m.mut.Unlock()
+		return 0, err
+	}
+
+	m.counter++
+	var counter = m.counter
+
+	ctx, cancel := context.WithCancel(ctx)
+	m.games[g] = &managed{port: port, cancel: cancel}
+	m.wg.Add(1)
+	m.mut.Unlock()
+
+	g.PlayerLimit = m.PlayerLimit
+
+	var eid = g.On(&StageChanged{}, func(ev *network.Event) {
+		var s = ev.Arg.(*StageChanged)
+		m.Fire(&GameStageChanged{Game: g, StageChanged: *s})
+		if s.New == StageDone {
+			cancel()
+		}
+	})
+
+	m.Fire(&GameHosted{Game: g, Port: port})
+
+	var adv = make(chan struct{})
+	if info != nil && m.Mux != nil {
+		var gi = *info
+		gi.GamePort = uint16(port)
+		if gi.HostCounter == 0 {
+			gi.HostCounter = counter
+		}
+
+		advctx, advcancel := context.WithCancel(ctx)
+		var aid = g.On(&StageChanged{}, func(ev *network.Event) {
+			if ev.Arg.(*StageChanged).New != StageLobby {
+				advcancel()
+			}
+		})
+
+		go func() {
+			defer close(adv)
+			defer advcancel()
+			defer g.Off(aid)
+
+			if g.Stage() != StageLobby {
+				return
+			}
+			if err := g.Advertise(advctx, m.Mux, &gi); err != nil && err != context.Canceled && !network.IsCloseError(err) {
+				m.Fire(&network.AsyncError{Src: "Manager.HostContext[Advertise]", Err: err})
+			}
+		}()
+	} else {
+		close(adv)
+	}
+
+	go func() {
+		if err := g.ServeContext(ctx, ln); err != nil && err != context.Canceled && !network.IsCloseError(err) {
+			m.Fire(&network.AsyncError{Src: "Manager.HostContext[Serve]", Err: err})
+		}
+
+		cancel()
+		<-adv
+		g.Off(eid)
+
+		m.mut.Lock()
+		delete(m.games, g)
+		m.mut.Unlock()
+
+		m.Fire(&GameClosed{Game: g, Port: port})
+		m.wg.Done()
+	}()
+
+	return port, nil
+}
+
+// Unhost stops serving g and closes all its connections
+func (m *Manager) Unhost(g *Game) error {
+	m.mut.Lock()
+	var mg = m.games[g]
+	m.mut.Unlock()
+
+	if mg == nil {
+		return ErrInvalidArgument
+	}
+
+	mg.cancel()
+	return nil
+}
+
+// Wait for all games to close
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Close all games and stop accepting new ones, waits for games to close
+func (m *Manager) Close() {
+	m.mut.Lock()
+	m.closed = true
+	for _, mg := range m.games {
+		mg.cancel()
+	}
+	m.mut.Unlock()
+
+	m.wg.Wait()
+}
@@ -6,6 +6,7 @@ package lobby
 
 import (
 	"math"
+	"net"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,11 +24,17 @@ type Player struct {
 	// Atomic
 	tick  uint32
 	rtt   uint32
+	ping  uint32
 	ready uint32
 	leave uint32
 	lag   uint32
 	tag   atomic.Value //string
 
+	encmut sync.Mutex
+	enc    w3gs.Encoder
+
+	gproxy *gproxyConn
+
 	ackmut sync.Mutex
 	ackarr [2048]uint32
 	ackidx int
@@ -37,6 +44,10 @@ type Player struct {
 	PlayerInfo   w3gs.PlayerInfo
 	StartTime    time.Time
 	PingInterval time.Duration
+	SendQueue    *network.SendQueue // Outbound FIFO queue for all packets (nil to write directly)
+
+	KeepAliveTimeout time.Duration // Max time without received packets before the connection is closed (0 to disable)
+	DownloadSize     uint32        // Map size offered for download, players reporting a different size are not ready (0 to kick players without the map)
 }
 
 // NewPlayer initializes a new Player struct
@@ -46,9 +57,13 @@ func NewPlayer(info *w3gs.PlayerInfo) *Player {
 		StartTime:    time.Now(),
 		PingInterval: 5 * time.Second,
 
-		rtt: math.MaxUint32,
+		KeepAliveTimeout: time.Minute,
+
+		rtt:  math.MaxUint32,
+		ping: math.MaxUint32,
 	}
 
+	p.SendQueue = network.NewW3GSSendQueue(&p.W3GSConn, SendQueueSize, network.QueueDrop)
 	p.InitDefaultHandlers()
 	p.SetWriteTimeout(time.Second)
 
@@ -65,11 +80,21 @@ func (p *Player) RTT() uint32 {
 	return atomic.LoadUint32(&p.rtt)
 }
 
+// Ping to host, smoothed RTT (math.MaxUint32 if unknown)
+func (p *Player) Ping() uint32 {
+	return atomic.LoadUint32(&p.ping)
+}
+
 // Ready to start the game (ping and map packets received)
 func (p *Player) Ready() bool {
 	return atomic.LoadUint32(&p.ready) != 0 && p.RTT() != math.MaxUint32
 }
 
+// GProxy reports if the player supports GProxy++ reconnects
+func (p *Player) GProxy() bool {
+	return p.gproxy != nil && p.gproxy.initialized()
+}
+
 // LeaveReason from lobby
 func (p *Player) LeaveReason() w3gs.LeaveReason {
 	var reason = (w3gs.LeaveReason)(atomic.LoadUint32(&p.leave))
@@ -83,25 +108,42 @@ func (p *Player) setLeaveReason(reason w3gs.LeaveReason) {
 	atomic.CompareAndSwapUint32(&p.leave, 0, (uint32)(reason))
 }
 
-// Lag in receiving packets
+// Lag in receiving packets or acknowledging TimeSlots
 func (p *Player) Lag() bool {
 	return atomic.LoadUint32(&p.lag) != 0
 }
 
-func (p *Player) setLag(lag bool) bool {
-	var l uint32
-	if lag {
-		l = ^l
-	}
-	if !atomic.CompareAndSwapUint32(&p.lag, ^l, l) {
-		return false
-	}
-	if lag {
-		p.Fire(&StartLag{})
-	} else {
-		p.Fire(&StopLag{})
+// Lag sources
+const (
+	lagPing uint32 = 1 << iota // Ping response timeout
+	lagSync                    // Too many unacknowledged TimeSlots
+)
+
+// setLag sets or clears lag source src, fires StartLag/StopLag if the overall lag state changed
+func (p *Player) setLag(src uint32, lag bool) bool {
+	for {
+		var old = atomic.LoadUint32(&p.lag)
+		var new = old &^ src
+		if lag {
+			new |= src
+		}
+		if old == new {
+			return false
+		}
+		if !atomic.CompareAndSwapUint32(&p.lag, old, new) {
+			continue
+		}
+		if (old == 0) == (new == 0) {
+			return false
+		}
+
+		if lag {
+			p.Fire(&StartLag{})
+		} else {
+			p.Fire(&StopLag{})
+		}
+		return true
 	}
-	return true
 }
 
 // BattleTag for player
@@ -112,9 +154,43 @@ func (p *Player) BattleTag() string {
 	return ""
 }
 
+// SetConn closes the old connection and starts using the new net.Conn
+func (p *Player) SetConn(conn net.Conn, fact w3gs.PacketFactory, enc w3gs.Encoding) {
+	p.W3GSConn.SetConn(conn, fact, enc)
+
+	p.encmut.Lock()
+	p.enc.Encoding = enc
+	p.encmut.Unlock()
+}
+
+// Send pkt to player
+// If SendQueue is set, pkt is serialized immediately and queued behind all previously sent packets
+// Queued packets are written by Run() and skip the egress middleware chain
+func (p *Player) Send(pkt w3gs.Packet) (int, error) {
+	if p.SendQueue == nil {
+		return p.W3GSConn.Send(pkt)
+	}
+
+	p.encmut.Lock()
+	var b, err = p.enc.Serialize(pkt)
+	if err == nil {
+		// Encoder buffer is reused, copy before queueing
+		b = append([]byte(nil), b...)
+	}
+	p.encmut.Unlock()
+
+	if err != nil {
+		return 0, err
+	}
+	if err := p.SendQueue.Enqueue(b, network.PriorityNormal); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
 // SendOrClose sends pkt to player, closes connection on failure
 func (p *Player) SendOrClose(pkt w3gs.Packet) (int, error) {
-	n, err := p.W3GSConn.Send(pkt)
+	n, err := p.Send(pkt)
 	if err == nil || network.IsCloseError(err) {
 		return n, nil
 	}
@@ -123,6 +199,54 @@ func (p *Player) SendOrClose(pkt w3gs.Packet) (int, error) {
 	return n, err
 }
 
+// Enqueue serialized packet b to be sent by Run() in order with Send(), closes connection if the queue is full
+// b must not be modified after calling Enqueue
+func (p *Player) Enqueue(b []byte) error {
+	var err error
+	if p.SendQueue == nil {
+		_, err = p.W3GSConn.Write(b)
+	} else {
+		err = p.SendQueue.Enqueue(b, network.PriorityNormal)
+	}
+
+	if err == nil || network.IsCloseError(err) {
+		return nil
+	}
+
+	p.Close()
+	return err
+}
+
+func (p *Player) runQueue() func() {
+	var done = make(chan struct{})
+
+	go func() {
+		if err := p.SendQueue.Run(); err != nil && !network.IsCloseError(err) {
+			p.Fire(&network.AsyncError{Src: "runQueue[Send]", Err: err})
+		}
+
+		// Queue is either closed (and flushed) or broken
+		p.Close()
+		close(done)
+	}()
+
+	return func() {
+		p.SendQueue.Close()
+		<-done
+	}
+}
+
+// closeAfterFlush closes the connection once all queued packets are sent
+func (p *Player) closeAfterFlush() {
+	if p.SendQueue == nil {
+		p.Close()
+		return
+	}
+
+	// runQueue() closes the connection once the queue is empty
+	p.SendQueue.Close()
+}
+
 // Kick from lobby
 func (p *Player) Kick(reason w3gs.LeaveReason) {
 	p.setLeaveReason(reason)
@@ -131,7 +255,7 @@ func (p *Player) Kick(reason w3gs.LeaveReason) {
 			Reason: reason,
 		}})
 	}
-	p.Close()
+	p.closeAfterFlush()
 }
 
 // DequeueAck from queue
@@ -208,13 +332,13 @@ func (p *Player) runPing() func() {
 						// Stop lagging
 						if !lagging {
 							delay = LagDelay
-							p.setLag(false)
+							p.setLag(lagPing, false)
 						}
 					case <-timeout.C:
 						// Response timeout, start lagging
 						delay = LagRecoverDelay
 						lagging = true
-						p.setLag(true)
+						p.setLag(lagPing, true)
 						continue
 					}
 					break
@@ -225,17 +349,32 @@ func (p *Player) runPing() func() {
 
 	return func() {
 		stop <- struct{}{}
-		p.setLag(false)
+		p.setLag(lagPing|lagSync, false)
 	}
 }
 
 // Run reads packets and emits an event for each received packet
 // Not safe for concurrent invocation
 func (p *Player) Run() error {
+	if p.SendQueue != nil {
+		var stop = p.runQueue()
+		defer stop()
+	}
 	if p.PingInterval != 0 {
 		var stop = p.runPing()
 		defer stop()
 	}
+	if p.KeepAliveTimeout != 0 {
+		// Pings are sent by runPing(), only check for activity
+		var k = network.NewW3GSKeepAlive(&p.W3GSConn, 0, p.KeepAliveTimeout)
+		k.Emitter = &p.EventEmitter
+
+		var eid = k.Bind(&p.EventEmitter)
+		defer p.Off(eid)
+
+		var stop = k.Start()
+		defer stop()
+	}
 
 	return p.W3GSConn.Run(&p.EventEmitter, time.Minute)
 }
@@ -266,6 +405,15 @@ func (p *Player) onPong(ev *network.Event) {
 	} else {
 		atomic.StoreUint32(&p.rtt, rtt)
 	}
+
+	var ping = rtt
+	if old := atomic.LoadUint32(&p.ping); old != math.MaxUint32 {
+		// Exponentially weighted moving average
+		ping = uint32((uint64(old)*(PingSmoothing-1) + uint64(rtt)) / PingSmoothing)
+	}
+	atomic.StoreUint32(&p.ping, ping)
+
+	p.Fire(&PingUpdated{RTT: rtt, Ping: ping})
 }
 
 func (p *Player) onLeave(ev *network.Event) {
@@ -273,7 +421,7 @@ func (p *Player) onLeave(ev *network.Event) {
 	p.setLeaveReason(pkt.Reason)
 
 	p.Send(&w3gs.LeaveAck{})
-	p.Close()
+	p.closeAfterFlush()
 }
 
 func (p *Player) onPlayerExtra(ev *network.Event) {
@@ -294,6 +442,10 @@ func (p *Player) onPlayerExtra(ev *network.Event) {
 
 func (p *Player) onMapState(ev *network.Event) {
 	var s = ev.Arg.(*w3gs.MapState)
+	if p.DownloadSize != 0 && (!s.Ready || s.FileSize != p.DownloadSize) {
+		// Map download in progress
+		return
+	}
 	if !s.Ready {
 		p.Fire(&network.AsyncError{Src: "onMapState[notReady]", Err: ErrMapUnavailable})
 		p.Kick(w3gs.LeaveLobby)
@@ -308,6 +460,9 @@ func (p *Player) onMapState(ev *network.Event) {
 }
 
 func (p *Player) onStartDownload(ev *network.Event) {
+	if p.DownloadSize != 0 {
+		return
+	}
 	p.Fire(&network.AsyncError{Src: "onStartDownload", Err: ErrMapUnavailable})
 	p.Kick(w3gs.LeaveLobby)
 }
@@ -0,0 +1,120 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// RehostPolicy keeps a fresh lobby available, a new game is hosted whenever the current one starts or is aborted
+type RehostPolicy struct {
+	Create     func() (*Game, error) // Creates a new game with the desired settings
+	Info       *w3gs.GameInfo        // Advertised game info (nil to disable advertising), GameName is numbered with NameFormat
+	NameFormat string                // Format of numbered game names, with the name and number as arguments (empty for "%s #%d")
+	Number     int                   // Number of the first game (0 for 1)
+	Full       bool                  // Also rehost once the current lobby is full
+}
+
+// GameName returns the advertised name of game number n
+func (p *RehostPolicy) GameName(n int) string {
+	var name string
+	if p.Info != nil {
+		name = p.Info.GameName
+	}
+
+	var format = p.NameFormat
+	if format == "" {
+		format = "%s #%d"
+	}
+	return fmt.Sprintf(format, name, n)
+}
+
+// Rehost games created by p until m is closed
+func (m *Manager) Rehost(p *RehostPolicy) error {
+	return m.RehostContext(context.Background(), p)
+}
+
+// RehostContext hosts games created by p until ctx is done or m is closed
+// Games that already left the lobby are unaffected by ctx, the current lobby is unhosted once ctx is done
+func (m *Manager) RehostContext(ctx context.Context, p *RehostPolicy) error {
+	var num = p.Number
+	if num == 0 {
+		num = 1
+	}
+
+	for ; ; num++ {
+		g, err := p.Create()
+		if err != nil {
+			return err
+		}
+
+		var info *w3gs.GameInfo
+		if p.Info != nil {
+			var gi = *p.Info
+			gi.GameName = p.GameName(num)
+			info = &gi
+		}
+
+		var trigger = make(chan struct{}, 1)
+		var notify = func(ev *network.Event) {
+			select {
+			case trigger <- struct{}{}:
+			default:
+			}
+		}
+
+		var sid = g.On(&StageChanged{}, notify)
+		var cid = m.On(&GameClosed{}, func(ev *network.Event) {
+			if ev.Arg.(*GameClosed).Game == g {
+				notify(ev)
+			}
+		})
+
+		// Events are fired while slotmut is locked, check asynchronously
+		var fid network.EventID
+		if p.Full {
+			fid = g.On(&SlotChanged{}, notify)
+		}
+
+		var off = func() {
+			g.Off(sid)
+			m.Off(cid)
+			if p.Full {
+				g.Off(fid)
+			}
+		}
+
+		if _, err := m.Host(g, info); err != nil {
+			off()
+			return err
+		}
+
+		for wait := true; wait; {
+			select {
+			case <-ctx.Done():
+				off()
+				if g.Stage() == StageLobby {
+					m.Unhost(g)
+				}
+				return ctx.Err()
+			case <-trigger:
+			}
+
+			switch {
+			case g.Stage() != StageLobby, m.Port(g) == 0:
+				// Started or aborted
+				wait = false
+			case p.Full && g.SlotsAvailable() == 0:
+				wait = false
+			}
+		}
+
+		off()
+	}
+}
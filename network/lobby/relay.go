@@ -0,0 +1,120 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// RelayQueueSize is the maximum number of lobby messages queued per ChatRelay before new messages are dropped
+const RelayQueueSize = 64
+
+// ChatRelay forwards chat between a Lobby and a chat client (BNCS or CAPI, see network.Chat)
+type ChatRelay struct {
+	// Set once before Bind(), read-only after that
+	LobbyFormat string            // Format of lobby messages sent to chat, replaces {player}, {scope} and {message} ("" to disable)
+	ChatFormat  string            // Format of chat messages sent to lobby, replaces {user} and {message} ("" to disable)
+	Scopes      protocol.BitSet32 // Relayed message scopes (bit n+1 for w3gs.MessageScope n), lobby chat has ScopeAll and directed chat ScopeDirected
+}
+
+// NewChatRelay initializes a ChatRelay struct with default formats that relays public chat only
+func NewChatRelay() *ChatRelay {
+	var r = ChatRelay{
+		LobbyFormat: "<{player}> {message}",
+		ChatFormat:  "[{user}] {message}",
+	}
+	r.Scopes.Set(uint(w3gs.ScopeAll) + 1)
+	return &r
+}
+
+func relayScope(msg *w3gs.Message) w3gs.MessageScope {
+	if msg.Type != w3gs.MsgChatExtra {
+		return w3gs.ScopeAll
+	}
+	if msg.Scope > w3gs.ScopeDirected {
+		return w3gs.ScopeDirected
+	}
+	return msg.Scope
+}
+
+// Bind relays chat of l to c and vice versa, whispers and emotes in c are ignored
+// Lobby messages are sent on a separate goroutine (chat clients may block while rate-limiting),
+// at most RelayQueueSize messages are queued before new messages are dropped
+// Returns a function that unbinds l and c and stops the goroutine
+func (r *ChatRelay) Bind(l *Lobby, c network.Chat) func() {
+	var queue = make(chan string, RelayQueueSize)
+	var done = make(chan struct{})
+
+	go func() {
+		for s := range queue {
+			if err := c.Say(s); err != nil && !network.IsCloseError(err) {
+				l.Fire(&network.AsyncError{Src: "ChatRelay.Bind[Say]", Err: err})
+			}
+		}
+		close(done)
+	}()
+
+	var mut sync.Mutex
+	var closed bool
+
+	var lid = l.On(&PlayerChat{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*PlayerChat)
+
+		var scope = relayScope(pkt.Message)
+		if r.LobbyFormat == "" || !r.Scopes.Test(uint(scope)+1) {
+			return
+		}
+
+		var s = strings.NewReplacer(
+			"{player}", pkt.Player.PlayerInfo.PlayerName,
+			"{scope}", scope.String(),
+			"{message}", pkt.Content,
+		).Replace(r.LobbyFormat)
+
+		mut.Lock()
+		if closed {
+			mut.Unlock()
+			return
+		}
+		select {
+		case queue <- s:
+			mut.Unlock()
+		default:
+			mut.Unlock()
+			l.Fire(&network.AsyncError{Src: "ChatRelay.Bind[Queue]", Err: network.ErrQueueFull})
+		}
+	})
+
+	var cid = c.On(&network.ChatMessage{}, func(ev *network.Event) {
+		var pkt = ev.Arg.(*network.ChatMessage)
+		if r.ChatFormat == "" || pkt.Whisper || pkt.Emote {
+			return
+		}
+
+		l.Say(strings.NewReplacer(
+			"{user}", pkt.Name,
+			"{message}", pkt.Content,
+		).Replace(r.ChatFormat))
+	})
+
+	return func() {
+		l.Off(lid)
+		c.Off(cid)
+
+		mut.Lock()
+		if !closed {
+			closed = true
+			close(queue)
+		}
+		mut.Unlock()
+
+		<-done
+	}
+}
@@ -0,0 +1,145 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// PlayerResult of a player in a finished game
+type PlayerResult struct {
+	PlayerID uint8
+	Name     string
+	Team     uint8
+	Observer bool
+	Reason   w3gs.LeaveReason // Reason reported by the player when leaving (LeaveDisconnect if it did not report one)
+	Left     time.Duration    // Time between game start and leaving (Duration if the player stayed until the end)
+}
+
+// Result of a finished game, used for ladder recording (see Reporter)
+type Result struct {
+	Start    time.Time     // Time all players finished loading
+	Duration time.Duration // Time between Start and the end of the game
+	Players  []PlayerResult
+	Winners  []uint8 // Team(s) that probably won the game (empty if undetermined)
+}
+
+// Reporter is called with the result once a game is done
+type Reporter func(r *Result)
+
+// slotmut should be locked
+func (g *Game) initResult() {
+	g.resmut.Lock()
+	g.result = Result{}
+	g.resultIdx = make(map[uint8]int)
+
+	for _, s := range g.slots {
+		if s.SlotStatus != w3gs.SlotOccupied || s.Computer {
+			continue
+		}
+		var p, ok = g.players[s.PlayerID]
+		if !ok {
+			continue
+		}
+
+		g.resultIdx[s.PlayerID] = len(g.result.Players)
+		g.result.Players = append(g.result.Players, PlayerResult{
+			PlayerID: s.PlayerID,
+			Name:     p.PlayerInfo.PlayerName,
+			Team:     s.Team,
+			Observer: s.Team == g.ObsTeam,
+		})
+	}
+	g.resmut.Unlock()
+}
+
+func (g *Game) startResult() {
+	g.resmut.Lock()
+	g.result.Start = time.Now()
+	g.resmut.Unlock()
+}
+
+func (g *Game) recordLeave(p *Player) {
+	g.resmut.Lock()
+	if i, ok := g.resultIdx[p.PlayerInfo.PlayerID]; ok && g.result.Players[i].Reason == 0 {
+		g.result.Players[i].Reason = p.LeaveReason()
+		if !g.result.Start.IsZero() {
+			g.result.Players[i].Left = time.Since(g.result.Start)
+		}
+	}
+	g.resmut.Unlock()
+}
+
+func (g *Game) report() {
+	if g.Report == nil {
+		return
+	}
+
+	g.resmut.Lock()
+	var res = g.result
+	res.Players = append([]PlayerResult(nil), g.result.Players...)
+	g.resmut.Unlock()
+
+	if res.Start.IsZero() {
+		res.Start = time.Now()
+	}
+	res.Duration = time.Since(res.Start)
+
+	for i := range res.Players {
+		if res.Players[i].Reason != 0 {
+			continue
+		}
+
+		// Player stayed until the end
+		res.Players[i].Reason = w3gs.LeaveDisconnect
+		res.Players[i].Left = res.Duration
+		if p := g.Player(res.Players[i].PlayerID); p != nil {
+			res.Players[i].Reason = p.LeaveReason()
+		}
+	}
+
+	res.Winners = winners(res.Players)
+	g.Report(&res)
+}
+
+// winners determines the probable winning team(s), based on the leave reasons reported by the players
+// Teams with a player that reported a win have won, otherwise the only team without a reported loss has won
+func winners(players []PlayerResult) []uint8 {
+	var won = make(map[uint8]bool)
+	var lost = make(map[uint8]bool)
+	var teams = make(map[uint8]bool)
+	for _, p := range players {
+		if p.Observer {
+			continue
+		}
+
+		teams[p.Team] = true
+		switch p.Reason {
+		case w3gs.LeaveWon:
+			won[p.Team] = true
+		case w3gs.LeaveLost, w3gs.LeaveLostBuildings:
+			lost[p.Team] = true
+		}
+	}
+
+	var res []uint8
+	if len(won) > 0 {
+		for t := range won {
+			res = append(res, t)
+		}
+	} else if len(lost) == len(teams)-1 {
+		for t := range teams {
+			if !lost[t] {
+				res = append(res, t)
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
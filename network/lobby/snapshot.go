@@ -0,0 +1,263 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Settings of a game that are kept in a Snapshot
+type Settings struct {
+	ObsTeam          uint8
+	Observers        ObsMode
+	ColorSet         protocol.BitSet32
+	ReadyTimeout     time.Duration
+	MaxPing          time.Duration
+	ShareAddr        bool
+	UploadRate       int
+	ReconnectPort    uint16
+	ReconnectTimeout time.Duration
+	Realm            string
+
+	LoadTimeout  time.Duration
+	LagTimeout   time.Duration
+	LagObservers bool
+	TurnRate     int
+	Countdown    time.Duration
+	DropDelay    time.Duration
+	SyncLimit    uint32
+	MinLatency   time.Duration
+	MaxLatency   time.Duration
+	Equalize     time.Duration
+	HCL          string
+}
+
+// Snapshot of the state of a game in the lobby, used to restore the lobby after a restart (see RestoreGame)
+// Player connections cannot be restored, instead their slots are reserved so they can rejoin
+type Snapshot struct {
+	Encoding w3gs.Encoding
+	MapCheck w3gs.MapCheck
+	SlotBase w3gs.SlotInfo
+	Slots    []w3gs.SlotData
+	Players  []string // Name of the player per slot (empty if not occupied by a player)
+	Reserved []string // Name of the player slot is reserved for (empty if none)
+	Fakes    []string // Name of the fake player per slot (empty if not occupied by a fake player)
+	Bans     []Ban    // Only stored if Bans is a *BanList
+	Saved    bool
+	Settings Settings
+}
+
+// Snapshot returns the current state of g
+func (g *Game) Snapshot() *Snapshot {
+	var s = Snapshot{
+		Encoding: g.Encoding,
+		MapCheck: g.MapCheck,
+		Saved:    g.saved,
+		Settings: Settings{
+			ObsTeam:          g.ObsTeam,
+			Observers:        g.Observers,
+			ColorSet:         g.ColorSet,
+			ReadyTimeout:     g.ReadyTimeout,
+			MaxPing:          g.MaxPing,
+			ShareAddr:        g.ShareAddr,
+			UploadRate:       g.UploadRate,
+			ReconnectPort:    g.ReconnectPort,
+			ReconnectTimeout: g.ReconnectTimeout,
+			Realm:            g.Realm,
+			LoadTimeout:      g.LoadTimeout,
+			LagTimeout:       g.LagTimeout,
+			LagObservers:     g.LagObservers,
+			TurnRate:         g.TurnRate,
+			Countdown:        g.Countdown,
+			DropDelay:        g.DropDelay,
+			SyncLimit:        g.SyncLimit,
+			MinLatency:       g.MinLatency,
+			MaxLatency:       g.MaxLatency,
+			Equalize:         g.Equalize,
+		},
+	}
+
+	if b, ok := g.Bans.(*BanList); ok {
+		s.Bans = b.Bans()
+	}
+
+	g.slotmut.Lock()
+	s.Settings.HCL = g.HCL
+	s.SlotBase = g.slotBase
+	s.SlotBase.Slots = append([]w3gs.SlotData(nil), g.slotBase.Slots...)
+	s.Slots = append([]w3gs.SlotData(nil), g.slots...)
+	s.Reserved = append([]string(nil), g.reserved...)
+	s.Players = make([]string, len(g.slots))
+	s.Fakes = make([]string, len(g.slots))
+	for i, sd := range g.slots {
+		if p, ok := g.players[sd.PlayerID]; ok && sd.SlotStatus == w3gs.SlotOccupied && !sd.Computer {
+			s.Players[i] = p.PlayerInfo.PlayerName
+		}
+		if g.isFakeSlot(g.slots, i) {
+			s.Fakes[i] = g.fakes[sd.PlayerID].PlayerName
+		}
+	}
+	g.slotmut.Unlock()
+
+	return &s
+}
+
+// RestoreGame initializes a new Game struct in the lobby state stored in s
+func RestoreGame(s *Snapshot) (*Game, error) {
+	var n = len(s.SlotBase.Slots)
+	if len(s.Slots) != n || len(s.Players) != n || len(s.Reserved) != n || (s.Fakes != nil && len(s.Fakes) != n) {
+		return nil, ErrInvalidArgument
+	}
+
+	var g = NewGame(s.Encoding, s.SlotBase, s.MapCheck)
+	g.saved = s.Saved
+	g.ObsTeam = s.Settings.ObsTeam
+	g.Observers = s.Settings.Observers
+	g.ColorSet = s.Settings.ColorSet
+	g.ReadyTimeout = s.Settings.ReadyTimeout
+	g.MaxPing = s.Settings.MaxPing
+	g.ShareAddr = s.Settings.ShareAddr
+	g.UploadRate = s.Settings.UploadRate
+	g.ReconnectPort = s.Settings.ReconnectPort
+	g.ReconnectTimeout = s.Settings.ReconnectTimeout
+	g.Realm = s.Settings.Realm
+	g.LoadTimeout = s.Settings.LoadTimeout
+	g.LagTimeout = s.Settings.LagTimeout
+	g.LagObservers = s.Settings.LagObservers
+	g.TurnRate = s.Settings.TurnRate
+	g.Countdown = s.Settings.Countdown
+	g.DropDelay = s.Settings.DropDelay
+	g.SyncLimit = s.Settings.SyncLimit
+	g.MinLatency = s.Settings.MinLatency
+	g.MaxLatency = s.Settings.MaxLatency
+	g.Equalize = s.Settings.Equalize
+	g.HCL = s.Settings.HCL
+
+	if s.Bans != nil {
+		g.Bans = &BanList{bans: append([]Ban(nil), s.Bans...)}
+	}
+
+	copy(g.slots, s.Slots)
+	copy(g.fired, s.Slots)
+	copy(g.reserved, s.Reserved)
+	for i, name := range s.Fakes {
+		if name == "" {
+			continue
+		}
+
+		var pid = g.slots[i].PlayerID
+		g.fakes[pid] = &w3gs.PlayerInfo{
+			PlayerID:   pid,
+			PlayerName: name,
+		}
+	}
+	for i, name := range s.Players {
+		if name == "" {
+			continue
+		}
+
+		// Keep slot for disconnected player
+		g.slots[i] = g.slotBase.Slots[i]
+		g.slots[i].SlotStatus = w3gs.SlotOpen
+		if g.reserved[i] == "" {
+			g.reserved[i] = name
+		}
+	}
+
+	return g, nil
+}
+
+// Encode snapshot to w
+func (s *Snapshot) Encode(w io.Writer) error {
+	var e = json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(s)
+}
+
+// Save snapshot to file name, replaces the file atomically
+func (s *Snapshot) Save(name string) error {
+	var tmp = name + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Encode(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, name)
+}
+
+// DecodeSnapshot decodes a snapshot from r
+func DecodeSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadSnapshot loads a snapshot from file name
+func LoadSnapshot(name string) (*Snapshot, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return DecodeSnapshot(f)
+}
+
+// Persist saves a snapshot of g to file name whenever its slots change, until ctx is done
+// The file is removed once the game leaves the lobby, Persist returns nil afterwards
+func (g *Game) Persist(ctx context.Context, name string) error {
+	var update = make(chan struct{}, 1)
+	update <- struct{}{}
+
+	// Events are fired while slotmut is locked, save asynchronously
+	var notify = func(ev *network.Event) {
+		select {
+		case update <- struct{}{}:
+		default:
+		}
+	}
+
+	var sid = g.On(&w3gs.SlotInfo{}, notify)
+	defer g.Off(sid)
+	var tid = g.On(&StageChanged{}, notify)
+	defer g.Off(tid)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-update:
+			if g.Stage() != StageLobby {
+				if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				return nil
+			}
+			if err := g.Snapshot().Save(name); err != nil {
+				return err
+			}
+		}
+	}
+}
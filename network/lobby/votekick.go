@@ -0,0 +1,205 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lobby
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// VoteKickStarted event
+type VoteKickStarted struct {
+	Target    *Player
+	Initiator *Player
+}
+
+// VoteKickEnded event
+type VoteKickEnded struct {
+	Target *Player
+	Passed bool
+}
+
+// VoteKick lets the players of a game vote to kick another player, progress is announced in chat
+// The vote passes once Threshold of the other players voted in favor, and fails once Duration has passed
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type VoteKick struct {
+	g *Game
+
+	mut    sync.Mutex
+	seq    uint32
+	target *Player
+	votes  protocol.BitSet32
+	timer  *time.Timer
+	last   time.Time
+
+	// Set once before Start(), read-only after that
+	Threshold float64       // Fraction of players (excluding the target) required to pass a vote
+	Duration  time.Duration // Max duration of a vote
+	Cooldown  time.Duration // Min time between the end of a vote and the start of the next
+}
+
+// NewVoteKick initializes a new VoteKick struct for g
+func NewVoteKick(g *Game) *VoteKick {
+	return &VoteKick{
+		g:         g,
+		Threshold: 0.6,
+		Duration:  time.Minute,
+		Cooldown:  30 * time.Second,
+	}
+}
+
+// Target of the vote in progress (nil if none)
+func (v *VoteKick) Target() *Player {
+	v.mut.Lock()
+	var t = v.target
+	v.mut.Unlock()
+	return t
+}
+
+// Start a vote by initiator to kick target, initiator votes in favor
+func (v *VoteKick) Start(initiator *Player, target *Player) error {
+	if initiator == target || v.g.Player(target.PlayerInfo.PlayerID) != target {
+		return ErrInvalidArgument
+	}
+
+	v.mut.Lock()
+	if v.target != nil {
+		v.mut.Unlock()
+		return ErrVoteActive
+	}
+	if time.Since(v.last) < v.Cooldown {
+		v.mut.Unlock()
+		return ErrVoteCooldown
+	}
+
+	v.seq++
+	var seq = v.seq
+
+	v.target = target
+	v.votes = 0
+	v.timer = time.AfterFunc(v.Duration, func() {
+		v.mut.Lock()
+		if v.seq != seq || v.target == nil {
+			v.mut.Unlock()
+			return
+		}
+		v.reset()
+		v.mut.Unlock()
+
+		v.ended(target, false)
+	})
+	v.mut.Unlock()
+
+	v.g.Fire(&VoteKickStarted{Target: target, Initiator: initiator})
+	v.g.Say(fmt.Sprintf("%s started a vote to kick %s", initiator.PlayerInfo.PlayerName, target.PlayerInfo.PlayerName))
+
+	return v.Vote(initiator)
+}
+
+// Vote in favor of kicking the target of the vote in progress
+func (v *VoteKick) Vote(voter *Player) error {
+	v.mut.Lock()
+	var target = v.target
+	if target == nil {
+		v.mut.Unlock()
+		return ErrNoVote
+	}
+	if voter == target {
+		v.mut.Unlock()
+		return ErrInvalidArgument
+	}
+
+	var pid = uint(voter.PlayerInfo.PlayerID)
+	if v.votes.Test(pid) {
+		v.mut.Unlock()
+		return nil
+	}
+	v.votes.Set(pid)
+
+	var votes, required, present = v.count()
+	if !present || votes >= required {
+		v.reset()
+	}
+	v.mut.Unlock()
+
+	switch {
+	case !present:
+		v.ended(target, false)
+	case votes >= required:
+		v.ended(target, true)
+		target.Kick(v.reason())
+	default:
+		v.g.Say(fmt.Sprintf("%s voted to kick %s (%d/%d)", voter.PlayerInfo.PlayerName, target.PlayerInfo.PlayerName, votes, required))
+	}
+
+	return nil
+}
+
+// Cancel the vote in progress
+func (v *VoteKick) Cancel() {
+	v.mut.Lock()
+	var target = v.target
+	if target != nil {
+		v.reset()
+	}
+	v.mut.Unlock()
+
+	if target != nil {
+		v.ended(target, false)
+	}
+}
+
+// mut should be locked
+func (v *VoteKick) count() (votes int, required int, present bool) {
+	var eligible = 0
+
+	v.g.slotmut.Lock()
+	_, present = v.g.players[v.target.PlayerInfo.PlayerID]
+	for pid, p := range v.g.players {
+		if p == v.target {
+			continue
+		}
+		eligible++
+		if v.votes.Test(uint(pid)) {
+			votes++
+		}
+	}
+	v.g.slotmut.Unlock()
+
+	required = int(math.Ceil(v.Threshold * float64(eligible)))
+	if required < 1 {
+		required = 1
+	}
+	return votes, required, present
+}
+
+// mut should be locked
+func (v *VoteKick) reset() {
+	v.timer.Stop()
+	v.target = nil
+	v.votes = 0
+	v.last = time.Now()
+}
+
+func (v *VoteKick) reason() w3gs.LeaveReason {
+	if v.g.Stage() == StageLobby {
+		return w3gs.LeaveLobby
+	}
+	return w3gs.LeaveDisconnect
+}
+
+func (v *VoteKick) ended(target *Player, passed bool) {
+	if passed {
+		v.g.Say(fmt.Sprintf("Vote passed, kicking %s", target.PlayerInfo.PlayerName))
+	} else {
+		v.g.Say(fmt.Sprintf("Vote to kick %s failed", target.PlayerInfo.PlayerName))
+	}
+	v.g.Fire(&VoteKickEnded{Target: target, Passed: passed})
+}
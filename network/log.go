@@ -0,0 +1,114 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// Logger is a minimal structured logging interface
+// Arguments are alternating key/value pairs (compatible with *slog.Logger)
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Common structured logging keys
+const (
+	LogKeyPacket = "packet"
+	LogKeyPeer   = "peer"
+	LogKeyGameID = "game_id"
+	LogKeyUser   = "user"
+	LogKeyError  = "err"
+)
+
+// NopLogger discards all messages
+type NopLogger struct{}
+
+// Debug message
+func (NopLogger) Debug(msg string, args ...interface{}) {}
+
+// Info message
+func (NopLogger) Info(msg string, args ...interface{}) {}
+
+// Warn message
+func (NopLogger) Warn(msg string, args ...interface{}) {}
+
+// Error message
+func (NopLogger) Error(msg string, args ...interface{}) {}
+
+// StdLogger writes messages as "LEVEL msg key=value ..." lines to a standard library *log.Logger
+type StdLogger struct {
+	*log.Logger
+
+	// Set once before first use, read-only after that
+	Verbose bool
+}
+
+// NewStdLogger initializes a StdLogger struct
+func NewStdLogger(l *log.Logger, verbose bool) *StdLogger {
+	return &StdLogger{Logger: l, Verbose: verbose}
+}
+
+func (l *StdLogger) print(level string, msg string, args []interface{}) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteByte(' ')
+	b.WriteString(msg)
+
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " !BADKEY=%v", args[i])
+		}
+	}
+
+	l.Logger.Println(b.String())
+}
+
+// Debug message, only printed if Verbose is set
+func (l *StdLogger) Debug(msg string, args ...interface{}) {
+	if l.Verbose {
+		l.print("DEBUG", msg, args)
+	}
+}
+
+// Info message
+func (l *StdLogger) Info(msg string, args ...interface{}) {
+	l.print("INFO", msg, args)
+}
+
+// Warn message
+func (l *StdLogger) Warn(msg string, args ...interface{}) {
+	l.print("WARN", msg, args)
+}
+
+// Error message
+func (l *StdLogger) Error(msg string, args ...interface{}) {
+	l.print("ERROR", msg, args)
+}
+
+// PacketType returns the type name of pkt (e.g. "w3gs.Ping") for logging
+func PacketType(pkt interface{}) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", pkt), "*")
+}
+
+func logPacket(l Logger, msg string, pkt interface{}, peer net.Addr) {
+	if l != nil {
+		l.Debug(msg, LogKeyPacket, PacketType(pkt), LogKeyPeer, peer)
+	}
+}
+
+func logError(l Logger, msg string, err error, peer net.Addr) {
+	if l != nil {
+		l.Warn(msg, LogKeyPeer, peer, LogKeyError, err)
+	}
+}
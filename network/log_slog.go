@@ -0,0 +1,26 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+//go:build go1.21
+// +build go1.21
+
+package network
+
+import "log/slog"
+
+// SlogLogger adapts *slog.Logger to Logger
+// Note that *slog.Logger already satisfies Logger, SlogLogger merely makes that explicit
+type SlogLogger struct {
+	*slog.Logger
+}
+
+var _ Logger = &SlogLogger{}
+
+// NewSlogLogger initializes a SlogLogger struct, uses slog.Default() if l is nil
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogLogger{Logger: l}
+}
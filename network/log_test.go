@@ -0,0 +1,28 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var l = network.NewStdLogger(log.New(&buf, "", 0), false)
+
+	l.Debug("hidden")
+	l.Info("Received packet", network.LogKeyPacket, network.PacketType(&w3gs.Ping{}), network.LogKeyGameID, 42)
+	l.Warn("odd", "key")
+
+	var exp = "INFO Received packet packet=w3gs.Ping game_id=42\nWARN odd !BADKEY=key\n"
+	if buf.String() != exp {
+		t.Fatalf("Unexpected output %q", buf.String())
+	}
+}
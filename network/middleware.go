@@ -0,0 +1,116 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// W3GSHandler processes a single W3GS packet
+type W3GSHandler func(pkt w3gs.Packet) error
+
+// W3GSMiddleware wraps next in additional behavior
+// Middleware can inspect, rewrite (call next with another packet), or drop (not call next) packets
+type W3GSMiddleware func(next W3GSHandler) W3GSHandler
+
+// BNCSHandler processes a single BNCS packet
+type BNCSHandler func(pkt bncs.Packet) error
+
+// BNCSMiddleware wraps next in additional behavior
+// Middleware can inspect, rewrite (call next with another packet), or drop (not call next) packets
+type BNCSMiddleware func(next BNCSHandler) BNCSHandler
+
+type w3gsChain struct {
+	mut sync.Mutex
+	in  []W3GSMiddleware
+	out []W3GSMiddleware
+}
+
+// Ingress returns the chain that processes received packets
+func (c *w3gsChain) Ingress() []W3GSMiddleware {
+	c.mut.Lock()
+	var res = c.in
+	c.mut.Unlock()
+	return res
+}
+
+// Egress returns the chain that processes sent packets
+func (c *w3gsChain) Egress() []W3GSMiddleware {
+	c.mut.Lock()
+	var res = c.out
+	c.mut.Unlock()
+	return res
+}
+
+// UseIngress appends mw to the chain that processes received packets (first added is called first)
+// Errors returned by the chain are returned by NextPacket
+func (c *w3gsChain) UseIngress(mw ...W3GSMiddleware) {
+	c.mut.Lock()
+	c.in = append(c.in[:len(c.in):len(c.in)], mw...)
+	c.mut.Unlock()
+}
+
+// UseEgress appends mw to the chain that processes sent packets (first added is called first)
+func (c *w3gsChain) UseEgress(mw ...W3GSMiddleware) {
+	c.mut.Lock()
+	c.out = append(c.out[:len(c.out):len(c.out)], mw...)
+	c.mut.Unlock()
+}
+
+// ChainW3GS composes mw into a single handler that ends in h
+func ChainW3GS(mw []W3GSMiddleware, h W3GSHandler) W3GSHandler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+type bncsChain struct {
+	mut sync.Mutex
+	in  []BNCSMiddleware
+	out []BNCSMiddleware
+}
+
+// Ingress returns the chain that processes received packets
+func (c *bncsChain) Ingress() []BNCSMiddleware {
+	c.mut.Lock()
+	var res = c.in
+	c.mut.Unlock()
+	return res
+}
+
+// Egress returns the chain that processes sent packets
+func (c *bncsChain) Egress() []BNCSMiddleware {
+	c.mut.Lock()
+	var res = c.out
+	c.mut.Unlock()
+	return res
+}
+
+// UseIngress appends mw to the chain that processes received packets (first added is called first)
+// Errors returned by the chain are returned by NextPacket
+func (c *bncsChain) UseIngress(mw ...BNCSMiddleware) {
+	c.mut.Lock()
+	c.in = append(c.in[:len(c.in):len(c.in)], mw...)
+	c.mut.Unlock()
+}
+
+// UseEgress appends mw to the chain that processes sent packets (first added is called first)
+func (c *bncsChain) UseEgress(mw ...BNCSMiddleware) {
+	c.mut.Lock()
+	c.out = append(c.out[:len(c.out):len(c.out)], mw...)
+	c.mut.Unlock()
+}
+
+// ChainBNCS composes mw into a single handler that ends in h
+func ChainBNCS(mw []BNCSMiddleware, h BNCSHandler) BNCSHandler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
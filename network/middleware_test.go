@@ -0,0 +1,62 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestW3GSMiddleware(t *testing.T) {
+	var c1, c2 = net.Pipe()
+
+	var src = network.NewW3GSConn(c1, nil, w3gs.Encoding{})
+	var dst = network.NewW3GSConn(c2, nil, w3gs.Encoding{})
+	defer src.Close()
+	defer dst.Close()
+
+	var sent int
+	src.UseEgress(func(next network.W3GSHandler) network.W3GSHandler {
+		return func(pkt w3gs.Packet) error {
+			sent++
+			if p, ok := pkt.(*w3gs.Ping); ok {
+				// Rewrite
+				return next(&w3gs.Ping{Payload: p.Payload + 1})
+			}
+			return next(pkt)
+		}
+	})
+
+	dst.UseIngress(func(next network.W3GSHandler) network.W3GSHandler {
+		return func(pkt w3gs.Packet) error {
+			if _, ok := pkt.(*w3gs.Pong); ok {
+				// Drop
+				return nil
+			}
+			return next(pkt)
+		}
+	})
+
+	go func() {
+		src.Send(&w3gs.Pong{Ping: w3gs.Ping{Payload: 1}})
+		src.Send(&w3gs.Ping{Payload: 41})
+	}()
+
+	pkt, err := dst.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, ok := pkt.(*w3gs.Ping); !ok || p.Payload != 42 {
+		t.Fatalf("Expected rewritten Ping, got %+v", pkt)
+	}
+	if sent != 2 {
+		t.Fatal("Expected 2 packets through egress chain, got", sent)
+	}
+}
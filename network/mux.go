@@ -0,0 +1,338 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// UDPEndpointBuffer is the number of datagrams buffered per endpoint, excess datagrams are dropped
+const UDPEndpointBuffer = 64
+
+// UDPFilter selects the datagrams that are routed to an endpoint
+type UDPFilter struct {
+	Types  []uint8                  // W3GS packet type identifiers (nil for all datagrams)
+	Source func(addr net.Addr) bool // Source address filter (optional)
+}
+
+func (f *UDPFilter) match(pid int, addr net.Addr) bool {
+	if f.Types != nil {
+		var ok = false
+		for _, t := range f.Types {
+			if int(t) == pid {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return f.Source == nil || f.Source(addr)
+}
+
+// UDPMux shares a single UDP socket between multiple consumers (i.e. LAN advertiser and game list)
+// Inbound datagrams are routed to every endpoint with a matching filter, outbound datagrams are sent directly
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type UDPMux struct {
+	conn   net.PacketConn
+	family IPFamily
+
+	emut sync.Mutex
+	eps  map[*UDPEndpoint]struct{}
+	err  error
+
+	snap []*UDPEndpoint
+	buf  [2048]byte
+}
+
+// NewUDPMux opens a UDP socket on port for f (FamilyDefault is treated as FamilyIPv4)
+func NewUDPMux(f IPFamily, port int) (*UDPMux, error) {
+	conn, err := ListenUDP(f, port)
+	if err != nil {
+		return nil, err
+	}
+	return NewUDPMuxConn(conn, f), nil
+}
+
+// NewUDPMuxConn returns conn (bound to IP family f) wrapped in UDPMux
+func NewUDPMuxConn(conn net.PacketConn, f IPFamily) *UDPMux {
+	return &UDPMux{
+		conn:   conn,
+		family: f.Or(FamilyIPv4),
+		eps:    make(map[*UDPEndpoint]struct{}),
+	}
+}
+
+// Family of the underlying socket
+func (m *UDPMux) Family() IPFamily {
+	return m.family
+}
+
+// LocalAddr of the underlying socket
+func (m *UDPMux) LocalAddr() net.Addr {
+	return m.conn.LocalAddr()
+}
+
+// Endpoint registers a new consumer that receives all datagrams matching filter
+func (m *UDPMux) Endpoint(filter UDPFilter) *UDPEndpoint {
+	var e = UDPEndpoint{
+		mux:    m,
+		filter: filter,
+		recv:   make(chan udpDatagram, UDPEndpointBuffer),
+		done:   make(chan struct{}),
+		rdl:    makeDeadline(),
+	}
+
+	m.emut.Lock()
+	if m.err != nil {
+		e.shutdown(m.err)
+	} else {
+		m.eps[&e] = struct{}{}
+	}
+	m.emut.Unlock()
+
+	return &e
+}
+
+func (m *UDPMux) route(b []byte, addr net.Addr) {
+	var pid = -1
+	if len(b) >= 2 && b[0] == w3gs.ProtocolSig {
+		pid = int(b[1])
+	}
+
+	// Snapshot endpoints, filters are called without holding emut
+	m.emut.Lock()
+	m.snap = m.snap[:0]
+	for e := range m.eps {
+		m.snap = append(m.snap, e)
+	}
+	m.emut.Unlock()
+
+	for _, e := range m.snap {
+		if !e.filter.match(pid, addr) {
+			continue
+		}
+		select {
+		case e.recv <- udpDatagram{data: append([]byte(nil), b...), addr: addr}:
+		default:
+			// Buffer full, drop datagram
+		}
+	}
+}
+
+// Detach all endpoints, their reads return err from now on
+func (m *UDPMux) shutdown(err error) {
+	m.emut.Lock()
+	if m.err == nil {
+		m.err = err
+	}
+	for e := range m.eps {
+		e.shutdown(err)
+		delete(m.eps, e)
+	}
+	m.emut.Unlock()
+}
+
+// Close the underlying socket and all endpoints
+func (m *UDPMux) Close() error {
+	m.shutdown(io.EOF)
+	return m.conn.Close()
+}
+
+// Run reads datagrams from the socket and routes them to the registered endpoints
+// Not safe for concurrent invocation
+func (m *UDPMux) Run() error {
+	return m.RunContext(context.Background())
+}
+
+// RunContext reads datagrams from the socket until ctx is done and routes them to the registered endpoints
+// All endpoints are closed with the returned error, reads from (new) endpoints fail after RunContext returns
+// Not safe for concurrent invocation
+func (m *UDPMux) RunContext(ctx context.Context) error {
+	var err = m.run(ctx)
+	m.shutdown(err)
+	return err
+}
+
+func (m *UDPMux) run(ctx context.Context) error {
+	for {
+		if err := setReadDeadline(ctx, m.conn, NoTimeout); err != nil {
+			return err
+		}
+
+		var stop = WatchContext(ctx, m.conn)
+		size, addr, err := m.conn.ReadFrom(m.buf[:])
+		stop()
+
+		if err != nil {
+			return ContextError(ctx, err)
+		}
+
+		m.route(m.buf[:size], addr)
+	}
+}
+
+type udpDatagram struct {
+	data []byte
+	addr net.Addr
+}
+
+// UDPEndpoint is a net.PacketConn that receives datagrams routed by UDPMux
+// Writes go directly to the shared socket, closing an endpoint does not close the socket
+type UDPEndpoint struct {
+	mux    *UDPMux
+	filter UDPFilter
+	recv   chan udpDatagram
+	done   chan struct{}
+	once   sync.Once
+	err    error
+	rdl    deadline
+}
+
+// Close endpoint, reads return err from now on
+func (e *UDPEndpoint) shutdown(err error) {
+	e.once.Do(func() {
+		e.err = err
+		close(e.done)
+	})
+}
+
+// ReadFrom implements net.PacketConn
+func (e *UDPEndpoint) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case <-e.done:
+		return 0, nil, e.err
+	case <-e.rdl.wait():
+		return 0, nil, errDeadline
+	default:
+	}
+
+	select {
+	case d := <-e.recv:
+		return copy(b, d.data), d.addr, nil
+	case <-e.done:
+		return 0, nil, e.err
+	case <-e.rdl.wait():
+		return 0, nil, errDeadline
+	}
+}
+
+// WriteTo implements net.PacketConn
+func (e *UDPEndpoint) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-e.done:
+		return 0, io.EOF
+	default:
+	}
+	return e.mux.conn.WriteTo(b, addr)
+}
+
+// Close detaches the endpoint from UDPMux
+func (e *UDPEndpoint) Close() error {
+	e.mux.emut.Lock()
+	delete(e.mux.eps, e)
+	e.mux.emut.Unlock()
+
+	e.shutdown(io.EOF)
+	return nil
+}
+
+// LocalAddr implements net.PacketConn
+func (e *UDPEndpoint) LocalAddr() net.Addr {
+	return e.mux.conn.LocalAddr()
+}
+
+// SetDeadline implements net.PacketConn
+func (e *UDPEndpoint) SetDeadline(t time.Time) error {
+	e.rdl.set(t)
+	return e.mux.conn.SetWriteDeadline(t)
+}
+
+// SetReadDeadline implements net.PacketConn
+func (e *UDPEndpoint) SetReadDeadline(t time.Time) error {
+	e.rdl.set(t)
+	return nil
+}
+
+// SetWriteDeadline implements net.PacketConn
+// The write deadline is shared by all endpoints
+func (e *UDPEndpoint) SetWriteDeadline(t time.Time) error {
+	return e.mux.conn.SetWriteDeadline(t)
+}
+
+type deadlineError struct{}
+
+func (deadlineError) Error() string   { return "i/o timeout" }
+func (deadlineError) Timeout() bool   { return true }
+func (deadlineError) Temporary() bool { return true }
+func (deadlineError) Is(target error) bool {
+	return target == protocol.KindTimeout
+}
+
+var errDeadline error = deadlineError{}
+
+// deadline closes its channel once the deadline is exceeded
+type deadline struct {
+	mut    sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+func makeDeadline() deadline {
+	return deadline{cancel: make(chan struct{})}
+}
+
+func (d *deadline) set(t time.Time) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		// Wait for timer callback to close cancel
+		<-d.cancel
+	}
+	d.timer = nil
+
+	var closed = false
+	select {
+	case <-d.cancel:
+		closed = true
+	default:
+	}
+
+	if t.IsZero() {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+
+	if dur := time.Until(t); dur > 0 {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		var cancel = d.cancel
+		d.timer = time.AfterFunc(dur, func() { close(cancel) })
+		return
+	}
+
+	if !closed {
+		close(d.cancel)
+	}
+}
+
+func (d *deadline) wait() chan struct{} {
+	d.mut.Lock()
+	var c = d.cancel
+	d.mut.Unlock()
+	return c
+}
@@ -0,0 +1,133 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestUDPMux(t *testing.T) {
+	m, err := network.NewUDPMux(network.FamilyIPv4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	var search = network.NewW3GSPacketConn(m.Endpoint(network.UDPFilter{Types: []uint8{w3gs.PidSearchGame}}), nil, w3gs.Encoding{})
+	var info = network.NewW3GSPacketConn(m.Endpoint(network.UDPFilter{Types: []uint8{w3gs.PidGameInfo, w3gs.PidDecreateGame}}), nil, w3gs.Encoding{})
+
+	go m.Run()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var sender = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{})
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: m.LocalAddr().(*net.UDPAddr).Port}
+
+	if _, err := sender.Send(dst, &w3gs.DecreateGame{HostCounter: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sender.Send(dst, &w3gs.SearchGame{HostCounter: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	pkt, addr, err := search.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sg, ok := pkt.(*w3gs.SearchGame); !ok || sg.HostCounter != 2 {
+		t.Fatal("Unexpected packet", pkt)
+	}
+
+	if _, err := search.Send(addr, &w3gs.GameInfo{HostCounter: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if pkt, _, err := sender.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	} else if _, ok := pkt.(*w3gs.GameInfo); !ok {
+		t.Fatal("Unexpected reply", pkt)
+	}
+
+	if pkt, _, err := info.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	} else if _, ok := pkt.(*w3gs.DecreateGame); !ok {
+		t.Fatal("Unexpected packet", pkt)
+	}
+
+	if _, _, err := info.NextPacket(10 * time.Millisecond); !network.IsTimeout(err) {
+		t.Fatal("Expected timeout, got", err)
+	}
+
+	search.Close()
+	if _, _, err := search.NextPacket(time.Second); err != io.EOF {
+		t.Fatal("Expected EOF, got", err)
+	}
+	if _, err := sender.Send(dst, &w3gs.SearchGame{HostCounter: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Close()
+	if _, _, err := info.NextPacket(time.Second); err != io.EOF {
+		t.Fatal("Expected EOF after closing mux, got", err)
+	}
+}
+
+func TestUDPMuxRunExit(t *testing.T) {
+	m, err := network.NewUDPMux(network.FamilyIPv4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	var dst = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: m.LocalAddr().(*net.UDPAddr).Port}
+
+	// Source filter is called without holding the endpoint lock, so it may use the mux
+	var filtered = make(chan struct{}, 1)
+	var ep = network.NewW3GSPacketConn(m.Endpoint(network.UDPFilter{Source: func(addr net.Addr) bool {
+		m.Endpoint(network.UDPFilter{}).Close()
+		filtered <- struct{}{}
+		return true
+	}}), nil, w3gs.Encoding{})
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan error)
+	go func() { done <- m.RunContext(ctx) }()
+
+	if _, err := ep.Send(dst, &w3gs.SearchGame{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-filtered:
+	case <-time.After(time.Second):
+		t.Fatal("Source filter not called")
+	}
+	if _, _, err := ep.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("Expected context.Canceled, got", err)
+	}
+
+	// Endpoints are closed with the error of RunContext
+	if _, _, err := ep.NextPacket(time.Second); err != context.Canceled {
+		t.Fatal("Expected context.Canceled from endpoint, got", err)
+	}
+	var late = network.NewW3GSPacketConn(m.Endpoint(network.UDPFilter{}), nil, w3gs.Encoding{})
+	if _, _, err := late.NextPacket(time.Second); err != context.Canceled {
+		t.Fatal("Expected context.Canceled from new endpoint, got", err)
+	}
+}
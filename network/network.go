@@ -6,6 +6,7 @@
 package network
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -47,8 +48,10 @@ type W3GSPacketConn struct {
 	conn net.PacketConn
 	wto  time.Duration
 
-	smut sync.Mutex
-	enc  w3gs.Encoder
+	smut  sync.Mutex
+	enc   w3gs.Encoder
+	log   Logger
+	bcast []net.Addr
 
 	dec w3gs.Decoder
 	buf [2048]byte
@@ -89,6 +92,20 @@ func (c *W3GSPacketConn) SetWriteTimeout(wto time.Duration) {
 	c.smut.Unlock()
 }
 
+// SetLogger for packet tracing, nil disables logging
+func (c *W3GSPacketConn) SetLogger(l Logger) {
+	c.smut.Lock()
+	c.log = l
+	c.smut.Unlock()
+}
+
+func (c *W3GSPacketConn) logger() Logger {
+	c.smut.Lock()
+	var l = c.log
+	c.smut.Unlock()
+	return l
+}
+
 // Close the connection
 func (c *W3GSPacketConn) Close() error {
 	c.cmut.RLock()
@@ -122,20 +139,54 @@ func (c *W3GSPacketConn) Send(addr net.Addr, pkt w3gs.Packet) (int, error) {
 	if err == nil {
 		n, err = c.conn.WriteTo(raw, addr)
 	}
+	if err == nil {
+		logPacket(c.log, "Sent packet", pkt, addr)
+	}
 	c.smut.Unlock()
 	c.cmut.RUnlock()
 
 	return n, err
 }
 
+// SetBroadcastAddrs for Broadcast() calls (defaults to W3GSBroadcastAddr)
+func (c *W3GSPacketConn) SetBroadcastAddrs(addrs ...net.Addr) {
+	c.smut.Lock()
+	c.bcast = addrs
+	c.smut.Unlock()
+}
+
 // Broadcast a packet over LAN
 func (c *W3GSPacketConn) Broadcast(pkt w3gs.Packet) (int, error) {
-	return c.Send(&W3GSBroadcastAddr, pkt)
+	c.smut.Lock()
+	var addrs = c.bcast
+	c.smut.Unlock()
+
+	if len(addrs) == 0 {
+		return c.Send(&W3GSBroadcastAddr, pkt)
+	}
+
+	var n = 0
+	var err error
+	for _, addr := range addrs {
+		s, e := c.Send(addr, pkt)
+		n += s
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return n, err
 }
 
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
 // Not safe for concurrent invocation
 func (c *W3GSPacketConn) NextPacket(timeout time.Duration) (w3gs.Packet, net.Addr, error) {
+	return c.NextPacketContext(context.Background(), timeout)
+}
+
+// NextPacketContext waits for the next packet (with given timeout) until ctx is done and returns its deserialized representation
+// Not safe for concurrent invocation
+func (c *W3GSPacketConn) NextPacketContext(ctx context.Context, timeout time.Duration) (w3gs.Packet, net.Addr, error) {
 	c.cmut.RLock()
 
 	if c.conn == nil {
@@ -143,17 +194,18 @@ func (c *W3GSPacketConn) NextPacket(timeout time.Duration) (w3gs.Packet, net.Add
 		return nil, nil, io.EOF
 	}
 
-	if timeout >= 0 {
-		if err := c.conn.SetReadDeadline(Deadline(timeout)); err != nil {
-			c.cmut.RUnlock()
-			return nil, nil, err
-		}
+	if err := setReadDeadline(ctx, c.conn, timeout); err != nil {
+		c.cmut.RUnlock()
+		return nil, nil, err
 	}
 
+	var stop = WatchContext(ctx, c.conn)
 	size, addr, err := c.conn.ReadFrom(c.buf[:])
+	stop()
+
 	if err != nil {
 		c.cmut.RUnlock()
-		return nil, nil, err
+		return nil, nil, ContextError(ctx, err)
 	}
 
 	pkt, _, err := c.dec.Deserialize(c.buf[:size])
@@ -169,15 +221,24 @@ func (c *W3GSPacketConn) NextPacket(timeout time.Duration) (w3gs.Packet, net.Add
 // Run reads packets (with given max time between packets) from Conn and emits an event for each received packet
 // Not safe for concurrent invocation
 func (c *W3GSPacketConn) Run(f Emitter, timeout time.Duration) error {
+	return c.RunContext(context.Background(), f, timeout)
+}
+
+// RunContext reads packets (with given max time between packets) from Conn until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (c *W3GSPacketConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var log = c.logger()
+
 	c.cmut.RLock()
 	f.Fire(RunStart{})
 	for {
-		pkt, addr, err := c.NextPacket(timeout)
+		pkt, addr, err := c.NextPacketContext(ctx, timeout)
 
 		if err != nil {
 			switch err {
 			// Connection is still valid after these errors, only deserialization failed
 			case w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst:
+				logError(log, "Invalid packet", err, nil)
 				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
 				continue
 			default:
@@ -187,6 +248,7 @@ func (c *W3GSPacketConn) Run(f Emitter, timeout time.Duration) error {
 			}
 		}
 
+		logPacket(log, "Received packet", pkt, addr)
 		f.Fire(pkt, addr)
 	}
 }
@@ -198,9 +260,14 @@ type W3GSConn struct {
 	conn net.Conn
 	wto  time.Duration
 
-	smut sync.Mutex
-	enc  w3gs.Encoder
-	dec  w3gs.Decoder
+	smut  sync.Mutex
+	enc   w3gs.Encoder
+	dec   w3gs.Decoder
+	log   Logger
+	trace *TraceWriter
+
+	phaseTimeouts
+	w3gsChain
 }
 
 // NewW3GSConn returns conn wrapped in W3GSConn
@@ -238,6 +305,35 @@ func (c *W3GSConn) SetWriteTimeout(wto time.Duration) {
 	c.smut.Unlock()
 }
 
+// SetLogger for packet tracing, nil disables logging
+func (c *W3GSConn) SetLogger(l Logger) {
+	c.smut.Lock()
+	c.log = l
+	c.smut.Unlock()
+}
+
+func (c *W3GSConn) logger() Logger {
+	c.smut.Lock()
+	var l = c.log
+	c.smut.Unlock()
+	return l
+}
+
+// SetTrace records the raw bytes of all packets sent and received to t, nil disables tracing
+func (c *W3GSConn) SetTrace(t *TraceWriter) {
+	c.smut.Lock()
+	c.trace = t
+	c.smut.Unlock()
+}
+
+// Trace returns the TraceWriter set by SetTrace
+func (c *W3GSConn) Trace() *TraceWriter {
+	c.smut.Lock()
+	var t = c.trace
+	c.smut.Unlock()
+	return t
+}
+
 // Close the connection
 func (c *W3GSConn) Close() error {
 	c.cmut.RLock()
@@ -262,8 +358,9 @@ func (c *W3GSConn) Write(b []byte) (int, error) {
 	}
 
 	c.smut.Lock()
-	if c.wto >= 0 {
-		if err := c.conn.SetWriteDeadline(Deadline(c.wto)); err != nil {
+	var wto = c.writeTimeout(nil, c.wto)
+	if wto >= 0 {
+		if err := c.conn.SetWriteDeadline(Deadline(wto)); err != nil {
 			c.smut.Unlock()
 			c.cmut.RUnlock()
 			return 0, err
@@ -277,8 +374,24 @@ func (c *W3GSConn) Write(b []byte) (int, error) {
 	return n, err
 }
 
-// Send pkt to addr over net.Conn
+// Send pkt to addr over net.Conn, passes pkt through the egress middleware chain first
 func (c *W3GSConn) Send(pkt w3gs.Packet) (int, error) {
+	var mw = c.Egress()
+	if len(mw) == 0 {
+		return c.send(pkt)
+	}
+
+	var n = 0
+	var err = ChainW3GS(mw, func(p w3gs.Packet) error {
+		var s, err = c.send(p)
+		n += s
+		return err
+	})(pkt)
+
+	return n, err
+}
+
+func (c *W3GSConn) send(pkt w3gs.Packet) (int, error) {
 	c.cmut.RLock()
 
 	if c.conn == nil {
@@ -287,15 +400,26 @@ func (c *W3GSConn) Send(pkt w3gs.Packet) (int, error) {
 	}
 
 	c.smut.Lock()
-	if c.wto >= 0 {
-		if err := c.conn.SetWriteDeadline(Deadline(c.wto)); err != nil {
+	var wto = c.writeTimeout(pkt, c.wto)
+	if wto >= 0 {
+		if err := c.conn.SetWriteDeadline(Deadline(wto)); err != nil {
 			c.smut.Unlock()
 			c.cmut.RUnlock()
 			return 0, err
 		}
 	}
 
-	var n, err = c.enc.Write(c.conn, pkt)
+	var n = 0
+	var b, err = c.enc.Serialize(pkt)
+	if err == nil {
+		n, err = c.conn.Write(b)
+		if n > 0 {
+			c.trace.record(TraceEgress, b[:n])
+		}
+	}
+	if err == nil {
+		logPacket(c.log, "Sent packet", pkt, c.conn.RemoteAddr())
+	}
 	c.smut.Unlock()
 	c.cmut.RUnlock()
 
@@ -305,6 +429,42 @@ func (c *W3GSConn) Send(pkt w3gs.Packet) (int, error) {
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
 // Not safe for concurrent invocation
 func (c *W3GSConn) NextPacket(timeout time.Duration) (w3gs.Packet, error) {
+	return c.NextPacketContext(context.Background(), timeout)
+}
+
+// NextPacketContext waits for the next packet (with given timeout) until ctx is done and returns its deserialized representation
+// The connection is closed if a packet is only partially received once ctx is done or timeout expires
+// Packets are passed through the ingress middleware chain, packets dropped by middleware are skipped
+// Not safe for concurrent invocation
+func (c *W3GSConn) NextPacketContext(ctx context.Context, timeout time.Duration) (w3gs.Packet, error) {
+	var mw = c.Ingress()
+	if len(mw) == 0 {
+		return c.nextPacket(ctx, timeout)
+	}
+
+	var res w3gs.Packet
+	var h = ChainW3GS(mw, func(p w3gs.Packet) error {
+		res = p
+		return nil
+	})
+
+	for {
+		pkt, err := c.nextPacket(ctx, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		res = nil
+		if err := h(pkt); err != nil {
+			return nil, err
+		}
+		if res != nil {
+			return res, nil
+		}
+	}
+}
+
+func (c *W3GSConn) nextPacket(ctx context.Context, timeout time.Duration) (w3gs.Packet, error) {
 	c.cmut.RLock()
 
 	if c.conn == nil {
@@ -312,31 +472,64 @@ func (c *W3GSConn) NextPacket(timeout time.Duration) (w3gs.Packet, error) {
 		return nil, io.EOF
 	}
 
-	if timeout >= 0 {
-		if err := c.conn.SetReadDeadline(Deadline(timeout)); err != nil {
-			c.cmut.RUnlock()
-			return nil, err
+	if err := setReadDeadline(ctx, c.conn, c.readTimeout(timeout)); err != nil {
+		c.cmut.RUnlock()
+		return nil, err
+	}
+
+	var stop = WatchContext(ctx, c.conn)
+	b, n, err := c.dec.ReadRaw(c.conn)
+	stop()
+
+	if interrupted(n, err) {
+		// Stream is out of sync after a partial read
+		c.conn.Close()
+	}
+
+	var pkt w3gs.Packet
+	if err == nil {
+		c.smut.Lock()
+		c.trace.record(TraceIngress, b)
+		c.smut.Unlock()
+
+		var m int
+		pkt, m, err = c.dec.Deserialize(b)
+		if err == nil && m != n {
+			pkt, err = nil, w3gs.ErrInvalidPacketSize
 		}
 	}
 
-	pkt, _, err := c.dec.Read(c.conn)
 	c.cmut.RUnlock()
 
-	return pkt, err
+	return pkt, ContextError(ctx, err)
 }
 
 // Run reads packets (with given max time between packets) from Conn and fires an event through f for each received packet
 // Not safe for concurrent invocation
 func (c *W3GSConn) Run(f Emitter, timeout time.Duration) error {
+	return c.RunContext(context.Background(), f, timeout)
+}
+
+// RunContext reads packets (with given max time between packets) from Conn until ctx is done and fires an event through f for each received packet
+// Not safe for concurrent invocation
+func (c *W3GSConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var log = c.logger()
+
 	c.cmut.RLock()
+	var peer net.Addr
+	if c.conn != nil {
+		peer = c.conn.RemoteAddr()
+	}
+
 	f.Fire(RunStart{})
 	for {
-		pkt, err := c.NextPacket(timeout)
+		pkt, err := c.NextPacketContext(ctx, timeout)
 
 		if err != nil {
 			switch err {
 			// Connection is still valid after these errors, only deserialization failed
 			case w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst:
+				logError(log, "Invalid packet", err, peer)
 				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
 				continue
 			default:
@@ -346,6 +539,7 @@ func (c *W3GSConn) Run(f Emitter, timeout time.Duration) error {
 			}
 		}
 
+		logPacket(log, "Received packet", pkt, peer)
 		f.Fire(pkt)
 	}
 }
@@ -357,9 +551,14 @@ type BNCSConn struct {
 	conn net.Conn
 	wto  time.Duration
 
-	smut sync.Mutex
-	enc  bncs.Encoder
-	dec  bncs.Decoder
+	smut  sync.Mutex
+	enc   bncs.Encoder
+	dec   bncs.Decoder
+	log   Logger
+	trace *TraceWriter
+
+	phaseTimeouts
+	bncsChain
 
 	lmut sync.Mutex
 	lnxt time.Time
@@ -400,6 +599,35 @@ func (c *BNCSConn) SetWriteTimeout(wto time.Duration) {
 	c.smut.Unlock()
 }
 
+// SetLogger for packet tracing, nil disables logging
+func (c *BNCSConn) SetLogger(l Logger) {
+	c.smut.Lock()
+	c.log = l
+	c.smut.Unlock()
+}
+
+func (c *BNCSConn) logger() Logger {
+	c.smut.Lock()
+	var l = c.log
+	c.smut.Unlock()
+	return l
+}
+
+// SetTrace records the raw bytes of all packets sent and received to t, nil disables tracing
+func (c *BNCSConn) SetTrace(t *TraceWriter) {
+	c.smut.Lock()
+	c.trace = t
+	c.smut.Unlock()
+}
+
+// Trace returns the TraceWriter set by SetTrace
+func (c *BNCSConn) Trace() *TraceWriter {
+	c.smut.Lock()
+	var t = c.trace
+	c.smut.Unlock()
+	return t
+}
+
 // Close the connection
 func (c *BNCSConn) Close() error {
 	c.cmut.RLock()
@@ -424,8 +652,9 @@ func (c *BNCSConn) Write(b []byte) (int, error) {
 	}
 
 	c.smut.Lock()
-	if c.wto >= 0 {
-		if err := c.conn.SetWriteDeadline(Deadline(c.wto)); err != nil {
+	var wto = c.writeTimeout(nil, c.wto)
+	if wto >= 0 {
+		if err := c.conn.SetWriteDeadline(Deadline(wto)); err != nil {
 			c.smut.Unlock()
 			c.cmut.RUnlock()
 			return 0, err
@@ -439,8 +668,24 @@ func (c *BNCSConn) Write(b []byte) (int, error) {
 	return n, err
 }
 
-// Send pkt to addr over net.Conn
+// Send pkt to addr over net.Conn, passes pkt through the egress middleware chain first
 func (c *BNCSConn) Send(pkt bncs.Packet) (int, error) {
+	var mw = c.Egress()
+	if len(mw) == 0 {
+		return c.send(pkt)
+	}
+
+	var n = 0
+	var err = ChainBNCS(mw, func(p bncs.Packet) error {
+		var s, err = c.send(p)
+		n += s
+		return err
+	})(pkt)
+
+	return n, err
+}
+
+func (c *BNCSConn) send(pkt bncs.Packet) (int, error) {
 	c.cmut.RLock()
 
 	if c.conn == nil {
@@ -449,15 +694,26 @@ func (c *BNCSConn) Send(pkt bncs.Packet) (int, error) {
 	}
 
 	c.smut.Lock()
-	if c.wto >= 0 {
-		if err := c.conn.SetWriteDeadline(Deadline(c.wto)); err != nil {
+	var wto = c.writeTimeout(pkt, c.wto)
+	if wto >= 0 {
+		if err := c.conn.SetWriteDeadline(Deadline(wto)); err != nil {
 			c.smut.Unlock()
 			c.cmut.RUnlock()
 			return 0, err
 		}
 	}
 
-	var n, err = c.enc.Write(c.conn, pkt)
+	var n = 0
+	var b, err = c.enc.Serialize(pkt)
+	if err == nil {
+		n, err = c.conn.Write(b)
+		if n > 0 {
+			c.trace.record(TraceEgress, b[:n])
+		}
+	}
+	if err == nil {
+		logPacket(c.log, "Sent packet", pkt, c.conn.RemoteAddr())
+	}
 	c.smut.Unlock()
 	c.cmut.RUnlock()
 
@@ -466,11 +722,23 @@ func (c *BNCSConn) Send(pkt bncs.Packet) (int, error) {
 
 // SendRL pkt to addr over net.Conn with rate limit
 func (c *BNCSConn) SendRL(pkt bncs.Packet) (int, error) {
+	return c.SendRLContext(context.Background(), pkt)
+}
+
+// SendRLContext pkt to addr over net.Conn with rate limit, gives up if ctx is done before pkt is sent
+func (c *BNCSConn) SendRLContext(ctx context.Context, pkt bncs.Packet) (int, error) {
 	c.lmut.Lock()
 
 	var t = time.Now()
 	if t.Before(c.lnxt) {
-		time.Sleep(c.lnxt.Sub(t))
+		var timer = time.NewTimer(c.lnxt.Sub(t))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.lmut.Unlock()
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
 	}
 
 	var n, err = c.Send(pkt)
@@ -491,38 +759,107 @@ func (c *BNCSConn) SendRL(pkt bncs.Packet) (int, error) {
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
 // Not safe for concurrent invocation
 func (c *BNCSConn) NextPacket(timeout time.Duration) (bncs.Packet, error) {
+	return c.NextPacketContext(context.Background(), timeout)
+}
+
+// NextPacketContext waits for the next packet (with given timeout) until ctx is done and returns its deserialized representation
+// The connection is closed if a packet is only partially received once ctx is done or timeout expires
+// Packets are passed through the ingress middleware chain, packets dropped by middleware are skipped
+// Not safe for concurrent invocation
+func (c *BNCSConn) NextPacketContext(ctx context.Context, timeout time.Duration) (bncs.Packet, error) {
+	var mw = c.Ingress()
+	if len(mw) == 0 {
+		return c.nextPacket(ctx, timeout)
+	}
+
+	var res bncs.Packet
+	var h = ChainBNCS(mw, func(p bncs.Packet) error {
+		res = p
+		return nil
+	})
+
+	for {
+		pkt, err := c.nextPacket(ctx, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		res = nil
+		if err := h(pkt); err != nil {
+			return nil, err
+		}
+		if res != nil {
+			return res, nil
+		}
+	}
+}
+
+func (c *BNCSConn) nextPacket(ctx context.Context, timeout time.Duration) (bncs.Packet, error) {
 	c.cmut.RLock()
 	if c.conn == nil {
 		c.cmut.RUnlock()
 		return nil, io.EOF
 	}
 
-	if timeout >= 0 {
-		if err := c.conn.SetReadDeadline(Deadline(timeout)); err != nil {
-			c.cmut.RUnlock()
-			return nil, err
+	if err := setReadDeadline(ctx, c.conn, c.readTimeout(timeout)); err != nil {
+		c.cmut.RUnlock()
+		return nil, err
+	}
+
+	var stop = WatchContext(ctx, c.conn)
+	b, n, err := c.dec.ReadRaw(c.conn)
+	stop()
+
+	if interrupted(n, err) {
+		// Stream is out of sync after a partial read
+		c.conn.Close()
+	}
+
+	var pkt bncs.Packet
+	if err == nil {
+		c.smut.Lock()
+		c.trace.record(TraceIngress, b)
+		c.smut.Unlock()
+
+		var m int
+		pkt, m, err = c.dec.Deserialize(b)
+		if err == nil && m != n {
+			pkt, err = nil, bncs.ErrInvalidPacketSize
 		}
 	}
 
-	pkt, _, err := c.dec.Read(c.conn)
 	c.cmut.RUnlock()
 
-	return pkt, err
+	return pkt, ContextError(ctx, err)
 }
 
 // Run reads packets (with given max time between packets) from Conn and emits an event for each received packet
 // Not safe for concurrent invocation
 func (c *BNCSConn) Run(f Emitter, timeout time.Duration) error {
+	return c.RunContext(context.Background(), f, timeout)
+}
+
+// RunContext reads packets (with given max time between packets) from Conn until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (c *BNCSConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var log = c.logger()
+
 	c.cmut.RLock()
+	var peer net.Addr
+	if c.conn != nil {
+		peer = c.conn.RemoteAddr()
+	}
+
 	f.Fire(RunStart{})
 	for {
-		pkt, err := c.NextPacket(timeout)
+		pkt, err := c.NextPacketContext(ctx, timeout)
 
 		if err != nil {
 			switch err {
 			// Connection is still valid after these errors, only deserialization failed
 			case bncs.ErrInvalidPacketSize, bncs.ErrInvalidChecksum, bncs.ErrUnexpectedConst,
 				w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst:
+				logError(log, "Invalid packet", err, peer)
 				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
 				continue
 			default:
@@ -532,6 +869,7 @@ func (c *BNCSConn) Run(f Emitter, timeout time.Duration) error {
 			}
 		}
 
+		logPacket(log, "Received packet", pkt, peer)
 		f.Fire(pkt)
 	}
 }
@@ -552,6 +890,7 @@ type CAPIConn struct {
 
 	cmut RWMutex
 	smut sync.Mutex
+	log  Logger
 }
 
 // NewCAPIConn returns conn wrapped in CAPIConn
@@ -585,6 +924,20 @@ func (c *CAPIConn) SetWriteTimeout(wto time.Duration) {
 	c.smut.Unlock()
 }
 
+// SetLogger for packet tracing, nil disables logging
+func (c *CAPIConn) SetLogger(l Logger) {
+	c.smut.Lock()
+	c.log = l
+	c.smut.Unlock()
+}
+
+func (c *CAPIConn) logger() Logger {
+	c.smut.Lock()
+	var l = c.log
+	c.smut.Unlock()
+	return l
+}
+
 // Close the connection
 func (c *CAPIConn) Close() error {
 	c.cmut.RLock()
@@ -621,6 +974,9 @@ func (c *CAPIConn) Send(pkt *capi.Packet) error {
 	if err == nil {
 		err = capi.Write(w, pkt)
 	}
+	if err == nil {
+		logPacket(c.log, "Sent packet", pkt.Payload, c.conn.RemoteAddr())
+	}
 
 	w.Close()
 	c.smut.Unlock()
@@ -632,6 +988,12 @@ func (c *CAPIConn) Send(pkt *capi.Packet) error {
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
 // Not safe for concurrent invocation
 func (c *CAPIConn) NextPacket(timeout time.Duration) (*capi.Packet, error) {
+	return c.NextPacketContext(context.Background(), timeout)
+}
+
+// NextPacketContext waits for the next packet (with given timeout) until ctx is done and returns its deserialized representation
+// Not safe for concurrent invocation
+func (c *CAPIConn) NextPacketContext(ctx context.Context, timeout time.Duration) (*capi.Packet, error) {
 	c.cmut.RLock()
 
 	if c.conn == nil {
@@ -639,13 +1001,13 @@ func (c *CAPIConn) NextPacket(timeout time.Duration) (*capi.Packet, error) {
 		return nil, io.EOF
 	}
 
-	if timeout >= 0 {
-		if err := c.conn.SetReadDeadline(Deadline(timeout)); err != nil {
-			c.cmut.RUnlock()
-			return nil, err
-		}
+	if err := setReadDeadline(ctx, c.conn, timeout); err != nil {
+		c.cmut.RUnlock()
+		return nil, err
 	}
 
+	// Note that websocket.Conn can not recover from an interrupted read
+	var stop = WatchContext(ctx, c.conn)
 	_, r, err := c.conn.NextReader()
 
 	var pkt *capi.Packet
@@ -654,23 +1016,38 @@ func (c *CAPIConn) NextPacket(timeout time.Duration) (*capi.Packet, error) {
 		io.Copy(ioutil.Discard, r)
 	}
 
+	stop()
 	c.cmut.RUnlock()
 
-	return pkt, err
+	return pkt, ContextError(ctx, err)
 }
 
 // Run reads packets (with given max time between packets) from Conn and fires an event through f for each received packet
 // Not safe for concurrent invocation
 func (c *CAPIConn) Run(f Emitter, timeout time.Duration) error {
+	return c.RunContext(context.Background(), f, timeout)
+}
+
+// RunContext reads packets (with given max time between packets) from Conn until ctx is done and fires an event through f for each received packet
+// Not safe for concurrent invocation
+func (c *CAPIConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var log = c.logger()
+
 	c.cmut.RLock()
+	var peer net.Addr
+	if c.conn != nil {
+		peer = c.conn.RemoteAddr()
+	}
+
 	f.Fire(RunStart{})
 	for {
-		pkt, err := c.NextPacket(timeout)
+		pkt, err := c.NextPacketContext(ctx, timeout)
 
 		if err != nil {
 			switch err.(type) {
 			// Connection is still valid after these errors, only deserialization failed
 			case *json.SyntaxError, *json.UnmarshalTypeError:
+				logError(log, "Invalid packet", err, peer)
 				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
 				continue
 			default:
@@ -680,6 +1057,7 @@ func (c *CAPIConn) Run(f Emitter, timeout time.Duration) error {
 			}
 		}
 
+		logPacket(log, "Received packet", pkt.Payload, peer)
 		f.Fire(pkt)
 		f.Fire(pkt.Payload, pkt)
 	}
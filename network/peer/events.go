@@ -29,3 +29,13 @@ type Chat struct {
 	Event
 	Content string
 }
+
+// Degraded event, fired when the connection quality of a peer drops below the threshold set in Host
+type Degraded struct {
+	Event
+	RTT  uint32
+	Loss uint32
+}
+
+// Recovered event, fired when the connection quality of a degraded peer is restored
+type Recovered Event
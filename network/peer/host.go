@@ -6,6 +6,7 @@ package peer
 
 import (
 	"net"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +16,9 @@ import (
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
+// LossWindow is the number of pings over which packet loss is measured
+const LossWindow = 10
+
 // Host manages (incoming/outgoing) peer connections in a game lobby
 // Public methods/fields are thread-safe unless explicitly stated otherwise
 type Host struct {
@@ -35,6 +39,12 @@ type Host struct {
 	PlayerInfo   w3gs.PlayerInfo
 	EntryKey     uint32
 	PingInterval time.Duration
+	Family       network.IPFamily // IP family for listening (IPv4 by default) and dialing (dual-stack by default)
+	Logger       network.Logger
+
+	KeepAliveTimeout time.Duration // Max time without received packets before a connection is closed (0 to disable)
+	DegradeRTT       time.Duration // Max RTT before a peer is marked as degraded (0 to disable)
+	DegradeLoss      uint32        // Max percentage of unanswered pings before a peer is marked as degraded (0 to disable)
 }
 
 // GameTicks state sent to peers
@@ -56,6 +66,20 @@ func (h *Host) PeerSet() protocol.BitSet32 {
 	return peerset
 }
 
+// HealthySet of connected peers that are not degraded
+func (h *Host) HealthySet() protocol.BitSet32 {
+	h.pmut.Lock()
+	var res = h.peerset
+	for id, p := range h.peers {
+		if p.Degraded() {
+			res.Clear(uint(id))
+		}
+	}
+	h.pmut.Unlock()
+
+	return res
+}
+
 // Peer returns registered Player for playerID
 func (h *Host) Peer(playerID uint8) *Player {
 	h.pmut.Lock()
@@ -65,6 +89,19 @@ func (h *Host) Peer(playerID uint8) *Player {
 	return peer
 }
 
+// PeerIDs of registered players
+func (h *Host) PeerIDs() []uint8 {
+	h.pmut.Lock()
+	var res = make([]uint8, 0, len(h.peers))
+	for id := range h.peers {
+		res = append(res, id)
+	}
+	h.pmut.Unlock()
+
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
 // ListenAndServe opens a new TCP listener on InternalAddr and serves incoming peer connections
 // On success, listening address overrides InternalAddr/ExternalAddr
 // Not safe for concurrent invocation
@@ -73,13 +110,13 @@ func (h *Host) ListenAndServe() error {
 		h.listener.Close()
 	}
 
-	var l, err = net.ListenTCP("tcp4", h.PlayerInfo.InternalAddr.TCPAddr())
+	var l, err = network.ListenTCP(h.Family, h.PlayerInfo.InternalAddr.TCPAddr())
 	if err != nil {
 		return err
 	}
 
 	h.listener = l
-	h.PlayerInfo.InternalAddr = protocol.Addr(l.Addr())
+	h.PlayerInfo.InternalAddr = protocol.Addr4(l.Addr())
 	h.PlayerInfo.ExternalAddr = h.PlayerInfo.InternalAddr
 
 	h.wg.Add(1)
@@ -180,6 +217,10 @@ func (h *Host) Accept(conn net.Conn) (*Player, error) {
 		// This ensures RunStart is only called once and serve() is actually using conn
 		h.pmut.Unlock()
 
+		if h.Logger != nil {
+			h.Logger.Info("Peer connected", network.LogKeyUser, peer.PlayerInfo.PlayerName, network.LogKeyPeer, conn.RemoteAddr())
+		}
+
 		h.Fire(&Connected{
 			Event: Event{Peer: peer},
 			Dial:  false,
@@ -188,6 +229,7 @@ func (h *Host) Accept(conn net.Conn) (*Player, error) {
 		done <- struct{}{}
 	})
 
+	peer.SetLogger(h.Logger)
 	peer.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), h.Encoding)
 
 	h.wg.Add(1)
@@ -258,15 +300,26 @@ func (h *Host) Dial(playerID uint8) (*Player, error) {
 		return nil, ErrAlreadyConnected
 	}
 
+	h.start(peer, conn, pc.PeerSet, "Host.Dial[Serve]")
+	return peer, nil
+}
+
+// start serving outgoing connection conn to peer
+// pmut should be locked, unlocks once serve() is running
+func (h *Host) start(peer *Player, conn net.Conn, peerset protocol.BitSet32, src string) {
 	var done = make(chan struct{})
 	peer.Once(network.RunStart{}, func(ev *network.Event) {
-		atomic.StoreUint32(&peer.peerset, uint32(pc.PeerSet))
+		atomic.StoreUint32(&peer.peerset, uint32(peerset))
 		h.peerset.Set(uint(peer.PlayerInfo.PlayerID))
 
 		// Unlock only once serve() is running
 		// This ensures RunStart is only called once and serve() is actually using conn
 		h.pmut.Unlock()
 
+		if h.Logger != nil {
+			h.Logger.Info("Peer connected", network.LogKeyUser, peer.PlayerInfo.PlayerName, network.LogKeyPeer, conn.RemoteAddr())
+		}
+
 		h.Fire(&Connected{
 			Event: Event{Peer: peer},
 			Dial:  true,
@@ -277,12 +330,13 @@ func (h *Host) Dial(playerID uint8) (*Player, error) {
 		ev.PreventNext()
 	})
 
+	peer.SetLogger(h.Logger)
 	peer.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), h.Encoding)
 
 	h.wg.Add(1)
 	go func() {
 		if err := h.serve(peer); err != nil && !network.IsCloseError(err) {
-			peer.Fire(&network.AsyncError{Src: "Host.Dial[Serve]", Err: err})
+			peer.Fire(&network.AsyncError{Src: src, Err: err})
 		}
 
 		h.disconnectPlayer(conn, peer)
@@ -290,16 +344,19 @@ func (h *Host) Dial(playerID uint8) (*Player, error) {
 	}()
 
 	<-done
-
-	return peer, nil
 }
 
 // Say sends a chat message to peers, returns failed PIDs
+// Degraded peers are skipped and returned as failed, so that the message can be rerouted through the game host
 func (h *Host) Say(s string) []uint8 {
 	var fail = []uint8{}
 
 	h.pmut.Lock()
 	for _, p := range h.peers {
+		if p.Degraded() {
+			fail = append(fail, p.PlayerInfo.PlayerID)
+			continue
+		}
 		if _, err := p.Send(&w3gs.PeerMessage{Message: w3gs.Message{
 			RecipientIDs: []uint8{p.PlayerInfo.PlayerID},
 			SenderID:     h.PlayerInfo.PlayerID,
@@ -363,11 +420,19 @@ func (h *Host) disconnectPlayer(conn net.Conn, peer *Player) {
 		peer.W3GSConn.SetConn(nil, nil, h.Encoding)
 		atomic.StoreUint32(&peer.peerset, 0)
 		atomic.StoreUint32(&peer.rtt, 0)
+		atomic.StoreUint32(&peer.pings, 0)
+		atomic.StoreUint32(&peer.pongs, 0)
+		atomic.StoreUint32(&peer.loss, 0)
+		atomic.StoreUint32(&peer.degraded, 0)
 		h.peerset.Clear(uint(peer.PlayerInfo.PlayerID))
 	}
 	h.pmut.Unlock()
 
 	if dc {
+		if h.Logger != nil {
+			h.Logger.Info("Peer disconnected", network.LogKeyUser, peer.PlayerInfo.PlayerName, network.LogKeyPeer, conn.RemoteAddr())
+		}
+
 		h.Fire(&Disconnected{Peer: peer})
 	}
 }
@@ -385,12 +450,15 @@ func (h *Host) servePeerPing(peer *Player) func() {
 				ticker.Stop()
 				return
 			case c := <-ticker.C:
+				h.checkHealth(peer)
+
 				pkt.Payload = uint32(c.Sub(peer.StartTime).Milliseconds())
 				pkt.PeerSet = h.PeerSet()
 				pkt.GameTicks = h.GameTicks()
 				if _, err := peer.SendOrClose(&pkt); err != nil {
 					peer.Fire(&network.AsyncError{Src: "Host.serve[PeerPing]", Err: err})
 				}
+				atomic.AddUint32(&peer.pings, 1)
 			}
 		}
 	}()
@@ -400,11 +468,54 @@ func (h *Host) servePeerPing(peer *Player) func() {
 	}
 }
 
+func (h *Host) checkHealth(peer *Player) {
+	if pings := atomic.LoadUint32(&peer.pings); pings >= LossWindow {
+		var pongs = atomic.SwapUint32(&peer.pongs, 0)
+		atomic.StoreUint32(&peer.pings, 0)
+		if pongs > pings {
+			pongs = pings
+		}
+		atomic.StoreUint32(&peer.loss, 100-pongs*100/pings)
+	}
+
+	var rtt = peer.RTT()
+	var loss = peer.Loss()
+	var degraded = (h.DegradeRTT > 0 && time.Duration(rtt)*time.Millisecond > h.DegradeRTT) || (h.DegradeLoss > 0 && loss > h.DegradeLoss)
+
+	var val uint32
+	if degraded {
+		val = 1
+	}
+	if atomic.SwapUint32(&peer.degraded, val) == val {
+		return
+	}
+
+	if degraded {
+		if h.Logger != nil {
+			h.Logger.Warn("Peer degraded", network.LogKeyUser, peer.PlayerInfo.PlayerName)
+		}
+		h.Fire(&Degraded{Event: Event{Peer: peer}, RTT: rtt, Loss: loss})
+	} else {
+		h.Fire(&Recovered{Peer: peer})
+	}
+}
+
 func (h *Host) serve(peer *Player) error {
 	if h.PingInterval != 0 {
 		var stop = h.servePeerPing(peer)
 		defer stop()
 	}
+	if h.KeepAliveTimeout != 0 {
+		// Peers are pinged by servePeerPing(), only check for activity
+		var k = network.NewW3GSKeepAlive(&peer.W3GSConn, 0, h.KeepAliveTimeout)
+		k.Emitter = &peer.EventEmitter
+
+		var eid = k.Bind(&peer.EventEmitter)
+		defer peer.Off(eid)
+
+		var stop = k.Start()
+		defer stop()
+	}
 
 	return peer.Run()
 }
@@ -5,6 +5,7 @@
 package peer_test
 
 import (
+	"net"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -243,3 +244,137 @@ func TestMass(t *testing.T) {
 
 	closeAll(hosts)
 }
+
+func TestPunch(t *testing.T) {
+	udp, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r = peer.NewRendezvous(udp, 0)
+	r.On(&network.AsyncError{}, func(ev *network.Event) {
+		var err = ev.Arg.(*network.AsyncError)
+		t.Logf("[ERROR][RENDEZVOUS] %s\n", err.Error())
+	})
+
+	var done = make(chan struct{})
+	go func() {
+		r.Run()
+		done <- struct{}{}
+	}()
+
+	var hosts = makeHosts(t, 2)
+	registerHosts(t, hosts)
+	for _, h := range hosts {
+		r.Register(&h.PlayerInfo)
+	}
+
+	var chat int32
+	hosts[1].On(&peer.Chat{}, func(ev *network.Event) {
+		if ev.Arg.(*peer.Chat).Content == "Hello world!" {
+			atomic.AddInt32(&chat, 1)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := range hosts {
+		wg.Add(1)
+		go func(h *peer.Host, id uint8) {
+			if _, err := h.Punch(id, udp.LocalAddr()); err != nil {
+				t.Error(h.PlayerInfo.PlayerID, err)
+			}
+			wg.Done()
+		}(hosts[i], hosts[1-i].PlayerInfo.PlayerID)
+	}
+	wg.Wait()
+
+	if r.Endpoint(1) == nil || r.Endpoint(2) == nil {
+		t.Fatal("Expected endpoints to be registered")
+	}
+	if !hosts[0].PeerSet().Test(2) || !hosts[1].PeerSet().Test(1) {
+		t.Fatal("Expected hosts to be connected")
+	}
+	if _, err := hosts[0].Punch(2, udp.LocalAddr()); err != peer.ErrAlreadyConnected {
+		t.Fatal("Expected ErrAlreadyConnected")
+	}
+
+	if fail := hosts[0].Say("Hello world!"); len(fail) > 0 {
+		t.Fatal("Failed to send to ", fail)
+	}
+	for try := 0; try < 100 && atomic.LoadInt32(&chat) == 0; try++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&chat) != 1 {
+		t.Fatal("Expected chat over punched connection")
+	}
+
+	closeAll(hosts)
+	r.Close()
+	<-done
+}
+
+func TestHealth(t *testing.T) {
+	var hosts = makeHosts(t, 2)
+	hosts[0].DegradeLoss = 50
+	if err := hosts[0].ListenAndServe(); err != nil {
+		t.Fatal(err)
+	}
+	registerHosts(t, hosts)
+
+	// Drop pings from hosts[0]
+	var drop int32 = 1
+	hosts[1].Peer(1).OnPriority(&w3gs.PeerPing{}, 1, func(ev *network.Event) {
+		if atomic.LoadInt32(&drop) != 0 {
+			ev.PreventNext()
+		}
+	})
+
+	var degraded = make(chan *peer.Degraded, 1)
+	var recovered = make(chan struct{}, 1)
+	hosts[0].On(&peer.Degraded{}, func(ev *network.Event) {
+		select {
+		case degraded <- ev.Arg.(*peer.Degraded):
+		default:
+		}
+	})
+	hosts[0].On(&peer.Recovered{}, func(ev *network.Event) {
+		select {
+		case recovered <- struct{}{}:
+		default:
+		}
+	})
+
+	if _, err := hosts[1].Dial(1); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case d := <-degraded:
+		if d.Peer.PlayerInfo.PlayerID != 2 || d.Loss <= 50 || !d.Peer.Degraded() {
+			t.Fatal("Unexpected degraded peer", d.Peer.PlayerInfo.PlayerID, d.Loss)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Degraded event")
+	}
+
+	if !hosts[0].PeerSet().Test(2) || hosts[0].HealthySet().Test(2) {
+		t.Fatal("Expected peer to be connected but unhealthy")
+	}
+	if fail := hosts[0].Say("Hello world!"); len(fail) != 1 || fail[0] != 2 {
+		t.Fatal("Expected degraded peer to be skipped", fail)
+	}
+
+	atomic.StoreInt32(&drop, 0)
+
+	select {
+	case <-recovered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Recovered event")
+	}
+
+	if !hosts[0].HealthySet().Test(2) || hosts[0].Peer(2).Degraded() {
+		t.Fatal("Expected peer to be healthy")
+	}
+
+	closeAll(hosts)
+}
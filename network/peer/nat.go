@@ -0,0 +1,293 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package peer
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// PunchInterval is the time between UDP hole punching attempts
+const PunchInterval = 100 * time.Millisecond
+
+// Rendezvous assists players behind NAT in connecting to each other (UDP hole punching), typically run by the game host
+// Players register by sending PeerConnect (with their own JoinCounter and the peers they want to reach as PeerSet),
+// Rendezvous replies with the PlayerInfo of the other player (ExternalAddr set to its observed endpoint)
+// once both players of a pair are registered, after which they punch simultaneously (see Host.Punch)
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Rendezvous struct {
+	network.EventEmitter
+	network.W3GSPacketConn
+
+	mut     sync.Mutex
+	players map[uint8]*endpoint
+
+	// Set once before Run(), read-only after that
+	EntryKey uint32
+}
+
+type endpoint struct {
+	info w3gs.PlayerInfo
+	addr net.Addr
+	want protocol.BitSet32
+}
+
+// NewRendezvous initializes a new Rendezvous struct serving on conn
+func NewRendezvous(conn net.PacketConn, entryKey uint32) *Rendezvous {
+	var r = Rendezvous{
+		players:  make(map[uint8]*endpoint),
+		EntryKey: entryKey,
+	}
+
+	r.InitDefaultHandlers()
+	r.SetWriteTimeout(time.Second)
+	r.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), w3gs.Encoding{})
+
+	return &r
+}
+
+// Register player that is allowed to use the rendezvous
+func (r *Rendezvous) Register(info *w3gs.PlayerInfo) {
+	r.mut.Lock()
+	r.players[info.PlayerID] = &endpoint{info: *info}
+	r.mut.Unlock()
+}
+
+// Deregister player
+func (r *Rendezvous) Deregister(playerID uint8) {
+	r.mut.Lock()
+	delete(r.players, playerID)
+	r.mut.Unlock()
+}
+
+// Endpoint returns the observed external endpoint of player, nil if not registered yet
+func (r *Rendezvous) Endpoint(playerID uint8) net.Addr {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if p := r.players[playerID]; p != nil {
+		return p.addr
+	}
+	return nil
+}
+
+// Run reads packets and emits an event for each received packet
+// Not safe for concurrent invocation
+func (r *Rendezvous) Run() error {
+	return r.RunContext(context.Background())
+}
+
+// RunContext reads packets until ctx is done and emits an event for each received packet
+// Not safe for concurrent invocation
+func (r *Rendezvous) RunContext(ctx context.Context) error {
+	return r.W3GSPacketConn.RunContext(ctx, &r.EventEmitter, network.NoTimeout)
+}
+
+// InitDefaultHandlers adds the default callbacks for relevant packets
+func (r *Rendezvous) InitDefaultHandlers() {
+	r.On(&w3gs.PeerConnect{}, r.onPeerConnect)
+}
+
+func (r *Rendezvous) onPeerConnect(ev *network.Event) {
+	var pkt = ev.Arg.(*w3gs.PeerConnect)
+	var addr = ev.Opt[0].(net.Addr)
+
+	if pkt.EntryKey != r.EntryKey {
+		return
+	}
+
+	type reply struct {
+		addr net.Addr
+		info w3gs.PlayerInfo
+	}
+	var replies []reply
+
+	r.mut.Lock()
+	var p = r.players[pkt.PlayerID]
+	if p == nil || p.info.JoinCounter != pkt.JoinCounter {
+		r.mut.Unlock()
+		return
+	}
+
+	p.addr = addr
+	p.want = pkt.PeerSet
+
+	for id, q := range r.players {
+		if q == p || q.addr == nil || !p.want.Test(uint(id)) || !q.want.Test(uint(pkt.PlayerID)) {
+			continue
+		}
+
+		var pi = p.info
+		pi.ExternalAddr = protocol.Addr(p.addr)
+
+		var qi = q.info
+		qi.ExternalAddr = protocol.Addr(q.addr)
+
+		replies = append(replies, reply{addr: p.addr, info: qi}, reply{addr: q.addr, info: pi})
+	}
+	r.mut.Unlock()
+
+	for i := range replies {
+		if _, err := r.Send(replies[i].addr, &replies[i].info); err != nil {
+			r.Fire(&network.AsyncError{Src: "Rendezvous.onPeerConnect[Send]", Err: err})
+		}
+	}
+}
+
+// Punch opens a new connection to player through NAT with UDP hole punching, assisted by the Rendezvous at addr
+// Both players should punch at the same time, the connection is established once their packets pass each other's NAT
+// Packets are sent as datagrams over the punched connection, delivery is not guaranteed
+func (h *Host) Punch(playerID uint8, addr net.Addr) (*Player, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return h.PunchContext(ctx, playerID, addr)
+}
+
+// PunchContext opens a new connection to player through NAT with UDP hole punching, aborts once ctx is done
+func (h *Host) PunchContext(ctx context.Context, playerID uint8, addr net.Addr) (*Player, error) {
+	h.pmut.Lock()
+	peer := h.peers[playerID]
+	if peer == nil {
+		h.pmut.Unlock()
+		return nil, ErrUnknownPeerID
+	}
+	if peer.W3GSConn.Conn() != nil {
+		h.pmut.Unlock()
+		return nil, ErrAlreadyConnected
+	}
+
+	var want protocol.BitSet32
+	want.Set(uint(playerID))
+
+	var register = w3gs.PeerConnect{
+		JoinCounter: h.PlayerInfo.JoinCounter,
+		EntryKey:    h.EntryKey,
+		PlayerID:    h.PlayerInfo.PlayerID,
+		PeerSet:     want,
+	}
+	var punch = w3gs.PeerConnect{
+		JoinCounter: peer.PlayerInfo.JoinCounter,
+		EntryKey:    h.EntryKey,
+		PlayerID:    h.PlayerInfo.PlayerID,
+		PeerSet:     h.peerset,
+	}
+	h.pmut.Unlock()
+
+	udp, err := network.ListenUDP(h.Family, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, peerset, err := h.punch(ctx, udp, addr, &register, &punch, playerID)
+	if err != nil {
+		udp.Close()
+		return nil, err
+	}
+
+	var conn = &datagramConn{PacketConn: udp, raddr: remote}
+
+	h.pmut.Lock()
+	if peer.W3GSConn.Conn() != nil {
+		h.pmut.Unlock()
+		conn.Close()
+		return nil, ErrAlreadyConnected
+	}
+
+	h.start(peer, conn, peerset, "Host.Punch[Serve]")
+	return peer, nil
+}
+
+func (h *Host) punch(ctx context.Context, udp net.PacketConn, rendezvous net.Addr, register *w3gs.PeerConnect, punch *w3gs.PeerConnect, playerID uint8) (net.Addr, protocol.BitSet32, error) {
+	var conn = network.NewW3GSPacketConn(udp, w3gs.NewFactoryCache(w3gs.DefaultFactory), h.Encoding)
+
+	var remote net.Addr
+	for {
+		if _, err := conn.Send(rendezvous, register); err != nil {
+			return nil, 0, err
+		}
+		if remote != nil {
+			if _, err := conn.Send(remote, punch); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		var deadline = time.Now().Add(PunchInterval)
+		for {
+			pkt, src, err := conn.NextPacketContext(ctx, time.Until(deadline))
+			if err != nil {
+				if ctx.Err() == nil && network.IsTimeout(err) {
+					break
+				}
+				switch err {
+				// Not a valid packet, ignore
+				case w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst:
+					continue
+				}
+				return nil, 0, err
+			}
+
+			switch p := pkt.(type) {
+			case *w3gs.PlayerInfo:
+				if p.PlayerID == playerID && src.String() == rendezvous.String() {
+					remote = p.ExternalAddr.UDPAddr()
+				}
+			case *w3gs.PeerConnect:
+				if p.PlayerID != playerID || p.EntryKey != h.EntryKey || p.JoinCounter != h.PlayerInfo.JoinCounter {
+					break
+				}
+
+				// Make sure the other side receives at least one punch
+				if _, err := conn.Send(src, punch); err != nil {
+					return nil, 0, err
+				}
+				return src, p.PeerSet, nil
+			default:
+				// Other side already finished punching
+				if remote != nil && src.String() == remote.String() {
+					return remote, 0, nil
+				}
+			}
+		}
+	}
+}
+
+// datagramConn exchanges packets with raddr over a PacketConn, one packet per datagram
+type datagramConn struct {
+	net.PacketConn
+	raddr net.Addr
+	buf   [2048]byte
+	rem   []byte
+}
+
+func (c *datagramConn) Read(b []byte) (int, error) {
+	for len(c.rem) == 0 {
+		n, addr, err := c.PacketConn.ReadFrom(c.buf[:])
+		if err != nil {
+			return 0, err
+		}
+		if addr.String() == c.raddr.String() {
+			c.rem = c.buf[:n]
+		}
+	}
+
+	var n = copy(b, c.rem)
+	c.rem = c.rem[n:]
+	return n, nil
+}
+
+func (c *datagramConn) Write(b []byte) (int, error) {
+	return c.PacketConn.WriteTo(b, c.raddr)
+}
+
+func (c *datagramConn) RemoteAddr() net.Addr {
+	return c.raddr
+}
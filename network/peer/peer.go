@@ -21,8 +21,12 @@ type Player struct {
 	network.W3GSConn
 
 	// Atomic
-	rtt     uint32
-	peerset uint32
+	rtt      uint32
+	peerset  uint32
+	pings    uint32
+	pongs    uint32
+	loss     uint32
+	degraded uint32
 
 	// Set once before Run(), read-only after that
 	PlayerInfo w3gs.PlayerInfo
@@ -52,6 +56,16 @@ func (p *Player) PeerSet() protocol.BitSet32 {
 	return protocol.BitSet32(atomic.LoadUint32(&p.peerset))
 }
 
+// Loss percentage of unanswered pings in the last LossWindow pings
+func (p *Player) Loss() uint32 {
+	return atomic.LoadUint32(&p.loss)
+}
+
+// Degraded returns true if the connection quality dropped below the threshold set in Host
+func (p *Player) Degraded() bool {
+	return atomic.LoadUint32(&p.degraded) != 0
+}
+
 // SendOrClose sends pkt to player, closes connection on failure
 func (p *Player) SendOrClose(pkt w3gs.Packet) (int, error) {
 	n, err := p.W3GSConn.Send(pkt)
@@ -90,4 +104,5 @@ func (p *Player) onPong(ev *network.Event) {
 	var rtt = uint32(time.Now().Sub(p.StartTime).Milliseconds()) - pkt.Payload
 
 	atomic.StoreUint32(&p.rtt, rtt)
+	atomic.AddUint32(&p.pongs, 1)
 }
@@ -0,0 +1,254 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// SendPriority class for queued packets, higher priority packets are sent first
+type SendPriority int
+
+// Send priorities
+const (
+	PriorityLow    SendPriority = iota // Chat
+	PriorityNormal                     // Default
+	PriorityHigh                       // Game actions
+	numPriorities
+)
+
+// QueuePolicy determines what happens when enqueuing into a full queue
+type QueuePolicy int
+
+// Queue policies
+const (
+	QueueBlock QueuePolicy = iota // Block until space is available
+	QueueDrop                     // Drop item and return ErrQueueFull
+)
+
+// SendQueue is a bounded outbound queue with priority classes
+// Items are written by Run() in a separate goroutine so that slow peers do not stall the caller
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type SendQueue struct {
+	mut    sync.Mutex
+	items  [numPriorities][]interface{}
+	size   int
+	closed bool
+	ready  chan struct{}
+	space  chan struct{}
+
+	// Set once before Run(), read-only after that
+	Capacity int
+	Policy   QueuePolicy
+	Send     func(item interface{}) error
+}
+
+// NewSendQueue initializes a SendQueue struct
+func NewSendQueue(capacity int, policy QueuePolicy, send func(item interface{}) error) *SendQueue {
+	return &SendQueue{
+		Capacity: capacity,
+		Policy:   policy,
+		Send:     send,
+	}
+}
+
+// W3GSPriority returns the default send priority for pkt (chat after everything else)
+// Lockstep packets (e.g. TimeSlot, StartLag, PlayerLeft) depend on each other's order, so they share PriorityNormal
+func W3GSPriority(pkt w3gs.Packet) SendPriority {
+	switch pkt.(type) {
+	case *w3gs.Message, *w3gs.MessageRelay, *w3gs.PeerMessage:
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
+// NewW3GSSendQueue initializes a SendQueue that writes w3gs.Packet or []byte items to conn
+func NewW3GSSendQueue(conn *W3GSConn, capacity int, policy QueuePolicy) *SendQueue {
+	return NewSendQueue(capacity, policy, func(item interface{}) error {
+		var err error
+		switch v := item.(type) {
+		case []byte:
+			_, err = conn.Write(v)
+		case w3gs.Packet:
+			_, err = conn.Send(v)
+		}
+		return err
+	})
+}
+
+// NewBNCSSendQueue initializes a SendQueue that writes bncs.Packet or []byte items to conn
+func NewBNCSSendQueue(conn *BNCSConn, capacity int, policy QueuePolicy) *SendQueue {
+	return NewSendQueue(capacity, policy, func(item interface{}) error {
+		var err error
+		switch v := item.(type) {
+		case []byte:
+			_, err = conn.Write(v)
+		case bncs.Packet:
+			_, err = conn.Send(v)
+		}
+		return err
+	})
+}
+
+// Make sure mut is locked before calling
+func (q *SendQueue) init() {
+	if q.ready == nil {
+		q.ready = make(chan struct{}, 1)
+		q.space = make(chan struct{})
+	}
+}
+
+// Len returns the number of queued items
+func (q *SendQueue) Len() int {
+	q.mut.Lock()
+	var n = q.size
+	q.mut.Unlock()
+	return n
+}
+
+// LenPriority returns the number of queued items with priority p
+func (q *SendQueue) LenPriority(p SendPriority) int {
+	if p < 0 || p >= numPriorities {
+		return 0
+	}
+
+	q.mut.Lock()
+	var n = len(q.items[p])
+	q.mut.Unlock()
+	return n
+}
+
+// Enqueue item with priority p
+func (q *SendQueue) Enqueue(item interface{}, p SendPriority) error {
+	return q.EnqueueContext(context.Background(), item, p)
+}
+
+// EnqueueContext enqueues item with priority p, gives up if ctx is done while blocking
+func (q *SendQueue) EnqueueContext(ctx context.Context, item interface{}, p SendPriority) error {
+	if p < 0 {
+		p = PriorityLow
+	} else if p >= numPriorities {
+		p = PriorityHigh
+	}
+
+	q.mut.Lock()
+	q.init()
+
+	for q.Capacity > 0 && q.size >= q.Capacity && !q.closed {
+		if q.Policy == QueueDrop {
+			q.mut.Unlock()
+			return ErrQueueFull
+		}
+
+		var space = q.space
+		q.mut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-space:
+		}
+
+		q.mut.Lock()
+	}
+
+	if q.closed {
+		q.mut.Unlock()
+		return io.EOF
+	}
+
+	q.items[p] = append(q.items[p], item)
+	q.size++
+	q.mut.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Make sure mut is locked before calling
+func (q *SendQueue) dequeue() (interface{}, bool) {
+	for p := numPriorities - 1; p >= 0; p-- {
+		if len(q.items[p]) == 0 {
+			continue
+		}
+
+		var item = q.items[p][0]
+		q.items[p][0] = nil
+		q.items[p] = q.items[p][1:]
+		q.size--
+
+		// Wake up blocked producers
+		close(q.space)
+		q.space = make(chan struct{})
+
+		return item, true
+	}
+	return nil, false
+}
+
+// Close the queue, Run() returns once all remaining items are sent
+func (q *SendQueue) Close() {
+	q.mut.Lock()
+	q.init()
+	if !q.closed {
+		q.closed = true
+		close(q.space)
+		q.space = make(chan struct{})
+	}
+	q.mut.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends queued items until the queue is closed or Send fails
+// Not safe for concurrent invocation
+func (q *SendQueue) Run() error {
+	return q.RunContext(context.Background())
+}
+
+// RunContext sends queued items until the queue is closed, Send fails, or ctx is done
+// Not safe for concurrent invocation
+func (q *SendQueue) RunContext(ctx context.Context) error {
+	q.mut.Lock()
+	q.init()
+	var ready = q.ready
+	q.mut.Unlock()
+
+	for {
+		q.mut.Lock()
+		item, ok := q.dequeue()
+		var closed = q.closed
+		q.mut.Unlock()
+
+		if ok {
+			if err := q.Send(item); err != nil {
+				return err
+			}
+			continue
+		}
+		if closed {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ready:
+		}
+	}
+}
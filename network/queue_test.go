@@ -0,0 +1,90 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+)
+
+func TestSendQueuePriority(t *testing.T) {
+	var res []interface{}
+	var q = network.NewSendQueue(0, network.QueueBlock, func(item interface{}) error {
+		res = append(res, item)
+		return nil
+	})
+
+	q.Enqueue("chat1", network.PriorityLow)
+	q.Enqueue("slot", network.PriorityNormal)
+	q.Enqueue("chat2", network.PriorityLow)
+	q.Enqueue("action", network.PriorityHigh)
+
+	if q.Len() != 4 || q.LenPriority(network.PriorityLow) != 2 {
+		t.Fatal("Unexpected queue depth", q.Len())
+	}
+
+	q.Close()
+	if err := q.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []interface{}{"action", "slot", "chat1", "chat2"}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatal("Unexpected order", res)
+	}
+	if err := q.Enqueue("late", network.PriorityNormal); err == nil {
+		t.Fatal("Expected error after Close()")
+	}
+}
+
+func TestSendQueuePolicy(t *testing.T) {
+	var q = network.NewSendQueue(2, network.QueueDrop, nil)
+	q.Enqueue(1, network.PriorityNormal)
+	q.Enqueue(2, network.PriorityNormal)
+	if err := q.Enqueue(3, network.PriorityHigh); err != network.ErrQueueFull {
+		t.Fatal("Expected ErrQueueFull, got", err)
+	}
+
+	var unblock = make(chan struct{})
+	var errSend = errors.New("send")
+
+	q = network.NewSendQueue(1, network.QueueBlock, func(item interface{}) error {
+		<-unblock
+		if item == 3 {
+			return errSend
+		}
+		return nil
+	})
+
+	q.Enqueue(1, network.PriorityNormal)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.EnqueueContext(ctx, 2, network.PriorityNormal); err != context.DeadlineExceeded {
+		t.Fatal("Expected context.DeadlineExceeded, got", err)
+	}
+
+	var done = make(chan error)
+	go func() { done <- q.Run() }()
+
+	// Blocks until Run() dequeued 1
+	if err := q.Enqueue(2, network.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	close(unblock)
+	if err := q.Enqueue(3, network.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != errSend {
+		t.Fatal("Expected send error, got", err)
+	}
+}
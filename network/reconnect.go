@@ -0,0 +1,222 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// ReconnectConfig stores the backoff configuration for Reconnector
+type ReconnectConfig struct {
+	MinDelay    time.Duration // Delay before the first reconnect attempt
+	MaxDelay    time.Duration // Upper bound for the exponential backoff
+	Multiplier  float64       // Delay growth factor per failed attempt
+	Jitter      float64       // Randomize each delay by ±Jitter (fraction in [0,1], 0 to disable)
+	MaxAttempts int           // Max consecutive failed attempts (0 for unlimited)
+}
+
+// DefaultReconnectConfig for Reconnector
+var DefaultReconnectConfig = ReconnectConfig{
+	MinDelay:   time.Second,
+	MaxDelay:   2 * time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Reconnecting event, fired before waiting for the next connection attempt
+type Reconnecting struct {
+	Attempt int
+	Delay   time.Duration
+	Err     error
+}
+
+// Reconnected event, fired after the connection was restored
+type Reconnected struct {
+	Attempt int
+}
+
+// Reconnector keeps a connection alive by reconnecting with exponential backoff whenever it breaks
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Reconnector struct {
+	smut    sync.Mutex
+	cancel  context.CancelFunc
+	stopped bool
+
+	// Set once before Run(), read-only after that
+	ReconnectConfig
+	Connect func(ctx context.Context) error // Establishes the connection (optional)
+	Serve   func(ctx context.Context) error // Runs the connection until it breaks
+	Probe   func(ctx context.Context) error // Health probe before each reconnect attempt (optional)
+	Resume  func(ctx context.Context) error // Restores session state after reconnecting (optional)
+	Retry   func(err error) bool            // Reports if err is recoverable (optional, defaults to always)
+	Emitter Emitter                         // Receives Reconnecting and Reconnected events (optional)
+}
+
+// NewReconnector initializes a Reconnector struct, zero delay settings in conf are replaced by their defaults
+func NewReconnector(conf ReconnectConfig, connect func(ctx context.Context) error, serve func(ctx context.Context) error) *Reconnector {
+	if conf.MinDelay == 0 {
+		conf.MinDelay = DefaultReconnectConfig.MinDelay
+	}
+	if conf.MaxDelay == 0 {
+		conf.MaxDelay = DefaultReconnectConfig.MaxDelay
+	}
+	if conf.Multiplier == 0 {
+		conf.Multiplier = DefaultReconnectConfig.Multiplier
+	}
+
+	return &Reconnector{
+		ReconnectConfig: conf,
+		Connect:         connect,
+		Serve:           serve,
+	}
+}
+
+// DialProbe returns a Probe function that checks if addr accepts connections
+func DialProbe(network string, addr string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// Delay before reconnect attempt (starting at 1)
+func (r *Reconnector) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		return 0
+	}
+
+	var mul = r.Multiplier
+	if mul < 1 {
+		mul = 1
+	}
+
+	var d = float64(r.MinDelay) * math.Pow(mul, float64(attempt-1))
+	if r.Jitter > 0 {
+		d += d * r.Jitter * (2*rand.Float64() - 1)
+	}
+	if r.MaxDelay > 0 && d > float64(r.MaxDelay) {
+		d = float64(r.MaxDelay)
+	}
+	if d < 0 {
+		d = 0
+	}
+
+	return time.Duration(d)
+}
+
+// Stopped reports whether Stop() was called
+func (r *Reconnector) Stopped() bool {
+	r.smut.Lock()
+	var res = r.stopped
+	r.smut.Unlock()
+	return res
+}
+
+// Stop reconnecting and interrupt Run()
+func (r *Reconnector) Stop() {
+	r.smut.Lock()
+	r.stopped = true
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.smut.Unlock()
+}
+
+func (r *Reconnector) connect(ctx context.Context, resume bool) error {
+	if resume && r.Probe != nil {
+		if err := r.Probe(ctx); err != nil {
+			return err
+		}
+	}
+	if r.Connect != nil {
+		if err := r.Connect(ctx); err != nil {
+			return err
+		}
+	}
+	if resume && r.Resume != nil {
+		return r.Resume(ctx)
+	}
+	return nil
+}
+
+// Run connects and serves, reconnects when the connection breaks
+// Returns nil once Serve returns nil or Stop() is called
+// Not safe for concurrent invocation
+func (r *Reconnector) Run() error {
+	return r.RunContext(context.Background())
+}
+
+// RunContext connects and serves until ctx is done, reconnects when the connection breaks
+// Returns nil once Serve returns nil or Stop() is called
+// Not safe for concurrent invocation
+func (r *Reconnector) RunContext(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.smut.Lock()
+	if r.stopped {
+		r.smut.Unlock()
+		return nil
+	}
+	r.cancel = cancel
+	r.smut.Unlock()
+
+	var connected = false
+	var attempt = 0
+	for {
+		var err = r.connect(ctx, connected)
+		if err == nil {
+			if connected && r.Emitter != nil {
+				r.Emitter.Fire(&Reconnected{Attempt: attempt})
+			}
+			connected = true
+			attempt = 0
+
+			if err = r.Serve(ctx); err == nil {
+				return nil
+			}
+		}
+
+		if r.Stopped() {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if r.Retry != nil && !r.Retry(err) {
+			return err
+		}
+
+		attempt++
+		if r.MaxAttempts > 0 && attempt > r.MaxAttempts {
+			return err
+		}
+
+		var delay = r.Delay(attempt)
+		if r.Emitter != nil {
+			r.Emitter.Fire(&Reconnecting{Attempt: attempt, Delay: delay, Err: err})
+		}
+
+		var t = time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			if r.Stopped() {
+				return nil
+			}
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+)
+
+func TestReconnectDelay(t *testing.T) {
+	var r = network.NewReconnector(network.ReconnectConfig{
+		MinDelay:   time.Second,
+		MaxDelay:   5 * time.Second,
+		Multiplier: 2,
+	}, nil, nil)
+
+	var expected = []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, e := range expected {
+		if d := r.Delay(i); d != e {
+			t.Fatalf("Expected delay %v for attempt %d, got %v", e, i, d)
+		}
+	}
+
+	r.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := r.Delay(2); d < time.Second || d > 3*time.Second {
+			t.Fatal("Jitter out of bounds", d)
+		}
+		if d := r.Delay(10); d < 2500*time.Millisecond || d > 5*time.Second {
+			t.Fatal("Expected jitter to respect MaxDelay, got", d)
+		}
+	}
+}
+
+func TestReconnector(t *testing.T) {
+	var errConn = errors.New("connect")
+	var errFatal = errors.New("fatal")
+
+	var connects, serves, probes, resumes int
+	var r = network.NewReconnector(network.ReconnectConfig{
+		MinDelay: time.Millisecond,
+		MaxDelay: 5 * time.Millisecond,
+	}, func(ctx context.Context) error {
+		connects++
+		if connects == 2 || connects == 3 {
+			return errConn
+		}
+		return nil
+	}, func(ctx context.Context) error {
+		serves++
+		if serves == 3 {
+			return errFatal
+		}
+		return errConn
+	})
+
+	r.Probe = func(ctx context.Context) error {
+		probes++
+		return nil
+	}
+	r.Resume = func(ctx context.Context) error {
+		resumes++
+		return nil
+	}
+	r.Retry = func(err error) bool {
+		return err != errFatal
+	}
+
+	var e network.EventEmitter
+	var reconnecting, reconnected int
+	e.On(&network.Reconnecting{}, func(ev *network.Event) { reconnecting++ })
+	e.On(&network.Reconnected{}, func(ev *network.Event) {
+		reconnected++
+		if a := ev.Arg.(*network.Reconnected).Attempt; reconnected == 1 && a != 3 {
+			t.Fatal("Expected attempt 3, got", a)
+		}
+	})
+	r.Emitter = &e
+
+	if err := r.Run(); err != errFatal {
+		t.Fatal("Expected errFatal, got", err)
+	}
+	if connects != 5 || serves != 3 || probes != 4 || resumes != 2 {
+		t.Fatal("Unexpected call count", connects, serves, probes, resumes)
+	}
+	if reconnecting != 4 || reconnected != 2 {
+		t.Fatal("Unexpected event count", reconnecting, reconnected)
+	}
+
+	r.MaxAttempts = 2
+	r.Retry = nil
+	r.Connect = func(ctx context.Context) error { return errConn }
+	if err := r.Run(); err != errConn {
+		t.Fatal("Expected errConn after max attempts, got", err)
+	}
+
+	r.MaxAttempts = 0
+	r.MinDelay = time.Hour
+	time.AfterFunc(10*time.Millisecond, r.Stop)
+	if err := r.Run(); err != nil {
+		t.Fatal("Expected nil after Stop(), got", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// W3GSMatcher reports whether pkt is the expected response to a request
+type W3GSMatcher func(pkt w3gs.Packet) bool
+
+// MatchW3GS returns a matcher that accepts packets with the same type as one of pkts
+func MatchW3GS(pkts ...w3gs.Packet) W3GSMatcher {
+	var types = make([]reflect.Type, len(pkts))
+	for i, p := range pkts {
+		types[i] = reflect.TypeOf(p)
+	}
+	return func(pkt w3gs.Packet) bool {
+		var t = reflect.TypeOf(pkt)
+		for _, m := range types {
+			if t == m {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Request sends req (if not nil) and waits (with given timeout) for the first packet accepted by match
+// Packets rejected by match are discarded, a nil match accepts the first packet
+// Not safe for concurrent invocation with NextPacket/Run
+func (c *W3GSConn) Request(req w3gs.Packet, match W3GSMatcher, timeout time.Duration) (w3gs.Packet, error) {
+	return c.RequestContext(context.Background(), req, match, timeout)
+}
+
+// RequestContext sends req (if not nil) and waits (with given timeout) until ctx is done for the first packet accepted by match
+// Packets rejected by match are discarded, a nil match accepts the first packet
+// Not safe for concurrent invocation with NextPacket/Run
+func (c *W3GSConn) RequestContext(ctx context.Context, req w3gs.Packet, match W3GSMatcher, timeout time.Duration) (w3gs.Packet, error) {
+	if timeout >= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if req != nil {
+		if _, err := c.Send(req); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		pkt, err := c.NextPacketContext(ctx, NoTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if match == nil || match(pkt) {
+			return pkt, nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestRequest(t *testing.T) {
+	var c1, c2 = net.Pipe()
+	var conn1 = network.NewW3GSConn(c1, nil, w3gs.Encoding{})
+	var conn2 = network.NewW3GSConn(c2, nil, w3gs.Encoding{})
+	defer conn1.Close()
+	defer conn2.Close()
+
+	go func() {
+		pkt, err := conn2.NextPacket(time.Second)
+		if err != nil {
+			return
+		}
+		conn2.Send(&w3gs.Ping{Payload: 1})
+		conn2.Send(&w3gs.Pong{Ping: w3gs.Ping{Payload: pkt.(*w3gs.Ping).Payload}})
+	}()
+
+	pkt, err := conn1.Request(&w3gs.Ping{Payload: 42}, network.MatchW3GS(&w3gs.Pong{}), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pong, ok := pkt.(*w3gs.Pong); !ok || pong.Payload != 42 {
+		t.Fatal("Unexpected response", pkt)
+	}
+
+	if _, err := conn1.Request(nil, nil, 10*time.Millisecond); !network.IsTimeout(err) || network.ErrorKind(err) != protocol.KindTimeout {
+		t.Fatal("Expected timeout, got", err)
+	}
+}
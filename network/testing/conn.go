@@ -0,0 +1,142 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package testing
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ConnBuffer is the number of writes that can be in flight before Write blocks
+const ConnBuffer = 256
+
+type chunk struct {
+	t    time.Time
+	data []byte
+}
+
+// Conn is one end of a simulated stream link, see Pipe()
+// Data is delivered in order, Loss and Reorder are ignored
+type Conn struct {
+	r net.Conn
+	w net.Conn
+	s *shaper
+
+	queue chan chunk
+	done  chan struct{}
+	once  sync.Once
+
+	laddr net.Addr
+	raddr net.Addr
+}
+
+// Pipe creates an in-memory, full duplex stream link with simulated network conditions c
+func Pipe(c Conditions) (*Conn, *Conn) {
+	var r1, w1 = net.Pipe()
+	var r2, w2 = net.Pipe()
+
+	var a1 = &net.TCPAddr{IP: localhost, Port: nextPort()}
+	var a2 = &net.TCPAddr{IP: localhost, Port: nextPort()}
+
+	var c1 = newConn(r1, w2, c, a1, a2)
+	var c2 = newConn(r2, w1, c, a2, a1)
+	return c1, c2
+}
+
+func newConn(r net.Conn, w net.Conn, c Conditions, laddr net.Addr, raddr net.Addr) *Conn {
+	var conn = Conn{
+		r:     r,
+		w:     w,
+		s:     newShaper(c),
+		queue: make(chan chunk, ConnBuffer),
+		done:  make(chan struct{}),
+		laddr: laddr,
+		raddr: raddr,
+	}
+	go conn.run()
+	return &conn
+}
+
+func (c *Conn) deliver(ch chunk) {
+	if d := time.Until(ch.t); d > 0 {
+		time.Sleep(d)
+	}
+	c.w.Write(ch.data)
+}
+
+func (c *Conn) run() {
+	for {
+		select {
+		case ch := <-c.queue:
+			c.deliver(ch)
+		case <-c.done:
+			// Flush data that was written before Close()
+			for {
+				select {
+				case ch := <-c.queue:
+					c.deliver(ch)
+				default:
+					c.w.Close()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Read implements net.Conn
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// Write implements net.Conn, data is delivered to the remote end asynchronously
+func (c *Conn) Write(b []byte) (int, error) {
+	select {
+	case <-c.done:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	var t, _ = c.s.schedule(len(b), true)
+	select {
+	case c.queue <- chunk{t: t, data: append([]byte(nil), b...)}:
+		return len(b), nil
+	case <-c.done:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// Close implements net.Conn, pending writes are still delivered
+func (c *Conn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.r.Close()
+}
+
+// LocalAddr implements net.Conn
+func (c *Conn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+// RemoteAddr implements net.Conn
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+// SetDeadline implements net.Conn
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.r.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements net.Conn
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.r.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements net.Conn, writes are buffered and never time out
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
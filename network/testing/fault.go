@@ -0,0 +1,105 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package testing
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// ErrInjected is returned by writes that failed because of an injected fault
+var ErrInjected = errors.New("testing: Injected fault")
+
+type fault func(b []byte) (out []byte, err error, disconnect bool)
+
+// FaultConn wraps a net.Conn and applies injected faults to subsequent writes, one fault per Write() call
+// Faults are applied in the order they were injected, so tests can deterministically break a connection
+// at a known point in the stream. Public methods/fields are thread-safe unless explicitly stated otherwise
+type FaultConn struct {
+	net.Conn
+
+	fmut   sync.Mutex
+	faults []fault
+}
+
+// NewFaultConn wraps conn
+func NewFaultConn(conn net.Conn) *FaultConn {
+	return &FaultConn{Conn: conn}
+}
+
+func (c *FaultConn) inject(f fault) {
+	c.fmut.Lock()
+	c.faults = append(c.faults, f)
+	c.fmut.Unlock()
+}
+
+// Disconnect closes the connection mid-stream after the next write delivered its first n bytes
+func (c *FaultConn) Disconnect(n int) {
+	c.inject(func(b []byte) ([]byte, error, bool) {
+		if n < len(b) {
+			b = b[:n]
+		}
+		return b, ErrInjected, true
+	})
+}
+
+// PartialWrite only delivers the first n bytes of the next write, the connection stays open
+func (c *FaultConn) PartialWrite(n int) {
+	c.inject(func(b []byte) ([]byte, error, bool) {
+		if n >= len(b) {
+			return b, nil, false
+		}
+		return b[:n], io.ErrShortWrite, false
+	})
+}
+
+// Corrupt flips all bits of the byte at offset i of the next write
+func (c *FaultConn) Corrupt(i int) {
+	c.inject(func(b []byte) ([]byte, error, bool) {
+		if i < len(b) {
+			b = append([]byte(nil), b...)
+			b[i] = ^b[i]
+		}
+		return b, nil, false
+	})
+}
+
+// Pending returns the number of injected faults that have not been applied yet
+func (c *FaultConn) Pending() int {
+	c.fmut.Lock()
+	var n = len(c.faults)
+	c.fmut.Unlock()
+	return n
+}
+
+// Write implements net.Conn
+func (c *FaultConn) Write(b []byte) (int, error) {
+	c.fmut.Lock()
+	if len(c.faults) == 0 {
+		c.fmut.Unlock()
+		return c.Conn.Write(b)
+	}
+
+	var f = c.faults[0]
+	c.faults = c.faults[1:]
+	c.fmut.Unlock()
+
+	out, ferr, disconnect := f(b)
+
+	var n = 0
+	if len(out) > 0 {
+		var err error
+		if n, err = c.Conn.Write(out); err != nil {
+			return n, err
+		}
+	}
+	if disconnect {
+		c.Conn.Close()
+	}
+
+	return n, ferr
+}
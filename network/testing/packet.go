@@ -0,0 +1,161 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package testing
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// PacketBuffer is the number of datagrams buffered per receiver, excess datagrams are dropped
+const PacketBuffer = 256
+
+type datagram struct {
+	data []byte
+	addr net.Addr
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// PacketConn is one end of a simulated datagram link, see PacketPipe()
+// Datagrams written to any address are delivered to the remote end
+type PacketConn struct {
+	s    *shaper
+	peer *PacketConn
+
+	recv chan datagram
+	done chan struct{}
+	once sync.Once
+
+	dmut sync.Mutex
+	rdl  time.Time
+	dchg chan struct{}
+
+	laddr net.Addr
+}
+
+// PacketPipe creates an in-memory, full duplex datagram link with simulated network conditions c
+func PacketPipe(c Conditions) (*PacketConn, *PacketConn) {
+	var c1 = newPacketConn(c, &net.UDPAddr{IP: localhost, Port: nextPort()})
+	var c2 = newPacketConn(c, &net.UDPAddr{IP: localhost, Port: nextPort()})
+	c1.peer = c2
+	c2.peer = c1
+	return c1, c2
+}
+
+func newPacketConn(c Conditions, laddr net.Addr) *PacketConn {
+	return &PacketConn{
+		s:     newShaper(c),
+		recv:  make(chan datagram, PacketBuffer),
+		done:  make(chan struct{}),
+		dchg:  make(chan struct{}),
+		laddr: laddr,
+	}
+}
+
+func (c *PacketConn) deliver(d datagram) {
+	select {
+	case <-c.done:
+	case c.recv <- d:
+	default:
+		// Buffer full, drop datagram
+	}
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// ReadFrom implements net.PacketConn
+func (c *PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		c.dmut.Lock()
+		var rdl, chg = c.rdl, c.dchg
+		c.dmut.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !rdl.IsZero() {
+			var d = time.Until(rdl)
+			if d <= 0 {
+				return 0, nil, timeoutError{}
+			}
+			timer = time.NewTimer(d)
+			timeout = timer.C
+		}
+
+		select {
+		case d := <-c.recv:
+			stopTimer(timer)
+			return copy(b, d.data), d.addr, nil
+		case <-c.done:
+			stopTimer(timer)
+			return 0, nil, io.EOF
+		case <-timeout:
+			return 0, nil, timeoutError{}
+		case <-chg:
+			// Deadline changed
+			stopTimer(timer)
+		}
+	}
+}
+
+// WriteTo implements net.PacketConn, the datagram is delivered to the remote end asynchronously
+func (c *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.done:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	var t, drop = c.s.schedule(len(b), false)
+	if drop {
+		return len(b), nil
+	}
+
+	var d = datagram{data: append([]byte(nil), b...), addr: c.laddr}
+	time.AfterFunc(time.Until(t), func() { c.peer.deliver(d) })
+
+	return len(b), nil
+}
+
+// Close implements net.PacketConn
+func (c *PacketConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+// LocalAddr implements net.PacketConn
+func (c *PacketConn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+// SetDeadline implements net.PacketConn
+func (c *PacketConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements net.PacketConn
+func (c *PacketConn) SetReadDeadline(t time.Time) error {
+	c.dmut.Lock()
+	c.rdl = t
+	close(c.dchg)
+	c.dchg = make(chan struct{})
+	c.dmut.Unlock()
+	return nil
+}
+
+// SetWriteDeadline implements net.PacketConn, writes never block
+func (c *PacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,85 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package testing implements in-process network links with simulated latency, jitter, bandwidth limits, reordering and loss,
+// and connection wrappers that inject faults (disconnects, partial writes, corrupted frames) at deterministic points.
+package testing
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Conditions of a simulated link (applied to each direction separately)
+type Conditions struct {
+	Latency   time.Duration // One-way delay
+	Jitter    time.Duration // Maximum random deviation from Latency
+	Bandwidth int           // Bytes per second (0 for unlimited)
+	Loss      float64       // Probability that a datagram is dropped (PacketPipe only)
+	Reorder   float64       // Probability that a datagram is delayed past its successors (PacketPipe only)
+	Seed      int64         // Seed for random number generator
+}
+
+type shaper struct {
+	Conditions
+
+	mut  sync.Mutex
+	rng  *rand.Rand
+	busy time.Time
+	last time.Time
+}
+
+func newShaper(c Conditions) *shaper {
+	return &shaper{
+		Conditions: c,
+		rng:        rand.New(rand.NewSource(c.Seed)),
+	}
+}
+
+// schedule returns the delivery time for a write of n bytes, ordered writes are never delivered before their predecessors
+func (s *shaper) schedule(n int, ordered bool) (t time.Time, drop bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var now = time.Now()
+	if !ordered && s.Loss > 0 && s.rng.Float64() < s.Loss {
+		return now, true
+	}
+
+	if s.busy.Before(now) {
+		s.busy = now
+	}
+	if s.Bandwidth > 0 {
+		s.busy = s.busy.Add(time.Duration(n) * time.Second / time.Duration(s.Bandwidth))
+	}
+
+	t = s.busy.Add(s.Latency)
+	if s.Jitter > 0 {
+		t = t.Add(time.Duration(s.rng.Int63n(int64(2*s.Jitter)+1)) - s.Jitter)
+	}
+	if !ordered && s.Reorder > 0 && s.rng.Float64() < s.Reorder {
+		t = t.Add(s.Latency + 2*s.Jitter + time.Millisecond)
+	}
+
+	if ordered && t.Before(s.last) {
+		t = s.last
+	}
+	if t.Before(now) {
+		t = now
+	}
+
+	s.last = t
+	return t, false
+}
+
+var port uint32 = 10000
+
+func nextPort() int {
+	return int(atomic.AddUint32(&port, 1))
+}
+
+var localhost = net.IPv4(127, 0, 0, 1)
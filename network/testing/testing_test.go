@@ -0,0 +1,141 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package testing_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	nettest "github.com/nielsAD/gowarcraft3/network/testing"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestPipe(t *testing.T) {
+	var c1, c2 = nettest.Pipe(nettest.Conditions{
+		Latency: 20 * time.Millisecond,
+		Jitter:  10 * time.Millisecond,
+	})
+
+	var conn1 = network.NewW3GSConn(c1, nil, w3gs.Encoding{})
+	var conn2 = network.NewW3GSConn(c2, nil, w3gs.Encoding{})
+	defer conn1.Close()
+	defer conn2.Close()
+
+	var start = time.Now()
+	for i := uint32(0); i < 10; i++ {
+		if _, err := conn1.Send(&w3gs.Ping{Payload: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := uint32(0); i < 10; i++ {
+		pkt, err := conn2.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pkt.(*w3gs.Ping).Payload != i {
+			t.Fatal("Out of order delivery", i, pkt)
+		}
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Fatal("Expected latency")
+	}
+
+	conn1.Send(&w3gs.Ping{Payload: 42})
+	conn1.Close()
+	if pkt, err := conn2.NextPacket(time.Second); err != nil || pkt.(*w3gs.Ping).Payload != 42 {
+		t.Fatal("Expected delivery of pending write after close", pkt, err)
+	}
+	if _, err := conn2.NextPacket(time.Second); err == nil {
+		t.Fatal("Expected error after disconnect")
+	}
+}
+
+func TestPacketPipe(t *testing.T) {
+	var c1, c2 = nettest.PacketPipe(nettest.Conditions{
+		Bandwidth: 10000,
+		Loss:      0.5,
+		Seed:      1,
+	})
+
+	var conn1 = network.NewW3GSPacketConn(c1, nil, w3gs.Encoding{})
+	var conn2 = network.NewW3GSPacketConn(c2, nil, w3gs.Encoding{})
+	defer conn1.Close()
+	defer conn2.Close()
+
+	var start = time.Now()
+	for i := uint32(0); i < 100; i++ {
+		if _, err := conn1.Send(c2.LocalAddr(), &w3gs.Ping{Payload: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var n = 0
+	var last time.Time
+	for {
+		_, addr, err := conn2.NextPacket(100 * time.Millisecond)
+		if network.IsTimeout(err) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if addr.String() != c1.LocalAddr().String() {
+			t.Fatal("Unexpected source address", addr)
+		}
+		n++
+		last = time.Now()
+	}
+
+	if n == 0 || n == 100 {
+		t.Fatal("Expected partial loss, received", n)
+	}
+
+	// 8 bytes per ping at 10KB/s
+	if last.Sub(start) < time.Duration(n)*800*time.Microsecond-time.Millisecond {
+		t.Fatal("Expected bandwidth limit")
+	}
+}
+
+func TestFaultConn(t *testing.T) {
+	var c1, c2 = nettest.Pipe(nettest.Conditions{})
+	var f = nettest.NewFaultConn(c1)
+
+	var conn1 = network.NewW3GSConn(f, nil, w3gs.Encoding{})
+	var conn2 = network.NewW3GSConn(c2, nil, w3gs.Encoding{})
+	defer conn1.Close()
+	defer conn2.Close()
+
+	f.Corrupt(0)
+	if _, err := conn1.Send(&w3gs.Ping{Payload: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn2.NextPacket(time.Second); err != w3gs.ErrNoProtocolSig {
+		t.Fatal("Expected ErrNoProtocolSig, got", err)
+	}
+
+	// Rest of the corrupted frame
+	conn2.NextPacket(10 * time.Millisecond)
+
+	f.PartialWrite(2)
+	if _, err := conn1.Send(&w3gs.Ping{Payload: 2}); err != io.ErrShortWrite {
+		t.Fatal("Expected ErrShortWrite, got", err)
+	}
+	if f.Pending() != 0 {
+		t.Fatal("Expected no pending faults")
+	}
+
+	f.Disconnect(5)
+	if _, err := conn1.Send(&w3gs.Ping{Payload: 3}); err != nettest.ErrInjected {
+		t.Fatal("Expected ErrInjected, got", err)
+	}
+	if _, err := conn2.NextPacket(time.Second); err != io.ErrUnexpectedEOF {
+		t.Fatal("Expected ErrUnexpectedEOF, got", err)
+
+	}
+	if _, err := conn2.NextPacket(time.Second); err == nil {
+		t.Fatal("Expected error after disconnect")
+	}
+}
@@ -0,0 +1,133 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase of a connection
+type Phase int
+
+// Connection phases
+const (
+	PhaseHandshake Phase = iota // Connection is being set up (joining, logging on)
+	PhaseSteady                 // Connection is established (in lobby, in game, in chat)
+	PhaseLoading                // Connection is mostly idle while a map is being downloaded or loaded
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseHandshake:
+		return "Handshake"
+	case PhaseSteady:
+		return "Steady"
+	case PhaseLoading:
+		return "Loading"
+	default:
+		return "Phase(?)"
+	}
+}
+
+// Timeouts for a single connection phase
+// Zero values fall back to the timeout passed to NextPacket() (read) or SetWriteTimeout() (write)
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+// TimeoutPolicy configures read/write timeouts per connection phase and packet class
+type TimeoutPolicy struct {
+	Handshake Timeouts
+	Steady    Timeouts
+	Loading   Timeouts
+
+	// Write timeout for specific packets, takes precedence over phase timeouts (optional, return 0 for no override)
+	Packet func(pkt interface{}) time.Duration
+}
+
+func (p *TimeoutPolicy) timeouts(ph Phase) *Timeouts {
+	switch ph {
+	case PhaseSteady:
+		return &p.Steady
+	case PhaseLoading:
+		return &p.Loading
+	default:
+		return &p.Handshake
+	}
+}
+
+// ReadTimeout returns the read timeout in phase ph, def if not configured
+func (p *TimeoutPolicy) ReadTimeout(ph Phase, def time.Duration) time.Duration {
+	if t := p.timeouts(ph).Read; t != 0 {
+		return t
+	}
+	return def
+}
+
+// WriteTimeout returns the timeout for writing pkt (nil for raw writes) in phase ph, def if not configured
+func (p *TimeoutPolicy) WriteTimeout(ph Phase, pkt interface{}, def time.Duration) time.Duration {
+	if pkt != nil && p.Packet != nil {
+		if t := p.Packet(pkt); t != 0 {
+			return t
+		}
+	}
+	if t := p.timeouts(ph).Write; t != 0 {
+		return t
+	}
+	return def
+}
+
+type phaseTimeouts struct {
+	tmut   sync.Mutex
+	phase  Phase
+	policy *TimeoutPolicy
+}
+
+// Phase returns the current connection phase
+func (t *phaseTimeouts) Phase() Phase {
+	t.tmut.Lock()
+	var res = t.phase
+	t.tmut.Unlock()
+	return res
+}
+
+// SetPhase switches connection phase, which selects the active timeouts in TimeoutPolicy
+func (t *phaseTimeouts) SetPhase(ph Phase) {
+	t.tmut.Lock()
+	t.phase = ph
+	t.tmut.Unlock()
+}
+
+// SetTimeoutPolicy for read/write deadlines, nil restores the default behavior
+// p must not be modified after calling SetTimeoutPolicy
+func (t *phaseTimeouts) SetTimeoutPolicy(p *TimeoutPolicy) {
+	t.tmut.Lock()
+	t.policy = p
+	t.tmut.Unlock()
+}
+
+func (t *phaseTimeouts) readTimeout(def time.Duration) time.Duration {
+	t.tmut.Lock()
+	var p, ph = t.policy, t.phase
+	t.tmut.Unlock()
+
+	if p == nil {
+		return def
+	}
+	return p.ReadTimeout(ph, def)
+}
+
+func (t *phaseTimeouts) writeTimeout(pkt interface{}, def time.Duration) time.Duration {
+	t.tmut.Lock()
+	var p, ph = t.policy, t.phase
+	t.tmut.Unlock()
+
+	if p == nil {
+		return def
+	}
+	return p.WriteTimeout(ph, pkt, def)
+}
@@ -0,0 +1,66 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestTimeoutPolicy(t *testing.T) {
+	var p = network.TimeoutPolicy{
+		Handshake: network.Timeouts{Read: time.Second},
+		Steady:    network.Timeouts{Write: time.Minute},
+		Loading:   network.Timeouts{Read: 3 * time.Minute},
+		Packet: func(pkt interface{}) time.Duration {
+			if _, ok := pkt.(*w3gs.TimeSlot); ok {
+				return time.Millisecond
+			}
+			return 0
+		},
+	}
+
+	if p.ReadTimeout(network.PhaseHandshake, time.Hour) != time.Second || p.ReadTimeout(network.PhaseSteady, time.Hour) != time.Hour {
+		t.Fatal("Unexpected read timeout")
+	}
+	if p.ReadTimeout(network.PhaseLoading, time.Hour) != 3*time.Minute || p.WriteTimeout(network.PhaseLoading, nil, time.Hour) != time.Hour {
+		t.Fatal("Unexpected loading timeout")
+	}
+	if p.WriteTimeout(network.PhaseHandshake, nil, time.Hour) != time.Hour || p.WriteTimeout(network.PhaseSteady, &w3gs.Ping{}, time.Hour) != time.Minute {
+		t.Fatal("Unexpected write timeout")
+	}
+	if p.WriteTimeout(network.PhaseSteady, &w3gs.TimeSlot{}, time.Hour) != time.Millisecond {
+		t.Fatal("Expected packet override")
+	}
+
+	var c1, c2 = net.Pipe()
+	var conn = network.NewW3GSConn(c1, nil, w3gs.Encoding{})
+	defer conn.Close()
+	defer c2.Close()
+
+	conn.SetTimeoutPolicy(&network.TimeoutPolicy{
+		Handshake: network.Timeouts{Read: 10 * time.Millisecond},
+	})
+	if conn.Phase() != network.PhaseHandshake {
+		t.Fatal("Expected handshake phase")
+	}
+
+	if _, err := conn.NextPacket(network.NoTimeout); !network.IsTimeout(err) {
+		t.Fatal("Expected timeout in handshake phase, got", err)
+	}
+
+	conn.SetPhase(network.PhaseSteady)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		w3gs.Write(c2, &w3gs.Ping{Payload: 1}, w3gs.Encoding{})
+	}()
+	if _, err := conn.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
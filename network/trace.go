@@ -0,0 +1,228 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Trace file format
+// Header: "GW3T" (4 bytes), version (1 byte), start time in unix nanoseconds (int64, little endian)
+// Record: direction (1 byte), time since previous record in microseconds (uvarint), size (uvarint), raw packet (size bytes)
+const (
+	traceMagic   = "GW3T"
+	traceVersion = 1
+	traceMaxSize = 0xFFFF
+)
+
+// TraceDirection of a recorded packet
+type TraceDirection uint8
+
+// Trace directions
+const (
+	TraceIngress TraceDirection = iota // Received packet
+	TraceEgress                        // Sent packet
+)
+
+// TraceRecord is a single recorded packet
+type TraceRecord struct {
+	Dir  TraceDirection
+	Time time.Time
+	Data []byte
+}
+
+// TraceWriter writes records to a trace file
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type TraceWriter struct {
+	mut  sync.Mutex
+	w    io.Writer
+	last time.Time
+	hdr  [1 + 2*binary.MaxVarintLen64]byte
+}
+
+// NewTraceWriter writes the trace header to w and returns a TraceWriter
+func NewTraceWriter(w io.Writer) (*TraceWriter, error) {
+	var t = TraceWriter{
+		w:    w,
+		last: time.Now(),
+	}
+
+	var hdr [len(traceMagic) + 1 + 8]byte
+	copy(hdr[:], traceMagic)
+	hdr[len(traceMagic)] = traceVersion
+	binary.LittleEndian.PutUint64(hdr[len(traceMagic)+1:], uint64(t.last.UnixNano()))
+
+	if _, err := w.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Write rec to trace, rec.Time must not precede the previous record
+func (t *TraceWriter) Write(rec *TraceRecord) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	var delta = rec.Time.Sub(t.last)
+	if delta < 0 {
+		delta = 0
+	} else {
+		t.last = rec.Time
+	}
+
+	t.hdr[0] = byte(rec.Dir)
+	var n = 1
+	n += binary.PutUvarint(t.hdr[n:], uint64(delta/time.Microsecond))
+	n += binary.PutUvarint(t.hdr[n:], uint64(len(rec.Data)))
+
+	if _, err := t.w.Write(t.hdr[:n]); err != nil {
+		return err
+	}
+	_, err := t.w.Write(rec.Data)
+	return err
+}
+
+// TraceW3GS records the raw bytes of all packets sent and received by c
+func (t *TraceWriter) TraceW3GS(c *W3GSConn) {
+	c.SetTrace(t)
+}
+
+// TraceBNCS records the raw bytes of all packets sent and received by c
+func (t *TraceWriter) TraceBNCS(c *BNCSConn) {
+	c.SetTrace(t)
+}
+
+// record b in trace, errors are ignored to not interrupt the connection
+func (t *TraceWriter) record(dir TraceDirection, b []byte) {
+	if t == nil {
+		return
+	}
+	t.Write(&TraceRecord{Dir: dir, Time: time.Now(), Data: b})
+}
+
+// TraceReader reads records from a trace file
+// Not safe for concurrent invocation
+type TraceReader struct {
+	r    *bufio.Reader
+	last time.Time
+}
+
+// NewTraceReader reads the trace header from r and returns a TraceReader
+func NewTraceReader(r io.Reader) (*TraceReader, error) {
+	var t = TraceReader{
+		r: bufio.NewReader(r),
+	}
+
+	var hdr [len(traceMagic) + 1 + 8]byte
+	if _, err := io.ReadFull(t.r, hdr[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidTrace
+		}
+		return nil, err
+	}
+	if string(hdr[:len(traceMagic)]) != traceMagic || hdr[len(traceMagic)] != traceVersion {
+		return nil, ErrInvalidTrace
+	}
+
+	t.last = time.Unix(0, int64(binary.LittleEndian.Uint64(hdr[len(traceMagic)+1:])))
+	return &t, nil
+}
+
+// Next record in trace, returns io.EOF at the end of the trace
+func (t *TraceReader) Next() (*TraceRecord, error) {
+	dir, err := t.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	delta, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		return nil, ErrInvalidTrace
+	}
+	size, err := binary.ReadUvarint(t.r)
+	if err != nil || size > traceMaxSize {
+		return nil, ErrInvalidTrace
+	}
+
+	var rec = TraceRecord{
+		Dir:  TraceDirection(dir),
+		Time: t.last.Add(time.Duration(delta) * time.Microsecond),
+		Data: make([]byte, size),
+	}
+	if _, err := io.ReadFull(t.r, rec.Data); err != nil {
+		return nil, ErrInvalidTrace
+	}
+
+	t.last = rec.Time
+	return &rec, nil
+}
+
+func replay(ctx context.Context, r *TraceReader, dir TraceDirection, speed float64, fire func(b []byte)) error {
+	var prev time.Time
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if rec.Dir != dir {
+			continue
+		}
+
+		if speed > 0 && !prev.IsZero() {
+			var t = time.NewTimer(time.Duration(float64(rec.Time.Sub(prev)) / speed))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		prev = rec.Time
+
+		fire(rec.Data)
+	}
+}
+
+// ReplayW3GS deserializes the records in r with direction dir and fires an event through f for each packet
+// Delays between records are divided by speed (0 to replay without delays)
+func ReplayW3GS(ctx context.Context, r *TraceReader, f Emitter, dir TraceDirection, enc w3gs.Encoding, speed float64) error {
+	var dec = w3gs.NewDecoder(enc, w3gs.NewFactoryCache(w3gs.DefaultFactory))
+	return replay(ctx, r, dir, speed, func(b []byte) {
+		pkt, _, err := dec.Deserialize(b)
+		if err != nil {
+			f.Fire(&AsyncError{Src: "ReplayW3GS[Deserialize]", Err: err})
+			return
+		}
+		f.Fire(pkt)
+	})
+}
+
+// ReplayBNCS deserializes the records in r with direction dir and fires an event through f for each packet
+// Delays between records are divided by speed (0 to replay without delays)
+func ReplayBNCS(ctx context.Context, r *TraceReader, f Emitter, dir TraceDirection, enc bncs.Encoding, speed float64) error {
+	var dec = bncs.NewDecoder(enc, bncs.NewFactoryCache(bncs.DefaultFactory))
+	return replay(ctx, r, dir, speed, func(b []byte) {
+		pkt, _, err := dec.Deserialize(b)
+		if err != nil {
+			f.Fire(&AsyncError{Src: "ReplayBNCS[Deserialize]", Err: err})
+			return
+		}
+		f.Fire(pkt)
+	})
+}
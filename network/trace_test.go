@@ -0,0 +1,99 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestTrace(t *testing.T) {
+	var c1, c2 = net.Pipe()
+	var conn1 = network.NewW3GSConn(c1, nil, w3gs.Encoding{})
+	var conn2 = network.NewW3GSConn(c2, nil, w3gs.Encoding{})
+	defer conn1.Close()
+	defer conn2.Close()
+
+	var buf bytes.Buffer
+	tw, err := network.NewTraceWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw.TraceW3GS(conn1)
+
+	// Ping with truncated payload
+	var invalid = []byte{w3gs.ProtocolSig, w3gs.PidPingFromHost, 6, 0, 1, 2}
+
+	go func() {
+		conn2.NextPacket(time.Second)
+		conn2.Send(&w3gs.Pong{Ping: w3gs.Ping{Payload: 42}})
+		conn2.Write(invalid)
+	}()
+
+	if _, err := conn1.Send(&w3gs.Ping{Payload: 42}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn1.NextPacket(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn1.NextPacket(time.Second); err == nil {
+		t.Fatal("Expected error for invalid packet")
+	}
+
+	var data = buf.Bytes()
+
+	tr, err := network.NewTraceReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dirs = []network.TraceDirection{network.TraceEgress, network.TraceIngress, network.TraceIngress}
+	for _, d := range dirs {
+		rec, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Dir != d || len(rec.Data) == 0 || rec.Data[0] != w3gs.ProtocolSig {
+			t.Fatal("Unexpected record", rec)
+		}
+		if rec.Data[1] == w3gs.PidPingFromHost && rec.Dir == network.TraceIngress && !bytes.Equal(rec.Data, invalid) {
+			t.Fatal("Expected raw bytes of invalid packet, got", rec.Data)
+		}
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatal("Expected EOF, got", err)
+	}
+
+	tr, _ = network.NewTraceReader(bytes.NewReader(data))
+
+	var e network.EventEmitter
+	var pongs, pings, errs int
+	e.On(&network.AsyncError{}, func(ev *network.Event) { errs++ })
+	e.On(&w3gs.Pong{}, func(ev *network.Event) {
+		if ev.Arg.(*w3gs.Pong).Payload != 42 {
+			t.Fatal("Unexpected payload")
+		}
+		pongs++
+	})
+	e.On(&w3gs.Ping{}, func(ev *network.Event) { pings++ })
+
+	if err := network.ReplayW3GS(context.Background(), tr, &e, network.TraceIngress, w3gs.Encoding{}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if pongs != 1 || pings != 0 || errs != 1 {
+		t.Fatal("Unexpected replay", pongs, pings, errs)
+	}
+
+	if _, err := network.NewTraceReader(bytes.NewReader([]byte("GW3X"))); err != network.ErrInvalidTrace {
+		t.Fatal("Expected ErrInvalidTrace, got", err)
+	}
+}
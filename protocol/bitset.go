@@ -118,7 +118,7 @@ func (b BitSet16) MarshalText() ([]byte, error) {
 
 // UnmarshalText implements TextUnmarshaler
 func (b *BitSet16) UnmarshalText(txt []byte) error {
-	i, err := strconv.ParseUint(string(txt), 2, 8)
+	i, err := strconv.ParseUint(string(txt), 2, 16)
 	if err == nil {
 		*b = BitSet16(i)
 	}
@@ -176,7 +176,7 @@ func (b BitSet32) MarshalText() ([]byte, error) {
 
 // UnmarshalText implements TextUnmarshaler
 func (b *BitSet32) UnmarshalText(txt []byte) error {
-	i, err := strconv.ParseUint(string(txt), 2, 8)
+	i, err := strconv.ParseUint(string(txt), 2, 32)
 	if err == nil {
 		*b = BitSet32(i)
 	}
@@ -104,6 +104,12 @@ func TestBitSet32(t *testing.T) {
 	}
 
 	b.Set(32)
+
+	var u protocol.BitSet32
+	if txt, err := b.MarshalText(); err != nil || u.UnmarshalText(txt) != nil || u != b {
+		t.Fatal("Text round-trip failed", u)
+	}
+
 	defer func() {
 		if r := recover(); r == nil {
 			t.Fatal("Panic expected when out of index")
@@ -7,6 +7,8 @@ package bncs
 import (
 	"errors"
 	"fmt"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
 // Errors
@@ -14,7 +16,7 @@ var (
 	ErrNoFactory         = errors.New("bncs: Invalid bncs packet (empty factory)")
 	ErrNoProtocolSig     = errors.New("bncs: Invalid bncs packet (no signature found)")
 	ErrInvalidPacketSize = errors.New("bncs: Invalid packet size")
-	ErrInvalidChecksum   = errors.New("bncs: Checksum invalid")
+	ErrInvalidChecksum   = protocol.NewError(protocol.KindChecksum, "bncs: Checksum invalid")
 	ErrUnexpectedConst   = errors.New("bncs: Unexpected constant value")
 )
 
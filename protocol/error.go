@@ -0,0 +1,67 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package protocol
+
+import (
+	"errors"
+)
+
+// ErrorKind classifies errors, use errors.Is(err, kind) to test if err is of a certain kind
+type ErrorKind string
+
+func (k ErrorKind) Error() string {
+	return string(k)
+}
+
+// Error kinds
+const (
+	KindTimeout         ErrorKind = "Timeout"
+	KindRejected        ErrorKind = "Rejected"
+	KindVersionMismatch ErrorKind = "Version mismatch"
+	KindChecksum        ErrorKind = "Checksum mismatch"
+	KindRateLimited     ErrorKind = "Rate limited"
+)
+
+// KindError is an error of a specific kind
+type KindError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// NewError returns a new error of kind with given text
+func NewError(kind ErrorKind, text string) error {
+	return &KindError{Kind: kind, Err: errors.New(text)}
+}
+
+// WrapError marks err as kind
+func WrapError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &KindError{Kind: kind, Err: err}
+}
+
+func (e *KindError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *KindError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of e
+func (e *KindError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Timeout reports whether e is a timeout
+func (e *KindError) Timeout() bool {
+	if e.Kind == KindTimeout {
+		return true
+	}
+	t, ok := e.Err.(interface{ Timeout() bool })
+	return ok && t.Timeout()
+}
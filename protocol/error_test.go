@@ -0,0 +1,37 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package protocol_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+func TestKindError(t *testing.T) {
+	var err = protocol.NewError(protocol.KindRejected, "test: Rejected")
+	if !errors.Is(err, protocol.KindRejected) || errors.Is(err, protocol.KindTimeout) {
+		t.Fatal("Unexpected kind")
+	}
+	if err.Error() != "test: Rejected" {
+		t.Fatal("Unexpected message", err.Error())
+	}
+
+	var wrapped = fmt.Errorf("context: %w", err)
+	if !errors.Is(wrapped, err) || !errors.Is(wrapped, protocol.KindRejected) {
+		t.Fatal("Kind lost after wrapping")
+	}
+
+	var k *protocol.KindError
+	if !errors.As(protocol.WrapError(protocol.KindTimeout, io.EOF), &k) || k.Kind != protocol.KindTimeout || !k.Timeout() || k.Unwrap() != io.EOF {
+		t.Fatal("Unexpected KindError", k)
+	}
+	if protocol.WrapError(protocol.KindTimeout, nil) != nil {
+		t.Fatal("Expected nil")
+	}
+}
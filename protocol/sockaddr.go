@@ -34,6 +34,24 @@ func Addr(a net.Addr) SockAddr {
 	}
 }
 
+// Addr4 converts net.Addr to SockAddr for addresses that are serialized in packets, which can only hold IPv4
+// The unspecified IPv6 address is replaced by the unspecified IPv4 address, other IPv6 addresses
+// cannot be represented and result in an empty SockAddr (no address)
+func Addr4(a net.Addr) SockAddr {
+	var s = Addr(a)
+	if s.IP == nil {
+		return s
+	}
+	if ip4 := s.IP.To4(); ip4 != nil {
+		s.IP = ip4
+	} else if s.IP.IsUnspecified() {
+		s.IP = net.IPv4zero
+	} else {
+		s = SockAddr{}
+	}
+	return s
+}
+
 // Equal compares s against o and returns true if they represent the same address
 func (s *SockAddr) Equal(o *SockAddr) bool {
 	return o.Port == s.Port && ((o.IP == nil && s.IP == nil) || o.IP.Equal(s.IP))
@@ -38,4 +38,21 @@ func TestSockAddrConv(t *testing.T) {
 	if tcpA.String() != tcpB.TCPAddr().String() {
 		t.Fatal("ResolveTCPAddr != TCPAddr")
 	}
+
+	tcp6, e := net.ResolveTCPAddr("tcp", "[::1]:6112")
+	if e != nil {
+		t.Fatal(e)
+	}
+	tcp4 := protocol.Addr4(tcp6)
+	if tcp4.Port != 0 || tcp4.IP != nil {
+		t.Fatal("Addr4(IPv6) != SockAddr{}")
+	}
+	tcp4 = protocol.Addr4(&net.TCPAddr{IP: net.IPv6unspecified, Port: 6112})
+	if tcp4.Port != 6112 || !tcp4.IP.Equal(net.IPv4zero) {
+		t.Fatal("Addr4([::]) != 0.0.0.0")
+	}
+	tcp4 = protocol.Addr4(tcpA)
+	if tcpA.String() != tcp4.TCPAddr().String() {
+		t.Fatal("Addr4(IPv4) != TCPAddr")
+	}
 }
@@ -16,7 +16,7 @@ var (
 	ErrNoFactory         = errors.New("w3gs: Invalid w3gs packet (empty factory)")
 	ErrNoProtocolSig     = errors.New("w3gs: Invalid w3gs packet (no signature found)")
 	ErrInvalidPacketSize = errors.New("w3gs: Invalid packet size")
-	ErrInvalidChecksum   = errors.New("w3gs: Checksum invalid")
+	ErrInvalidChecksum   = protocol.NewError(protocol.KindChecksum, "w3gs: Checksum invalid")
 	ErrUnexpectedConst   = errors.New("w3gs: Unexpected constant value")
 )
 